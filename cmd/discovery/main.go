@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
@@ -17,6 +23,8 @@ import (
 	"github.com/toska-mesh/toska-mesh/internal/consul"
 	"github.com/toska-mesh/toska-mesh/internal/discovery"
 	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
 	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
 )
 
@@ -34,23 +42,177 @@ func run(logger *slog.Logger) error {
 	consulAddr := envOr("CONSUL_ADDRESS", "http://localhost:8500")
 	rabbitURL := os.Getenv("RABBITMQ_URL")
 
-	// Consul registry.
-	registry, err := consul.NewRegistry(consulAddr, logger)
+	// Metrics sink. Discovery defaults to Prometheus, served on
+	// DISCOVERY_METRICS_PORT since the main port speaks only gRPC; set
+	// TELEMETRY_SINK=none to disable it.
+	telemetryCfg := telemetry.ConfigFromEnv("discovery")
+	if os.Getenv("TELEMETRY_SINK") == "" {
+		telemetryCfg.Sink = telemetry.SinkPrometheus
+	}
+	sink, err := telemetry.New(telemetryCfg, logger)
+	if err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+	tracer := telemetry.NewTracer(telemetryCfg, logger)
+
+	// Service registry: Consul, or etcd with REGISTRY_BACKEND=etcd.
+	registryCfg := registry.ConfigFromEnv(consulAddr)
+	registry, err := registry.New(registryCfg, logger)
 	if err != nil {
-		return fmt.Errorf("consul registry: %w", err)
+		return fmt.Errorf("registry: %w", err)
 	}
 
-	// RabbitMQ publisher (no-op if URL is empty).
-	publisher, err := messaging.NewPublisher(rabbitURL, logger)
+	// RabbitMQ publisher (no-op if URL is empty). RABBITMQ_EXCHANGE_MODE selects
+	// fanout (MassTransit, default), topic, or both.
+	pubOpts := messaging.DefaultPublisherOptions()
+	if v := os.Getenv("RABBITMQ_EXCHANGE_MODE"); v != "" {
+		pubOpts.Mode = messaging.ExchangeMode(v)
+	}
+	if v := os.Getenv("RABBITMQ_TOPIC_EXCHANGE"); v != "" {
+		pubOpts.TopicExchange = v
+	}
+	pubOpts.Telemetry = sink
+	publisher, err := messaging.NewPublisherWithOptions(rabbitURL, pubOpts, logger)
 	if err != nil {
 		return fmt.Errorf("rabbitmq publisher: %w", err)
 	}
 	defer publisher.Close()
 
-	// gRPC server.
-	grpcServer := grpc.NewServer()
+	grpcCfg := discovery.DefaultGRPCConfig()
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_MAX_RECV_MSG_BYTES")); err == nil && v > 0 {
+		grpcCfg.MaxRecvMsgSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_MAX_SEND_MSG_BYTES")); err == nil && v > 0 {
+		grpcCfg.MaxSendMsgSize = v
+	}
+
+	// Authentication. With no tokens or client CA configured the registry
+	// stays open; TLS alone only encrypts.
+	authCfg, err := authConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("auth config: %w", err)
+	}
+	tlsCfg, err := authCfg.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	authz := discovery.NewAuthorizer(authCfg)
+
+	// Per-client limits on the RPCs that write to Consul.
+	rateCfg := discovery.DefaultRateLimitConfig()
+	if os.Getenv("DISCOVERY_RATE_LIMIT_ENABLED") == "false" {
+		rateCfg.Enabled = false
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_RATE_LIMIT_PERMITS")); err == nil && v > 0 {
+		rateCfg.PermitLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_RATE_LIMIT_WINDOW_SECONDS")); err == nil && v > 0 {
+		rateCfg.WindowSeconds = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_RATE_LIMIT_MAX_CLIENTS")); err == nil && v > 0 {
+		rateCfg.MaxClients = v
+	}
+	if v := os.Getenv("DISCOVERY_RATE_LIMIT_METHODS"); v != "" {
+		rateCfg.Methods = splitComma(v)
+	}
+	limiter := discovery.NewRateLimiter(rateCfg, sink)
+
+	// gRPC server (gzip compression is registered by the discovery package).
+	// Tracing runs first so rejected calls are traced too; auth runs before
+	// rate limiting so authenticated callers are limited by identity rather
+	// than IP.
+	unary := []grpc.UnaryServerInterceptor{
+		discovery.TracingInterceptor(tracer),
+		discovery.TelemetryInterceptor(sink),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		discovery.TracingStreamInterceptor(tracer),
+		discovery.TelemetryStreamInterceptor(sink),
+	}
+	// Installed even when auth is off, since it refuses the admin RPCs then.
+	unary = append(unary, authz.UnaryInterceptor())
+	stream = append(stream, authz.StreamInterceptor())
+	if limiter != nil {
+		unary = append(unary, limiter.UnaryInterceptor())
+		stream = append(stream, limiter.StreamInterceptor())
+	}
+	serverOpts := append(grpcCfg.ServerOptions(),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	if tlsCfg != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
+
+	// HTTP webhooks notified alongside RabbitMQ. DISCOVERY_WEBHOOKS is a JSON
+	// array of {"name","url","secret","events"}.
+	var webhooks *messaging.WebhookNotifier
+	if v := os.Getenv("DISCOVERY_WEBHOOKS"); v != "" {
+		var hooks []messaging.Webhook
+		if err := json.Unmarshal([]byte(v), &hooks); err != nil {
+			return fmt.Errorf("invalid DISCOVERY_WEBHOOKS: %w", err)
+		}
+		webhookOpts := messaging.DefaultWebhookOptions()
+		if n, err := strconv.Atoi(os.Getenv("DISCOVERY_WEBHOOK_MAX_ATTEMPTS")); err == nil && n > 0 {
+			webhookOpts.MaxAttempts = n
+		}
+		webhookOpts.Telemetry = sink
+		webhooks, err = messaging.NewWebhookNotifier(hooks, webhookOpts, logger)
+		if err != nil {
+			return fmt.Errorf("webhooks: %w", err)
+		}
+	}
+
+	// Leader election among replicas (Consul only). Followers serve every
+	// RPC; the leader alone runs the cleanup sweep.
+	var (
+		election   *consul.Election
+		leadership discovery.Leadership
+	)
+	if os.Getenv("DISCOVERY_LEADER_ELECTION") == "true" {
+		if _, ok := registry.(*consul.Registry); !ok {
+			return fmt.Errorf("leader election requires the consul registry backend, not %q", registryCfg.Backend)
+		}
+		electionCfg := consul.DefaultElectionConfig()
+		if v := os.Getenv("DISCOVERY_LEADER_KEY"); v != "" {
+			electionCfg.Key = v
+		}
+		electionCfg.Value, _ = os.Hostname()
+		election, err = consul.NewElection(consulAddr, electionCfg, logger)
+		if err != nil {
+			return fmt.Errorf("leader election: %w", err)
+		}
+		leadership = election
+	}
+
+	// DISCOVERY_CONFLICT_POLICY: allow (default), reject, or supersede a
+	// registration that clashes with an existing instance.
+	conflictPolicy := discovery.ConflictPolicy(envOr("DISCOVERY_CONFLICT_POLICY", string(discovery.ConflictAllow)))
+	if !conflictPolicy.Valid() {
+		return fmt.Errorf("unknown DISCOVERY_CONFLICT_POLICY %q", conflictPolicy)
+	}
+
+	// Deregistered instances stay restorable for DISCOVERY_TOMBSTONE_GRACE_SECONDS.
+	tombstoneCfg := discovery.DefaultTombstoneConfig()
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_TOMBSTONE_GRACE_SECONDS")); err == nil && v > 0 {
+		tombstoneCfg.Grace = time.Duration(v) * time.Second
+	}
+	if v := os.Getenv("DISCOVERY_TOMBSTONE_PREFIX"); v != "" {
+		tombstoneCfg.KVPrefix = v
+	}
 
-	discoverySvc := discovery.NewServer(registry, publisher, logger)
+	// TTL renewal is opt-in per service; see RunRenewal below.
+	autoRenewServices := splitComma(os.Getenv("DISCOVERY_AUTO_RENEW_SERVICES"))
+	discoverySvc := discovery.NewServerWithOptions(registry, publisher, logger, discovery.Options{
+		Telemetry:         sink,
+		Tracer:            tracer,
+		Leadership:        leadership,
+		ConflictPolicy:    conflictPolicy,
+		Tombstones:        tombstoneCfg,
+		Webhooks:          webhooks,
+		AutoRenewServices: autoRenewServices,
+	})
 	pb.RegisterDiscoveryRegistryServer(grpcServer, discoverySvc)
 
 	// Standard gRPC health check service.
@@ -76,10 +238,182 @@ func run(logger *slog.Logger) error {
 		grpcServer.GracefulStop()
 	}()
 
-	logger.Info("discovery server starting", "port", port, "consul", consulAddr)
+	if otlp, ok := sink.(*telemetry.OTLP); ok {
+		go otlp.Run(ctx)
+	}
+	if tracer != nil {
+		go tracer.Run(ctx)
+	}
+	if election != nil {
+		go election.Run(ctx)
+	}
+	if webhooks != nil {
+		go webhooks.Run(ctx)
+	}
+	if os.Getenv("DISCOVERY_CLEANUP_ENABLED") == "true" {
+		cleanupCfg := discovery.DefaultCleanupConfig()
+		if v, err := strconv.Atoi(os.Getenv("DISCOVERY_CLEANUP_EXPIRE_SECONDS")); err == nil && v > 0 {
+			cleanupCfg.ExpireAfter = time.Duration(v) * time.Second
+		}
+		go discoverySvc.RunCleanup(ctx, cleanupCfg)
+	}
+	if tombstoneCfg.Grace > 0 {
+		go discoverySvc.RunTombstonePurge(ctx)
+	}
+	// TTL renewal for autoRenew registrations of the services listed in
+	// DISCOVERY_AUTO_RENEW_SERVICES, driven by the HealthMonitor.
+	renewCfg := discovery.DefaultRenewConfig()
+	if v, ok := os.LookupEnv("DISCOVERY_HEALTHMONITOR_URL"); ok {
+		renewCfg.HealthMonitorURL = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_AUTO_RENEW_INTERVAL_SECONDS")); err == nil && v > 0 {
+		renewCfg.Interval = time.Duration(v) * time.Second
+	}
+	if renewCfg.HealthMonitorURL != "" && len(autoRenewServices) > 0 {
+		go discoverySvc.RunRenewal(ctx, renewCfg)
+	}
+	if prom, ok := sink.(*telemetry.Prometheus); ok {
+		metricsPort := envOr("DISCOVERY_METRICS_PORT", "9090")
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", prom)
+		metricsServer := &http.Server{Addr: ":" + metricsPort, Handler: metricsMux, ReadTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			metricsServer.Close()
+		}()
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
+				logger.Error("metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	// HTTP/JSON façade for clients without gRPC (e.g. the dashboard proxy).
+	httpPort := envOr("DISCOVERY_HTTP_PORT", "5010")
+	if httpPort != "0" {
+		restHandler := discovery.NewRESTHandlerWithOptions(discoverySvc, discovery.RESTOptions{
+			Authorizer:  authz,
+			RateLimiter: limiter,
+			Tracer:      tracer,
+		})
+		restServer := &http.Server{
+			Addr:              ":" + httpPort,
+			Handler:           restHandler,
+			TLSConfig:         tlsCfg,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+		}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			restServer.Shutdown(shutdownCtx)
+		}()
+		go func() {
+			var err error
+			if tlsCfg != nil {
+				err = restServer.ListenAndServeTLS("", "")
+			} else {
+				err = restServer.ListenAndServe()
+			}
+			if err != http.ErrServerClosed {
+				logger.Error("REST server failed", "error", err)
+			}
+		}()
+	}
+
+	// Optional DNS interface for clients that cannot speak gRPC, e.g.
+	// dig @discovery -p 8600 orders.service.mesh SRV.
+	dnsCfg := discovery.DefaultDNSConfig()
+	dnsCfg.Enabled = os.Getenv("DISCOVERY_DNS_ENABLED") == "true"
+	if v := os.Getenv("DISCOVERY_DNS_ADDR"); v != "" {
+		dnsCfg.Addr = v
+	}
+	if v := os.Getenv("DISCOVERY_DNS_DOMAIN"); v != "" {
+		dnsCfg.Domain = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_DNS_TTL_SECONDS")); err == nil && v > 0 {
+		dnsCfg.TTL = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("DISCOVERY_DNS_MAX_CONCURRENT")); err == nil && v > 0 {
+		dnsCfg.MaxConcurrent = v
+	}
+	if dnsCfg.Enabled {
+		dnsServer := discovery.NewDNSServer(registry, dnsCfg, sink, logger)
+		go func() {
+			if err := dnsServer.ListenAndServe(ctx); err != nil {
+				logger.Error("DNS server failed", "error", err)
+			}
+		}()
+	}
+
+	logger.Info("discovery server starting",
+		"port", port,
+		"http_port", httpPort,
+		"registry", registryCfg.Backend,
+		"consul", consulAddr,
+		"max_recv_msg_bytes", grpcCfg.MaxRecvMsgSize,
+		"max_send_msg_bytes", grpcCfg.MaxSendMsgSize,
+		"tls", tlsCfg != nil,
+		"auth", authz != nil,
+		"rate_limit", limiter != nil,
+		"tracing", tracer != nil,
+		"dns", dnsCfg.Addr,
+		"leader_election", election != nil,
+		"conflict_policy", conflictPolicy,
+		"healthmonitor", renewCfg.HealthMonitorURL,
+		"tombstone_grace", tombstoneCfg.Grace,
+		"webhooks", webhooks != nil,
+	)
 	return grpcServer.Serve(lis)
 }
 
+// authConfigFromEnv reads the DISCOVERY_TLS_* and DISCOVERY_AUTH_* settings.
+// Unlike most settings, invalid auth JSON is fatal: ignoring it would leave
+// the registry open.
+//
+//	DISCOVERY_AUTH_TOKENS          {"<token>":"<identity>"}
+//	DISCOVERY_AUTH_ROLES           {"<identity>":["admin"]}
+//	DISCOVERY_AUTH_METHOD_ROLES    {"Deregister":["admin"]}
+//	DISCOVERY_AUTH_PUBLIC_METHODS  GetServices,GetInstances
+//
+// ExpireInstance and RebuildTracking need the admin role unless
+// DISCOVERY_AUTH_METHOD_ROLES lists them, and are refused with auth off.
+func authConfigFromEnv() (discovery.AuthConfig, error) {
+	cfg := discovery.AuthConfig{
+		CertFile:     os.Getenv("DISCOVERY_TLS_CERT_FILE"),
+		KeyFile:      os.Getenv("DISCOVERY_TLS_KEY_FILE"),
+		ClientCAFile: os.Getenv("DISCOVERY_TLS_CLIENT_CA_FILE"),
+	}
+	for key, dst := range map[string]any{
+		"DISCOVERY_AUTH_TOKENS":       &cfg.Tokens,
+		"DISCOVERY_AUTH_ROLES":        &cfg.Roles,
+		"DISCOVERY_AUTH_METHOD_ROLES": &cfg.MethodRoles,
+	} {
+		if v := os.Getenv(key); v != "" {
+			if err := json.Unmarshal([]byte(v), dst); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", key, err)
+			}
+		}
+	}
+	if v := os.Getenv("DISCOVERY_AUTH_PUBLIC_METHODS"); v != "" {
+		cfg.PublicMethods = splitComma(v)
+	}
+	return cfg, nil
+}
+
+// splitComma splits a comma-separated list, dropping blanks.
+func splitComma(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
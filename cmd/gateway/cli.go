@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/gateway"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+)
+
+const usage = `Usage: gateway [command]
+
+Commands:
+  serve                  run the gateway (default)
+  routes print           render the route table from a registry snapshot
+  jwt generate           mint a test token from the configured JWT secret
+  config print-defaults  print the built-in default configuration
+
+Configuration is read from the same environment variables as serve.
+`
+
+var errUsage = errors.New("invalid usage")
+
+// runCommand executes an offline operator subcommand and writes its output to out.
+func runCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch strings.Join(args[:min(2, len(args))], " ") {
+	case "routes print":
+		return routesPrint(args[2:], out)
+	case "jwt generate":
+		return jwtGenerate(args[2:], out)
+	case "config print-defaults":
+		return configPrintDefaults(out)
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(out, usage)
+		return nil
+	}
+	return errUsage
+}
+
+func routesPrint(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("routes print", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	registry, err := registry.New(cfg.RegistryConfig(), logger)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	routeTable := gateway.NewRouteTable(registry, cfg.Routing, logger)
+	if err := routeTable.Refresh(); err != nil {
+		return err
+	}
+	routes := routeTable.Snapshot()
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ROUTE\tSERVICE ID\tBACKEND")
+		for _, route := range routes {
+			for _, b := range route.Backends {
+				fmt.Fprintf(tw, "%s%s/\t%s\t%s\n", routeTable.Prefix(), route.ServiceName, b.ServiceID, b.Address)
+			}
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func jwtGenerate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("jwt generate", flag.ContinueOnError)
+	subject := fs.String("sub", "test-user", "subject claim")
+	ttl := fs.Duration("ttl", time.Hour, "token lifetime")
+	var claims claimFlags
+	fs.Var(&claims, "claim", "extra claim as key=value (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := loadConfig()
+	token, err := gateway.SignJWT(cfg.JWT, *subject, *ttl, claims)
+	if err != nil {
+		return fmt.Errorf("sign token (is JWT_SECRET_KEY set?): %w", err)
+	}
+	fmt.Fprintln(out, token)
+	return nil
+}
+
+func configPrintDefaults(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(printable(reflect.ValueOf(gateway.DefaultConfig())))
+}
+
+// printable converts a config value into JSON-friendly data, rendering
+// durations as strings (e.g. "30s") instead of nanosecond integers.
+func printable(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				m[v.Type().Field(i).Name] = printable(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return []any{}
+		}
+		s := make([]any, v.Len())
+		for i := range v.Len() {
+			s[i] = printable(v.Index(i))
+		}
+		return s
+	default:
+		return v.Interface()
+	}
+}
+
+// claimFlags collects repeated -claim key=value flags.
+type claimFlags map[string]any
+
+func (c *claimFlags) String() string { return "" }
+
+func (c *claimFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("claim must be key=value, got %q", s)
+	}
+	if *c == nil {
+		*c = make(claimFlags)
+	}
+	(*c)[k] = v
+	return nil
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,11 +15,32 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/auth"
 	"github.com/toska-mesh/toska-mesh/internal/gateway"
+	"github.com/toska-mesh/toska-mesh/internal/geoip"
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/proxyproto"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/router"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/watchdog"
+	"github.com/toska-mesh/toska-mesh/pkg/gatewayplugin"
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		if err := runCommand(os.Args[1:], os.Stdout); err != nil {
+			if errors.Is(err, errUsage) {
+				fmt.Fprint(os.Stderr, usage)
+				os.Exit(2)
+			}
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	if err := run(logger); err != nil {
@@ -29,13 +52,19 @@ func main() {
 func run(logger *slog.Logger) error {
 	cfg := loadConfig()
 
-	// Consul registry.
-	registry, err := consul.NewRegistry(cfg.ConsulAddr, logger)
+	// Service registry: Consul, or etcd with REGISTRY_BACKEND=etcd.
+	registry, err := registry.New(cfg.RegistryConfig(), logger)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	// Metrics sink shared by the proxy and load balancer.
+	sink, err := telemetry.New(cfg.Telemetry, logger)
 	if err != nil {
-		return fmt.Errorf("consul registry: %w", err)
+		return fmt.Errorf("telemetry: %w", err)
 	}
 
-	// Route table (polls Consul periodically).
+	// Route table (watches Consul, with periodic polling as a fallback).
 	routeTable := gateway.NewRouteTable(registry, cfg.Routing, logger)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -44,10 +73,35 @@ func run(logger *slog.Logger) error {
 	// Start route table refresh in background.
 	go routeTable.Run(ctx)
 
+	// Watchdog sheds load while the gateway itself is overloaded.
+	wd := watchdog.New("gateway", cfg.Watchdog, logger)
+	go wd.Run(ctx)
+
+	if otlp, ok := sink.(*telemetry.OTLP); ok {
+		go otlp.Run(ctx)
+	}
+
+	// Hold off accepting traffic until routes exist, so cold starts don't 502.
+	startupCtx, cancelStartup := context.WithTimeout(ctx, cfg.Routing.StartupTimeout)
+	if err := routeTable.WaitReady(startupCtx); err != nil {
+		logger.Warn("starting without initial route snapshot", "timeout", cfg.Routing.StartupTimeout, "error", err)
+	}
+	cancelStartup()
+
 	// Build the handler chain.
-	proxy := gateway.NewProxy(routeTable, cfg.Resilience, logger)
+	proxy := gateway.NewProxyWithOptions(routeTable, cfg.Resilience, cfg.Response, gateway.ProxyOptions{Telemetry: sink, Balancer: cfg.Balancer}, logger)
 	dashboard := gateway.NewDashboardProxy(cfg.Dashboard, registry, logger)
 
+	// Persisted load balancer stats survive restarts.
+	var statsStore *gateway.StatsStore
+	if cfg.Stats.PersistPath != "" {
+		statsStore = gateway.NewStatsStore(proxy, cfg.Stats, logger)
+		if err := statsStore.Load(); err != nil {
+			logger.Warn("starting without persisted load balancer stats", "error", err)
+		}
+		go statsStore.Run(ctx)
+	}
+
 	mux := http.NewServeMux()
 
 	// Health endpoint (no auth, no rate limiting).
@@ -56,54 +110,196 @@ func run(logger *slog.Logger) error {
 		json.NewEncoder(w).Encode(map[string]string{"status": "Healthy"})
 	})
 
+	// Readiness for load balancers: routes loaded and startup dependencies up.
+	readiness := gateway.NewReadiness(routeTable, cfg.Readiness.CheckTimeout)
+	if cfg.Readiness.RequireRabbitMQ && cfg.RabbitURL != "" {
+		readiness.AddStartupCheck("rabbitmq", func(ctx context.Context) error {
+			return messaging.Ping(ctx, cfg.RabbitURL)
+		})
+	}
+	mux.Handle("GET /ready", readiness)
+
+	// Aggregated mesh health (routes, breakers, HealthMonitor probes), behind
+	// JWT auth since it exposes the topology.
+	mux.Handle("GET /api/mesh/health", gateway.NewMeshHealth(routeTable, proxy, cfg.MeshHealth, logger))
+
+	// Prometheus scrape endpoint (no auth).
+	if prom, ok := sink.(*telemetry.Prometheus); ok {
+		mux.Handle("GET /metrics", prom)
+	}
+
+	// Admin endpoints (behind JWT auth): circuit breaker introspection.
+	mux.Handle("GET /admin/breakers", gateway.BreakersHandler(proxy))
+
+	// Route table inspection and last-refresh diff.
+	mux.Handle("GET /admin/routes", gateway.RoutesHandler(routeTable, proxy))
+	mux.Handle("GET /admin/routes/diff", gateway.RouteDiffHandler(routeTable))
+
+	// Load balancer stats export (JSON, or CSV with ?format=csv).
+	mux.Handle("GET /admin/stats", gateway.StatsExportHandler(proxy))
+	mux.Handle("GET /admin/stats/{service}/errors", gateway.ErrorRatesHandler(proxy))
+
+	// Resetting stats changes routing decisions, so only admins may do it.
+	if adminOnly, ok := auth.AdminAuth(cfg.JWT, ""); ok {
+		mux.Handle("DELETE /admin/stats", adminOnly(gateway.StatsResetHandler(proxy)))
+		mux.Handle("DELETE /admin/stats/{service}", adminOnly(gateway.StatsResetHandler(proxy)))
+	} else {
+		logger.Warn("stats reset API disabled: it requires JWT_SECRET_KEY or a JWKS URL")
+	}
+
+	// gRPC-Web bridge to the discovery service for browser clients.
+	if cfg.GRPCWeb.Enabled {
+		bridge, err := gateway.NewGRPCWebBridge(cfg.GRPCWeb, logger)
+		if err != nil {
+			return fmt.Errorf("grpc-web bridge: %w", err)
+		}
+		defer bridge.Close()
+		mux.Handle(cfg.GRPCWeb.PathPrefix, bridge)
+	}
+
 	// Dashboard proxy routes.
 	mux.Handle("/api/dashboard/", dashboard.Handler())
 
-	// Dynamic service proxy (catch-all under the route prefix).
-	mux.Handle(cfg.Routing.RoutePrefix, proxy)
+	// Dynamic service proxy (catch-all under the route prefix), optionally
+	// behind a per-service request queue, GET coalescing, priority load
+	// shedding, A/B experiments, and maintenance mode.
+	var proxyHandler http.Handler = proxy
+	if cfg.Queue.Enabled {
+		proxyHandler = gateway.NewRequestQueue(cfg.Queue, routeTable).Middleware(proxyHandler)
+	}
+	if cfg.Coalesce.Enabled {
+		proxyHandler = gateway.NewCoalescer(cfg.Coalesce, routeTable).Middleware(proxyHandler)
+	}
+	if cfg.Priority.Enabled {
+		proxyHandler = gateway.NewPriorityShedder(cfg.Priority, routeTable).Middleware(proxyHandler)
+	}
+	if len(cfg.Experiments.Experiments) > 0 {
+		proxyHandler = gateway.NewExperimentRouter(cfg.Experiments, routeTable).Middleware(proxyHandler)
+	}
+	if cfg.Maintenance.Enabled {
+		maintenance := gateway.NewMaintenance(registry, routeTable, cfg.Maintenance, logger)
+		go maintenance.Run(ctx)
+		proxyHandler = maintenance.Middleware(proxyHandler)
+		// The admin API writes Consul KV, so it is only served to admins.
+		if adminOnly, ok := auth.AdminAuth(cfg.JWT, ""); ok {
+			mux.Handle("/admin/maintenance", adminOnly(maintenance.AdminHandler()))
+			mux.Handle("/admin/maintenance/", adminOnly(maintenance.AdminHandler()))
+		} else {
+			logger.Warn("maintenance admin API disabled: it requires JWT_SECRET_KEY or a JWKS URL")
+		}
+	}
+	// Tenant-scoped routes (/t/{tenant}/api/{service}/...). Their instances
+	// are kept off the shared routes.
+	if cfg.Tenancy.Enabled {
+		mux.Handle(cfg.Routing.RoutePrefix, gateway.SharedRoutes(cfg.Tenancy, proxyHandler))
+		mux.Handle(cfg.Tenancy.PathPrefix, gateway.TenantRouter(cfg.Tenancy, proxyHandler))
+	} else {
+		mux.Handle(cfg.Routing.RoutePrefix, proxyHandler)
+	}
+
+	// Middleware plugins (registered from GATEWAY_PLUGIN_DIR, enabled by name).
+	plugins, err := loadPlugins(cfg.Plugins)
+	if err != nil {
+		return fmt.Errorf("gateway plugins: %w", err)
+	}
 
 	// Compose middleware stack (outermost first).
 	var handler http.Handler = mux
 
-	// JWT auth (skip health and dashboard).
-	handler = gateway.JWTAuth(cfg.JWT, []string{"/health", "/api/dashboard/"})(handler)
+	// Post-auth plugins see requests that passed JWT validation.
+	handler = plugins.Wrap(gatewayplugin.StagePostAuth, handler)
+
+	// JWT auth (skip health checks, metrics, and dashboard).
+	handler = gateway.JWTAuthWithRoutes(cfg.JWT, routeTable, []string{"/health", "/ready", "/metrics", "/api/dashboard/"})(handler)
+
+	// Service-to-service request signatures (keys in Consul KV).
+	if cfg.Signing.Enabled {
+		verifier := gateway.NewSignatureVerifier(registry, cfg.Signing, logger)
+		go verifier.Run(ctx)
+		handler = verifier.Middleware(handler)
+	}
+
+	// Pre-auth plugins (custom authentication, early rejection).
+	handler = plugins.Wrap(gatewayplugin.StagePreAuth, handler)
+
+	// GeoIP access rules and region preference.
+	if cfg.Geo.DatabasePath != "" {
+		geoDB, err := geoip.Open(cfg.Geo.DatabasePath)
+		if err != nil {
+			return fmt.Errorf("geoip: %w", err)
+		}
+		logger.Info("geoip database loaded", "path", cfg.Geo.DatabasePath, "networks", geoDB.Len())
+		handler = gateway.NewGeoFilter(cfg.Geo, geoDB).Middleware(handler)
+	}
 
 	// Rate limiting.
 	if cfg.RateLimit.Enabled {
-		rl := gateway.NewRateLimiter(cfg.RateLimit.PermitLimit, cfg.RateLimit.WindowSeconds)
+		rl := gateway.NewRateLimiterWithOptions(cfg.RateLimit.PermitLimit, cfg.RateLimit.WindowSeconds, gateway.RateLimiterOptions{
+			Name:       "global",
+			MaxBuckets: cfg.RateLimit.MaxBuckets,
+			Telemetry:  sink,
+		})
 		handler = rl.Middleware(handler)
 	}
 
 	// CORS.
-	handler = gateway.CORS(cfg.CORS)(handler)
+	handler = gateway.CORSWithRoutes(cfg.CORS, routeTable)(handler)
+
+	// Overload shedding happens before any other work, but the liveness
+	// check stays reachable so orchestrators can see the gateway is alive.
+	handler = wd.Middleware([]string{"/health"}, handler)
 
 	// Request logging.
 	handler = gateway.RequestLogging(logger, handler)
 
 	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
 	}
 
 	go func() {
 		<-ctx.Done()
 		logger.Info("shutting down gateway")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 		server.Shutdown(shutdownCtx)
 	}()
 
 	logger.Info("gateway starting",
 		"port", cfg.Port,
+		"registry", cfg.RegistryBackend,
 		"consul", cfg.ConsulAddr,
 		"route_prefix", cfg.Routing.RoutePrefix,
 	)
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if cfg.Server.ProxyProtocol.Enabled {
+		ppConfig, err := cfg.Server.ProxyProtocol.ListenerConfig()
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("proxy protocol: %w", err)
+		}
+		listener = proxyproto.NewListener(listener, ppConfig)
+		logger.Info("PROXY protocol enabled", "trusted_proxies", cfg.Server.ProxyProtocol.TrustedProxies)
+	}
+	if err := server.Serve(listener); err != http.ErrServerClosed {
 		return fmt.Errorf("http server: %w", err)
 	}
+
+	// Shutdown returns once in-flight requests drain, so the final snapshot
+	// includes them.
+	if statsStore != nil {
+		if err := statsStore.Save(); err != nil {
+			logger.Warn("failed to persist load balancer stats", "error", err)
+		}
+	}
 	return nil
 }
 
@@ -116,12 +312,174 @@ func loadConfig() gateway.Config {
 	if v := os.Getenv("CONSUL_ADDRESS"); v != "" {
 		cfg.ConsulAddr = v
 	}
+	if v := os.Getenv("CONSUL_DATACENTERS"); v != "" {
+		cfg.ConsulDatacenters = splitComma(v)
+	}
+	rc := registry.ConfigFromEnv(cfg.ConsulAddr)
+	cfg.RegistryBackend, cfg.ConsulReads = rc.Backend, rc.ConsulReads
+	cfg.Etcd, cfg.Kubernetes = rc.Etcd, rc.Kubernetes
+	cfg.RabbitURL = os.Getenv("RABBITMQ_URL")
+	if os.Getenv("GATEWAY_READY_REQUIRE_RABBITMQ") == "true" {
+		cfg.Readiness.RequireRabbitMQ = true
+	}
+
+	// HTTP server limits.
+	for env, dst := range map[string]*time.Duration{
+		"GATEWAY_READ_TIMEOUT_SECONDS":        &cfg.Server.ReadTimeout,
+		"GATEWAY_READ_HEADER_TIMEOUT_SECONDS": &cfg.Server.ReadHeaderTimeout,
+		"GATEWAY_WRITE_TIMEOUT_SECONDS":       &cfg.Server.WriteTimeout,
+		"GATEWAY_IDLE_TIMEOUT_SECONDS":        &cfg.Server.IdleTimeout,
+		"GATEWAY_SHUTDOWN_TIMEOUT_SECONDS":    &cfg.Server.ShutdownTimeout,
+	} {
+		if v, err := strconv.Atoi(os.Getenv(env)); err == nil && v >= 0 {
+			*dst = time.Duration(v) * time.Second
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_MAX_HEADER_BYTES")); err == nil && v > 0 {
+		cfg.Server.MaxHeaderBytes = v
+	}
+	if os.Getenv("GATEWAY_PROXY_PROTOCOL_ENABLED") == "true" {
+		cfg.Server.ProxyProtocol.Enabled = true
+	}
+	if os.Getenv("GATEWAY_PROXY_PROTOCOL_REQUIRED") == "true" {
+		cfg.Server.ProxyProtocol.Required = true
+	}
+	if v := os.Getenv("GATEWAY_PROXY_PROTOCOL_TRUSTED_PROXIES"); v != "" {
+		cfg.Server.ProxyProtocol.TrustedProxies = splitComma(v)
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_PROXY_PROTOCOL_HEADER_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		cfg.Server.ProxyProtocol.HeaderTimeout = time.Duration(v) * time.Second
+	}
 	if v := os.Getenv("GATEWAY_ROUTE_PREFIX"); v != "" {
 		cfg.Routing.RoutePrefix = v
 	}
 	if v, err := strconv.Atoi(os.Getenv("GATEWAY_ROUTE_REFRESH_SECONDS")); err == nil && v > 0 {
 		cfg.Routing.RefreshInterval = time.Duration(v) * time.Second
 	}
+	if v := os.Getenv("GATEWAY_WARM_SERVICES"); v != "" {
+		cfg.Routing.WarmServices = splitComma(v)
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_STARTUP_TIMEOUT_SECONDS")); err == nil && v >= 0 {
+		cfg.Routing.StartupTimeout = time.Duration(v) * time.Second
+	}
+	if os.Getenv("GATEWAY_ROUTE_WATCH_ENABLED") == "false" {
+		cfg.Routing.WatchEnabled = false
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_ROUTE_WATCH_WAIT_SECONDS")); err == nil && v > 0 {
+		cfg.Routing.WatchWaitTime = time.Duration(v) * time.Second
+	}
+	if v := os.Getenv("GATEWAY_ROUTE_RULES"); v != "" {
+		var rules []routing.Rule
+		if err := json.Unmarshal([]byte(v), &rules); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_ROUTE_RULES:", err)
+		} else if _, err := routing.CompileRules(rules); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_ROUTE_RULES:", err)
+		} else {
+			cfg.Routing.Rules = rules
+		}
+	}
+
+	// Load balancing: each gateway routes to a deterministic subset of
+	// large services, keyed by its index among the gateways, ramps traffic
+	// to new or recovered instances over the slow-start window, and pins
+	// hashed sessions to their instance for the affinity TTL.
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_SUBSET_SIZE")); err == nil && v > 0 {
+		cfg.Balancer.SubsetSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_CLIENT_COUNT")); err == nil && v > 0 {
+		cfg.Balancer.ClientCount = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_CLIENT_INDEX")); err == nil && v >= 0 {
+		if v < cfg.Balancer.ClientCount {
+			cfg.Balancer.ClientIndex = v
+		} else {
+			fmt.Fprintln(os.Stderr, "ignoring GATEWAY_CLIENT_INDEX outside GATEWAY_CLIENT_COUNT:", v)
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_SLOW_START_SECONDS")); err == nil && v > 0 {
+		cfg.Balancer.SlowStartWindow = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_AFFINITY_TTL_SECONDS")); err == nil && v > 0 {
+		cfg.Balancer.AffinityTTL = time.Duration(v) * time.Second
+	}
+	// GATEWAY_LB_STRATEGIES takes comma-separated service=strategy pairs,
+	// which override the services' lb_strategy metadata.
+	for _, pair := range splitComma(os.Getenv("GATEWAY_LB_STRATEGIES")) {
+		service, strategy, ok := strings.Cut(pair, "=")
+		if !ok {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_LB_STRATEGIES entry:", pair)
+			continue
+		}
+		if cfg.Balancer.Strategies == nil {
+			cfg.Balancer.Strategies = make(map[string]router.Strategy)
+		}
+		cfg.Balancer.Strategies[strings.ToLower(strings.TrimSpace(service))] = router.ParseStrategy(strings.TrimSpace(strategy))
+	}
+	if v := os.Getenv("GATEWAY_LB_DEFAULT_STRATEGY"); v != "" {
+		cfg.Balancer.DefaultStrategy = router.ParseStrategy(v)
+	}
+	cfg.Balancer.ShardHeader = os.Getenv("GATEWAY_SHARD_HEADER")
+	cfg.Balancer.LocalDatacenter = os.Getenv("GATEWAY_LOCAL_DATACENTER")
+
+	// Request coalescing.
+	if os.Getenv("GATEWAY_COALESCE_ENABLED") == "true" {
+		cfg.Coalesce.Enabled = true
+	}
+	if v := os.Getenv("GATEWAY_COALESCE_SERVICES"); v != "" {
+		cfg.Coalesce.Services = splitComma(v)
+	}
+	// Headers keyed on top of Authorization and Cookie, which always are.
+	if v := os.Getenv("GATEWAY_COALESCE_KEY_HEADERS"); v != "" {
+		cfg.Coalesce.KeyHeaders = splitComma(v)
+	}
+
+	// A/B experiments.
+	if v := os.Getenv("GATEWAY_EXPERIMENTS"); v != "" {
+		var experiments []gateway.Experiment
+		if err := json.Unmarshal([]byte(v), &experiments); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_EXPERIMENTS:", err)
+		} else if err := gateway.ValidateExperiments(experiments); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_EXPERIMENTS:", err)
+		} else {
+			cfg.Experiments.Experiments = experiments
+		}
+	}
+	if v, ok := os.LookupEnv("GATEWAY_EXPERIMENT_VARIANT_HEADER"); ok {
+		cfg.Experiments.VariantHeader = v
+	}
+
+	// GeoIP. GATEWAY_GEOIP_COUNTRY_RATE_LIMITS and GATEWAY_GEOIP_COUNTRY_REGIONS
+	// take comma-separated country=value pairs.
+	cfg.Geo.DatabasePath = os.Getenv("GATEWAY_GEOIP_DATABASE")
+	if v := os.Getenv("GATEWAY_GEOIP_BLOCKED_COUNTRIES"); v != "" {
+		cfg.Geo.BlockedCountries = splitComma(v)
+	}
+	for _, pair := range splitComma(os.Getenv("GATEWAY_GEOIP_COUNTRY_RATE_LIMITS")) {
+		country, limit, _ := strings.Cut(pair, "=")
+		if v, err := strconv.Atoi(limit); err == nil && v > 0 {
+			if cfg.Geo.CountryRateLimits == nil {
+				cfg.Geo.CountryRateLimits = make(map[string]int)
+			}
+			cfg.Geo.CountryRateLimits[strings.ToUpper(country)] = v
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_GEOIP_RATE_LIMIT_WINDOW_SECONDS")); err == nil && v > 0 {
+		cfg.Geo.RateLimitWindowSeconds = v
+	}
+	if os.Getenv("GATEWAY_GEOIP_PREFER_REGION") == "true" {
+		cfg.Geo.PreferRegion = true
+	}
+	if v := os.Getenv("GATEWAY_GEOIP_REGION_METADATA_KEY"); v != "" {
+		cfg.Geo.RegionMetadataKey = v
+	}
+	for _, pair := range splitComma(os.Getenv("GATEWAY_GEOIP_COUNTRY_REGIONS")) {
+		if country, region, ok := strings.Cut(pair, "="); ok && region != "" {
+			if cfg.Geo.CountryRegions == nil {
+				cfg.Geo.CountryRegions = make(map[string]string)
+			}
+			cfg.Geo.CountryRegions[strings.ToUpper(country)] = region
+		}
+	}
 
 	// Rate limit.
 	if os.Getenv("GATEWAY_RATE_LIMIT_ENABLED") == "false" {
@@ -133,6 +491,9 @@ func loadConfig() gateway.Config {
 	if v, err := strconv.Atoi(os.Getenv("GATEWAY_RATE_LIMIT_WINDOW_SECONDS")); err == nil && v > 0 {
 		cfg.RateLimit.WindowSeconds = v
 	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_RATE_LIMIT_MAX_BUCKETS")); err == nil && v > 0 {
+		cfg.RateLimit.MaxBuckets = v
+	}
 
 	// CORS.
 	if os.Getenv("GATEWAY_CORS_ALLOW_ANY_ORIGIN") == "false" {
@@ -141,37 +502,260 @@ func loadConfig() gateway.Config {
 	if v := os.Getenv("GATEWAY_CORS_ALLOWED_ORIGINS"); v != "" {
 		cfg.CORS.AllowedOrigins = splitComma(v)
 	}
+	if v := os.Getenv("GATEWAY_CORS_PASSTHROUGH_SERVICES"); v != "" {
+		cfg.CORS.PassthroughServices = splitComma(v)
+	}
 
 	// JWT.
 	cfg.JWT.SecretKey = os.Getenv("JWT_SECRET_KEY")
 	cfg.JWT.Issuer = envOr("JWT_ISSUER", "ToskaMesh.Gateway")
 	cfg.JWT.Audience = envOr("JWT_AUDIENCE", "ToskaMesh.Services")
+	cfg.JWT.JWKSURL = os.Getenv("JWT_JWKS_URL")
+	// GATEWAY_SERVICE_JWT is a JSON object of per-service overrides, e.g.
+	// {"partner-api":{"issuer":"https://idp.partner","jwksUrl":"https://idp.partner/jwks"}}.
+	if v := os.Getenv("GATEWAY_SERVICE_JWT"); v != "" {
+		var overrides map[string]gateway.JWTOverride
+		if err := json.Unmarshal([]byte(v), &overrides); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_SERVICE_JWT:", err)
+		} else {
+			cfg.JWT.Services = make(map[string]gateway.JWTOverride, len(overrides))
+			for name, o := range overrides {
+				cfg.JWT.Services[strings.ToLower(name)] = o
+			}
+		}
+	}
 
 	// Resilience.
 	if v, err := strconv.Atoi(os.Getenv("GATEWAY_RETRY_COUNT")); err == nil && v >= 0 {
 		cfg.Resilience.RetryCount = v
 	}
+	// GATEWAY_SERVICE_RESILIENCE is a JSON object of per-service overrides,
+	// e.g. {"payments":{"retryCount":0}}.
+	if v := os.Getenv("GATEWAY_SERVICE_RESILIENCE"); v != "" {
+		var overrides map[string]gateway.ResilienceOverride
+		if err := json.Unmarshal([]byte(v), &overrides); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid GATEWAY_SERVICE_RESILIENCE:", err)
+		} else {
+			cfg.Resilience.Services = make(map[string]gateway.ResilienceOverride, len(overrides))
+			for name, o := range overrides {
+				cfg.Resilience.Services[strings.ToLower(name)] = o
+			}
+		}
+	}
+
+	// Upstream response limits. GATEWAY_SERVICE_MAX_RESPONSE_BYTES takes
+	// comma-separated service=bytes pairs.
+	if v, err := strconv.ParseInt(os.Getenv("GATEWAY_MAX_RESPONSE_BYTES"), 10, 64); err == nil && v >= 0 {
+		cfg.Response.MaxBytes = v
+	}
+	if v := os.Getenv("GATEWAY_RESPONSE_OVERFLOW"); v != "" {
+		cfg.Response.Overflow = gateway.OverflowMode(v)
+	}
+	for _, pair := range splitComma(os.Getenv("GATEWAY_SERVICE_MAX_RESPONSE_BYTES")) {
+		name, limit, _ := strings.Cut(pair, "=")
+		if v, err := strconv.ParseInt(limit, 10, 64); err == nil && v >= 0 {
+			if cfg.Response.ServiceMaxBytes == nil {
+				cfg.Response.ServiceMaxBytes = make(map[string]int64)
+			}
+			cfg.Response.ServiceMaxBytes[strings.ToLower(name)] = v
+		}
+	}
+	// Coalescing buffers no more of a response than the proxy does.
+	cfg.Coalesce.MaxResponseBytes = cfg.Response.MaxBytes
+
+	// Request queue.
+	if os.Getenv("GATEWAY_QUEUE_ENABLED") == "true" {
+		cfg.Queue.Enabled = true
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_QUEUE_MAX_CONCURRENT_PER_BACKEND")); err == nil && v > 0 {
+		cfg.Queue.MaxConcurrentPerBackend = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_QUEUE_MAX_SIZE")); err == nil && v >= 0 {
+		cfg.Queue.MaxQueueSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_QUEUE_TIMEOUT_MS")); err == nil && v > 0 {
+		cfg.Queue.QueueTimeout = time.Duration(v) * time.Millisecond
+	}
+
+	// Tenancy.
+	if os.Getenv("GATEWAY_TENANCY_ENABLED") == "true" {
+		cfg.Tenancy.Enabled = true
+	}
+	if v := os.Getenv("GATEWAY_TENANT_PATH_PREFIX"); v != "" {
+		cfg.Tenancy.PathPrefix = v
+	}
+	if v := os.Getenv("GATEWAY_TENANT_CLAIM"); v != "" {
+		cfg.Tenancy.TenantClaim = v
+	}
+	if v := os.Getenv("GATEWAY_TENANT_METADATA_KEY"); v != "" {
+		cfg.Tenancy.MetadataKey = v
+	}
+	// Auth and CORS resolve tenant paths before TenantRouter strips them.
+	if cfg.Tenancy.Enabled {
+		cfg.Routing.TenantPrefix = cfg.Tenancy.PathPrefix
+	}
 
-	// Dashboard.
-	if v := os.Getenv("DASHBOARD_PROMETHEUS_URL"); v != "" {
-		cfg.Dashboard.PrometheusBaseURL = v
+	// Priority load shedding.
+	if os.Getenv("GATEWAY_PRIORITY_ENABLED") == "true" {
+		cfg.Priority.Enabled = true
+	}
+	if v, ok := os.LookupEnv("GATEWAY_PRIORITY_CLAIM"); ok {
+		cfg.Priority.Claim = v
 	}
-	if v := os.Getenv("DASHBOARD_TRACING_URL"); v != "" {
-		cfg.Dashboard.TracingBaseURL = v
+	if v := os.Getenv("GATEWAY_HIGH_PRIORITY_SERVICES"); v != "" {
+		cfg.Priority.HighPriorityServices = splitComma(v)
 	}
-	if v := os.Getenv("DASHBOARD_DISCOVERY_URL"); v != "" {
-		cfg.Dashboard.DiscoveryBaseURL = v
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_PRIORITY_MAX_INFLIGHT_LOW")); err == nil && v > 0 {
+		cfg.Priority.MaxInFlightLow = v
 	}
-	if v := os.Getenv("DASHBOARD_HEALTHMONITOR_URL"); v != "" {
-		cfg.Dashboard.HealthMonitorBaseURL = v
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_PRIORITY_MAX_INFLIGHT_HIGH")); err == nil && v > 0 {
+		cfg.Priority.MaxInFlightHigh = v
+	}
+
+	// Plugins.
+	cfg.Plugins.Dir = os.Getenv("GATEWAY_PLUGIN_DIR")
+	if v := os.Getenv("GATEWAY_PLUGINS"); v != "" {
+		cfg.Plugins.Enabled = splitComma(v)
+	}
+	cfg.Plugins.Config = pluginConfigFromEnv(os.Environ(), cfg.Plugins.Enabled)
+
+	// Mesh health.
+	if v, ok := os.LookupEnv("GATEWAY_HEALTHMONITOR_URL"); ok {
+		cfg.MeshHealth.HealthMonitorURL = v
+	}
+
+	// Dashboard. DASHBOARD_UPSTREAMS replaces the upstream list with a JSON
+	// array of {"name","baseUrl","pathTemplate"}; the per-upstream URL
+	// variables then override individual base URLs.
+	if v := os.Getenv("DASHBOARD_UPSTREAMS"); v != "" {
+		var upstreams []gateway.DashboardUpstream
+		if err := json.Unmarshal([]byte(v), &upstreams); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid DASHBOARD_UPSTREAMS:", err)
+		} else if err := gateway.ValidateDashboardUpstreams(upstreams); err != nil {
+			fmt.Fprintln(os.Stderr, "ignoring invalid DASHBOARD_UPSTREAMS:", err)
+		} else {
+			cfg.Dashboard.Upstreams = upstreams
+		}
+	}
+	for name, env := range map[string]string{
+		"prometheus": "DASHBOARD_PROMETHEUS_URL",
+		"traces":     "DASHBOARD_TRACING_URL",
+		"discovery":  "DASHBOARD_DISCOVERY_URL",
+		"health":     "DASHBOARD_HEALTHMONITOR_URL",
+	} {
+		if v := os.Getenv(env); v != "" {
+			cfg.Dashboard.SetBaseURL(name, v)
+		}
 	}
 	if v := os.Getenv("MESH_SERVICE_AUTH_SECRET"); v != "" {
 		cfg.Dashboard.ServiceAuthSecret = v
 	}
 
+	// Maintenance mode (per-service flags in Consul KV).
+	if os.Getenv("GATEWAY_MAINTENANCE_ENABLED") == "true" {
+		cfg.Maintenance.Enabled = true
+	}
+	if v := os.Getenv("GATEWAY_MAINTENANCE_KV_PREFIX"); v != "" {
+		cfg.Maintenance.KVPrefix = strings.TrimSuffix(v, "/") + "/"
+	}
+	if v := os.Getenv("GATEWAY_MAINTENANCE_MESSAGE"); v != "" {
+		cfg.Maintenance.DefaultMessage = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_MAINTENANCE_RETRY_AFTER_SECONDS")); err == nil && v > 0 {
+		cfg.Maintenance.DefaultRetryAfter = time.Duration(v) * time.Second
+	}
+
+	// Service-to-service request signing.
+	if os.Getenv("GATEWAY_SIGNING_ENABLED") == "true" {
+		cfg.Signing.Enabled = true
+	}
+	if v := os.Getenv("GATEWAY_SIGNING_KV_PREFIX"); v != "" {
+		cfg.Signing.KVPrefix = strings.TrimSuffix(v, "/") + "/"
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_SIGNING_MAX_CLOCK_SKEW_SECONDS")); err == nil && v > 0 {
+		cfg.Signing.MaxClockSkew = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_SIGNING_MAX_BODY_BYTES")); err == nil && v > 0 {
+		cfg.Signing.MaxBodyBytes = int64(v)
+	}
+	// GATEWAY_SIGNING_ACCEPT_WITHOUT_JWT lists the services whose signed
+	// requests need no JWT, or "*" for every service.
+	cfg.Signing.AcceptWithoutJWT = splitComma(os.Getenv("GATEWAY_SIGNING_ACCEPT_WITHOUT_JWT"))
+
+	// gRPC-Web bridge.
+	if os.Getenv("GATEWAY_GRPCWEB_ENABLED") == "true" {
+		cfg.GRPCWeb.Enabled = true
+		// Browser gRPC-Web clients send these on every call.
+		cfg.CORS.AllowedHeaders = append(cfg.CORS.AllowedHeaders, "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout")
+	}
+	if v := os.Getenv("GATEWAY_GRPCWEB_TARGET"); v != "" {
+		cfg.GRPCWeb.Target = v
+	}
+	if v := os.Getenv("GATEWAY_GRPCWEB_METHODS"); v != "" {
+		cfg.GRPCWeb.AllowedMethods = splitComma(v)
+	}
+
+	// Load balancer stats persistence.
+	if v := os.Getenv("GATEWAY_STATS_PERSIST_PATH"); v != "" {
+		cfg.Stats.PersistPath = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_STATS_PERSIST_INTERVAL_SECONDS")); err == nil && v > 0 {
+		cfg.Stats.PersistInterval = time.Duration(v) * time.Second
+	}
+
+	// Watchdog (goroutines, heap, ticker lag).
+	if os.Getenv("GATEWAY_WATCHDOG_ENABLED") == "true" {
+		cfg.Watchdog.Enabled = true
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_WATCHDOG_MAX_GOROUTINES")); err == nil && v > 0 {
+		cfg.Watchdog.MaxGoroutines = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_WATCHDOG_MAX_HEAP_MB")); err == nil && v > 0 {
+		cfg.Watchdog.MaxHeapBytes = uint64(v) << 20
+	}
+	if v, err := strconv.Atoi(os.Getenv("GATEWAY_WATCHDOG_MAX_LAG_MS")); err == nil && v > 0 {
+		cfg.Watchdog.MaxLag = time.Duration(v) * time.Millisecond
+	}
+	cfg.Watchdog.DumpDir = os.Getenv("GATEWAY_WATCHDOG_DUMP_DIR")
+	cfg.Watchdog.AlertWebhookURL = os.Getenv("GATEWAY_WATCHDOG_ALERT_WEBHOOK_URL")
+
+	// Telemetry sink (none, prometheus, or otlp).
+	cfg.Telemetry = telemetry.ConfigFromEnv("gateway")
+
 	return cfg
 }
 
+// loadPlugins registers plugins from the configured directory and builds the
+// enabled chain.
+func loadPlugins(cfg gateway.PluginConfig) (*gatewayplugin.Chain, error) {
+	reg := gatewayplugin.NewRegistry()
+	if cfg.Dir != "" {
+		if err := reg.LoadDir(cfg.Dir); err != nil {
+			return nil, err
+		}
+	}
+	return reg.Build(cfg.Enabled, cfg.Config)
+}
+
+// pluginConfigFromEnv collects GATEWAY_PLUGIN_<NAME>_<KEY>=value variables for
+// each enabled plugin. NAME is the plugin name upper-cased with "-" replaced
+// by "_"; keys are passed to the plugin lower-cased.
+func pluginConfigFromEnv(environ []string, enabled []string) map[string]map[string]string {
+	configs := make(map[string]map[string]string, len(enabled))
+	for _, name := range enabled {
+		prefix := "GATEWAY_PLUGIN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := make(map[string]string)
+		for _, kv := range environ {
+			k, v, _ := strings.Cut(kv, "=")
+			if key, ok := strings.CutPrefix(k, prefix); ok && key != "" {
+				config[strings.ToLower(key)] = v
+			}
+		}
+		configs[name] = config
+	}
+	return configs
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
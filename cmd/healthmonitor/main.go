@@ -2,19 +2,32 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
+
+	"github.com/toska-mesh/toska-mesh/internal/auth"
 	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
 	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/watchdog"
+	"github.com/toska-mesh/toska-mesh/pkg/healthprobe"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
 )
 
 func main() {
@@ -28,6 +41,7 @@ func main() {
 
 func run(logger *slog.Logger) error {
 	port := envOr("HEALTHMONITOR_PORT", "8081")
+	grpcPort := envOr("HEALTHMONITOR_GRPC_PORT", "8082")
 	consulAddr := envOr("CONSUL_ADDRESS", "http://localhost:8500")
 	rabbitURL := os.Getenv("RABBITMQ_URL")
 
@@ -35,36 +49,249 @@ func run(logger *slog.Logger) error {
 	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_PROBE_INTERVAL_SECONDS")); err == nil && v > 0 {
 		cfg.ProbeInterval = time.Duration(v) * time.Second
 	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_MAX_CONCURRENT_PROBES")); err == nil && v > 0 {
+		cfg.MaxConcurrentProbes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_PROBE_CYCLE_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		cfg.ProbeCycleTimeout = time.Duration(v) * time.Second
+	}
+	if os.Getenv("HEALTHMONITOR_WATCH_ENABLED") == "false" {
+		cfg.WatchEnabled = false
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_WATCH_WAIT_SECONDS")); err == nil && v > 0 {
+		cfg.WatchWaitTime = time.Duration(v) * time.Second
+	}
+	if os.Getenv("HEALTHMONITOR_REPORT_HEALTH") == "true" {
+		cfg.ReportHealth = true
+	}
+	if os.Getenv("HEALTHMONITOR_SPREAD_PROBES") == "true" {
+		cfg.SpreadProbes = true
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_PROBE_JITTER_PERCENT")); err == nil && v >= 0 && v < 100 {
+		cfg.ProbeJitter = float64(v) / 100
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_MIN_PROBE_INTERVAL_SECONDS")); err == nil && v > 0 {
+		cfg.MinProbeInterval = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_MAX_PROBE_INTERVAL_SECONDS")); err == nil && v > 0 {
+		cfg.MaxProbeInterval = time.Duration(v) * time.Second
+	}
 	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_HTTP_TIMEOUT_SECONDS")); err == nil && v > 0 {
 		cfg.HTTPTimeout = time.Duration(v) * time.Second
 	}
 	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_TCP_TIMEOUT_SECONDS")); err == nil && v > 0 {
 		cfg.TCPTimeout = time.Duration(v) * time.Second
 	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_TLS_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		cfg.TLSTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_TLS_EXPIRY_THRESHOLD_DAYS")); err == nil && v >= 0 {
+		cfg.TLSExpiryThreshold = time.Duration(v) * 24 * time.Hour
+	}
+	if path := os.Getenv("HEALTHMONITOR_TLS_CA_FILE"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("tls ca file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls ca file %s: no certificates found", path)
+		}
+		cfg.TLSRootCAs = roots
+	}
+	if os.Getenv("HEALTHMONITOR_EXEC_ENABLED") == "true" {
+		cfg.ExecEnabled = true
+	}
+	// HEALTHMONITOR_EXEC_COMMANDS takes semicolon-separated name=argv
+	// entries, e.g. "db=/usr/local/bin/check-db --host {address}".
+	for _, entry := range strings.Split(os.Getenv("HEALTHMONITOR_EXEC_COMMANDS"), ";") {
+		name, command, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if name, argv := strings.TrimSpace(name), strings.Fields(command); name != "" && len(argv) > 0 {
+			if cfg.ExecCommands == nil {
+				cfg.ExecCommands = make(map[string][]string)
+			}
+			cfg.ExecCommands[name] = argv
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_EXEC_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		cfg.ExecTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_UDP_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		cfg.UDPTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_LATENCY_THRESHOLD_MS")); err == nil && v > 0 {
+		cfg.LatencyThreshold = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_LATENCY_CONSECUTIVE_PROBES")); err == nil && v > 0 {
+		cfg.LatencyConsecutive = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_FLAP_THRESHOLD")); err == nil && v > 0 {
+		cfg.FlapThreshold = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_FLAP_WINDOW_SECONDS")); err == nil && v > 0 {
+		cfg.FlapWindow = time.Duration(v) * time.Second
+	}
+	if os.Getenv("HEALTHMONITOR_FLAP_HOLD_OUT") == "true" {
+		cfg.FlapHoldOut = true
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_ALERT_MIN_UNHEALTHY_SECONDS")); err == nil && v > 0 {
+		cfg.AlertRules.MinUnhealthy = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("HEALTHMONITOR_ALERT_SERVICE_DOWN_PERCENT"), 64); err == nil && v > 0 {
+		cfg.AlertRules.ServiceDownPercent = v
+	}
+	if v := os.Getenv("HEALTHMONITOR_ALERT_QUIET_HOURS"); v != "" {
+		quiet, err := healthmonitor.ParseQuietHours(v)
+		if err != nil {
+			return fmt.Errorf("HEALTHMONITOR_ALERT_QUIET_HOURS: %w", err)
+		}
+		cfg.AlertRules.QuietHours = quiet
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_ALERT_REPEAT_MINUTES")); err == nil && v > 0 {
+		cfg.AlertRules.RepeatInterval = time.Duration(v) * time.Minute
+	}
 	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_FAILURE_THRESHOLD")); err == nil && v > 0 {
 		cfg.FailureThreshold = v
 	}
+	if os.Getenv("HEALTHMONITOR_DETECT_ENABLED") == "true" {
+		cfg.DetectEnabled = true
+	}
+	if os.Getenv("HEALTHMONITOR_DETECT_WRITE_BACK") == "true" {
+		cfg.DetectWriteBack = true
+	}
+	if v := os.Getenv("HEALTHMONITOR_DETECT_PATHS"); v != "" {
+		var paths []string
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				paths = append(paths, p)
+			}
+		}
+		cfg.DetectHealthPaths = paths
+	}
+	if os.Getenv("HEALTHMONITOR_CLUSTER_ENABLED") == "true" {
+		hostname, _ := os.Hostname()
+		cfg.Cluster = healthmonitor.ClusterConfig{
+			Enabled:   true,
+			Service:   envOr("HEALTHMONITOR_CLUSTER_SERVICE", healthmonitor.DefaultClusterService),
+			ReplicaID: envOr("HEALTHMONITOR_REPLICA_ID", hostname),
+			Address:   envOr("HEALTHMONITOR_ADVERTISE_ADDRESS", hostname),
+		}
+		cfg.Cluster.Port, _ = strconv.Atoi(port)
+		if cfg.Cluster.ReplicaID == "" {
+			return fmt.Errorf("HEALTHMONITOR_REPLICA_ID is required when the hostname is unknown")
+		}
+	}
 
-	// Consul registry.
-	registry, err := consul.NewRegistry(consulAddr, logger)
+	// Metrics sink shared by the probe worker and publisher.
+	// Unlike the other components, the monitor exports Prometheus metrics
+	// by default so mesh health can be scraped without extra configuration.
+	telemetryCfg := telemetry.ConfigFromEnv("healthmonitor")
+	if os.Getenv("TELEMETRY_SINK") == "" {
+		telemetryCfg.Sink = telemetry.SinkPrometheus
+	}
+	sink, err := telemetry.New(telemetryCfg, logger)
 	if err != nil {
-		return fmt.Errorf("consul registry: %w", err)
+		return fmt.Errorf("telemetry: %w", err)
 	}
 
-	// RabbitMQ publisher (no-op if URL is empty).
-	publisher, err := messaging.NewPublisher(rabbitURL, logger)
+	// Service registry: Consul, or etcd with REGISTRY_BACKEND=etcd.
+	registryCfg := registry.ConfigFromEnv(consulAddr)
+	registry, err := registry.New(registryCfg, logger)
+	if err != nil {
+		return fmt.Errorf("registry: %w", err)
+	}
+
+	// RabbitMQ publisher (no-op if URL is empty). RABBITMQ_EXCHANGE_MODE selects
+	// fanout (MassTransit, default), topic, or both.
+	pubOpts := messaging.DefaultPublisherOptions()
+	if v := os.Getenv("RABBITMQ_EXCHANGE_MODE"); v != "" {
+		pubOpts.Mode = messaging.ExchangeMode(v)
+	}
+	if v := os.Getenv("RABBITMQ_TOPIC_EXCHANGE"); v != "" {
+		pubOpts.TopicExchange = v
+	}
+	pubOpts.Telemetry = sink
+	publisher, err := messaging.NewPublisherWithOptions(rabbitURL, pubOpts, logger)
 	if err != nil {
 		return fmt.Errorf("rabbitmq publisher: %w", err)
 	}
 	defer publisher.Close()
 
-	cache := healthmonitor.NewCache()
-	worker := healthmonitor.NewWorker(registry, publisher, cache, cfg, logger)
+	// Alert webhooks fired when an instance becomes Unhealthy and when it
+	// recovers. HEALTHMONITOR_ALERT_WEBHOOKS is a JSON array of
+	// {"name","url","secret","services","template","contentType"}.
+	var alerts *messaging.WebhookNotifier
+	if v := os.Getenv("HEALTHMONITOR_ALERT_WEBHOOKS"); v != "" {
+		var hooks []messaging.Webhook
+		if err := json.Unmarshal([]byte(v), &hooks); err != nil {
+			return fmt.Errorf("invalid HEALTHMONITOR_ALERT_WEBHOOKS: %w", err)
+		}
+		alertOpts := messaging.DefaultWebhookOptions()
+		if n, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_ALERT_MAX_ATTEMPTS")); err == nil && n > 0 {
+			alertOpts.MaxAttempts = n
+		}
+		alertOpts.Telemetry = sink
+		alertOpts.Service = "toska-mesh-healthmonitor"
+		alerts, err = messaging.NewWebhookNotifier(hooks, alertOpts, logger)
+		if err != nil {
+			return fmt.Errorf("alert webhooks: %w", err)
+		}
+	}
+
+	// Watchdog skips probe cycles while the monitor itself is overloaded.
+	wd := watchdog.New("healthmonitor", watchdogConfigFromEnv(), logger)
+
+	historySize := healthmonitor.DefaultHistorySize
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_HISTORY_SIZE")); err == nil && v >= 0 {
+		historySize = v
+	}
+	cache := healthmonitor.NewCacheWithHistory(historySize)
 
 	// Graceful shutdown.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Optional persistent history: HEALTHMONITOR_STORE=sqlite or postgres.
+	var store healthmonitor.Store
+	if dialect := os.Getenv("HEALTHMONITOR_STORE"); dialect != "" {
+		db, err := openStoreDB(dialect, os.Getenv("HEALTHMONITOR_STORE_DSN"))
+		if err != nil {
+			return fmt.Errorf("health history store: %w", err)
+		}
+		defer db.Close()
+		sqlStore, err := healthmonitor.NewSQLStore(ctx, db, dialect)
+		if err != nil {
+			return fmt.Errorf("health history store: %w", err)
+		}
+		store = sqlStore
+
+		retention := 30 * 24 * time.Hour
+		if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_STORE_RETENTION_DAYS")); err == nil && v > 0 {
+			retention = time.Duration(v) * 24 * time.Hour
+		}
+		go healthmonitor.RunStorePurge(ctx, store, retention, time.Hour, logger)
+	}
+
+	worker := healthmonitor.NewWorkerWithOptions(registry, publisher, cache, cfg, healthmonitor.WorkerOptions{
+		Watchdog:  wd,
+		Telemetry: sink,
+		Store:     store,
+		Alerts:    alerts,
+		Probers:   healthprobe.Default.Probers(),
+	}, logger)
+
+	go wd.Run(ctx)
+	if otlp, ok := sink.(*telemetry.OTLP); ok {
+		go otlp.Run(ctx)
+	}
+
+	if alerts != nil {
+		go alerts.Run(ctx)
+	}
+
 	// Start probe worker in background.
 	go worker.Run(ctx)
 
@@ -76,9 +303,20 @@ func run(logger *slog.Logger) error {
 		json.NewEncoder(w).Encode(map[string]string{"status": "Healthy"})
 	})
 
+	if prom, ok := sink.(*telemetry.Prometheus); ok {
+		mux.Handle("GET /metrics", prom)
+	}
+
 	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		q, err := healthmonitor.ParseStatusQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, total := cache.Query(q)
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(cache.GetAll())
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		json.NewEncoder(w).Encode(page)
 	})
 
 	mux.HandleFunc("GET /api/status/{serviceName}", func(w http.ResponseWriter, r *http.Request) {
@@ -87,14 +325,114 @@ func run(logger *slog.Logger) error {
 		json.NewEncoder(w).Encode(cache.GetByService(serviceName))
 	})
 
+	mux.HandleFunc("GET /api/history/{serviceId}", func(w http.ResponseWriter, r *http.Request) {
+		history := cache.History(r.PathValue("serviceId"))
+		if history == nil {
+			http.Error(w, "no probe history for this instance", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+	})
+
+	mux.HandleFunc("GET /api/composite", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(healthmonitor.EvaluateComposites(cache))
+	})
+
+	mux.HandleFunc("GET /api/composite/{serviceName}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(healthmonitor.EvaluateComposite(cache, r.PathValue("serviceName")))
+	})
+
+	if store != nil {
+		mux.HandleFunc("GET /api/availability/{serviceId}", func(w http.ResponseWriter, r *http.Request) {
+			window := 24 * time.Hour
+			if v := r.URL.Query().Get("window"); v != "" {
+				d, err := healthmonitor.ParseWindow(v)
+				if err != nil {
+					http.Error(w, "invalid window", http.StatusBadRequest)
+					return
+				}
+				window = d
+			}
+			a, err := store.Availability(r.Context(), r.PathValue("serviceId"), time.Now().Add(-window))
+			if err != nil {
+				logger.Error("availability query failed", "error", err)
+				http.Error(w, "availability query failed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(a)
+		})
+
+		mux.HandleFunc("GET /api/sla/{serviceName}", func(w http.ResponseWriter, r *http.Request) {
+			window := 30 * 24 * time.Hour
+			if v := r.URL.Query().Get("window"); v != "" {
+				d, err := healthmonitor.ParseWindow(v)
+				if err != nil {
+					http.Error(w, "invalid window", http.StatusBadRequest)
+					return
+				}
+				window = d
+			}
+			sla, err := store.SLA(r.Context(), r.PathValue("serviceName"), time.Now().Add(-window))
+			if err != nil {
+				logger.Error("sla query failed", "error", err)
+				http.Error(w, "sla query failed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(sla)
+		})
+	}
+
+	// Authentication: JWTs validated like the gateway's (JWT_* settings) or
+	// API keys from HEALTHMONITOR_API_KEYS, a JSON object of key to role
+	// ("read" or "admin"), on both the HTTP and gRPC listeners. Liveness and
+	// metrics stay open for probes and scrapers.
+	authCfg, err := authConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("auth config: %w", err)
+	}
+	if !authCfg.Enabled() {
+		if !authCfg.AllowAnonymous {
+			return fmt.Errorf("auth config: set JWT_SECRET_KEY, JWT_JWKS_URL or HEALTHMONITOR_API_KEYS, or HEALTHMONITOR_AUTH_DISABLED=true")
+		}
+		logger.Warn("healthmonitor API authentication disabled")
+	}
+
 	server := &http.Server{
 		Addr:         ":" + port,
-		Handler:      mux,
+		Handler:      auth.APIAuth(authCfg)(mux),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// gRPC API for other mesh components: GetStatus, GetHistory and the
+	// WatchStatus stream.
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryInterceptor(authCfg)),
+		grpc.StreamInterceptor(auth.StreamInterceptor(authCfg)),
+	)
+	pb.RegisterHealthMonitorServer(grpcServer, healthmonitor.NewGRPCServer(cache, logger))
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		return fmt.Errorf("grpc listen: %w", err)
+	}
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.Error("gRPC server failed", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("shutting down gRPC server")
+		grpcServer.GracefulStop()
+	}()
+
 	go func() {
 		<-ctx.Done()
 		logger.Info("shutting down HTTP server")
@@ -103,13 +441,75 @@ func run(logger *slog.Logger) error {
 		server.Shutdown(shutdownCtx)
 	}()
 
-	logger.Info("healthmonitor starting", "port", port, "consul", consulAddr, "probe_interval", cfg.ProbeInterval)
+	logger.Info("healthmonitor starting", "port", port, "grpc_port", grpcPort, "registry", registryCfg.Backend, "consul", consulAddr, "probe_interval", cfg.ProbeInterval)
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		return fmt.Errorf("http server: %w", err)
 	}
 	return nil
 }
 
+// authConfigFromEnv reads the JWT_* settings shared with the gateway and the
+// HEALTHMONITOR_API_KEYS, HEALTHMONITOR_JWT_ROLE_CLAIM and
+// HEALTHMONITOR_AUTH_DISABLED settings. Invalid API key JSON is fatal:
+// ignoring it would leave the API unusable.
+func authConfigFromEnv() (auth.APIConfig, error) {
+	cfg := auth.APIConfig{
+		JWT:            auth.Config{ValidateIssuer: true, ValidateAudience: true},
+		RoleClaim:      os.Getenv("HEALTHMONITOR_JWT_ROLE_CLAIM"),
+		SkipPaths:      []string{"/health", "/metrics"},
+		AllowAnonymous: os.Getenv("HEALTHMONITOR_AUTH_DISABLED") == "true",
+	}
+	cfg.JWT.SecretKey = os.Getenv("JWT_SECRET_KEY")
+	cfg.JWT.Issuer = envOr("JWT_ISSUER", "ToskaMesh.Gateway")
+	cfg.JWT.Audience = envOr("JWT_AUDIENCE", "ToskaMesh.Services")
+	cfg.JWT.JWKSURL = os.Getenv("JWT_JWKS_URL")
+	if v := os.Getenv("HEALTHMONITOR_API_KEYS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &cfg.APIKeys); err != nil {
+			return cfg, fmt.Errorf("invalid HEALTHMONITOR_API_KEYS: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// storeDrivers maps store dialects to the database/sql drivers linked in
+// above: github.com/mattn/go-sqlite3 and github.com/jackc/pgx/v5/stdlib.
+var storeDrivers = map[string]string{
+	healthmonitor.DialectSQLite:   "sqlite3",
+	healthmonitor.DialectPostgres: "pgx",
+}
+
+// openStoreDB opens the database for a store dialect.
+func openStoreDB(dialect, dsn string) (*sql.DB, error) {
+	driver, ok := storeDrivers[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported store %q (want sqlite or postgres)", dialect)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("HEALTHMONITOR_STORE_DSN is required")
+	}
+	return sql.Open(driver, dsn)
+}
+
+// watchdogConfigFromEnv reads HEALTHMONITOR_WATCHDOG_* settings.
+func watchdogConfigFromEnv() watchdog.Config {
+	cfg := watchdog.DefaultConfig()
+	if os.Getenv("HEALTHMONITOR_WATCHDOG_ENABLED") == "true" {
+		cfg.Enabled = true
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_WATCHDOG_MAX_GOROUTINES")); err == nil && v > 0 {
+		cfg.MaxGoroutines = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_WATCHDOG_MAX_HEAP_MB")); err == nil && v > 0 {
+		cfg.MaxHeapBytes = uint64(v) << 20
+	}
+	if v, err := strconv.Atoi(os.Getenv("HEALTHMONITOR_WATCHDOG_MAX_LAG_MS")); err == nil && v > 0 {
+		cfg.MaxLag = time.Duration(v) * time.Millisecond
+	}
+	cfg.DumpDir = os.Getenv("HEALTHMONITOR_WATCHDOG_DUMP_DIR")
+	cfg.AlertWebhookURL = os.Getenv("HEALTHMONITOR_WATCHDOG_ALERT_WEBHOOK_URL")
+	return cfg
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Roles granted by APIAuth. Read-only callers, such as dashboards, may only
+// make GET and HEAD requests.
+const (
+	RoleReadOnly = "read"
+	RoleAdmin    = "admin"
+)
+
+// APIConfig protects an internal API, such as the HealthMonitor's HTTP and
+// gRPC listeners, with JWTs or static API keys. With neither configured,
+// every request is refused unless AllowAnonymous is set.
+type APIConfig struct {
+	JWT Config
+	// APIKeys maps keys, sent in the X-API-Key header, to a role.
+	APIKeys map[string]string
+	// RoleClaim names the JWT claim holding the caller's role. Tokens
+	// without it are read-only.
+	RoleClaim string
+	// SkipPaths are served without authentication (e.g. /health). A path
+	// matches exactly; one ending in "/" also matches everything below it.
+	SkipPaths []string
+	// AllowAnonymous serves unauthenticated requests when neither JWTs nor
+	// API keys are configured. The API is closed unless it is set.
+	AllowAnonymous bool
+}
+
+// Enabled reports whether JWTs or API keys are configured.
+func (c APIConfig) Enabled() bool {
+	return c.JWT.Enabled() || len(c.APIKeys) > 0
+}
+
+// skipped reports whether path is served without authentication.
+func (c APIConfig) skipped(path string) bool {
+	for _, p := range c.SkipPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// APIAuth returns middleware that authenticates requests by API key or JWT
+// bearer token and rejects writes from read-only callers.
+func APIAuth(cfg APIConfig) func(http.Handler) http.Handler {
+	a := newAuthenticator(cfg)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.skipped(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, role, err := a.authenticate(r.Context(), r.Header.Get("X-API-Key"), r.Header.Get("Authorization"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			authorizeRole(w, r.WithContext(ctx), role, next)
+		})
+	}
+}
+
+// UnaryInterceptor authenticates unary RPCs like APIAuth, reading the API
+// key from "x-api-key" metadata and the token from "authorization". Every
+// known role may call the RPCs, so they must be read-only.
+func UnaryInterceptor(cfg APIConfig) grpc.UnaryServerInterceptor {
+	a := newAuthenticator(cfg)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticateGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authenticates streaming RPCs like UnaryInterceptor.
+func StreamInterceptor(cfg APIConfig) grpc.StreamServerInterceptor {
+	a := newAuthenticator(cfg)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateGRPC(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// AdminAuth returns middleware that admits only callers presenting a JWT
+// whose role claim (default "role") is RoleAdmin, whatever the method. It
+// reports false when no JWT key is configured; admin endpoints must then not
+// be mounted, since they would be open to anyone.
+func AdminAuth(jwt Config, roleClaim string) (func(http.Handler) http.Handler, bool) {
+	if !jwt.Enabled() {
+		return nil, false
+	}
+	a := newAuthenticator(APIConfig{JWT: jwt, RoleClaim: roleClaim})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, role, err := a.authenticate(r.Context(), "", r.Header.Get("Authorization"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if role != RoleAdmin {
+				http.Error(w, "admin role required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}, true
+}
+
+// authenticator resolves a caller's role from an API key or bearer token.
+type authenticator struct {
+	cfg       APIConfig
+	roleClaim string
+	keys      *Keys
+}
+
+func newAuthenticator(cfg APIConfig) *authenticator {
+	roleClaim := cfg.RoleClaim
+	if roleClaim == "" {
+		roleClaim = "role"
+	}
+	return &authenticator{
+		cfg:       cfg,
+		roleClaim: roleClaim,
+		keys:      NewKeys(&http.Client{Timeout: 5 * time.Second}),
+	}
+}
+
+// authenticate returns the caller's role and ctx carrying its token claims.
+// An API key, when presented, takes precedence over a token.
+func (a *authenticator) authenticate(ctx context.Context, apiKey, authorization string) (context.Context, string, error) {
+	if apiKey != "" {
+		role, ok := lookupAPIKey(a.cfg.APIKeys, apiKey)
+		if !ok {
+			return ctx, "", errInvalidAPIKey
+		}
+		return ctx, role, nil
+	}
+
+	switch {
+	case a.cfg.JWT.Enabled():
+		token, ok := BearerToken(authorization)
+		if !ok {
+			return ctx, "", errMissingBearer
+		}
+		claims, err := Verify(ctx, token, a.cfg.JWT, a.keys)
+		if err != nil {
+			return ctx, "", fmt.Errorf("invalid token: %w", err)
+		}
+		role := claims.String(a.roleClaim)
+		if role == "" {
+			role = RoleReadOnly
+		}
+		return WithClaims(ctx, claims), role, nil
+	case len(a.cfg.APIKeys) > 0:
+		return ctx, "", errMissingAPIKey
+	case a.cfg.AllowAnonymous:
+		return ctx, RoleAdmin, nil
+	default:
+		return ctx, "", errNotConfigured
+	}
+}
+
+func (a *authenticator) authenticateGRPC(ctx context.Context) (context.Context, error) {
+	var apiKey, authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-api-key"); len(v) > 0 {
+			apiKey = v[0]
+		}
+		if v := md.Get("authorization"); len(v) > 0 {
+			authorization = v[0]
+		}
+	}
+	ctx, role, err := a.authenticate(ctx, apiKey, authorization)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if role != RoleAdmin && role != RoleReadOnly {
+		return ctx, status.Error(codes.PermissionDenied, "unknown role")
+	}
+	return ctx, nil
+}
+
+// contextStream replaces a stream's context, so interceptors can pass the
+// caller's claims to stream handlers.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+// lookupAPIKey finds a key's role, comparing keys in constant time.
+func lookupAPIKey(keys map[string]string, key string) (string, bool) {
+	for k, role := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// authorizeRole serves the request if the role permits its method.
+func authorizeRole(w http.ResponseWriter, r *http.Request, role string, next http.Handler) {
+	switch role {
+	case RoleAdmin:
+	case RoleReadOnly:
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "read-only role", http.StatusForbidden)
+			return
+		}
+	default:
+		http.Error(w, "unknown role", http.StatusForbidden)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+const (
+	errInvalidAPIKey = authError("invalid API key")
+	errMissingAPIKey = authError("missing API key")
+	errMissingBearer = authError("missing or invalid authorization header")
+	errNotConfigured = authError("authentication is not configured")
+)
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIAuth(t *testing.T) {
+	jwt := Config{SecretKey: "test-secret-key-that-is-long-enough", Issuer: "mesh", Audience: "mesh", ValidateIssuer: true, ValidateAudience: true}
+	adminToken, err := Sign(jwt, "operator", time.Hour, map[string]any{"role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainToken, err := Sign(jwt, "dashboard", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := APIConfig{
+		JWT:       jwt,
+		APIKeys:   map[string]string{"dash-key": RoleReadOnly, "ops-key": RoleAdmin},
+		SkipPaths: []string{"/health", "/static/"},
+	}
+	tests := []struct {
+		name   string
+		cfg    APIConfig
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"skipped path", cfg, http.MethodGet, "/health", "", "", http.StatusOK},
+		{"skip path is exact", cfg, http.MethodGet, "/healthz", "", "", http.StatusUnauthorized},
+		{"skip path is not a prefix", cfg, http.MethodGet, "/health/../api/status", "", "", http.StatusUnauthorized},
+		{"skipped subtree", cfg, http.MethodGet, "/static/app.js", "", "", http.StatusOK},
+		{"no credentials", cfg, http.MethodGet, "/api/status", "", "", http.StatusUnauthorized},
+		{"read key reads", cfg, http.MethodGet, "/api/status", "X-API-Key", "dash-key", http.StatusOK},
+		{"read key cannot write", cfg, http.MethodPost, "/api/status", "X-API-Key", "dash-key", http.StatusForbidden},
+		{"admin key writes", cfg, http.MethodPost, "/api/status", "X-API-Key", "ops-key", http.StatusOK},
+		{"wrong key", cfg, http.MethodGet, "/api/status", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"admin token writes", cfg, http.MethodPost, "/api/status", "Authorization", "Bearer " + adminToken, http.StatusOK},
+		{"token without role reads", cfg, http.MethodGet, "/api/status", "Authorization", "Bearer " + plainToken, http.StatusOK},
+		{"token without role cannot write", cfg, http.MethodPost, "/api/status", "Authorization", "Bearer " + plainToken, http.StatusForbidden},
+		{"bad token", cfg, http.MethodGet, "/api/status", "Authorization", "Bearer x.y.z", http.StatusUnauthorized},
+		{"keys only", APIConfig{APIKeys: cfg.APIKeys}, http.MethodGet, "/api/status", "", "", http.StatusUnauthorized},
+		{"closed by default", APIConfig{}, http.MethodGet, "/api/status", "", "", http.StatusUnauthorized},
+		{"anonymous allowed", APIConfig{AllowAnonymous: true}, http.MethodPost, "/api/status", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := APIAuth(tt.cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d (%s)", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	if _, ok := AdminAuth(Config{}, ""); ok {
+		t.Fatal("expected admin auth to be unavailable without a JWT key")
+	}
+
+	jwt := Config{SecretKey: "test-secret-key-that-is-long-enough"}
+	adminToken, _ := Sign(jwt, "operator", time.Hour, map[string]any{"role": "admin"})
+	readToken, _ := Sign(jwt, "dashboard", time.Hour, map[string]any{"role": "read"})
+	adminOnly, ok := AdminAuth(jwt, "")
+	if !ok {
+		t.Fatal("expected admin auth with a JWT key")
+	}
+	h := adminOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		want   int
+	}{
+		{"no token", http.MethodGet, "", http.StatusUnauthorized},
+		{"read role cannot read", http.MethodGet, readToken, http.StatusForbidden},
+		{"read role cannot write", http.MethodPut, readToken, http.StatusForbidden},
+		{"admin writes", http.MethodPut, adminToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/maintenance/orders", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	jwt := Config{SecretKey: "test-secret-key-that-is-long-enough"}
+	token, _ := Sign(jwt, "dashboard", time.Hour, nil)
+	cfg := APIConfig{JWT: jwt, APIKeys: map[string]string{"dash-key": RoleReadOnly, "odd-key": "owner"}}
+
+	tests := []struct {
+		name string
+		cfg  APIConfig
+		md   metadata.MD
+		want codes.Code
+	}{
+		{"no credentials", cfg, nil, codes.Unauthenticated},
+		{"token", cfg, metadata.Pairs("authorization", "Bearer "+token), codes.OK},
+		{"bad token", cfg, metadata.Pairs("authorization", "Bearer x.y.z"), codes.Unauthenticated},
+		{"api key", cfg, metadata.Pairs("x-api-key", "dash-key"), codes.OK},
+		{"unknown role", cfg, metadata.Pairs("x-api-key", "odd-key"), codes.PermissionDenied},
+		{"closed by default", APIConfig{}, nil, codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			unary := UnaryInterceptor(tt.cfg)
+			_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/healthmonitor.HealthMonitor/GetStatus"},
+				func(ctx context.Context, req any) (any, error) { return nil, nil })
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("unary: expected %v, got %v", tt.want, got)
+			}
+
+			stream := StreamInterceptor(tt.cfg)
+			err = stream(nil, &testStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/healthmonitor.HealthMonitor/WatchStatus"},
+				func(srv any, ss grpc.ServerStream) error { return nil })
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("stream: expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamInterceptor_PassesClaims(t *testing.T) {
+	jwt := Config{SecretKey: "test-secret-key-that-is-long-enough"}
+	token, _ := Sign(jwt, "dashboard", time.Hour, nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	var sub string
+	err := StreamInterceptor(APIConfig{JWT: jwt})(nil, &testStream{ctx: ctx}, &grpc.StreamServerInfo{},
+		func(srv any, ss grpc.ServerStream) error {
+			sub = ClaimsFromContext(ss.Context()).String("sub")
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub != "dashboard" {
+		t.Fatalf("expected claims on the stream context, got sub %q", sub)
+	}
+}
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksTTL is how long fetched signing keys are trusted before refetching.
+	jwksTTL = 10 * time.Minute
+	// jwksMinRefresh bounds refetches triggered by unknown key IDs, so tokens
+	// with bogus kids cannot be used to hammer the identity provider.
+	jwksMinRefresh = 30 * time.Second
+)
+
+// Keys resolves RSA signing keys from JSON Web Key Set URLs, caching each
+// set per URL.
+type Keys struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	sets  map[string]*jwksSet
+	group singleflight.Group
+}
+
+type jwksSet struct {
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewKeys creates a key cache that fetches sets with client.
+func NewKeys(client *http.Client) *Keys {
+	return &Keys{client: client, sets: make(map[string]*jwksSet)}
+}
+
+// key returns the public key with the given key ID from the set at url,
+// fetching the set if it is stale or does not contain kid. Fetches happen
+// outside the set's lock, and concurrent fetches of one URL are collapsed
+// into a single request.
+func (j *Keys) key(ctx context.Context, url, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	set, ok := j.sets[url]
+	if !ok {
+		set = &jwksSet{}
+		j.sets[url] = set
+	}
+	j.mu.Unlock()
+
+	k, known, age := set.lookup(kid)
+	if k != nil && age < jwksTTL {
+		return k, nil
+	}
+	if !known || age >= jwksMinRefresh {
+		// The fetch is shared by every waiting caller, so it must not be
+		// cancelled by whichever of them started it.
+		_, err, _ := j.group.Do(url, func() (any, error) {
+			keys, err := j.fetch(context.WithoutCancel(ctx), url)
+			if err != nil {
+				return nil, err
+			}
+			set.mu.Lock()
+			set.keys = keys
+			set.fetchedAt = time.Now()
+			set.mu.Unlock()
+			return nil, nil
+		})
+		if err != nil {
+			// Keep serving known keys if the provider is briefly unavailable.
+			if k != nil {
+				return k, nil
+			}
+			return nil, err
+		}
+		k, _, _ = set.lookup(kid)
+	}
+
+	if k != nil {
+		return k, nil
+	}
+	return nil, errUnknownKey
+}
+
+// lookup returns the key with the given ID, whether the set has been
+// fetched, and the age of the set.
+func (s *jwksSet) lookup(kid string) (*rsa.PublicKey, bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[kid], s.keys != nil, time.Since(s.fetchedAt)
+}
+
+func (j *Keys) fetch(ctx context.Context, url string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build jwks request: %w", err)
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: HTTP %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,267 @@
+// Package auth validates the JWT bearer tokens and API keys that protect the
+// mesh's HTTP and gRPC APIs. The gateway, the HealthMonitor and the admin
+// endpoints share it so that a token accepted by one is accepted by all.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Config controls JWT bearer token validation. Tokens are HS256-signed with
+// SecretKey unless JWKSURL is set, in which case they must be RS256-signed by a
+// key from that JSON Web Key Set.
+type Config struct {
+	SecretKey        string
+	Issuer           string
+	Audience         string
+	JWKSURL          string
+	ValidateIssuer   bool
+	ValidateAudience bool
+
+	// Services holds per-service overrides keyed by lowercase service name, for
+	// backends that trust a different identity provider.
+	Services map[string]Override
+}
+
+// Override replaces the issuer, audience, or key set for one service.
+// Empty fields keep the global value.
+type Override struct {
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	JWKSURL  string `json:"jwksUrl,omitempty"`
+}
+
+// Enabled reports whether a signing secret or key set is configured.
+func (c Config) Enabled() bool {
+	return c.SecretKey != "" || c.JWKSURL != ""
+}
+
+// ForService returns the validation settings for a service.
+func (c Config) ForService(serviceName string) Config {
+	o, ok := c.Services[strings.ToLower(serviceName)]
+	if !ok {
+		return c
+	}
+	if o.Issuer != "" {
+		c.Issuer = o.Issuer
+	}
+	if o.Audience != "" {
+		c.Audience = o.Audience
+	}
+	if o.JWKSURL != "" {
+		c.JWKSURL = o.JWKSURL
+	}
+	return c
+}
+
+// Claims holds the decoded payload of a validated JWT.
+type Claims map[string]any
+
+// String returns a string claim, or "" if absent or not a string.
+func (c Claims) String(name string) string {
+	v, _ := c[name].(string)
+	return v
+}
+
+type claimsKey struct{}
+
+// WithClaims returns ctx carrying the claims of a validated token.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token validated for the
+// request, or nil if the request was not authenticated by a token.
+func ClaimsFromContext(ctx context.Context) Claims {
+	c, _ := ctx.Value(claimsKey{}).(Claims)
+	return c
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) (string, bool) {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	return token, ok && token != ""
+}
+
+// Verify checks the token signature and standard claims. When cfg.JWKSURL
+// is set, tokens must be RS256-signed by a key from that set, resolved
+// through keys; otherwise they must be HS256-signed with cfg.SecretKey.
+func Verify(ctx context.Context, tokenStr string, cfg Config, keys *Keys) (Claims, error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+	signingInput := parts[0] + "." + parts[1]
+
+	if cfg.JWKSURL != "" {
+		if err := verifyRS256(ctx, parts[0], signingInput, parts[2], cfg.JWKSURL, keys); err != nil {
+			return nil, err
+		}
+	} else {
+		// Verify signature (HS256).
+		mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+		mac.Write([]byte(signingInput))
+		expectedSig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+		if !hmac.Equal([]byte(expectedSig), []byte(parts[2])) {
+			return nil, errInvalidSignature
+		}
+	}
+
+	// Decode payload.
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	var claims struct {
+		Exp int64    `json:"exp"`
+		Iss string   `json:"iss"`
+		Aud audience `json:"aud"`
+	}
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, errInvalidToken
+	}
+
+	// Check expiration.
+	if claims.Exp > 0 && time.Now().Unix() > claims.Exp {
+		return nil, errTokenExpired
+	}
+
+	// Check issuer.
+	if cfg.ValidateIssuer && cfg.Issuer != "" && claims.Iss != cfg.Issuer {
+		return nil, errInvalidIssuer
+	}
+
+	// Check audience.
+	if cfg.ValidateAudience && cfg.Audience != "" && !claims.Aud.contains(cfg.Audience) {
+		return nil, errInvalidAudience
+	}
+
+	var all Claims
+	if err := json.Unmarshal(payloadJSON, &all); err != nil {
+		return nil, errInvalidToken
+	}
+	return all, nil
+}
+
+// verifyRS256 checks an RS256 signature against the key named by the token's
+// kid header in the JWKS at url.
+func verifyRS256(ctx context.Context, headerB64, signingInput, sigB64, url string, keys *Keys) error {
+	if keys == nil {
+		return errUnknownKey
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(headerB64)
+	if err != nil {
+		return errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return errInvalidToken
+	}
+	if header.Alg != "RS256" {
+		return errUnsupportedAlg
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil {
+		return errInvalidToken
+	}
+	pub, err := keys.key(ctx, url, header.Kid)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// audience is the aud claim, which may be a single string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(want string) bool {
+	for _, v := range a {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign mints an HS256 token signed with cfg.SecretKey and carrying the
+// configured issuer and audience. Extra claims are merged over the defaults.
+func Sign(cfg Config, subject string, ttl time.Duration, extra map[string]any) (string, error) {
+	if cfg.SecretKey == "" {
+		return "", errMissingSecret
+	}
+
+	now := time.Now().UTC()
+	claims := map[string]any{
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	if cfg.Issuer != "" {
+		claims["iss"] = cfg.Issuer
+	}
+	if cfg.Audience != "" {
+		claims["aud"] = cfg.Audience
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+
+	payloadJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+type authError string
+
+func (e authError) Error() string { return string(e) }
+
+const (
+	errInvalidToken     = authError("invalid token format")
+	errInvalidSignature = authError("invalid signature")
+	errTokenExpired     = authError("token expired")
+	errInvalidIssuer    = authError("invalid issuer")
+	errInvalidAudience  = authError("invalid audience")
+	errMissingSecret    = authError("no signing secret configured")
+	errUnsupportedAlg   = authError("unsupported signing algorithm")
+	errUnknownKey       = authError("unknown signing key")
+)
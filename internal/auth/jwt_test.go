@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSign_RoundTripsThroughVerify(t *testing.T) {
+	cfg := Config{
+		SecretKey:        "test-secret-key-at-least-32-characters",
+		Issuer:           "test-issuer",
+		Audience:         "test-audience",
+		ValidateIssuer:   true,
+		ValidateAudience: true,
+	}
+
+	token, err := Sign(cfg, "operator", time.Hour, map[string]any{"role": "admin"})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	claims, err := Verify(context.Background(), token, cfg, nil)
+	if err != nil {
+		t.Fatalf("expected generated token to verify, got %v", err)
+	}
+	if claims.String("role") != "admin" {
+		t.Fatalf("expected role claim, got %v", claims)
+	}
+
+	if _, err := Sign(Config{}, "operator", time.Hour, nil); err == nil {
+		t.Fatal("expected error without a secret")
+	}
+}
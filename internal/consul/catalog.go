@@ -0,0 +1,100 @@
+package consul
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// lookupService finds a service instance by ID in the catalog, whichever node
+// it is registered on, returning its node, definition and checks, or nil if
+// no node has it.
+func (r *Registry) lookupService(serviceID string) (*api.ServiceEntry, error) {
+	services, _, err := r.client.Catalog().Services(&api.QueryOptions{
+		Filter: fmt.Sprintf("ServiceID == %q", serviceID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name := range services {
+		entries, _, err := r.client.Health().Service(name, "", false, &api.QueryOptions{
+			Filter: fmt.Sprintf("Service.ID == %q", serviceID),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			return entries[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// agentFor returns the agent of node. A service belongs to the agent it was
+// registered with, whose anti-entropy sync undoes changes written to the
+// catalog directly, so changes to it must go through that agent.
+func (r *Registry) agentFor(node *api.Node) (*api.Agent, error) {
+	local, err := r.localNode()
+	if err != nil {
+		return nil, err
+	}
+	if node == nil || node.Node == local {
+		return r.client.Agent(), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.agents[node.Node]; ok {
+		return c.Agent(), nil
+	}
+	// Agents are assumed to listen like the local one.
+	cfg := api.DefaultConfig()
+	scheme, host := r.address()
+	if scheme != "" {
+		cfg.Scheme = scheme
+	}
+	_, port, err := net.SplitHostPort(host)
+	if err != nil {
+		port = "8500"
+	}
+	cfg.Address = net.JoinHostPort(node.Address, port)
+	c, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("consul client for node %s: %w", node.Node, err)
+	}
+	r.agents[node.Node] = c
+	return c.Agent(), nil
+}
+
+// localNode returns the name of the local agent's node.
+func (r *Registry) localNode() (string, error) {
+	r.mu.RLock()
+	name := r.nodeName
+	r.mu.RUnlock()
+	if name != "" {
+		return name, nil
+	}
+
+	name, err := r.client.Agent().NodeName()
+	if err != nil {
+		return "", fmt.Errorf("consul local node: %w", err)
+	}
+	r.mu.Lock()
+	r.nodeName = name
+	r.mu.Unlock()
+	return name, nil
+}
+
+// address returns the scheme, if any, and host:port of the configured agent.
+func (r *Registry) address() (scheme, host string) {
+	addr := r.config.Address
+	if addr == "" {
+		addr = api.DefaultConfig().Address
+	}
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		return u.Scheme, u.Host
+	}
+	return "", addr
+}
@@ -0,0 +1,130 @@
+package consul
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// GetInstancesAcrossDatacenters returns the instances of a service in each
+// configured datacenter, labelling each with its datacenter under the
+// types.MetadataDatacenter key. Datacenters that cannot be queried are
+// logged and skipped; an error is returned only if every query fails.
+func (r *Registry) GetInstancesAcrossDatacenters(serviceName string) ([]Instance, error) {
+	datacenters := r.datacenters()
+
+	var instances []Instance
+	var errs []error
+	for _, dc := range datacenters {
+		entries, err := read(r, &api.QueryOptions{Datacenter: dc}, func(opts *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+			return r.client.Health().Service(serviceName, "", false, opts)
+		})
+		if err != nil {
+			r.logger.Warn("failed to get instances from datacenter", "service", serviceName, "datacenter", dc, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", dc, err))
+			continue
+		}
+		instances = append(instances, r.toLabelledInstances(entries, dc)...)
+	}
+	if len(errs) == len(datacenters) {
+		return nil, fmt.Errorf("consul get instances across datacenters: %w", errors.Join(errs...))
+	}
+	return instances, nil
+}
+
+// WatchInstancesAcrossDatacenters streams the instances of a service in each
+// configured datacenter, labelled like GetInstancesAcrossDatacenters, with
+// one WatchInstances-style blocking query per datacenter. The merged list is
+// sent once every datacenter has answered, then again whenever any of them
+// changes. The channel is closed when ctx is cancelled.
+func (r *Registry) WatchInstancesAcrossDatacenters(ctx context.Context, serviceName string, wait time.Duration) <-chan []Instance {
+	datacenters := r.datacenters()
+
+	type update struct {
+		dc        int
+		instances []Instance
+	}
+	updates := make(chan update)
+	var wg sync.WaitGroup
+	for i, dc := range datacenters {
+		what := "instances of " + serviceName + " in " + cmp.Or(dc, "local datacenter")
+		results := watch(ctx, r, what, wait, func(opts *api.QueryOptions) ([]Instance, uint64, error) {
+			opts.Datacenter = dc
+			entries, meta, err := r.client.Health().Service(serviceName, "", false, opts)
+			if err != nil {
+				return nil, 0, fmt.Errorf("consul watch instances: %w", err)
+			}
+			return r.toLabelledInstances(entries, dc), meta.LastIndex, nil
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for instances := range results {
+				select {
+				case updates <- update{i, instances}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(updates)
+	}()
+
+	out := make(chan []Instance)
+	go func() {
+		defer close(out)
+		latest := make([][]Instance, len(datacenters))
+		answered := make([]bool, len(datacenters))
+		for u := range updates {
+			latest[u.dc], answered[u.dc] = u.instances, true
+			if slices.Contains(answered, false) {
+				continue
+			}
+			select {
+			case out <- slices.Concat(latest...):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Datacenters returns the datacenters queried across, or nil when only the
+// local one is.
+func (r *Registry) Datacenters() []string {
+	return r.config.Datacenters
+}
+
+func (r *Registry) datacenters() []string {
+	if len(r.config.Datacenters) == 0 {
+		return []string{""} // the agent's own datacenter
+	}
+	return r.config.Datacenters
+}
+
+// toLabelledInstances converts entries queried from dc, labelling each with
+// its node's datacenter, or dc when the node does not say.
+func (r *Registry) toLabelledInstances(entries []*api.ServiceEntry, dc string) []Instance {
+	instances := r.toInstances(entries)
+	for i, inst := range instances {
+		var nodeDC string
+		if entries[i].Node != nil {
+			nodeDC = entries[i].Node.Datacenter
+		}
+		if label := cmp.Or(nodeDC, dc); label != "" {
+			inst.Metadata[types.MetadataDatacenter] = label
+		}
+	}
+	return instances
+}
@@ -0,0 +1,94 @@
+package consul
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+func TestGetInstancesAcrossDatacenters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("dc") {
+		case "eu-west":
+			w.Write([]byte(`[{"Node":{"Datacenter":"eu-west"},"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"passing"}]}]`))
+		case "us-east":
+			w.Write([]byte(`[{"Service":{"ID":"api-2","Service":"api"},"Checks":[{"Status":"critical"}]}]`))
+		default:
+			http.Error(w, "No path to datacenter", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	reg, err := NewRegistryWithConfig(Config{Address: srv.URL, Datacenters: []string{"eu-west", "us-east", "ap-south"}}, logger)
+	if err != nil {
+		t.Fatalf("NewRegistryWithConfig: %v", err)
+	}
+	instances, err := reg.GetInstancesAcrossDatacenters("api")
+	if err != nil {
+		t.Fatalf("GetInstancesAcrossDatacenters: %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expected instances from the reachable datacenters, got %+v", instances)
+	}
+	for i, want := range []string{"eu-west", "us-east"} {
+		if got := instances[i].Metadata[types.MetadataDatacenter]; got != want {
+			t.Errorf("%s labelled %q, want %q", instances[i].ServiceID, got, want)
+		}
+	}
+	if instances[1].Status != HealthUnhealthy {
+		t.Errorf("expected remote health to be kept, got %v", instances[1].Status)
+	}
+
+	unreachable, err := NewRegistryWithConfig(Config{Address: srv.URL, Datacenters: []string{"ap-south"}}, logger)
+	if err != nil {
+		t.Fatalf("NewRegistryWithConfig: %v", err)
+	}
+	if _, err := unreachable.GetInstancesAcrossDatacenters("api"); err == nil {
+		t.Fatal("expected an error when no datacenter can be queried")
+	}
+}
+
+func TestWatchInstancesAcrossDatacenters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("index") != "" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", "7")
+		switch r.URL.Query().Get("dc") {
+		case "eu-west":
+			w.Write([]byte(`[{"Node":{"Datacenter":"eu-west"},"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"passing"}]}]`))
+		case "us-east":
+			w.Write([]byte(`[{"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"passing"}]}]`))
+		}
+	}))
+	defer srv.Close()
+
+	reg, err := NewRegistryWithConfig(Config{Address: srv.URL, Datacenters: []string{"eu-west", "us-east"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistryWithConfig: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	select {
+	case instances := <-reg.WatchInstancesAcrossDatacenters(ctx, "api", time.Minute):
+		if len(instances) != 2 {
+			t.Fatalf("expected an instance from each datacenter, got %+v", instances)
+		}
+		for i, want := range []string{"eu-west", "us-east"} {
+			if got := instances[i].Metadata[types.MetadataDatacenter]; got != want {
+				t.Errorf("instance %d labelled %q, want %q", i, got, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the merged instances")
+	}
+}
@@ -0,0 +1,118 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// ElectionConfig configures leader election.
+type ElectionConfig struct {
+	// Key is the KV key every candidate contends for.
+	Key string
+	// Value identifies this candidate in the key, e.g. its hostname.
+	Value string
+	// SessionTTL bounds how long a crashed leader keeps the lock.
+	SessionTTL time.Duration
+	// LockDelay stops a new leader acquiring the lock for this long after
+	// a session is invalidated, so the old leader can notice first.
+	LockDelay time.Duration
+	// RetryInterval is the pause after a failed attempt to contend.
+	RetryInterval time.Duration
+}
+
+// DefaultElectionConfig returns the election defaults for discovery.
+func DefaultElectionConfig() ElectionConfig {
+	return ElectionConfig{
+		Key:           "toska-mesh/discovery/leader",
+		SessionTTL:    15 * time.Second,
+		LockDelay:     5 * time.Second,
+		RetryInterval: 5 * time.Second,
+	}
+}
+
+// Election elects one leader among replicas using a Consul session lock. The
+// lock is held for as long as the session is renewed, so a leader that dies
+// is replaced within SessionTTL plus LockDelay.
+type Election struct {
+	client *api.Client
+	config ElectionConfig
+	logger *slog.Logger
+	leader atomic.Bool
+}
+
+// NewElection creates an Election using the provided Consul address. Call
+// Run to start contending.
+func NewElection(addr string, config ElectionConfig, logger *slog.Logger) (*Election, error) {
+	cfg := api.DefaultConfig()
+	if addr != "" {
+		cfg.Address = addr
+	}
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("consul client: %w", err)
+	}
+	return &Election{client: client, config: config, logger: logger}, nil
+}
+
+// IsLeader reports whether this replica currently holds the lock.
+func (e *Election) IsLeader() bool { return e.leader.Load() }
+
+// Run contends for leadership until ctx is cancelled, releasing the lock on
+// the way out so another replica can take over without waiting for the TTL.
+func (e *Election) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		lock, err := e.client.LockOpts(&api.LockOptions{
+			Key:            e.config.Key,
+			Value:          []byte(e.config.Value),
+			SessionName:    "toska-mesh leader: " + e.config.Key,
+			SessionTTL:     e.config.SessionTTL.String(),
+			LockDelay:      e.config.LockDelay,
+			MonitorRetries: 3,
+		})
+		if err != nil {
+			e.logger.Error("invalid leader lock options", "error", err)
+			return
+		}
+
+		lost, err := lock.Lock(ctx.Done())
+		if err != nil {
+			e.logger.Warn("leader election failed", "key", e.config.Key, "error", err, "retry_in", e.config.RetryInterval)
+			if !sleepContext(ctx, e.config.RetryInterval) {
+				return
+			}
+			continue
+		}
+		if lost == nil {
+			return // cancelled while waiting
+		}
+
+		e.leader.Store(true)
+		e.logger.Info("acquired leadership", "key", e.config.Key)
+		select {
+		case <-lost:
+			e.logger.Warn("lost leadership", "key", e.config.Key)
+		case <-ctx.Done():
+		}
+		e.leader.Store(false)
+		// Unlock also stops renewing and destroys the session.
+		if err := lock.Unlock(); err != nil {
+			e.logger.Debug("failed to release leader lock", "key", e.config.Key, "error", err)
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
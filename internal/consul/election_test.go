@@ -0,0 +1,193 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLockServer implements the Consul session and KV endpoints used by
+// api.Lock: session create/renew/destroy, blocking KV reads, and
+// acquire/release writes.
+type fakeLockServer struct {
+	mu       sync.Mutex
+	index    uint64
+	changed  chan struct{}
+	sessions int
+	pair     *lockPair
+}
+
+// lockPair mirrors the JSON of api.KVPair for the single lock key.
+type lockPair struct {
+	Key         string
+	Value       []byte
+	Flags       uint64
+	Session     string
+	ModifyIndex uint64
+}
+
+func newFakeLockServer() *fakeLockServer {
+	return &fakeLockServer{index: 1, changed: make(chan struct{})}
+}
+
+func (f *fakeLockServer) bumpLocked() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeLockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		f.mu.Lock()
+		f.sessions++
+		id := fmt.Sprintf("session-%d", f.sessions)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		json.NewEncoder(w).Encode([]map[string]string{{"ID": strings.TrimPrefix(r.URL.Path, "/v1/session/renew/"), "TTL": "15s"}})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/")
+		f.mu.Lock()
+		if f.pair != nil && f.pair.Session == id {
+			f.pair.Session = ""
+			f.bumpLocked()
+		}
+		f.mu.Unlock()
+		io.WriteString(w, "true")
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/") && r.Method == http.MethodGet:
+		f.get(w, r)
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/") && r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		ok := false
+		switch {
+		case q.Has("acquire"):
+			if f.pair == nil || f.pair.Session == "" {
+				flags, _ := strconv.ParseUint(q.Get("flags"), 10, 64)
+				f.pair = &lockPair{Key: strings.TrimPrefix(r.URL.Path, "/v1/kv/"), Value: body, Flags: flags, Session: q.Get("acquire")}
+				ok = true
+			}
+		case q.Has("release"):
+			if f.pair != nil && f.pair.Session == q.Get("release") {
+				f.pair.Session = ""
+				ok = true
+			}
+		}
+		if ok {
+			f.bumpLocked()
+			f.pair.ModifyIndex = f.index
+		}
+		json.NewEncoder(w).Encode(ok)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// get serves a KV read, blocking while the index is unchanged.
+func (f *fakeLockServer) get(w http.ResponseWriter, r *http.Request) {
+	wait := 5 * time.Second
+	if d, err := time.ParseDuration(r.URL.Query().Get("wait")); err == nil {
+		wait = d
+	}
+	want, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	timeout := time.After(wait)
+	for {
+		f.mu.Lock()
+		index, changed := f.index, f.changed
+		var pair lockPair
+		found := f.pair != nil
+		if found {
+			pair = *f.pair
+		}
+		f.mu.Unlock()
+
+		if want == 0 || index > want {
+			w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode([]lockPair{pair})
+			return
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			want = 0
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func newTestElection(t *testing.T, addr, name string) *Election {
+	t.Helper()
+	cfg := DefaultElectionConfig()
+	cfg.Value = name
+	cfg.RetryInterval = 50 * time.Millisecond
+	e, err := NewElection(addr, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestElection_FailsOverWhenLeaderStops(t *testing.T) {
+	fake := newFakeLockServer()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	defer srv.CloseClientConnections() // end blocking reads still in flight
+
+	first := newTestElection(t, srv.URL, "replica-a")
+	second := newTestElection(t, srv.URL, "replica-b")
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { first.Run(ctxA); close(doneA) }()
+	waitFor(t, "first replica to lead", first.IsLeader)
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	defer cancelB()
+	go second.Run(ctxB)
+	time.Sleep(100 * time.Millisecond)
+	if second.IsLeader() {
+		t.Fatal("expected only one leader")
+	}
+
+	fake.mu.Lock()
+	holder := string(fake.pair.Value)
+	fake.mu.Unlock()
+	if holder != "replica-a" {
+		t.Fatalf("expected lock value replica-a, got %q", holder)
+	}
+
+	cancelA()
+	<-doneA
+	if first.IsLeader() {
+		t.Fatal("expected stopped replica to give up leadership")
+	}
+	waitFor(t, "second replica to take over", second.IsLeader)
+}
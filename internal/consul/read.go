@@ -0,0 +1,29 @@
+package consul
+
+import "github.com/hashicorp/consul/api"
+
+// read runs a catalog query with the consistency set by Config.Reads. An
+// answer older than MaxStale is discarded and the query repeated against
+// the leader.
+func read[T any](r *Registry, opts *api.QueryOptions, query func(*api.QueryOptions) (T, *api.QueryMeta, error)) (T, error) {
+	reads := r.config.Reads
+	if !reads.AllowStale && !reads.UseCache {
+		result, _, err := query(opts)
+		return result, err
+	}
+
+	relaxed := *opts
+	relaxed.AllowStale = reads.AllowStale
+	if reads.UseCache {
+		relaxed.UseCache = true
+		relaxed.MaxAge = reads.MaxStale
+	}
+	result, meta, err := query(&relaxed)
+	if err != nil || reads.MaxStale <= 0 || meta.LastContact <= reads.MaxStale {
+		return result, err
+	}
+
+	r.logger.Debug("consul answer too stale, reading from the leader", "last_contact", meta.LastContact, "max_stale", reads.MaxStale)
+	result, _, err = query(opts)
+	return result, err
+}
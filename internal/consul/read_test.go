@@ -0,0 +1,51 @@
+package consul
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetInstances_ReadConsistency(t *testing.T) {
+	tests := []struct {
+		name        string
+		reads       ReadConfig
+		lastContact string
+		want        []string // query strings and Cache-Control headers sent, in order
+	}{
+		{"leader by default", ReadConfig{}, "0", []string{" "}},
+		{"stale", ReadConfig{AllowStale: true}, "60000", []string{"stale= "}},
+		{"cached with max age", ReadConfig{UseCache: true, MaxStale: 5 * time.Second}, "0", []string{"cached= max-age=5"}},
+		{"stale within bound", ReadConfig{AllowStale: true, MaxStale: 5 * time.Second}, "4000", []string{"stale= "}},
+		{"too stale rereads from leader", ReadConfig{AllowStale: true, MaxStale: 5 * time.Second}, "6000", []string{"stale= ", " "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+				q.Del("passing")
+				queries = append(queries, q.Encode()+" "+r.Header.Get("Cache-Control"))
+				w.Header().Set("X-Consul-LastContact", tt.lastContact)
+				w.Write([]byte(`[{"Service":{"ID":"api-1","Service":"api"}}]`))
+			}))
+			defer srv.Close()
+
+			reg, err := NewRegistryWithConfig(Config{Address: srv.URL, Reads: tt.reads}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatalf("NewRegistryWithConfig: %v", err)
+			}
+			instances, err := reg.GetInstances("api")
+			if err != nil || len(instances) != 1 {
+				t.Fatalf("GetInstances = %v, %v", instances, err)
+			}
+			if !slices.Equal(queries, tt.want) {
+				t.Fatalf("expected queries %q, got %q", tt.want, queries)
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@
 package consul
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
 	"time"
 
@@ -23,53 +26,68 @@ const (
 	HealthDegraded  = types.HealthDegraded
 )
 
-// Instance represents a service instance stored in Consul.
-type Instance struct {
-	ServiceName    string
-	ServiceID      string
-	Address        string
-	Port           int
-	Status         HealthStatus
-	Metadata       map[string]string
-	RegisteredAt   time.Time
-	LastHealthCheck time.Time
-}
-
-// Registration contains the information needed to register a service.
-type Registration struct {
-	ServiceName string
-	ServiceID   string
-	Address     string
-	Port        int
-	Metadata    map[string]string
-	HealthCheck *HealthCheckConfig
-}
-
-// HealthCheckConfig defines health check parameters for registration.
-type HealthCheckConfig struct {
-	Endpoint           string
-	IntervalSeconds    int
-	TimeoutSeconds     int
-	UnhealthyThreshold int
-}
+// Registry types are shared with the other backends; the aliases keep
+// existing consumers compiling unchanged.
+type (
+	Instance          = types.Instance
+	Registration      = types.Registration
+	HealthCheckConfig = types.HealthCheckConfig
+	MetadataUpdate    = types.MetadataUpdate
+	MetadataChange    = types.MetadataChange
+)
 
 // Registry is a Consul-backed service registry.
 type Registry struct {
 	client *api.Client
 	logger *slog.Logger
+	config Config
 
 	mu                sync.RWMutex
 	registrationTimes map[string]time.Time
+	nodeName          string                 // the local agent's node
+	agents            map[string]*api.Client // other nodes' agents, keyed by node name
+}
+
+// Config configures a Registry.
+type Config struct {
+	// Address is the Consul agent's address; empty uses the API default.
+	Address string
+	// Datacenters lists the datacenters, including the local one, that
+	// GetInstancesAcrossDatacenters queries. Empty queries only the local
+	// datacenter.
+	Datacenters []string
+	// Reads sets the consistency of instance and service lookups.
+	Reads ReadConfig
+}
+
+// ReadConfig trades the freshness of GetInstances and GetServices for less
+// load on the Consul servers. The zero value reads from the leader.
+type ReadConfig struct {
+	// AllowStale lets any server answer, which may lag the leader.
+	AllowStale bool
+	// UseCache answers from the local agent's cache, refreshed in the
+	// background.
+	UseCache bool
+	// MaxStale bounds how old an answer may be: cached answers older than
+	// it, and stale answers from a server that has not heard from the
+	// leader for longer, are read again from the leader. Zero accepts any
+	// age.
+	MaxStale time.Duration
 }
 
 // NewRegistry creates a Registry using the provided Consul address.
 func NewRegistry(addr string, logger *slog.Logger) (*Registry, error) {
-	cfg := api.DefaultConfig()
-	if addr != "" {
-		cfg.Address = addr
+	return NewRegistryWithConfig(Config{Address: addr}, logger)
+}
+
+// NewRegistryWithConfig creates a Registry from cfg.
+func NewRegistryWithConfig(cfg Config, logger *slog.Logger) (*Registry, error) {
+	apiCfg := api.DefaultConfig()
+	if cfg.Address != "" {
+		apiCfg.Address = cfg.Address
 	}
 
-	client, err := api.NewClient(cfg)
+	client, err := api.NewClient(apiCfg)
 	if err != nil {
 		return nil, fmt.Errorf("consul client: %w", err)
 	}
@@ -77,21 +95,15 @@ func NewRegistry(addr string, logger *slog.Logger) (*Registry, error) {
 	return &Registry{
 		client:            client,
 		logger:            logger,
+		config:            cfg,
 		registrationTimes: make(map[string]time.Time),
+		agents:            make(map[string]*api.Client),
 	}, nil
 }
 
 // Register registers a service instance with Consul using TTL health checks.
 func (r *Registry) Register(reg Registration) error {
-	ttlInterval := 30 * time.Second
-	if reg.HealthCheck != nil && reg.HealthCheck.IntervalSeconds > 0 {
-		ttlInterval = time.Duration(reg.HealthCheck.IntervalSeconds) * time.Second
-	}
-
-	ttlWithBuffer := ttlInterval + 5*time.Second
-	if ttlWithBuffer < 10*time.Second {
-		ttlWithBuffer = 10 * time.Second
-	}
+	ttlWithBuffer := reg.TTLWithBuffer()
 
 	consulReg := &api.AgentServiceRegistration{
 		ID:      reg.ServiceID,
@@ -125,10 +137,33 @@ func (r *Registry) Register(reg Registration) error {
 	return nil
 }
 
-// Deregister removes a service instance from Consul.
+// Deregister removes a service instance from whichever node it is registered
+// on, found through the catalog. It goes through the owning agent, so the
+// agent does not sync the instance back; if that agent cannot be reached, the
+// instance is removed from the catalog with its node instead.
 func (r *Registry) Deregister(serviceID string) error {
-	if err := r.client.Agent().ServiceDeregister(serviceID); err != nil {
-		return fmt.Errorf("consul deregister: %w", err)
+	entry, err := r.lookupService(serviceID)
+	if err != nil {
+		return fmt.Errorf("consul get instance: %w", err)
+	}
+	if entry == nil {
+		return fmt.Errorf("consul deregister: service %s %w", serviceID, ErrNotRegistered)
+	}
+	agent, err := r.agentFor(entry.Node)
+	if err == nil {
+		err = agent.ServiceDeregister(serviceID)
+	}
+	if err != nil {
+		r.logger.Warn("owning agent deregistration failed, deregistering from the catalog",
+			"service_id", serviceID, "node", entry.Node.Node, "error", err)
+		_, err = r.client.Catalog().Deregister(&api.CatalogDeregistration{
+			Node:       entry.Node.Node,
+			Datacenter: entry.Node.Datacenter,
+			ServiceID:  serviceID,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("consul deregister: %w", err)
+		}
 	}
 
 	r.mu.Lock()
@@ -141,11 +176,16 @@ func (r *Registry) Deregister(serviceID string) error {
 
 // GetInstances returns all instances of a service, including health status.
 func (r *Registry) GetInstances(serviceName string) ([]Instance, error) {
-	entries, _, err := r.client.Health().Service(serviceName, "", false, nil)
+	entries, err := read(r, &api.QueryOptions{}, func(opts *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+		return r.client.Health().Service(serviceName, "", false, opts)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("consul get instances: %w", err)
 	}
+	return r.toInstances(entries), nil
+}
 
+func (r *Registry) toInstances(entries []*api.ServiceEntry) []Instance {
 	instances := make([]Instance, 0, len(entries))
 	for _, entry := range entries {
 		meta := make(map[string]string)
@@ -168,17 +208,19 @@ func (r *Registry) GetInstances(serviceName string) ([]Instance, error) {
 			LastHealthCheck: time.Time{},
 		})
 	}
-
-	return instances, nil
+	return instances
 }
 
 // GetServices returns a list of all registered service names.
 func (r *Registry) GetServices() ([]string, error) {
-	services, _, err := r.client.Catalog().Services(nil)
+	services, err := read(r, &api.QueryOptions{}, r.client.Catalog().Services)
 	if err != nil {
 		return nil, fmt.Errorf("consul get services: %w", err)
 	}
+	return serviceNames(services), nil
+}
 
+func serviceNames(services map[string][]string) []string {
 	names := make([]string, 0, len(services))
 	for name := range services {
 		if name == "consul" {
@@ -186,53 +228,167 @@ func (r *Registry) GetServices() ([]string, error) {
 		}
 		names = append(names, name)
 	}
-	return names, nil
+	return names
 }
 
 // UpdateHealth updates the TTL health check status for a service instance.
+// Instances the local agent does not know are found through the catalog and
+// updated with the agent that owns them, since TTL checks live on agents.
 func (r *Registry) UpdateHealth(serviceID string, status HealthStatus, output string) error {
 	checkID := fmt.Sprintf("service:%s", serviceID)
+	err := updateTTL(r.client.Agent(), checkID, status, output)
+	var statusErr api.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		return err
+	}
 
+	entry, err := r.lookupService(serviceID)
+	if err != nil {
+		return fmt.Errorf("consul get instance: %w", err)
+	}
+	if entry == nil {
+		return fmt.Errorf("consul update health: service %s %w", serviceID, ErrNotRegistered)
+	}
+	agent, err := r.agentFor(entry.Node)
+	if err != nil {
+		return fmt.Errorf("consul update health: %w", err)
+	}
+	return updateTTL(agent, checkID, status, output)
+}
+
+// updateTTL sets a TTL check's status through agent.
+func updateTTL(agent *api.Agent, checkID string, status HealthStatus, output string) error {
 	switch status {
-	case HealthHealthy:
-		return r.client.Agent().PassTTL(checkID, output)
 	case HealthUnhealthy:
-		return r.client.Agent().FailTTL(checkID, output)
+		return agent.FailTTL(checkID, output)
 	case HealthDegraded:
-		return r.client.Agent().WarnTTL(checkID, output)
+		return agent.WarnTTL(checkID, output)
 	default:
-		return r.client.Agent().PassTTL(checkID, output)
+		return agent.PassTTL(checkID, output)
 	}
 }
 
-// GetInstance returns a single service instance by ID, or nil if not found.
+// GetInstance returns a single service instance by ID, wherever it is
+// registered, with its health status, or nil if not found.
 func (r *Registry) GetInstance(serviceID string) (*Instance, error) {
-	svc, _, err := r.client.Agent().Service(serviceID, nil)
+	entry, err := r.lookupService(serviceID)
 	if err != nil {
 		return nil, fmt.Errorf("consul get instance: %w", err)
 	}
-	if svc == nil {
+	if entry == nil {
 		return nil, nil
 	}
+	inst := r.toInstances([]*api.ServiceEntry{entry})[0]
+	return &inst, nil
+}
 
-	meta := make(map[string]string)
+// ErrNotRegistered is returned when a service is not in the Consul catalog.
+var ErrNotRegistered = types.ErrNotRegistered
+
+// UpdateMetadata merges meta into the metadata of a registered service. The
+// service is re-registered in place with the agent that owns it; its existing
+// checks, and their status, are kept.
+func (r *Registry) UpdateMetadata(serviceID string, meta map[string]string) error {
+	_, err := r.ApplyMetadata(serviceID, MetadataUpdate{Set: meta})
+	return err
+}
+
+// ApplyMetadata applies update to the metadata of a registered service,
+// found through the catalog on whichever node it runs, re-registering it in
+// place as UpdateMetadata does.
+func (r *Registry) ApplyMetadata(serviceID string, update MetadataUpdate) (MetadataChange, error) {
+	entry, err := r.lookupService(serviceID)
+	if err != nil {
+		return MetadataChange{}, fmt.Errorf("consul get instance: %w", err)
+	}
+	if entry == nil {
+		return MetadataChange{}, fmt.Errorf("consul update metadata: service %s %w", serviceID, ErrNotRegistered)
+	}
+	svc := entry.Service
+
+	previous := make(map[string]string, len(svc.Meta))
 	for k, v := range svc.Meta {
-		meta[k] = v
+		previous[k] = v
+	}
+	current := make(map[string]string, len(svc.Meta)+len(update.Set))
+	if !update.Replace {
+		for k, v := range svc.Meta {
+			current[k] = v
+		}
+	}
+	for k, v := range update.Set {
+		current[k] = v
+	}
+	for _, k := range update.Remove {
+		delete(current, k)
+	}
+
+	reg := &api.AgentServiceRegistration{
+		ID:                svc.ID,
+		Name:              svc.Service,
+		Address:           svc.Address,
+		Port:              svc.Port,
+		Tags:              svc.Tags,
+		Meta:              current,
+		EnableTagOverride: svc.EnableTagOverride,
+		TaggedAddresses:   svc.TaggedAddresses,
+		Weights:           &svc.Weights,
+	}
+	agent, err := r.agentFor(entry.Node)
+	if err != nil {
+		return MetadataChange{}, fmt.Errorf("consul update metadata: %w", err)
+	}
+	// The registration carries no checks, so the agent must be told to keep
+	// the existing ones rather than replace them with none.
+	if err := agent.ServiceRegisterOpts(reg, api.ServiceRegisterOpts{ReplaceExistingChecks: false}); err != nil {
+		return MetadataChange{}, fmt.Errorf("consul update metadata: %w", err)
 	}
+	return MetadataChange{ServiceName: svc.Service, Previous: previous, Current: current}, nil
+}
 
-	r.mu.RLock()
-	regTime := r.registrationTimes[serviceID]
-	r.mu.RUnlock()
+// WaitForChange performs a Consul blocking query against the cluster-wide
+// health state and returns once its index moves past index or wait elapses.
+// Any registration, deregistration, or check status change advances the index.
+// Pass index 0 to return immediately with the current index.
+func (r *Registry) WaitForChange(ctx context.Context, index uint64, wait time.Duration) (uint64, error) {
+	opts := (&api.QueryOptions{WaitIndex: index, WaitTime: wait}).WithContext(ctx)
+	_, meta, err := r.client.Health().State(api.HealthAny, opts)
+	if err != nil {
+		return index, fmt.Errorf("consul wait for change: %w", err)
+	}
+	return meta.LastIndex, nil
+}
 
-	return &Instance{
-		ServiceName: svc.Service,
-		ServiceID:   svc.ID,
-		Address:     svc.Address,
-		Port:        svc.Port,
-		Status:      HealthUnknown, // single-instance lookup doesn't include health
-		Metadata:    meta,
-		RegisteredAt: regTime,
-	}, nil
+// ListKV performs a Consul blocking query for all keys under prefix and returns
+// their values keyed by full key, along with the index to pass on the next
+// call. Pass index 0 to return immediately.
+func (r *Registry) ListKV(ctx context.Context, prefix string, index uint64, wait time.Duration) (map[string][]byte, uint64, error) {
+	opts := (&api.QueryOptions{WaitIndex: index, WaitTime: wait}).WithContext(ctx)
+	pairs, meta, err := r.client.KV().List(prefix, opts)
+	if err != nil {
+		return nil, index, fmt.Errorf("consul kv list %s: %w", prefix, err)
+	}
+	out := make(map[string][]byte, len(pairs))
+	for _, p := range pairs {
+		out[p.Key] = p.Value
+	}
+	return out, meta.LastIndex, nil
+}
+
+// PutKV writes value to key in the Consul KV store.
+func (r *Registry) PutKV(key string, value []byte) error {
+	if _, err := r.client.KV().Put(&api.KVPair{Key: key, Value: value}, nil); err != nil {
+		return fmt.Errorf("consul kv put %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteKV removes key from the Consul KV store.
+func (r *Registry) DeleteKV(key string) error {
+	if _, err := r.client.KV().Delete(key, nil); err != nil {
+		return fmt.Errorf("consul kv delete %s: %w", key, err)
+	}
+	return nil
 }
 
 func mapHealthStatus(checks api.HealthChecks) HealthStatus {
@@ -1,7 +1,17 @@
 package consul
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 )
@@ -79,3 +89,300 @@ func TestMapHealthStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitForChange_PassesIndexAndReturnsLastIndex(t *testing.T) {
+	var gotIndex string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/state/any" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		gotIndex = r.URL.Query().Get("index")
+		w.Header().Set("X-Consul-Index", "42")
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	reg, err := NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	index, err := reg.WaitForChange(context.Background(), 7, time.Second)
+	if err != nil {
+		t.Fatalf("WaitForChange: %v", err)
+	}
+	if gotIndex != "7" {
+		t.Errorf("expected index=7 in query, got %q", gotIndex)
+	}
+	if index != 42 {
+		t.Errorf("expected index 42, got %d", index)
+	}
+}
+
+func TestWaitForChange_ErrorKeepsIndex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	reg, err := NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	index, err := reg.WaitForChange(context.Background(), 7, time.Second)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if index != 7 {
+		t.Errorf("expected index to stay 7, got %d", index)
+	}
+}
+
+// catalogAgent fakes the agent of node-a, whose catalog holds entry, a
+// health entry of the "api" service, if set. Other requests go to next.
+func catalogAgent(t *testing.T, entry string, next http.HandlerFunc) *Registry {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/agent/self":
+			w.Write([]byte(`{"Config":{"NodeName":"node-a"}}`))
+		case "/v1/catalog/services":
+			if entry == "" {
+				w.Write([]byte(`{}`))
+				return
+			}
+			w.Write([]byte(`{"api":[]}`))
+		case "/v1/health/service/api":
+			w.Write([]byte("[" + entry + "]"))
+		default:
+			next(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	reg, err := NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	return reg
+}
+
+func TestUpdateMetadata_MergesAndReregisters(t *testing.T) {
+	var registered api.AgentServiceRegistration
+	reg := catalogAgent(t,
+		`{"Node":{"Node":"node-a"},"Service":{"ID":"api-1","Service":"api","Address":"10.0.0.1","Port":8080,"Meta":{"version":"2"}}}`,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/agent/service/register" {
+				t.Errorf("unexpected path %s", r.URL.Path)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&registered); err != nil {
+				t.Errorf("decode registration: %v", err)
+			}
+		})
+
+	if err := reg.UpdateMetadata("api-1", map[string]string{"scheme": "https"}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	if registered.ID != "api-1" || registered.Port != 8080 {
+		t.Fatalf("expected service to be re-registered unchanged, got %+v", registered)
+	}
+	if registered.Meta["version"] != "2" || registered.Meta["scheme"] != "https" {
+		t.Fatalf("expected merged metadata, got %v", registered.Meta)
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		update MetadataUpdate
+		want   map[string]string
+	}{
+		{"merge and remove", MetadataUpdate{Set: map[string]string{"weight": "5"}, Remove: []string{"zone"}}, map[string]string{"version": "2", "weight": "5"}},
+		{"replace", MetadataUpdate{Set: map[string]string{"weight": "5"}, Replace: true}, map[string]string{"weight": "5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registered api.AgentServiceRegistration
+			reg := catalogAgent(t,
+				`{"Node":{"Node":"node-a"},"Service":{"ID":"api-1","Service":"api","Meta":{"version":"2","zone":"eu-west"}}}`,
+				func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/v1/agent/service/register" {
+						http.NotFound(w, r)
+						return
+					}
+					json.NewDecoder(r.Body).Decode(&registered)
+				})
+
+			change, err := reg.ApplyMetadata("api-1", tt.update)
+			if err != nil {
+				t.Fatalf("ApplyMetadata: %v", err)
+			}
+			if !maps.Equal(change.Current, tt.want) || !maps.Equal(registered.Meta, tt.want) {
+				t.Fatalf("expected metadata %v, got %v (registered %v)", tt.want, change.Current, registered.Meta)
+			}
+			if change.Previous["zone"] != "eu-west" || change.ServiceName != "api" {
+				t.Fatalf("expected previous metadata and service name, got %+v", change)
+			}
+		})
+	}
+}
+
+func TestApplyMetadata_KeepsChecks(t *testing.T) {
+	// The fake agent drops a service's checks when it is re-registered with
+	// replace-existing-checks, as Consul does.
+	checks := map[string]string{"service:api-1": "passing"}
+	reg := catalogAgent(t,
+		`{"Node":{"Node":"node-a"},"Service":{"ID":"api-1","Service":"api","Meta":{"version":"2"}},"Checks":[{"CheckID":"service:api-1","ServiceID":"api-1","Status":"passing"}]}`,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/agent/service/register" {
+				http.NotFound(w, r)
+				return
+			}
+			var registration api.AgentServiceRegistration
+			json.NewDecoder(r.Body).Decode(&registration)
+			if r.URL.Query().Get("replace-existing-checks") == "true" && registration.Check == nil && len(registration.Checks) == 0 {
+				clear(checks)
+			}
+		})
+
+	if _, err := reg.ApplyMetadata("api-1", MetadataUpdate{Set: map[string]string{"weight": "5"}}); err != nil {
+		t.Fatalf("ApplyMetadata: %v", err)
+	}
+	if checks["service:api-1"] != "passing" {
+		t.Fatalf("expected the TTL check to survive the metadata update, have %v", checks)
+	}
+}
+
+func TestApplyMetadata_NotRegistered(t *testing.T) {
+	reg := catalogAgent(t, "", http.NotFound)
+	if _, err := reg.ApplyMetadata("missing", MetadataUpdate{}); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+}
+
+func TestDeregister(t *testing.T) {
+	tests := []struct {
+		name      string
+		agentCode int
+		want      []string
+	}{
+		{"through the owning agent", http.StatusOK, []string{"PUT /v1/agent/service/deregister/api-1"}},
+		{"catalog when the agent fails", http.StatusInternalServerError, []string{
+			"PUT /v1/agent/service/deregister/api-1",
+			"PUT /v1/catalog/deregister node-b api-1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			reg := catalogAgent(t,
+				`{"Node":{"Node":"node-b","Address":"127.0.0.1"},"Service":{"ID":"api-1","Service":"api"}}`,
+				func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/v1/agent/service/deregister/api-1":
+						calls = append(calls, r.Method+" "+r.URL.Path)
+						w.WriteHeader(tt.agentCode)
+					case "/v1/catalog/deregister":
+						var dereg api.CatalogDeregistration
+						json.NewDecoder(r.Body).Decode(&dereg)
+						calls = append(calls, r.Method+" "+r.URL.Path+" "+dereg.Node+" "+dereg.ServiceID)
+						w.Write([]byte("true"))
+					default:
+						http.NotFound(w, r)
+					}
+				})
+
+			if err := reg.Deregister("api-1"); err != nil {
+				t.Fatalf("Deregister: %v", err)
+			}
+			if !slices.Equal(calls, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, calls)
+			}
+		})
+	}
+
+	missing := catalogAgent(t, "", http.NotFound)
+	if err := missing.Deregister("api-1"); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+}
+
+func TestUpdateHealth_ReportsThroughTheOwningAgent(t *testing.T) {
+	// The fake serves both agents; the local one does not know the check.
+	var calls []string
+	reg := catalogAgent(t,
+		`{"Node":{"Node":"node-b","Address":"127.0.0.1"},"Service":{"ID":"api-1","Service":"api"}}`,
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/agent/check/fail/service:api-1" {
+				http.NotFound(w, r)
+				return
+			}
+			calls = append(calls, r.URL.Path)
+			if len(calls) == 1 {
+				http.Error(w, "Unknown check ID", http.StatusNotFound)
+			}
+		})
+
+	if err := reg.UpdateHealth("api-1", HealthUnhealthy, "probe failed"); err != nil {
+		t.Fatalf("UpdateHealth: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected the local agent then the owning agent, got %v", calls)
+	}
+
+	missing := catalogAgent(t, "", http.NotFound)
+	if err := missing.UpdateHealth("api-1", HealthHealthy, ""); !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+}
+
+func TestGetInstance_LooksUpTheCatalog(t *testing.T) {
+	reg := catalogAgent(t,
+		`{"Node":{"Node":"node-b"},"Service":{"ID":"api-1","Service":"api","Address":"10.0.0.2","Port":80},"Checks":[{"Status":"critical"}]}`,
+		http.NotFound)
+
+	inst, err := reg.GetInstance("api-1")
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	if inst == nil || inst.ServiceName != "api" || inst.Address != "10.0.0.2" || inst.Status != HealthUnhealthy {
+		t.Fatalf("expected api-1 on another node with its health, got %+v", inst)
+	}
+
+	missing := catalogAgent(t, "", http.NotFound)
+	if inst, err := missing.GetInstance("api-1"); err != nil || inst != nil {
+		t.Fatalf("expected no instance, got %+v, %v", inst, err)
+	}
+}
+
+func TestListKV_ReturnsValuesAndIndex(t *testing.T) {
+	var gotIndex string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/toska-mesh/maintenance" || !r.URL.Query().Has("recurse") {
+			t.Errorf("unexpected request %s", r.URL.String())
+		}
+		gotIndex = r.URL.Query().Get("index")
+		w.Header().Set("X-Consul-Index", "12")
+		// Values are base64 encoded by the KV API ("e30=" is "{}").
+		w.Write([]byte(`[{"Key":"toska-mesh/maintenance/orders","Value":"e30="}]`))
+	}))
+	defer srv.Close()
+
+	reg, err := NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	values, index, err := reg.ListKV(context.Background(), "toska-mesh/maintenance", 3, time.Second)
+	if err != nil {
+		t.Fatalf("ListKV: %v", err)
+	}
+	if gotIndex != "3" || index != 12 {
+		t.Errorf("expected index 3 in query and 12 returned, got %q and %d", gotIndex, index)
+	}
+	if got := string(values["toska-mesh/maintenance/orders"]); got != "{}" {
+		t.Errorf("expected decoded value {}, got %q", got)
+	}
+}
@@ -0,0 +1,92 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Retry delays of watches whose blocking query failed.
+const (
+	watchMinBackoff = time.Second
+	watchMaxBackoff = 30 * time.Second
+)
+
+// WatchInstances streams the instances of a service, including health
+// status, using Consul blocking queries. The current instances are sent
+// first, then the full list again each time Consul's index for the service
+// advances. Each query blocks for up to wait. Failed queries are logged and
+// retried with backoff. The channel is closed when ctx is cancelled.
+func (r *Registry) WatchInstances(ctx context.Context, serviceName string, wait time.Duration) <-chan []Instance {
+	return watch(ctx, r, "instances of "+serviceName, wait, func(opts *api.QueryOptions) ([]Instance, uint64, error) {
+		entries, meta, err := r.client.Health().Service(serviceName, "", false, opts)
+		if err != nil {
+			return nil, 0, fmt.Errorf("consul watch instances: %w", err)
+		}
+		return r.toInstances(entries), meta.LastIndex, nil
+	})
+}
+
+// WatchServices streams the names of all registered services like
+// WatchInstances, sending the list again each time the catalog's services
+// change.
+func (r *Registry) WatchServices(ctx context.Context, wait time.Duration) <-chan []string {
+	return watch(ctx, r, "services", wait, func(opts *api.QueryOptions) ([]string, uint64, error) {
+		services, meta, err := r.client.Catalog().Services(opts)
+		if err != nil {
+			return nil, 0, fmt.Errorf("consul watch services: %w", err)
+		}
+		return serviceNames(services), meta.LastIndex, nil
+	})
+}
+
+// watch repeats a blocking query, tracking its index, and sends its result
+// whenever the index advances. Queries that time out with the index
+// unchanged are not sent.
+func watch[T any](ctx context.Context, r *Registry, what string, wait time.Duration, query func(*api.QueryOptions) (T, uint64, error)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		backoff := watchMinBackoff
+		var index uint64
+		for {
+			opts := (&api.QueryOptions{WaitIndex: index, WaitTime: wait}).WithContext(ctx)
+			result, newIndex, err := query(opts)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				r.logger.Warn("consul watch failed", "watch", what, "error", err, "retry_in", backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, watchMaxBackoff)
+				continue
+			}
+			backoff = watchMinBackoff
+
+			// Consul may reset its index (e.g. after a snapshot restore);
+			// start over.
+			if newIndex < index {
+				index = 0
+				continue
+			}
+			if index != 0 && newIndex == index {
+				continue
+			}
+			// An index of 0 would make the next query return immediately.
+			index = max(newIndex, 1)
+
+			select {
+			case out <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
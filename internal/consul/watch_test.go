@@ -0,0 +1,110 @@
+package consul
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingServer answers the i-th query with responses[i] and its Consul
+// index, recording the index each query waited on. Queries beyond the last
+// response block until the client goes away.
+func blockingServer(t *testing.T, path string, responses []blockingResponse) (*Registry, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var waited []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		mu.Lock()
+		n := len(waited)
+		waited = append(waited, r.URL.Query().Get("index"))
+		mu.Unlock()
+		if n >= len(responses) {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("X-Consul-Index", responses[n].index)
+		w.Write([]byte(responses[n].body))
+	}))
+	t.Cleanup(srv.Close)
+
+	reg, err := NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	return reg, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(waited)
+	}
+}
+
+type blockingResponse struct {
+	index string
+	body  string
+}
+
+func TestWatchInstances_SendsOnlyWhenIndexAdvances(t *testing.T) {
+	reg, waited := blockingServer(t, "/v1/health/service/api", []blockingResponse{
+		{"5", `[{"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"passing"}]}]`},
+		{"5", `[{"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"passing"}]}]`},
+		{"9", `[{"Service":{"ID":"api-1","Service":"api"},"Checks":[{"Status":"critical"}]},{"Service":{"ID":"api-2","Service":"api"}}]`},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := reg.WatchInstances(ctx, "api", time.Minute)
+
+	first := <-updates
+	if len(first) != 1 || first[0].Status != HealthHealthy {
+		t.Fatalf("expected the healthy api-1 first, got %+v", first)
+	}
+	second := <-updates
+	if len(second) != 2 || second[0].Status != HealthUnhealthy || second[1].ServiceID != "api-2" {
+		t.Fatalf("expected the changed instances, got %+v", second)
+	}
+
+	// The timed-out query at index 5 sends nothing; the next waits on 9.
+	deadline := time.Now().Add(time.Second)
+	for len(waited()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, want := waited(), []string{"", "5", "5", "9"}; !slices.Equal(got, want) {
+		t.Fatalf("expected queries at indexes %q, got %q", want, got)
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Fatal("expected the channel to close once ctx is cancelled")
+	}
+}
+
+func TestWatchServices_RestartsWhenIndexGoesBack(t *testing.T) {
+	reg, waited := blockingServer(t, "/v1/catalog/services", []blockingResponse{
+		{"20", `{"consul":[],"api":[]}`},
+		{"3", `{"consul":[],"api":[]}`},
+		{"4", `{"consul":[],"api":[],"web":[]}`},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := reg.WatchServices(ctx, time.Minute)
+
+	if got := <-updates; !slices.Equal(got, []string{"api"}) {
+		t.Fatalf("expected [api], got %v", got)
+	}
+	got := <-updates
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"api", "web"}) {
+		t.Fatalf("expected [api web], got %v", got)
+	}
+	if got, want := waited()[:3], []string{"", "20", ""}; !slices.Equal(got, want) {
+		t.Fatalf("expected queries at indexes %q, got %q", want, got)
+	}
+}
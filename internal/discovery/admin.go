@@ -0,0 +1,280 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// The admin RPCs below inspect and repair this replica's in-memory state for
+// debugging. ExpireInstance and RebuildTracking change state, so the
+// Authorizer refuses them unless auth is enabled and the caller has
+// AdminRole (see adminMethods).
+
+// maxHistoryEntries bounds the history kept per service; older entries are
+// dropped first.
+const maxHistoryEntries = 100
+
+// maxHistoryServices bounds the services with history; the one with the
+// oldest latest entry is dropped first.
+const maxHistoryServices = 1000
+
+// History actions.
+const (
+	historyRegistered      = "registered"
+	historyDeregistered    = "deregistered"
+	historyHealthChanged   = "health_changed"
+	historyMetadataChanged = "metadata_changed"
+	historyConflict        = "conflict"
+)
+
+// historyEntry is one event in a service's registration history.
+type historyEntry struct {
+	timestamp time.Time
+	serviceID string
+	action    string
+	detail    string
+}
+
+// recordHistory appends the entries event describes to its service history.
+// Events of other types are ignored.
+func (s *Server) recordHistory(event any) {
+	type named struct {
+		service string
+		entry   historyEntry
+	}
+	var entries []named
+	add := func(service, id, action, detail string, at time.Time) {
+		entries = append(entries, named{service, historyEntry{timestamp: at, serviceID: id, action: action, detail: detail}})
+	}
+
+	switch e := event.(type) {
+	case messaging.ServiceRegisteredEvent:
+		add(e.ServiceName, e.ServiceID, historyRegistered, hostPort(e.Address, e.Port), e.Timestamp)
+	case messaging.ServiceDeregisteredEvent:
+		add(e.ServiceName, e.ServiceID, historyDeregistered, e.Reason, e.Timestamp)
+	case messaging.ServiceHealthChangedEvent:
+		add(e.ServiceName, e.ServiceID, historyHealthChanged, e.PreviousStatus+" -> "+e.CurrentStatus, e.Timestamp)
+	case messaging.ServiceMetadataChangedEvent:
+		add(e.ServiceName, e.ServiceID, historyMetadataChanged, formatMetadata(e.Metadata), e.Timestamp)
+	case messaging.ServiceBatchRegisteredEvent:
+		for _, inst := range e.Instances {
+			add(inst.ServiceName, inst.ServiceID, historyRegistered, hostPort(inst.Address, inst.Port)+" (batch)", e.Timestamp)
+		}
+	case messaging.ServiceBatchDeregisteredEvent:
+		for _, inst := range e.Instances {
+			add(inst.ServiceName, inst.ServiceID, historyDeregistered, e.Reason, e.Timestamp)
+		}
+	case messaging.ServiceDecommissionedEvent:
+		for _, id := range e.ServiceIDs {
+			add(e.ServiceName, id, historyDeregistered, e.Reason, e.Timestamp)
+		}
+	case messaging.ServiceRegistrationConflictEvent:
+		detail := fmt.Sprintf("%s conflict with %s at %s, %s", e.Kind, e.ExistingServiceID, hostPort(e.ExistingAddress, e.ExistingPort), e.Resolution)
+		add(e.ServiceName, e.ServiceID, historyConflict, detail, e.Timestamp)
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	for _, n := range entries {
+		if _, ok := s.history[n.service]; !ok && len(s.history) >= maxHistoryServices {
+			s.evictHistory()
+		}
+		h := append(s.history[n.service], n.entry)
+		if len(h) > maxHistoryEntries {
+			h = slices.Delete(h, 0, len(h)-maxHistoryEntries)
+		}
+		s.history[n.service] = h
+	}
+}
+
+// evictHistory drops the history of the service whose latest entry is
+// oldest. historyMu must be held.
+func (s *Server) evictHistory() {
+	var oldest string
+	var at time.Time
+	for name, h := range s.history {
+		if last := h[len(h)-1].timestamp; oldest == "" || last.Before(at) {
+			oldest, at = name, last
+		}
+	}
+	delete(s.history, oldest)
+}
+
+func hostPort(address string, port int) string {
+	return address + ":" + strconv.Itoa(port)
+}
+
+// formatMetadata renders metadata as sorted key=value pairs.
+func formatMetadata(meta map[string]string) string {
+	pairs := make([]string, 0, len(meta))
+	for _, k := range slices.Sorted(maps.Keys(meta)) {
+		pairs = append(pairs, k+"="+meta[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// GetServiceHistory returns the recent events of a service seen by this
+// replica, oldest first.
+func (s *Server) GetServiceHistory(ctx context.Context, req *pb.GetServiceHistoryRequest) (*pb.GetServiceHistoryResponse, error) {
+	if req.ServiceName == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceName is required")
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	resp := &pb.GetServiceHistoryResponse{}
+	for _, e := range s.history[req.ServiceName] {
+		resp.Entries = append(resp.Entries, &pb.HistoryEntry{
+			Timestamp: timestamppb.New(e.timestamp),
+			ServiceId: e.serviceID,
+			Action:    e.action,
+			Detail:    e.detail,
+		})
+	}
+	return resp, nil
+}
+
+// ListTracking returns the tracking table, sorted by service name and ID.
+func (s *Server) ListTracking(ctx context.Context, req *pb.ListTrackingRequest) (*pb.ListTrackingResponse, error) {
+	s.mu.RLock()
+	resp := &pb.ListTrackingResponse{}
+	for id, t := range s.tracking {
+		if req.ServiceName != "" && t.ServiceName != req.ServiceName {
+			continue
+		}
+		inst := &pb.TrackedInstance{
+			ServiceId:    id,
+			ServiceName:  t.ServiceName,
+			RegisteredAt: timestamppb.New(t.RegisteredAt),
+			LastUpdated:  timestamppb.New(t.LastUpdated),
+			Status:       toProtoHealth(t.Status),
+			Metadata:     maps.Clone(t.Metadata),
+		}
+		if t.DeregisteredAt != nil {
+			inst.DeregisteredAt = timestamppb.New(*t.DeregisteredAt)
+		}
+		if t.LastHealthCheck != nil {
+			inst.LastHealthCheck = timestamppb.New(*t.LastHealthCheck)
+		}
+		resp.Instances = append(resp.Instances, inst)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(resp.Instances, func(a, b *pb.TrackedInstance) int {
+		if c := strings.Compare(a.ServiceName, b.ServiceName); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ServiceId, b.ServiceId)
+	})
+	return resp, nil
+}
+
+// ExpireInstance deregisters an instance the way the cleanup sweep does,
+// without waiting for its health check to expire. The instance is found
+// through the registry catalog, on whichever node it runs.
+func (s *Server) ExpireInstance(ctx context.Context, req *pb.ExpireInstanceRequest) (*pb.ExpireInstanceResponse, error) {
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceId is required")
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "Expired by operator"
+	}
+
+	var inst *types.Instance
+	err := s.consulCall(ctx, "get_instance", func() (err error) {
+		inst, err = s.registry.GetInstance(req.ServiceId)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get instance: %w", err)
+	}
+	if inst == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s is not registered", req.ServiceId)
+	}
+
+	if err := s.expire(ctx, *inst, reason, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("expire %s: %w", req.ServiceId, err)
+	}
+	return &pb.ExpireInstanceResponse{Removed: true}, nil
+}
+
+// RebuildTracking resynchronises the tracking table with the registry, for
+// a replica whose table has drifted, e.g. after a restart or a missed
+// deregistration. Untracked instances are added with the registry's
+// timestamps; entries for instances no longer registered are dropped.
+func (s *Server) RebuildTracking(ctx context.Context, req *pb.RebuildTrackingRequest) (*pb.RebuildTrackingResponse, error) {
+	var names []string
+	err := s.consulCall(ctx, "get_services", func() (err error) {
+		names, err = s.registry.GetServices()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get services: %w", err)
+	}
+
+	// Read the whole registry before touching the table, so a failure
+	// part-way through leaves it as it was.
+	live := make(map[string]types.Instance)
+	for _, name := range names {
+		var instances []types.Instance
+		err := s.consulCall(ctx, "get_instances", func() (err error) {
+			instances, err = s.registry.GetInstances(name)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get instances of %s: %w", name, err)
+		}
+		for _, inst := range instances {
+			live[inst.ServiceID] = inst
+		}
+	}
+
+	now := time.Now().UTC()
+	var added, removed int
+	s.mu.Lock()
+	for id := range s.tracking {
+		if _, ok := live[id]; !ok {
+			delete(s.tracking, id)
+			removed++
+		}
+	}
+	for id, inst := range live {
+		if _, ok := s.tracking[id]; ok {
+			continue
+		}
+		registeredAt := inst.RegisteredAt
+		if registeredAt.IsZero() {
+			registeredAt = now
+		}
+		s.tracking[id] = &trackingInfo{
+			ServiceName:  inst.ServiceName,
+			Address:      inst.Address,
+			Port:         inst.Port,
+			RegisteredAt: registeredAt,
+			LastUpdated:  now,
+			Status:       inst.Status,
+			Metadata:     inst.Metadata,
+		}
+		added++
+	}
+	total := len(s.tracking)
+	s.mu.Unlock()
+	s.reportTracked()
+
+	s.logger.Info("rebuilt tracking", "added", added, "removed", removed, "total", total)
+	return &pb.RebuildTrackingResponse{Added: int32(added), Removed: int32(removed), Total: int32(total)}, nil
+}
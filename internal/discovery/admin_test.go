@@ -0,0 +1,211 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestGetServiceHistory_RecordsPublishedEvents(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, 0)
+	ctx := context.Background()
+
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080})
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-2", Address: "10.0.0.5", Port: 8080})
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-1"})
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "billing", ServiceId: "billing-1", Address: "10.0.0.6", Port: 9090})
+
+	resp, err := server.GetServiceHistory(ctx, &pb.GetServiceHistoryRequest{ServiceName: "orders"})
+	if err != nil {
+		t.Fatalf("GetServiceHistory: %v", err)
+	}
+	want := []struct{ id, action, detail string }{
+		{"orders-1", "registered", "10.0.0.5:8080"},
+		{"orders-2", "conflict", "address conflict with orders-1 at 10.0.0.5:8080, allowed"},
+		{"orders-2", "registered", "10.0.0.5:8080"},
+		{"orders-1", "deregistered", "Manual deregistration"},
+	}
+	if len(resp.Entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %v", len(resp.Entries), len(want), resp.Entries)
+	}
+	for i, w := range want {
+		got := resp.Entries[i]
+		if got.ServiceId != w.id || got.Action != w.action || got.Detail != w.detail {
+			t.Errorf("entry %d = %s %s %q, want %s %s %q", i, got.ServiceId, got.Action, got.Detail, w.id, w.action, w.detail)
+		}
+	}
+
+	if _, err := server.GetServiceHistory(ctx, &pb.GetServiceHistoryRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without serviceName, got %v", err)
+	}
+}
+
+func TestRecordHistory_KeepsNewestEntries(t *testing.T) {
+	server := newTombstoneServer(newMemRegistry(), 0)
+	for i := range maxHistoryEntries + 5 {
+		server.recordHistory(messaging.ServiceDeregisteredEvent{ServiceName: "orders", ServiceID: fmt.Sprintf("orders-%d", i)})
+	}
+	server.recordHistory(messaging.ServiceDecommissionedEvent{ServiceName: "orders", ServiceIDs: []string{"orders-a", "orders-b"}, Reason: "Service decommissioned"})
+
+	resp, _ := server.GetServiceHistory(context.Background(), &pb.GetServiceHistoryRequest{ServiceName: "orders"})
+	if len(resp.Entries) != maxHistoryEntries {
+		t.Fatalf("got %d entries, want %d", len(resp.Entries), maxHistoryEntries)
+	}
+	if first := resp.Entries[0].ServiceId; first != "orders-7" {
+		t.Errorf("oldest entry = %s, want orders-7", first)
+	}
+	if last := resp.Entries[len(resp.Entries)-1]; last.ServiceId != "orders-b" || last.Detail != "Service decommissioned" {
+		t.Errorf("newest entry = %s %q", last.ServiceId, last.Detail)
+	}
+}
+
+func TestRecordHistory_BoundsServices(t *testing.T) {
+	server := newTombstoneServer(newMemRegistry(), 0)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range maxHistoryServices + 1 {
+		// svc-0 is the stalest service, even though it gained an entry last.
+		at := start.Add(time.Duration(i+1) * time.Minute)
+		if i == maxHistoryServices {
+			at = start
+		}
+		server.recordHistory(messaging.ServiceDeregisteredEvent{ServiceName: fmt.Sprintf("svc-%d", i%maxHistoryServices), Timestamp: at})
+	}
+	server.recordHistory(messaging.ServiceDeregisteredEvent{ServiceName: "new", Timestamp: start.Add(time.Hour)})
+
+	if n := len(server.history); n != maxHistoryServices {
+		t.Fatalf("expected %d services with history, got %d", maxHistoryServices, n)
+	}
+	if _, ok := server.history["svc-0"]; ok {
+		t.Error("expected the service with the oldest latest entry evicted")
+	}
+	if _, ok := server.history["new"]; !ok {
+		t.Error("expected the new service kept")
+	}
+}
+
+func TestListTracking_FiltersAndSorts(t *testing.T) {
+	server := newTombstoneServer(newMemRegistry(), 0)
+	ctx := context.Background()
+	for _, r := range []struct{ name, id string }{{"orders", "orders-2"}, {"billing", "billing-1"}, {"orders", "orders-1"}} {
+		server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: r.name, ServiceId: r.id, Address: "10.0.0.5", Port: 8080, Metadata: map[string]string{"id": r.id}})
+	}
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-2"})
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"billing-1", "orders-1", "orders-2"}},
+		{"orders", []string{"orders-1", "orders-2"}},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		resp, err := server.ListTracking(ctx, &pb.ListTrackingRequest{ServiceName: tt.filter})
+		if err != nil {
+			t.Fatalf("ListTracking(%q): %v", tt.filter, err)
+		}
+		var got []string
+		for _, inst := range resp.Instances {
+			got = append(got, inst.ServiceId)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("ListTracking(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	resp, _ := server.ListTracking(ctx, &pb.ListTrackingRequest{ServiceName: "orders"})
+	if resp.Instances[0].DeregisteredAt != nil || resp.Instances[1].DeregisteredAt == nil {
+		t.Errorf("expected only orders-2 deregistered: %v", resp.Instances)
+	}
+	if resp.Instances[0].Metadata["id"] != "orders-1" {
+		t.Errorf("expected metadata copied, got %v", resp.Instances[0].Metadata)
+	}
+}
+
+func TestExpireInstance(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, 0)
+	ctx := context.Background()
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080})
+
+	if _, err := server.ExpireInstance(ctx, &pb.ExpireInstanceRequest{ServiceId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unregistered instance, got %v", err)
+	}
+	if _, err := server.ExpireInstance(ctx, &pb.ExpireInstanceRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without serviceId, got %v", err)
+	}
+
+	resp, err := server.ExpireInstance(ctx, &pb.ExpireInstanceRequest{ServiceId: "orders-1"})
+	if err != nil || !resp.Removed {
+		t.Fatalf("ExpireInstance = %v, %v", resp, err)
+	}
+	if _, ok := reg.instances["orders-1"]; ok {
+		t.Error("expected orders-1 deregistered")
+	}
+	history, _ := server.GetServiceHistory(ctx, &pb.GetServiceHistoryRequest{ServiceName: "orders"})
+	last := history.Entries[len(history.Entries)-1]
+	if last.Action != "deregistered" || last.Detail != "Expired by operator" {
+		t.Errorf("last history entry = %s %q", last.Action, last.Detail)
+	}
+	tracked, _ := server.ListTracking(ctx, &pb.ListTrackingRequest{})
+	if tracked.Instances[0].DeregisteredAt == nil {
+		t.Error("expected tracking marked deregistered")
+	}
+}
+
+// failingDeregistry is a memRegistry whose deregistrations fail.
+type failingDeregistry struct{ *memRegistry }
+
+func (failingDeregistry) Deregister(string) error { return errors.New("consul down") }
+
+func TestExpireInstance_ReturnsDeregisterError(t *testing.T) {
+	reg := newMemRegistry()
+	reg.instances["orders-1"] = types.Instance{ServiceName: "orders", ServiceID: "orders-1"}
+	server := newTombstoneServer(failingDeregistry{reg}, 0)
+
+	if resp, err := server.ExpireInstance(context.Background(), &pb.ExpireInstanceRequest{ServiceId: "orders-1"}); err == nil {
+		t.Fatalf("expected the deregistration error, got %v", resp)
+	}
+}
+
+func TestRebuildTracking(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, 0)
+	ctx := context.Background()
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080})
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-2", Address: "10.0.0.6", Port: 8080})
+	registeredAt := server.tracking["orders-1"].RegisteredAt
+
+	// Drift: one instance vanished behind the server's back, another was
+	// registered through a different replica.
+	reg.Deregister("orders-2")
+	reg.Register(types.Registration{ServiceName: "billing", ServiceID: "billing-1", Address: "10.0.0.7", Port: 9090, Metadata: map[string]string{"zone": "eu"}})
+
+	resp, err := server.RebuildTracking(ctx, &pb.RebuildTrackingRequest{})
+	if err != nil {
+		t.Fatalf("RebuildTracking: %v", err)
+	}
+	if resp.Added != 1 || resp.Removed != 1 || resp.Total != 2 {
+		t.Errorf("RebuildTracking = %v, want added 1, removed 1, total 2", resp)
+	}
+	if _, ok := server.tracking["orders-2"]; ok {
+		t.Error("expected orders-2 dropped")
+	}
+	if got := server.tracking["orders-1"].RegisteredAt; !got.Equal(registeredAt) {
+		t.Errorf("orders-1 RegisteredAt = %v, want it kept as %v", got, registeredAt)
+	}
+	billing := server.tracking["billing-1"]
+	if billing == nil || billing.ServiceName != "billing" || billing.Metadata["zone"] != "eu" || billing.RegisteredAt.IsZero() {
+		t.Errorf("billing-1 tracking = %+v", billing)
+	}
+}
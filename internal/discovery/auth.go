@@ -0,0 +1,225 @@
+package discovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// registryServicePrefix is the full method prefix of DiscoveryRegistry RPCs.
+// Only these are authenticated; gRPC health checks and reflection stay open.
+const registryServicePrefix = "/toskamesh.discovery.DiscoveryRegistry/"
+
+// AdminRole may call the admin methods that are not listed in
+// AuthConfig.MethodRoles.
+const AdminRole = "admin"
+
+// adminMethods change registry state outside registration. They are refused
+// unless auth is enabled, and need AdminRole unless MethodRoles says
+// otherwise; they are never public.
+var adminMethods = []string{"ExpireInstance", "RebuildTracking"}
+
+// AuthConfig controls who may call the DiscoveryRegistry. Callers
+// authenticate with a bearer token or, when ClientCAFile is set, a client
+// certificate; either yields an identity, which maps to roles.
+type AuthConfig struct {
+	// CertFile and KeyFile enable TLS on the gRPC and HTTP listeners.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS: client certificates signed by these CAs
+	// authenticate as their first URI SAN (e.g. a SPIFFE ID) or, failing
+	// that, their subject common name.
+	ClientCAFile string
+
+	// Tokens maps bearer tokens to the identity they authenticate.
+	Tokens map[string]string
+	// Roles maps identities to their roles.
+	Roles map[string][]string
+	// MethodRoles maps RPC names (e.g. "Deregister") to the roles allowed to
+	// call them. Methods not listed are open to any authenticated caller.
+	MethodRoles map[string][]string
+	// PublicMethods may be called without credentials (e.g. "GetServices").
+	PublicMethods []string
+}
+
+// Enabled reports whether any credential source is configured. Without one
+// the registry stays open, as before authentication existed.
+func (c AuthConfig) Enabled() bool {
+	return len(c.Tokens) > 0 || c.ClientCAFile != ""
+}
+
+// TLSConfig builds the server TLS configuration, or nil when TLS is off.
+// Client certificates are verified if presented but not required, so token
+// callers can still connect.
+func (c AuthConfig) TLSConfig() (*tls.Config, error) {
+	if c.CertFile == "" && c.KeyFile == "" {
+		if c.ClientCAFile != "" {
+			return nil, fmt.Errorf("client CA requires a server certificate and key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA %s contains no certificates", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, nil
+}
+
+// Authorizer authenticates DiscoveryRegistry callers and enforces
+// per-method roles for both the gRPC and HTTP/JSON APIs.
+type Authorizer struct {
+	config AuthConfig
+	tokens map[[sha256.Size]byte]string // keyed by token hash
+}
+
+// NewAuthorizer creates an authorizer. It returns nil when auth is not
+// enabled; a nil Authorizer allows every call except the admin methods.
+func NewAuthorizer(config AuthConfig) *Authorizer {
+	if !config.Enabled() {
+		return nil
+	}
+	tokens := make(map[[sha256.Size]byte]string, len(config.Tokens))
+	for token, identity := range config.Tokens {
+		tokens[sha256.Sum256([]byte(token))] = identity
+	}
+	return &Authorizer{config: config, tokens: tokens}
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the authenticated caller identity, or "" if
+// the call was not authenticated.
+func IdentityFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(identityKey{}).(string)
+	return id
+}
+
+// Authorize checks a call to method (e.g. "Deregister") made with the given
+// bearer token and verified client certificate chains, returning ctx with
+// the caller's identity.
+func (a *Authorizer) Authorize(ctx context.Context, method, token string, chains [][]*x509.Certificate) (context.Context, error) {
+	admin := slices.Contains(adminMethods, method)
+	if a == nil {
+		if admin {
+			return ctx, status.Errorf(codes.PermissionDenied, "%s requires authentication to be configured", method)
+		}
+		return ctx, nil
+	}
+
+	identity := ""
+	if token != "" {
+		identity = a.tokens[sha256.Sum256([]byte(token))]
+		if identity == "" {
+			return ctx, status.Error(codes.Unauthenticated, "invalid bearer token")
+		}
+	} else if len(chains) > 0 && len(chains[0]) > 0 {
+		identity = certIdentity(chains[0][0])
+	}
+
+	if identity == "" {
+		if !admin && slices.Contains(a.config.PublicMethods, method) {
+			return ctx, nil
+		}
+		return ctx, status.Error(codes.Unauthenticated, "credentials required")
+	}
+
+	allowed, ok := a.config.MethodRoles[method]
+	if !ok && admin {
+		allowed, ok = []string{AdminRole}, true
+	}
+	if ok {
+		roles := a.config.Roles[identity]
+		if !slices.ContainsFunc(allowed, func(r string) bool { return slices.Contains(roles, r) }) {
+			return ctx, status.Errorf(codes.PermissionDenied, "%s may not call %s", identity, method)
+		}
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// UnaryInterceptor enforces Authorize on unary DiscoveryRegistry RPCs.
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authorizeGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor enforces Authorize on streaming DiscoveryRegistry RPCs.
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorizeGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *Authorizer) authorizeGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	method, ok := strings.CutPrefix(fullMethod, registryServicePrefix)
+	if !ok {
+		return ctx, nil
+	}
+
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get("authorization") {
+			if t, ok := strings.CutPrefix(v, "Bearer "); ok {
+				token = t
+				break
+			}
+		}
+	}
+
+	var chains [][]*x509.Certificate
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			chains = info.State.VerifiedChains
+		}
+	}
+	return a.Authorize(ctx, method, token, chains)
+}
+
+// contextStream replaces a stream's context, so interceptors can pass values
+// such as the caller identity to stream handlers.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
+
+func certIdentity(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return cert.Subject.CommonName
+}
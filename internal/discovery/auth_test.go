@@ -0,0 +1,209 @@
+package discovery
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func testAuthConfig() AuthConfig {
+	return AuthConfig{
+		Tokens: map[string]string{
+			"admin-token":  "ops",
+			"orders-token": "orders",
+		},
+		Roles: map[string][]string{
+			"ops":                              {"admin"},
+			"spiffe://mesh/ns/default/sa/ctrl": {"admin"},
+		},
+		MethodRoles:   map[string][]string{"Deregister": {"admin"}},
+		PublicMethods: []string{"GetServices"},
+	}
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://mesh/ns/default/sa/ctrl")
+	certChain := func(cert *x509.Certificate) [][]*x509.Certificate { return [][]*x509.Certificate{{cert}} }
+
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		chains   [][]*x509.Certificate
+		wantCode codes.Code
+		wantID   string
+	}{
+		{"token", "Register", "orders-token", nil, codes.OK, "orders"},
+		{"invalid token", "Register", "nope", nil, codes.Unauthenticated, ""},
+		{"no credentials", "Register", "", nil, codes.Unauthenticated, ""},
+		{"public method", "GetServices", "", nil, codes.OK, ""},
+		{"missing role", "Deregister", "orders-token", nil, codes.PermissionDenied, ""},
+		{"admin role", "Deregister", "admin-token", nil, codes.OK, "ops"},
+		{"cert uri san", "Deregister", "", certChain(&x509.Certificate{URIs: []*url.URL{spiffe}}), codes.OK, spiffe.String()},
+		{"cert common name", "Register", "", certChain(&x509.Certificate{Subject: pkix.Name{CommonName: "payments"}}), codes.OK, "payments"},
+		{"cert without role", "Deregister", "", certChain(&x509.Certificate{Subject: pkix.Name{CommonName: "payments"}}), codes.PermissionDenied, ""},
+		// Admin methods need AdminRole when MethodRoles does not list them.
+		{"admin method", "ExpireInstance", "admin-token", nil, codes.OK, "ops"},
+		{"admin method without role", "RebuildTracking", "orders-token", nil, codes.PermissionDenied, ""},
+	}
+
+	authz := NewAuthorizer(testAuthConfig())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := authz.Authorize(context.Background(), tt.method, tt.token, tt.chains)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("expected %s, got %v", tt.wantCode, err)
+			}
+			if got := IdentityFromContext(ctx); got != tt.wantID {
+				t.Fatalf("expected identity %q, got %q", tt.wantID, got)
+			}
+		})
+	}
+}
+
+func TestNewAuthorizer_DisabledAllowsAll(t *testing.T) {
+	authz := NewAuthorizer(AuthConfig{MethodRoles: map[string][]string{"Deregister": {"admin"}}})
+	if authz != nil {
+		t.Fatal("expected nil authorizer without credentials configured")
+	}
+	if _, err := authz.Authorize(context.Background(), "Deregister", "", nil); err != nil {
+		t.Fatalf("expected nil authorizer to allow calls, got %v", err)
+	}
+	for _, method := range adminMethods {
+		if _, err := authz.Authorize(context.Background(), method, "", nil); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("expected %s refused without auth, got %v", method, err)
+		}
+	}
+}
+
+func TestAuthorizer_AdminMethodsAreNeverPublic(t *testing.T) {
+	cfg := testAuthConfig()
+	cfg.PublicMethods = append(cfg.PublicMethods, "ExpireInstance")
+	if _, err := NewAuthorizer(cfg).Authorize(context.Background(), "ExpireInstance", "", nil); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+}
+
+// serveWithAuth serves a test server over an in-memory listener behind
+// authz's interceptors and returns a client for it.
+func serveWithAuth(t *testing.T, authz *Authorizer) pb.DiscoveryRegistryClient {
+	t.Helper()
+	server, _ := newTestServer(t)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authz.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(authz.StreamInterceptor()),
+	)
+	pb.RegisterDiscoveryRegistryServer(srv, server)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewDiscoveryRegistryClient(conn)
+}
+
+func TestAuthorizer_Interceptors(t *testing.T) {
+	client := serveWithAuth(t, NewAuthorizer(testAuthConfig()))
+
+	withToken := func(token string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+
+	// The fake agent has no catalog endpoint; only authorization matters here.
+	if _, err := client.GetServices(context.Background(), &pb.GetServicesRequest{}); status.Code(err) == codes.Unauthenticated {
+		t.Fatalf("expected public method to be allowed, got %v", err)
+	}
+	if _, err := client.Register(context.Background(), &pb.RegisterServiceRequest{ServiceName: "orders"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a token, got %v", err)
+	}
+	if _, err := client.Deregister(withToken("orders-token"), &pb.DeregisterServiceRequest{ServiceId: "orders-1"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for non-admin, got %v", err)
+	}
+	if _, err := client.Deregister(withToken("admin-token"), &pb.DeregisterServiceRequest{ServiceId: "orders-1"}); err != nil {
+		t.Fatalf("expected admin to deregister, got %v", err)
+	}
+
+	stream, err := client.Heartbeat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected stream without a token to be rejected, got %v", err)
+	}
+}
+
+func TestAuthorizer_InterceptorsRefuseAdminMethodsWithoutAuth(t *testing.T) {
+	client := serveWithAuth(t, NewAuthorizer(AuthConfig{}))
+
+	if _, err := client.ExpireInstance(context.Background(), &pb.ExpireInstanceRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected ExpireInstance refused without auth, got %v", err)
+	}
+	if _, err := client.RebuildTracking(context.Background(), &pb.RebuildTrackingRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected RebuildTracking refused without auth, got %v", err)
+	}
+	if _, err := client.Deregister(context.Background(), &pb.DeregisterServiceRequest{ServiceId: "orders-1"}); err != nil {
+		t.Fatalf("expected other methods allowed without auth, got %v", err)
+	}
+}
+
+func TestRESTHandlerWithAuth(t *testing.T) {
+	handler := NewRESTHandlerWithAuth(&fakeRegistry{}, NewAuthorizer(testAuthConfig()))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{"public", http.MethodGet, RESTPrefix + "/services", "", http.StatusOK},
+		{"no token", http.MethodPost, RESTPrefix + "/register", "", http.StatusUnauthorized},
+		{"not bearer", http.MethodPost, RESTPrefix + "/register", "Basic b3JkZXJzOng=", http.StatusUnauthorized},
+		{"token", http.MethodPost, RESTPrefix + "/register", "Bearer orders-token", http.StatusOK},
+		{"missing role", http.MethodPost, RESTPrefix + "/deregister", "Bearer orders-token", http.StatusForbidden},
+		{"admin", http.MethodPost, RESTPrefix + "/deregister", "Bearer admin-token", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			switch tt.path {
+			case RESTPrefix + "/register":
+				body = strings.NewReader(`{"serviceName":"orders"}`)
+			default:
+				body = strings.NewReader(`{"serviceId":"orders-1"}`)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
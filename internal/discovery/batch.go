@@ -0,0 +1,252 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// maxBatchSize bounds a single batch RPC so one call cannot hold the server
+// against Consul indefinitely.
+const maxBatchSize = 1000
+
+// RegisterBatch registers several instances for orchestrators restarting many
+// at once. The batch is all-or-nothing: if any registration fails, those that
+// succeeded are rolled back and every result reports failure. Rolling back
+// re-registers instances that were registered before the batch as they were,
+// and deregisters the rest. A single ServiceBatchRegisteredEvent replaces the
+// per-instance events.
+func (s *Server) RegisterBatch(ctx context.Context, req *pb.RegisterBatchRequest) (*pb.RegisterBatchResponse, error) {
+	if len(req.Services) == 0 || len(req.Services) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "batch must contain 1 to %d services", maxBatchSize)
+	}
+
+	regs := make([]types.Registration, len(req.Services))
+	seen := make(map[string]bool, len(req.Services))
+	for i, item := range req.Services {
+		if item.ServiceName == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "services[%d]: serviceName is required", i)
+		}
+		regs[i] = s.registration(ctx, item)
+		if seen[regs[i].ServiceID] {
+			return nil, status.Errorf(codes.InvalidArgument, "services[%d]: duplicate serviceId %q", i, regs[i].ServiceID)
+		}
+		seen[regs[i].ServiceID] = true
+	}
+
+	resp := &pb.RegisterBatchResponse{Results: make([]*pb.RegisterServiceResponse, len(regs))}
+	for i, reg := range regs {
+		resp.Results[i] = &pb.RegisterServiceResponse{ServiceId: reg.ServiceID}
+	}
+
+	// The conflict policy applies to each item as to a single registration;
+	// a rejected item fails the batch before anything is written.
+	for i, reg := range regs {
+		_, conflicts := s.checkConflicts(ctx, reg)
+		if err := s.resolveConflicts(ctx, reg, conflicts); err != nil {
+			for _, result := range resp.Results {
+				result.ErrorMessage = "not attempted: batch failed"
+			}
+			resp.Results[i].ErrorMessage = err.Error()
+			return resp, nil
+		}
+	}
+
+	var failure error
+	registered := 0
+	prior := make([]*types.Instance, len(regs)) // registration each item replaced
+	for i, reg := range regs {
+		if failure != nil {
+			resp.Results[i].ErrorMessage = "not attempted: batch failed"
+			continue
+		}
+		err := s.consulCall(ctx, "get_instance", func() (err error) {
+			prior[i], err = s.registry.GetInstance(reg.ServiceID)
+			return err
+		})
+		if err != nil {
+			s.logger.Error("batch registration lookup failed", "service_id", reg.ServiceID, "error", err)
+			resp.Results[i].ErrorMessage = err.Error()
+			failure = err
+			continue
+		}
+		err = s.consulCall(ctx, "register", func() error { return s.registry.Register(reg) })
+		s.countOutcome("discovery_registrations_total", err, nil)
+		if err != nil {
+			s.logger.Error("batch registration failed", "service_id", reg.ServiceID, "error", err)
+			resp.Results[i].ErrorMessage = err.Error()
+			failure = err
+			continue
+		}
+		registered++
+	}
+
+	if failure != nil {
+		for i, reg := range regs[:registered] {
+			var err error
+			if prior[i] != nil {
+				restored := s.registrationOf(*prior[i])
+				err = s.consulCall(ctx, "register", func() error { return s.registry.Register(restored) })
+				s.countOutcome("discovery_registrations_total", err, nil)
+			} else {
+				err = s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(reg.ServiceID) })
+				s.countOutcome("discovery_deregistrations_total", err, nil)
+			}
+			if err != nil {
+				s.logger.Error("batch rollback failed", "service_id", reg.ServiceID, "error", err)
+				resp.Results[i].ErrorMessage = fmt.Sprintf("rollback failed: %v", err)
+				continue
+			}
+			resp.Results[i].ErrorMessage = "rolled back: batch failed"
+		}
+		return resp, nil
+	}
+
+	now := time.Now().UTC()
+	event := messaging.ServiceBatchRegisteredEvent{
+		EventID:   fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp: now,
+		Instances: make([]messaging.RegisteredInstance, len(regs)),
+	}
+	for i, reg := range regs {
+		s.track(reg, now)
+		resp.Results[i].Success = true
+		event.Instances[i] = messaging.RegisteredInstance{
+			ServiceID:   reg.ServiceID,
+			ServiceName: reg.ServiceName,
+			Address:     reg.Address,
+			Port:        reg.Port,
+			Metadata:    reg.Metadata,
+		}
+	}
+	resp.Success = true
+
+	if err := s.publish(ctx, event); err != nil {
+		s.logger.Warn("failed to publish batch registration event", "count", len(regs), "error", err)
+	}
+	s.logger.Info("services registered in batch", "count", len(regs))
+	return resp, nil
+}
+
+// DeregisterBatch deregisters several instances, each independently, and
+// publishes one ServiceBatchDeregisteredEvent for those removed.
+func (s *Server) DeregisterBatch(ctx context.Context, req *pb.DeregisterBatchRequest) (*pb.DeregisterBatchResponse, error) {
+	if len(req.ServiceIds) == 0 || len(req.ServiceIds) > maxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "batch must contain 1 to %d service IDs", maxBatchSize)
+	}
+
+	now := time.Now().UTC()
+	resp := &pb.DeregisterBatchResponse{Results: make([]*pb.DeregisterResult, len(req.ServiceIds))}
+	event := messaging.ServiceBatchDeregisteredEvent{
+		EventID:   fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp: now,
+		Reason:    "Batch deregistration",
+	}
+	for i, id := range req.ServiceIds {
+		resp.Results[i] = &pb.DeregisterResult{ServiceId: id}
+		if id == "" {
+			resp.Results[i].ErrorMessage = "serviceId is required"
+			continue
+		}
+		buried := s.lookupForTombstone(ctx, id)
+		err := s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(id) })
+		s.countOutcome("discovery_deregistrations_total", err, nil)
+		if err != nil {
+			s.logger.Error("batch deregistration failed", "service_id", id, "error", err)
+			resp.Results[i].ErrorMessage = err.Error()
+			continue
+		}
+		resp.Results[i].Removed = true
+		s.bury(ctx, buried, event.Reason, now)
+
+		serviceName := ""
+		s.mu.Lock()
+		if t, ok := s.tracking[id]; ok {
+			serviceName = t.ServiceName
+			t.DeregisteredAt = &now
+			t.LastUpdated = now
+		}
+		s.mu.Unlock()
+		event.Instances = append(event.Instances, messaging.DeregisteredInstance{ServiceID: id, ServiceName: serviceName})
+	}
+
+	if len(event.Instances) > 0 {
+		if err := s.publish(ctx, event); err != nil {
+			s.logger.Warn("failed to publish batch deregistration event", "count", len(event.Instances), "error", err)
+		}
+	}
+	return resp, nil
+}
+
+// DeregisterByServiceName deregisters every instance of a service, each
+// independently and on whichever node it is registered, and publishes one
+// ServiceDecommissionedEvent for those removed. Each result reports whether
+// its instance was removed or why not. A service with no instances yields an
+// empty result.
+func (s *Server) DeregisterByServiceName(ctx context.Context, req *pb.DeregisterByServiceNameRequest) (*pb.DeregisterByServiceNameResponse, error) {
+	if req.ServiceName == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceName is required")
+	}
+
+	var instances []types.Instance
+	err := s.consulCall(ctx, "get_instances", func() (err error) {
+		instances, err = s.registry.GetInstances(req.ServiceName)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get instances: %w", err)
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "Service decommissioned"
+	}
+	now := time.Now().UTC()
+	resp := &pb.DeregisterByServiceNameResponse{Results: make([]*pb.DeregisterResult, len(instances))}
+	event := messaging.ServiceDecommissionedEvent{
+		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:   now,
+		ServiceName: req.ServiceName,
+		Reason:      reason,
+	}
+	for i, inst := range instances {
+		id := inst.ServiceID
+		resp.Results[i] = &pb.DeregisterResult{ServiceId: id}
+		err := s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(id) })
+		s.countOutcome("discovery_deregistrations_total", err, nil)
+		if err != nil {
+			s.logger.Error("deregistration by service name failed", "service_id", id, "error", err)
+			resp.Results[i].ErrorMessage = err.Error()
+			continue
+		}
+		resp.Results[i].Removed = true
+		s.bury(ctx, &inst, reason, now)
+
+		s.mu.Lock()
+		if t, ok := s.tracking[id]; ok {
+			t.DeregisteredAt = &now
+			t.LastUpdated = now
+		}
+		s.mu.Unlock()
+		event.ServiceIDs = append(event.ServiceIDs, id)
+	}
+
+	if len(event.ServiceIDs) > 0 {
+		if err := s.publish(ctx, event); err != nil {
+			s.logger.Warn("failed to publish decommission event", "service_name", req.ServiceName, "error", err)
+		}
+	}
+	if failed := len(instances) - len(event.ServiceIDs); failed > 0 {
+		s.logger.Warn("service partially deregistered by name", "service_name", req.ServiceName, "removed", len(event.ServiceIDs), "failed", failed)
+		return resp, nil
+	}
+	s.logger.Info("service deregistered by name", "service_name", req.ServiceName, "removed", len(event.ServiceIDs), "instances", len(instances))
+	return resp, nil
+}
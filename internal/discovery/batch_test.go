@@ -0,0 +1,203 @@
+package discovery
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func batchOf(ids ...string) *pb.RegisterBatchRequest {
+	req := &pb.RegisterBatchRequest{}
+	for _, id := range ids {
+		req.Services = append(req.Services, &pb.RegisterServiceRequest{
+			ServiceName: "orders", ServiceId: id, Address: "10.0.0.5", Port: 8080,
+		})
+	}
+	return req
+}
+
+func TestRegisterBatch_RegistersAll(t *testing.T) {
+	server, agent := newTestServer(t)
+
+	resp, err := server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || len(resp.Results) != 2 || !resp.Results[0].Success || !resp.Results[1].Success {
+		t.Fatalf("expected every registration to succeed, got %v", resp)
+	}
+	if _, ok := server.tracking["orders-2"]; !ok {
+		t.Fatal("expected batch registrations to be tracked")
+	}
+	if got := strings.Join(agent.Calls(), "|"); !strings.Contains(got, "register orders-1") || !strings.Contains(got, "register orders-2") {
+		t.Fatalf("expected both instances registered, got %s", got)
+	}
+}
+
+func TestRegisterBatch_RollsBackOnFailure(t *testing.T) {
+	server, agent := newTestServer(t, "orders-2")
+
+	resp, err := server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2", "orders-3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success {
+		t.Fatal("expected batch to fail")
+	}
+	wantMessages := []string{"rolled back", "register refused", "not attempted"}
+	for i, want := range wantMessages {
+		if resp.Results[i].Success || !strings.Contains(resp.Results[i].ErrorMessage, want) {
+			t.Errorf("result %d: expected failure containing %q, got %v", i, want, resp.Results[i])
+		}
+	}
+	if got := strings.Join(agent.Calls(), "|"); !strings.Contains(got, "deregister orders-1") || strings.Contains(got, "register orders-3") {
+		t.Fatalf("expected orders-1 rolled back and orders-3 never attempted, got %s", got)
+	}
+	if len(server.tracking) != 0 {
+		t.Fatalf("expected nothing tracked after rollback, got %v", server.tracking)
+	}
+}
+
+func TestRegisterBatch_RollbackRestoresExistingInstances(t *testing.T) {
+	server, agent := newTestServer(t, "orders-2")
+	agent.services["orders-1"] = fakeAgentService{ID: "orders-1", Service: "orders", Address: "10.0.0.9", Port: 9090}
+
+	resp, err := server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Results[0].ErrorMessage, "rolled back") {
+		t.Fatalf("expected orders-1 rolled back, got %v", resp.Results[0])
+	}
+	if got := strings.Join(agent.Calls(), "|"); strings.Contains(got, "deregister orders-1") {
+		t.Fatalf("expected the existing orders-1 kept, got %s", got)
+	}
+	if svc := agent.services["orders-1"]; svc.Address != "10.0.0.9" || svc.Port != 9090 {
+		t.Fatalf("expected orders-1 restored to 10.0.0.9:9090, got %s:%d", svc.Address, svc.Port)
+	}
+}
+
+func TestRegisterBatch_AppliesConflictPolicy(t *testing.T) {
+	server, agent := newTestServer(t)
+	sink := telemetry.NewPrometheus()
+	server.sink, server.conflicts = sink, ConflictReject
+	agent.services["orders-9"] = fakeAgentService{ID: "orders-9", Service: "orders", Address: "10.0.0.5", Port: 8080}
+
+	resp, err := server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Success || !strings.Contains(resp.Results[0].ErrorMessage, "already registered by orders-9") ||
+		!strings.Contains(resp.Results[1].ErrorMessage, "not attempted") {
+		t.Fatalf("expected the batch rejected on orders-1's conflict, got %v", resp.Results)
+	}
+	if got := strings.Join(agent.Calls(), "|"); strings.Contains(got, "register orders-") {
+		t.Fatalf("expected nothing registered, got %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	sink.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `discovery_registration_conflicts_total{kind="address",resolution="rejected"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Fatalf("expected %s in:\n%s", want, rec.Body.String())
+	}
+}
+
+func TestRegisterBatch_ValidatesRequest(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		req  *pb.RegisterBatchRequest
+	}{
+		{"empty", &pb.RegisterBatchRequest{}},
+		{"duplicate ids", batchOf("orders-1", "orders-1")},
+		{"missing name", &pb.RegisterBatchRequest{Services: []*pb.RegisterServiceRequest{{ServiceId: "x"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.RegisterBatch(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeregisterBatch_ReportsPerItemResults(t *testing.T) {
+	server, agent := newTestServer(t)
+	server.RegisterBatch(context.Background(), batchOf("orders-1"))
+
+	resp, err := server.DeregisterBatch(context.Background(), &pb.DeregisterBatchRequest{ServiceIds: []string{"orders-1", ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Results[0].Removed || resp.Results[1].Removed || resp.Results[1].ErrorMessage == "" {
+		t.Fatalf("unexpected results: %v", resp.Results)
+	}
+	if server.tracking["orders-1"].DeregisteredAt == nil {
+		t.Fatal("expected tracking to record the deregistration")
+	}
+	if got := strings.Join(agent.Calls(), "|"); !strings.Contains(got, "deregister orders-1") {
+		t.Fatalf("expected orders-1 deregistered, got %s", got)
+	}
+}
+
+func TestDeregisterByServiceName_RemovesEveryInstance(t *testing.T) {
+	server, agent := newTestServer(t)
+	server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2"))
+	server.Register(context.Background(), &pb.RegisterServiceRequest{ServiceName: "payments", ServiceId: "payments-1", Address: "10.0.0.6", Port: 8080})
+
+	resp, err := server.DeregisterByServiceName(context.Background(), &pb.DeregisterByServiceNameRequest{ServiceName: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[0].Removed || !resp.Results[1].Removed {
+		t.Fatalf("expected both orders instances removed, got %v", resp.Results)
+	}
+	got := strings.Join(agent.Calls(), "|")
+	if !strings.Contains(got, "deregister orders-1") || !strings.Contains(got, "deregister orders-2") || strings.Contains(got, "deregister payments-1") {
+		t.Fatalf("expected only orders instances deregistered, got %s", got)
+	}
+	if server.tracking["orders-2"].DeregisteredAt == nil || server.tracking["payments-1"].DeregisteredAt != nil {
+		t.Fatal("expected tracking to record only the orders deregistrations")
+	}
+
+	resp, err = server.DeregisterByServiceName(context.Background(), &pb.DeregisterByServiceNameRequest{ServiceName: "orders"})
+	if err != nil || len(resp.Results) != 0 {
+		t.Fatalf("expected an empty result once decommissioned, got %v, %v", resp, err)
+	}
+	if _, err := server.DeregisterByServiceName(context.Background(), &pb.DeregisterByServiceNameRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without a name, got %v", err)
+	}
+}
+
+func TestDeregisterByServiceName_ReportsFailuresPerInstance(t *testing.T) {
+	server, agent := newTestServer(t)
+	server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2"))
+	agent.failDeregister["orders-2"] = true
+
+	resp, err := server.DeregisterByServiceName(context.Background(), &pb.DeregisterByServiceNameRequest{ServiceName: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]*pb.DeregisterResult{}
+	for _, r := range resp.Results {
+		results[r.ServiceId] = r
+	}
+	if !results["orders-1"].GetRemoved() || results["orders-1"].GetErrorMessage() != "" {
+		t.Fatalf("expected orders-1 removed, got %v", results["orders-1"])
+	}
+	if results["orders-2"].GetRemoved() || results["orders-2"].GetErrorMessage() == "" {
+		t.Fatalf("expected orders-2 to report its failure, got %v", results["orders-2"])
+	}
+	if server.tracking["orders-2"].DeregisteredAt != nil {
+		t.Fatal("expected orders-2 to stay tracked as registered")
+	}
+}
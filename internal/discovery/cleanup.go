@@ -0,0 +1,158 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// Leadership reports whether this replica is the leader among discovery
+// replicas. consul.Election implements it.
+type Leadership interface {
+	IsLeader() bool
+}
+
+// isLeader reports whether this replica should run cluster-wide work.
+func (s *Server) isLeader() bool {
+	return s.leader == nil || s.leader.IsLeader()
+}
+
+// CleanupConfig controls the leader's sweep for expired instances.
+type CleanupConfig struct {
+	// Interval between sweeps.
+	Interval time.Duration
+	// ExpireAfter is how long an instance must stay unhealthy before it is
+	// deregistered. Keep it below the registry's own reaping delay (one
+	// minute for Consul) so the removal is announced.
+	ExpireAfter time.Duration
+}
+
+// DefaultCleanupConfig returns the cleanup defaults.
+func DefaultCleanupConfig() CleanupConfig {
+	return CleanupConfig{
+		Interval:    15 * time.Second,
+		ExpireAfter: 30 * time.Second,
+	}
+}
+
+// RunCleanup sweeps the registry every Interval until ctx is cancelled,
+// deregistering instances whose health check has expired and publishing a
+// ServiceDeregisteredEvent for each. Only the leader sweeps, and it checks
+// its leadership again before each removal, so with several replicas each
+// expiry is acted on and announced once. Events for RPCs are
+// still published by the replica that served the call, since only it sees
+// the call.
+func (s *Server) RunCleanup(ctx context.Context, cfg CleanupConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	unhealthySince := make(map[string]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		leading := s.isLeader()
+		s.sink.Gauge("discovery_leader", boolGauge(leading), nil)
+		if !leading {
+			// Another replica owns the sweep; start afresh if we take over.
+			clear(unhealthySince)
+			continue
+		}
+		s.sweep(ctx, cfg, unhealthySince, time.Now())
+	}
+}
+
+// sweep deregisters instances that have been unhealthy since before
+// ExpireAfter, tracking first sightings in since.
+func (s *Server) sweep(ctx context.Context, cfg CleanupConfig, since map[string]time.Time, now time.Time) {
+	var names []string
+	err := s.consulCall(ctx, "get_services", func() (err error) {
+		names, err = s.registry.GetServices()
+		return err
+	})
+	if err != nil {
+		s.logger.Warn("cleanup sweep failed", "error", err)
+		return
+	}
+
+	unhealthy := make(map[string]bool)
+	for _, name := range names {
+		var instances []types.Instance
+		err := s.consulCall(ctx, "get_instances", func() (err error) {
+			instances, err = s.registry.GetInstances(name)
+			return err
+		})
+		if err != nil {
+			// Leave first sightings alone rather than forget the
+			// instances we could not see this time.
+			s.logger.Warn("cleanup sweep failed", "service", name, "error", err)
+			return
+		}
+		for _, inst := range instances {
+			if inst.Status != types.HealthUnhealthy {
+				continue
+			}
+			unhealthy[inst.ServiceID] = true
+			first, ok := since[inst.ServiceID]
+			if !ok {
+				since[inst.ServiceID] = now
+				continue
+			}
+			if now.Sub(first) >= cfg.ExpireAfter {
+				// Leadership may pass mid-sweep; the new leader then
+				// removes and announces what is left.
+				if !s.isLeader() {
+					return
+				}
+				s.expire(ctx, inst, "Health check expired", now)
+			}
+		}
+	}
+	for id := range since {
+		if !unhealthy[id] {
+			delete(since, id)
+		}
+	}
+}
+
+// expire deregisters an expired instance, announcing it with reason.
+func (s *Server) expire(ctx context.Context, inst types.Instance, reason string, now time.Time) error {
+	err := s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(inst.ServiceID) })
+	s.countOutcome("discovery_expired_instances_total", err, nil)
+	if err != nil {
+		s.logger.Warn("failed to deregister expired instance", "service_id", inst.ServiceID, "error", err)
+		return err
+	}
+	s.logger.Info("deregistered expired instance", "service_id", inst.ServiceID, "service_name", inst.ServiceName, "reason", reason)
+
+	s.mu.Lock()
+	if t, ok := s.tracking[inst.ServiceID]; ok {
+		t.DeregisteredAt = &now
+		t.LastUpdated = now
+	}
+	s.mu.Unlock()
+
+	if err := s.publish(ctx, messaging.ServiceDeregisteredEvent{
+		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:   now.UTC(),
+		ServiceID:   inst.ServiceID,
+		ServiceName: inst.ServiceName,
+		Reason:      reason,
+	}); err != nil {
+		s.logger.Warn("failed to publish deregistration event", "service_id", inst.ServiceID, "error", err)
+	}
+	return nil
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// sweepRegistry serves a mutable catalog and records deregistrations.
+type sweepRegistry struct {
+	registry.Registry
+
+	mu           sync.Mutex
+	instances    map[string][]types.Instance
+	lookups      int
+	deregistered []string
+}
+
+func (r *sweepRegistry) GetServices() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	var names []string
+	for name := range r.instances {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (r *sweepRegistry) GetInstances(name string) ([]types.Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.instances[name]), nil
+}
+
+func (r *sweepRegistry) Deregister(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deregistered = append(r.deregistered, id)
+	return nil
+}
+
+func (r *sweepRegistry) setStatus(name, id string, status types.HealthStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.instances[name] {
+		if r.instances[name][i].ServiceID == id {
+			r.instances[name][i].Status = status
+		}
+	}
+}
+
+type leaderFlag struct{ atomic.Bool }
+
+func (f *leaderFlag) IsLeader() bool { return f.Load() }
+
+func newCleanupServer(t *testing.T, reg registry.Registry, leader Leadership) (*Server, *telemetry.Prometheus) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	publisher, _ := messaging.NewPublisher("", logger)
+	sink := telemetry.NewPrometheus()
+	return NewServerWithOptions(reg, publisher, logger, Options{Telemetry: sink, Leadership: leader}), sink
+}
+
+func TestSweep_DeregistersAfterExpiry(t *testing.T) {
+	reg := &sweepRegistry{instances: map[string][]types.Instance{
+		"orders": {
+			{ServiceName: "orders", ServiceID: "orders-1", Status: types.HealthUnhealthy},
+			{ServiceName: "orders", ServiceID: "orders-2", Status: types.HealthUnhealthy},
+			{ServiceName: "orders", ServiceID: "orders-3", Status: types.HealthHealthy},
+		},
+	}}
+	s, sink := newCleanupServer(t, reg, nil)
+	cfg := DefaultCleanupConfig()
+	since := make(map[string]time.Time)
+	start := time.Now()
+
+	s.sweep(context.Background(), cfg, since, start)
+	if len(reg.deregistered) != 0 {
+		t.Fatalf("expected no deregistration on first sighting, got %v", reg.deregistered)
+	}
+
+	// orders-2 recovers, so its clock resets.
+	reg.setStatus("orders", "orders-2", types.HealthHealthy)
+	s.sweep(context.Background(), cfg, since, start.Add(10*time.Second))
+	reg.setStatus("orders", "orders-2", types.HealthUnhealthy)
+
+	s.sweep(context.Background(), cfg, since, start.Add(cfg.ExpireAfter))
+	if !slices.Equal(reg.deregistered, []string{"orders-1"}) {
+		t.Fatalf("expected only orders-1 expired, got %v", reg.deregistered)
+	}
+	if _, ok := since["orders-2"]; !ok {
+		t.Fatal("expected orders-2 to be tracked again from its new sighting")
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `discovery_expired_instances_total{outcome="success"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, w.Body.String())
+	}
+}
+
+func TestSweep_StopsWhenLeadershipIsLost(t *testing.T) {
+	reg := &sweepRegistry{instances: map[string][]types.Instance{
+		"orders": {{ServiceName: "orders", ServiceID: "orders-1", Status: types.HealthUnhealthy}},
+	}}
+	var leader leaderFlag
+	leader.Store(true)
+	s, sink := newCleanupServer(t, reg, &leader)
+	cfg := DefaultCleanupConfig()
+	since := make(map[string]time.Time)
+	start := time.Now()
+
+	s.sweep(context.Background(), cfg, since, start)
+	leader.Store(false)
+	s.sweep(context.Background(), cfg, since, start.Add(cfg.ExpireAfter))
+	if len(reg.deregistered) != 0 {
+		t.Fatalf("expected a former leader neither to deregister nor announce, got %v", reg.deregistered)
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "discovery_expired_instances_total") {
+		t.Errorf("expected no expiry recorded, got:\n%s", w.Body.String())
+	}
+}
+
+func TestRunCleanup_OnlyLeaderSweeps(t *testing.T) {
+	reg := &sweepRegistry{instances: map[string][]types.Instance{}}
+	var leader leaderFlag
+	s, sink := newCleanupServer(t, reg, &leader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunCleanup(ctx, CleanupConfig{Interval: 5 * time.Millisecond, ExpireAfter: time.Minute})
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	reg.mu.Lock()
+	followerLookups := reg.lookups
+	reg.mu.Unlock()
+	if followerLookups != 0 {
+		t.Fatalf("expected a follower not to sweep, got %d lookups", followerLookups)
+	}
+
+	leader.Store(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		reg.mu.Lock()
+		n := reg.lookups
+		reg.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the leader to sweep")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := "discovery_leader 1"; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, w.Body.String())
+	}
+}
@@ -0,0 +1,175 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// ConflictPolicy decides what Register does when a registration clashes with
+// an instance already in the registry: another ID at the same address:port.
+// Re-registering an ID with different data, such as an instance restarting
+// with a new IP, is an update rather than a conflict.
+type ConflictPolicy string
+
+const (
+	// ConflictAllow registers anyway and reports the conflict. The default.
+	ConflictAllow ConflictPolicy = "allow"
+	// ConflictReject fails the registration, leaving the existing instance.
+	ConflictReject ConflictPolicy = "reject"
+	// ConflictSupersede replaces the existing instance: another ID at the
+	// same address:port is deregistered first.
+	ConflictSupersede ConflictPolicy = "supersede"
+)
+
+// Valid reports whether p is a known policy. The empty policy means
+// ConflictAllow.
+func (p ConflictPolicy) Valid() bool {
+	switch p {
+	case "", ConflictAllow, ConflictReject, ConflictSupersede:
+		return true
+	}
+	return false
+}
+
+// Conflict kinds, as reported in events and metrics.
+const conflictAddress = "address"
+
+// registrationConflict is an existing instance that clashes with a
+// registration.
+type registrationConflict struct {
+	kind     string
+	existing types.Instance
+}
+
+// checkConflicts compares reg with the registered instances of its service.
+// It reports whether reg repeats its current registration exactly, and any
+// conflicts. A tracked instance re-registering at the same address:port was
+// checked when it first registered, so it is compared against the tracking
+// table without a registry lookup. Only the same service is searched, so one
+// address shared by two services is not detected. A failed lookup is logged
+// and treated as no conflict, leaving the registry write to surface the
+// outage.
+func (s *Server) checkConflicts(ctx context.Context, reg types.Registration) (unchanged bool, conflicts []registrationConflict) {
+	s.mu.RLock()
+	t, ok := s.tracking[reg.ServiceID]
+	current := ok && t.DeregisteredAt == nil && t.ServiceName == reg.ServiceName &&
+		t.Address == reg.Address && t.Port == reg.Port
+	if current {
+		unchanged = maps.Equal(t.Metadata, reg.Metadata)
+	}
+	s.mu.RUnlock()
+	if current {
+		return unchanged, nil
+	}
+
+	var instances []types.Instance
+	err := s.consulCall(ctx, "get_instances", func() (err error) {
+		instances, err = s.registry.GetInstances(reg.ServiceName)
+		return err
+	})
+	if err != nil {
+		s.logger.Warn("conflict check failed", "service_id", reg.ServiceID, "error", err)
+		return false, nil
+	}
+
+	for _, inst := range instances {
+		switch {
+		case inst.ServiceID == reg.ServiceID:
+			// The same ID with different data is an update.
+			unchanged = inst.Address == reg.Address && inst.Port == reg.Port && maps.Equal(inst.Metadata, reg.Metadata)
+		case inst.Address == reg.Address && inst.Port == reg.Port:
+			conflicts = append(conflicts, registrationConflict{kind: conflictAddress, existing: inst})
+		}
+	}
+	return unchanged, conflicts
+}
+
+// resolveConflicts applies the server's policy to conflicts, publishing a
+// ServiceRegistrationConflictEvent for each. It returns an error when the
+// registration must be rejected.
+func (s *Server) resolveConflicts(ctx context.Context, reg types.Registration, conflicts []registrationConflict) error {
+	resolution := "allowed"
+	switch s.conflicts {
+	case ConflictReject:
+		resolution = "rejected"
+	case ConflictSupersede:
+		resolution = "superseded"
+	}
+
+	var rejected error
+	for _, c := range conflicts {
+		s.sink.Count("discovery_registration_conflicts_total", 1, telemetry.Labels{"kind": c.kind, "resolution": resolution})
+		s.logger.Warn("registration conflict",
+			"service_id", reg.ServiceID,
+			"kind", c.kind,
+			"existing_service_id", c.existing.ServiceID,
+			"address", reg.Address,
+			"port", reg.Port,
+			"resolution", resolution,
+		)
+		if err := s.publish(ctx, messaging.ServiceRegistrationConflictEvent{
+			EventID:           fmt.Sprintf("%d", time.Now().UnixNano()),
+			Timestamp:         time.Now().UTC(),
+			ServiceID:         reg.ServiceID,
+			ServiceName:       reg.ServiceName,
+			Address:           reg.Address,
+			Port:              reg.Port,
+			Kind:              c.kind,
+			ExistingServiceID: c.existing.ServiceID,
+			ExistingAddress:   c.existing.Address,
+			ExistingPort:      c.existing.Port,
+			Resolution:        resolution,
+		}); err != nil {
+			s.logger.Warn("failed to publish conflict event", "service_id", reg.ServiceID, "error", err)
+		}
+
+		switch {
+		case s.conflicts == ConflictReject && rejected == nil:
+			rejected = conflictError(reg, c)
+		case s.conflicts == ConflictSupersede:
+			s.supersede(ctx, c.existing, reg.ServiceID)
+		}
+	}
+	return rejected
+}
+
+func conflictError(reg types.Registration, c registrationConflict) error {
+	addr := reg.Address + ":" + strconv.Itoa(reg.Port)
+	return fmt.Errorf("address %s is already registered by %s", addr, c.existing.ServiceID)
+}
+
+// supersede deregisters an instance whose address:port has been taken over by
+// newID. A failure is logged; the new registration goes ahead regardless.
+func (s *Server) supersede(ctx context.Context, old types.Instance, newID string) {
+	err := s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(old.ServiceID) })
+	s.countOutcome("discovery_deregistrations_total", err, nil)
+	if err != nil {
+		s.logger.Error("failed to deregister superseded instance", "service_id", old.ServiceID, "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	if t, ok := s.tracking[old.ServiceID]; ok {
+		t.DeregisteredAt = &now
+		t.LastUpdated = now
+	}
+	s.mu.Unlock()
+
+	if err := s.publish(ctx, messaging.ServiceDeregisteredEvent{
+		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:   now,
+		ServiceID:   old.ServiceID,
+		ServiceName: old.ServiceName,
+		Reason:      "Superseded by " + newID,
+	}); err != nil {
+		s.logger.Warn("failed to publish deregistration event", "service_id", old.ServiceID, "error", err)
+	}
+}
@@ -0,0 +1,151 @@
+package discovery
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestRegister_Conflicts(t *testing.T) {
+	orders1 := &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080}
+
+	tests := []struct {
+		name        string
+		policy      ConflictPolicy
+		req         *pb.RegisterServiceRequest
+		wantSuccess bool
+		wantError   string
+		wantCalls   []string
+		avoidCalls  []string
+		wantMetric  string
+	}{
+		{
+			name:        "address allowed",
+			req:         &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-2", Address: "10.0.0.5", Port: 8080},
+			wantSuccess: true,
+			wantCalls:   []string{"register orders-2"},
+			avoidCalls:  []string{"deregister orders-1"},
+			wantMetric:  `discovery_registration_conflicts_total{kind="address",resolution="allowed"} 1`,
+		},
+		{
+			name:       "address rejected",
+			policy:     ConflictReject,
+			req:        &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-2", Address: "10.0.0.5", Port: 8080},
+			wantError:  "address 10.0.0.5:8080 is already registered by orders-1",
+			avoidCalls: []string{"register orders-2"},
+			wantMetric: `discovery_registration_conflicts_total{kind="address",resolution="rejected"} 1`,
+		},
+		{
+			name:        "address superseded",
+			policy:      ConflictSupersede,
+			req:         &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-2", Address: "10.0.0.5", Port: 8080},
+			wantSuccess: true,
+			wantCalls:   []string{"deregister orders-1", "register orders-2"},
+			wantMetric:  `discovery_registration_conflicts_total{kind="address",resolution="superseded"} 1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, agent := newTestServer(t)
+			sink := telemetry.NewPrometheus()
+			server.sink, server.conflicts = sink, tt.policy
+			if _, err := server.Register(context.Background(), orders1); err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := server.Register(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Success != tt.wantSuccess || !strings.Contains(resp.ErrorMessage, tt.wantError) {
+				t.Fatalf("got success=%v error=%q, want success=%v error containing %q", resp.Success, resp.ErrorMessage, tt.wantSuccess, tt.wantError)
+			}
+			calls := strings.Join(agent.Calls(), "|")
+			for _, want := range tt.wantCalls {
+				if !strings.Contains(calls, want) {
+					t.Errorf("expected call %q, got %s", want, calls)
+				}
+			}
+			for _, avoid := range tt.avoidCalls {
+				if strings.Contains(calls, avoid) {
+					t.Errorf("unexpected call %q in %s", avoid, calls)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+			if !strings.Contains(w.Body.String(), tt.wantMetric) {
+				t.Errorf("expected %q in:\n%s", tt.wantMetric, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegister_RepeatIsIdempotent(t *testing.T) {
+	server, agent := newTestServer(t)
+	sink := telemetry.NewPrometheus()
+	server.sink, server.conflicts = sink, ConflictReject
+	req := &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080, Metadata: map[string]string{"zone": "eu-west"}}
+
+	if _, err := server.Register(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	registeredAt := server.tracking["orders-1"].RegisteredAt
+
+	resp, err := server.Register(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected a repeated registration to succeed, got %v", resp)
+	}
+	if got := server.tracking["orders-1"].RegisteredAt; !got.Equal(registeredAt) {
+		t.Fatalf("expected registration time %v kept, got %v", registeredAt, got)
+	}
+	if n := strings.Count(strings.Join(agent.Calls(), "|"), "register orders-1"); n != 2 {
+		t.Fatalf("expected the registry to be renewed, got %d registrations", n)
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "discovery_registration_conflicts_total") {
+		t.Errorf("expected no conflict reported for an identical registration:\n%s", w.Body.String())
+	}
+	// The repeat is checked against tracking, not the registry.
+	if want := `discovery_consul_duration_seconds_count{operation="get_instances",outcome="success"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, w.Body.String())
+	}
+}
+
+func TestRegister_ChangedIDIsAnUpdate(t *testing.T) {
+	server, agent := newTestServer(t)
+	sink := telemetry.NewPrometheus()
+	server.sink, server.conflicts = sink, ConflictReject
+
+	// An instance restarting with a new IP keeps its ID.
+	for _, addr := range []string{"10.0.0.5", "10.0.0.6"} {
+		resp, err := server.Register(context.Background(), &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: addr, Port: 8080})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Success {
+			t.Fatalf("expected registration at %s to succeed, got %v", addr, resp)
+		}
+	}
+	if n := strings.Count(strings.Join(agent.Calls(), "|"), "register orders-1"); n != 2 {
+		t.Fatalf("expected 2 registrations, got %d", n)
+	}
+	if got := server.tracking["orders-1"].Address; got != "10.0.0.6" {
+		t.Fatalf("expected tracked address 10.0.0.6, got %q", got)
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "discovery_registration_conflicts_total") {
+		t.Errorf("expected no conflict reported for a re-registration:\n%s", w.Body.String())
+	}
+}
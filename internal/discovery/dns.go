@@ -0,0 +1,432 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// DNSConfig controls the optional DNS interface, which lets clients without
+// gRPC resolve healthy instances. It is unauthenticated, so it is off unless
+// enabled and should listen only where mesh clients can reach it:
+//
+//	orders.service.mesh          A/AAAA records of healthy instances
+//	_orders._tcp.service.mesh    SRV records (the SRV form is also accepted
+//	                             without the leading labels)
+//	0a000005.addr.mesh           the A record an SRV target points at
+type DNSConfig struct {
+	// Enabled starts the DNS server.
+	Enabled bool
+	// Addr is the UDP and TCP listen address, e.g. ":8600".
+	Addr string
+	// Domain is the zone answered for; other names are refused.
+	Domain string
+	// TTL is the record TTL, and how long registry lookups are cached.
+	// Keep it short: instances come and go.
+	TTL time.Duration
+	// MaxConcurrent bounds the UDP queries, and separately the TCP
+	// connections, handled at once. Further packets wait in the socket
+	// buffer and connections in the accept queue.
+	MaxConcurrent int
+}
+
+// DefaultDNSConfig returns the DNS defaults.
+func DefaultDNSConfig() DNSConfig {
+	return DNSConfig{
+		Enabled:       false,
+		Addr:          ":8600",
+		Domain:        "mesh",
+		TTL:           5 * time.Second,
+		MaxConcurrent: 64,
+	}
+}
+
+// Sizes bounding DNS responses: the classic UDP limit, the largest EDNS0
+// buffer honoured, and TCP's 16-bit length prefix.
+const (
+	dnsUDPSize    = 512
+	dnsMaxUDPSize = 4096
+	dnsTCPSize    = 65535
+)
+
+// dnsCacheSize bounds the services whose lookups are cached, since any
+// client can query arbitrary names.
+const dnsCacheSize = 1024
+
+// DNSServer answers DNS queries from the registry. Lookups are cached for
+// the record TTL, so answers are as fresh as the TTL allows and a burst of
+// queries costs one registry call per service.
+type DNSServer struct {
+	registry registry.Registry
+	config   DNSConfig
+	domain   string // lowercase, with leading and trailing dots: ".mesh."
+	sink     telemetry.Sink
+	logger   *slog.Logger
+
+	lookups singleflight.Group
+	mu      sync.Mutex
+	cache   map[string]dnsCacheEntry // keyed by service name
+}
+
+// dnsCacheEntry holds a service's healthy instances until expires.
+type dnsCacheEntry struct {
+	healthy []types.Instance
+	expires time.Time
+}
+
+// NewDNSServer creates a DNS server over reg. A nil sink disables metrics.
+func NewDNSServer(reg registry.Registry, cfg DNSConfig, sink telemetry.Sink, logger *slog.Logger) *DNSServer {
+	domain := strings.Trim(strings.ToLower(cfg.Domain), ".")
+	return &DNSServer{
+		registry: reg,
+		config:   cfg,
+		domain:   "." + domain + ".",
+		sink:     telemetry.OrNop(sink),
+		logger:   logger,
+		cache:    make(map[string]dnsCacheEntry),
+	}
+}
+
+// ListenAndServe serves DNS over UDP and TCP on the configured address until
+// ctx is cancelled.
+func (d *DNSServer) ListenAndServe(ctx context.Context) error {
+	pc, err := net.ListenPacket("udp", d.config.Addr)
+	if err != nil {
+		return fmt.Errorf("dns listen udp: %w", err)
+	}
+	ln, err := net.Listen("tcp", d.config.Addr)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("dns listen tcp: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		pc.Close()
+		ln.Close()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	var udpErr, tcpErr error
+	go func() { defer wg.Done(); udpErr = d.serveUDP(pc) }()
+	go func() { defer wg.Done(); tcpErr = d.serveTCP(ln) }()
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return errors.Join(udpErr, tcpErr)
+}
+
+func (d *DNSServer) serveUDP(pc net.PacketConn) error {
+	buf := make([]byte, dnsMaxUDPSize)
+	slots := make(chan struct{}, max(d.config.MaxConcurrent, 1))
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		query := append([]byte(nil), buf[:n]...)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots }()
+			if resp := d.Answer(query, true); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+func (d *DNSServer) serveTCP(ln net.Listener) error {
+	slots := make(chan struct{}, max(d.config.MaxConcurrent, 1))
+	for {
+		slots <- struct{}{}
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer func() { <-slots }()
+			d.handleTCP(conn)
+		}()
+	}
+}
+
+// handleTCP answers length-prefixed queries until the client closes the
+// connection or idles.
+func (d *DNSServer) handleTCP(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		var size uint16
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		query := make([]byte, size)
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		resp := d.Answer(query, false)
+		if resp == nil {
+			return
+		}
+		if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(resp)))); err != nil {
+			return
+		}
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+// Answer builds the response to a wire-format query, truncating it to the
+// client's buffer size when udp is set. It returns nil for messages that
+// should be dropped, such as responses or unparseable packets.
+func (d *DNSServer) Answer(query []byte, udp bool) []byte {
+	var req dnsmessage.Message
+	if err := req.Unpack(query); err != nil || req.Header.Response {
+		return nil
+	}
+
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 req.Header.ID,
+			Response:           true,
+			OpCode:             req.Header.OpCode,
+			Authoritative:      true,
+			RecursionDesired:   req.Header.RecursionDesired,
+			RecursionAvailable: false,
+		},
+		Questions: req.Questions,
+	}
+	limit := dnsTCPSize
+	if udp {
+		limit = dnsUDPSize
+	}
+	for _, rr := range req.Additionals {
+		if rr.Header.Type == dnsmessage.TypeOPT {
+			if size := int(rr.Header.Class); udp && size > limit {
+				limit = min(size, dnsMaxUDPSize)
+			}
+			var opt dnsmessage.ResourceHeader
+			opt.SetEDNS0(dnsMaxUDPSize, dnsmessage.RCodeSuccess, false)
+			resp.Additionals = append(resp.Additionals, dnsmessage.Resource{Header: opt, Body: &dnsmessage.OPTResource{}})
+		}
+	}
+
+	qtype := "unknown"
+	switch {
+	case req.Header.OpCode != 0:
+		resp.Header.RCode = dnsmessage.RCodeNotImplemented
+	case len(req.Questions) != 1:
+		resp.Header.RCode = dnsmessage.RCodeFormatError
+	default:
+		q := req.Questions[0]
+		qtype = strings.TrimPrefix(q.Type.String(), "Type")
+		answers, extra, rcode := d.resolve(q)
+		resp.Header.RCode = rcode
+		resp.Answers = answers
+		resp.Additionals = append(extra, resp.Additionals...)
+	}
+	d.sink.Count("discovery_dns_queries_total", 1, telemetry.Labels{
+		"qtype": qtype,
+		"rcode": strings.TrimPrefix(resp.Header.RCode.String(), "RCode"),
+	})
+
+	packed, err := resp.Pack()
+	for err == nil && len(packed) > limit {
+		// Drop glue first, then answers from the end, and let the client
+		// retry over TCP for the full set.
+		resp.Header.Truncated = true
+		if n := len(resp.Additionals); n > 0 && resp.Additionals[0].Header.Type != dnsmessage.TypeOPT {
+			resp.Additionals = resp.Additionals[1:]
+		} else if len(resp.Answers) > 0 {
+			resp.Answers = resp.Answers[:len(resp.Answers)-1]
+		} else {
+			break
+		}
+		packed, err = resp.Pack()
+	}
+	if err != nil {
+		d.logger.Warn("failed to pack DNS response", "error", err)
+		return nil
+	}
+	return packed
+}
+
+// resolve answers one question, returning answer and additional records.
+func (d *DNSServer) resolve(q dnsmessage.Question) ([]dnsmessage.Resource, []dnsmessage.Resource, dnsmessage.RCode) {
+	name := strings.ToLower(q.Name.String())
+	rel, ok := strings.CutSuffix(name, d.domain)
+	if !ok || q.Class != dnsmessage.ClassINET {
+		return nil, nil, dnsmessage.RCodeRefused
+	}
+	labels := strings.Split(rel, ".")
+	kind := labels[len(labels)-1]
+	labels = labels[:len(labels)-1]
+
+	switch {
+	case kind == "addr" && len(labels) == 1:
+		addr, ok := decodeAddrLabel(labels[0])
+		if !ok {
+			return nil, nil, dnsmessage.RCodeNameError
+		}
+		if rr, ok := d.addressRecord(q.Name, addr); ok && rr.Header.Type == q.Type {
+			return []dnsmessage.Resource{rr}, nil, dnsmessage.RCodeSuccess
+		}
+		return nil, nil, dnsmessage.RCodeSuccess
+	case kind == "service" && len(labels) == 1:
+		return d.resolveService(q, labels[0])
+	case kind == "service" && len(labels) == 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_"):
+		return d.resolveService(q, labels[0][1:])
+	default:
+		return nil, nil, dnsmessage.RCodeNameError
+	}
+}
+
+func (d *DNSServer) resolveService(q dnsmessage.Question, service string) ([]dnsmessage.Resource, []dnsmessage.Resource, dnsmessage.RCode) {
+	healthy, err := d.healthyInstances(service)
+	if err != nil {
+		d.logger.Warn("DNS lookup failed", "service", service, "error", err)
+		return nil, nil, dnsmessage.RCodeServerFailure
+	}
+	if len(healthy) == 0 {
+		return nil, nil, dnsmessage.RCodeNameError
+	}
+	// Shuffle so clients that take the first record spread their load.
+	rand.Shuffle(len(healthy), func(i, j int) { healthy[i], healthy[j] = healthy[j], healthy[i] })
+
+	var answers, extra []dnsmessage.Resource
+	for _, inst := range healthy {
+		addr, err := netip.ParseAddr(inst.Address)
+		isIP := err == nil
+		switch q.Type {
+		case dnsmessage.TypeA, dnsmessage.TypeAAAA:
+			if rr, ok := d.addressRecord(q.Name, addr); isIP && ok && rr.Header.Type == q.Type {
+				answers = append(answers, rr)
+			}
+		case dnsmessage.TypeSRV:
+			var target dnsmessage.Name
+			if isIP {
+				target = dnsmessage.MustNewName(encodeAddrLabel(addr) + ".addr" + d.domain)
+				if rr, ok := d.addressRecord(target, addr); ok {
+					extra = append(extra, rr)
+				}
+			} else if target, err = dnsmessage.NewName(strings.TrimSuffix(inst.Address, ".") + "."); err != nil {
+				continue
+			}
+			weight := uint16(1)
+			if w, err := strconv.ParseUint(inst.Metadata["weight"], 10, 16); err == nil && w > 0 {
+				weight = uint16(w)
+			}
+			answers = append(answers, dnsmessage.Resource{
+				Header: d.header(q.Name, dnsmessage.TypeSRV),
+				Body:   &dnsmessage.SRVResource{Priority: 1, Weight: weight, Port: uint16(inst.Port), Target: target},
+			})
+		}
+	}
+	return answers, extra, dnsmessage.RCodeSuccess
+}
+
+// healthyInstances returns a copy of the service's healthy instances, from
+// the cache while its entry is fresh. Concurrent misses share one registry
+// call, and failed lookups are not cached.
+func (d *DNSServer) healthyInstances(service string) ([]types.Instance, error) {
+	now := time.Now()
+	d.mu.Lock()
+	entry, ok := d.cache[service]
+	d.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return slices.Clone(entry.healthy), nil
+	}
+
+	v, err, _ := d.lookups.Do(service, func() (any, error) {
+		instances, err := d.registry.GetInstances(service)
+		if err != nil {
+			return nil, err
+		}
+		var healthy []types.Instance
+		for _, inst := range instances {
+			if inst.Status == types.HealthHealthy {
+				healthy = append(healthy, inst)
+			}
+		}
+		d.store(service, healthy, now)
+		return healthy, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]types.Instance)), nil
+}
+
+// store caches a lookup, first dropping expired entries when the cache is
+// full. A cache still full of fresh entries skips caching.
+func (d *DNSServer) store(service string, healthy []types.Instance, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.cache[service]; !ok && len(d.cache) >= dnsCacheSize {
+		for name, entry := range d.cache {
+			if !now.Before(entry.expires) {
+				delete(d.cache, name)
+			}
+		}
+		if len(d.cache) >= dnsCacheSize {
+			return
+		}
+	}
+	d.cache[service] = dnsCacheEntry{healthy: healthy, expires: now.Add(d.config.TTL)}
+}
+
+func (d *DNSServer) header(name dnsmessage.Name, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  name,
+		Type:  typ,
+		Class: dnsmessage.ClassINET,
+		TTL:   uint32(d.config.TTL / time.Second),
+	}
+}
+
+// addressRecord returns an A or AAAA record for addr.
+func (d *DNSServer) addressRecord(name dnsmessage.Name, addr netip.Addr) (dnsmessage.Resource, bool) {
+	switch {
+	case addr.Is4() || addr.Is4In6():
+		return dnsmessage.Resource{Header: d.header(name, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: addr.Unmap().As4()}}, true
+	case addr.Is6():
+		return dnsmessage.Resource{Header: d.header(name, dnsmessage.TypeAAAA), Body: &dnsmessage.AAAAResource{AAAA: addr.As16()}}, true
+	default:
+		return dnsmessage.Resource{}, false
+	}
+}
+
+// encodeAddrLabel renders an IP as a hex DNS label, e.g. 10.0.0.5 as
+// 0a000005, for SRV targets.
+func encodeAddrLabel(addr netip.Addr) string {
+	return hex.EncodeToString(addr.Unmap().AsSlice())
+}
+
+func decodeAddrLabel(label string) (netip.Addr, bool) {
+	b, err := hex.DecodeString(label)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return netip.AddrFromSlice(b)
+}
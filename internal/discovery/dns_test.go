@@ -0,0 +1,224 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// stubRegistry serves fixed instances; other Registry methods are not used
+// by the DNS server.
+type stubRegistry struct {
+	registry.Registry
+	instances map[string][]types.Instance
+	err       error
+	lookups   int
+}
+
+func (s *stubRegistry) GetInstances(name string) ([]types.Instance, error) {
+	s.lookups++
+	return slices.Clone(s.instances[name]), s.err
+}
+
+func newTestDNSServer(reg registry.Registry, sink telemetry.Sink) *DNSServer {
+	return NewDNSServer(reg, DefaultDNSConfig(), sink, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func dnsQuery(t *testing.T, d *DNSServer, name string, qtype dnsmessage.Type, udp bool) dnsmessage.Message {
+	t.Helper()
+	req := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := req.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := d.Answer(packed, udp)
+	if raw == nil {
+		t.Fatal("expected a response")
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(raw); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.ID != 42 || !resp.Header.Response {
+		t.Fatalf("unexpected header %+v", resp.Header)
+	}
+	return resp
+}
+
+var ordersInstances = map[string][]types.Instance{
+	"orders": {
+		{ServiceID: "orders-1", Address: "10.0.0.5", Port: 8080, Status: types.HealthHealthy, Metadata: map[string]string{"weight": "3"}},
+		{ServiceID: "orders-2", Address: "10.0.0.6", Port: 8081, Status: types.HealthUnhealthy},
+		{ServiceID: "orders-3", Address: "fd00::7", Port: 8082, Status: types.HealthHealthy},
+	},
+	"legacy": {
+		{ServiceID: "legacy-1", Address: "legacy.internal", Port: 80, Status: types.HealthHealthy},
+	},
+}
+
+func TestDNSServer_AddressRecords(t *testing.T) {
+	d := newTestDNSServer(&stubRegistry{instances: ordersInstances}, nil)
+
+	resp := dnsQuery(t, d, "orders.service.mesh.", dnsmessage.TypeA, true)
+	if resp.Header.RCode != dnsmessage.RCodeSuccess || len(resp.Answers) != 1 {
+		t.Fatalf("expected one healthy IPv4 answer, got %v %+v", resp.Header.RCode, resp.Answers)
+	}
+	if a := resp.Answers[0].Body.(*dnsmessage.AResource).A; a != [4]byte{10, 0, 0, 5} {
+		t.Fatalf("unexpected address %v", a)
+	}
+	if ttl := resp.Answers[0].Header.TTL; ttl != 5 {
+		t.Fatalf("expected TTL 5, got %d", ttl)
+	}
+
+	resp = dnsQuery(t, d, "ORDERS.Service.Mesh.", dnsmessage.TypeAAAA, true)
+	if len(resp.Answers) != 1 {
+		t.Fatalf("expected one AAAA answer for a mixed-case name, got %+v", resp.Answers)
+	}
+}
+
+func TestDNSServer_SRVRecords(t *testing.T) {
+	d := newTestDNSServer(&stubRegistry{instances: ordersInstances}, nil)
+
+	for _, name := range []string{"_orders._tcp.service.mesh.", "orders.service.mesh."} {
+		resp := dnsQuery(t, d, name, dnsmessage.TypeSRV, true)
+		if len(resp.Answers) != 2 || len(resp.Additionals) != 2 {
+			t.Fatalf("%s: expected 2 SRV answers with glue, got %+v", name, resp)
+		}
+		var targets []string
+		for _, rr := range resp.Answers {
+			srv := rr.Body.(*dnsmessage.SRVResource)
+			targets = append(targets, fmt.Sprintf("%s:%d/%d", srv.Target, srv.Port, srv.Weight))
+		}
+		slices.Sort(targets)
+		want := []string{"0a000005.addr.mesh.:8080/3", "fd000000000000000000000000000007.addr.mesh.:8082/1"}
+		if !slices.Equal(targets, want) {
+			t.Fatalf("%s: got targets %v, want %v", name, targets, want)
+		}
+	}
+
+	// Hostname addresses are used as the SRV target directly.
+	resp := dnsQuery(t, d, "_legacy._tcp.service.mesh.", dnsmessage.TypeSRV, true)
+	if len(resp.Answers) != 1 || resp.Answers[0].Body.(*dnsmessage.SRVResource).Target.String() != "legacy.internal." {
+		t.Fatalf("unexpected legacy answer %+v", resp.Answers)
+	}
+
+	// SRV targets resolve.
+	resp = dnsQuery(t, d, "0a000005.addr.mesh.", dnsmessage.TypeA, true)
+	if len(resp.Answers) != 1 || resp.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{10, 0, 0, 5} {
+		t.Fatalf("unexpected addr answer %+v", resp.Answers)
+	}
+}
+
+func TestDNSServer_ResponseCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		reg  *stubRegistry
+		want dnsmessage.RCode
+	}{
+		{"payments.service.mesh.", &stubRegistry{instances: ordersInstances}, dnsmessage.RCodeNameError},
+		{"orders.service.example.com.", &stubRegistry{instances: ordersInstances}, dnsmessage.RCodeRefused},
+		{"orders.node.mesh.", &stubRegistry{instances: ordersInstances}, dnsmessage.RCodeNameError},
+		{"zz.addr.mesh.", &stubRegistry{instances: ordersInstances}, dnsmessage.RCodeNameError},
+		{"orders.service.mesh.", &stubRegistry{err: errors.New("consul down")}, dnsmessage.RCodeServerFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := dnsQuery(t, newTestDNSServer(tt.reg, nil), tt.name, dnsmessage.TypeA, true)
+			if resp.Header.RCode != tt.want {
+				t.Errorf("got %v, want %v", resp.Header.RCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNSServer_TruncatesUDP(t *testing.T) {
+	var many []types.Instance
+	for i := range 60 {
+		many = append(many, types.Instance{Address: fmt.Sprintf("10.0.1.%d", i), Port: 80, Status: types.HealthHealthy})
+	}
+	d := newTestDNSServer(&stubRegistry{instances: map[string][]types.Instance{"big": many}}, nil)
+
+	udp := dnsQuery(t, d, "big.service.mesh.", dnsmessage.TypeA, true)
+	if !udp.Header.Truncated || len(udp.Answers) >= 60 {
+		t.Fatalf("expected a truncated UDP answer, got TC=%v with %d answers", udp.Header.Truncated, len(udp.Answers))
+	}
+	tcp := dnsQuery(t, d, "big.service.mesh.", dnsmessage.TypeA, false)
+	if tcp.Header.Truncated || len(tcp.Answers) != 60 {
+		t.Fatalf("expected all 60 answers over TCP, got TC=%v with %d", tcp.Header.Truncated, len(tcp.Answers))
+	}
+}
+
+func TestDNSServer_CountsQueries(t *testing.T) {
+	sink := telemetry.NewPrometheus()
+	d := newTestDNSServer(&stubRegistry{instances: ordersInstances}, sink)
+	dnsQuery(t, d, "orders.service.mesh.", dnsmessage.TypeSRV, true)
+	dnsQuery(t, d, "missing.service.mesh.", dnsmessage.TypeA, true)
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`discovery_dns_queries_total{qtype="SRV",rcode="Success"} 1`,
+		`discovery_dns_queries_total{qtype="A",rcode="NameError"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestDNSServer_CachesLookups(t *testing.T) {
+	reg := &stubRegistry{err: errors.New("consul down")}
+	d := newTestDNSServer(reg, nil)
+
+	// Failed lookups are retried on the next query.
+	dnsQuery(t, d, "orders.service.mesh.", dnsmessage.TypeA, true)
+	reg.err, reg.instances = nil, ordersInstances
+	for range 3 {
+		if resp := dnsQuery(t, d, "orders.service.mesh.", dnsmessage.TypeA, true); len(resp.Answers) != 1 {
+			t.Fatalf("expected one answer, got %+v", resp.Answers)
+		}
+	}
+	if reg.lookups != 2 {
+		t.Fatalf("expected 2 registry lookups, got %d", reg.lookups)
+	}
+
+	// An expired entry is looked up again.
+	d.mu.Lock()
+	entry := d.cache["orders"]
+	entry.expires = time.Now()
+	d.cache["orders"] = entry
+	d.mu.Unlock()
+	dnsQuery(t, d, "orders.service.mesh.", dnsmessage.TypeA, true)
+	if reg.lookups != 3 {
+		t.Fatalf("expected an expired entry to be refreshed, got %d lookups", reg.lookups)
+	}
+}
+
+func TestDNSServer_CacheIsBounded(t *testing.T) {
+	d := newTestDNSServer(&stubRegistry{}, nil)
+	for i := range dnsCacheSize + 10 {
+		dnsQuery(t, d, fmt.Sprintf("svc-%d.service.mesh.", i), dnsmessage.TypeA, true)
+	}
+	if n := len(d.cache); n != dnsCacheSize {
+		t.Fatalf("expected the cache capped at %d, got %d", dnsCacheSize, n)
+	}
+}
@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// Heartbeat keeps TTL checks alive for services over one long-lived stream,
+// so they need not talk to Consul directly. Each ping is applied like
+// ReportHealth (an unset status counts as healthy) and acknowledged on the
+// response stream. Closing the stream does not deregister the instance; its
+// TTL simply stops being renewed.
+func (s *Server) Heartbeat(stream grpc.BidiStreamingServer[pb.HeartbeatRequest, pb.HeartbeatResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &pb.HeartbeatResponse{ServiceId: req.ServiceId}
+		if req.ServiceId == "" {
+			resp.ErrorMessage = "serviceId is required"
+		} else {
+			status := req.Status
+			if status == pb.HealthStatus_HEALTH_STATUS_UNKNOWN {
+				status = pb.HealthStatus_HEALTH_STATUS_HEALTHY
+			}
+			output := req.Output
+			if output == "" {
+				output = "Heartbeat"
+			}
+			health, _ := s.ReportHealth(stream.Context(), &pb.ReportHealthRequest{
+				ServiceId: req.ServiceId,
+				Status:    status,
+				Output:    output,
+			})
+			resp.Acknowledged = health.GetSuccess()
+			if !resp.Acknowledged {
+				resp.ErrorMessage = "failed to renew TTL check"
+			}
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
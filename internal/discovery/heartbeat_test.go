@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestHeartbeat_RenewsTTLChecks(t *testing.T) {
+	// Fake Consul agent recording TTL check updates.
+	var mu sync.Mutex
+	var updates []string
+	consulSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// PassTTL/WarnTTL/FailTTL: PUT /v1/agent/check/{pass,warn,fail}/{id}?note=...
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/v1/agent/check/"); ok {
+			mu.Lock()
+			updates = append(updates, rest+" "+r.URL.Query().Get("note"))
+			mu.Unlock()
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer consulSrv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry, err := consul.NewRegistry(consulSrv.URL, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher, _ := messaging.NewPublisher("", logger)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterDiscoveryRegistryServer(srv, NewServer(registry, publisher, logger))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewDiscoveryRegistryClient(conn).Heartbeat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pings := []*pb.HeartbeatRequest{
+		{ServiceId: "orders-1"},
+		{ServiceId: "orders-1", Status: pb.HealthStatus_HEALTH_STATUS_DEGRADED, Output: "slow disk"},
+		{},
+	}
+	var acks []*pb.HeartbeatResponse
+	for _, ping := range pings {
+		if err := stream.Send(ping); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		acks = append(acks, resp)
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("expected stream to end cleanly, got %v", err)
+	}
+
+	if !acks[0].Acknowledged || !acks[1].Acknowledged {
+		t.Fatalf("expected pings to be acknowledged, got %v", acks)
+	}
+	if acks[2].Acknowledged || acks[2].ErrorMessage == "" {
+		t.Fatalf("expected ping without serviceId to be rejected, got %v", acks[2])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"pass/service:orders-1 Heartbeat", "warn/service:orders-1 slow disk"}
+	if strings.Join(updates, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected TTL updates %v, got %v", want, updates)
+	}
+}
+
+func TestHeartbeat_RenewsBatchRegisteredInstances(t *testing.T) {
+	server, agent := newTestServer(t)
+	if resp, err := server.RegisterBatch(context.Background(), batchOf("orders-1", "orders-2")); err != nil || !resp.Success {
+		t.Fatalf("register batch: %v %v", resp, err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterDiscoveryRegistryServer(srv, server)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewDiscoveryRegistryClient(conn).Heartbeat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"orders-1", "orders-2"} {
+		if err := stream.Send(&pb.HeartbeatRequest{ServiceId: id}); err != nil {
+			t.Fatal(err)
+		}
+		if resp, err := stream.Recv(); err != nil || !resp.Acknowledged {
+			t.Fatalf("expected ping for %s to be acknowledged, got %v (%v)", id, resp, err)
+		}
+	}
+	stream.CloseSend()
+
+	calls := strings.Join(agent.Calls(), "|")
+	for _, want := range []string{"pass/service:orders-1 Heartbeat", "pass/service:orders-2 Heartbeat"} {
+		if !strings.Contains(calls, want) {
+			t.Errorf("expected TTL update %q, got %s", want, calls)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// UpdateMetadata changes an instance's metadata in Consul, re-registering it
+// in place with its health checks kept, and publishes a
+// ServiceMetadataChangedEvent when the metadata actually changed.
+func (s *Server) UpdateMetadata(ctx context.Context, req *pb.UpdateMetadataRequest) (*pb.UpdateMetadataResponse, error) {
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceId is required")
+	}
+
+	var change types.MetadataChange
+	err := s.consulCall(ctx, "apply_metadata", func() (err error) {
+		change, err = s.registry.ApplyMetadata(req.ServiceId, types.MetadataUpdate{
+			Set:     req.Metadata,
+			Remove:  req.RemoveKeys,
+			Replace: req.Replace,
+		})
+		return err
+	})
+	if errors.Is(err, types.ErrNotRegistered) {
+		return nil, status.Errorf(codes.NotFound, "service %s is not registered", req.ServiceId)
+	}
+	if err != nil {
+		s.logger.Error("metadata update failed", "service_id", req.ServiceId, "error", err)
+		return &pb.UpdateMetadataResponse{Success: false, ErrorMessage: err.Error()}, nil
+	}
+
+	// Tracked metadata fills gaps in Consul's on reads, so it must follow the
+	// update or removed keys would reappear.
+	now := time.Now().UTC()
+	s.mu.Lock()
+	if t, ok := s.tracking[req.ServiceId]; ok {
+		t.Metadata = maps.Clone(change.Current)
+		t.LastUpdated = now
+	}
+	s.mu.Unlock()
+
+	if !maps.Equal(change.Previous, change.Current) {
+		if err := s.publish(ctx, messaging.ServiceMetadataChangedEvent{
+			EventID:          fmt.Sprintf("%d", time.Now().UnixNano()),
+			Timestamp:        now,
+			ServiceID:        req.ServiceId,
+			ServiceName:      change.ServiceName,
+			PreviousMetadata: change.Previous,
+			Metadata:         change.Current,
+		}); err != nil {
+			s.logger.Warn("failed to publish metadata change event", "service_id", req.ServiceId, "error", err)
+		}
+		s.logger.Info("service metadata updated", "service_id", req.ServiceId)
+	}
+
+	return &pb.UpdateMetadataResponse{Success: true, Metadata: change.Current}, nil
+}
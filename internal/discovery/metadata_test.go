@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestUpdateMetadata(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	if _, err := server.Register(ctx, &pb.RegisterServiceRequest{
+		ServiceName: "orders",
+		ServiceId:   "orders-1",
+		Address:     "10.0.0.5",
+		Metadata:    map[string]string{"weight": "1", "zone": "eu-west"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  *pb.UpdateMetadataRequest
+		want map[string]string
+	}{
+		{
+			"merge and remove",
+			&pb.UpdateMetadataRequest{ServiceId: "orders-1", Metadata: map[string]string{"weight": "5"}, RemoveKeys: []string{"zone"}},
+			map[string]string{"weight": "5"},
+		},
+		{
+			"replace",
+			&pb.UpdateMetadataRequest{ServiceId: "orders-1", Metadata: map[string]string{"lb_strategy": "LeastConnections"}, Replace: true},
+			map[string]string{"lb_strategy": "LeastConnections"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.UpdateMetadata(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Success || !maps.Equal(resp.Metadata, tt.want) {
+				t.Fatalf("expected metadata %v, got %v", tt.want, resp)
+			}
+			// Removed keys must not come back from the registration-time copy.
+			if got := server.mergeMetadata("orders-1", resp.Metadata); !maps.Equal(got, tt.want) {
+				t.Fatalf("expected tracked metadata %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUpdateMetadata_Errors(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	tests := []struct {
+		name string
+		req  *pb.UpdateMetadataRequest
+		want codes.Code
+	}{
+		{"missing id", &pb.UpdateMetadataRequest{}, codes.InvalidArgument},
+		{"unknown instance", &pb.UpdateMetadataRequest{ServiceId: "ghost-1"}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.UpdateMetadata(context.Background(), tt.req)
+			if status.Code(err) != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package discovery
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// Registry metrics reported to the server's telemetry sink, in addition to
+// the per-RPC metrics recorded by the interceptors:
+//
+//	discovery_registrations_total{outcome}           instances registered
+//	discovery_deregistrations_total{outcome}         instances deregistered
+//	discovery_health_reports_total{status,outcome}   TTL updates by reported status
+//	discovery_tracked_services                       size of the tracking map
+//	discovery_consul_errors_total{operation}         failed Consul calls
+//	discovery_event_publish_failures_total{event}    events not published
+//	discovery_dns_queries_total{qtype,rcode}         DNS interface queries
+//	discovery_expired_instances_total{outcome}       instances removed by cleanup or ExpireInstance
+//	discovery_leader                                 1 while this replica leads
+//	discovery_registration_conflicts_total{kind,resolution} clashing registrations
+//	discovery_restores_total{outcome}                tombstoned instances restored
+//	discovery_tombstones_purged_total                tombstones expired after the grace period
+
+// countOutcome increments name labelled success or failure.
+func (s *Server) countOutcome(name string, err error, labels telemetry.Labels) {
+	l := telemetry.Labels{"outcome": "success"}
+	if err != nil {
+		l["outcome"] = "failure"
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	s.sink.Count(name, 1, l)
+}
+
+// countHealthReport records a TTL update by the reported status.
+func (s *Server) countHealthReport(status types.HealthStatus, err error) {
+	s.countOutcome("discovery_health_reports_total", err, telemetry.Labels{"status": healthStatusName(status)})
+}
+
+// reportTracked publishes the tracking map size. Callers must not hold s.mu.
+func (s *Server) reportTracked() {
+	s.mu.RLock()
+	n := len(s.tracking)
+	s.mu.RUnlock()
+	s.sink.Gauge("discovery_tracked_services", float64(n), nil)
+}
+
+// publish sends an event, counting failures by event type, records it in the
+// service history, and queues it for any webhooks. Failures are still
+// returned so callers can log them with context.
+func (s *Server) publish(ctx context.Context, event any) error {
+	s.recordHistory(event)
+	if s.webhooks != nil {
+		s.webhooks.Notify(event)
+	}
+	err := s.publisher.Publish(ctx, event)
+	if err != nil {
+		s.sink.Count("discovery_event_publish_failures_total", 1, telemetry.Labels{"event": reflect.TypeOf(event).Name()})
+	}
+	return err
+}
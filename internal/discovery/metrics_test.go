@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestServer_RecordsRegistryMetrics(t *testing.T) {
+	ctx := context.Background()
+	scrape := func(sink *telemetry.Prometheus) string {
+		w := httptest.NewRecorder()
+		sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	sink := telemetry.NewPrometheus()
+	server, _ := newTestServer(t)
+	server.sink = sink
+
+	reg, err := server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", Address: "10.0.0.5", Port: 8080})
+	if err != nil || !reg.Success {
+		t.Fatalf("Register: %v %v", reg, err)
+	}
+	server.ReportHealth(ctx, &pb.ReportHealthRequest{ServiceId: reg.ServiceId, Status: pb.HealthStatus_HEALTH_STATUS_DEGRADED})
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: reg.ServiceId})
+
+	failing := telemetry.NewPrometheus()
+	broken, _ := newTestServer(t, "orders-bad")
+	broken.sink = failing
+	broken.Register(ctx, &pb.RegisterServiceRequest{ServiceId: "orders-bad", ServiceName: "orders", Address: "10.0.0.5", Port: 8080})
+
+	tests := []struct {
+		sink *telemetry.Prometheus
+		want string
+	}{
+		{sink, `discovery_registrations_total{outcome="success"} 1`},
+		{sink, `discovery_deregistrations_total{outcome="success"} 1`},
+		{sink, `discovery_health_reports_total{outcome="success",status="Degraded"} 1`},
+		{sink, `discovery_tracked_services 1`},
+		{failing, `discovery_registrations_total{outcome="failure"} 1`},
+		{failing, `discovery_consul_errors_total{operation="register"} 1`},
+	}
+	for _, tt := range tests {
+		if body := scrape(tt.sink); !strings.Contains(body, tt.want) {
+			t.Errorf("expected %q in:\n%s", tt.want, body)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package discovery
+
+import (
+	"google.golang.org/grpc"
+
+	// Register the gzip compressor so clients may send and request
+	// compressed payloads.
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// DefaultMaxMsgSize is the default maximum gRPC message size (16MB).
+// The gRPC default of 4MB is too small for catalog responses in large meshes.
+const DefaultMaxMsgSize = 16 << 20
+
+// GRPCConfig controls transport-level limits for the DiscoveryRegistry server.
+type GRPCConfig struct {
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+}
+
+// DefaultGRPCConfig returns the default transport limits.
+func DefaultGRPCConfig() GRPCConfig {
+	return GRPCConfig{
+		MaxRecvMsgSize: DefaultMaxMsgSize,
+		MaxSendMsgSize: DefaultMaxMsgSize,
+	}
+}
+
+// ServerOptions converts the config into gRPC server options.
+// Non-positive sizes leave the gRPC defaults in place.
+func (c GRPCConfig) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+	return opts
+}
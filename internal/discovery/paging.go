@@ -0,0 +1,87 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// maxPageSize caps pageSize on discovery queries.
+const maxPageSize = 1000
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// paginate returns one page of items, ordered by key. Page tokens encode the
+// last key returned rather than an offset, so registrations and
+// deregistrations between calls neither skip nor repeat items. A pageSize of
+// 0, or one above maxPageSize, returns maxPageSize items.
+func paginate[T any](items []T, key func(T) string, pageSize int32, token string) ([]T, string, error) {
+	if pageSize < 0 {
+		return nil, "", fmt.Errorf("pageSize must not be negative")
+	}
+	if pageSize == 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	sort.Slice(items, func(i, j int) bool { return key(items[i]) < key(items[j]) })
+
+	if token != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(raw) == 0 {
+			return nil, "", errInvalidPageToken
+		}
+		after := string(raw)
+		start := sort.Search(len(items), func(i int) bool { return key(items[i]) > after })
+		items = items[start:]
+	}
+
+	if int(pageSize) >= len(items) {
+		return items, "", nil
+	}
+	page := items[:pageSize]
+	next := base64.RawURLEncoding.EncodeToString([]byte(key(page[len(page)-1])))
+	return page, next, nil
+}
+
+// instanceFields resolves a field mask against ServiceInstance. Only
+// top-level fields may be selected; a nil result means all fields.
+func instanceFields(mask *fieldmaskpb.FieldMask) ([]protoreflect.FieldDescriptor, error) {
+	if len(mask.GetPaths()) == 0 {
+		return nil, nil
+	}
+	desc := (&pb.ServiceInstance{}).ProtoReflect().Descriptor().Fields()
+	fields := make([]protoreflect.FieldDescriptor, 0, len(mask.GetPaths()))
+	for _, path := range mask.GetPaths() {
+		fd := desc.ByJSONName(path)
+		if fd == nil {
+			fd = desc.ByName(protoreflect.Name(path))
+		}
+		if fd == nil || strings.Contains(path, ".") {
+			return nil, fmt.Errorf("unknown ServiceInstance field %q in field mask", path)
+		}
+		fields = append(fields, fd)
+	}
+	return fields, nil
+}
+
+// maskInstance returns a copy of inst holding only fields; nil fields
+// returns inst unchanged.
+func maskInstance(inst *pb.ServiceInstance, fields []protoreflect.FieldDescriptor) *pb.ServiceInstance {
+	if fields == nil {
+		return inst
+	}
+	out := &pb.ServiceInstance{}
+	src, dst := inst.ProtoReflect(), out.ProtoReflect()
+	for _, fd := range fields {
+		if src.Has(fd) {
+			dst.Set(fd, src.Get(fd))
+		}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestPaginate(t *testing.T) {
+	identity := func(s string) string { return s }
+	items := []string{"e", "b", "d", "a", "c"}
+
+	var got []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, next, err := paginate(items, identity, 2, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.Join(page, ""))
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if strings.Join(got, "|") != "ab|cd|e" {
+		t.Fatalf("expected pages ab|cd|e, got %s", strings.Join(got, "|"))
+	}
+
+	// Items removed between pages do not shift the next page.
+	_, next, _ := paginate([]string{"a", "b", "c", "d"}, identity, 2, "")
+	page, _, _ := paginate([]string{"b", "c", "d"}, identity, 2, next)
+	if strings.Join(page, "") != "cd" {
+		t.Fatalf("expected cd after removing a, got %v", page)
+	}
+
+	if all, next, _ := paginate(items, identity, 0, ""); len(all) != 5 || next != "" {
+		t.Fatalf("expected a short list to fit the default page, got %v %q", all, next)
+	}
+	many := make([]string, maxPageSize+5)
+	for i := range many {
+		many[i] = fmt.Sprintf("%05d", i)
+	}
+	for _, size := range []int32{0, maxPageSize + 1, 1 << 30} {
+		page, next, _ := paginate(many, identity, size, "")
+		if len(page) != maxPageSize || next == "" {
+			t.Fatalf("page size %d: expected %d items and a next token, got %d %q", size, maxPageSize, len(page), next)
+		}
+		if rest, next, _ := paginate(many, identity, size, next); len(rest) != 5 || next != "" {
+			t.Fatalf("page size %d: expected the last 5 items, got %d %q", size, len(rest), next)
+		}
+	}
+	if _, _, err := paginate(items, identity, 2, "!!"); err == nil {
+		t.Fatal("expected an invalid token to be rejected")
+	}
+}
+
+func TestGetInstances_PagesAndMasksFields(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		server.Register(ctx, &pb.RegisterServiceRequest{
+			ServiceName: "orders",
+			ServiceId:   fmt.Sprintf("orders-%d", i),
+			Address:     "10.0.0.5",
+			Port:        8080,
+			Metadata:    map[string]string{"zone": "eu-west"},
+		})
+	}
+
+	req := &pb.GetInstancesRequest{
+		ServiceName: "orders",
+		PageSize:    2,
+		FieldMask:   &fieldmaskpb.FieldMask{Paths: []string{"serviceId", "port"}},
+	}
+	first, err := server.GetInstances(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Instances) != 2 || first.NextPageToken == "" {
+		t.Fatalf("expected a full first page with a token, got %v", first)
+	}
+	inst := first.Instances[0]
+	if inst.ServiceId != "orders-1" || inst.Port != 8080 || inst.Address != "" || inst.Metadata != nil || inst.RegisteredAt != nil {
+		t.Fatalf("expected only serviceId and port, got %v", inst)
+	}
+
+	req.PageToken = first.NextPageToken
+	second, err := server.GetInstances(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Instances) != 1 || second.Instances[0].ServiceId != "orders-3" || second.NextPageToken != "" {
+		t.Fatalf("expected the last instance with no further token, got %v", second)
+	}
+
+	for _, bad := range []*pb.GetInstancesRequest{
+		{ServiceName: "orders", FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"bogus"}}},
+		{ServiceName: "orders", FieldMask: &fieldmaskpb.FieldMask{Paths: []string{"registeredAt.seconds"}}},
+		{ServiceName: "orders", PageToken: "%%%"},
+	} {
+		if _, err := server.GetInstances(ctx, bad); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for %v, got %v", bad, err)
+		}
+	}
+}
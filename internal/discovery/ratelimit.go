@@ -0,0 +1,185 @@
+package discovery
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+// DefaultRateLimitMaxClients bounds the number of clients a RateLimiter tracks.
+const DefaultRateLimitMaxClients = 10_000
+
+// RateLimitConfig controls per-client rate limiting of the RPCs that write to
+// Consul. Clients are keyed by authenticated identity when auth is enabled,
+// otherwise by peer IP.
+type RateLimitConfig struct {
+	Enabled       bool
+	PermitLimit   int // calls per window per client
+	WindowSeconds int
+	// MaxClients caps tracked clients; the least recently seen client is
+	// forgotten beyond it. Zero uses DefaultRateLimitMaxClients.
+	MaxClients int
+	// Methods lists the limited RPCs. Heartbeat is limited per message.
+	Methods []string
+}
+
+// DefaultRateLimitConfig returns a limit generous enough for one host running
+// many instances with short TTLs, while still stopping runaway loops.
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Enabled:       true,
+		PermitLimit:   600,
+		WindowSeconds: 60,
+		MaxClients:    DefaultRateLimitMaxClients,
+		Methods:       []string{"Register", "RegisterBatch", "ReportHealth", "Heartbeat"},
+	}
+}
+
+// RateLimiter applies fixed-window per-client limits to DiscoveryRegistry
+// RPCs. Windows are kept in least-recently-used order and capped, like the
+// gateway's limiter, so spoofed or short-lived clients cannot grow memory.
+type RateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*list.Element // values are *rateWindow
+	lru     *list.List               // front is most recently used
+	config  RateLimitConfig
+	window  time.Duration
+	sink    telemetry.Sink
+	now     func() time.Time
+}
+
+type rateWindow struct {
+	key     string
+	count   int
+	resetAt time.Time
+}
+
+// NewRateLimiter creates a rate limiter. It returns nil when the config is
+// disabled; a nil RateLimiter allows every call.
+func NewRateLimiter(config RateLimitConfig, sink telemetry.Sink) *RateLimiter {
+	if !config.Enabled || config.PermitLimit <= 0 {
+		return nil
+	}
+	if config.MaxClients <= 0 {
+		config.MaxClients = DefaultRateLimitMaxClients
+	}
+	if config.WindowSeconds <= 0 {
+		config.WindowSeconds = 60
+	}
+	return &RateLimiter{
+		windows: make(map[string]*list.Element),
+		lru:     list.New(),
+		config:  config,
+		window:  time.Duration(config.WindowSeconds) * time.Second,
+		sink:    telemetry.OrNop(sink),
+		now:     time.Now,
+	}
+}
+
+// Allow charges one call to method against the caller in ctx and returns a
+// ResourceExhausted error once the caller is over its limit. Methods not in
+// the config are always allowed.
+func (rl *RateLimiter) Allow(ctx context.Context, method string) error {
+	if rl == nil || !slices.Contains(rl.config.Methods, method) {
+		return nil
+	}
+	if rl.allow(rateLimitKey(ctx)) {
+		return nil
+	}
+	rl.sink.Count("discovery_ratelimit_rejected_total", 1, telemetry.Labels{"method": method})
+	return status.Errorf(codes.ResourceExhausted, "rate limit of %d %s calls per %s exceeded", rl.config.PermitLimit, method, rl.window)
+}
+
+func (rl *RateLimiter) allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	el, ok := rl.windows[key]
+	if !ok {
+		if len(rl.windows) >= rl.config.MaxClients {
+			oldest := rl.lru.Back()
+			rl.lru.Remove(oldest)
+			delete(rl.windows, oldest.Value.(*rateWindow).key)
+		}
+		rl.windows[key] = rl.lru.PushFront(&rateWindow{key: key, count: 1, resetAt: now.Add(rl.window)})
+		return true
+	}
+
+	rl.lru.MoveToFront(el)
+	w := el.Value.(*rateWindow)
+	if now.After(w.resetAt) {
+		w.count, w.resetAt = 1, now.Add(rl.window)
+		return true
+	}
+	if w.count >= rl.config.PermitLimit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// rateLimitKey identifies the caller: its authenticated identity if any,
+// otherwise its IP. Ports are dropped so reconnecting does not reset the limit.
+func rateLimitKey(ctx context.Context) string {
+	if id := IdentityFromContext(ctx); id != "" {
+		return "id:" + id
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		return "ip:" + addr
+	}
+	return "unknown"
+}
+
+// UnaryInterceptor enforces Allow on unary DiscoveryRegistry RPCs. It must run
+// after the auth interceptor so callers are keyed by identity.
+func (rl *RateLimiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if method, ok := strings.CutPrefix(info.FullMethod, registryServicePrefix); ok {
+			if err := rl.Allow(ctx, method); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor enforces Allow on every message received on a limited
+// streaming RPC, ending the stream once the caller is over its limit.
+func (rl *RateLimiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		method, ok := strings.CutPrefix(info.FullMethod, registryServicePrefix)
+		if !ok || rl == nil || !slices.Contains(rl.config.Methods, method) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &rateLimitedStream{ServerStream: ss, limiter: rl, method: method})
+	}
+}
+
+type rateLimitedStream struct {
+	grpc.ServerStream
+	limiter *RateLimiter
+	method  string
+}
+
+func (s *rateLimitedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.limiter.Allow(s.Context(), s.method)
+}
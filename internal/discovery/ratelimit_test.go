@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func peerContext(addr string) context.Context {
+	tcp, _ := net.ResolveTCPAddr("tcp", addr)
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: tcp})
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	cfg := DefaultRateLimitConfig()
+	cfg.PermitLimit = 2
+	rl := NewRateLimiter(cfg, nil)
+	now := time.Unix(1_700_000_000, 0)
+	rl.now = func() time.Time { return now }
+
+	a1 := peerContext("10.0.0.1:5000")
+	a2 := peerContext("10.0.0.1:5001") // same host, new connection
+	b := peerContext("10.0.0.2:5000")
+	ops := context.WithValue(a1, identityKey{}, "ops")
+
+	steps := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{"first", a1, "Register", codes.OK},
+		{"second", a2, "ReportHealth", codes.OK},
+		{"over limit", a1, "Register", codes.ResourceExhausted},
+		{"other port same ip", a2, "Register", codes.ResourceExhausted},
+		{"unlimited method", a1, "GetInstances", codes.OK},
+		{"other client", b, "Register", codes.OK},
+		{"identity keyed separately", ops, "Register", codes.OK},
+	}
+	for _, step := range steps {
+		if got := status.Code(rl.Allow(step.ctx, step.method)); got != step.wantCode {
+			t.Fatalf("%s: expected %s, got %s", step.name, step.wantCode, got)
+		}
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if err := rl.Allow(a1, "Register"); err != nil {
+		t.Fatalf("expected a new window to allow calls, got %v", err)
+	}
+}
+
+func TestRateLimiter_EvictsLeastRecentlySeen(t *testing.T) {
+	cfg := DefaultRateLimitConfig()
+	cfg.PermitLimit = 1
+	cfg.MaxClients = 2
+	rl := NewRateLimiter(cfg, nil)
+
+	a, b, c := peerContext("10.0.0.1:1"), peerContext("10.0.0.2:1"), peerContext("10.0.0.3:1")
+	rl.Allow(a, "Register")
+	rl.Allow(b, "Register")
+	rl.Allow(c, "Register") // evicts a
+
+	if len(rl.windows) != 2 {
+		t.Fatalf("expected 2 tracked clients, got %d", len(rl.windows))
+	}
+	if err := rl.Allow(a, "Register"); err != nil {
+		t.Fatalf("expected evicted client to start a new window, got %v", err)
+	}
+}
+
+func TestNewRateLimiter_Disabled(t *testing.T) {
+	cfg := DefaultRateLimitConfig()
+	cfg.Enabled = false
+	rl := NewRateLimiter(cfg, nil)
+	if rl != nil {
+		t.Fatal("expected nil limiter when disabled")
+	}
+	if err := rl.Allow(context.Background(), "Register"); err != nil {
+		t.Fatalf("expected nil limiter to allow calls, got %v", err)
+	}
+}
+
+func TestRateLimiter_Interceptors(t *testing.T) {
+	server, _ := newTestServer(t)
+	cfg := DefaultRateLimitConfig()
+	cfg.PermitLimit = 2
+	rl := NewRateLimiter(cfg, nil)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(rl.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(rl.StreamInterceptor()),
+	)
+	pb.RegisterDiscoveryRegistryServer(srv, server)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewDiscoveryRegistryClient(conn)
+
+	ctx := context.Background()
+	if _, err := client.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", Address: "10.0.0.5", Port: 8080}); err != nil {
+		t.Fatal(err)
+	}
+
+	// One permit is left: the first heartbeat is acknowledged, the second
+	// ends the stream.
+	stream, err := client.Heartbeat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&pb.HeartbeatRequest{ServiceId: "orders-1"})
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("expected first heartbeat to be acknowledged, got %v", err)
+	}
+	stream.Send(&pb.HeartbeatRequest{ServiceId: "orders-1"})
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected heartbeat stream to be limited, got %v", err)
+	}
+
+	if _, err := client.ReportHealth(ctx, &pb.ReportHealthRequest{ServiceId: "orders-1"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ReportHealth to be limited, got %v", err)
+	}
+}
+
+func TestRESTHandlerWithOptions_RateLimit(t *testing.T) {
+	cfg := DefaultRateLimitConfig()
+	cfg.PermitLimit = 1
+	handler := NewRESTHandlerWithOptions(&fakeRegistry{}, RESTOptions{RateLimiter: NewRateLimiter(cfg, nil)})
+
+	want := []int{http.StatusOK, http.StatusTooManyRequests}
+	for i, code := range want {
+		req := httptest.NewRequest(http.MethodPost, RESTPrefix+"/register", strings.NewReader(`{"serviceName":"orders"}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Fatalf("request %d: expected status %d, got %d", i, code, rec.Code)
+		}
+	}
+}
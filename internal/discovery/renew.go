@@ -0,0 +1,123 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// AutoRenewMetadataKey marks an instance registered with autoRenew for a
+// service in Options.AutoRenewServices. It is kept in the registry so every
+// replica, and the leader after a failover, knows which TTL checks discovery
+// owns. Registrants cannot set it themselves.
+const AutoRenewMetadataKey = "ttl_auto_renew"
+
+// RenewConfig controls server-side TTL renewal for autoRenew registrations.
+type RenewConfig struct {
+	// HealthMonitorURL is the base URL of the HealthMonitor HTTP API whose
+	// /api/status results drive renewal.
+	HealthMonitorURL string
+	// Interval between renewals. Keep it well below the TTL (35s by default).
+	Interval time.Duration
+	// StaleAfter ignores probe results older than this, so a stalled
+	// HealthMonitor lets checks expire instead of reporting old results.
+	StaleAfter time.Duration
+	// Timeout bounds each HealthMonitor request.
+	Timeout time.Duration
+}
+
+// DefaultRenewConfig returns the renewal defaults.
+func DefaultRenewConfig() RenewConfig {
+	return RenewConfig{
+		HealthMonitorURL: "http://localhost:5005",
+		Interval:         10 * time.Second,
+		StaleAfter:       90 * time.Second,
+		Timeout:          5 * time.Second,
+	}
+}
+
+// RunRenewal renews the TTL checks of autoRenew instances every Interval
+// until ctx is cancelled, reporting each instance's latest probe status
+// through the registry, which updates the check on the agent that owns the
+// instance. Instances the HealthMonitor has not probed, or cannot probe, are
+// left to expire. Only the leader renews. Health change events are left to
+// the HealthMonitor, which publishes them for every probed instance.
+func (s *Server) RunRenewal(ctx context.Context, cfg RenewConfig) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !s.isLeader() {
+			continue
+		}
+		monitored, err := fetchProbeResults(ctx, client, cfg.HealthMonitorURL)
+		if err != nil {
+			s.logger.Warn("ttl renewal skipped", "error", err)
+			continue
+		}
+		s.renew(ctx, monitored, cfg.StaleAfter, time.Now())
+	}
+}
+
+// renew reports the probe status of each fresh autoRenew result. The marker
+// is checked against Options.AutoRenewServices too, in case an instance was
+// registered with it directly in the registry.
+func (s *Server) renew(ctx context.Context, monitored []healthmonitor.MonitoredInstance, staleAfter time.Duration, now time.Time) {
+	for _, inst := range monitored {
+		if inst.Metadata[AutoRenewMetadataKey] != "true" || !s.autoRenew[inst.ServiceName] {
+			continue
+		}
+		if inst.Status == types.HealthUnknown || now.Sub(inst.LastProbe) > staleAfter {
+			continue
+		}
+		output := fmt.Sprintf("Renewed by discovery: %s probe, %s", inst.ProbeType, inst.Message)
+		err := s.consulCall(ctx, "update_health", func() error { return s.registry.UpdateHealth(inst.ServiceID, inst.Status, output) })
+		s.countHealthReport(inst.Status, err)
+		if err != nil {
+			s.logger.Warn("ttl renewal failed", "service_id", inst.ServiceID, "error", err)
+			continue
+		}
+
+		checked := now.UTC()
+		s.mu.Lock()
+		if t, ok := s.tracking[inst.ServiceID]; ok {
+			t.Status = inst.Status
+			t.LastHealthCheck = &checked
+			t.LastUpdated = checked
+		}
+		s.mu.Unlock()
+	}
+}
+
+func fetchProbeResults(ctx context.Context, client *http.Client, baseURL string) ([]healthmonitor.MonitoredInstance, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/api/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build healthmonitor request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query healthmonitor: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("healthmonitor returned HTTP %d", resp.StatusCode)
+	}
+
+	var instances []healthmonitor.MonitoredInstance
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		return nil, fmt.Errorf("decode healthmonitor status: %w", err)
+	}
+	return instances, nil
+}
@@ -0,0 +1,98 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestRegister_AutoRenewMarksMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		service   string
+		autoRenew bool
+		metadata  map[string]string
+		want      bool
+	}{
+		{"configured service", "batch", true, nil, true},
+		{"service not configured", "orders", true, nil, false},
+		{"marker set by the registrant", "orders", false, map[string]string{AutoRenewMetadataKey: "true"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t)
+			server.autoRenew = map[string]bool{"batch": true}
+			if _, err := server.Register(context.Background(), &pb.RegisterServiceRequest{
+				ServiceName: tt.service,
+				ServiceId:   tt.service + "-1",
+				Address:     "10.0.0.5",
+				Port:        8080,
+				Metadata:    tt.metadata,
+				HealthCheck: &pb.HealthCheckConfig{Endpoint: "/healthz"},
+				AutoRenew:   tt.autoRenew,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			meta := server.tracking[tt.service+"-1"].Metadata
+			if got := meta[AutoRenewMetadataKey] == "true"; got != tt.want {
+				t.Fatalf("expected auto-renew %v, got metadata %v", tt.want, meta)
+			}
+			if tt.want && meta["health_check_endpoint"] != "/healthz" {
+				t.Fatalf("expected probe metadata, got %v", meta)
+			}
+		})
+	}
+}
+
+func TestRenew_ReportsFreshProbeResults(t *testing.T) {
+	server, agent := newTestServer(t)
+	server.autoRenew = map[string]bool{"batch": true}
+	now := time.Now()
+	autoRenew := map[string]string{AutoRenewMetadataKey: "true"}
+
+	server.renew(context.Background(), []healthmonitor.MonitoredInstance{
+		{ServiceID: "batch-1", ServiceName: "batch", Status: healthmonitor.StatusHealthy, LastProbe: now, ProbeType: "http", Message: "HTTP 200", Metadata: autoRenew},
+		{ServiceID: "batch-2", ServiceName: "batch", Status: healthmonitor.StatusUnhealthy, LastProbe: now, ProbeType: "tcp", Message: "refused", Metadata: autoRenew},
+		{ServiceID: "batch-3", ServiceName: "batch", Status: healthmonitor.StatusHealthy, LastProbe: now.Add(-time.Hour), Metadata: autoRenew},
+		{ServiceID: "batch-4", ServiceName: "batch", Status: healthmonitor.StatusUnknown, LastProbe: now, Metadata: autoRenew},
+		{ServiceID: "orders-1", ServiceName: "orders", Status: healthmonitor.StatusHealthy, LastProbe: now},
+		// Marked directly in the registry for a service not configured.
+		{ServiceID: "orders-2", ServiceName: "orders", Status: healthmonitor.StatusHealthy, LastProbe: now, Metadata: autoRenew},
+	}, time.Minute, now)
+
+	got := strings.Join(agent.Calls(), "|")
+	want := "pass/service:batch-1 Renewed by discovery: http probe, HTTP 200|fail/service:batch-2 Renewed by discovery: tcp probe, refused"
+	if got != want {
+		t.Fatalf("got calls %q, want %q", got, want)
+	}
+}
+
+func TestFetchProbeResults(t *testing.T) {
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/status" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode([]healthmonitor.MonitoredInstance{{ServiceID: "batch-1", Status: healthmonitor.StatusDegraded}})
+	}))
+	defer monitor.Close()
+
+	got, err := fetchProbeResults(context.Background(), monitor.Client(), monitor.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ServiceID != "batch-1" || got[0].Status != healthmonitor.StatusDegraded {
+		t.Fatalf("unexpected results %+v", got)
+	}
+
+	if _, err := fetchProbeResults(context.Background(), monitor.Client(), monitor.URL+"/missing"); err == nil {
+		t.Fatal("expected an error for a non-200 response")
+	}
+}
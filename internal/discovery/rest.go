@@ -0,0 +1,352 @@
+package discovery
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// RESTPrefix is where the HTTP/JSON API is mounted. It matches the path the
+// gateway dashboard proxies discovery requests to.
+const RESTPrefix = "/api/ServiceDiscovery"
+
+// maxRESTBodyBytes bounds request bodies on the HTTP/JSON API.
+const maxRESTBodyBytes = 1 << 20
+
+var (
+	restMarshal   = protojson.MarshalOptions{EmitUnpopulated: true}
+	restUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// NewRESTHandler exposes the DiscoveryRegistry as HTTP/JSON for clients that
+// cannot speak gRPC, such as the dashboard. Messages use the protobuf JSON
+// mapping, so request and response bodies match the gRPC API field for field:
+//
+//	GET  /api/ServiceDiscovery/services                             GetServices
+//	GET  /api/ServiceDiscovery/services/{serviceName}/instances     GetInstances
+//	GET  /api/ServiceDiscovery/instances/{serviceId}                GetInstance
+//	POST /api/ServiceDiscovery/register                             Register
+//	POST /api/ServiceDiscovery/deregister                           Deregister
+//	POST /api/ServiceDiscovery/services/{serviceName}/deregister    DeregisterByServiceName
+//	POST /api/ServiceDiscovery/restore                              Restore
+//	POST /api/ServiceDiscovery/health                               ReportHealth
+//	GET  /api/ServiceDiscovery/admin/tracking                       ListTracking
+//	POST /api/ServiceDiscovery/admin/tracking/rebuild               RebuildTracking
+//	POST /api/ServiceDiscovery/admin/expire                         ExpireInstance
+//	GET  /api/ServiceDiscovery/admin/services/{serviceName}/history GetServiceHistory
+//
+// On the GET endpoints, query parameters fill request fields: pageSize and
+// pageToken on services and instances, plus selector (repeatable),
+// healthyOnly, and fields (a comma-separated field mask) on instances, and
+// serviceName on admin/tracking. The body of the service-name deregister
+// endpoint is optional and may carry a reason.
+func NewRESTHandler(svc pb.DiscoveryRegistryServer) http.Handler {
+	return NewRESTHandlerWithAuth(svc, nil)
+}
+
+// NewRESTHandlerWithAuth is NewRESTHandler with each endpoint authorized as
+// its RPC. Callers send "Authorization: Bearer <token>" or present a client
+// certificate; a nil authz allows every call except the admin RPCs.
+func NewRESTHandlerWithAuth(svc pb.DiscoveryRegistryServer, authz *Authorizer) http.Handler {
+	return NewRESTHandlerWithOptions(svc, RESTOptions{Authorizer: authz})
+}
+
+// RESTOptions holds the per-call policies the gRPC interceptors apply, so
+// the HTTP/JSON API enforces the same ones.
+type RESTOptions struct {
+	Authorizer  *Authorizer
+	RateLimiter *RateLimiter
+	// Tracer starts a server span per request, parented by its traceparent
+	// header and recording any X-Correlation-ID.
+	Tracer *telemetry.Tracer
+}
+
+// NewRESTHandlerWithOptions is NewRESTHandler with each endpoint authorized,
+// rate limited, and traced as its RPC.
+func NewRESTHandlerWithOptions(svc pb.DiscoveryRegistryServer, opts RESTOptions) http.Handler {
+	mux := &restMux{ServeMux: http.NewServeMux(), opts: opts}
+
+	mux.handle("GET "+RESTPrefix+"/services", "GetServices", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		resp, err := svc.GetServices(restContext(r), &pb.GetServicesRequest{
+			PageSize:  restPageSize(q),
+			PageToken: q.Get("pageToken"),
+		})
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("GET "+RESTPrefix+"/services/{serviceName}/instances", "GetInstances", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := &pb.GetInstancesRequest{
+			ServiceName: r.PathValue("serviceName"),
+			Selectors:   q["selector"],
+			HealthyOnly: q.Get("healthyOnly") == "true",
+			PageSize:    restPageSize(q),
+			PageToken:   q.Get("pageToken"),
+		}
+		if fields := q.Get("fields"); fields != "" {
+			req.FieldMask = &fieldmaskpb.FieldMask{Paths: strings.Split(fields, ",")}
+		}
+		resp, err := svc.GetInstances(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("GET "+RESTPrefix+"/instances/{serviceId}", "GetInstance", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := svc.GetInstance(restContext(r), &pb.GetInstanceRequest{ServiceId: r.PathValue("serviceId")})
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/register", "Register", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.RegisterServiceRequest{}
+		if !readREST(w, r, req) {
+			return
+		}
+		if req.ServiceName == "" {
+			http.Error(w, "serviceName is required", http.StatusBadRequest)
+			return
+		}
+		resp, err := svc.Register(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/deregister", "Deregister", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.DeregisterServiceRequest{}
+		if !readREST(w, r, req) {
+			return
+		}
+		if req.ServiceId == "" {
+			http.Error(w, "serviceId is required", http.StatusBadRequest)
+			return
+		}
+		resp, err := svc.Deregister(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/services/{serviceName}/deregister", "DeregisterByServiceName", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.DeregisterByServiceNameRequest{}
+		if r.ContentLength != 0 && !readREST(w, r, req) {
+			return
+		}
+		req.ServiceName = r.PathValue("serviceName")
+		resp, err := svc.DeregisterByServiceName(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/restore", "Restore", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.RestoreServiceRequest{}
+		if !readREST(w, r, req) {
+			return
+		}
+		if req.ServiceId == "" {
+			http.Error(w, "serviceId is required", http.StatusBadRequest)
+			return
+		}
+		resp, err := svc.Restore(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/health", "ReportHealth", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.ReportHealthRequest{}
+		if !readREST(w, r, req) {
+			return
+		}
+		if req.ServiceId == "" {
+			http.Error(w, "serviceId is required", http.StatusBadRequest)
+			return
+		}
+		resp, err := svc.ReportHealth(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("GET "+RESTPrefix+"/admin/tracking", "ListTracking", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := svc.ListTracking(restContext(r), &pb.ListTrackingRequest{ServiceName: r.URL.Query().Get("serviceName")})
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/admin/tracking/rebuild", "RebuildTracking", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := svc.RebuildTracking(restContext(r), &pb.RebuildTrackingRequest{})
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("POST "+RESTPrefix+"/admin/expire", "ExpireInstance", func(w http.ResponseWriter, r *http.Request) {
+		req := &pb.ExpireInstanceRequest{}
+		if !readREST(w, r, req) {
+			return
+		}
+		if req.ServiceId == "" {
+			http.Error(w, "serviceId is required", http.StatusBadRequest)
+			return
+		}
+		resp, err := svc.ExpireInstance(restContext(r), req)
+		writeREST(w, resp, err)
+	})
+
+	mux.handle("GET "+RESTPrefix+"/admin/services/{serviceName}/history", "GetServiceHistory", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := svc.GetServiceHistory(restContext(r), &pb.GetServiceHistoryRequest{ServiceName: r.PathValue("serviceName")})
+		writeREST(w, resp, err)
+	})
+
+	return mux
+}
+
+// restMux registers endpoints behind the tracer, authorizer, and rate
+// limiter, replacing the request context with the authorized one.
+type restMux struct {
+	*http.ServeMux
+	opts RESTOptions
+}
+
+func (m *restMux) handle(pattern, method string, fn http.HandlerFunc) {
+	m.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if m.opts.Tracer != nil {
+			ctx := r.Context()
+			if parent, ok := telemetry.Extract(r.Header.Get); ok {
+				ctx = telemetry.ContextWithRemoteParent(ctx, parent)
+			}
+			ctx, span := m.opts.Tracer.Start(ctx, registryServicePrefix[1:]+method, telemetry.SpanKindServer)
+			span.SetAttribute("rpc.system", "http")
+			span.SetAttribute("http.method", r.Method)
+			span.SetAttribute("http.route", pattern)
+			span.SetAttribute("net.peer.address", r.RemoteAddr)
+			telemetry.SetCorrelationID(span, r.Header.Get)
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				span.SetAttribute("http.status_code", strconv.Itoa(rec.status))
+				var err error
+				if rec.status >= 500 {
+					err = errors.New(http.StatusText(rec.status))
+				}
+				span.End(err)
+			}()
+			w, r = rec, r.WithContext(ctx)
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+		}
+		var chains [][]*x509.Certificate
+		if r.TLS != nil {
+			chains = r.TLS.VerifiedChains
+		}
+		ctx, err := m.opts.Authorizer.Authorize(restContext(r), method, token, chains)
+		if err == nil {
+			err = m.opts.RateLimiter.Allow(ctx, method)
+		}
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), httpStatusFromError(err))
+			return
+		}
+		fn(w, r.WithContext(ctx))
+	})
+}
+
+// statusRecorder captures the response status for the request span.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// restPageSize reads the pageSize query parameter; invalid values mean the
+// default page size, matching an unset field.
+func restPageSize(q url.Values) int32 {
+	n, err := strconv.ParseInt(q.Get("pageSize"), 10, 32)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return int32(n)
+}
+
+// restContext carries the HTTP client address as the gRPC peer, so Register
+// resolves loopback addresses the same way for both transports.
+func restContext(r *http.Request) context.Context {
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return r.Context()
+	}
+	return peer.NewContext(r.Context(), &peer.Peer{Addr: addr})
+}
+
+func readREST(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRESTBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := restUnmarshal.Unmarshal(body, msg); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeREST(w http.ResponseWriter, msg proto.Message, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), httpStatusFromError(err))
+		return
+	}
+	body, err := restMarshal.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// httpStatusFromError maps gRPC status codes to HTTP. Errors without a
+// status come from Consul, so they are reported as a bad upstream.
+func httpStatusFromError(err error) int {
+	st, ok := status.FromError(err)
+	if !ok {
+		return http.StatusBadGateway
+	}
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,127 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// fakeRegistry records the requests it receives.
+type fakeRegistry struct {
+	pb.UnimplementedDiscoveryRegistryServer
+	registered *pb.RegisterServiceRequest
+	peerAddr   string
+}
+
+func (f *fakeRegistry) Register(ctx context.Context, req *pb.RegisterServiceRequest) (*pb.RegisterServiceResponse, error) {
+	f.registered = req
+	if p, ok := peer.FromContext(ctx); ok {
+		f.peerAddr = p.Addr.String()
+	}
+	return &pb.RegisterServiceResponse{Success: true, ServiceId: "orders-1"}, nil
+}
+
+func (f *fakeRegistry) Deregister(ctx context.Context, req *pb.DeregisterServiceRequest) (*pb.DeregisterServiceResponse, error) {
+	return nil, status.Error(codes.NotFound, "no such service")
+}
+
+func (f *fakeRegistry) DeregisterByServiceName(ctx context.Context, req *pb.DeregisterByServiceNameRequest) (*pb.DeregisterByServiceNameResponse, error) {
+	return &pb.DeregisterByServiceNameResponse{Results: []*pb.DeregisterResult{{
+		ServiceId:    req.ServiceName + "-1",
+		Removed:      true,
+		ErrorMessage: req.Reason,
+	}}}, nil
+}
+
+func (f *fakeRegistry) GetInstance(ctx context.Context, req *pb.GetInstanceRequest) (*pb.GetInstanceResponse, error) {
+	if req.ServiceId != "orders-1" {
+		return nil, status.Errorf(codes.NotFound, "service %s is not registered", req.ServiceId)
+	}
+	return &pb.GetInstanceResponse{Instance: &pb.ServiceInstance{ServiceName: "orders", ServiceId: "orders-1"}}, nil
+}
+
+func (f *fakeRegistry) GetServices(ctx context.Context, req *pb.GetServicesRequest) (*pb.GetServicesResponse, error) {
+	return &pb.GetServicesResponse{ServiceNames: []string{"orders", "payments"}}, nil
+}
+
+func (f *fakeRegistry) GetInstances(ctx context.Context, req *pb.GetInstancesRequest) (*pb.GetInstancesResponse, error) {
+	if req.ServiceName != "orders" {
+		return nil, errors.New("consul unavailable")
+	}
+	return &pb.GetInstancesResponse{Instances: []*pb.ServiceInstance{{
+		ServiceName: "orders",
+		ServiceId:   "orders-1",
+		Address:     "10.0.0.5",
+		Port:        8080,
+		Status:      pb.HealthStatus_HEALTH_STATUS_HEALTHY,
+	}}}, nil
+}
+
+func (f *fakeRegistry) ListTracking(ctx context.Context, req *pb.ListTrackingRequest) (*pb.ListTrackingResponse, error) {
+	return &pb.ListTrackingResponse{Instances: []*pb.TrackedInstance{{ServiceId: req.ServiceName + "-1", ServiceName: req.ServiceName}}}, nil
+}
+
+func (f *fakeRegistry) GetServiceHistory(ctx context.Context, req *pb.GetServiceHistoryRequest) (*pb.GetServiceHistoryResponse, error) {
+	return &pb.GetServiceHistoryResponse{Entries: []*pb.HistoryEntry{{ServiceId: req.ServiceName + "-1", Action: "registered"}}}, nil
+}
+
+func TestRESTHandler(t *testing.T) {
+	fake := &fakeRegistry{}
+	handler := NewRESTHandler(fake)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{"services", "GET", "/api/ServiceDiscovery/services", "", http.StatusOK, `"serviceNames":["orders","payments"]`},
+		{"instances", "GET", "/api/ServiceDiscovery/services/orders/instances", "", http.StatusOK, `"status":"HEALTH_STATUS_HEALTHY"`},
+		{"instance", "GET", "/api/ServiceDiscovery/instances/orders-1", "", http.StatusOK, `"serviceId":"orders-1"`},
+		{"instance not found", "GET", "/api/ServiceDiscovery/instances/orders-9", "", http.StatusNotFound, "not registered"},
+		{"consul error", "GET", "/api/ServiceDiscovery/services/payments/instances", "", http.StatusBadGateway, "consul unavailable"},
+		{"register", "POST", "/api/ServiceDiscovery/register", `{"serviceName":"orders","address":"127.0.0.1","port":8080,"metadata":{"version":"2"}}`, http.StatusOK, `"serviceId":"orders-1"`},
+		{"register missing name", "POST", "/api/ServiceDiscovery/register", `{"port":8080}`, http.StatusBadRequest, "serviceName is required"},
+		{"register invalid json", "POST", "/api/ServiceDiscovery/register", `{`, http.StatusBadRequest, "invalid request"},
+		{"deregister status code", "POST", "/api/ServiceDiscovery/deregister", `{"serviceId":"gone"}`, http.StatusNotFound, "no such service"},
+		{"deregister by name", "POST", "/api/ServiceDiscovery/services/orders/deregister", "", http.StatusOK, `"serviceId":"orders-1","removed":true`},
+		{"deregister by name with reason", "POST", "/api/ServiceDiscovery/services/orders/deregister", `{"serviceName":"ignored","reason":"retired"}`, http.StatusOK, `"serviceId":"orders-1","removed":true,"errorMessage":"retired"`},
+		{"tracking", "GET", "/api/ServiceDiscovery/admin/tracking?serviceName=orders", "", http.StatusOK, `"serviceId":"orders-1","serviceName":"orders"`},
+		{"history", "GET", "/api/ServiceDiscovery/admin/services/orders/history", "", http.StatusOK, `"serviceId":"orders-1","action":"registered"`},
+		{"expire without auth", "POST", "/api/ServiceDiscovery/admin/expire", `{}`, http.StatusForbidden, "requires authentication"},
+		{"unimplemented", "POST", "/api/ServiceDiscovery/health", `{"serviceId":"orders-1","status":"HEALTH_STATUS_HEALTHY"}`, http.StatusNotImplemented, "not implemented"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.RemoteAddr = "10.1.2.3:45678"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			// protojson output has unstable whitespace; compare without it.
+			if !strings.Contains(strings.Join(strings.Fields(w.Body.String()), ""), strings.Join(strings.Fields(tt.wantBody), "")) {
+				t.Fatalf("expected body to contain %q, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+
+	if fake.registered.GetMetadata()["version"] != "2" || fake.registered.GetPort() != 8080 {
+		t.Fatalf("expected register request to be decoded, got %v", fake.registered)
+	}
+	if fake.peerAddr != "10.1.2.3:45678" {
+		t.Fatalf("expected HTTP client address as gRPC peer, got %q", fake.peerAddr)
+	}
+}
@@ -0,0 +1,108 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// selector is one parsed metadata selector from GetInstancesRequest.
+type selector struct {
+	key    string
+	value  string
+	op     selectorOp
+	prefix []string // value segments before a trailing wildcard, if any
+	wild   bool
+}
+
+type selectorOp int
+
+const (
+	opEquals selectorOp = iota
+	opNotEquals
+	opExists
+	opNotExists
+)
+
+// parseSelectors parses "key=value", "key!=value", "key" and "!key"
+// selectors.
+func parseSelectors(raw []string) ([]selector, error) {
+	out := make([]selector, 0, len(raw))
+	for _, r := range raw {
+		r = strings.TrimSpace(r)
+		var sel selector
+		switch {
+		case strings.Contains(r, "!="):
+			sel.key, sel.value, _ = strings.Cut(r, "!=")
+			sel.op = opNotEquals
+		case strings.Contains(r, "="):
+			sel.key, sel.value, _ = strings.Cut(r, "=")
+			sel.op = opEquals
+		case strings.HasPrefix(r, "!"):
+			sel.key = r[1:]
+			sel.op = opNotExists
+		default:
+			sel.key = r
+			sel.op = opExists
+		}
+		sel.key = strings.TrimSpace(sel.key)
+		sel.value = strings.TrimSpace(sel.value)
+		if sel.key == "" {
+			return nil, fmt.Errorf("invalid selector %q: empty key", r)
+		}
+
+		// "*" is always a wildcard segment, "x" only within a dotted
+		// version such as "2.x", so a plain value of "x" matches literally.
+		segments := strings.Split(sel.value, ".")
+		for i, seg := range segments {
+			if seg == "*" || (seg == "x" && len(segments) > 1) {
+				if i != len(segments)-1 {
+					return nil, fmt.Errorf("invalid selector %q: wildcard must be the last segment", r)
+				}
+				sel.wild = true
+				sel.prefix = segments[:i]
+			}
+		}
+		out = append(out, sel)
+	}
+	return out, nil
+}
+
+// matches reports whether meta satisfies the selector.
+func (s selector) matches(meta map[string]string) bool {
+	v, ok := meta[s.key]
+	switch s.op {
+	case opExists:
+		return ok
+	case opNotExists:
+		return !ok
+	case opEquals:
+		return ok && s.matchValue(v)
+	default: // opNotEquals
+		return !ok || !s.matchValue(v)
+	}
+}
+
+func (s selector) matchValue(v string) bool {
+	if !s.wild {
+		return v == s.value
+	}
+	segments := strings.Split(v, ".")
+	if len(segments) < len(s.prefix) {
+		return false
+	}
+	for i, p := range s.prefix {
+		if segments[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAll(selectors []selector, meta map[string]string) bool {
+	for _, s := range selectors {
+		if !s.matches(meta) {
+			return false
+		}
+	}
+	return true
+}
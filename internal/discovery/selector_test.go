@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestSelectors(t *testing.T) {
+	meta := map[string]string{"zone": "eu-west", "version": "2.1.3", "canary": "", "tier": "x"}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"zone=eu-west", true},
+		{"zone=us-east", false},
+		{"zone!=us-east", true},
+		{"zone!=eu-west", false},
+		{"region!=eu", true}, // absent keys satisfy !=
+		{"canary", true},
+		{"!canary", false},
+		{"!region", true},
+		{"version=2.x", true},
+		{"version=2.1.*", true},
+		{"version=2.2.x", false},
+		{"version=20.x", false},
+		{"version=2.1", false},
+		{"version=*", true},
+		{"version=x", false}, // a lone "x" is a literal value
+		{"tier=x", true},
+		{"zone=x", false},
+		{" zone = eu-west ", true},
+	}
+	for _, tt := range tests {
+		sels, err := parseSelectors([]string{tt.selector})
+		if err != nil {
+			t.Fatalf("%q: unexpected error %v", tt.selector, err)
+		}
+		if got := matchesAll(sels, meta); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.selector, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"=eu-west", "!", "version=x.2"} {
+		if _, err := parseSelectors([]string{bad}); err == nil {
+			t.Errorf("%q: expected parse error", bad)
+		}
+	}
+}
+
+func TestGetInstances_FiltersServerSide(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	for _, inst := range []struct {
+		id, zone, version string
+	}{
+		{"orders-1", "eu-west", "2.0"},
+		{"orders-2", "eu-west", "1.9"},
+		{"orders-3", "us-east", "2.1"},
+	} {
+		server.Register(ctx, &pb.RegisterServiceRequest{
+			ServiceName: "orders", ServiceId: inst.id, Address: "10.0.0.5",
+			Metadata: map[string]string{"zone": inst.zone, "version": inst.version},
+		})
+	}
+	server.ReportHealth(ctx, &pb.ReportHealthRequest{ServiceId: "orders-1", Status: pb.HealthStatus_HEALTH_STATUS_UNHEALTHY})
+
+	tests := []struct {
+		name string
+		req  *pb.GetInstancesRequest
+		want string
+	}{
+		{"all", &pb.GetInstancesRequest{ServiceName: "orders"}, "orders-1,orders-2,orders-3"},
+		{"zone", &pb.GetInstancesRequest{ServiceName: "orders", Selectors: []string{"zone=eu-west"}}, "orders-1,orders-2"},
+		{"zone and version", &pb.GetInstancesRequest{ServiceName: "orders", Selectors: []string{"zone=eu-west", "version=2.x"}}, "orders-1"},
+		{"healthy only", &pb.GetInstancesRequest{ServiceName: "orders", HealthyOnly: true}, "orders-2,orders-3"},
+		{"healthy in zone", &pb.GetInstancesRequest{ServiceName: "orders", Selectors: []string{"zone=eu-west"}, HealthyOnly: true}, "orders-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.GetInstances(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, inst := range resp.Instances {
+				ids = append(ids, inst.ServiceId)
+			}
+			sort.Strings(ids)
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	_, err := server.GetInstances(ctx, &pb.GetInstancesRequest{ServiceName: "orders", Selectors: []string{"=x"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a bad selector, got %v", err)
+	}
+}
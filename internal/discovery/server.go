@@ -10,11 +10,15 @@ import (
 	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
 	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
 	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
 )
 
@@ -22,36 +26,166 @@ import (
 type Server struct {
 	pb.UnimplementedDiscoveryRegistryServer
 
-	registry  *consul.Registry
-	publisher *messaging.Publisher
-	logger    *slog.Logger
+	registry   registry.Registry
+	publisher  *messaging.Publisher
+	logger     *slog.Logger
+	sink       telemetry.Sink
+	tracer     *telemetry.Tracer
+	leader     Leadership
+	conflicts  ConflictPolicy
+	tombstones TombstoneConfig
+	webhooks   *messaging.WebhookNotifier
+	autoRenew  map[string]bool // services whose autoRenew requests are honoured
 
 	// In-memory tracking for metadata and timestamps that Consul doesn't store.
 	mu       sync.RWMutex
 	tracking map[string]*trackingInfo
+
+	// Recent events per service name, for GetServiceHistory.
+	historyMu sync.Mutex
+	history   map[string][]historyEntry
 }
 
 type trackingInfo struct {
-	ServiceName    string
-	RegisteredAt   time.Time
-	DeregisteredAt *time.Time
-	LastUpdated    time.Time
-	Status         consul.HealthStatus
+	ServiceName     string
+	Address         string
+	Port            int
+	RegisteredAt    time.Time
+	DeregisteredAt  *time.Time
+	LastUpdated     time.Time
+	Status          types.HealthStatus
 	LastHealthCheck *time.Time
-	Metadata       map[string]string
+	Metadata        map[string]string
+	// HealthCheck is the check the instance registered with, kept for
+	// its tombstone since the registry does not return it.
+	HealthCheck *types.HealthCheckConfig
 }
 
 // NewServer creates a Discovery gRPC server backed by Consul.
-func NewServer(registry *consul.Registry, publisher *messaging.Publisher, logger *slog.Logger) *Server {
+func NewServer(registry registry.Registry, publisher *messaging.Publisher, logger *slog.Logger) *Server {
+	return NewServerWithOptions(registry, publisher, logger, Options{})
+}
+
+// Options holds optional Server dependencies.
+type Options struct {
+	// Telemetry receives Consul call durations.
+	Telemetry telemetry.Sink
+	// Tracer records a client span per Consul call.
+	Tracer *telemetry.Tracer
+	// Leadership gates cluster-wide background work when several replicas
+	// run. Nil means this replica is the only one, and always leads.
+	Leadership Leadership
+	// ConflictPolicy decides how Register handles a clash with an existing
+	// instance. Empty means ConflictAllow.
+	ConflictPolicy ConflictPolicy
+	// Tombstones keeps deregistered instances restorable for a grace
+	// period. The zero value disables them.
+	Tombstones TombstoneConfig
+	// Webhooks, if set, is notified of every event published to RabbitMQ.
+	Webhooks *messaging.WebhookNotifier
+	// AutoRenewServices lists the services whose autoRenew registrations
+	// discovery renews. Requests for other services are registered without
+	// it, so a registrant cannot keep itself healthy on its own say-so.
+	AutoRenewServices []string
+}
+
+// NewServerWithOptions is like NewServer with explicit options.
+func NewServerWithOptions(registry registry.Registry, publisher *messaging.Publisher, logger *slog.Logger, opts Options) *Server {
+	defaults := DefaultTombstoneConfig()
+	if opts.Tombstones.KVPrefix == "" {
+		opts.Tombstones.KVPrefix = defaults.KVPrefix
+	}
+	if opts.Tombstones.PurgeInterval <= 0 {
+		opts.Tombstones.PurgeInterval = defaults.PurgeInterval
+	}
+	autoRenew := make(map[string]bool)
+	for _, name := range opts.AutoRenewServices {
+		autoRenew[name] = true
+	}
 	return &Server{
-		registry:  registry,
-		publisher: publisher,
-		logger:    logger,
-		tracking:  make(map[string]*trackingInfo),
+		registry:   registry,
+		publisher:  publisher,
+		logger:     logger,
+		sink:       telemetry.OrNop(opts.Telemetry),
+		tracer:     opts.Tracer,
+		leader:     opts.Leadership,
+		conflicts:  opts.ConflictPolicy,
+		tombstones: opts.Tombstones,
+		webhooks:   opts.Webhooks,
+		autoRenew:  autoRenew,
+		tracking:   make(map[string]*trackingInfo),
+		history:    make(map[string][]historyEntry),
 	}
 }
 
 func (s *Server) Register(ctx context.Context, req *pb.RegisterServiceRequest) (*pb.RegisterServiceResponse, error) {
+	reg := s.registration(ctx, req)
+
+	unchanged, conflicts := s.checkConflicts(ctx, reg)
+	if err := s.resolveConflicts(ctx, reg, conflicts); err != nil {
+		return &pb.RegisterServiceResponse{
+			Success:      false,
+			ServiceId:    reg.ServiceID,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	err := s.consulCall(ctx, "register", func() error { return s.registry.Register(reg) })
+	s.countOutcome("discovery_registrations_total", err, nil)
+	if err != nil {
+		s.logger.Error("registration failed", "service_id", reg.ServiceID, "error", err)
+		return &pb.RegisterServiceResponse{
+			Success:      false,
+			ServiceId:    reg.ServiceID,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	// Track registration in memory.
+	now := time.Now().UTC()
+	if unchanged {
+		// Repeating a registration renews it in the registry but is not news:
+		// keep the original registration time and publish nothing.
+		s.mu.RLock()
+		_, tracked := s.tracking[reg.ServiceID]
+		s.mu.RUnlock()
+		if !tracked {
+			s.track(reg, now)
+		}
+		s.logger.Debug("service re-registered unchanged", "service_id", reg.ServiceID)
+		return &pb.RegisterServiceResponse{Success: true, ServiceId: reg.ServiceID}, nil
+	}
+	s.track(reg, now)
+
+	// Publish event.
+	if err := s.publish(ctx, messaging.ServiceRegisteredEvent{
+		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:   now,
+		ServiceID:   reg.ServiceID,
+		ServiceName: reg.ServiceName,
+		Address:     reg.Address,
+		Port:        reg.Port,
+		Metadata:    reg.Metadata,
+	}); err != nil {
+		s.logger.Warn("failed to publish registration event", "service_id", reg.ServiceID, "error", err)
+	}
+
+	s.logger.Info("service registered",
+		"service_id", reg.ServiceID,
+		"service_name", reg.ServiceName,
+		"address", reg.Address,
+		"port", reg.Port,
+	)
+
+	return &pb.RegisterServiceResponse{
+		Success:   true,
+		ServiceId: reg.ServiceID,
+	}, nil
+}
+
+// registration converts a request into a Consul registration, generating an
+// ID if none was given and resolving the caller's address.
+func (s *Server) registration(ctx context.Context, req *pb.RegisterServiceRequest) types.Registration {
 	serviceID := req.ServiceId
 	if serviceID == "" {
 		serviceID = fmt.Sprintf("%s-%d", req.ServiceName, time.Now().UnixNano())
@@ -64,8 +198,10 @@ func (s *Server) Register(ctx context.Context, req *pb.RegisterServiceRequest) (
 	for k, v := range req.Metadata {
 		metadata[k] = v
 	}
+	// Only discovery marks instances for renewal.
+	delete(metadata, AutoRenewMetadataKey)
 
-	reg := consul.Registration{
+	reg := types.Registration{
 		ServiceName: req.ServiceName,
 		ServiceID:   serviceID,
 		Address:     address,
@@ -73,60 +209,61 @@ func (s *Server) Register(ctx context.Context, req *pb.RegisterServiceRequest) (
 		Metadata:    metadata,
 	}
 
+	if req.AutoRenew && !s.autoRenew[req.ServiceName] {
+		s.logger.Warn("autoRenew ignored, service not configured for renewal", "service", req.ServiceName, "service_id", serviceID)
+	} else if req.AutoRenew {
+		metadata[AutoRenewMetadataKey] = "true"
+		// The HealthMonitor probes from metadata, so expose the endpoint.
+		if ep := req.HealthCheck.GetEndpoint(); ep != "" && metadata["health_check_endpoint"] == "" {
+			metadata["health_check_endpoint"] = ep
+		}
+	}
+
 	if req.HealthCheck != nil {
-		reg.HealthCheck = &consul.HealthCheckConfig{
+		reg.HealthCheck = &types.HealthCheckConfig{
 			Endpoint:           req.HealthCheck.Endpoint,
 			IntervalSeconds:    int(req.HealthCheck.IntervalSeconds),
 			TimeoutSeconds:     int(req.HealthCheck.TimeoutSeconds),
 			UnhealthyThreshold: int(req.HealthCheck.UnhealthyThreshold),
 		}
 	}
+	return reg
+}
 
-	if err := s.registry.Register(reg); err != nil {
-		s.logger.Error("registration failed", "service_id", serviceID, "error", err)
-		return &pb.RegisterServiceResponse{
-			Success:      false,
-			ServiceId:    serviceID,
-			ErrorMessage: err.Error(),
-		}, nil
+// registrationOf returns the registration that recreates a registered
+// instance, with the health check it registered with if this replica
+// tracked it.
+func (s *Server) registrationOf(inst types.Instance) types.Registration {
+	reg := types.Registration{
+		ServiceName: inst.ServiceName,
+		ServiceID:   inst.ServiceID,
+		Address:     inst.Address,
+		Port:        inst.Port,
+		Metadata:    inst.Metadata,
+	}
+	s.mu.RLock()
+	if t, ok := s.tracking[inst.ServiceID]; ok {
+		reg.HealthCheck = t.HealthCheck
 	}
+	s.mu.RUnlock()
+	return reg
+}
 
-	// Track registration in memory.
-	now := time.Now().UTC()
+// track records a successful registration in memory.
+func (s *Server) track(reg types.Registration, now time.Time) {
 	s.mu.Lock()
-	s.tracking[serviceID] = &trackingInfo{
-		ServiceName:  req.ServiceName,
+	s.tracking[reg.ServiceID] = &trackingInfo{
+		ServiceName:  reg.ServiceName,
+		Address:      reg.Address,
+		Port:         reg.Port,
 		RegisteredAt: now,
 		LastUpdated:  now,
-		Status:       consul.HealthHealthy,
-		Metadata:     metadata,
+		Status:       types.HealthHealthy,
+		Metadata:     reg.Metadata,
+		HealthCheck:  reg.HealthCheck,
 	}
 	s.mu.Unlock()
-
-	// Publish event.
-	if err := s.publisher.Publish(ctx, messaging.ServiceRegisteredEvent{
-		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
-		Timestamp:   now,
-		ServiceID:   serviceID,
-		ServiceName: req.ServiceName,
-		Address:     address,
-		Port:        int(req.Port),
-		Metadata:    metadata,
-	}); err != nil {
-		s.logger.Warn("failed to publish registration event", "service_id", serviceID, "error", err)
-	}
-
-	s.logger.Info("service registered",
-		"service_id", serviceID,
-		"service_name", req.ServiceName,
-		"address", address,
-		"port", req.Port,
-	)
-
-	return &pb.RegisterServiceResponse{
-		Success:   true,
-		ServiceId: serviceID,
-	}, nil
+	s.reportTracked()
 }
 
 func (s *Server) Deregister(ctx context.Context, req *pb.DeregisterServiceRequest) (*pb.DeregisterServiceResponse, error) {
@@ -140,13 +277,17 @@ func (s *Server) Deregister(ctx context.Context, req *pb.DeregisterServiceReques
 		serviceName = info.ServiceName
 	}
 
-	if err := s.registry.Deregister(req.ServiceId); err != nil {
+	buried := s.lookupForTombstone(ctx, req.ServiceId)
+	err := s.consulCall(ctx, "deregister", func() error { return s.registry.Deregister(req.ServiceId) })
+	s.countOutcome("discovery_deregistrations_total", err, nil)
+	if err != nil {
 		s.logger.Error("deregistration failed", "service_id", req.ServiceId, "error", err)
 		return &pb.DeregisterServiceResponse{Removed: false}, nil
 	}
 
 	// Update tracking.
 	now := time.Now().UTC()
+	s.bury(ctx, buried, "Manual deregistration", now)
 	s.mu.Lock()
 	if t, ok := s.tracking[req.ServiceId]; ok {
 		t.DeregisteredAt = &now
@@ -155,7 +296,7 @@ func (s *Server) Deregister(ctx context.Context, req *pb.DeregisterServiceReques
 	s.mu.Unlock()
 
 	// Publish event.
-	if err := s.publisher.Publish(ctx, messaging.ServiceDeregisteredEvent{
+	if err := s.publish(ctx, messaging.ServiceDeregisteredEvent{
 		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
 		Timestamp:   now,
 		ServiceID:   req.ServiceId,
@@ -169,39 +310,104 @@ func (s *Server) Deregister(ctx context.Context, req *pb.DeregisterServiceReques
 }
 
 func (s *Server) GetInstances(ctx context.Context, req *pb.GetInstancesRequest) (*pb.GetInstancesResponse, error) {
-	instances, err := s.registry.GetInstances(req.ServiceName)
+	selectors, err := parseSelectors(req.Selectors)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	fields, err := instanceFields(req.FieldMask)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var instances []types.Instance
+	err = s.consulCall(ctx, "get_instances", func() (err error) {
+		instances, err = s.registry.GetInstances(req.ServiceName)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get instances: %w", err)
 	}
 
-	resp := &pb.GetInstancesResponse{}
+	var matched []*pb.ServiceInstance
 	for _, inst := range instances {
+		if req.HealthyOnly && inst.Status != types.HealthHealthy {
+			continue
+		}
+
 		// Merge tracking metadata with Consul metadata.
 		meta := s.mergeMetadata(inst.ServiceID, inst.Metadata)
-		regTime, lastCheck := s.getTimestamps(inst.ServiceID, inst.RegisteredAt)
-
-		resp.Instances = append(resp.Instances, &pb.ServiceInstance{
-			ServiceName:     inst.ServiceName,
-			ServiceId:       inst.ServiceID,
-			Address:         inst.Address,
-			Port:            int32(inst.Port),
-			Status:          toProtoHealth(inst.Status),
-			Metadata:        meta,
-			RegisteredAt:    timestamppb.New(regTime),
-			LastHealthCheck: timestamppb.New(lastCheck),
-		})
+		if !matchesAll(selectors, meta) {
+			continue
+		}
+		matched = append(matched, s.toProtoInstance(inst, meta))
 	}
 
+	page, next, err := paginate(matched, (*pb.ServiceInstance).GetServiceId, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &pb.GetInstancesResponse{NextPageToken: next}
+	for _, inst := range page {
+		resp.Instances = append(resp.Instances, maskInstance(inst, fields))
+	}
 	return resp, nil
 }
 
+// GetInstance returns one instance by ID. The registry's single-instance
+// lookup carries no health, so the status comes from the service's checks.
+func (s *Server) GetInstance(ctx context.Context, req *pb.GetInstanceRequest) (*pb.GetInstanceResponse, error) {
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceId is required")
+	}
+
+	var inst *types.Instance
+	err := s.consulCall(ctx, "get_instance", func() (err error) {
+		inst, err = s.registry.GetInstance(req.ServiceId)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get instance: %w", err)
+	}
+	if inst == nil {
+		return nil, status.Errorf(codes.NotFound, "service %s is not registered", req.ServiceId)
+	}
+
+	if inst.Status == types.HealthUnknown {
+		var instances []types.Instance
+		err := s.consulCall(ctx, "get_instances", func() (err error) {
+			instances, err = s.registry.GetInstances(inst.ServiceName)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get instances: %w", err)
+		}
+		for _, other := range instances {
+			if other.ServiceID == inst.ServiceID {
+				inst.Status = other.Status
+				break
+			}
+		}
+	}
+
+	meta := s.mergeMetadata(inst.ServiceID, inst.Metadata)
+	return &pb.GetInstanceResponse{Instance: s.toProtoInstance(*inst, meta)}, nil
+}
+
 func (s *Server) GetServices(ctx context.Context, req *pb.GetServicesRequest) (*pb.GetServicesResponse, error) {
-	names, err := s.registry.GetServices()
+	var names []string
+	err := s.consulCall(ctx, "get_services", func() (err error) {
+		names, err = s.registry.GetServices()
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("get services: %w", err)
 	}
 
-	return &pb.GetServicesResponse{ServiceNames: names}, nil
+	page, next, err := paginate(names, func(name string) string { return name }, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.GetServicesResponse{ServiceNames: page, NextPageToken: next}, nil
 }
 
 func (s *Server) ReportHealth(ctx context.Context, req *pb.ReportHealthRequest) (*pb.ReportHealthResponse, error) {
@@ -212,14 +418,16 @@ func (s *Server) ReportHealth(ctx context.Context, req *pb.ReportHealthRequest)
 	info := s.tracking[req.ServiceId]
 	s.mu.RUnlock()
 
-	var previousStatus consul.HealthStatus
+	var previousStatus types.HealthStatus
 	serviceName := ""
 	if info != nil {
 		previousStatus = info.Status
 		serviceName = info.ServiceName
 	}
 
-	if err := s.registry.UpdateHealth(req.ServiceId, newStatus, req.Output); err != nil {
+	err := s.consulCall(ctx, "update_health", func() error { return s.registry.UpdateHealth(req.ServiceId, newStatus, req.Output) })
+	s.countHealthReport(newStatus, err)
+	if err != nil {
 		s.logger.Error("health update failed", "service_id", req.ServiceId, "error", err)
 		return &pb.ReportHealthResponse{Success: false}, nil
 	}
@@ -236,7 +444,7 @@ func (s *Server) ReportHealth(ctx context.Context, req *pb.ReportHealthRequest)
 
 	// Publish health change event if status actually changed.
 	if info != nil && previousStatus != newStatus {
-		if err := s.publisher.Publish(ctx, messaging.ServiceHealthChangedEvent{
+		if err := s.publish(ctx, messaging.ServiceHealthChangedEvent{
 			EventID:           fmt.Sprintf("%d", time.Now().UnixNano()),
 			Timestamp:         now,
 			ServiceID:         req.ServiceId,
@@ -320,32 +528,48 @@ func (s *Server) getTimestamps(serviceID string, fallbackReg time.Time) (registe
 	return fallbackReg, time.Time{}
 }
 
-func toProtoHealth(s consul.HealthStatus) pb.HealthStatus {
+// toProtoInstance converts a registry instance, using meta as its merged
+// metadata and tracked timestamps where known.
+func (s *Server) toProtoInstance(inst types.Instance, meta map[string]string) *pb.ServiceInstance {
+	regTime, lastCheck := s.getTimestamps(inst.ServiceID, inst.RegisteredAt)
+	return &pb.ServiceInstance{
+		ServiceName:     inst.ServiceName,
+		ServiceId:       inst.ServiceID,
+		Address:         inst.Address,
+		Port:            int32(inst.Port),
+		Status:          toProtoHealth(inst.Status),
+		Metadata:        meta,
+		RegisteredAt:    timestamppb.New(regTime),
+		LastHealthCheck: timestamppb.New(lastCheck),
+	}
+}
+
+func toProtoHealth(s types.HealthStatus) pb.HealthStatus {
 	switch s {
-	case consul.HealthHealthy:
+	case types.HealthHealthy:
 		return pb.HealthStatus_HEALTH_STATUS_HEALTHY
-	case consul.HealthUnhealthy:
+	case types.HealthUnhealthy:
 		return pb.HealthStatus_HEALTH_STATUS_UNHEALTHY
-	case consul.HealthDegraded:
+	case types.HealthDegraded:
 		return pb.HealthStatus_HEALTH_STATUS_DEGRADED
 	default:
 		return pb.HealthStatus_HEALTH_STATUS_UNKNOWN
 	}
 }
 
-func fromProtoHealth(s pb.HealthStatus) consul.HealthStatus {
+func fromProtoHealth(s pb.HealthStatus) types.HealthStatus {
 	switch s {
 	case pb.HealthStatus_HEALTH_STATUS_HEALTHY:
-		return consul.HealthHealthy
+		return types.HealthHealthy
 	case pb.HealthStatus_HEALTH_STATUS_UNHEALTHY:
-		return consul.HealthUnhealthy
+		return types.HealthUnhealthy
 	case pb.HealthStatus_HEALTH_STATUS_DEGRADED:
-		return consul.HealthDegraded
+		return types.HealthDegraded
 	default:
-		return consul.HealthUnknown
+		return types.HealthUnknown
 	}
 }
 
-func healthStatusName(s consul.HealthStatus) string {
+func healthStatusName(s types.HealthStatus) string {
 	return s.String()
 }
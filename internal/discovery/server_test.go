@@ -2,15 +2,172 @@ package discovery
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
 	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
 )
 
+// fakeAgent is a minimal Consul agent API recording the calls the discovery
+// server makes.
+type fakeAgent struct {
+	mu           sync.Mutex
+	calls        []string // e.g. "register orders-1", "pass/service:orders-1 note"
+	services     map[string]fakeAgentService
+	failRegister map[string]bool
+	// failDeregister holds instances the agent refuses to deregister. The
+	// fake serves no catalog deregistration, so the fallback fails too.
+	failDeregister map[string]bool
+}
+
+type fakeAgentService struct {
+	ID      string
+	Service string
+	Address string
+	Port    int
+	Meta    map[string]string
+	status  string // TTL check status: passing, warning, critical
+}
+
+func (a *fakeAgent) record(call string) {
+	a.mu.Lock()
+	a.calls = append(a.calls, call)
+	a.mu.Unlock()
+}
+
+func (a *fakeAgent) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// newTestServer returns a discovery server backed by a fake Consul agent and
+// a no-op publisher.
+func newTestServer(t *testing.T, failRegister ...string) (*Server, *fakeAgent) {
+	t.Helper()
+	agent := &fakeAgent{services: make(map[string]fakeAgentService), failRegister: make(map[string]bool), failDeregister: make(map[string]bool)}
+	for _, id := range failRegister {
+		agent.failRegister[id] = true
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/agent/service/register":
+			var reg struct {
+				ID      string
+				Name    string
+				Address string
+				Port    int
+				Meta    map[string]string
+			}
+			json.NewDecoder(r.Body).Decode(&reg)
+			if agent.failRegister[reg.ID] {
+				http.Error(w, "register refused", http.StatusInternalServerError)
+				return
+			}
+			agent.mu.Lock()
+			agent.services[reg.ID] = fakeAgentService{ID: reg.ID, Service: reg.Name, Address: reg.Address, Port: reg.Port, Meta: reg.Meta}
+			agent.mu.Unlock()
+			agent.record("register " + reg.ID)
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/")
+			if agent.failDeregister[id] {
+				http.Error(w, "deregister refused", http.StatusInternalServerError)
+				return
+			}
+			agent.mu.Lock()
+			delete(agent.services, id)
+			agent.mu.Unlock()
+			agent.record("deregister " + id)
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/service/"):
+			agent.mu.Lock()
+			svc, ok := agent.services[strings.TrimPrefix(r.URL.Path, "/v1/agent/service/")]
+			agent.mu.Unlock()
+			if !ok {
+				http.Error(w, "unknown service ID", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(svc)
+		case strings.HasPrefix(r.URL.Path, "/v1/agent/check/"):
+			// PassTTL/WarnTTL/FailTTL: PUT /v1/agent/check/{pass,warn,fail}/service:{id}?note=...
+			update := strings.TrimPrefix(r.URL.Path, "/v1/agent/check/")
+			action, checkID, _ := strings.Cut(update, "/")
+			agent.mu.Lock()
+			if svc, ok := agent.services[strings.TrimPrefix(checkID, "service:")]; ok {
+				svc.status = map[string]string{"pass": "passing", "warn": "warning", "fail": "critical"}[action]
+				agent.services[svc.ID] = svc
+			}
+			agent.mu.Unlock()
+			agent.record(update + " " + r.URL.Query().Get("note"))
+		case r.URL.Path == "/v1/agent/self":
+			w.Write([]byte(`{"Config":{"NodeName":"node-1"}}`))
+		case r.URL.Path == "/v1/catalog/services":
+			id := filterValue(r)
+			out := map[string][]string{}
+			agent.mu.Lock()
+			for _, svc := range agent.services {
+				if id == "" || svc.ID == id {
+					out[svc.Service] = []string{}
+				}
+			}
+			agent.mu.Unlock()
+			json.NewEncoder(w).Encode(out)
+		case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+			name := strings.TrimPrefix(r.URL.Path, "/v1/health/service/")
+			id := filterValue(r)
+			out := []map[string]any{}
+			agent.mu.Lock()
+			for _, svc := range agent.services {
+				if svc.Service == name && (id == "" || svc.ID == id) {
+					out = append(out, map[string]any{
+						"Node":    map[string]any{"Node": "node-1", "Address": "127.0.0.1"},
+						"Service": svc,
+						"Checks":  []map[string]any{{"CheckID": "service:" + svc.ID, "ServiceID": svc.ID, "Status": svc.status}},
+					})
+				}
+			}
+			agent.mu.Unlock()
+			json.NewEncoder(w).Encode(out)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry, err := consul.NewRegistry(srv.URL, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher, _ := messaging.NewPublisher("", logger)
+	return NewServer(registry, publisher, logger), agent
+}
+
+// filterValue returns the quoted value of a Consul filter such as
+// `ServiceID == "orders-1"`, or "" without one.
+func filterValue(r *http.Request) string {
+	_, quoted, ok := strings.Cut(r.URL.Query().Get("filter"), "== ")
+	if !ok {
+		return ""
+	}
+	v, _ := strconv.Unquote(quoted)
+	return v
+}
+
 func TestIsRoutable(t *testing.T) {
 	tests := []struct {
 		addr     string
@@ -115,3 +272,42 @@ func TestHealthStatusName(t *testing.T) {
 		}
 	}
 }
+
+func TestGetInstance_MergesHealthAndTracking(t *testing.T) {
+	server, _ := newTestServer(t)
+	ctx := context.Background()
+	if _, err := server.Register(ctx, &pb.RegisterServiceRequest{
+		ServiceName: "orders",
+		ServiceId:   "orders-1",
+		Address:     "10.0.0.5",
+		Port:        8080,
+		Metadata:    map[string]string{"zone": "eu-west"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.ReportHealth(ctx, &pb.ReportHealthRequest{ServiceId: "orders-1", Status: pb.HealthStatus_HEALTH_STATUS_DEGRADED}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := server.GetInstance(ctx, &pb.GetInstanceRequest{ServiceId: "orders-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst := resp.Instance
+	if inst.ServiceName != "orders" || inst.Port != 8080 || inst.Metadata["zone"] != "eu-west" {
+		t.Fatalf("unexpected catalog data %v", inst)
+	}
+	if inst.Status != pb.HealthStatus_HEALTH_STATUS_DEGRADED {
+		t.Fatalf("expected the check status, got %v", inst.Status)
+	}
+	if inst.LastHealthCheck.AsTime().IsZero() || inst.RegisteredAt.AsTime().IsZero() {
+		t.Fatalf("expected tracked timestamps, got %v", inst)
+	}
+
+	if _, err := server.GetInstance(ctx, &pb.GetInstanceRequest{ServiceId: "orders-9"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+	if _, err := server.GetInstance(ctx, &pb.GetInstanceRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
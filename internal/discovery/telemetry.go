@@ -0,0 +1,121 @@
+package discovery
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+// TelemetryInterceptor records a count and latency for every unary RPC,
+// labelled by full method name and gRPC status code.
+func TelemetryInterceptor(sink telemetry.Sink) grpc.UnaryServerInterceptor {
+	sink = telemetry.OrNop(sink)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		sink.Count("discovery_rpc_total", 1, telemetry.Labels{
+			"method": info.FullMethod,
+			"code":   status.Code(err).String(),
+		})
+		sink.Observe("discovery_rpc_duration_seconds", time.Since(start).Seconds(), telemetry.Labels{"method": info.FullMethod})
+		return resp, err
+	}
+}
+
+// TelemetryStreamInterceptor records a count and duration for every
+// streaming RPC, like TelemetryInterceptor. Duration covers the whole stream.
+func TelemetryStreamInterceptor(sink telemetry.Sink) grpc.StreamServerInterceptor {
+	sink = telemetry.OrNop(sink)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		sink.Count("discovery_rpc_total", 1, telemetry.Labels{
+			"method": info.FullMethod,
+			"code":   status.Code(err).String(),
+		})
+		sink.Observe("discovery_rpc_duration_seconds", time.Since(start).Seconds(), telemetry.Labels{"method": info.FullMethod})
+		return err
+	}
+}
+
+// TracingInterceptor starts a server span for every unary RPC. The parent is
+// taken from the traceparent metadata, so calls made on behalf of a traced
+// request join its trace; an x-correlation-id is recorded as an attribute.
+func TracingInterceptor(tracer *telemetry.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := startRPCSpan(ctx, tracer, info.FullMethod)
+		resp, err := handler(ctx, req)
+		span.SetAttribute("rpc.grpc.status_code", strconv.Itoa(int(status.Code(err))))
+		span.End(err)
+		return resp, err
+	}
+}
+
+// TracingStreamInterceptor starts a server span covering each streaming RPC.
+func TracingStreamInterceptor(tracer *telemetry.Tracer) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := startRPCSpan(ss.Context(), tracer, info.FullMethod)
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		span.SetAttribute("rpc.grpc.status_code", strconv.Itoa(int(status.Code(err))))
+		span.End(err)
+		return err
+	}
+}
+
+func startRPCSpan(ctx context.Context, tracer *telemetry.Tracer, fullMethod string) (context.Context, *telemetry.Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	if parent, ok := telemetry.Extract(get); ok {
+		ctx = telemetry.ContextWithRemoteParent(ctx, parent)
+	}
+
+	name := strings.TrimPrefix(fullMethod, "/")
+	ctx, span := tracer.Start(ctx, name, telemetry.SpanKindServer)
+	service, method, _ := strings.Cut(name, "/")
+	span.SetAttribute("rpc.system", "grpc")
+	span.SetAttribute("rpc.service", service)
+	span.SetAttribute("rpc.method", method)
+	telemetry.SetCorrelationID(span, get)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		span.SetAttribute("net.peer.address", p.Addr.String())
+	}
+	return ctx, span
+}
+
+// consulCall runs one Consul operation as a client span of the RPC in ctx and
+// records its duration, labelled by operation and outcome.
+func (s *Server) consulCall(ctx context.Context, op string, fn func() error) error {
+	_, span := s.tracer.Start(ctx, "consul "+op, telemetry.SpanKindClient)
+	span.SetAttribute("peer.service", "consul")
+	start := time.Now()
+	err := fn()
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	s.sink.Observe("discovery_consul_duration_seconds", time.Since(start).Seconds(), telemetry.Labels{
+		"operation": op,
+		"outcome":   outcome,
+	})
+	if err != nil {
+		s.sink.Count("discovery_consul_errors_total", 1, telemetry.Labels{"operation": op})
+	}
+	span.End(err)
+	return err
+}
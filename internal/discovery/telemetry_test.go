@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+func TestTelemetryInterceptor_RecordsMethodAndCode(t *testing.T) {
+	sink := telemetry.NewPrometheus()
+	intercept := TelemetryInterceptor(sink)
+	info := &grpc.UnaryServerInfo{FullMethod: "/toskamesh.discovery.DiscoveryRegistry/Register"}
+
+	intercept(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, nil
+	})
+	intercept(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad")
+	})
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`discovery_rpc_total{code="OK",method="/toskamesh.discovery.DiscoveryRegistry/Register"} 1`,
+		`discovery_rpc_total{code="InvalidArgument",method="/toskamesh.discovery.DiscoveryRegistry/Register"} 1`,
+		`discovery_rpc_duration_seconds_count{method="/toskamesh.discovery.DiscoveryRegistry/Register"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestTracingInterceptor_JoinsGatewayTrace(t *testing.T) {
+	var got struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Attributes   []struct {
+						Key   string            `json:"key"`
+						Value map[string]string `json:"value"`
+					} `json:"attributes"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer collector.Close()
+
+	cfg := telemetry.DefaultConfig("discovery")
+	cfg.Tracing = true
+	cfg.OTLPTracesEndpoint = collector.URL
+	tracer := telemetry.NewTracer(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sink := telemetry.NewPrometheus()
+
+	server, _ := newTestServer(t)
+	server.sink, server.tracer = sink, tracer
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(TracingInterceptor(tracer)))
+	pb.RegisterDiscoveryRegistryServer(srv, server)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const correlationID = "11111111111111111111111111111111"
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01",
+		"x-correlation-id", correlationID,
+	)
+	if _, err := pb.NewDiscoveryRegistryClient(conn).Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", Address: "10.0.0.5", Port: 8080}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	// The conflict check's lookup, the registration, then the RPC itself.
+	if len(spans) != 3 {
+		t.Fatalf("expected RPC and Consul spans, got %+v", spans)
+	}
+	lookupSpan, consulSpan, rpcSpan := spans[0], spans[1], spans[2]
+	if rpcSpan.Name != "toskamesh.discovery.DiscoveryRegistry/Register" || consulSpan.Name != "consul register" || lookupSpan.Name != "consul get_instances" {
+		t.Fatalf("unexpected span names %q, %q, %q", rpcSpan.Name, consulSpan.Name, lookupSpan.Name)
+	}
+	if rpcSpan.TraceID != traceID || consulSpan.TraceID != traceID {
+		t.Fatalf("expected spans in trace %s, got %s and %s", traceID, rpcSpan.TraceID, consulSpan.TraceID)
+	}
+	if consulSpan.ParentSpanID != rpcSpan.SpanID {
+		t.Fatalf("expected Consul span to be a child of the RPC span")
+	}
+	// The client-supplied correlation ID is kept, but not as the trace ID.
+	var recorded string
+	for _, a := range rpcSpan.Attributes {
+		if a.Key == telemetry.CorrelationIDAttribute {
+			recorded = a.Value["stringValue"]
+		}
+	}
+	if recorded != correlationID {
+		t.Fatalf("expected correlation ID %s on the RPC span, got %q", correlationID, recorded)
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `discovery_consul_duration_seconds_count{operation="register",outcome="success"} 1`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, w.Body.String())
+	}
+}
@@ -0,0 +1,206 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// TombstoneConfig controls the grace period after a deregistration during
+// which Restore can bring the instance back. Tombstones live in the
+// registry's key/value store, so every replica can restore them and they
+// survive restarts.
+type TombstoneConfig struct {
+	// Grace is how long a tombstone is kept. Zero disables tombstones.
+	Grace time.Duration
+	// KVPrefix is where tombstones are stored, one key per service ID.
+	KVPrefix string
+	// PurgeInterval is how often the leader deletes expired tombstones.
+	PurgeInterval time.Duration
+}
+
+// DefaultTombstoneConfig returns the tombstone defaults, with tombstones
+// disabled.
+func DefaultTombstoneConfig() TombstoneConfig {
+	return TombstoneConfig{
+		KVPrefix:      "toska-mesh/tombstones/",
+		PurgeInterval: 30 * time.Second,
+	}
+}
+
+// tombstone is the stored form of a deregistered instance.
+type tombstone struct {
+	Registration   types.Registration `json:"registration"`
+	DeregisteredAt time.Time          `json:"deregisteredAt"`
+	ExpiresAt      time.Time          `json:"expiresAt"`
+	Reason         string             `json:"reason,omitempty"`
+}
+
+// lookupForTombstone fetches an instance about to be deregistered from the
+// catalog, whichever node it is on, or nil if tombstones are disabled or the
+// lookup fails. A failed lookup only costs the chance to restore, so it does
+// not block the deregistration.
+func (s *Server) lookupForTombstone(ctx context.Context, serviceID string) *types.Instance {
+	if s.tombstones.Grace <= 0 {
+		return nil
+	}
+	var inst *types.Instance
+	err := s.consulCall(ctx, "get_instance", func() (err error) {
+		inst, err = s.registry.GetInstance(serviceID)
+		return err
+	})
+	if err != nil {
+		s.logger.Warn("no tombstone for deregistration", "service_id", serviceID, "error", err)
+		return nil
+	}
+	return inst
+}
+
+// bury stores a tombstone for a deregistered instance, with the health check
+// it registered with if this replica tracked it. A nil inst is ignored.
+func (s *Server) bury(ctx context.Context, inst *types.Instance, reason string, now time.Time) {
+	if inst == nil || s.tombstones.Grace <= 0 {
+		return
+	}
+	value, err := json.Marshal(tombstone{
+		Registration:   s.registrationOf(*inst),
+		DeregisteredAt: now,
+		ExpiresAt:      now.Add(s.tombstones.Grace),
+		Reason:         reason,
+	})
+	if err != nil {
+		s.logger.Warn("failed to encode tombstone", "service_id", inst.ServiceID, "error", err)
+		return
+	}
+	key := s.tombstones.KVPrefix + inst.ServiceID
+	if err := s.consulCall(ctx, "put_kv", func() error { return s.registry.PutKV(key, value) }); err != nil {
+		s.logger.Warn("failed to store tombstone", "service_id", inst.ServiceID, "error", err)
+	}
+}
+
+// listTombstones returns the tombstones under prefix keyed by service ID.
+// Entries that cannot be decoded are skipped.
+func (s *Server) listTombstones(ctx context.Context, prefix string) (map[string]tombstone, error) {
+	var values map[string][]byte
+	err := s.consulCall(ctx, "list_kv", func() (err error) {
+		values, _, err = s.registry.ListKV(ctx, prefix, 0, 0)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]tombstone, len(values))
+	for key, value := range values {
+		var t tombstone
+		if err := json.Unmarshal(value, &t); err != nil {
+			s.logger.Warn("ignoring invalid tombstone", "key", key, "error", err)
+			continue
+		}
+		out[strings.TrimPrefix(key, s.tombstones.KVPrefix)] = t
+	}
+	return out, nil
+}
+
+// Restore re-registers an instance deregistered within the grace period,
+// with the address, port, metadata and health check it had, and publishes a
+// ServiceRegisteredEvent.
+func (s *Server) Restore(ctx context.Context, req *pb.RestoreServiceRequest) (*pb.RestoreServiceResponse, error) {
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceId is required")
+	}
+	if s.tombstones.Grace <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "tombstones are disabled")
+	}
+
+	// Listing by the full key also matches longer IDs sharing the prefix.
+	found, err := s.listTombstones(ctx, s.tombstones.KVPrefix+req.ServiceId)
+	if err != nil {
+		return nil, fmt.Errorf("list tombstones: %w", err)
+	}
+	now := time.Now().UTC()
+	t, ok := found[req.ServiceId]
+	if !ok || now.After(t.ExpiresAt) {
+		return nil, status.Errorf(codes.NotFound, "no tombstone for service %s", req.ServiceId)
+	}
+
+	reg := t.Registration
+	err = s.consulCall(ctx, "register", func() error { return s.registry.Register(reg) })
+	s.countOutcome("discovery_registrations_total", err, nil)
+	s.countOutcome("discovery_restores_total", err, nil)
+	if err != nil {
+		s.logger.Error("restore failed", "service_id", reg.ServiceID, "error", err)
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+	key := s.tombstones.KVPrefix + reg.ServiceID
+	if err := s.consulCall(ctx, "delete_kv", func() error { return s.registry.DeleteKV(key) }); err != nil {
+		s.logger.Warn("failed to delete tombstone", "service_id", reg.ServiceID, "error", err)
+	}
+	s.track(reg, now)
+
+	if err := s.publish(ctx, messaging.ServiceRegisteredEvent{
+		EventID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:   now,
+		ServiceID:   reg.ServiceID,
+		ServiceName: reg.ServiceName,
+		Address:     reg.Address,
+		Port:        reg.Port,
+		Metadata:    reg.Metadata,
+	}); err != nil {
+		s.logger.Warn("failed to publish registration event", "service_id", reg.ServiceID, "error", err)
+	}
+	s.logger.Info("service restored", "service_id", reg.ServiceID, "service_name", reg.ServiceName, "deregistered_at", t.DeregisteredAt)
+
+	return &pb.RestoreServiceResponse{Instance: s.toProtoInstance(types.Instance{
+		ServiceName: reg.ServiceName,
+		ServiceID:   reg.ServiceID,
+		Address:     reg.Address,
+		Port:        reg.Port,
+		Status:      types.HealthHealthy,
+	}, s.mergeMetadata(reg.ServiceID, reg.Metadata))}, nil
+}
+
+// RunTombstonePurge deletes expired tombstones every PurgeInterval until ctx
+// is cancelled. Only the leader purges.
+func (s *Server) RunTombstonePurge(ctx context.Context) {
+	ticker := time.NewTicker(s.tombstones.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if s.isLeader() {
+			s.purgeTombstones(ctx, time.Now())
+		}
+	}
+}
+
+func (s *Server) purgeTombstones(ctx context.Context, now time.Time) {
+	found, err := s.listTombstones(ctx, s.tombstones.KVPrefix)
+	if err != nil {
+		s.logger.Warn("tombstone purge failed", "error", err)
+		return
+	}
+	for id, t := range found {
+		if now.Before(t.ExpiresAt) {
+			continue
+		}
+		key := s.tombstones.KVPrefix + id
+		if err := s.consulCall(ctx, "delete_kv", func() error { return s.registry.DeleteKV(key) }); err != nil {
+			s.logger.Warn("failed to purge tombstone", "service_id", id, "error", err)
+			continue
+		}
+		s.sink.Count("discovery_tombstones_purged_total", 1, nil)
+		s.logger.Info("purged tombstone", "service_id", id, "deregistered_at", t.DeregisteredAt)
+	}
+}
@@ -0,0 +1,212 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// memRegistry keeps instances and KV pairs in memory.
+type memRegistry struct {
+	registry.Registry
+
+	mu        sync.Mutex
+	instances map[string]types.Instance
+	checks    map[string]*types.HealthCheckConfig
+	kv        map[string][]byte
+}
+
+func newMemRegistry() *memRegistry {
+	return &memRegistry{instances: make(map[string]types.Instance), checks: make(map[string]*types.HealthCheckConfig), kv: make(map[string][]byte)}
+}
+
+func (m *memRegistry) Register(reg types.Registration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[reg.ServiceID] = types.Instance{
+		ServiceName: reg.ServiceName, ServiceID: reg.ServiceID,
+		Address: reg.Address, Port: reg.Port, Metadata: maps.Clone(reg.Metadata),
+		Status: types.HealthHealthy,
+	}
+	m.checks[reg.ServiceID] = reg.HealthCheck
+	return nil
+}
+
+func (m *memRegistry) Deregister(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.instances, id)
+	return nil
+}
+
+func (m *memRegistry) GetInstances(name string) ([]types.Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []types.Instance
+	for _, inst := range m.instances {
+		if inst.ServiceName == name {
+			out = append(out, inst)
+		}
+	}
+	return out, nil
+}
+
+func (m *memRegistry) GetServices() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool)
+	var out []string
+	for _, inst := range m.instances {
+		if !seen[inst.ServiceName] {
+			seen[inst.ServiceName] = true
+			out = append(out, inst.ServiceName)
+		}
+	}
+	return out, nil
+}
+
+func (m *memRegistry) GetInstance(id string) (*types.Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inst, ok := m.instances[id]
+	if !ok {
+		return nil, nil
+	}
+	return &inst, nil
+}
+
+func (m *memRegistry) ListKV(ctx context.Context, prefix string, index uint64, wait time.Duration) (map[string][]byte, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string][]byte)
+	for k, v := range m.kv {
+		if strings.HasPrefix(k, prefix) {
+			out[k] = v
+		}
+	}
+	return out, 1, nil
+}
+
+func (m *memRegistry) PutKV(key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kv[key] = value
+	return nil
+}
+
+func (m *memRegistry) DeleteKV(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.kv, key)
+	return nil
+}
+
+func newTombstoneServer(reg registry.Registry, grace time.Duration) *Server {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	publisher, _ := messaging.NewPublisher("", logger)
+	return NewServerWithOptions(reg, publisher, logger, Options{Tombstones: TombstoneConfig{Grace: grace}})
+}
+
+func TestRestore_BringsBackDeregisteredInstance(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, time.Minute)
+	ctx := context.Background()
+	for _, id := range []string{"orders-1", "orders-10"} {
+		server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: id, Address: "10.0.0.5", Port: 8080, Metadata: map[string]string{"zone": "eu-west"}})
+	}
+	if resp, _ := server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-1"}); !resp.Removed {
+		t.Fatal("expected orders-1 deregistered")
+	}
+	server.DeregisterByServiceName(ctx, &pb.DeregisterByServiceNameRequest{ServiceName: "orders"})
+	if len(reg.instances) != 0 || len(reg.kv) != 2 {
+		t.Fatalf("expected both instances removed and tombstoned, got %v and %d tombstones", reg.instances, len(reg.kv))
+	}
+
+	resp, err := server.Restore(ctx, &pb.RestoreServiceRequest{ServiceId: "orders-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Instance.ServiceId != "orders-1" || resp.Instance.Metadata["zone"] != "eu-west" {
+		t.Fatalf("unexpected restored instance %v", resp.Instance)
+	}
+	inst, ok := reg.instances["orders-1"]
+	if !ok || inst.Address != "10.0.0.5" || inst.Port != 8080 {
+		t.Fatalf("expected orders-1 re-registered, got %+v", reg.instances)
+	}
+	if _, ok := reg.kv["toska-mesh/tombstones/orders-1"]; ok {
+		t.Fatal("expected the tombstone to be removed")
+	}
+	if _, ok := reg.instances["orders-10"]; ok {
+		t.Fatal("expected only the requested instance restored")
+	}
+
+	if _, err := server.Restore(ctx, &pb.RestoreServiceRequest{ServiceId: "orders-1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound restoring twice, got %v", err)
+	}
+}
+
+func TestRestore_KeepsHealthCheck(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, time.Minute)
+	ctx := context.Background()
+	server.Register(ctx, &pb.RegisterServiceRequest{
+		ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080,
+		HealthCheck: &pb.HealthCheckConfig{Endpoint: "/ready", IntervalSeconds: 10, TimeoutSeconds: 2},
+	})
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-1"})
+
+	if _, err := server.Restore(ctx, &pb.RestoreServiceRequest{ServiceId: "orders-1"}); err != nil {
+		t.Fatal(err)
+	}
+	check := reg.checks["orders-1"]
+	if check == nil || check.Endpoint != "/ready" || check.IntervalSeconds != 10 || check.TimeoutSeconds != 2 {
+		t.Fatalf("expected the original health check restored, got %+v", check)
+	}
+}
+
+func TestPurgeTombstones_DropsExpired(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, time.Minute)
+	ctx := context.Background()
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080})
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-1"})
+
+	server.purgeTombstones(ctx, time.Now())
+	if len(reg.kv) != 1 {
+		t.Fatal("expected a fresh tombstone to be kept")
+	}
+	server.purgeTombstones(ctx, time.Now().Add(2*time.Minute))
+	if len(reg.kv) != 0 {
+		t.Fatalf("expected the expired tombstone purged, got %v", reg.kv)
+	}
+	if _, err := server.Restore(ctx, &pb.RestoreServiceRequest{ServiceId: "orders-1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound after purge, got %v", err)
+	}
+}
+
+func TestRestore_DisabledWithoutGrace(t *testing.T) {
+	reg := newMemRegistry()
+	server := newTombstoneServer(reg, 0)
+	ctx := context.Background()
+	server.Register(ctx, &pb.RegisterServiceRequest{ServiceName: "orders", ServiceId: "orders-1", Address: "10.0.0.5", Port: 8080})
+	server.Deregister(ctx, &pb.DeregisterServiceRequest{ServiceId: "orders-1"})
+	if len(reg.kv) != 0 {
+		t.Fatalf("expected no tombstones, got %v", reg.kv)
+	}
+	if _, err := server.Restore(ctx, &pb.RestoreServiceRequest{ServiceId: "orders-1"}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition, got %v", err)
+	}
+}
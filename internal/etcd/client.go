@@ -0,0 +1,298 @@
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// client speaks the etcd v3 JSON gateway (/v3/kv/range and friends). Keys and
+// values are base64 in the JSON mapping, and 64-bit integers are strings.
+type client struct {
+	endpoints []string
+	http      *http.Client
+}
+
+type keyValue struct {
+	Key         []byte
+	Value       []byte
+	ModRevision int64
+	Lease       int64
+}
+
+type responseHeader struct {
+	Revision string `json:"revision"`
+}
+
+func (h responseHeader) revision() int64 {
+	n, _ := strconv.ParseInt(h.Revision, 10, 64)
+	return n
+}
+
+type rawKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	ModRevision string `json:"mod_revision"`
+	Lease       string `json:"lease"`
+}
+
+func (kv rawKeyValue) decode() (keyValue, error) {
+	key, err := base64.StdEncoding.DecodeString(kv.Key)
+	if err != nil {
+		return keyValue{}, fmt.Errorf("decode key: %w", err)
+	}
+	value, err := base64.StdEncoding.DecodeString(kv.Value)
+	if err != nil {
+		return keyValue{}, fmt.Errorf("decode value of %s: %w", key, err)
+	}
+	modRev, _ := strconv.ParseInt(kv.ModRevision, 10, 64)
+	lease, _ := strconv.ParseInt(kv.Lease, 10, 64)
+	return keyValue{Key: key, Value: value, ModRevision: modRev, Lease: lease}, nil
+}
+
+func b64(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+// prefixEnd returns the range_end that selects every key starting with
+// prefix.
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return "\x00" // prefix of all 0xff bytes: range to the end of the keyspace
+}
+
+// call POSTs req to path on each endpoint in turn until one answers, and
+// decodes the response into resp.
+func (c *client) call(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	var lastErr error
+	for _, endpoint := range c.endpoints {
+		r, err := c.post(ctx, endpoint+path, body)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		defer r.Body.Close()
+		if err := checkStatus(r); err != nil {
+			return err
+		}
+		if resp == nil {
+			return nil
+		}
+		if err := json.NewDecoder(r.Body).Decode(resp); err != nil {
+			return fmt.Errorf("decode %s: %w", path, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("etcd %s: %w", path, lastErr)
+}
+
+func (c *client) post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.http.Do(req)
+}
+
+// checkStatus turns a gateway error response into an error.
+func checkStatus(r *http.Response) error {
+	if r.StatusCode < 300 {
+		return nil
+	}
+	var e struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(r.Body, 4096))
+	json.Unmarshal(data, &e)
+	msg := e.Message
+	if msg == "" {
+		msg = e.Error
+	}
+	if msg == "" {
+		msg = string(data)
+	}
+	return fmt.Errorf("etcd returned %s: %s", r.Status, msg)
+}
+
+// get returns the keys equal to key, or starting with it if prefix is set,
+// along with the store revision.
+func (c *client) get(ctx context.Context, key string, prefix bool) ([]keyValue, int64, error) {
+	req := map[string]any{"key": b64(key)}
+	if prefix {
+		req["range_end"] = b64(prefixEnd(key))
+	}
+	var resp struct {
+		Header responseHeader `json:"header"`
+		KVs    []rawKeyValue  `json:"kvs"`
+	}
+	if err := c.call(ctx, "/v3/kv/range", req, &resp); err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]keyValue, 0, len(resp.KVs))
+	for _, raw := range resp.KVs {
+		kv, err := raw.decode()
+		if err != nil {
+			return nil, 0, err
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs, resp.Header.revision(), nil
+}
+
+func putRequest(key string, value []byte, lease int64) map[string]any {
+	req := map[string]any{"key": b64(key), "value": base64.StdEncoding.EncodeToString(value)}
+	if lease != 0 {
+		req["lease"] = strconv.FormatInt(lease, 10)
+	}
+	return req
+}
+
+func (c *client) put(ctx context.Context, key string, value []byte, lease int64) error {
+	return c.call(ctx, "/v3/kv/put", putRequest(key, value, lease), nil)
+}
+
+func (c *client) delete(ctx context.Context, key string) error {
+	return c.call(ctx, "/v3/kv/deleterange", map[string]any{"key": b64(key)}, nil)
+}
+
+// errConflict means a compare-and-swap lost a race.
+var errConflict = errors.New("concurrent modification")
+
+// putIf writes the given key/values in one transaction, provided guard has
+// not been modified since modRevision.
+func (c *client) putIf(ctx context.Context, guard string, modRevision int64, lease int64, puts map[string][]byte) error {
+	ops := make([]map[string]any, 0, len(puts))
+	for key, value := range puts {
+		ops = append(ops, map[string]any{"request_put": putRequest(key, value, lease)})
+	}
+	req := map[string]any{
+		"compare": []map[string]any{{
+			"key":          b64(guard),
+			"target":       "MOD",
+			"result":       "EQUAL",
+			"mod_revision": strconv.FormatInt(modRevision, 10),
+		}},
+		"success": ops,
+	}
+	var resp struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := c.call(ctx, "/v3/kv/txn", req, &resp); err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return errConflict
+	}
+	return nil
+}
+
+func (c *client) grant(ctx context.Context, ttlSeconds int64) (int64, error) {
+	var resp struct {
+		ID string `json:"ID"`
+	}
+	if err := c.call(ctx, "/v3/lease/grant", map[string]any{"TTL": strconv.FormatInt(ttlSeconds, 10)}, &resp); err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(resp.ID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("etcd lease grant: invalid lease ID %q", resp.ID)
+	}
+	return id, nil
+}
+
+// errLeaseExpired means a lease to keep alive no longer exists.
+var errLeaseExpired = errors.New("lease expired")
+
+// keepAlive restarts a lease's TTL without touching its keys, so watchers see
+// no event.
+func (c *client) keepAlive(ctx context.Context, lease int64) error {
+	var resp struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	if err := c.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": strconv.FormatInt(lease, 10)}, &resp); err != nil {
+		return err
+	}
+	if ttl, _ := strconv.ParseInt(resp.Result.TTL, 10, 64); ttl <= 0 {
+		return errLeaseExpired
+	}
+	return nil
+}
+
+// revoke ends a lease, deleting every key still attached to it.
+func (c *client) revoke(ctx context.Context, lease int64) error {
+	return c.call(ctx, "/v3/lease/revoke", map[string]any{"ID": strconv.FormatInt(lease, 10)}, nil)
+}
+
+// watch blocks until a key under prefix changes at or after startRevision,
+// returning the revision of the change. It returns 0 if ctx ends first. A
+// compacted start revision counts as a change, since events may be lost.
+func (c *client) watch(ctx context.Context, prefix string, startRevision int64) (int64, error) {
+	body, _ := json.Marshal(map[string]any{"create_request": map[string]any{
+		"key":            b64(prefix),
+		"range_end":      b64(prefixEnd(prefix)),
+		"start_revision": strconv.FormatInt(startRevision, 10),
+	}})
+
+	var lastErr error
+	for _, endpoint := range c.endpoints {
+		r, err := c.post(ctx, endpoint+"/v3/watch", body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, nil
+			}
+			lastErr = err
+			continue
+		}
+		defer r.Body.Close()
+		if err := checkStatus(r); err != nil {
+			return 0, err
+		}
+
+		dec := json.NewDecoder(r.Body)
+		for {
+			var msg struct {
+				Result struct {
+					Header          responseHeader    `json:"header"`
+					CompactRevision string            `json:"compact_revision"`
+					Canceled        bool              `json:"canceled"`
+					Events          []json.RawMessage `json:"events"`
+				} `json:"result"`
+			}
+			if err := dec.Decode(&msg); err != nil {
+				if ctx.Err() != nil {
+					return 0, nil
+				}
+				return 0, fmt.Errorf("etcd watch %s: %w", prefix, err)
+			}
+			res := msg.Result
+			if len(res.Events) > 0 || (res.CompactRevision != "" && res.CompactRevision != "0") {
+				return res.Header.revision(), nil
+			}
+			if res.Canceled {
+				return 0, fmt.Errorf("etcd watch %s: canceled", prefix)
+			}
+		}
+	}
+	return 0, fmt.Errorf("etcd watch %s: %w", prefix, lastErr)
+}
@@ -0,0 +1,505 @@
+// Package etcd implements the service registry on etcd v3. It talks to etcd's
+// JSON gateway over plain HTTP, so it needs no client library beyond net/http.
+//
+// Each instance is a JSON record at <prefix>services/<name>/<id>, with an
+// index key at <prefix>ids/<id> naming its service and its latest health
+// report at <prefix>health/<name>/<id>. All three are bound to a lease that
+// each health report keeps alive, which gives Consul's TTL semantics: an
+// instance that misses its TTL reads as unhealthy, and one that stays silent
+// for DeregisterCriticalAfter beyond that is deleted with its lease. A report
+// rewrites the record only when the status changes, so watchers of the
+// services prefix are not woken by every heartbeat.
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// Config configures the etcd registry.
+type Config struct {
+	// Endpoints are etcd client URLs, tried in order.
+	Endpoints []string
+	// Prefix namespaces the registry's keys. KV methods use keys as given.
+	Prefix string
+	// RequestTimeout bounds each non-blocking call.
+	RequestTimeout time.Duration
+	// DeregisterCriticalAfter is how long an instance may miss its TTL
+	// before it is removed, matching the Consul check setting.
+	DeregisterCriticalAfter time.Duration
+}
+
+// DefaultConfig returns settings for a local etcd.
+func DefaultConfig() Config {
+	return Config{
+		Endpoints:               []string{"http://localhost:2379"},
+		Prefix:                  "toska-mesh/registry/",
+		RequestTimeout:          10 * time.Second,
+		DeregisterCriticalAfter: time.Minute,
+	}
+}
+
+// Registry is an etcd-backed service registry.
+type Registry struct {
+	client *client
+	config Config
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewRegistry creates a Registry for the configured endpoints.
+func NewRegistry(config Config, logger *slog.Logger) (*Registry, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd: no endpoints configured")
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+	endpoints := make([]string, len(config.Endpoints))
+	for i, e := range config.Endpoints {
+		endpoints[i] = strings.TrimRight(e, "/")
+	}
+	return &Registry{
+		client: &client{endpoints: endpoints, http: &http.Client{}},
+		config: config,
+		logger: logger,
+		now:    time.Now,
+	}, nil
+}
+
+// record is the stored form of an instance.
+type record struct {
+	ServiceName  string             `json:"serviceName"`
+	ServiceID    string             `json:"serviceId"`
+	Address      string             `json:"address"`
+	Port         int                `json:"port"`
+	Metadata     map[string]string  `json:"metadata,omitempty"`
+	RegisteredAt time.Time          `json:"registeredAt"`
+	Status       types.HealthStatus `json:"status"`
+	TTLSeconds   int64              `json:"ttlSeconds"`
+}
+
+// healthRecord is the stored form of an instance's latest health report.
+type healthRecord struct {
+	Output    string    `json:"output,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// instance combines rec with its health report. A missing report reads as
+// an expired TTL.
+func (rec record) instance(health healthRecord, now time.Time) types.Instance {
+	status := rec.Status
+	if now.After(health.CheckedAt.Add(time.Duration(rec.TTLSeconds) * time.Second)) {
+		status = types.HealthUnhealthy // TTL expired, like a critical Consul check
+	}
+	meta := make(map[string]string, len(rec.Metadata))
+	for k, v := range rec.Metadata {
+		meta[k] = v
+	}
+	return types.Instance{
+		ServiceName:     rec.ServiceName,
+		ServiceID:       rec.ServiceID,
+		Address:         rec.Address,
+		Port:            rec.Port,
+		Status:          status,
+		Metadata:        meta,
+		RegisteredAt:    rec.RegisteredAt,
+		LastHealthCheck: health.CheckedAt,
+	}
+}
+
+func (r *Registry) servicesPrefix() string { return r.config.Prefix + "services/" }
+
+func (r *Registry) serviceKey(name, id string) string {
+	return r.servicesPrefix() + name + "/" + id
+}
+
+func (r *Registry) idKey(id string) string { return r.config.Prefix + "ids/" + id }
+
+func (r *Registry) healthPrefix(name string) string { return r.config.Prefix + "health/" + name + "/" }
+
+func (r *Registry) healthKey(name, id string) string { return r.healthPrefix(name) + id }
+
+func (r *Registry) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), r.config.RequestTimeout)
+}
+
+// leaseTTL is how long a record lives without a health report.
+func (r *Registry) leaseTTL(ttlSeconds int64) int64 {
+	return ttlSeconds + int64(r.config.DeregisterCriticalAfter/time.Second)
+}
+
+// Register stores an instance under a fresh lease, passing its health check.
+// Re-registering an ID revokes its previous lease, removing the old record
+// even if the service name changed.
+func (r *Registry) Register(reg types.Registration) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	now := r.now().UTC()
+	rec := record{
+		ServiceName:  reg.ServiceName,
+		ServiceID:    reg.ServiceID,
+		Address:      reg.Address,
+		Port:         reg.Port,
+		Metadata:     reg.Metadata,
+		RegisteredAt: now,
+		Status:       types.HealthHealthy,
+		TTLSeconds:   int64(reg.TTLWithBuffer() / time.Second),
+	}
+	health := healthRecord{Output: "Service registered", CheckedAt: now}
+
+	previous, _, err := r.client.get(ctx, r.idKey(reg.ServiceID), false)
+	if err != nil {
+		return fmt.Errorf("etcd register: %w", err)
+	}
+	if err := r.store(ctx, rec, health); err != nil {
+		return fmt.Errorf("etcd register: %w", err)
+	}
+	if len(previous) > 0 && previous[0].Lease != 0 {
+		if err := r.client.revoke(ctx, previous[0].Lease); err != nil {
+			r.logger.Warn("failed to revoke previous lease", "service_id", reg.ServiceID, "error", err)
+		}
+	}
+
+	r.logger.Info("registered service", "service_id", reg.ServiceID, "service_name", reg.ServiceName)
+	return nil
+}
+
+// store writes rec, its index key and its health report under a new lease.
+func (r *Registry) store(ctx context.Context, rec record, health healthRecord) error {
+	lease, err := r.client.grant(ctx, r.leaseTTL(rec.TTLSeconds))
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := r.client.put(ctx, r.serviceKey(rec.ServiceName, rec.ServiceID), value, lease); err != nil {
+		return err
+	}
+	if err := r.client.put(ctx, r.idKey(rec.ServiceID), []byte(rec.ServiceName), lease); err != nil {
+		return err
+	}
+	return r.putHealth(ctx, rec, health, lease)
+}
+
+// putHealth writes an instance's health report under lease.
+func (r *Registry) putHealth(ctx context.Context, rec record, health healthRecord, lease int64) error {
+	value, err := json.Marshal(health)
+	if err != nil {
+		return err
+	}
+	return r.client.put(ctx, r.healthKey(rec.ServiceName, rec.ServiceID), value, lease)
+}
+
+// health returns the health reports of a service's instances, keyed by ID.
+func (r *Registry) health(ctx context.Context, serviceName string) (map[string]healthRecord, error) {
+	prefix := r.healthPrefix(serviceName)
+	kvs, _, err := r.client.get(ctx, prefix, true)
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[string]healthRecord, len(kvs))
+	for _, kv := range kvs {
+		var h healthRecord
+		if err := json.Unmarshal(kv.Value, &h); err != nil {
+			r.logger.Warn("skipping unreadable health record", "key", string(kv.Key), "error", err)
+			continue
+		}
+		reports[strings.TrimPrefix(string(kv.Key), prefix)] = h
+	}
+	return reports, nil
+}
+
+// lookup returns an instance's record and its key/value, or ErrNotRegistered.
+func (r *Registry) lookup(ctx context.Context, serviceID string) (record, keyValue, error) {
+	ids, _, err := r.client.get(ctx, r.idKey(serviceID), false)
+	if err != nil {
+		return record{}, keyValue{}, err
+	}
+	if len(ids) == 0 {
+		return record{}, keyValue{}, fmt.Errorf("service %s %w", serviceID, types.ErrNotRegistered)
+	}
+	kvs, _, err := r.client.get(ctx, r.serviceKey(string(ids[0].Value), serviceID), false)
+	if err != nil {
+		return record{}, keyValue{}, err
+	}
+	if len(kvs) == 0 {
+		return record{}, keyValue{}, fmt.Errorf("service %s %w", serviceID, types.ErrNotRegistered)
+	}
+	var rec record
+	if err := json.Unmarshal(kvs[0].Value, &rec); err != nil {
+		return record{}, keyValue{}, fmt.Errorf("decode %s: %w", kvs[0].Key, err)
+	}
+	return rec, kvs[0], nil
+}
+
+// Deregister removes an instance by revoking its lease.
+func (r *Registry) Deregister(serviceID string) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	_, kv, err := r.lookup(ctx, serviceID)
+	if err != nil {
+		return fmt.Errorf("etcd deregister: %w", err)
+	}
+	if err := r.client.revoke(ctx, kv.Lease); err != nil {
+		return fmt.Errorf("etcd deregister: %w", err)
+	}
+
+	r.logger.Info("deregistered service", "service_id", serviceID)
+	return nil
+}
+
+// GetInstances returns all instances of a service, including health status.
+func (r *Registry) GetInstances(serviceName string) ([]types.Instance, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	kvs, _, err := r.client.get(ctx, r.servicesPrefix()+serviceName+"/", true)
+	if err != nil {
+		return nil, fmt.Errorf("etcd get instances: %w", err)
+	}
+	health, err := r.health(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("etcd get instances: %w", err)
+	}
+	now := r.now()
+	instances := make([]types.Instance, 0, len(kvs))
+	for _, kv := range kvs {
+		var rec record
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			r.logger.Warn("skipping unreadable instance record", "key", string(kv.Key), "error", err)
+			continue
+		}
+		instances = append(instances, rec.instance(health[rec.ServiceID], now))
+	}
+	return instances, nil
+}
+
+// GetServices returns the names of all services with at least one instance.
+func (r *Registry) GetServices() ([]string, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	prefix := r.servicesPrefix()
+	kvs, _, err := r.client.get(ctx, prefix, true)
+	if err != nil {
+		return nil, fmt.Errorf("etcd get services: %w", err)
+	}
+	var names []string
+	for _, kv := range kvs {
+		name, _, ok := strings.Cut(strings.TrimPrefix(string(kv.Key), prefix), "/")
+		if ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// GetInstance returns a single service instance by ID, or nil if not found.
+func (r *Registry) GetInstance(serviceID string) (*types.Instance, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	rec, _, err := r.lookup(ctx, serviceID)
+	if errors.Is(err, types.ErrNotRegistered) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("etcd get instance: %w", err)
+	}
+	health, err := r.health(ctx, rec.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("etcd get instance: %w", err)
+	}
+	inst := rec.instance(health[serviceID], r.now())
+	return &inst, nil
+}
+
+// maxUpdateAttempts bounds compare-and-swap retries when health reports and
+// metadata updates race on the same instance.
+const maxUpdateAttempts = 3
+
+// modify applies fn to an instance's record and writes it back atomically,
+// keeping its lease.
+func (r *Registry) modify(ctx context.Context, serviceID string, fn func(*record)) (record, record, error) {
+	for attempt := 0; ; attempt++ {
+		rec, kv, err := r.lookup(ctx, serviceID)
+		if err != nil {
+			return record{}, record{}, err
+		}
+		previous := rec
+		previous.Metadata = make(map[string]string, len(rec.Metadata))
+		for k, v := range rec.Metadata {
+			previous.Metadata[k] = v
+		}
+		fn(&rec)
+
+		value, err := json.Marshal(rec)
+		if err != nil {
+			return record{}, record{}, err
+		}
+		err = r.client.putIf(ctx, string(kv.Key), kv.ModRevision, kv.Lease, map[string][]byte{
+			string(kv.Key):         value,
+			r.idKey(rec.ServiceID): []byte(rec.ServiceName),
+		})
+		if errors.Is(err, errConflict) && attempt+1 < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return record{}, record{}, err
+		}
+		return previous, rec, nil
+	}
+}
+
+// UpdateHealth records a health report, renewing the instance's TTL. The
+// lease is kept alive and the report written to the instance's health key;
+// the record itself is rewritten only when the status changes.
+func (r *Registry) UpdateHealth(serviceID string, status types.HealthStatus, output string) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	if status == types.HealthUnknown {
+		status = types.HealthHealthy
+	}
+	rec, kv, err := r.lookup(ctx, serviceID)
+	if err != nil {
+		return fmt.Errorf("etcd update health: %w", err)
+	}
+	if err := r.client.keepAlive(ctx, kv.Lease); errors.Is(err, errLeaseExpired) {
+		return fmt.Errorf("etcd update health: service %s %w", serviceID, types.ErrNotRegistered)
+	} else if err != nil {
+		return fmt.Errorf("etcd update health: %w", err)
+	}
+	if rec.Status != status {
+		if _, _, err := r.modify(ctx, serviceID, func(rec *record) { rec.Status = status }); err != nil {
+			return fmt.Errorf("etcd update health: %w", err)
+		}
+	}
+	if err := r.putHealth(ctx, rec, healthRecord{Output: output, CheckedAt: r.now().UTC()}, kv.Lease); err != nil {
+		return fmt.Errorf("etcd update health: %w", err)
+	}
+	return nil
+}
+
+// UpdateMetadata merges meta into the metadata of a service.
+func (r *Registry) UpdateMetadata(serviceID string, meta map[string]string) error {
+	_, err := r.ApplyMetadata(serviceID, types.MetadataUpdate{Set: meta})
+	return err
+}
+
+// ApplyMetadata applies update to the metadata of a service, keeping its
+// lease.
+func (r *Registry) ApplyMetadata(serviceID string, update types.MetadataUpdate) (types.MetadataChange, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+
+	previous, current, err := r.modify(ctx, serviceID, func(rec *record) {
+		meta := make(map[string]string, len(rec.Metadata)+len(update.Set))
+		if !update.Replace {
+			for k, v := range rec.Metadata {
+				meta[k] = v
+			}
+		}
+		for k, v := range update.Set {
+			meta[k] = v
+		}
+		for _, k := range update.Remove {
+			delete(meta, k)
+		}
+		rec.Metadata = meta
+	})
+	if err != nil {
+		return types.MetadataChange{}, fmt.Errorf("etcd update metadata: %w", err)
+	}
+	if current.Metadata == nil {
+		current.Metadata = map[string]string{}
+	}
+	return types.MetadataChange{ServiceName: current.ServiceName, Previous: previous.Metadata, Current: current.Metadata}, nil
+}
+
+// WaitForChange watches the instance records and returns once a change after
+// index arrives or wait elapses. Pass index 0 to return immediately with the
+// current revision.
+func (r *Registry) WaitForChange(ctx context.Context, index uint64, wait time.Duration) (uint64, error) {
+	return r.waitPrefix(ctx, r.servicesPrefix(), index, wait)
+}
+
+func (r *Registry) waitPrefix(ctx context.Context, prefix string, index uint64, wait time.Duration) (uint64, error) {
+	if index > 0 {
+		watchCtx, cancel := context.WithTimeout(ctx, wait)
+		rev, err := r.client.watch(watchCtx, prefix, int64(index)+1)
+		cancel()
+		if err != nil {
+			return index, fmt.Errorf("etcd wait for change: %w", err)
+		}
+		if rev == 0 {
+			return index, nil // wait elapsed without a change
+		}
+		return uint64(rev), nil
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, r.config.RequestTimeout)
+	defer cancel()
+	_, rev, err := r.client.get(rctx, prefix, true)
+	if err != nil {
+		return index, fmt.Errorf("etcd wait for change: %w", err)
+	}
+	return uint64(rev), nil
+}
+
+// ListKV returns the keys under prefix once they change after index or wait
+// elapses, with the revision to pass on the next call. Pass index 0 to return
+// immediately.
+func (r *Registry) ListKV(ctx context.Context, prefix string, index uint64, wait time.Duration) (map[string][]byte, uint64, error) {
+	if index > 0 {
+		if _, err := r.waitPrefix(ctx, prefix, index, wait); err != nil {
+			return nil, index, fmt.Errorf("etcd kv list %s: %w", prefix, err)
+		}
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, r.config.RequestTimeout)
+	defer cancel()
+	kvs, rev, err := r.client.get(rctx, prefix, true)
+	if err != nil {
+		return nil, index, fmt.Errorf("etcd kv list %s: %w", prefix, err)
+	}
+	out := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		out[string(kv.Key)] = kv.Value
+	}
+	return out, uint64(rev), nil
+}
+
+// PutKV writes value to key.
+func (r *Registry) PutKV(key string, value []byte) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	if err := r.client.put(ctx, key, value, 0); err != nil {
+		return fmt.Errorf("etcd kv put %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeleteKV removes key.
+func (r *Registry) DeleteKV(key string) error {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	if err := r.client.delete(ctx, key); err != nil {
+		return fmt.Errorf("etcd kv delete %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package etcd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// fakeEtcd implements the parts of the etcd v3 JSON gateway the registry uses.
+type fakeEtcd struct {
+	mu        sync.Mutex
+	revision  int64
+	nextLease int64
+	kvs       map[string]fakeKV
+	leases    map[int64]int64 // lease ID to TTL
+}
+
+type fakeKV struct {
+	value  []byte
+	modRev int64
+	lease  int64
+}
+
+func newFakeEtcd(t *testing.T) (*fakeEtcd, *Registry) {
+	t.Helper()
+	f := &fakeEtcd{revision: 1, kvs: map[string]fakeKV{}, leases: map[int64]int64{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig()
+	cfg.Endpoints = []string{srv.URL}
+	reg, err := NewRegistry(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, reg
+}
+
+func unb64(s string) string {
+	b, _ := base64.StdEncoding.DecodeString(s)
+	return string(b)
+}
+
+func atoi(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+func (f *fakeEtcd) inRange(key, start, end string) bool {
+	if end == "" {
+		return key == start
+	}
+	return key >= start && (end == "\x00" || key < end)
+}
+
+func (f *fakeEtcd) putLocked(key string, value []byte, lease int64) {
+	f.revision++
+	f.kvs[key] = fakeKV{value: value, modRev: f.revision, lease: lease}
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]any
+	json.NewDecoder(r.Body).Decode(&req)
+	str := func(m map[string]any, k string) string { s, _ := m[k].(string); return s }
+
+	if r.URL.Path == "/v3/watch" {
+		f.watch(w, r, req["create_request"].(map[string]any))
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	header := map[string]string{"revision": strconv.FormatInt(f.revision, 10)}
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		start, end := unb64(str(req, "key")), unb64(str(req, "range_end"))
+		var kvs []map[string]string
+		for _, key := range slices.Sorted(maps.Keys(f.kvs)) {
+			if f.inRange(key, start, end) {
+				kv := f.kvs[key]
+				kvs = append(kvs, map[string]string{
+					"key":          base64.StdEncoding.EncodeToString([]byte(key)),
+					"value":        base64.StdEncoding.EncodeToString(kv.value),
+					"mod_revision": strconv.FormatInt(kv.modRev, 10),
+					"lease":        strconv.FormatInt(kv.lease, 10),
+				})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"header": header, "kvs": kvs})
+	case "/v3/kv/put":
+		lease := atoi(str(req, "lease"))
+		if _, ok := f.leases[lease]; lease != 0 && !ok {
+			http.Error(w, `{"message":"etcdserver: requested lease not found"}`, http.StatusNotFound)
+			return
+		}
+		value, _ := base64.StdEncoding.DecodeString(str(req, "value"))
+		f.putLocked(unb64(str(req, "key")), value, lease)
+		json.NewEncoder(w).Encode(map[string]any{"header": header})
+	case "/v3/kv/deleterange":
+		f.revision++
+		delete(f.kvs, unb64(str(req, "key")))
+		json.NewEncoder(w).Encode(map[string]any{"header": header})
+	case "/v3/kv/txn":
+		cmp := req["compare"].([]any)[0].(map[string]any)
+		if f.kvs[unb64(str(cmp, "key"))].modRev != atoi(str(cmp, "mod_revision")) {
+			json.NewEncoder(w).Encode(map[string]any{"header": header, "succeeded": false})
+			return
+		}
+		for _, op := range req["success"].([]any) {
+			put := op.(map[string]any)["request_put"].(map[string]any)
+			value, _ := base64.StdEncoding.DecodeString(str(put, "value"))
+			f.putLocked(unb64(str(put, "key")), value, atoi(str(put, "lease")))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"header": header, "succeeded": true})
+	case "/v3/lease/grant":
+		f.nextLease++
+		f.leases[f.nextLease] = atoi(str(req, "TTL"))
+		json.NewEncoder(w).Encode(map[string]any{"header": header, "ID": strconv.FormatInt(f.nextLease, 10), "TTL": str(req, "TTL")})
+	case "/v3/lease/keepalive":
+		lease := atoi(str(req, "ID"))
+		ttl := f.leases[lease] // 0 once revoked
+		json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{"header": header, "ID": str(req, "ID"), "TTL": strconv.FormatInt(ttl, 10)}})
+	case "/v3/lease/revoke":
+		lease := atoi(str(req, "ID"))
+		delete(f.leases, lease)
+		for key, kv := range f.kvs {
+			if kv.lease == lease {
+				f.revision++
+				delete(f.kvs, key)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"header": header})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// watch reports an event if any key in range changed at or after the start
+// revision, otherwise it holds the stream open until the client gives up.
+func (f *fakeEtcd) watch(w http.ResponseWriter, r *http.Request, create map[string]any) {
+	start, _ := create["key"].(string)
+	end, _ := create["range_end"].(string)
+	startRev := atoi(create["start_revision"].(string))
+
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]any{"result": map[string]any{"created": true}})
+	w.(http.Flusher).Flush()
+
+	for {
+		f.mu.Lock()
+		changed := false
+		for key, kv := range f.kvs {
+			if f.inRange(key, unb64(start), unb64(end)) && kv.modRev >= startRev {
+				changed = true
+			}
+		}
+		rev := f.revision
+		f.mu.Unlock()
+		if changed {
+			enc.Encode(map[string]any{"result": map[string]any{
+				"header": map[string]string{"revision": strconv.FormatInt(rev, 10)},
+				"events": []map[string]any{{"type": "PUT"}},
+			}})
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestRegistry_RegisterAndQuery(t *testing.T) {
+	f, reg := newFakeEtcd(t)
+
+	for _, r := range []types.Registration{
+		{ServiceName: "orders", ServiceID: "orders-1", Address: "10.0.0.1", Port: 8080, Metadata: map[string]string{"version": "2"}},
+		{ServiceName: "orders", ServiceID: "orders-2", Address: "10.0.0.2", Port: 8080},
+		{ServiceName: "payments", ServiceID: "payments-1", Address: "10.0.0.3", Port: 9090},
+	} {
+		if err := reg.Register(r); err != nil {
+			t.Fatalf("Register %s: %v", r.ServiceID, err)
+		}
+	}
+
+	names, err := reg.GetServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"orders", "payments"}) {
+		t.Fatalf("expected orders and payments, got %v", names)
+	}
+
+	instances, err := reg.GetInstances("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 || instances[0].ServiceID != "orders-1" || instances[0].Metadata["version"] != "2" {
+		t.Fatalf("unexpected instances %+v", instances)
+	}
+	if instances[0].Status != types.HealthHealthy {
+		t.Fatalf("expected new instance to be healthy, got %v", instances[0].Status)
+	}
+
+	// TTL is the 30s default plus buffer; the lease adds a minute for the
+	// critical grace period.
+	f.mu.Lock()
+	lease := f.kvs["toska-mesh/registry/services/orders/orders-1"].lease
+	ttl := f.leases[lease]
+	f.mu.Unlock()
+	if ttl != 95 {
+		t.Fatalf("expected lease TTL 95s, got %d", ttl)
+	}
+
+	inst, err := reg.GetInstance("payments-1")
+	if err != nil || inst == nil || inst.Port != 9090 {
+		t.Fatalf("GetInstance: %+v %v", inst, err)
+	}
+	if inst, err := reg.GetInstance("missing"); inst != nil || err != nil {
+		t.Fatalf("expected nil for unknown instance, got %+v %v", inst, err)
+	}
+}
+
+func TestRegistry_UpdateHealthAndTTLExpiry(t *testing.T) {
+	f, reg := newFakeEtcd(t)
+	now := time.Unix(1_700_000_000, 0)
+	reg.now = func() time.Time { return now }
+
+	if err := reg.Register(types.Registration{ServiceName: "orders", ServiceID: "orders-1"}); err != nil {
+		t.Fatal(err)
+	}
+	const recordKey = "toska-mesh/registry/services/orders/orders-1"
+	f.mu.Lock()
+	lease := f.kvs[recordKey].lease
+	registered := f.kvs[recordKey].modRev
+	f.mu.Unlock()
+
+	// A report with an unchanged status leaves the watched record alone.
+	now = now.Add(20 * time.Second)
+	if err := reg.UpdateHealth("orders-1", types.HealthHealthy, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	rec := f.kvs[recordKey]
+	f.mu.Unlock()
+	if rec.modRev != registered || rec.lease != lease {
+		t.Fatalf("expected an unchanged heartbeat to leave the record alone, got revision %d lease %d", rec.modRev, rec.lease)
+	}
+
+	now = now.Add(20 * time.Second) // within the 35s TTL of the last report
+	if err := reg.UpdateHealth("orders-1", types.HealthDegraded, "slow disk"); err != nil {
+		t.Fatal(err)
+	}
+	instances, _ := reg.GetInstances("orders")
+	if instances[0].Status != types.HealthDegraded || !instances[0].LastHealthCheck.Equal(now) {
+		t.Fatalf("expected degraded as of %v, got %+v", now, instances[0])
+	}
+	f.mu.Lock()
+	rec = f.kvs[recordKey]
+	f.mu.Unlock()
+	if rec.modRev == registered || rec.lease != lease {
+		t.Fatalf("expected a status change to rewrite the record on its lease, got revision %d lease %d", rec.modRev, rec.lease)
+	}
+
+	now = now.Add(36 * time.Second) // past the 35s TTL
+	instances, _ = reg.GetInstances("orders")
+	if instances[0].Status != types.HealthUnhealthy {
+		t.Fatalf("expected expired TTL to read as unhealthy, got %v", instances[0].Status)
+	}
+
+	if err := reg.UpdateHealth("missing", types.HealthHealthy, ""); !errors.Is(err, types.ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+}
+
+func TestRegistry_ApplyMetadataAndDeregister(t *testing.T) {
+	f, reg := newFakeEtcd(t)
+	reg.Register(types.Registration{ServiceName: "orders", ServiceID: "orders-1", Metadata: map[string]string{"version": "1", "zone": "a"}})
+
+	change, err := reg.ApplyMetadata("orders-1", types.MetadataUpdate{Set: map[string]string{"version": "2"}, Remove: []string{"zone"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.ServiceName != "orders" || change.Previous["version"] != "1" || !maps.Equal(change.Current, map[string]string{"version": "2"}) {
+		t.Fatalf("unexpected change %+v", change)
+	}
+	if _, err := reg.ApplyMetadata("missing", types.MetadataUpdate{}); !errors.Is(err, types.ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+
+	if err := reg.Deregister("orders-1"); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	remaining := len(f.kvs)
+	f.mu.Unlock()
+	if remaining != 0 {
+		t.Fatalf("expected deregistration to remove every key, %d left", remaining)
+	}
+	if err := reg.Deregister("orders-1"); !errors.Is(err, types.ErrNotRegistered) {
+		t.Fatalf("expected ErrNotRegistered, got %v", err)
+	}
+}
+
+func TestRegistry_WaitForChange(t *testing.T) {
+	_, reg := newFakeEtcd(t)
+	ctx := context.Background()
+
+	index, err := reg.WaitForChange(ctx, 0, time.Second)
+	if err != nil || index == 0 {
+		t.Fatalf("expected current revision, got %d %v", index, err)
+	}
+
+	// No change: the wait elapses and the index is unchanged.
+	if got, err := reg.WaitForChange(ctx, index, 50*time.Millisecond); err != nil || got != index {
+		t.Fatalf("expected unchanged index %d, got %d %v", index, got, err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		reg.Register(types.Registration{ServiceName: "orders", ServiceID: "orders-1"})
+	}()
+	got, err := reg.WaitForChange(ctx, index, 5*time.Second)
+	if err != nil || got <= index {
+		t.Fatalf("expected index past %d after a registration, got %d %v", index, got, err)
+	}
+}
+
+func TestRegistry_KV(t *testing.T) {
+	_, reg := newFakeEtcd(t)
+	ctx := context.Background()
+
+	if err := reg.PutKV("toska-mesh/maintenance/orders", []byte(`{"message":"upgrade"}`)); err != nil {
+		t.Fatal(err)
+	}
+	reg.PutKV("toska-mesh/other", []byte("x"))
+
+	values, index, err := reg.ListKV(ctx, "toska-mesh/maintenance/", 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || string(values["toska-mesh/maintenance/orders"]) != `{"message":"upgrade"}` {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		reg.DeleteKV("toska-mesh/maintenance/orders")
+		reg.PutKV("toska-mesh/maintenance/payments", []byte("{}"))
+	}()
+	values, newIndex, err := reg.ListKV(ctx, "toska-mesh/maintenance/", index, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newIndex <= index {
+		t.Fatalf("expected index to advance past %d, got %d", index, newIndex)
+	}
+	if _, ok := values["toska-mesh/maintenance/payments"]; !ok {
+		t.Fatalf("expected the new key after the watch fired, got %v", values)
+	}
+}
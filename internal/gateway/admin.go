@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+)
+
+// breakerStatus is the JSON view of a backend's circuit breaker.
+type breakerStatus struct {
+	State            string     `json:"state"`
+	FailureCount     int        `json:"failureCount"`
+	FailureThreshold int        `json:"failureThreshold"`
+	LastTransition   *time.Time `json:"lastTransition,omitempty"`
+	HalfOpenInMs     int64      `json:"halfOpenInMs"`
+}
+
+// BreakersHandler serves GET /admin/breakers: the circuit breaker of every
+// backend the proxy has sent traffic to, keyed by service ID, so operators can
+// see why requests are failing fast.
+func BreakersHandler(proxy *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshots := proxy.BreakerSnapshots()
+		out := make(map[string]breakerStatus, len(snapshots))
+		for id, snap := range snapshots {
+			status := breakerStatus{
+				State:            snap.State.String(),
+				FailureCount:     snap.FailureCount,
+				FailureThreshold: snap.FailureThreshold,
+				HalfOpenInMs:     snap.HalfOpenIn.Milliseconds(),
+			}
+			if !snap.LastTransition.IsZero() {
+				t := snap.LastTransition.UTC()
+				status.LastTransition = &t
+			}
+			out[id] = status
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+}
+
+// routesView is the JSON view of the route table.
+type routesView struct {
+	LastRefresh *time.Time         `json:"lastRefresh"`
+	Services    []serviceRouteView `json:"services"`
+}
+
+type serviceRouteView struct {
+	ServiceName string        `json:"serviceName"`
+	Backends    []backendView `json:"backends"`
+}
+
+type backendView struct {
+	ServiceID string            `json:"serviceId"`
+	Address   string            `json:"address"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Health    string            `json:"health"`  // registry health at the last refresh
+	Breaker   string            `json:"breaker"` // gateway circuit breaker state
+}
+
+// RoutesHandler serves GET /admin/routes: every routed service with its
+// backends, their breaker state, and when routes were last refreshed, to
+// debug "service not found" responses.
+func RoutesHandler(routes *RouteTable, proxy *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		breakers := proxy.BreakerStates()
+		view := routesView{Services: []serviceRouteView{}}
+		if t := routes.LastRefresh(); !t.IsZero() {
+			t = t.UTC()
+			view.LastRefresh = &t
+		}
+		for _, route := range routes.Snapshot() {
+			sv := serviceRouteView{ServiceName: route.ServiceName, Backends: []backendView{}}
+			for _, b := range route.Backends {
+				bv := backendView{
+					ServiceID: b.ServiceID,
+					Address:   b.Address,
+					Metadata:  b.Metadata,
+					Health:    b.Status.String(),
+					Breaker:   healthmonitor.BreakerClosed.String(),
+				}
+				if state, ok := breakers[b.ServiceID]; ok {
+					bv.Breaker = state.String()
+				}
+				sv.Backends = append(sv.Backends, bv)
+			}
+			view.Services = append(view.Services, sv)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view)
+	})
+}
+
+// RouteDiffHandler serves GET /admin/routes/diff: what the last route table
+// refresh that changed anything added, removed, or changed, and when.
+func RouteDiffHandler(routes *RouteTable) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routes.LastDiff())
+	})
+}
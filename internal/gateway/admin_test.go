@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBreakersHandler(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 1, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	proxy.breakers.get("svc-1").RecordFailure()
+	proxy.breakers.get("svc-2")
+
+	w := httptest.NewRecorder()
+	BreakersHandler(proxy).ServeHTTP(w, httptest.NewRequest("GET", "/admin/breakers", nil))
+
+	var got map[string]breakerStatus
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	open := got["svc-1"]
+	if open.State != "open" || open.FailureCount != 1 || open.FailureThreshold != 1 || open.LastTransition == nil {
+		t.Fatalf("unexpected open breaker status %+v", open)
+	}
+	if open.HalfOpenInMs <= 0 || open.HalfOpenInMs > time.Minute.Milliseconds() {
+		t.Fatalf("expected half-open countdown within break duration, got %d", open.HalfOpenInMs)
+	}
+
+	closed := got["svc-2"]
+	if closed.State != "closed" || closed.LastTransition != nil || closed.HalfOpenInMs != 0 {
+		t.Fatalf("unexpected closed breaker status %+v", closed)
+	}
+}
+
+func TestRoutesHandler_ShowsBackendsAndDiff(t *testing.T) {
+	services := map[string][]fakeConsulInstance{
+		"orders": {{ID: "orders-1", Address: "10.0.0.1", Port: 8080, Status: "passing"}},
+		"users":  {{ID: "users-1", Address: "10.0.0.2", Port: 8080, Status: "passing"}},
+	}
+	reg := newFakeConsul(t, services)
+	rt := NewRouteTable(reg, RoutingConfig{RoutePrefix: "/api/"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := rt.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Second refresh: users goes away, orders gains and moves a backend.
+	delete(services, "users")
+	services["orders"] = []fakeConsulInstance{
+		{ID: "orders-1", Address: "10.0.0.9", Port: 8080, Status: "passing"},
+		{ID: "orders-2", Address: "10.0.0.3", Port: 8080, Status: "passing"},
+	}
+	services["billing"] = []fakeConsulInstance{{ID: "billing-1", Address: "10.0.0.4", Port: 8080, Status: "passing"}}
+	if err := rt.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 1, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	proxy.breakers.get("orders-2").RecordFailure()
+
+	w := httptest.NewRecorder()
+	RoutesHandler(rt, proxy).ServeHTTP(w, httptest.NewRequest("GET", "/admin/routes", nil))
+	var view routesView
+	if err := json.NewDecoder(w.Body).Decode(&view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if view.LastRefresh == nil || len(view.Services) != 2 || view.Services[1].ServiceName != "orders" {
+		t.Fatalf("unexpected routes view %+v", view)
+	}
+	breakers := map[string]string{}
+	for _, b := range view.Services[1].Backends {
+		breakers[b.ServiceID] = b.Breaker
+		if b.Health != "Healthy" {
+			t.Fatalf("expected routed backend %s to be Healthy, got %q", b.ServiceID, b.Health)
+		}
+	}
+	if breakers["orders-1"] != "closed" || breakers["orders-2"] != "open" {
+		t.Fatalf("unexpected breaker states %v", breakers)
+	}
+
+	// A refresh that changes nothing keeps the last diff.
+	if err := rt.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	RouteDiffHandler(rt).ServeHTTP(w, httptest.NewRequest("GET", "/admin/routes/diff", nil))
+	var diff RouteDiff
+	if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(diff.AddedServices, []string{"billing"}) || !reflect.DeepEqual(diff.RemovedServices, []string{"users"}) {
+		t.Fatalf("unexpected service diff %+v", diff)
+	}
+	want := []ServiceDiff{{ServiceName: "orders", AddedBackends: []string{"orders-2"}, UpdatedBackends: []string{"orders-1"}}}
+	if !reflect.DeepEqual(diff.ChangedServices, want) {
+		t.Fatalf("expected %+v, got %+v", want, diff.ChangedServices)
+	}
+	if diff.PreviousRefresh.IsZero() || !diff.RefreshedAt.After(diff.PreviousRefresh) {
+		t.Fatalf("unexpected refresh times %v -> %v", diff.PreviousRefresh, diff.RefreshedAt)
+	}
+}
@@ -0,0 +1,207 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Coalescer collapses identical concurrent GET requests into one upstream
+// call. The first request (the leader) is proxied; requests with the same key
+// that arrive while it is in flight wait and receive a copy of its response.
+// Responses that must not be shared (those setting cookies or marked private
+// or no-store) and responses over CoalesceConfig.MaxResponseBytes, which are
+// streamed to the leader rather than buffered, are not copied: waiting
+// requests are proxied on their own instead. Nothing is cached once the
+// leader completes.
+type Coalescer struct {
+	config     CoalesceConfig
+	routes     *RouteTable
+	services   map[string]struct{} // lowercase; empty means all services
+	keyHeaders []string            // credentialHeaders, then config.KeyHeaders
+
+	mu       sync.Mutex
+	inFlight map[string]*coalescedCall
+}
+
+// credentialHeaders are always part of the coalescing key, whatever
+// CoalesceConfig.KeyHeaders lists, so different users never share a response.
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *recordedResponse // nil if the response may not be shared
+}
+
+// NewCoalescer creates a request coalescer.
+func NewCoalescer(config CoalesceConfig, routes *RouteTable) *Coalescer {
+	services := make(map[string]struct{}, len(config.Services))
+	for _, s := range config.Services {
+		services[strings.ToLower(s)] = struct{}{}
+	}
+	keyHeaders := slices.Clone(credentialHeaders)
+	for _, h := range config.KeyHeaders {
+		if !slices.ContainsFunc(keyHeaders, func(k string) bool { return strings.EqualFold(k, h) }) {
+			keyHeaders = append(keyHeaders, h)
+		}
+	}
+	return &Coalescer{
+		config:     config,
+		routes:     routes,
+		services:   services,
+		keyHeaders: keyHeaders,
+		inFlight:   make(map[string]*coalescedCall),
+	}
+}
+
+// Middleware returns an http.Handler that coalesces eligible requests to next.
+func (c *Coalescer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := c.key(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c.mu.Lock()
+		if call, ok := c.inFlight[key]; ok {
+			c.mu.Unlock()
+			select {
+			case <-call.done:
+				if call.resp == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("X-Coalesced", "true")
+				call.resp.writeTo(w)
+			case <-r.Context().Done():
+				http.Error(w, "request cancelled", http.StatusServiceUnavailable)
+			}
+			return
+		}
+		call := &coalescedCall{done: make(chan struct{})}
+		c.inFlight[key] = call
+		c.mu.Unlock()
+
+		rec := newRecordedResponse(w, c.config.MaxResponseBytes)
+		defer func() {
+			c.mu.Lock()
+			delete(c.inFlight, key)
+			c.mu.Unlock()
+			if rec.shareable() {
+				call.resp = rec
+			}
+			close(call.done)
+		}()
+		next.ServeHTTP(rec, r)
+		if !rec.passthrough {
+			rec.writeTo(w)
+		}
+	})
+}
+
+// key identifies requests that may share a response. It covers the target
+// URL, the headers that make responses client-specific, and any routing
+// decisions made by outer handlers (experiments, tenancy, region).
+func (c *Coalescer) key(r *http.Request) (string, bool) {
+	if r.Method != http.MethodGet || r.Header.Get("Range") != "" {
+		return "", false
+	}
+	serviceName, _, ok := c.routes.Resolve(r)
+	if !ok {
+		return "", false
+	}
+	if len(c.services) > 0 {
+		if _, ok := c.services[strings.ToLower(serviceName)]; !ok {
+			return "", false
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(r.URL.RequestURI())
+	sb.WriteByte(0)
+	sb.WriteString(serviceOverrideFromContext(r.Context()))
+	writeSortedPairs(&sb, metadataFilterFromContext(r.Context()))
+	writeSortedPairs(&sb, metadataPreferenceFromContext(r.Context()))
+	for _, h := range c.keyHeaders {
+		sb.WriteByte(0)
+		sb.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return sb.String(), true
+}
+
+func writeSortedPairs(sb *strings.Builder, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(m[k])
+	}
+}
+
+// recordedResponse captures a complete response so it can be replayed to
+// several clients. Once the body exceeds limit (if positive) it stops
+// buffering and writes the response through to the leader's client instead.
+type recordedResponse struct {
+	w           http.ResponseWriter
+	limit       int64
+	header      http.Header
+	statusCode  int
+	body        bytes.Buffer
+	passthrough bool // over limit: written through to w, not replayable
+}
+
+func newRecordedResponse(w http.ResponseWriter, limit int64) *recordedResponse {
+	return &recordedResponse{w: w, limit: limit, header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (rr *recordedResponse) Header() http.Header { return rr.header }
+
+func (rr *recordedResponse) Write(b []byte) (int, error) {
+	if rr.passthrough {
+		return rr.w.Write(b)
+	}
+	if rr.limit > 0 && int64(rr.body.Len()+len(b)) > rr.limit {
+		rr.passthrough = true
+		rr.writeTo(rr.w)
+		return rr.w.Write(b)
+	}
+	return rr.body.Write(b)
+}
+
+func (rr *recordedResponse) WriteHeader(code int) { rr.statusCode = code }
+
+// shareable reports whether the response may be replayed to other clients:
+// it was fully buffered, sets no cookie, and is not private or no-store.
+func (rr *recordedResponse) shareable() bool {
+	if rr.passthrough || len(rr.header.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	for _, v := range rr.header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(name, "private") || strings.EqualFold(name, "no-store") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (rr *recordedResponse) writeTo(w http.ResponseWriter) {
+	for k, vv := range rr.header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(rr.statusCode)
+	w.Write(rr.body.Bytes())
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalescer_CollapsesConcurrentGETs(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("X-Backend", "orders-1")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "payload")
+	})
+
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	c := NewCoalescer(CoalesceConfig{KeyHeaders: []string{"Authorization"}}, rt)
+	recorders := coalesceConcurrently(t, c.Middleware(upstream), &calls, release)
+	const n = len(recorders)
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", got)
+	}
+	coalesced := 0
+	for _, w := range recorders {
+		if w.Code != http.StatusAccepted || w.Body.String() != "payload" || w.Header().Get("X-Backend") != "orders-1" {
+			t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+		}
+		if w.Header().Get("X-Coalesced") == "true" {
+			coalesced++
+		}
+	}
+	if coalesced != n-1 {
+		t.Fatalf("expected %d coalesced responses, got %d", n-1, coalesced)
+	}
+}
+
+// coalesceConcurrently sends identical GETs through handler: one, which
+// must reach upstream and block until release is closed, then the others
+// while it is in flight.
+func coalesceConcurrently(t *testing.T, handler http.Handler, calls *atomic.Int32, release chan struct{}) [10]*httptest.ResponseRecorder {
+	t.Helper()
+	var recorders [10]*httptest.ResponseRecorder
+	var wg sync.WaitGroup
+	send := func(i int) {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(recorders[i], httptest.NewRequest("GET", "/api/orders/list?page=1", nil))
+		}()
+	}
+	send(0)
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	for i := 1; i < len(recorders); i++ {
+		send(i)
+	}
+	// Give the followers time to park behind the leader.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return recorders
+}
+
+func TestCoalescer_DoesNotShareUnshareableResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		body   string
+		limit  int64
+	}{
+		{"set-cookie", "Set-Cookie", "session=leader", "payload", 0},
+		{"private", "Cache-Control", "max-age=60, private", "payload", 0},
+		{"no-store", "Cache-Control", "no-store", "payload", 0},
+		{"over limit", "", "", "a payload over the limit", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			release := make(chan struct{})
+			upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					<-release
+				}
+				if tt.header != "" {
+					w.Header().Set(tt.header, tt.value)
+				}
+				fmt.Fprint(w, tt.body)
+			})
+			rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+			c := NewCoalescer(CoalesceConfig{MaxResponseBytes: tt.limit}, rt)
+
+			recorders := coalesceConcurrently(t, c.Middleware(upstream), &calls, release)
+			if got := calls.Load(); got != int32(len(recorders)) {
+				t.Fatalf("expected every request proxied, got %d upstream calls", got)
+			}
+			for _, w := range recorders {
+				if w.Body.String() != tt.body || w.Header().Get("X-Coalesced") != "" {
+					t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestCoalescer_Key(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	c := NewCoalescer(CoalesceConfig{Services: []string{"orders"}, KeyHeaders: []string{"Accept"}}, rt)
+
+	base := httptest.NewRequest("GET", "/api/orders/list", nil)
+	baseKey, ok := c.key(base)
+	if !ok {
+		t.Fatal("expected GET to an enabled service to be coalescable")
+	}
+
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		wantOK  bool
+		sameKey bool
+	}{
+		{"identical", func() *http.Request { return httptest.NewRequest("GET", "/api/orders/list", nil) }, true, true},
+		{"different query", func() *http.Request { return httptest.NewRequest("GET", "/api/orders/list?x=1", nil) }, true, false},
+		{"different credentials", func() *http.Request {
+			r := httptest.NewRequest("GET", "/api/orders/list", nil)
+			r.Header.Set("Authorization", "Bearer other")
+			return r
+		}, true, false},
+		{"different cookie", func() *http.Request {
+			r := httptest.NewRequest("GET", "/api/orders/list", nil)
+			r.Header.Set("Cookie", "session=other")
+			return r
+		}, true, false},
+		{"experiment override", func() *http.Request {
+			r := httptest.NewRequest("GET", "/api/orders/list", nil)
+			return r.WithContext(withServiceOverride(r.Context(), "orders-v2"))
+		}, true, false},
+		{"POST", func() *http.Request { return httptest.NewRequest("POST", "/api/orders/list", nil) }, false, false},
+		{"range request", func() *http.Request {
+			r := httptest.NewRequest("GET", "/api/orders/list", nil)
+			r.Header.Set("Range", "bytes=0-10")
+			return r
+		}, false, false},
+		{"service not enabled", func() *http.Request { return httptest.NewRequest("GET", "/api/users/list", nil) }, false, false},
+	}
+	for _, tt := range tests {
+		key, ok := c.key(tt.req())
+		if ok != tt.wantOK {
+			t.Errorf("%s: expected ok=%v, got %v", tt.name, tt.wantOK, ok)
+			continue
+		}
+		if ok && (key == baseKey) != tt.sameKey {
+			t.Errorf("%s: expected sameKey=%v", tt.name, tt.sameKey)
+		}
+	}
+}
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
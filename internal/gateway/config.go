@@ -2,7 +2,24 @@
 // with dynamic Consul-based routing, rate limiting, CORS, JWT auth, and resilience.
 package gateway
 
-import "time"
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/auth"
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/etcd"
+	"github.com/toska-mesh/toska-mesh/internal/kubernetes"
+	"github.com/toska-mesh/toska-mesh/internal/proxyproto"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/router"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/watchdog"
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
+)
 
 // Config holds all Gateway runtime configuration.
 type Config struct {
@@ -10,12 +27,57 @@ type Config struct {
 	ConsulAddr string
 	RabbitURL  string
 
+	// RegistryBackend selects the service registry: consul (default), etcd
+	// or kubernetes, configured by Etcd and Kubernetes respectively.
+	RegistryBackend string
+	Etcd            etcd.Config
+	Kubernetes      kubernetes.Config
+
+	// ConsulDatacenters, if set, are the datacenters routes are built from.
+	// Instances are labelled with their datacenter, so Balancer can prefer
+	// its LocalDatacenter.
+	ConsulDatacenters []string
+	// ConsulReads allows stale or agent-cached Consul lookups.
+	ConsulReads consul.ReadConfig
+
+	Server     ServerConfig
 	Routing    RoutingConfig
 	RateLimit  RateLimitConfig
 	CORS       CORSConfig
 	JWT        JWTConfig
 	Resilience ResilienceConfig
+	Response   ResponseConfig
+	Balancer   router.Config
 	Dashboard  DashboardConfig
+	Queue      QueueConfig
+	Tenancy    TenancyConfig
+	MeshHealth MeshHealthConfig
+	Plugins    PluginConfig
+	Priority   PriorityConfig
+	Watchdog   watchdog.Config
+
+	Maintenance MaintenanceConfig
+	Stats       StatsConfig
+	GRPCWeb     GRPCWebConfig
+	Telemetry   telemetry.Config
+	Experiments ExperimentConfig
+	Geo         GeoConfig
+	Coalesce    CoalesceConfig
+	Readiness   ReadinessConfig
+	Signing     SigningConfig
+}
+
+// RegistryConfig returns the settings for registry.New.
+func (c Config) RegistryConfig() registry.Config {
+	return registry.Config{
+		Backend:       c.RegistryBackend,
+		ConsulAddress: c.ConsulAddr,
+		Etcd:          c.Etcd,
+		Kubernetes:    c.Kubernetes,
+
+		ConsulDatacenters: c.ConsulDatacenters,
+		ConsulReads:       c.ConsulReads,
+	}
 }
 
 // DefaultConfig returns sensible defaults matching the C# appsettings.json.
@@ -23,14 +85,35 @@ func DefaultConfig() Config {
 	return Config{
 		Port:       "5000",
 		ConsulAddr: "http://localhost:8500",
+
+		RegistryBackend: registry.BackendConsul,
+		Etcd:            etcd.DefaultConfig(),
+		Kubernetes:      kubernetes.DefaultConfig(),
+
+		Server: ServerConfig{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   10 * time.Second,
+			ProxyProtocol: ProxyProtocolConfig{
+				HeaderTimeout: 5 * time.Second,
+			},
+		},
 		Routing: RoutingConfig{
 			RoutePrefix:     "/api/",
 			RefreshInterval: 30 * time.Second,
+			WatchEnabled:    true,
+			WatchWaitTime:   5 * time.Minute,
+			WatchDebounce:   500 * time.Millisecond,
+			StartupTimeout:  10 * time.Second,
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:       true,
 			PermitLimit:   100,
 			WindowSeconds: 60,
+			MaxBuckets:    DefaultRateLimitMaxBuckets,
 		},
 		CORS: CORSConfig{
 			AllowAnyOrigin: true,
@@ -49,19 +132,163 @@ func DefaultConfig() Config {
 			BreakerFailureThreshold: 3,
 			BreakerBreakDuration:    20 * time.Second,
 		},
+		Response: ResponseConfig{
+			MaxBytes: 10 << 20,
+			Overflow: OverflowError,
+		},
+		Queue: QueueConfig{
+			Enabled:                 false,
+			MaxConcurrentPerBackend: 100,
+			MaxQueueSize:            1000,
+			QueueTimeout:            2 * time.Second,
+		},
+		Tenancy: TenancyConfig{
+			Enabled:     false,
+			PathPrefix:  "/t/",
+			TenantClaim: "tenant",
+			MetadataKey: "tenant",
+		},
+		Priority: PriorityConfig{
+			Enabled:         false,
+			Claim:           "priority",
+			MaxInFlightLow:  800,
+			MaxInFlightHigh: 1000,
+		},
+		Watchdog:  watchdog.DefaultConfig(),
+		Telemetry: telemetry.DefaultConfig("gateway"),
+		Experiments: ExperimentConfig{
+			VariantHeader: "X-Experiment-Variant",
+		},
+		Readiness: ReadinessConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Coalesce: CoalesceConfig{
+			Enabled:          false,
+			KeyHeaders:       []string{"Accept", "Accept-Encoding", "Accept-Language"},
+			MaxResponseBytes: 10 << 20,
+		},
+		Geo: GeoConfig{
+			RateLimitWindowSeconds: 60,
+			RegionMetadataKey:      "region",
+		},
+		GRPCWeb: GRPCWebConfig{
+			Enabled:    false,
+			PathPrefix: "/grpc-web/",
+			Target:     "localhost:8080",
+			AllowedMethods: []string{
+				"toskamesh.discovery.DiscoveryRegistry/GetServices",
+				"toskamesh.discovery.DiscoveryRegistry/GetInstances",
+				"toskamesh.discovery.DiscoveryRegistry/GetInstance",
+			},
+			MaxTimeout: 30 * time.Second,
+		},
+		Stats: StatsConfig{
+			PersistInterval: time.Minute,
+		},
+		Maintenance: MaintenanceConfig{
+			KVPrefix:          "toska-mesh/maintenance/",
+			DefaultMessage:    "service is undergoing maintenance",
+			DefaultRetryAfter: 5 * time.Minute,
+			WatchWaitTime:     5 * time.Minute,
+		},
+		Signing: SigningConfig{
+			KVPrefix:      "toska-mesh/signing-keys/",
+			MaxClockSkew:  5 * time.Minute,
+			MaxBodyBytes:  10 << 20,
+			WatchWaitTime: 5 * time.Minute,
+		},
+		MeshHealth: MeshHealthConfig{
+			HealthMonitorURL: "http://localhost:5005",
+			Timeout:          2 * time.Second,
+		},
 		Dashboard: DashboardConfig{
-			PrometheusBaseURL:    "http://localhost:9090",
-			TracingBaseURL:       "http://localhost:5004",
-			DiscoveryBaseURL:     "http://localhost:5010",
-			HealthMonitorBaseURL: "http://localhost:5005",
+			Upstreams: []DashboardUpstream{
+				{Name: "prometheus", BaseURL: "http://localhost:9090", PathTemplate: "/api/v1{path}"},
+				{Name: "traces", BaseURL: "http://localhost:5004", PathTemplate: "/api/traces{path}"},
+				{Name: "discovery", BaseURL: "http://localhost:5010", PathTemplate: "/api/ServiceDiscovery{path}"},
+				{Name: "health", BaseURL: "http://localhost:5005", PathTemplate: "/api/status{path}"},
+			},
 		},
 	}
 }
 
+// ServerConfig bounds how long clients may hold connections and how large
+// their request headers may be, so slow or oversized clients cannot exhaust
+// the gateway. ReadHeaderTimeout is what defeats slowloris-style attacks;
+// ReadTimeout and WriteTimeout also cover bodies. Zero disables a timeout.
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration // grace period for in-flight requests
+
+	ProxyProtocol ProxyProtocolConfig
+}
+
+// ProxyProtocolConfig enables PROXY protocol (v1 and v2) parsing on the
+// listener, for deployments behind an L4 load balancer. Without it every
+// request appears to come from the load balancer, so per-IP rate limits and
+// GeoIP rules see one client.
+type ProxyProtocolConfig struct {
+	Enabled bool
+	// TrustedProxies lists the CIDRs (or bare IPs) of the load balancers
+	// allowed to send headers. Empty trusts every peer, which is only safe
+	// when the gateway is unreachable except through the load balancer.
+	TrustedProxies []string
+	// Required rejects connections from trusted proxies that lack a header.
+	Required      bool
+	HeaderTimeout time.Duration
+}
+
+// ListenerConfig converts the config for proxyproto.NewListener.
+func (c ProxyProtocolConfig) ListenerConfig() (proxyproto.Config, error) {
+	out := proxyproto.Config{Required: c.Required, HeaderTimeout: c.HeaderTimeout}
+	for _, s := range c.TrustedProxies {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return proxyproto.Config{}, fmt.Errorf("trusted proxy %q: %w", s, err)
+			}
+			out.TrustedProxies = append(out.TrustedProxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return proxyproto.Config{}, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		out.TrustedProxies = append(out.TrustedProxies, prefix.Masked())
+	}
+	return out, nil
+}
+
 // RoutingConfig controls dynamic route building from Consul.
 type RoutingConfig struct {
 	RoutePrefix     string
-	RefreshInterval time.Duration
+	RefreshInterval time.Duration // fallback polling interval
+
+	// WatchEnabled uses Consul blocking queries to refresh routes as soon as
+	// instances or health checks change.
+	WatchEnabled  bool
+	WatchWaitTime time.Duration // max duration of a single blocking query
+	WatchDebounce time.Duration // minimum delay between watch-triggered refreshes
+
+	// WarmServices are critical services whose routes are fetched before the
+	// first full refresh.
+	WarmServices []string
+	// StartupTimeout bounds how long the gateway waits for the initial route
+	// snapshot before it starts accepting traffic anyway.
+	StartupTimeout time.Duration
+
+	// Rules route requests by method and path pattern ahead of the
+	// {RoutePrefix}{service}/... convention. Rule paths must lie under
+	// RoutePrefix to reach the proxy.
+	Rules []routing.Rule
+
+	// TenantPrefix, if set, is TenancyConfig.PathPrefix: tenant-scoped
+	// paths are resolved as the path after their tenant segment.
+	TenantPrefix string
 }
 
 // RateLimitConfig controls per-client-IP rate limiting.
@@ -69,6 +296,7 @@ type RateLimitConfig struct {
 	Enabled       bool
 	PermitLimit   int
 	WindowSeconds int
+	MaxBuckets    int // tracked clients before LRU eviction
 }
 
 // CORSConfig controls Cross-Origin Resource Sharing headers.
@@ -77,17 +305,19 @@ type CORSConfig struct {
 	AllowedOrigins []string
 	AllowedHeaders []string
 	AllowedMethods []string
-}
 
-// JWTConfig controls JWT bearer token validation.
-type JWTConfig struct {
-	SecretKey        string
-	Issuer           string
-	Audience         string
-	ValidateIssuer   bool
-	ValidateAudience bool
+	// PassthroughServices handle CORS themselves: the gateway forwards their
+	// preflights and adds no CORS headers to their responses.
+	PassthroughServices []string
 }
 
+// JWTConfig controls JWT bearer token validation; the settings are shared
+// with the other mesh APIs through the auth package.
+type JWTConfig = auth.Config
+
+// JWTOverride replaces the issuer, audience, or key set for one service.
+type JWTOverride = auth.Override
+
 // ResilienceConfig controls retry and circuit breaker behavior.
 type ResilienceConfig struct {
 	RetryCount              int
@@ -96,13 +326,280 @@ type ResilienceConfig struct {
 	RetryJitterMax          time.Duration
 	BreakerFailureThreshold int
 	BreakerBreakDuration    time.Duration
+
+	// Services holds per-service overrides keyed by lowercase service name.
+	// They take precedence over the same settings in Consul metadata
+	// (retry_count, retry_base_delay_ms, breaker_failure_threshold,
+	// breaker_break_seconds).
+	Services map[string]ResilienceOverride
+}
+
+// ResilienceOverride replaces individual resilience settings for one service.
+// Nil fields keep the inherited value.
+type ResilienceOverride struct {
+	RetryCount              *int `json:"retryCount,omitempty"`
+	RetryBaseDelayMs        *int `json:"retryBaseDelayMs,omitempty"`
+	BreakerFailureThreshold *int `json:"breakerFailureThreshold,omitempty"`
+	BreakerBreakSeconds     *int `json:"breakerBreakSeconds,omitempty"`
+}
+
+func (o ResilienceOverride) apply(rc ResilienceConfig) ResilienceConfig {
+	if o.RetryCount != nil {
+		rc.RetryCount = *o.RetryCount
+	}
+	if o.RetryBaseDelayMs != nil {
+		rc.RetryBaseDelay = time.Duration(*o.RetryBaseDelayMs) * time.Millisecond
+	}
+	if o.BreakerFailureThreshold != nil {
+		rc.BreakerFailureThreshold = *o.BreakerFailureThreshold
+	}
+	if o.BreakerBreakSeconds != nil {
+		rc.BreakerBreakDuration = time.Duration(*o.BreakerBreakSeconds) * time.Second
+	}
+	return rc
 }
 
-// DashboardConfig holds base URLs for dashboard proxy endpoints.
+// resilienceOverrideFromMetadata reads resilience overrides from Consul
+// service metadata. Missing or invalid values are ignored.
+func resilienceOverrideFromMetadata(meta map[string]string) ResilienceOverride {
+	field := func(key string, min int) *int {
+		v, err := strconv.Atoi(meta[key])
+		if err != nil || v < min {
+			return nil
+		}
+		return &v
+	}
+	return ResilienceOverride{
+		RetryCount:              field("retry_count", 0),
+		RetryBaseDelayMs:        field("retry_base_delay_ms", 0),
+		BreakerFailureThreshold: field("breaker_failure_threshold", 1),
+		BreakerBreakSeconds:     field("breaker_break_seconds", 1),
+	}
+}
+
+// OverflowMode selects what the proxy does with upstream responses larger than
+// the configured limit.
+type OverflowMode string
+
+const (
+	// OverflowError replies 502 with an explanatory message.
+	OverflowError OverflowMode = "error"
+	// OverflowStream buffers up to the limit, then streams the remainder.
+	OverflowStream OverflowMode = "stream"
+)
+
+// ResponseConfig bounds how much of an upstream response the proxy buffers.
+// Services can set their own limit with max_response_bytes metadata;
+// ServiceMaxBytes (keyed by lowercase service name) overrides both. A limit of
+// zero means unlimited.
+type ResponseConfig struct {
+	MaxBytes        int64
+	Overflow        OverflowMode
+	ServiceMaxBytes map[string]int64
+}
+
+// QueueConfig controls the optional per-service request queue. When all of a
+// service's backends are at MaxConcurrentPerBackend, up to MaxQueueSize requests
+// wait for up to QueueTimeout; beyond that they are rejected with 429, and
+// requests that time out in the queue get 503.
+type QueueConfig struct {
+	Enabled                 bool
+	MaxConcurrentPerBackend int
+	MaxQueueSize            int
+	QueueTimeout            time.Duration
+}
+
+// TenancyConfig controls tenant-scoped routes of the form
+// {PathPrefix}{tenant}{RoutePrefix}{service}/... The tenant must match the
+// TenantClaim of the caller's JWT, and only instances whose MetadataKey
+// metadata equals the tenant are selected.
+type TenancyConfig struct {
+	Enabled     bool
+	PathPrefix  string
+	TenantClaim string
+	MetadataKey string
+}
+
+// PriorityConfig controls two-tier load shedding of proxied requests. A request
+// is high priority if the caller's JWT has Claim set to "high" or its service
+// is listed in HighPriorityServices ("low" in the claim always downgrades).
+// Once the gateway has MaxInFlightLow requests in flight, further low-priority
+// requests get 503; high-priority requests are shed only beyond
+// MaxInFlightHigh.
+type PriorityConfig struct {
+	Enabled              bool
+	Claim                string // empty ignores token-supplied priority
+	HighPriorityServices []string
+	MaxInFlightLow       int
+	MaxInFlightHigh      int
+}
+
+// MaintenanceConfig controls per-service maintenance mode. A service is in
+// maintenance while a key {KVPrefix}{service} exists in Consul KV; the gateway
+// then answers its routes with 503 without touching its registrations. It is
+// off by default, and its admin API is only mounted behind JWT auth.
+type MaintenanceConfig struct {
+	Enabled           bool
+	KVPrefix          string
+	DefaultMessage    string
+	DefaultRetryAfter time.Duration
+	WatchWaitTime     time.Duration // Consul blocking query timeout
+}
+
+// SigningConfig controls HMAC request signing for service-to-service calls.
+// A caller signs with the key stored at {KVPrefix}{its service name} and sends
+// the requestsign headers. Verified requests to the services listed in
+// AcceptWithoutJWT (lowercase names, or "*" for all) need no JWT; others
+// still need a valid one.
+type SigningConfig struct {
+	Enabled          bool
+	KVPrefix         string
+	MaxClockSkew     time.Duration // accepted distance between signature and gateway clocks
+	MaxBodyBytes     int64         // largest body buffered for verification
+	WatchWaitTime    time.Duration // Consul blocking query timeout
+	AcceptWithoutJWT []string
+}
+
+// ExperimentConfig controls A/B routing. Each experiment splits one service's
+// clients between the service and an alternate service; the assignment is
+// reported to clients in VariantHeader as "{experiment}={variant}".
+type ExperimentConfig struct {
+	Experiments   []Experiment
+	VariantHeader string // empty disables tagging
+}
+
+// ReadinessConfig controls GET /ready. Routes must always be loaded; the
+// RabbitMQ check is opt-in because the gateway does not publish events itself.
+type ReadinessConfig struct {
+	RequireRabbitMQ bool // requires Config.RabbitURL to be reachable once
+	CheckTimeout    time.Duration
+}
+
+// CoalesceConfig controls collapsing of identical concurrent GET requests.
+// Requests share a response only if their URLs, Authorization and Cookie
+// headers, and KeyHeaders match, so responses for different credentials are
+// never mixed. Responses over MaxResponseBytes (zero means unlimited) are
+// not buffered for sharing.
+type CoalesceConfig struct {
+	Enabled          bool
+	Services         []string // empty coalesces every service
+	KeyHeaders       []string // keyed in addition to Authorization and Cookie
+	MaxResponseBytes int64
+}
+
+// GeoConfig controls GeoIP access rules and routing. Client addresses are
+// resolved against the CSV network database at DatabasePath; an empty path
+// disables GeoIP. Country codes are ISO 3166-1 alpha-2.
+type GeoConfig struct {
+	DatabasePath           string
+	BlockedCountries       []string
+	CountryRateLimits      map[string]int // permits per window per client IP
+	RateLimitWindowSeconds int
+	// PreferRegion routes clients to backends whose RegionMetadataKey
+	// metadata matches their region, when any such backend is available.
+	PreferRegion      bool
+	RegionMetadataKey string
+	CountryRegions    map[string]string // overrides the database's region
+}
+
+// GRPCWebConfig controls the gRPC-Web bridge. Browser clients call
+// {PathPrefix}{package.Service}/{Method}; the call is forwarded over gRPC to
+// Target (the discovery service by default). Only methods listed in
+// AllowedMethods, as "package.Service/Method", are exposed. The default
+// exposes the registry's read-only lookups; write methods such as Register
+// must be opted in one by one.
+type GRPCWebConfig struct {
+	Enabled        bool
+	PathPrefix     string
+	Target         string
+	AllowedMethods []string
+	MaxTimeout     time.Duration
+}
+
+// StatsConfig controls persistence of load balancer request statistics. When
+// PersistPath is set, stats are loaded from it at start-up and written back
+// every PersistInterval and on shutdown.
+type StatsConfig struct {
+	PersistPath     string
+	PersistInterval time.Duration
+}
+
+// MeshHealthConfig controls the aggregated /api/mesh/health endpoint, which
+// merges route table and breaker state with HealthMonitor probe results.
+type MeshHealthConfig struct {
+	HealthMonitorURL string // empty skips the HealthMonitor lookup
+	Timeout          time.Duration
+}
+
+// PluginConfig selects middleware plugins from pkg/gatewayplugin. Plugins in
+// Dir (Go plugin .so files) are registered at startup; only those listed in
+// Enabled are added to the chain, in that order.
+type PluginConfig struct {
+	Dir     string
+	Enabled []string
+	Config  map[string]map[string]string // per-plugin settings keyed by name
+}
+
+// DashboardConfig holds the observability upstreams exposed under
+// /api/dashboard/.
 type DashboardConfig struct {
-	PrometheusBaseURL    string
-	TracingBaseURL       string
-	DiscoveryBaseURL     string
-	HealthMonitorBaseURL string
-	ServiceAuthSecret    string // shared secret for service-to-service JWT
+	Upstreams         []DashboardUpstream
+	ServiceAuthSecret string // shared secret for service-to-service JWT
+}
+
+// DashboardUpstream maps /api/dashboard/{Name}/... to BaseURL. PathTemplate is
+// the upstream path, where "{path}" is replaced by the request path after the
+// mount point (e.g. "/api/v1{path}" maps .../prometheus/query to /api/v1/query).
+type DashboardUpstream struct {
+	Name         string `json:"name"`
+	BaseURL      string `json:"baseUrl"`
+	PathTemplate string `json:"pathTemplate"`
+}
+
+// TargetPath expands the path template for a request path remainder.
+func (u DashboardUpstream) TargetPath(path string) string {
+	if u.PathTemplate == "" {
+		return path
+	}
+	return strings.ReplaceAll(u.PathTemplate, "{path}", path)
+}
+
+// ValidateDashboardUpstreams reports the first upstream that cannot be
+// mounted under /api/dashboard/: a missing or malformed name, the reserved
+// "services" catalog route, a duplicate name, or a missing base URL.
+func ValidateDashboardUpstreams(upstreams []DashboardUpstream) error {
+	seen := make(map[string]struct{}, len(upstreams))
+	for i, u := range upstreams {
+		switch {
+		case u.Name == "":
+			return fmt.Errorf("dashboard upstream %d: name is required", i)
+		case strings.IndexFunc(u.Name, invalidUpstreamNameRune) >= 0:
+			return fmt.Errorf("dashboard upstream %q: name may only contain letters, digits, '-', '_' and '.'", u.Name)
+		case u.Name == "services":
+			return fmt.Errorf("dashboard upstream %q: name is reserved for the service catalog", u.Name)
+		case u.BaseURL == "":
+			return fmt.Errorf("dashboard upstream %q: baseUrl is required", u.Name)
+		}
+		if _, dup := seen[u.Name]; dup {
+			return fmt.Errorf("dashboard upstream %q: name is already used", u.Name)
+		}
+		seen[u.Name] = struct{}{}
+	}
+	return nil
+}
+
+func invalidUpstreamNameRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+}
+
+// SetBaseURL overrides the base URL of the named upstream, adding the upstream
+// with a pass-through path template if it does not exist yet.
+func (c *DashboardConfig) SetBaseURL(name, baseURL string) {
+	for i := range c.Upstreams {
+		if c.Upstreams[i].Name == name {
+			c.Upstreams[i].BaseURL = baseURL
+			return
+		}
+	}
+	c.Upstreams = append(c.Upstreams, DashboardUpstream{Name: name, BaseURL: baseURL, PathTemplate: "{path}"})
 }
@@ -12,21 +12,21 @@ import (
 	"strings"
 	"time"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
 )
 
 // DashboardProxy proxies requests to internal observability services
-// (Prometheus, Tracing, HealthMonitor) and serves the service catalog
-// directly from Consul.
+// configured as named upstreams and serves the service catalog directly
+// from Consul.
 type DashboardProxy struct {
 	config   DashboardConfig
 	logger   *slog.Logger
 	client   *http.Client
-	registry *consul.Registry
+	registry registry.Registry
 }
 
 // NewDashboardProxy creates a proxy for dashboard API routes.
-func NewDashboardProxy(config DashboardConfig, registry *consul.Registry, logger *slog.Logger) *DashboardProxy {
+func NewDashboardProxy(config DashboardConfig, registry registry.Registry, logger *slog.Logger) *DashboardProxy {
 	return &DashboardProxy{
 		config:   config,
 		logger:   logger,
@@ -35,31 +35,30 @@ func NewDashboardProxy(config DashboardConfig, registry *consul.Registry, logger
 	}
 }
 
+// dashboardMount is the path under which all dashboard routes are served.
+const dashboardMount = "/api/dashboard/"
+
 // Handler returns an http.Handler mounted at /api/dashboard/.
+// Each upstream is served at /api/dashboard/{name} and its subtree.
 func (dp *DashboardProxy) Handler() http.Handler {
 	mux := http.NewServeMux()
 
-	// Prometheus proxy.
-	mux.HandleFunc("/api/dashboard/prometheus/", func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/api/dashboard/prometheus")
-		dp.proxy(w, r, dp.config.PrometheusBaseURL, "/api/v1"+path)
-	})
-
-	// Tracing proxy (exact path for list endpoint + subtree for sub-paths).
-	tracingHandler := func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/api/dashboard/traces")
-		dp.proxy(w, r, dp.config.TracingBaseURL, "/api/traces"+path)
+	for _, up := range dp.config.Upstreams {
+		if up.Name == "" || up.BaseURL == "" {
+			dp.logger.Warn("skipping incomplete dashboard upstream", "name", up.Name)
+			continue
+		}
+		mount := dashboardMount + up.Name
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, mount)
+			dp.proxy(w, r, up.BaseURL, up.TargetPath(path))
+		}
+		mux.HandleFunc(mount, handler)
+		mux.HandleFunc(mount+"/", handler)
 	}
-	mux.HandleFunc("/api/dashboard/traces", tracingHandler)
-	mux.HandleFunc("/api/dashboard/traces/", tracingHandler)
 
 	// Services catalog (direct from Consul).
-	mux.HandleFunc("/api/dashboard/services", dp.handleServices)
-
-	// Health snapshots (via HealthMonitor).
-	mux.HandleFunc("/api/dashboard/health", func(w http.ResponseWriter, r *http.Request) {
-		dp.proxy(w, r, dp.config.HealthMonitorBaseURL, "/api/status")
-	})
+	mux.HandleFunc(dashboardMount+"services", dp.handleServices)
 
 	return mux
 }
@@ -196,7 +195,7 @@ func (dp *DashboardProxy) proxy(w http.ResponseWriter, r *http.Request, baseURL,
 	}
 
 	// Forward relevant headers.
-	for _, h := range []string{"Content-Type", "Accept"} {
+	for _, h := range []string{"Content-Type", "Accept", CorrelationIDHeader} {
 		if v := r.Header.Get(h); v != "" {
 			req.Header.Set(h, v)
 		}
@@ -210,7 +209,7 @@ func (dp *DashboardProxy) proxy(w http.ResponseWriter, r *http.Request, baseURL,
 
 	resp, err := dp.client.Do(req)
 	if err != nil {
-		dp.logger.Warn("dashboard proxy failed", "url", targetURL, "error", err)
+		dp.logger.Warn("dashboard proxy failed", "url", targetURL, "error", err, "correlation_id", CorrelationIDFromContext(r.Context()))
 		http.Error(w, "upstream unavailable", http.StatusBadGateway)
 		return
 	}
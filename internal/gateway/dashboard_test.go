@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDashboardProxy_UpstreamPathTemplates(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RequestURI())
+	}))
+	defer upstream.Close()
+
+	cfg := DashboardConfig{Upstreams: []DashboardUpstream{
+		{Name: "prometheus", BaseURL: upstream.URL, PathTemplate: "/api/v1{path}"},
+		{Name: "health", BaseURL: upstream.URL, PathTemplate: "/api/status{path}"},
+		{Name: "loki", BaseURL: upstream.URL, PathTemplate: "/loki/api/v1{path}"},
+		{Name: "broken"},
+	}}
+	handler := NewDashboardProxy(cfg, nil, slog.New(slog.NewTextHandler(io.Discard, nil))).Handler()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/dashboard/prometheus/query?query=up", "/api/v1/query?query=up"},
+		{"/api/dashboard/health", "/api/status"},
+		{"/api/dashboard/health/orders", "/api/status/orders"},
+		{"/api/dashboard/loki/labels", "/loki/api/v1/labels"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tt.path, w.Code)
+		}
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: expected upstream path %q, got %q", tt.path, tt.want, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/dashboard/broken/x", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected incomplete upstream to be skipped (404), got %d", w.Code)
+	}
+}
+
+func TestDashboardConfig_SetBaseURL(t *testing.T) {
+	cfg := DefaultConfig().Dashboard
+	cfg.SetBaseURL("prometheus", "http://prom:9090")
+	cfg.SetBaseURL("grafana", "http://grafana:3000")
+
+	for _, up := range cfg.Upstreams {
+		if up.Name == "prometheus" && (up.BaseURL != "http://prom:9090" || up.PathTemplate != "/api/v1{path}") {
+			t.Fatalf("expected prometheus base URL override to keep its template, got %+v", up)
+		}
+	}
+	last := cfg.Upstreams[len(cfg.Upstreams)-1]
+	if last.Name != "grafana" || last.TargetPath("/x") != "/x" {
+		t.Fatalf("expected grafana pass-through upstream to be added, got %+v", last)
+	}
+}
+
+func TestValidateDashboardUpstreams(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []DashboardUpstream
+		wantErr   bool
+	}{
+		{"defaults", DefaultConfig().Dashboard.Upstreams, false},
+		{"missing name", []DashboardUpstream{{BaseURL: "http://a"}}, true},
+		{"reserved name", []DashboardUpstream{{Name: "services", BaseURL: "http://a"}}, true},
+		{"nested name", []DashboardUpstream{{Name: "a/b", BaseURL: "http://a"}}, true},
+		{"pattern name", []DashboardUpstream{{Name: "{x}", BaseURL: "http://a"}}, true},
+		{"missing base URL", []DashboardUpstream{{Name: "a"}}, true},
+		{"duplicate name", []DashboardUpstream{{Name: "a", BaseURL: "http://a"}, {Name: "a", BaseURL: "http://b"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDashboardUpstreams(tt.upstreams); (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDashboardUpstreams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
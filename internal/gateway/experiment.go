@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// Experiment variants reported in the variant response header.
+const (
+	VariantControl   = "control"
+	VariantTreatment = "treatment"
+)
+
+// Experiment routes a deterministic share of a service's clients to an
+// alternate service. Clients are identified by Header or, failing that, by
+// Cookie; clients carrying neither stay on the control service.
+type Experiment struct {
+	Name             string `json:"name"`
+	Service          string `json:"service"`
+	AlternateService string `json:"alternateService"`
+	Percent          int    `json:"percent"` // 0-100, share routed to AlternateService
+	Header           string `json:"header,omitempty"`
+	Cookie           string `json:"cookie,omitempty"`
+}
+
+// ValidateExperiments reports the first invalid experiment, if any.
+func ValidateExperiments(experiments []Experiment) error {
+	seen := make(map[string]struct{}, len(experiments))
+	for i, e := range experiments {
+		switch {
+		case e.Name == "":
+			return fmt.Errorf("experiment %d: name is required", i)
+		case e.Service == "" || e.AlternateService == "":
+			return fmt.Errorf("experiment %q: service and alternateService are required", e.Name)
+		case e.Percent < 0 || e.Percent > 100:
+			return fmt.Errorf("experiment %q: percent must be between 0 and 100", e.Name)
+		case e.Header == "" && e.Cookie == "":
+			return fmt.Errorf("experiment %q: header or cookie is required", e.Name)
+		}
+		key := strings.ToLower(e.Service)
+		if _, dup := seen[key]; dup {
+			return fmt.Errorf("experiment %q: service %s already has an experiment", e.Name, e.Service)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+// ExperimentRouter assigns clients of services under experiment to a variant
+// and steers treatment traffic to the alternate service.
+type ExperimentRouter struct {
+	config    ExperimentConfig
+	routes    *RouteTable
+	byService map[string]Experiment // keyed by lowercase service name
+}
+
+// NewExperimentRouter creates an experiment router. Call ValidateExperiments
+// on operator input first; invalid experiments here are ignored.
+func NewExperimentRouter(config ExperimentConfig, routes *RouteTable) *ExperimentRouter {
+	byService := make(map[string]Experiment, len(config.Experiments))
+	for _, e := range config.Experiments {
+		byService[strings.ToLower(e.Service)] = e
+	}
+	return &ExperimentRouter{config: config, routes: routes, byService: byService}
+}
+
+// Assign returns the experiment covering the request's service and the
+// variant the client falls into.
+func (er *ExperimentRouter) Assign(r *http.Request) (Experiment, string, bool) {
+	serviceName, _, ok := er.routes.Resolve(r)
+	if !ok {
+		return Experiment{}, "", false
+	}
+	e, ok := er.byService[strings.ToLower(serviceName)]
+	if !ok {
+		return Experiment{}, "", false
+	}
+
+	key := ""
+	if e.Header != "" {
+		key = r.Header.Get(e.Header)
+	}
+	if key == "" && e.Cookie != "" {
+		if c, err := r.Cookie(e.Cookie); err == nil {
+			key = c.Value
+		}
+	}
+	if key == "" {
+		return e, VariantControl, true
+	}
+	if experimentBucket(e.Name, key) < e.Percent {
+		return e, VariantTreatment, true
+	}
+	return e, VariantControl, true
+}
+
+// experimentBucket hashes a client key into [0, 100). The experiment name is
+// mixed in so one client is not always in the treatment group of every
+// experiment.
+func experimentBucket(name, key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32() % 100)
+}
+
+// Middleware tags responses with the assigned variant and routes treatment
+// requests to the experiment's alternate service.
+func (er *ExperimentRouter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e, variant, ok := er.Assign(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if er.config.VariantHeader != "" {
+			w.Header().Add(er.config.VariantHeader, e.Name+"="+variant)
+		}
+		if variant == VariantTreatment {
+			r = r.WithContext(withServiceOverride(r.Context(), e.AlternateService))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type serviceOverrideKey struct{}
+
+// withServiceOverride makes the proxy send the request to service instead of
+// the one named by its path.
+func withServiceOverride(ctx context.Context, service string) context.Context {
+	return context.WithValue(ctx, serviceOverrideKey{}, service)
+}
+
+func serviceOverrideFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(serviceOverrideKey{}).(string)
+	return s
+}
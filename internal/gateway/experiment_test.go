@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateExperiments(t *testing.T) {
+	valid := Experiment{Name: "checkout-v2", Service: "checkout", AlternateService: "checkout-v2", Percent: 10, Cookie: "uid"}
+	tests := []struct {
+		name    string
+		mutate  func(e *Experiment)
+		wantErr bool
+	}{
+		{"valid", func(e *Experiment) {}, false},
+		{"missing name", func(e *Experiment) { e.Name = "" }, true},
+		{"missing alternate", func(e *Experiment) { e.AlternateService = "" }, true},
+		{"percent too high", func(e *Experiment) { e.Percent = 101 }, true},
+		{"no client key", func(e *Experiment) { e.Cookie = "" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid
+			tt.mutate(&e)
+			if err := ValidateExperiments([]Experiment{e}); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := ValidateExperiments([]Experiment{valid, valid}); err == nil {
+		t.Fatal("expected duplicate service to be rejected")
+	}
+}
+
+func TestExperimentRouter_AssignIsDeterministic(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	tests := []struct {
+		percent int
+		want    string
+	}{
+		{0, VariantControl},
+		{100, VariantTreatment},
+	}
+	for _, tt := range tests {
+		er := NewExperimentRouter(ExperimentConfig{Experiments: []Experiment{
+			{Name: "exp", Service: "checkout", AlternateService: "checkout-v2", Percent: tt.percent, Header: "X-User-ID"},
+		}}, rt)
+		req := httptest.NewRequest("GET", "/api/checkout/cart", nil)
+		req.Header.Set("X-User-ID", "user-42")
+		if _, variant, ok := er.Assign(req); !ok || variant != tt.want {
+			t.Fatalf("percent %d: expected %s, got %s (ok=%v)", tt.percent, tt.want, variant, ok)
+		}
+	}
+
+	// The same client always lands in the same bucket; roughly Percent of
+	// distinct clients land in treatment.
+	er := NewExperimentRouter(ExperimentConfig{Experiments: []Experiment{
+		{Name: "exp", Service: "checkout", AlternateService: "checkout-v2", Percent: 30, Cookie: "uid"},
+	}}, rt)
+	treated := 0
+	for i := range 1000 {
+		req := httptest.NewRequest("GET", "/api/checkout/cart", nil)
+		req.AddCookie(&http.Cookie{Name: "uid", Value: fmt.Sprintf("user-%d", i)})
+		_, first, _ := er.Assign(req)
+		_, second, _ := er.Assign(req)
+		if first != second {
+			t.Fatalf("client user-%d assigned %s then %s", i, first, second)
+		}
+		if first == VariantTreatment {
+			treated++
+		}
+	}
+	if treated < 250 || treated > 350 {
+		t.Fatalf("expected about 300 of 1000 clients in treatment, got %d", treated)
+	}
+
+	// Clients without an identifier stay on control.
+	if _, variant, _ := er.Assign(httptest.NewRequest("GET", "/api/checkout/cart", nil)); variant != VariantControl {
+		t.Fatalf("expected anonymous client on control, got %s", variant)
+	}
+}
+
+func TestExperimentRouter_RoutesTreatmentToAlternateService(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, name)
+		}))
+	}
+	control, treatment := newBackend("v1"), newBackend("v2")
+	defer control.Close()
+	defer treatment.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"checkout":    {ServiceName: "checkout", Backends: []Backend{{ServiceID: "v1-1", Address: control.URL}}},
+			"checkout-v2": {ServiceName: "checkout-v2", Backends: []Backend{{ServiceID: "v2-1", Address: treatment.URL}}},
+		},
+	}
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		percent    int
+		wantBody   string
+		wantHeader string
+	}{
+		{0, "v1", "exp=control"},
+		{100, "v2", "exp=treatment"},
+	}
+	for _, tt := range tests {
+		er := NewExperimentRouter(ExperimentConfig{
+			VariantHeader: "X-Experiment-Variant",
+			Experiments: []Experiment{
+				{Name: "exp", Service: "checkout", AlternateService: "checkout-v2", Percent: tt.percent, Header: "X-User-ID"},
+			},
+		}, rt)
+		req := httptest.NewRequest("GET", "/api/checkout/cart", nil)
+		req.Header.Set("X-User-ID", "user-1")
+		w := httptest.NewRecorder()
+		er.Middleware(proxy).ServeHTTP(w, req)
+
+		if w.Body.String() != tt.wantBody {
+			t.Errorf("percent %d: expected body %q, got %q", tt.percent, tt.wantBody, w.Body.String())
+		}
+		if got := w.Header().Get("X-Experiment-Variant"); got != tt.wantHeader {
+			t.Errorf("percent %d: expected variant header %q, got %q", tt.percent, tt.wantHeader, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/toska-mesh/toska-mesh/internal/geoip"
+)
+
+// GeoFilter applies country-based access rules and tags requests with the
+// client's region so the proxy prefers backends in that region.
+type GeoFilter struct {
+	config   GeoConfig
+	locator  geoip.Locator
+	blocked  map[string]struct{}
+	limiters map[string]*RateLimiter // keyed by country
+	regions  map[string]string       // country -> region overrides
+}
+
+// NewGeoFilter creates a GeoFilter that resolves clients with locator.
+func NewGeoFilter(config GeoConfig, locator geoip.Locator) *GeoFilter {
+	g := &GeoFilter{
+		config:   config,
+		locator:  locator,
+		blocked:  make(map[string]struct{}, len(config.BlockedCountries)),
+		limiters: make(map[string]*RateLimiter, len(config.CountryRateLimits)),
+		regions:  make(map[string]string, len(config.CountryRegions)),
+	}
+	for _, c := range config.BlockedCountries {
+		g.blocked[strings.ToUpper(c)] = struct{}{}
+	}
+	for c, limit := range config.CountryRateLimits {
+		g.limiters[strings.ToUpper(c)] = NewRateLimiter(limit, config.RateLimitWindowSeconds)
+	}
+	for c, region := range config.CountryRegions {
+		g.regions[strings.ToUpper(c)] = region
+	}
+	return g
+}
+
+// Locate returns the location of the request's client, if known. Region
+// overrides from config take precedence over the database.
+func (g *GeoFilter) Locate(r *http.Request) (geoip.Location, bool) {
+	addr, err := netip.ParseAddr(clientIPAddress(r))
+	if err != nil {
+		return geoip.Location{}, false
+	}
+	loc, ok := g.locator.Lookup(addr)
+	if !ok {
+		return geoip.Location{}, false
+	}
+	if region, ok := g.regions[loc.Country]; ok {
+		loc.Region = region
+	}
+	return loc, true
+}
+
+// Middleware rejects clients from blocked countries with 403, applies
+// per-country rate limits (counted per client IP), and records the client
+// region as a backend preference. Clients with unknown locations pass
+// through untouched.
+func (g *GeoFilter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc, ok := g.Locate(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if _, blocked := g.blocked[loc.Country]; blocked {
+			http.Error(w, "access from your region is not permitted", http.StatusForbidden)
+			return
+		}
+		if rl, limited := g.limiters[loc.Country]; limited && !rl.allow(clientIPAddress(r)) {
+			http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
+			return
+		}
+
+		if g.config.PreferRegion && loc.Region != "" {
+			r = r.WithContext(withMetadataPreference(r.Context(), g.config.RegionMetadataKey, loc.Region))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
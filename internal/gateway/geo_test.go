@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/geoip"
+)
+
+const testGeoDB = `network,country_iso_code,region
+203.0.113.0/24,KP,
+198.51.100.0/24,DE,eu-central
+192.0.2.0/24,US,us-east
+`
+
+func TestGeoFilter_BlocksAndRateLimitsByCountry(t *testing.T) {
+	db, err := geoip.Parse(strings.NewReader(testGeoDB))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	geo := NewGeoFilter(GeoConfig{
+		BlockedCountries:       []string{"kp"},
+		CountryRateLimits:      map[string]int{"DE": 1},
+		RateLimitWindowSeconds: 60,
+	}, db)
+	handler := geo.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       int
+	}{
+		{"blocked country", "203.0.113.5:1234", http.StatusForbidden},
+		{"first request from limited country", "198.51.100.5:1234", http.StatusOK},
+		{"second request from limited country", "198.51.100.5:1234", http.StatusTooManyRequests},
+		{"other client in limited country", "198.51.100.6:1234", http.StatusOK},
+		{"unlimited country", "192.0.2.5:1234", http.StatusOK},
+		{"unknown location", "10.0.0.1:1234", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/orders/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestGeoFilter_PrefersRegionBackends(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+	}
+	eu, us := newBackend("eu"), newBackend("us")
+	defer eu.Close()
+	defer us.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"orders": {ServiceName: "orders", Backends: []Backend{
+				{ServiceID: "eu-1", Address: eu.URL, Metadata: map[string]string{"region": "eu-west"}},
+				{ServiceID: "us-1", Address: us.URL, Metadata: map[string]string{"region": "us-east"}},
+			}},
+		},
+	}
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	db, err := geoip.Parse(strings.NewReader(testGeoDB))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	geo := NewGeoFilter(GeoConfig{
+		PreferRegion:      true,
+		RegionMetadataKey: "region",
+		CountryRegions:    map[string]string{"de": "eu-west"},
+	}, db)
+	handler := geo.Middleware(proxy)
+
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{"198.51.100.5:1234", "eu"},
+		{"192.0.2.5:1234", "us"},
+	}
+	for _, tt := range tests {
+		for range 3 {
+			req := httptest.NewRequest("GET", "/api/orders/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Body.String() != tt.want {
+				t.Fatalf("%s: expected backend %q, got %q", tt.remoteAddr, tt.want, w.Body.String())
+			}
+		}
+	}
+}
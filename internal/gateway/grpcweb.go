@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCWebBridge translates gRPC-Web requests from browsers into gRPC calls on
+// a backend (the discovery service by default), so dashboard code can use the
+// gRPC API without a separate Envoy deployment. Both the binary
+// (application/grpc-web) and base64 text (application/grpc-web-text) wire
+// formats are supported, for unary and server-streaming methods.
+type GRPCWebBridge struct {
+	conn    *grpc.ClientConn
+	config  GRPCWebConfig
+	allowed map[string]struct{}
+	logger  *slog.Logger
+}
+
+// NewGRPCWebBridge creates a bridge to config.Target. The connection is
+// established lazily on the first call.
+func NewGRPCWebBridge(config GRPCWebConfig, logger *slog.Logger) (*GRPCWebBridge, error) {
+	conn, err := grpc.NewClient(config.Target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("grpc-web target %s: %w", config.Target, err)
+	}
+	allowed := make(map[string]struct{}, len(config.AllowedMethods))
+	for _, m := range config.AllowedMethods {
+		allowed[strings.TrimPrefix(m, "/")] = struct{}{}
+	}
+	return &GRPCWebBridge{conn: conn, config: config, allowed: allowed, logger: logger}, nil
+}
+
+// Close releases the backend connection.
+func (b *GRPCWebBridge) Close() error {
+	return b.conn.Close()
+}
+
+// gRPC-Web frame flags.
+const (
+	grpcWebDataFrame    byte = 0x00
+	grpcWebTrailerFrame byte = 0x80
+)
+
+// grpcWebSkipHeaders are HTTP headers that are not forwarded as gRPC metadata.
+var grpcWebSkipHeaders = map[string]struct{}{
+	"accept": {}, "accept-encoding": {}, "accept-language": {}, "connection": {},
+	"content-length": {}, "content-type": {}, "cookie": {}, "host": {},
+	"origin": {}, "referer": {}, "te": {}, "user-agent": {},
+	"grpc-timeout": {}, "x-grpc-web": {}, "x-user-agent": {},
+}
+
+// ServeHTTP handles POST {PathPrefix}{package.Service}/{Method}.
+func (b *GRPCWebBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "gRPC-Web requires POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, "application/grpc-web-text")
+	if !text && !strings.HasPrefix(contentType, "application/grpc-web") {
+		http.Error(w, "unsupported content type: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	method := "/" + strings.TrimPrefix(r.URL.Path, b.config.PathPrefix)
+	service, _, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !ok || service == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if _, ok := b.allowed[method[1:]]; !ok {
+		http.Error(w, "gRPC method not exposed: "+method[1:], http.StatusForbidden)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		http.Error(w, "read request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if text {
+		if body, err = decodeGRPCWebText(body); err != nil {
+			http.Error(w, "invalid grpc-web-text body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	msg, err := readGRPCWebMessage(body)
+	if err != nil {
+		http.Error(w, "invalid grpc-web frame: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), b.timeout(r.Header.Get("grpc-timeout")))
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, grpcWebMetadata(r.Header))
+
+	respType := "application/grpc-web+proto"
+	if text {
+		respType = "application/grpc-web-text+proto"
+	}
+	w.Header().Set("Content-Type", respType)
+	w.Header().Set("Access-Control-Expose-Headers", "grpc-status, grpc-message")
+
+	desc := &grpc.StreamDesc{StreamName: method, ServerStreams: true}
+	stream, err := b.conn.NewStream(ctx, desc, method, grpc.ForceCodec(rawCodec{}))
+	if err == nil {
+		err = stream.SendMsg(&msg)
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+
+	headerWritten := false
+	writeHeader := func() {
+		if headerWritten {
+			return
+		}
+		headerWritten = true
+		if md, err := stream.Header(); err == nil {
+			for k, vv := range md {
+				for _, v := range vv {
+					w.Header().Add(k, v)
+				}
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for err == nil {
+		var resp []byte
+		if err = stream.RecvMsg(&resp); err != nil {
+			break
+		}
+		writeHeader()
+		w.Write(encodeGRPCWebFrame(grpcWebDataFrame, resp, text))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	trailer := metadata.MD{}
+	if stream != nil {
+		writeHeader()
+		trailer = stream.Trailer()
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	st := status.Convert(err)
+	if st.Code() == codes.Unavailable {
+		b.logger.Warn("grpc-web backend unavailable", "method", method, "target", b.config.Target, "error", err)
+	}
+	w.Write(encodeGRPCWebFrame(grpcWebTrailerFrame, grpcWebTrailer(st, trailer), text))
+}
+
+// timeout returns the call deadline from a grpc-timeout header (e.g. "10S",
+// "500m"), capped at the configured maximum.
+func (b *GRPCWebBridge) timeout(header string) time.Duration {
+	limit := b.config.MaxTimeout
+	if len(header) < 2 {
+		return limit
+	}
+	n, err := strconv.ParseInt(header[:len(header)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return limit
+	}
+	units := map[byte]time.Duration{
+		'H': time.Hour, 'M': time.Minute, 'S': time.Second,
+		'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond,
+	}
+	unit, ok := units[header[len(header)-1]]
+	if !ok {
+		return limit
+	}
+	if d := time.Duration(n) * unit; limit <= 0 || d < limit {
+		return d
+	}
+	return limit
+}
+
+// grpcWebMetadata converts request headers into outgoing gRPC metadata.
+func grpcWebMetadata(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vv := range h {
+		key := strings.ToLower(k)
+		if _, skip := grpcWebSkipHeaders[key]; skip || strings.HasPrefix(key, "access-control-") {
+			continue
+		}
+		md.Append(key, vv...)
+	}
+	return md
+}
+
+// grpcWebTrailer encodes the call status and trailing metadata as the
+// HTTP/1-style header block carried in a gRPC-Web trailer frame.
+func grpcWebTrailer(st *status.Status, md metadata.MD) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "grpc-status: %d\r\n", st.Code())
+	if msg := st.Message(); msg != "" {
+		fmt.Fprintf(&sb, "grpc-message: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(msg))
+	}
+	for k, vv := range md {
+		for _, v := range vv {
+			fmt.Fprintf(&sb, "%s: %s\r\n", k, v)
+		}
+	}
+	return []byte(sb.String())
+}
+
+// encodeGRPCWebFrame builds a length-prefixed frame, base64 encoded for the
+// text format.
+func encodeGRPCWebFrame(flag byte, payload []byte, text bool) []byte {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	if text {
+		return []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	return frame
+}
+
+// readGRPCWebMessage returns the single message of a unary or
+// server-streaming request body.
+func readGRPCWebMessage(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("short frame")
+	}
+	if body[0]&0x01 != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(body[1:5])
+	if uint64(len(body)-5) < uint64(n) {
+		return nil, errors.New("truncated frame")
+	}
+	return body[5 : 5+n], nil
+}
+
+// decodeGRPCWebText decodes a grpc-web-text body, which may be a
+// concatenation of separately padded base64 chunks.
+func decodeGRPCWebText(body []byte) ([]byte, error) {
+	var out []byte
+	s := strings.TrimSpace(string(body))
+	for s != "" {
+		// A chunk ends after its padding, or at the end of the body.
+		end := len(s)
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			end = i
+			for end < len(s) && s[end] == '=' {
+				end++
+			}
+		}
+		chunk, err := base64.StdEncoding.DecodeString(s[:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+		s = s[end:]
+	}
+	return out, nil
+}
+
+// rawCodec passes already-serialized protobuf messages through unchanged.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec: unexpected type %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec: unexpected type %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
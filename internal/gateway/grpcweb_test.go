@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+type grpcWebTestServer struct {
+	meshpb.UnimplementedDiscoveryRegistryServer
+}
+
+func (grpcWebTestServer) GetServices(ctx context.Context, _ *meshpb.GetServicesRequest) (*meshpb.GetServicesResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &meshpb.GetServicesResponse{ServiceNames: append([]string{"orders"}, md.Get("x-tenant")...)}, nil
+}
+
+func TestGRPCWebBridge(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	meshpb.RegisterDiscoveryRegistryServer(srv, grpcWebTestServer{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	bridge, err := NewGRPCWebBridge(GRPCWebConfig{
+		PathPrefix:     "/grpc-web/",
+		Target:         lis.Addr().String(),
+		AllowedMethods: DefaultConfig().GRPCWeb.AllowedMethods,
+		MaxTimeout:     5 * time.Second,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewGRPCWebBridge: %v", err)
+	}
+	defer bridge.Close()
+
+	req, _ := proto.Marshal(&meshpb.GetServicesRequest{})
+	frame := encodeGRPCWebFrame(grpcWebDataFrame, req, false)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        []byte
+		wantCode    int
+		wantStatus  string
+		wantNames   []string
+	}{
+		{"binary", "/grpc-web/toskamesh.discovery.DiscoveryRegistry/GetServices", "application/grpc-web+proto", frame, http.StatusOK, "grpc-status: 0", []string{"orders", "acme"}},
+		{"text", "/grpc-web/toskamesh.discovery.DiscoveryRegistry/GetServices", "application/grpc-web-text", []byte(base64.StdEncoding.EncodeToString(frame)), http.StatusOK, "grpc-status: 0", []string{"orders", "acme"}},
+		{"unimplemented method", "/grpc-web/toskamesh.discovery.DiscoveryRegistry/GetInstance", "application/grpc-web+proto", encodeGRPCWebFrame(grpcWebDataFrame, nil, false), http.StatusOK, "grpc-status: 12", nil},
+		{"write method not exposed", "/grpc-web/toskamesh.discovery.DiscoveryRegistry/Register", "application/grpc-web+proto", encodeGRPCWebFrame(grpcWebDataFrame, nil, false), http.StatusForbidden, "", nil},
+		{"service not exposed", "/grpc-web/grpc.health.v1.Health/Check", "application/grpc-web+proto", frame, http.StatusForbidden, "", nil},
+		{"not grpc-web", "/grpc-web/toskamesh.discovery.DiscoveryRegistry/GetServices", "application/json", frame, http.StatusUnsupportedMediaType, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", tt.path, bytes.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r.Header.Set("X-Tenant", "acme")
+			w := httptest.NewRecorder()
+			bridge.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == "" {
+				return
+			}
+
+			body := w.Body.Bytes()
+			if strings.HasPrefix(tt.contentType, "application/grpc-web-text") {
+				if body, err = decodeGRPCWebText(body); err != nil {
+					t.Fatalf("decode text response: %v", err)
+				}
+			}
+
+			var names []string
+			var trailer string
+			for len(body) > 0 {
+				msg, err := readGRPCWebMessage(body)
+				if err != nil {
+					t.Fatalf("read frame: %v", err)
+				}
+				if body[0] == grpcWebTrailerFrame {
+					trailer = string(msg)
+				} else {
+					var resp meshpb.GetServicesResponse
+					if err := proto.Unmarshal(msg, &resp); err != nil {
+						t.Fatalf("unmarshal: %v", err)
+					}
+					names = append(names, resp.ServiceNames...)
+				}
+				body = body[5+len(msg):]
+			}
+
+			if !strings.Contains(trailer, tt.wantStatus) {
+				t.Fatalf("expected trailer %q, got %q", tt.wantStatus, trailer)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Fatalf("expected services %v, got %v", tt.wantNames, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+)
+
+// MaintenanceState is the JSON value stored under a service's maintenance key.
+// An empty value puts the service in maintenance with the configured defaults.
+type MaintenanceState struct {
+	Message           string    `json:"message,omitempty"`
+	RetryAfterSeconds int       `json:"retryAfterSeconds,omitempty"`
+	Since             time.Time `json:"since,omitzero"`
+}
+
+// Maintenance tracks which services are in maintenance mode by watching a
+// Consul KV prefix, and rejects their proxied requests with 503.
+type Maintenance struct {
+	registry registry.Registry
+	routes   *RouteTable
+	config   MaintenanceConfig
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	services map[string]MaintenanceState // keyed by lowercase service name
+}
+
+// NewMaintenance creates a maintenance tracker backed by Consul KV.
+func NewMaintenance(registry registry.Registry, routes *RouteTable, config MaintenanceConfig, logger *slog.Logger) *Maintenance {
+	return &Maintenance{
+		registry: registry,
+		routes:   routes,
+		config:   config,
+		logger:   logger,
+		services: make(map[string]MaintenanceState),
+	}
+}
+
+// Run watches the KV prefix until ctx is cancelled, reloading the set of
+// services in maintenance whenever it changes.
+func (m *Maintenance) Run(ctx context.Context) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	var index uint64
+	for {
+		values, newIndex, err := m.registry.ListKV(ctx, m.config.KVPrefix, index, m.config.WatchWaitTime)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			m.logger.Warn("maintenance watch failed", "error", err, "retry_in", backoff)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		m.load(values)
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+	}
+}
+
+// load replaces the maintenance set from raw KV values.
+func (m *Maintenance) load(values map[string][]byte) {
+	services := make(map[string]MaintenanceState, len(values))
+	for key, value := range values {
+		name := strings.ToLower(strings.TrimPrefix(key, m.config.KVPrefix))
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		var state MaintenanceState
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &state); err != nil {
+				m.logger.Warn("invalid maintenance value, using defaults", "key", key, "error", err)
+				state = MaintenanceState{}
+			}
+		}
+		services[name] = state
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range services {
+		if _, ok := m.services[name]; !ok {
+			m.logger.Info("service entered maintenance", "service", name)
+		}
+	}
+	for name := range m.services {
+		if _, ok := services[name]; !ok {
+			m.logger.Info("service left maintenance", "service", name)
+		}
+	}
+	m.services = services
+}
+
+// State returns the maintenance state of a service, with defaults applied.
+func (m *Maintenance) State(serviceName string) (MaintenanceState, bool) {
+	m.mu.RLock()
+	state, ok := m.services[strings.ToLower(serviceName)]
+	m.mu.RUnlock()
+	if !ok {
+		return MaintenanceState{}, false
+	}
+	if state.Message == "" {
+		state.Message = m.config.DefaultMessage
+	}
+	if state.RetryAfterSeconds <= 0 {
+		state.RetryAfterSeconds = int(m.config.DefaultRetryAfter.Seconds())
+	}
+	return state, true
+}
+
+// Middleware rejects requests for services in maintenance with 503 and a
+// Retry-After header. Other requests pass through to next.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serviceName, _, ok := m.routes.Resolve(r)
+		if ok {
+			if state, down := m.State(serviceName); down {
+				if state.RetryAfterSeconds > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(state.RetryAfterSeconds))
+				}
+				http.Error(w, state.Message, http.StatusServiceUnavailable)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminHandler serves the maintenance admin API:
+//
+//	GET    /admin/maintenance            list services in maintenance
+//	PUT    /admin/maintenance/{service}  enter maintenance (optional JSON MaintenanceState body)
+//	DELETE /admin/maintenance/{service}  leave maintenance
+func (m *Maintenance) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/maintenance", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.RLock()
+		names := make([]string, 0, len(m.services))
+		for name := range m.services {
+			names = append(names, name)
+		}
+		m.mu.RUnlock()
+
+		out := make(map[string]MaintenanceState, len(names))
+		for _, name := range names {
+			out[name], _ = m.State(name)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+
+	mux.HandleFunc("PUT /admin/maintenance/{service}", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.ToLower(r.PathValue("service"))
+		var state MaintenanceState
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&state); err != nil {
+				http.Error(w, "invalid maintenance state: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if state.Since.IsZero() {
+			state.Since = time.Now().UTC()
+		}
+		value, err := json.Marshal(state)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := m.registry.PutKV(m.config.KVPrefix+name, value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		// Apply locally right away; the watch confirms it shortly after.
+		m.mu.Lock()
+		m.services[name] = state
+		m.mu.Unlock()
+		m.logger.Info("service entered maintenance", "service", name)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /admin/maintenance/{service}", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.ToLower(r.PathValue("service"))
+		if err := m.registry.DeleteKV(m.config.KVPrefix + name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		m.mu.Lock()
+		delete(m.services, name)
+		m.mu.Unlock()
+		m.logger.Info("service left maintenance", "service", name)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
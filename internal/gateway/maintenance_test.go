@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenance_MiddlewareRejectsServicesInMaintenance(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	m := NewMaintenance(nil, rt, MaintenanceConfig{
+		KVPrefix:          "toska-mesh/maintenance/",
+		DefaultMessage:    "down for maintenance",
+		DefaultRetryAfter: 5 * time.Minute,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	m.load(map[string][]byte{
+		"toska-mesh/maintenance/Orders":   nil,
+		"toska-mesh/maintenance/payments": []byte(`{"message":"ledger migration","retryAfterSeconds":30}`),
+		"toska-mesh/maintenance/billing":  []byte(`not json`),
+		"toska-mesh/maintenance/a/b":      nil,
+	})
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path           string
+		wantCode       int
+		wantRetryAfter string
+		wantBody       string
+	}{
+		{"/api/orders/1", http.StatusServiceUnavailable, "300", "down for maintenance"},
+		{"/api/payments/charge", http.StatusServiceUnavailable, "30", "ledger migration"},
+		{"/api/billing/invoices", http.StatusServiceUnavailable, "300", "down for maintenance"},
+		{"/api/users/1", http.StatusOK, "", ""},
+		{"/health", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected %d, got %d", tt.path, tt.wantCode, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+			t.Errorf("%s: expected Retry-After %q, got %q", tt.path, tt.wantRetryAfter, got)
+		}
+		if !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%s: expected body to contain %q, got %q", tt.path, tt.wantBody, w.Body.String())
+		}
+	}
+
+	m.load(nil)
+	if _, down := m.State("orders"); down {
+		t.Fatal("expected orders to leave maintenance once its key is removed")
+	}
+}
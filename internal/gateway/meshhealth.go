@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+)
+
+// MeshHealth serves a single JSON summary of mesh health for external uptime
+// checks. It combines the gateway's route table and circuit breakers with the
+// HealthMonitor's latest probe results.
+type MeshHealth struct {
+	routes *RouteTable
+	proxy  *Proxy
+	config MeshHealthConfig
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewMeshHealth creates the aggregated health handler.
+func NewMeshHealth(routes *RouteTable, proxy *Proxy, config MeshHealthConfig, logger *slog.Logger) *MeshHealth {
+	return &MeshHealth{
+		routes: routes,
+		proxy:  proxy,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		logger: logger,
+	}
+}
+
+type meshHealthResponse struct {
+	Status           string              `json:"status"`
+	GeneratedAt      string              `json:"generatedAt"`
+	RoutesReady      bool                `json:"routesReady"`
+	MonitorAvailable bool                `json:"healthMonitorAvailable"`
+	Services         []meshServiceHealth `json:"services"`
+}
+
+type meshServiceHealth struct {
+	ServiceName      string            `json:"serviceName"`
+	Status           string            `json:"status"`
+	Instances        int               `json:"instances"`
+	HealthyInstances int               `json:"healthyInstances"`
+	RoutedBackends   int               `json:"routedBackends"`
+	Breakers         map[string]string `json:"breakers"`
+}
+
+// ServeHTTP writes the summary. The response is 503 when routes are not ready
+// or any service has no healthy instance, so simple HTTP checks can alert on it.
+func (m *MeshHealth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	monitored, err := m.fetchMonitorStatus(r)
+	if err != nil {
+		m.logger.Warn("mesh health: healthmonitor unavailable", "error", err)
+	}
+
+	resp := m.summarize(monitored, err == nil && m.config.HealthMonitorURL != "")
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status == "Unhealthy" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (m *MeshHealth) summarize(monitored []healthmonitor.MonitoredInstance, monitorAvailable bool) meshHealthResponse {
+	breakers := m.proxy.BreakerStates()
+
+	byService := make(map[string]map[string]healthmonitor.HealthStatus)
+	for _, inst := range monitored {
+		if byService[inst.ServiceName] == nil {
+			byService[inst.ServiceName] = make(map[string]healthmonitor.HealthStatus)
+		}
+		byService[inst.ServiceName][inst.ServiceID] = inst.Status
+	}
+
+	routed := make(map[string]ServiceRoute)
+	for _, route := range m.routes.Snapshot() {
+		routed[route.ServiceName] = route
+		if byService[route.ServiceName] == nil {
+			byService[route.ServiceName] = make(map[string]healthmonitor.HealthStatus)
+		}
+	}
+
+	resp := meshHealthResponse{
+		Status:           "Healthy",
+		GeneratedAt:      time.Now().UTC().Format(time.RFC3339),
+		RoutesReady:      m.routesReady(),
+		MonitorAvailable: monitorAvailable,
+		Services:         make([]meshServiceHealth, 0, len(byService)),
+	}
+
+	for name, statuses := range byService {
+		route := routed[name]
+		svc := meshServiceHealth{
+			ServiceName:    name,
+			RoutedBackends: len(route.Backends),
+			Breakers:       make(map[string]string),
+		}
+
+		ids := make(map[string]struct{}, len(statuses)+len(route.Backends))
+		for id := range statuses {
+			ids[id] = struct{}{}
+		}
+		// A routed backend is healthy unless its breaker is open or the
+		// monitor's last probe failed.
+		for _, b := range route.Backends {
+			// Backends the monitor probes are counted once, under their
+			// registry ID.
+			if _, monitored := statuses[b.RegistryID]; monitored {
+				ids[b.RegistryID] = struct{}{}
+			} else {
+				ids[b.ServiceID] = struct{}{}
+			}
+			state, tracked := breakers[b.ServiceID]
+			if tracked {
+				svc.Breakers[b.ServiceID] = state.String()
+			}
+			if tracked && state == healthmonitor.BreakerOpen {
+				continue
+			}
+			if st, ok := statuses[b.RegistryID]; ok && st == healthmonitor.StatusUnhealthy {
+				continue
+			}
+			svc.HealthyInstances++
+		}
+		svc.Instances = len(ids)
+
+		switch {
+		case svc.HealthyInstances == 0:
+			svc.Status = "Unhealthy"
+		case svc.HealthyInstances < svc.Instances:
+			svc.Status = "Degraded"
+		default:
+			svc.Status = "Healthy"
+		}
+		resp.Services = append(resp.Services, svc)
+	}
+	sort.Slice(resp.Services, func(i, j int) bool { return resp.Services[i].ServiceName < resp.Services[j].ServiceName })
+
+	for _, svc := range resp.Services {
+		if svc.Status == "Unhealthy" {
+			resp.Status = "Unhealthy"
+		} else if svc.Status == "Degraded" && resp.Status == "Healthy" {
+			resp.Status = "Degraded"
+		}
+	}
+	if !resp.RoutesReady {
+		resp.Status = "Unhealthy"
+	} else if !monitorAvailable && resp.Status == "Healthy" {
+		resp.Status = "Degraded"
+	}
+	return resp
+}
+
+func (m *MeshHealth) routesReady() bool {
+	select {
+	case <-m.routes.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+// fetchMonitorStatus reads every monitored instance from the HealthMonitor.
+func (m *MeshHealth) fetchMonitorStatus(r *http.Request) ([]healthmonitor.MonitoredInstance, error) {
+	if m.config.HealthMonitorURL == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, m.config.HealthMonitorURL+"/api/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("build healthmonitor request: %w", err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query healthmonitor: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("healthmonitor returned HTTP %d", resp.StatusCode)
+	}
+
+	var instances []healthmonitor.MonitoredInstance
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		return nil, fmt.Errorf("decode healthmonitor status: %w", err)
+	}
+	return instances, nil
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+)
+
+func TestMeshHealth_CombinesRoutesMonitorAndBreakers(t *testing.T) {
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/status" {
+			t.Errorf("unexpected healthmonitor path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode([]healthmonitor.MonitoredInstance{
+			{ServiceID: "orders-1", ServiceName: "orders", Status: healthmonitor.StatusHealthy},
+			{ServiceID: "orders-2", ServiceName: "orders", Status: healthmonitor.StatusHealthy},
+			{ServiceID: "orders-3", ServiceName: "orders", Status: healthmonitor.StatusUnhealthy},
+			{ServiceID: "users-1", ServiceName: "users", Status: healthmonitor.StatusHealthy},
+		})
+	}))
+	defer monitor.Close()
+
+	ready := make(chan struct{})
+	close(ready)
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		ready:  ready,
+		routes: map[string]*ServiceRoute{
+			"orders": {ServiceName: "orders", Backends: []Backend{
+				{ServiceID: "dc1/orders-1", RegistryID: "orders-1", Address: "http://10.0.0.1"},
+				{ServiceID: "orders-2", RegistryID: "orders-2", Address: "http://10.0.0.2"},
+			}},
+			"users": {ServiceName: "users", Backends: []Backend{{ServiceID: "users-1", RegistryID: "users-1", Address: "http://10.0.0.3"}}},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 1, BreakerBreakDuration: time.Minute}, ResponseConfig{}, logger)
+	proxy.breakers.get("orders-2").RecordFailure()
+	proxy.breakers.get("users-1").RecordFailure()
+
+	mh := NewMeshHealth(rt, proxy, MeshHealthConfig{HealthMonitorURL: monitor.URL, Timeout: time.Second}, logger)
+	w := httptest.NewRecorder()
+	mh.ServeHTTP(w, httptest.NewRequest("GET", "/api/mesh/health", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with a service down, got %d", w.Code)
+	}
+	var resp meshHealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.RoutesReady || !resp.MonitorAvailable || resp.Status != "Unhealthy" {
+		t.Fatalf("unexpected summary: %+v", resp)
+	}
+
+	want := map[string]meshServiceHealth{
+		"orders": {Status: "Degraded", Instances: 3, HealthyInstances: 1, RoutedBackends: 2},
+		"users":  {Status: "Unhealthy", Instances: 1, HealthyInstances: 0, RoutedBackends: 1},
+	}
+	if len(resp.Services) != len(want) {
+		t.Fatalf("expected %d services, got %d", len(want), len(resp.Services))
+	}
+	for _, svc := range resp.Services {
+		w := want[svc.ServiceName]
+		if svc.Status != w.Status || svc.Instances != w.Instances || svc.HealthyInstances != w.HealthyInstances || svc.RoutedBackends != w.RoutedBackends {
+			t.Errorf("%s: got %+v, want %+v", svc.ServiceName, svc, w)
+		}
+	}
+	if got := resp.Services[0].Breakers["orders-2"]; got != "open" {
+		t.Fatalf("expected orders-2 breaker open, got %q", got)
+	}
+}
+
+func TestMeshHealth_DegradedWithoutMonitor(t *testing.T) {
+	ready := make(chan struct{})
+	close(ready)
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		ready:  ready,
+		routes: map[string]*ServiceRoute{
+			"orders": {ServiceName: "orders", Backends: []Backend{{ServiceID: "orders-1", Address: "http://10.0.0.1"}}},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 1, BreakerBreakDuration: time.Minute}, ResponseConfig{}, logger)
+
+	mh := NewMeshHealth(rt, proxy, MeshHealthConfig{HealthMonitorURL: "http://127.0.0.1:1", Timeout: time.Second}, logger)
+	w := httptest.NewRecorder()
+	mh.ServeHTTP(w, httptest.NewRequest("GET", "/api/mesh/health", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp meshHealthResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.MonitorAvailable || resp.Status != "Degraded" {
+		t.Fatalf("expected Degraded without healthmonitor, got %+v", resp)
+	}
+}
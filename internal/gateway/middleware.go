@@ -1,29 +1,61 @@
 package gateway
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log/slog"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/auth"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
 )
 
 // --- Request Logging Middleware ---
 
+// CorrelationIDHeader carries the ID that ties together the logs of one
+// request across the gateway and backend services.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+type correlationIDKey struct{}
+
+// CorrelationIDFromContext returns the correlation ID assigned by
+// RequestLogging, or "".
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// newCorrelationID returns a random 128-bit hex ID.
+func newCorrelationID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
 // RequestLogging wraps a handler with structured request/response logging.
+// It also assigns the request's correlation ID: the client's X-Correlation-ID
+// or X-Request-ID if present, otherwise a new one. The ID is set on the
+// request (so it reaches backends on every attempt), stored in the context,
+// and echoed in the response.
 func RequestLogging(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		clientIP := clientIPAddress(r)
-		correlationID := r.Header.Get("X-Correlation-ID")
+		correlationID := r.Header.Get(CorrelationIDHeader)
 		if correlationID == "" {
 			correlationID = r.Header.Get("X-Request-ID")
 		}
+		if correlationID == "" {
+			correlationID = newCorrelationID()
+		}
+		r.Header.Set(CorrelationIDHeader, correlationID)
+		r = r.WithContext(context.WithValue(r.Context(), correlationIDKey{}, correlationID))
 
 		logger.Info("incoming request",
 			"method", r.Method,
@@ -32,7 +64,7 @@ func RequestLogging(logger *slog.Logger, next http.Handler) http.Handler {
 			"correlation_id", correlationID,
 		)
 
-		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, correlationID: correlationID}
 		next.ServeHTTP(rw, r)
 
 		logger.Info("outgoing response",
@@ -47,37 +79,95 @@ func RequestLogging(logger *slog.Logger, next http.Handler) http.Handler {
 
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode    int
+	correlationID string // echoed in the response when set
+	wroteHeader   bool
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader && rw.correlationID != "" {
+		// Set rather than Add: a backend echoing the ID must not duplicate it.
+		rw.Header().Set(CorrelationIDHeader, rw.correlationID)
+	}
+	rw.wroteHeader = true
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the wrapper.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if !rw.wroteHeader {
+			rw.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
 // --- Rate Limiting Middleware ---
 
-// RateLimiter implements fixed-window per-client-IP rate limiting.
+// RateLimiter implements fixed-window per-client-IP rate limiting. Buckets are
+// kept in least-recently-used order and capped, so a flood of distinct (e.g.
+// spoofed) client IPs evicts idle buckets instead of growing memory without
+// bound.
 type RateLimiter struct {
 	mu      sync.Mutex
-	buckets map[string]*bucket
+	buckets map[string]*list.Element // values are *bucket
+	lru     *list.List               // front is most recently used
 	limit   int
 	window  time.Duration
+	opts    RateLimiterOptions
 }
 
 type bucket struct {
+	key     string
 	count   int
 	resetAt time.Time
 }
 
+// DefaultRateLimitMaxBuckets bounds the number of tracked clients per limiter.
+const DefaultRateLimitMaxBuckets = 100_000
+
+// RateLimiterOptions holds optional rate limiter settings.
+type RateLimiterOptions struct {
+	// Name labels the limiter's metrics, e.g. "global" or "geo-DE".
+	Name string
+	// MaxBuckets caps tracked clients; the least recently seen client is
+	// evicted beyond it. Zero uses DefaultRateLimitMaxBuckets.
+	MaxBuckets int
+	// Telemetry receives bucket count and eviction metrics.
+	Telemetry telemetry.Sink
+}
+
 // NewRateLimiter creates a rate limiter with the given per-window limit.
 // It starts a background goroutine that evicts expired buckets every 2x window
 // to prevent unbounded memory growth.
 func NewRateLimiter(limit int, windowSeconds int) *RateLimiter {
+	return NewRateLimiterWithOptions(limit, windowSeconds, RateLimiterOptions{})
+}
+
+// NewRateLimiterWithOptions is like NewRateLimiter with explicit options.
+func NewRateLimiterWithOptions(limit int, windowSeconds int, opts RateLimiterOptions) *RateLimiter {
+	if opts.MaxBuckets <= 0 {
+		opts.MaxBuckets = DefaultRateLimitMaxBuckets
+	}
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	opts.Telemetry = telemetry.OrNop(opts.Telemetry)
 	rl := &RateLimiter{
-		buckets: make(map[string]*bucket),
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
 		limit:   limit,
 		window:  time.Duration(windowSeconds) * time.Second,
+		opts:    opts,
 	}
 	go rl.evictLoop()
 	return rl
@@ -93,15 +183,37 @@ func (rl *RateLimiter) evictLoop() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for key, b := range rl.buckets {
-			if now.After(b.resetAt) {
-				delete(rl.buckets, key)
-			}
+		rl.evictExpired(time.Now())
+	}
+}
+
+// evictExpired drops buckets whose window has ended and reports the bucket
+// count.
+func (rl *RateLimiter) evictExpired(now time.Time) {
+	rl.mu.Lock()
+	evicted := 0
+	for key, el := range rl.buckets {
+		if now.After(el.Value.(*bucket).resetAt) {
+			rl.lru.Remove(el)
+			delete(rl.buckets, key)
+			evicted++
 		}
-		rl.mu.Unlock()
 	}
+	size := len(rl.buckets)
+	rl.mu.Unlock()
+
+	labels := telemetry.Labels{"limiter": rl.opts.Name}
+	rl.opts.Telemetry.Gauge("gateway_ratelimit_buckets", float64(size), labels)
+	if evicted > 0 {
+		rl.opts.Telemetry.Count("gateway_ratelimit_evictions_total", float64(evicted), telemetry.Labels{"limiter": rl.opts.Name, "reason": "expired"})
+	}
+}
+
+// BucketCount returns the number of clients currently tracked.
+func (rl *RateLimiter) BucketCount() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.buckets)
 }
 
 // Middleware returns an http.Handler that enforces rate limiting.
@@ -123,9 +235,22 @@ func (rl *RateLimiter) allow(key string) bool {
 	defer rl.mu.Unlock()
 
 	now := time.Now()
-	b, ok := rl.buckets[key]
-	if !ok || now.After(b.resetAt) {
-		rl.buckets[key] = &bucket{count: 1, resetAt: now.Add(rl.window)}
+	el, ok := rl.buckets[key]
+	if !ok {
+		if len(rl.buckets) >= rl.opts.MaxBuckets {
+			oldest := rl.lru.Back()
+			rl.lru.Remove(oldest)
+			delete(rl.buckets, oldest.Value.(*bucket).key)
+			rl.opts.Telemetry.Count("gateway_ratelimit_evictions_total", 1, telemetry.Labels{"limiter": rl.opts.Name, "reason": "capacity"})
+		}
+		rl.buckets[key] = rl.lru.PushFront(&bucket{key: key, count: 1, resetAt: now.Add(rl.window)})
+		return true
+	}
+
+	rl.lru.MoveToFront(el)
+	b := el.Value.(*bucket)
+	if now.After(b.resetAt) {
+		b.count, b.resetAt = 1, now.Add(rl.window)
 		return true
 	}
 
@@ -141,8 +266,33 @@ func (rl *RateLimiter) allow(key string) bool {
 
 // CORS returns middleware that handles Cross-Origin Resource Sharing.
 func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	return CORSWithRoutes(cfg, nil)
+}
+
+// CORSWithRoutes is CORS with per-service settings: requests that routes
+// resolves to a service listed in cfg.PassthroughServices are left to that
+// service, so the gateway adds no CORS headers and forwards their preflights.
+//
+// Only real preflights (OPTIONS with Access-Control-Request-Method) are
+// answered by the gateway; other OPTIONS requests, such as WebDAV or API
+// capability discovery, are passed through to the backend.
+func CORSWithRoutes(cfg CORSConfig, routes *RouteTable) func(http.Handler) http.Handler {
+	passthrough := make(map[string]struct{}, len(cfg.PassthroughServices))
+	for _, s := range cfg.PassthroughServices {
+		passthrough[strings.ToLower(s)] = struct{}{}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if routes != nil && len(passthrough) > 0 {
+				if serviceName, _, ok := routes.Resolve(r); ok {
+					if _, skip := passthrough[strings.ToLower(serviceName)]; skip {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
 			origin := r.Header.Get("Origin")
 
 			if origin != "" {
@@ -170,7 +320,7 @@ func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
 			}
 
 			// Handle preflight.
-			if r.Method == http.MethodOptions {
+			if isPreflight(r) {
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
@@ -180,13 +330,36 @@ func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
 	}
 }
 
+// isPreflight reports whether r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
 // --- JWT Authentication Middleware ---
 
 // JWTAuth returns middleware that validates JWT bearer tokens.
 // It skips validation for paths in the skip list (e.g. /health).
 func JWTAuth(cfg JWTConfig, skipPaths []string) func(http.Handler) http.Handler {
+	return JWTAuthWithRoutes(cfg, nil, skipPaths)
+}
+
+// JWTAuthWithRoutes is JWTAuth with per-service overrides: requests that
+// routes resolves to a service listed in cfg.Services are validated against
+// that service's issuer, audience, and JWKS URL instead of the global
+// settings. Requests whose signature a SignatureVerifier in front verified
+// need no token when their service accepts signatures in place of JWTs.
+func JWTAuthWithRoutes(cfg JWTConfig, routes *RouteTable, skipPaths []string) func(http.Handler) http.Handler {
+	keys := auth.NewKeys(&http.Client{Timeout: 5 * time.Second})
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Browsers never send credentials on preflights; those reaching
+			// this far are forwarded to backends that handle CORS themselves.
+			if isPreflight(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Skip auth for configured paths.
 			for _, p := range skipPaths {
 				if strings.HasPrefix(r.URL.Path, p) {
@@ -195,8 +368,21 @@ func JWTAuth(cfg JWTConfig, skipPaths []string) func(http.Handler) http.Handler
 				}
 			}
 
-			// No secret configured = auth disabled.
-			if cfg.SecretKey == "" {
+			effective := cfg
+			serviceName := ""
+			if routes != nil {
+				if name, _, ok := routes.Resolve(r); ok {
+					serviceName = name
+					effective = cfg.ForService(serviceName)
+				}
+			}
+			if signatureReplacesJWT(r.Context(), serviceName) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// No secret or key set configured = auth disabled.
+			if effective.SecretKey == "" && effective.JWKSURL == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -208,77 +394,31 @@ func JWTAuth(cfg JWTConfig, skipPaths []string) func(http.Handler) http.Handler
 			}
 
 			token := strings.TrimPrefix(authHeader, "Bearer ")
-			if err := validateJWT(token, cfg); err != nil {
+			claims, err := auth.Verify(r.Context(), token, effective, keys)
+			if err != nil {
 				http.Error(w, "invalid token: "+err.Error(), http.StatusUnauthorized)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
 		})
 	}
 }
 
-// validateJWT performs minimal HS256 JWT validation (signature, expiry, issuer, audience).
-func validateJWT(tokenStr string, cfg JWTConfig) error {
-	parts := strings.Split(tokenStr, ".")
-	if len(parts) != 3 {
-		return errInvalidToken
-	}
-
-	// Verify signature (HS256).
-	signingInput := parts[0] + "." + parts[1]
-	mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
-	mac.Write([]byte(signingInput))
-	expectedSig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-
-	if !hmac.Equal([]byte(expectedSig), []byte(parts[2])) {
-		return errInvalidSignature
-	}
-
-	// Decode payload.
-	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return errInvalidToken
-	}
-
-	var claims struct {
-		Exp int64  `json:"exp"`
-		Iss string `json:"iss"`
-		Aud string `json:"aud"`
-	}
-	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
-		return errInvalidToken
-	}
-
-	// Check expiration.
-	if claims.Exp > 0 && time.Now().Unix() > claims.Exp {
-		return errTokenExpired
-	}
-
-	// Check issuer.
-	if cfg.ValidateIssuer && cfg.Issuer != "" && claims.Iss != cfg.Issuer {
-		return errInvalidIssuer
-	}
-
-	// Check audience.
-	if cfg.ValidateAudience && cfg.Audience != "" && claims.Aud != cfg.Audience {
-		return errInvalidAudience
-	}
+// Claims holds the decoded payload of a validated JWT.
+type Claims = auth.Claims
 
-	return nil
+// ClaimsFromContext returns the claims of the token validated by JWTAuth,
+// or nil if the request was not authenticated.
+func ClaimsFromContext(ctx context.Context) Claims {
+	return auth.ClaimsFromContext(ctx)
 }
 
-type jwtError string
-
-func (e jwtError) Error() string { return string(e) }
-
-const (
-	errInvalidToken     = jwtError("invalid token format")
-	errInvalidSignature = jwtError("invalid signature")
-	errTokenExpired     = jwtError("token expired")
-	errInvalidIssuer    = jwtError("invalid issuer")
-	errInvalidAudience  = jwtError("invalid audience")
-)
+// SignJWT mints an HS256 token signed with cfg.SecretKey and carrying the
+// configured issuer and audience. Extra claims are merged over the defaults.
+func SignJWT(cfg JWTConfig, subject string, ttl time.Duration, extra map[string]any) (string, error) {
+	return auth.Sign(cfg, subject, ttl, extra)
+}
 
 // --- Helpers ---
 
@@ -1,15 +1,25 @@
 package gateway
 
 import (
+	"crypto"
 	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
 )
 
 // --- Rate Limiter Tests ---
@@ -48,6 +58,49 @@ func TestRateLimiter_SeparateBucketsPerIP(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_EvictsLeastRecentlyUsedAtCapacity(t *testing.T) {
+	rl := NewRateLimiterWithOptions(1, 60, RateLimiterOptions{MaxBuckets: 2})
+
+	rl.allow("10.0.0.1")
+	rl.allow("10.0.0.2")
+	rl.allow("10.0.0.1") // blocked, but marks 10.0.0.1 as recently used
+	rl.allow("10.0.0.3") // evicts 10.0.0.2
+
+	if n := rl.BucketCount(); n != 2 {
+		t.Fatalf("expected 2 buckets, got %d", n)
+	}
+	if rl.allow("10.0.0.1") {
+		t.Fatal("expected recently used client to keep its bucket")
+	}
+	if !rl.allow("10.0.0.2") {
+		t.Fatal("expected evicted client to start a fresh window")
+	}
+}
+
+func TestRateLimiter_EvictExpiredReportsMetrics(t *testing.T) {
+	sink := telemetry.NewPrometheus()
+	rl := NewRateLimiterWithOptions(5, 60, RateLimiterOptions{Name: "global", Telemetry: sink})
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		rl.allow(ip)
+	}
+
+	rl.evictExpired(time.Now().Add(2 * time.Minute))
+
+	if n := rl.BucketCount(); n != 0 {
+		t.Fatalf("expected expired buckets to be evicted, got %d", n)
+	}
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`gateway_ratelimit_buckets{limiter="global"} 0`,
+		`gateway_ratelimit_evictions_total{limiter="global",reason="expired"} 3`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
 func TestRateLimiter_ResetsAfterWindow(t *testing.T) {
 	rl := NewRateLimiter(1, 1) // 1-second window
 
@@ -150,6 +203,7 @@ func TestCORS_PreflightReturns204(t *testing.T) {
 
 	req := httptest.NewRequest("OPTIONS", "/test", nil)
 	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
 
@@ -158,6 +212,48 @@ func TestCORS_PreflightReturns204(t *testing.T) {
 	}
 }
 
+func TestCORS_OptionsPassthrough(t *testing.T) {
+	handler := CORSWithRoutes(CORSConfig{
+		AllowAnyOrigin:      true,
+		AllowedMethods:      []string{"GET", "POST"},
+		PassthroughServices: []string{"Files"},
+	}, &RouteTable{config: RoutingConfig{RoutePrefix: "/api/", TenantPrefix: "/t/"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, PROPFIND")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name          string
+		path          string
+		preflight     bool
+		wantCode      int
+		wantAllowOrig string
+	}{
+		{"preflight answered by gateway", "/api/orders/1", true, http.StatusNoContent, "*"},
+		{"plain OPTIONS reaches backend", "/api/orders/1", false, http.StatusOK, "*"},
+		{"passthrough preflight reaches backend", "/api/files/docs", true, http.StatusOK, ""},
+		{"tenant passthrough preflight reaches backend", "/t/acme/api/files/docs", true, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("OPTIONS", tt.path, nil)
+			req.Header.Set("Origin", "http://example.com")
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrig {
+				t.Fatalf("expected ACAO %q, got %q", tt.wantAllowOrig, got)
+			}
+		})
+	}
+}
+
 // --- JWT Tests ---
 
 func makeTestJWT(secret, issuer, audience string, expiry time.Time) string {
@@ -273,6 +369,29 @@ func TestJWTAuth_SkipPaths(t *testing.T) {
 	}
 }
 
+func TestJWTAuth_PreflightSkipsAuth(t *testing.T) {
+	cfg := JWTConfig{SecretKey: "test-secret-key-at-least-32-characters"}
+
+	handler := JWTAuth(cfg, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/api/files/docs", nil)
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected preflight to skip auth, got %d", w.Code)
+	}
+
+	// Non-preflight OPTIONS is a real request and still needs a token.
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/api/files/docs", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for plain OPTIONS, got %d", w.Code)
+	}
+}
+
 func TestJWTAuth_NoSecretDisablesAuth(t *testing.T) {
 	cfg := JWTConfig{SecretKey: ""}
 
@@ -322,3 +441,177 @@ func TestClientIPAddress_UntrustedProxyIgnoresXFF(t *testing.T) {
 		t.Fatalf("expected 10.0.0.1 (ignoring XFF from non-loopback), got %s", got)
 	}
 }
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTAuthWithRoutes_PerServiceIdentityProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	var fetches int
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "partner-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer jwks.Close()
+
+	cfg := JWTConfig{
+		SecretKey:        "test-secret-key-at-least-32-characters",
+		Issuer:           "mesh",
+		Audience:         "mesh-services",
+		ValidateIssuer:   true,
+		ValidateAudience: true,
+		Services: map[string]JWTOverride{
+			"partner": {Issuer: "https://idp.partner", Audience: "partner-api", JWKSURL: jwks.URL},
+		},
+	}
+	rules, err := routing.CompileRules([]routing.Rule{
+		{Path: "/partner-api/{rest...}", Service: "partner", StripPrefix: "/partner-api"},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules: %v", err)
+	}
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/", TenantPrefix: "/t/"}, rules: rules}
+	handler := JWTAuthWithRoutes(cfg, rt, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	partnerToken := signRS256(t, key, "partner-1", map[string]any{
+		"iss": "https://idp.partner",
+		"aud": []string{"partner-api", "other"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	meshToken, _ := SignJWT(cfg, "user", time.Hour, nil)
+	unknownKidToken := signRS256(t, key, "rotated-away", map[string]any{"iss": "https://idp.partner", "aud": "partner-api"})
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"partner token on partner route", "/api/partner/orders", partnerToken, http.StatusOK},
+		{"mesh token on partner route", "/api/partner/orders", meshToken, http.StatusUnauthorized},
+		{"unknown key id", "/api/partner/orders", unknownKidToken, http.StatusUnauthorized},
+		{"mesh token on other route", "/api/orders/list", meshToken, http.StatusOK},
+		{"partner token on other route", "/api/orders/list", partnerToken, http.StatusUnauthorized},
+		{"partner token on rule route", "/partner-api/orders", partnerToken, http.StatusOK},
+		{"mesh token on rule route", "/partner-api/orders", meshToken, http.StatusUnauthorized},
+		{"partner token on tenant route", "/t/acme/api/partner/orders", partnerToken, http.StatusOK},
+		{"mesh token on tenant route", "/t/acme/api/partner/orders", meshToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if fetches != 1 {
+		t.Fatalf("expected the key set to be fetched once and cached, got %d fetches", fetches)
+	}
+}
+
+func TestRequestLogging_CorrelationID(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string // empty means a generated ID
+	}{
+		{"client correlation id", map[string]string{"X-Correlation-ID": "abc-123"}, "abc-123"},
+		{"request id fallback", map[string]string{"X-Request-ID": "req-9"}, "req-9"},
+		{"generated", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seenHeader, seenContext string
+			handler := RequestLogging(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seenHeader = r.Header.Get(CorrelationIDHeader)
+				seenContext = CorrelationIDFromContext(r.Context())
+				// A backend echoing the ID must not produce a duplicate.
+				w.Header().Add(CorrelationIDHeader, seenHeader)
+				w.Write([]byte("ok"))
+			}))
+			req := httptest.NewRequest("GET", "/api/orders/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			got := w.Header().Values(CorrelationIDHeader)
+			if len(got) != 1 {
+				t.Fatalf("expected exactly one echoed correlation id, got %v", got)
+			}
+			if tt.want != "" && got[0] != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got[0])
+			}
+			if len(got[0]) == 0 || seenHeader != got[0] || seenContext != got[0] {
+				t.Fatalf("expected request header, context, and response to agree: %q %q %q", seenHeader, seenContext, got[0])
+			}
+		})
+	}
+}
+
+func TestProxy_PropagatesCorrelationIDOnRetries(t *testing.T) {
+	var seen []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get(CorrelationIDHeader))
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"svc": {ServiceName: "svc", Backends: []Backend{{ServiceID: "svc-1", Address: backend.URL}}},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	proxy := NewProxy(rt, ResilienceConfig{
+		RetryCount:              2,
+		RetryBaseDelay:          time.Millisecond,
+		RetryBackoffExponent:    1.0,
+		BreakerFailureThreshold: 10,
+		BreakerBreakDuration:    time.Minute,
+	}, ResponseConfig{}, logger)
+
+	req := httptest.NewRequest("GET", "/api/svc/data", nil)
+	w := httptest.NewRecorder()
+	RequestLogging(logger, proxy).ServeHTTP(w, req)
+
+	id := w.Header().Get(CorrelationIDHeader)
+	if id == "" || len(seen) != 3 {
+		t.Fatalf("expected an echoed id and 3 attempts, got %q and %d", id, len(seen))
+	}
+	for i, s := range seen {
+		if s != id {
+			t.Fatalf("attempt %d: expected correlation id %q, got %q", i+1, id, s)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/auth"
+)
+
+func TestPriorityShedder_Classify(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	ps := NewPriorityShedder(PriorityConfig{Claim: "priority", HighPriorityServices: []string{"Payments"}}, rt)
+
+	tests := []struct {
+		name   string
+		path   string
+		claim  string
+		header string
+		want   PriorityClass
+	}{
+		{"default is low", "/api/catalog/items", "", "", PriorityLow},
+		{"high route class", "/api/payments/charge", "", "", PriorityHigh},
+		{"claim raises priority", "/api/catalog/items", "high", "", PriorityHigh},
+		{"claim lowers priority", "/api/payments/refunds", "low", "", PriorityLow},
+		{"unknown claim value uses route class", "/api/payments/charge", "urgent", "", PriorityHigh},
+		{"header is ignored", "/api/catalog/items", "", "high", PriorityLow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.claim != "" {
+				req = req.WithContext(auth.WithClaims(req.Context(), Claims{"priority": tt.claim}))
+			}
+			if tt.header != "" {
+				req.Header.Set(PriorityHeader, tt.header)
+			}
+			if got := ps.classify(req); got != tt.want {
+				t.Fatalf("classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriorityShedder_StripsHeader(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	ps := NewPriorityShedder(PriorityConfig{Claim: "priority"}, rt)
+
+	var forwarded string
+	handler := ps.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(PriorityHeader)
+	}))
+	req := httptest.NewRequest("GET", "/api/catalog/items", nil)
+	req.Header.Set(PriorityHeader, "high")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if forwarded != "" {
+		t.Fatalf("expected %s to be stripped, got %q", PriorityHeader, forwarded)
+	}
+}
+
+func TestPriorityShedder_ShedsLowPriorityFirst(t *testing.T) {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	ps := NewPriorityShedder(PriorityConfig{
+		Claim:                "priority",
+		HighPriorityServices: []string{"payments"},
+		MaxInFlightLow:       1,
+		MaxInFlightHigh:      2,
+	}, rt)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := ps.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/catalog/slow" {
+			entered <- struct{}{}
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Occupy one slot with a low-priority request.
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/catalog/slow", nil))
+	<-entered
+	defer close(unblock)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/catalog/items", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected low-priority request to be shed with 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/payments/charge", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected high-priority request to be served, got %d", w.Code)
+	}
+}
@@ -1,47 +1,87 @@
 package gateway
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"math"
 	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/toska-mesh/toska-mesh/internal/healthmonitor"
+	"github.com/toska-mesh/toska-mesh/internal/router"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
 )
 
 // Proxy is the reverse proxy handler that routes requests to backend services
 // with retry and circuit breaker resilience.
 type Proxy struct {
 	routes     *RouteTable
+	balancer   router.Balancer
 	resilience ResilienceConfig
+	response   ResponseConfig
 	logger     *slog.Logger
 	transport  http.RoundTripper
+	telemetry  telemetry.Sink
+	// shardHeader is forwarded to the balancer for HashByHeader.
+	shardHeader string
 
 	breakers *breakerMap
 }
 
-// NewProxy creates a reverse proxy backed by the given route table.
-func NewProxy(routes *RouteTable, resilience ResilienceConfig, logger *slog.Logger) *Proxy {
+// NewProxy creates a reverse proxy backed by the given route table. Backends
+// are chosen by a load balancer reading from the route table snapshot, using
+// each service's lb_strategy metadata.
+func NewProxy(routes *RouteTable, resilience ResilienceConfig, response ResponseConfig, logger *slog.Logger) *Proxy {
+	return NewProxyWithTelemetry(routes, resilience, response, nil, logger)
+}
+
+// NewProxyWithTelemetry is like NewProxy but records request, retry, and
+// circuit breaker metrics to sink. A nil sink disables metrics.
+func NewProxyWithTelemetry(routes *RouteTable, resilience ResilienceConfig, response ResponseConfig, sink telemetry.Sink, logger *slog.Logger) *Proxy {
+	return NewProxyWithOptions(routes, resilience, response, ProxyOptions{Telemetry: sink}, logger)
+}
+
+// ProxyOptions holds optional proxy settings.
+type ProxyOptions struct {
+	// Telemetry receives request, retry, and circuit breaker metrics.
+	Telemetry telemetry.Sink
+	// Balancer tunes backend selection, e.g. subsetting.
+	Balancer router.Config
+}
+
+// NewProxyWithOptions creates a reverse proxy with optional settings.
+func NewProxyWithOptions(routes *RouteTable, resilience ResilienceConfig, response ResponseConfig, opts ProxyOptions, logger *slog.Logger) *Proxy {
+	sink := telemetry.OrNop(opts.Telemetry)
 	return &Proxy{
-		routes:     routes,
-		resilience: resilience,
-		logger:     logger,
-		transport:  http.DefaultTransport,
-		breakers:   newBreakerMap(resilience.BreakerFailureThreshold, resilience.BreakerBreakDuration),
+		routes:      routes,
+		balancer:    router.NewLoadBalancerWithConfig(routes, opts.Balancer, sink),
+		resilience:  resilience,
+		response:    response,
+		logger:      logger,
+		transport:   http.DefaultTransport,
+		telemetry:   sink,
+		shardHeader: cmp.Or(opts.Balancer.ShardHeader, router.DefaultShardHeader),
+		breakers:    newBreakerMap(resilience.BreakerFailureThreshold, resilience.BreakerBreakDuration),
 	}
 }
 
 // bufferedResponse holds a captured upstream response so the proxy can
 // inspect the status code before committing bytes to the client.
+// Responses over the size limit in stream mode carry the unread remainder of
+// the upstream body in stream.
 type bufferedResponse struct {
 	statusCode int
 	header     http.Header
 	body       []byte
+	stream     io.ReadCloser
 }
 
 // writeTo flushes the buffered response to the client.
@@ -53,60 +93,138 @@ func (br *bufferedResponse) writeTo(w http.ResponseWriter) {
 	}
 	w.WriteHeader(br.statusCode)
 	w.Write(br.body)
+	if br.stream != nil {
+		io.Copy(w, br.stream)
+		br.stream.Close()
+	}
 }
 
 // maxRequestBody is the maximum allowed size for incoming client request bodies (10MB).
 const maxRequestBody = 10 << 20
 
+// unroutedServiceLabel is the metrics service label for requests to a
+// service with no routes.
+const unroutedServiceLabel = "unknown"
+
 // ServeHTTP handles an incoming request by routing it to a backend service.
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
-	prefix := p.routes.Prefix()
-
-	serviceName, remainder, ok := ParseServiceFromPath(prefix, r.URL.Path)
+	serviceName, remainder, ok := p.routes.Resolve(r)
 	if !ok {
 		http.NotFound(w, r)
 		return
 	}
+	if override := serviceOverrideFromContext(r.Context()); override != "" {
+		serviceName = override
+	}
 
-	backend := p.routes.Lookup(serviceName)
-	if backend == nil {
-		http.Error(w, "service not found: "+serviceName, http.StatusBadGateway)
-		return
+	// The service comes from the client's path, so only routed services get
+	// their own series.
+	label := serviceName
+	if p.routes.BackendCount(serviceName) == 0 {
+		label = unroutedServiceLabel
+	}
+	began := time.Now()
+	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	w = rw
+	defer func() {
+		p.telemetry.Count("gateway_requests_total", 1, telemetry.Labels{
+			"service": label,
+			"code":    strconv.Itoa(rw.statusCode),
+		})
+		p.telemetry.Observe("gateway_request_duration_seconds", time.Since(began).Seconds(), telemetry.Labels{"service": label})
+	}()
+
+	lbCtx := router.Context{
+		SessionID: clientIPAddress(r),
+		Headers: map[string]string{
+			CorrelationIDHeader: r.Header.Get(CorrelationIDHeader),
+			p.shardHeader:       r.Header.Get(p.shardHeader),
+		},
+	}
+	if filter := metadataFilterFromContext(r.Context()); len(filter) > 0 {
+		lbCtx.MetadataFilter = filter
+	}
+	if keys := excludedMetadataKeysFromContext(r.Context()); len(keys) > 0 {
+		lbCtx.ExcludeMetadataKeys = keys
+	}
+	if pref := metadataPreferenceFromContext(r.Context()); len(pref) > 0 {
+		lbCtx.PreferMetadata = pref
 	}
 
-	// Attempt the request with retries.
+	// Attempt the request with retries. Each attempt reserves a backend so the
+	// load balancer only counts requests that are actually sent.
 	var lastErr error
 	var lastStatus int
 	var lastResp *bufferedResponse
 
-	for attempt := range p.resilience.RetryCount + 1 {
+	rc := p.resilienceFor(serviceName)
+
+	for attempt := range rc.RetryCount + 1 {
 		if attempt > 0 {
-			delay := p.retryDelay(attempt)
+			delay := retryDelay(rc, attempt)
 			p.logger.Warn("retrying upstream request",
 				"attempt", attempt+1,
-				"max_attempts", p.resilience.RetryCount+1,
+				"max_attempts", rc.RetryCount+1,
 				"delay", delay,
 				"service", serviceName,
+				"correlation_id", CorrelationIDFromContext(r.Context()),
 			)
+			p.telemetry.Count("gateway_upstream_retries_total", 1, telemetry.Labels{"service": serviceName})
 			time.Sleep(delay)
+		}
 
-			// Re-lookup in case route table changed.
-			if b := p.routes.Lookup(serviceName); b != nil {
-				backend = b
-			}
+		res, err := p.balancer.Reserve(serviceName, lbCtx)
+		if res == nil && attempt == 0 {
+			http.Error(w, "service not found: "+serviceName, http.StatusBadGateway)
+			return
+		}
+		if err != nil || res == nil {
+			lastErr = err
+			continue
+		}
+		// Retries go to other instances while any remain.
+		lbCtx.Exclude = append(lbCtx.Exclude, res.Instance.ServiceID)
+		backend := p.routes.Backend(serviceName, res.Instance.ServiceID)
+		if backend == nil {
+			// Route table changed between selection and lookup.
+			res.Abort()
+			continue
 		}
 
 		// Circuit breaker check.
-		cb := p.breakers.get(backend.ServiceID)
+		cb := p.breakers.getWith(backend.ServiceID, rc.BreakerFailureThreshold, rc.BreakerBreakDuration)
 		if !cb.Allow() {
+			p.telemetry.Count("gateway_breaker_rejections_total", 1, telemetry.Labels{"service": serviceName})
+			res.Abort()
 			lastErr = errCircuitOpen
 			lastStatus = http.StatusServiceUnavailable
 			continue
 		}
 
-		br, err := p.forward(r, backend, remainder)
-		if err == nil && br.statusCode < 500 {
+		res.Commit()
+		start := time.Now()
+		br, err := p.forward(r, serviceName, backend, remainder)
+
+		// An oversized response is not a backend failure and would be just as
+		// large on retry, so report it to the client immediately.
+		if errors.Is(err, errResponseTooLarge) {
+			p.balancer.ReportResult(backend.ServiceID, requestResult(backend.ServiceID, true, time.Since(start), nil, nil))
+			cb.RecordSuccess()
+			p.logger.Warn("upstream response exceeds size limit",
+				"service", serviceName,
+				"service_id", backend.ServiceID,
+				"limit_bytes", p.responseLimit(serviceName, backend),
+				"correlation_id", CorrelationIDFromContext(r.Context()),
+			)
+			http.Error(w, "upstream response too large: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		success := err == nil && br.statusCode < 500
+		p.balancer.ReportResult(backend.ServiceID, requestResult(backend.ServiceID, success, time.Since(start), br, err))
+
+		if success {
 			cb.RecordSuccess()
 			br.writeTo(w)
 			return
@@ -132,6 +250,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		p.logger.Error("upstream request failed after retries",
 			"service", serviceName,
 			"error", lastErr,
+			"correlation_id", CorrelationIDFromContext(r.Context()),
 		)
 	}
 	if lastStatus == 0 {
@@ -140,7 +259,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "upstream request failed", lastStatus)
 }
 
-func (p *Proxy) forward(r *http.Request, backend *Backend, remainder string) (*bufferedResponse, error) {
+func (p *Proxy) forward(r *http.Request, serviceName string, backend *Backend, remainder string) (*bufferedResponse, error) {
 	backendURL, err := url.Parse(backend.Address)
 	if err != nil {
 		return nil, err
@@ -158,36 +277,136 @@ func (p *Proxy) forward(r *http.Request, backend *Backend, remainder string) (*b
 	// Forward hop-by-hop headers.
 	outReq.Header.Del("Connection")
 
-	// Limit the upstream response body to 10MB to prevent memory exhaustion.
-	const maxResponseBody = 10 << 20
-
 	resp, err := p.transport.RoundTrip(outReq)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	// Buffer up to the size limit to bound memory use. Larger responses are
+	// either rejected or, in stream mode, passed through once known to be
+	// successful. Failed (5xx) responses are never streamed because they may
+	// still be retried.
+	limit := p.responseLimit(serviceName, backend)
+	if limit > 0 && resp.ContentLength > limit &&
+		(p.response.Overflow != OverflowStream || resp.StatusCode >= 500) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes exceeds limit of %d", errResponseTooLarge, resp.ContentLength, limit)
+	}
+
+	reader := io.Reader(resp.Body)
+	if limit > 0 {
+		reader = io.LimitReader(resp.Body, limit+1)
+	}
+	body, err := io.ReadAll(reader)
 	if err != nil {
+		resp.Body.Close()
 		return nil, err
 	}
 
-	return &bufferedResponse{
+	br := &bufferedResponse{
 		statusCode: resp.StatusCode,
 		header:     resp.Header.Clone(),
 		body:       body,
-	}, nil
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		if p.response.Overflow != OverflowStream || resp.StatusCode >= 500 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: more than %d bytes", errResponseTooLarge, limit)
+		}
+		br.stream = resp.Body
+		return br, nil
+	}
+
+	resp.Body.Close()
+	return br, nil
+}
+
+// responseLimit returns the response size limit for a service: an operator
+// override from config wins over the backend's max_response_bytes metadata,
+// which wins over the global default. Zero means unlimited.
+func (p *Proxy) responseLimit(serviceName string, backend *Backend) int64 {
+	if v, ok := p.response.ServiceMaxBytes[strings.ToLower(serviceName)]; ok {
+		return v
+	}
+	if v, err := strconv.ParseInt(backend.Metadata["max_response_bytes"], 10, 64); err == nil && v >= 0 {
+		return v
+	}
+	return p.response.MaxBytes
 }
 
+// requestResult converts a forward outcome into a load balancer result.
+func requestResult(serviceID string, success bool, elapsed time.Duration, br *bufferedResponse, err error) router.RequestResult {
+	result := router.RequestResult{
+		ServiceID:    serviceID,
+		Success:      success,
+		ResponseTime: elapsed,
+	}
+	if br != nil {
+		result.StatusCode = br.statusCode
+	}
+	if err != nil {
+		result.ErrorMessage = err.Error()
+	}
+	return result
+}
 
-func (p *Proxy) retryDelay(attempt int) time.Duration {
-	base := float64(p.resilience.RetryBaseDelay)
-	exponential := base * math.Pow(p.resilience.RetryBackoffExponent, float64(attempt-1))
-	jitter := rand.Float64() * float64(p.resilience.RetryJitterMax)
+func retryDelay(rc ResilienceConfig, attempt int) time.Duration {
+	base := float64(rc.RetryBaseDelay)
+	exponential := base * math.Pow(rc.RetryBackoffExponent, float64(attempt-1))
+	jitter := rand.Float64() * float64(rc.RetryJitterMax)
 	return time.Duration(exponential + jitter)
 }
 
-var errCircuitOpen = errors.New("circuit breaker open")
+// resilienceFor returns the effective resilience settings for a service: the
+// global config, then overrides from the service's Consul metadata, then
+// operator overrides from config.
+func (p *Proxy) resilienceFor(serviceName string) ResilienceConfig {
+	rc := p.resilience
+	rc = resilienceOverrideFromMetadata(p.routes.ServiceMetadata(serviceName)).apply(rc)
+	if o, ok := p.resilience.Services[strings.ToLower(serviceName)]; ok {
+		rc = o.apply(rc)
+	}
+	return rc
+}
+
+// BreakerStates returns the circuit breaker state of every backend the proxy
+// has sent traffic to, keyed by service ID.
+func (p *Proxy) BreakerStates() map[string]healthmonitor.BreakerState {
+	return p.breakers.states()
+}
+
+var (
+	errCircuitOpen      = errors.New("circuit breaker open")
+	errResponseTooLarge = errors.New("upstream response exceeds size limit")
+)
+
+// ExportStats returns the load balancer's per-instance request history.
+func (p *Proxy) ExportStats() []router.InstanceStats {
+	return p.balancer.ExportStats()
+}
+
+// ImportStats restores request history, e.g. persisted before a restart.
+func (p *Proxy) ImportStats(stats []router.InstanceStats) {
+	p.balancer.ImportStats(stats)
+}
+
+// ResetStats discards the load balancer's request history of a service, or
+// of every service when serviceName is empty.
+func (p *Proxy) ResetStats(serviceName string) {
+	p.balancer.ResetStats(serviceName)
+}
+
+// ErrorRates returns the recent error rate of each instance of a service,
+// keyed by service ID.
+func (p *Proxy) ErrorRates(serviceName string) map[string]router.ErrorRate {
+	return p.balancer.ErrorRates(serviceName)
+}
+
+// BreakerSnapshots returns counters and timing for every backend breaker,
+// keyed by service ID.
+func (p *Proxy) BreakerSnapshots() map[string]healthmonitor.BreakerSnapshot {
+	return p.breakers.snapshots()
+}
 
 // --- Breaker map ---
 
@@ -196,6 +415,13 @@ type breakerMap struct {
 	duration  time.Duration
 	mu        sync.Mutex
 	breakers  map[string]*healthmonitor.CircuitBreaker
+	settings  map[string]breakerSettings
+}
+
+// breakerSettings are the parameters a breaker was created with.
+type breakerSettings struct {
+	threshold int
+	duration  time.Duration
 }
 
 func newBreakerMap(threshold int, duration time.Duration) *breakerMap {
@@ -203,16 +429,47 @@ func newBreakerMap(threshold int, duration time.Duration) *breakerMap {
 		threshold: threshold,
 		duration:  duration,
 		breakers:  make(map[string]*healthmonitor.CircuitBreaker),
+		settings:  make(map[string]breakerSettings),
+	}
+}
+
+// states returns the current state of every breaker, keyed by service ID.
+func (bm *breakerMap) states() map[string]healthmonitor.BreakerState {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	out := make(map[string]healthmonitor.BreakerState, len(bm.breakers))
+	for id, cb := range bm.breakers {
+		out[id] = cb.State()
+	}
+	return out
+}
+
+// snapshots returns a snapshot of every breaker, keyed by service ID.
+func (bm *breakerMap) snapshots() map[string]healthmonitor.BreakerSnapshot {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	out := make(map[string]healthmonitor.BreakerSnapshot, len(bm.breakers))
+	for id, cb := range bm.breakers {
+		out[id] = cb.Snapshot()
 	}
+	return out
 }
 
 func (bm *breakerMap) get(serviceID string) *healthmonitor.CircuitBreaker {
+	return bm.getWith(serviceID, bm.threshold, bm.duration)
+}
+
+// getWith returns the breaker for serviceID, replacing it if it was created
+// with different settings (e.g. after a per-service override changed).
+func (bm *breakerMap) getWith(serviceID string, threshold int, duration time.Duration) *healthmonitor.CircuitBreaker {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
+	want := breakerSettings{threshold: threshold, duration: duration}
 	cb, ok := bm.breakers[serviceID]
-	if !ok {
-		cb = healthmonitor.NewCircuitBreaker(bm.threshold, bm.duration)
+	if !ok || bm.settings[serviceID] != want {
+		cb = healthmonitor.NewCircuitBreaker(threshold, duration)
 		bm.breakers[serviceID] = cb
+		bm.settings[serviceID] = want
 	}
 	return cb
 }
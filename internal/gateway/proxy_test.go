@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
 )
 
 func TestProxy_RoutesToBackend(t *testing.T) {
@@ -34,7 +38,7 @@ func TestProxy_RoutesToBackend(t *testing.T) {
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, logger)
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, ResponseConfig{}, logger)
 
 	req := httptest.NewRequest("GET", "/api/my-service/hello", nil)
 	w := httptest.NewRecorder()
@@ -56,7 +60,7 @@ func TestProxy_Returns502ForUnknownService(t *testing.T) {
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, logger)
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, ResponseConfig{}, logger)
 
 	req := httptest.NewRequest("GET", "/api/unknown-svc/foo", nil)
 	w := httptest.NewRecorder()
@@ -97,7 +101,7 @@ func TestProxy_Retries5xxAndReturnsLastResponse(t *testing.T) {
 		RetryJitterMax:          0,
 		BreakerFailureThreshold: 10,
 		BreakerBreakDuration:    60_000_000_000,
-	}, logger)
+	}, ResponseConfig{}, logger)
 
 	req := httptest.NewRequest("GET", "/api/svc/data", nil)
 	w := httptest.NewRecorder()
@@ -111,6 +115,56 @@ func TestProxy_Retries5xxAndReturnsLastResponse(t *testing.T) {
 	}
 }
 
+func TestProxy_RetriesOnAnotherInstance(t *testing.T) {
+	var badHits, goodHits int
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		badHits++
+		http.Error(w, "error", http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		goodHits++
+		fmt.Fprintln(w, "ok")
+	}))
+	defer good.Close()
+
+	// IPHash sends every attempt from one client to the same instance,
+	// unless the retry excludes instances already tried.
+	iphash := map[string]string{"lb_strategy": "IPHash"}
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"svc": {
+				ServiceName: "svc",
+				Backends: []Backend{
+					{ServiceID: "svc-bad", Address: bad.URL, Metadata: iphash},
+					{ServiceID: "svc-good", Address: good.URL, Metadata: iphash},
+				},
+			},
+		},
+	}
+	proxy := NewProxy(rt, ResilienceConfig{
+		RetryCount:              1,
+		RetryBaseDelay:          time.Millisecond,
+		RetryBackoffExponent:    1.0,
+		BreakerFailureThreshold: 10,
+		BreakerBreakDuration:    time.Minute,
+	}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := range 10 {
+		req := httptest.NewRequest("GET", "/api/svc/data", nil)
+		req.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", i)
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("client %d: expected 200 from the retry, got %d", i, w.Code)
+		}
+	}
+	if goodHits != 10 || badHits == 0 {
+		t.Fatalf("expected each failed attempt retried once elsewhere, got %d bad and %d good hits", badHits, goodHits)
+	}
+}
+
 func TestProxy_PreservesQueryString(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.RawQuery != "page=2&limit=10" {
@@ -131,7 +185,7 @@ func TestProxy_PreservesQueryString(t *testing.T) {
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, logger)
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: 60_000_000_000}, ResponseConfig{}, logger)
 
 	req := httptest.NewRequest("GET", "/api/svc/data?page=2&limit=10", nil)
 	w := httptest.NewRecorder()
@@ -141,3 +195,249 @@ func TestProxy_PreservesQueryString(t *testing.T) {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
 }
+
+func TestProxy_OpenBreakerDoesNotCountRequest(t *testing.T) {
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"svc": {
+				ServiceName: "svc",
+				Backends:    []Backend{{ServiceID: "svc-1", Address: "http://127.0.0.1:1"}},
+			},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 1, BreakerBreakDuration: 60_000_000_000}, ResponseConfig{}, logger)
+	proxy.breakers.get("svc-1").RecordFailure()
+
+	req := httptest.NewRequest("GET", "/api/svc/data", nil)
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 with open breaker, got %d", w.Code)
+	}
+	if stats := proxy.balancer.Stats("svc"); stats.TotalRequests != 0 {
+		t.Fatalf("expected no committed requests, got %d", stats.TotalRequests)
+	}
+}
+
+func TestProxy_ResponseSizeLimit(t *testing.T) {
+	payload := strings.Repeat("x", 64)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+		}
+		w.Write([]byte(payload))
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name     string
+		response ResponseConfig
+		metadata map[string]string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"under limit", ResponseConfig{MaxBytes: 64}, nil, "", http.StatusOK, payload},
+		{"over limit errors", ResponseConfig{MaxBytes: 16, Overflow: OverflowError}, nil, "", http.StatusBadGateway, "too large"},
+		{"over limit without content length errors", ResponseConfig{MaxBytes: 16}, nil, "?chunked=1", http.StatusBadGateway, "too large"},
+		{"over limit streams", ResponseConfig{MaxBytes: 16, Overflow: OverflowStream}, nil, "?chunked=1", http.StatusOK, payload},
+		{"metadata raises limit", ResponseConfig{MaxBytes: 16}, map[string]string{"max_response_bytes": "128"}, "", http.StatusOK, payload},
+		{"config override wins over metadata", ResponseConfig{MaxBytes: 1024, ServiceMaxBytes: map[string]int64{"svc": 8}}, map[string]string{"max_response_bytes": "128"}, "", http.StatusBadGateway, "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &RouteTable{
+				config: RoutingConfig{RoutePrefix: "/api/"},
+				routes: map[string]*ServiceRoute{
+					"svc": {ServiceName: "svc", Backends: []Backend{{ServiceID: "svc-1", Address: backend.URL, Metadata: tt.metadata}}},
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			proxy := NewProxy(rt, ResilienceConfig{RetryCount: 2, BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, tt.response, logger)
+
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, httptest.NewRequest("GET", "/api/svc/file"+tt.query, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body to contain %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && w.Body.Len() != len(payload) {
+				t.Fatalf("expected full %d-byte body, got %d bytes", len(payload), w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestProxy_PerServiceResilienceOverrides(t *testing.T) {
+	retries := 2
+	tests := []struct {
+		name         string
+		metadata     map[string]string
+		services     map[string]ResilienceOverride
+		wantAttempts int
+	}{
+		{"global retry count", nil, nil, 4},
+		{"metadata disables retries", map[string]string{"retry_count": "0"}, nil, 1},
+		{"config override wins over metadata", map[string]string{"retry_count": "0"}, map[string]ResilienceOverride{"payments": {RetryCount: &retries}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer backend.Close()
+
+			rt := &RouteTable{
+				config: RoutingConfig{RoutePrefix: "/api/"},
+				routes: map[string]*ServiceRoute{
+					"payments": {ServiceName: "payments", Backends: []Backend{{ServiceID: "pay-1", Address: backend.URL, Metadata: tt.metadata}}},
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			proxy := NewProxy(rt, ResilienceConfig{
+				RetryCount:              3,
+				RetryBaseDelay:          time.Millisecond,
+				RetryBackoffExponent:    1.0,
+				BreakerFailureThreshold: 10,
+				BreakerBreakDuration:    time.Minute,
+				Services:                tt.services,
+			}, ResponseConfig{}, logger)
+
+			proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/payments/charge", nil))
+			if attempts != tt.wantAttempts {
+				t.Fatalf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestBreakerMap_RecreatesOnSettingsChange(t *testing.T) {
+	bm := newBreakerMap(5, time.Minute)
+	cb := bm.get("svc-1")
+	if bm.getWith("svc-1", 5, time.Minute) != cb {
+		t.Fatal("expected same breaker for unchanged settings")
+	}
+	if bm.getWith("svc-1", 1, time.Minute) == cb {
+		t.Fatal("expected a new breaker after the threshold changed")
+	}
+}
+
+func TestProxy_RouteRulesSplitByMethod(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+		}))
+	}
+	read, write := newBackend("read"), newBackend("write")
+	defer read.Close()
+	defer write.Close()
+
+	rules, err := routing.CompileRules([]routing.Rule{
+		{Methods: []string{"GET"}, Path: "/api/catalog/{rest...}", Service: "catalog-read", StripPrefix: "/api/catalog"},
+		{Methods: []string{"POST"}, Path: "/api/catalog/{rest...}", Service: "catalog-write", StripPrefix: "/api/catalog"},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules: %v", err)
+	}
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		rules:  rules,
+		routes: map[string]*ServiceRoute{
+			"catalog-read":  {ServiceName: "catalog-read", Backends: []Backend{{ServiceID: "read-1", Address: read.URL}}},
+			"catalog-write": {ServiceName: "catalog-write", Backends: []Backend{{ServiceID: "write-1", Address: write.URL}}},
+		},
+	}
+	proxy := NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/catalog/items", "read /items"},
+		{"POST", "/api/catalog", "write /"},
+		{"GET", "/api/catalog-read/direct", "read /direct"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != tt.want {
+			t.Errorf("%s %s: expected 200 %q, got %d %q", tt.method, tt.path, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestProxy_RecordsTelemetry(t *testing.T) {
+	attempts := 0
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "error", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}))
+	defer backend.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"svc": {ServiceName: "svc", Backends: []Backend{{ServiceID: "svc-1", Address: backend.URL}}},
+		},
+	}
+	sink := telemetry.NewPrometheus()
+	proxy := NewProxyWithTelemetry(rt, ResilienceConfig{
+		RetryCount:              1,
+		RetryBaseDelay:          time.Millisecond,
+		RetryBackoffExponent:    1.0,
+		BreakerFailureThreshold: 10,
+		BreakerBreakDuration:    time.Minute,
+	}, ResponseConfig{}, sink, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/svc/data", nil))
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	for _, want := range []string{
+		`gateway_requests_total{code="200",service="svc"} 1`,
+		`gateway_upstream_retries_total{service="svc"} 1`,
+		`gateway_request_duration_seconds_count{service="svc"} 1`,
+		`router_selections_total{service="svc",strategy="RoundRobin"} 2`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestProxy_UnroutedServiceMetricLabel(t *testing.T) {
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{},
+	}
+	sink := telemetry.NewPrometheus()
+	proxy := NewProxyWithTelemetry(rt, ResilienceConfig{}, ResponseConfig{}, sink, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, path := range []string{"/api/random-1/x", "/api/random-2/x"} {
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `gateway_requests_total{code="502",service="unknown"} 2`) {
+		t.Errorf("expected unrouted requests under service=\"unknown\" in:\n%s", body)
+	}
+	if strings.Contains(body, "random-") {
+		t.Errorf("client path segment leaked into metric labels:\n%s", body)
+	}
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestQueue bounds the number of concurrent proxied requests per service.
+// When a service is saturated, requests wait in a FIFO queue for up to the
+// configured timeout instead of failing immediately, smoothing short bursts.
+type RequestQueue struct {
+	config QueueConfig
+	routes *RouteTable
+
+	mu     sync.Mutex
+	queues map[string]*serviceQueue // keyed by lowercase service name
+}
+
+// NewRequestQueue creates a request queue sized from the route table's
+// current backend counts.
+func NewRequestQueue(config QueueConfig, routes *RouteTable) *RequestQueue {
+	return &RequestQueue{
+		config: config,
+		routes: routes,
+		queues: make(map[string]*serviceQueue),
+	}
+}
+
+// Middleware returns an http.Handler that admits requests to next once the
+// target service has capacity. Requests for unknown services pass through so
+// the proxy can report them.
+func (q *RequestQueue) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serviceName, _, ok := q.routes.Resolve(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		backends := q.routes.BackendCount(serviceName)
+		if backends == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		capacity := backends * q.config.MaxConcurrentPerBackend
+
+		sq := q.get(serviceName)
+		switch err := sq.acquire(r.Context(), capacity, q.config.MaxQueueSize, q.config.QueueTimeout); err {
+		case nil:
+		case errQueueFull:
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(q.config.QueueTimeout)))
+			http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
+			return
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(q.config.QueueTimeout)))
+			http.Error(w, "service saturated: "+serviceName, http.StatusServiceUnavailable)
+			return
+		}
+		defer sq.release(capacity)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (q *RequestQueue) get(serviceName string) *serviceQueue {
+	key := strings.ToLower(serviceName)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	sq, ok := q.queues[key]
+	if !ok {
+		sq = &serviceQueue{}
+		q.queues[key] = sq
+	}
+	return sq
+}
+
+// serviceQueue is a counting semaphore with a bounded FIFO of waiters.
+// Released slots are handed directly to the oldest waiter.
+type serviceQueue struct {
+	mu       sync.Mutex
+	inFlight int
+	waiters  []chan struct{}
+}
+
+func (sq *serviceQueue) acquire(ctx context.Context, capacity, maxQueue int, timeout time.Duration) error {
+	sq.mu.Lock()
+	if sq.inFlight < capacity && len(sq.waiters) == 0 {
+		sq.inFlight++
+		sq.mu.Unlock()
+		return nil
+	}
+	if len(sq.waiters) >= maxQueue {
+		sq.mu.Unlock()
+		return errQueueFull
+	}
+	ch := make(chan struct{})
+	sq.waiters = append(sq.waiters, ch)
+	sq.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ch:
+		return nil
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	sq.mu.Lock()
+	for i, w := range sq.waiters {
+		if w == ch {
+			sq.waiters = append(sq.waiters[:i], sq.waiters[i+1:]...)
+			sq.mu.Unlock()
+			return err
+		}
+	}
+	sq.mu.Unlock()
+
+	// A slot was handed to us just as we gave up; pass it on.
+	sq.release(capacity)
+	return err
+}
+
+func (sq *serviceQueue) release(capacity int) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	// Hand the slot to the oldest waiter unless capacity has shrunk since.
+	if len(sq.waiters) > 0 && sq.inFlight <= capacity {
+		ch := sq.waiters[0]
+		sq.waiters = sq.waiters[1:]
+		close(ch)
+		return
+	}
+	if sq.inFlight > 0 {
+		sq.inFlight--
+	}
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	secs := int(d.Round(time.Second) / time.Second)
+	if secs < 1 {
+		return 1
+	}
+	return secs
+}
+
+type queueError string
+
+func (e queueError) Error() string { return string(e) }
+
+const (
+	errQueueFull    = queueError("request queue full")
+	errQueueTimeout = queueError("request queue timeout")
+)
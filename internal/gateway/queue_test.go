@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServiceQueue_WaiterGetsReleasedSlot(t *testing.T) {
+	sq := &serviceQueue{}
+	if err := sq.acquire(context.Background(), 1, 1, time.Second); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sq.acquire(context.Background(), 1, 1, time.Second) }()
+
+	// Wait until the second caller is queued, then free the slot.
+	for {
+		sq.mu.Lock()
+		n := len(sq.waiters)
+		sq.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sq.release(1)
+
+	if err := <-done; err != nil {
+		t.Fatalf("queued acquire: %v", err)
+	}
+	if sq.inFlight != 1 {
+		t.Fatalf("expected slot to be handed off (inFlight=1), got %d", sq.inFlight)
+	}
+}
+
+func TestServiceQueue_RejectsWhenQueueFull(t *testing.T) {
+	sq := &serviceQueue{}
+	sq.acquire(context.Background(), 1, 0, time.Second)
+
+	if err := sq.acquire(context.Background(), 1, 0, time.Second); err != errQueueFull {
+		t.Fatalf("expected errQueueFull, got %v", err)
+	}
+}
+
+func TestServiceQueue_TimesOut(t *testing.T) {
+	sq := &serviceQueue{}
+	sq.acquire(context.Background(), 1, 1, time.Second)
+
+	if err := sq.acquire(context.Background(), 1, 1, 10*time.Millisecond); err != errQueueTimeout {
+		t.Fatalf("expected errQueueTimeout, got %v", err)
+	}
+	if len(sq.waiters) != 0 {
+		t.Fatalf("expected timed-out waiter to be removed, got %d waiters", len(sq.waiters))
+	}
+}
+
+func TestRequestQueue_Middleware(t *testing.T) {
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"svc": {ServiceName: "svc", Backends: []Backend{{ServiceID: "svc-1", Address: "http://127.0.0.1:1"}}},
+		},
+	}
+	q := NewRequestQueue(QueueConfig{MaxConcurrentPerBackend: 1, MaxQueueSize: 1, QueueTimeout: 20 * time.Millisecond}, rt)
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := q.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Occupy the only slot.
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/svc/a", nil))
+	<-entered
+
+	// Second request waits in the queue and times out with 503.
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/svc/b", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after queue timeout, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	close(unblock)
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReadinessCheck reports whether a dependency is usable; nil means ready.
+type ReadinessCheck func(ctx context.Context) error
+
+// Readiness serves GET /ready for load balancer integration. Unlike /health,
+// which only says the process is alive, it answers 503 until the route table
+// holds its first Consul snapshot and every startup check has passed once.
+type Readiness struct {
+	routes  *RouteTable
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []*startupCheck
+}
+
+type startupCheck struct {
+	name   string
+	check  ReadinessCheck
+	passed bool
+}
+
+// NewReadiness creates a readiness handler. timeout bounds each check run.
+func NewReadiness(routes *RouteTable, timeout time.Duration) *Readiness {
+	return &Readiness{routes: routes, timeout: timeout}
+}
+
+// AddStartupCheck registers a dependency that must become available before
+// the gateway reports ready. Once a check passes it is not run again, so a
+// later dependency outage does not pull the gateway out of rotation.
+func (rd *Readiness) AddStartupCheck(name string, check ReadinessCheck) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rd.checks = append(rd.checks, &startupCheck{name: name, check: check})
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ServeHTTP reports readiness as JSON, with 503 while not ready.
+func (rd *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp := rd.evaluate(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "Ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (rd *Readiness) evaluate(ctx context.Context) readinessResponse {
+	resp := readinessResponse{Status: "Ready", Checks: make(map[string]string)}
+
+	select {
+	case <-rd.routes.Ready():
+		resp.Checks["routes"] = "ok"
+	default:
+		resp.Checks["routes"] = "waiting for first Consul refresh"
+		resp.Status = "NotReady"
+	}
+
+	// Checks run under the lock so concurrent probes don't stampede a
+	// dependency that is still coming up.
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	for _, c := range rd.checks {
+		if !c.passed {
+			checkCtx, cancel := context.WithTimeout(ctx, rd.timeout)
+			err := c.check(checkCtx)
+			cancel()
+			if err != nil {
+				resp.Checks[c.name] = err.Error()
+				resp.Status = "NotReady"
+				continue
+			}
+			c.passed = true
+		}
+		resp.Checks[c.name] = "ok"
+	}
+	return resp
+}
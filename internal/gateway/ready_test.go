@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadiness_WaitsForRoutesAndStartupChecks(t *testing.T) {
+	rt := &RouteTable{ready: make(chan struct{})}
+	rd := NewReadiness(rt, time.Second)
+
+	brokerUp := false
+	calls := 0
+	rd.AddStartupCheck("rabbitmq", func(ctx context.Context) error {
+		calls++
+		if !brokerUp {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	probe := func() (int, readinessResponse) {
+		w := httptest.NewRecorder()
+		rd.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+		var resp readinessResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	if code, resp := probe(); code != http.StatusServiceUnavailable || resp.Checks["routes"] == "ok" {
+		t.Fatalf("expected 503 before routes load, got %d %+v", code, resp)
+	}
+
+	close(rt.ready)
+	if code, resp := probe(); code != http.StatusServiceUnavailable || resp.Checks["rabbitmq"] != "connection refused" {
+		t.Fatalf("expected 503 while broker is down, got %d %+v", code, resp)
+	}
+
+	brokerUp = true
+	if code, resp := probe(); code != http.StatusOK || resp.Status != "Ready" {
+		t.Fatalf("expected 200 once ready, got %d %+v", code, resp)
+	}
+
+	// A passed startup check is not re-run.
+	brokerUp = false
+	before := calls
+	if code, _ := probe(); code != http.StatusOK || calls != before {
+		t.Fatalf("expected latched readiness without re-running check, got %d (calls %d -> %d)", code, before, calls)
+	}
+}
@@ -4,19 +4,30 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"math/rand/v2"
-	"net/url"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/router"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
 )
 
 // Backend represents a single healthy service instance that can receive traffic.
 type Backend struct {
-	ServiceID string
-	Address   string // full URL: scheme://host:port
+	// ServiceID identifies the backend to the load balancer and circuit
+	// breakers. When routing across datacenters it is qualified as
+	// "{datacenter}/{RegistryID}", since IDs are only unique within one.
+	ServiceID  string
+	RegistryID string // the instance's ID in the registry
+	Address    string // full URL: scheme://host:port
+	Metadata   map[string]string
+	Status     types.HealthStatus // registry health when the route was built
 }
 
 // ServiceRoute holds the backends for a single service.
@@ -25,30 +36,85 @@ type ServiceRoute struct {
 	Backends    []Backend
 }
 
+// multiDatacenterRegistry is implemented by registries that can list and
+// watch a service's instances across datacenters, such as Consul's.
+type multiDatacenterRegistry interface {
+	GetInstancesAcrossDatacenters(serviceName string) ([]types.Instance, error)
+	WatchInstancesAcrossDatacenters(ctx context.Context, serviceName string, wait time.Duration) <-chan []types.Instance
+	Datacenters() []string
+}
+
+// acrossDatacenters makes a catalog watch follow each service's instances in
+// every datacenter rather than only the local one.
+type acrossDatacenters struct {
+	registry.Watcher
+	mdc multiDatacenterRegistry
+}
+
+func (a acrossDatacenters) WatchInstances(ctx context.Context, serviceName string, wait time.Duration) <-chan []types.Instance {
+	return a.mdc.WatchInstancesAcrossDatacenters(ctx, serviceName, wait)
+}
+
 // RouteTable maintains a dynamic mapping of service names to healthy backends,
 // refreshed periodically from Consul.
 type RouteTable struct {
-	registry *consul.Registry
+	registry registry.Registry
 	config   RoutingConfig
+	rules    *routing.RuleSet
 	logger   *slog.Logger
+	// qualifyIDs qualifies backend IDs with their datacenter, when routing
+	// across more than one.
+	qualifyIDs bool
+
+	mu          sync.RWMutex
+	routes      map[string]*ServiceRoute // keyed by lowercase service name
+	lastRefresh time.Time                // last successful full refresh
+	lastDiff    RouteDiff                // last refresh that changed anything
 
-	mu     sync.RWMutex
-	routes map[string]*ServiceRoute // keyed by lowercase service name
+	ready     chan struct{} // closed after the first successful full refresh
+	readyOnce sync.Once
 }
 
 // NewRouteTable creates a RouteTable that will poll Consul on the given interval.
-func NewRouteTable(registry *consul.Registry, config RoutingConfig, logger *slog.Logger) *RouteTable {
+func NewRouteTable(registry registry.Registry, config RoutingConfig, logger *slog.Logger) *RouteTable {
+	rules, err := routing.CompileRules(config.Rules)
+	if err != nil {
+		logger.Error("ignoring invalid route rules", "error", err)
+	}
+	mdc, multiDC := registry.(multiDatacenterRegistry)
 	return &RouteTable{
-		registry: registry,
-		config:   config,
-		logger:   logger,
-		routes:   make(map[string]*ServiceRoute),
+		registry:   registry,
+		config:     config,
+		rules:      rules,
+		logger:     logger,
+		qualifyIDs: multiDC && len(mdc.Datacenters()) > 1,
+		routes:     make(map[string]*ServiceRoute),
+		ready:      make(chan struct{}),
 	}
 }
 
 // Run starts the background refresh loop. Blocks until ctx is cancelled.
+// When watching is enabled, routes follow registry changes as soon as they
+// are reported: registries that stream per-service changes update only the
+// changed service's route, and others trigger a full refresh. The periodic
+// ticker remains as a fallback.
 func (rt *RouteTable) Run(ctx context.Context) {
-	rt.refresh()
+	if len(rt.config.WarmServices) > 0 {
+		rt.prefetch(rt.config.WarmServices)
+	}
+	rt.Refresh()
+
+	changes := make(chan struct{}, 1)
+	if rt.config.WatchEnabled {
+		if w, ok := rt.registry.(registry.Watcher); ok {
+			if mdc, ok := rt.registry.(multiDatacenterRegistry); ok {
+				w = acrossDatacenters{w, mdc}
+			}
+			registry.WatchCatalog(ctx, w, rt.config.WatchWaitTime, rt.applyService)
+		} else {
+			go rt.watch(ctx, changes)
+		}
+	}
 
 	ticker := time.NewTicker(rt.config.RefreshInterval)
 	defer ticker.Stop()
@@ -58,11 +124,86 @@ func (rt *RouteTable) Run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			rt.refresh()
+			rt.Refresh()
+		case <-changes:
+			rt.Refresh()
 		}
 	}
 }
 
+// watch issues registry blocking queries and signals changes whenever the
+// catalog index advances. Bursts of changes are coalesced by the debounce delay
+// and the single-slot changes channel.
+func (rt *RouteTable) watch(ctx context.Context, changes chan<- struct{}) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	var index uint64
+	for {
+		newIndex, err := rt.registry.WaitForChange(ctx, index, rt.config.WatchWaitTime)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			rt.logger.Warn("consul watch failed, falling back to polling", "error", err, "retry_in", backoff)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		// Consul may reset its index (e.g. after a snapshot restore). Anything
+		// may have changed, so rebuild and start over from index 0.
+		reset := newIndex < index
+		if reset || (index != 0 && newIndex != index) {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+			if !sleepContext(ctx, rt.config.WatchDebounce) {
+				return
+			}
+		}
+		if reset {
+			newIndex = 0
+		}
+		index = newIndex
+	}
+}
+
+// sleepContext waits for d or until ctx is cancelled. Returns false if cancelled.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// Ready returns a channel that is closed once the first full route snapshot
+// has been built.
+func (rt *RouteTable) Ready() <-chan struct{} {
+	return rt.ready
+}
+
+// WaitReady blocks until the first route snapshot is built or ctx is done.
+func (rt *RouteTable) WaitReady(ctx context.Context) error {
+	select {
+	case <-rt.ready:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for initial routes: %w", ctx.Err())
+	}
+}
+
 // Lookup returns a random healthy backend for the given service name, or nil.
 func (rt *RouteTable) Lookup(serviceName string) *Backend {
 	rt.mu.RLock()
@@ -79,6 +220,90 @@ func (rt *RouteTable) Lookup(serviceName string) *Backend {
 	return &route.Backends[idx]
 }
 
+// GetInstances implements router.InstanceProvider from the current route
+// snapshot, so load balancing never queries Consul on the request path.
+// Only healthy backends are present in the snapshot.
+func (rt *RouteTable) GetInstances(serviceName string) ([]router.Instance, error) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	route, ok := rt.routes[strings.ToLower(serviceName)]
+	if !ok {
+		return nil, nil
+	}
+
+	instances := make([]router.Instance, 0, len(route.Backends))
+	for _, b := range route.Backends {
+		instances = append(instances, router.Instance{
+			ServiceName: route.ServiceName,
+			ServiceID:   b.ServiceID,
+			Address:     b.Address,
+			Status:      router.HealthHealthy,
+			Metadata:    b.Metadata,
+		})
+	}
+	return instances, nil
+}
+
+// Backend returns the backend with the given service ID, or nil if it is no
+// longer routed.
+func (rt *RouteTable) Backend(serviceName, serviceID string) *Backend {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	route, ok := rt.routes[strings.ToLower(serviceName)]
+	if !ok {
+		return nil
+	}
+	for i := range route.Backends {
+		if route.Backends[i].ServiceID == serviceID {
+			b := route.Backends[i]
+			return &b
+		}
+	}
+	return nil
+}
+
+// ServiceMetadata returns the metadata of a service's first backend, which
+// carries service-level settings such as lb_strategy, or nil.
+func (rt *RouteTable) ServiceMetadata(serviceName string) map[string]string {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	route, ok := rt.routes[strings.ToLower(serviceName)]
+	if !ok || len(route.Backends) == 0 {
+		return nil
+	}
+	return route.Backends[0].Metadata
+}
+
+// BackendCount returns the number of healthy backends for a service.
+func (rt *RouteTable) BackendCount(serviceName string) int {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	if route, ok := rt.routes[strings.ToLower(serviceName)]; ok {
+		return len(route.Backends)
+	}
+	return 0
+}
+
+// Snapshot returns a copy of all current routes sorted by service name.
+func (rt *RouteTable) Snapshot() []ServiceRoute {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	out := make([]ServiceRoute, 0, len(rt.routes))
+	for _, route := range rt.routes {
+		out = append(out, ServiceRoute{
+			ServiceName: route.ServiceName,
+			Backends:    append([]Backend(nil), route.Backends...),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out
+}
+
 // Services returns the list of currently routed service names.
 func (rt *RouteTable) Services() []string {
 	rt.mu.RLock()
@@ -93,14 +318,34 @@ func (rt *RouteTable) Services() []string {
 
 // Prefix returns the normalized route prefix (e.g. "/api/").
 func (rt *RouteTable) Prefix() string {
-	return normalizePrefix(rt.config.RoutePrefix)
+	return routing.NormalizePrefix(rt.config.RoutePrefix)
+}
+
+// Resolve returns the service a request is routed to and the path to forward
+// to it. Route rules are tried first, then the {prefix}{service}/... convention.
+// Tenant-scoped paths resolve as TenantRouter forwards them, without their
+// tenant prefix, so middleware in front of it sees the same service.
+func (rt *RouteTable) Resolve(r *http.Request) (serviceName, remainder string, ok bool) {
+	path := r.URL.Path
+	if rt.config.TenantPrefix != "" {
+		if _, rest, ok := splitTenantPath(routing.NormalizePrefix(rt.config.TenantPrefix), path); ok {
+			path = rest
+		}
+	}
+	if rule, rest, ok := rt.rules.Match(r.Method, path); ok {
+		return rule.Service, rest, true
+	}
+	return routing.ParseServiceFromPath(rt.Prefix(), path)
 }
 
-func (rt *RouteTable) refresh() {
+// Refresh rebuilds the route table from a single Consul snapshot. Services
+// whose instances cannot be fetched are skipped; an error is returned only if
+// the service list itself is unavailable.
+func (rt *RouteTable) Refresh() error {
 	services, err := rt.registry.GetServices()
 	if err != nil {
 		rt.logger.Error("failed to list services from Consul", "error", err)
-		return
+		return fmt.Errorf("list services: %w", err)
 	}
 
 	newRoutes := make(map[string]*ServiceRoute, len(services))
@@ -110,89 +355,251 @@ func (rt *RouteTable) refresh() {
 			continue
 		}
 
-		instances, err := rt.registry.GetInstances(serviceName)
+		route, err := rt.buildRoute(serviceName)
 		if err != nil {
 			rt.logger.Error("failed to get instances", "service", serviceName, "error", err)
 			continue
 		}
-
-		var backends []Backend
-		for _, inst := range instances {
-			if inst.Status != consul.HealthHealthy {
-				continue
-			}
-
-			scheme := "http"
-			if s, ok := inst.Metadata["scheme"]; ok && s != "" {
-				scheme = s
-			}
-
-			backends = append(backends, Backend{
-				ServiceID: inst.ServiceID,
-				Address:   fmt.Sprintf("%s://%s:%d", scheme, inst.Address, inst.Port),
-			})
-		}
-
-		if len(backends) == 0 {
+		if route == nil {
 			rt.logger.Warn("no healthy instances", "service", serviceName)
 			continue
 		}
-
-		newRoutes[strings.ToLower(serviceName)] = &ServiceRoute{
-			ServiceName: serviceName,
-			Backends:    backends,
-		}
+		newRoutes[strings.ToLower(serviceName)] = route
 	}
 
+	now := time.Now()
 	rt.mu.Lock()
+	diff := diffRoutes(rt.routes, newRoutes)
+	diff.PreviousRefresh = rt.lastRefresh
+	diff.RefreshedAt = now
 	rt.routes = newRoutes
+	rt.lastRefresh = now
+	if !diff.Empty() {
+		rt.lastDiff = diff
+	}
 	rt.mu.Unlock()
 
+	if !diff.Empty() {
+		rt.logger.Info("route table changed",
+			"added_services", diff.AddedServices,
+			"removed_services", diff.RemovedServices,
+			"changed_services", len(diff.ChangedServices),
+		)
+	}
+
+	rt.readyOnce.Do(func() { close(rt.ready) })
+
 	rt.logger.Info("route table refreshed", "services", len(newRoutes))
+	return nil
+}
+
+// LastRefresh returns when the last successful full refresh completed, or the
+// zero time if none has.
+func (rt *RouteTable) LastRefresh() time.Time {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.lastRefresh
+}
+
+// LastDiff returns the changes made by the last full refresh that changed the
+// route table; refreshes that change nothing leave it in place.
+func (rt *RouteTable) LastDiff() RouteDiff {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.lastDiff
+}
+
+// RouteDiff describes how one refresh changed the route table.
+type RouteDiff struct {
+	RefreshedAt     time.Time     `json:"refreshedAt"`
+	PreviousRefresh time.Time     `json:"previousRefresh"`
+	AddedServices   []string      `json:"addedServices"`
+	RemovedServices []string      `json:"removedServices"`
+	ChangedServices []ServiceDiff `json:"changedServices"`
+}
+
+// ServiceDiff lists backend changes of a service present before and after a
+// refresh. Updated backends kept their ID but changed address or metadata.
+type ServiceDiff struct {
+	ServiceName     string   `json:"serviceName"`
+	AddedBackends   []string `json:"addedBackends,omitempty"`
+	RemovedBackends []string `json:"removedBackends,omitempty"`
+	UpdatedBackends []string `json:"updatedBackends,omitempty"`
+}
+
+// Empty reports whether the refresh changed nothing.
+func (d RouteDiff) Empty() bool {
+	return len(d.AddedServices) == 0 && len(d.RemovedServices) == 0 && len(d.ChangedServices) == 0
+}
+
+// diffRoutes compares two route maps keyed by lowercase service name.
+func diffRoutes(before, after map[string]*ServiceRoute) RouteDiff {
+	diff := RouteDiff{
+		AddedServices:   []string{},
+		RemovedServices: []string{},
+		ChangedServices: []ServiceDiff{},
+	}
+	for key, route := range after {
+		prev, ok := before[key]
+		if !ok {
+			diff.AddedServices = append(diff.AddedServices, route.ServiceName)
+			continue
+		}
+		if sd, changed := diffBackends(route.ServiceName, prev.Backends, route.Backends); changed {
+			diff.ChangedServices = append(diff.ChangedServices, sd)
+		}
+	}
+	for key, route := range before {
+		if _, ok := after[key]; !ok {
+			diff.RemovedServices = append(diff.RemovedServices, route.ServiceName)
+		}
+	}
+	sort.Strings(diff.AddedServices)
+	sort.Strings(diff.RemovedServices)
+	sort.Slice(diff.ChangedServices, func(i, j int) bool {
+		return diff.ChangedServices[i].ServiceName < diff.ChangedServices[j].ServiceName
+	})
+	return diff
 }
 
-// normalizePrefix ensures the prefix starts and ends with "/".
-func normalizePrefix(prefix string) string {
-	if prefix == "" {
-		return "/"
+func diffBackends(serviceName string, before, after []Backend) (ServiceDiff, bool) {
+	sd := ServiceDiff{ServiceName: serviceName}
+	prev := make(map[string]Backend, len(before))
+	for _, b := range before {
+		prev[b.ServiceID] = b
 	}
-	if !strings.HasPrefix(prefix, "/") {
-		prefix = "/" + prefix
+	seen := make(map[string]struct{}, len(after))
+	for _, b := range after {
+		seen[b.ServiceID] = struct{}{}
+		old, ok := prev[b.ServiceID]
+		switch {
+		case !ok:
+			sd.AddedBackends = append(sd.AddedBackends, b.ServiceID)
+		case old.Address != b.Address || !maps.Equal(old.Metadata, b.Metadata):
+			sd.UpdatedBackends = append(sd.UpdatedBackends, b.ServiceID)
+		}
 	}
-	if !strings.HasSuffix(prefix, "/") {
-		prefix += "/"
+	for _, b := range before {
+		if _, ok := seen[b.ServiceID]; !ok {
+			sd.RemovedBackends = append(sd.RemovedBackends, b.ServiceID)
+		}
 	}
-	return prefix
+	sort.Strings(sd.AddedBackends)
+	sort.Strings(sd.RemovedBackends)
+	sort.Strings(sd.UpdatedBackends)
+	changed := len(sd.AddedBackends)+len(sd.RemovedBackends)+len(sd.UpdatedBackends) > 0
+	return sd, changed
 }
 
-// ParseServiceFromPath extracts the service name from a request path given a prefix.
-// For example, with prefix "/api/" and path "/api/my-service/foo/bar",
-// returns ("my-service", "/foo/bar", true).
-func ParseServiceFromPath(prefix, path string) (serviceName, remainder string, ok bool) {
-	if !strings.HasPrefix(path, prefix) {
-		return "", "", false
+// prefetch builds routes for the given critical services ahead of the first
+// full refresh so they become routable as early as possible.
+func (rt *RouteTable) prefetch(services []string) {
+	warmed := 0
+	for _, serviceName := range services {
+		route, err := rt.buildRoute(serviceName)
+		if err != nil {
+			rt.logger.Warn("failed to prefetch service", "service", serviceName, "error", err)
+			continue
+		}
+		if route == nil {
+			rt.logger.Warn("no healthy instances for prefetched service", "service", serviceName)
+			continue
+		}
+
+		rt.mu.Lock()
+		rt.routes[strings.ToLower(serviceName)] = route
+		rt.mu.Unlock()
+		warmed++
 	}
 
-	rest := path[len(prefix):]
-	if rest == "" {
-		return "", "", false
+	rt.logger.Info("prefetched critical services", "requested", len(services), "warmed", warmed)
+}
+
+// buildRoute fetches the instances of a service and returns a route over its
+// healthy ones not held out by the health monitor, or nil if there are none.
+func (rt *RouteTable) buildRoute(serviceName string) (*ServiceRoute, error) {
+	var instances []types.Instance
+	var err error
+	if mdc, ok := rt.registry.(multiDatacenterRegistry); ok {
+		instances, err = mdc.GetInstancesAcrossDatacenters(serviceName)
+	} else {
+		instances, err = rt.registry.GetInstances(serviceName)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return rt.routeFromInstances(serviceName, instances), nil
+}
 
-	// Split on first "/".
-	idx := strings.IndexByte(rest, '/')
-	if idx < 0 {
-		return rest, "/", true
+// routeFromInstances returns a route over the healthy instances not held out
+// by the health monitor, or nil if there are none.
+func (rt *RouteTable) routeFromInstances(serviceName string, instances []types.Instance) *ServiceRoute {
+	var backends []Backend
+	for _, inst := range instances {
+		if inst.Status != types.HealthHealthy || inst.Metadata[types.MetadataHealthHold] != "" {
+			continue
+		}
+
+		id := inst.ServiceID
+		if dc := inst.Metadata[types.MetadataDatacenter]; rt.qualifyIDs && dc != "" {
+			id = dc + "/" + id
+		}
+		backends = append(backends, Backend{
+			ServiceID:  id,
+			RegistryID: inst.ServiceID,
+			Address:    routing.BackendAddress(inst.Metadata["scheme"], inst.Address, inst.Port),
+			Metadata:   inst.Metadata,
+			Status:     inst.Status,
+		})
+	}
+
+	if len(backends) == 0 {
+		return nil
+	}
+
+	return &ServiceRoute{
+		ServiceName: serviceName,
+		Backends:    backends,
 	}
-	return rest[:idx], rest[idx:], true
 }
 
-// BuildBackendURL constructs the full backend URL for a request.
-func BuildBackendURL(backendAddr, remainder, rawQuery string) string {
-	u, err := url.Parse(backendAddr)
-	if err != nil {
-		return backendAddr + remainder
+// applyService updates one service's route from a catalog watch. instances
+// is nil once the service is deregistered.
+func (rt *RouteTable) applyService(serviceName string, instances []types.Instance) {
+	if strings.EqualFold(serviceName, "consul") {
+		return
+	}
+	var route *ServiceRoute
+	if instances != nil {
+		route = rt.routeFromInstances(serviceName, instances)
+	}
+
+	key := strings.ToLower(serviceName)
+	before, after := map[string]*ServiceRoute{}, map[string]*ServiceRoute{}
+	now := time.Now()
+	rt.mu.Lock()
+	if prev, ok := rt.routes[key]; ok {
+		before[key] = prev
+	}
+	if route != nil {
+		after[key] = route
+		rt.routes[key] = route
+	} else {
+		delete(rt.routes, key)
+	}
+	diff := diffRoutes(before, after)
+	if !diff.Empty() {
+		diff.PreviousRefresh = rt.lastRefresh
+		diff.RefreshedAt = now
+		rt.lastDiff = diff
+	}
+	rt.mu.Unlock()
+
+	if !diff.Empty() {
+		rt.logger.Info("route table changed",
+			"added_services", diff.AddedServices,
+			"removed_services", diff.RemovedServices,
+			"changed_services", len(diff.ChangedServices),
+		)
 	}
-	u.Path = remainder
-	u.RawQuery = rawQuery
-	return u.String()
 }
@@ -1,73 +1,222 @@
 package gateway
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/types"
 )
 
-func TestNormalizePrefix(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"", "/"},
-		{"/", "/"},
-		{"/api", "/api/"},
-		{"/api/", "/api/"},
-		{"api", "/api/"},
-		{"api/", "/api/"},
-	}
-
-	for _, tt := range tests {
-		got := normalizePrefix(tt.input)
-		if got != tt.expected {
-			t.Errorf("normalizePrefix(%q) = %q, want %q", tt.input, got, tt.expected)
+// fakeConsulInstance is a service entry served by newFakeConsul.
+type fakeConsulInstance struct {
+	ID      string
+	Address string
+	Port    int
+	Status  string // Consul check status, e.g. "passing"
+	Meta    map[string]string
+}
+
+// newFakeConsul starts an HTTP server emulating the Consul catalog and health
+// endpoints used by consul.Registry, and returns a registry pointed at it.
+func newFakeConsul(t *testing.T, services map[string][]fakeConsulInstance) *consul.Registry {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Consul-Index", "1")
+		switch {
+		case r.URL.Path == "/v1/catalog/services":
+			out := make(map[string][]string, len(services))
+			for name := range services {
+				out[name] = nil
+			}
+			json.NewEncoder(w).Encode(out)
+		case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+			name := strings.TrimPrefix(r.URL.Path, "/v1/health/service/")
+			var out []map[string]any
+			for _, inst := range services[name] {
+				out = append(out, map[string]any{
+					"Service": map[string]any{
+						"ID": inst.ID, "Service": name, "Address": inst.Address, "Port": inst.Port, "Meta": inst.Meta,
+					},
+					"Checks": []map[string]any{{"Status": inst.Status}},
+				})
+			}
+			json.NewEncoder(w).Encode(out)
+		case r.URL.Path == "/v1/health/state/any":
+			w.Write([]byte("[]"))
+		default:
+			http.NotFound(w, r)
 		}
+	}))
+	t.Cleanup(srv.Close)
+
+	reg, err := consul.NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
 	}
+	return reg
 }
 
-func TestParseServiceFromPath(t *testing.T) {
-	tests := []struct {
-		prefix      string
-		path        string
-		wantService string
-		wantRest    string
-		wantOK      bool
-	}{
-		{"/api/", "/api/my-service/foo/bar", "my-service", "/foo/bar", true},
-		{"/api/", "/api/my-service", "my-service", "/", true},
-		{"/api/", "/api/my-service/", "my-service", "/", true},
-		{"/api/", "/api/", "", "", false},
-		{"/api/", "/other/path", "", "", false},
-		{"/", "/my-service/hello", "my-service", "/hello", true},
-		{"/", "/my-service", "my-service", "/", true},
-	}
-
-	for _, tt := range tests {
-		svc, rest, ok := ParseServiceFromPath(tt.prefix, tt.path)
-		if ok != tt.wantOK || svc != tt.wantService || rest != tt.wantRest {
-			t.Errorf("ParseServiceFromPath(%q, %q) = (%q, %q, %v), want (%q, %q, %v)",
-				tt.prefix, tt.path, svc, rest, ok, tt.wantService, tt.wantRest, tt.wantOK)
+func TestRouteTable_ReadyAfterInitialRefresh(t *testing.T) {
+	reg := newFakeConsul(t, map[string][]fakeConsulInstance{
+		"orders":  {{ID: "orders-1", Address: "10.0.0.1", Port: 8080, Status: "passing"}},
+		"billing": {{ID: "billing-1", Address: "10.0.0.2", Port: 8080, Status: "critical"}},
+	})
+	rt := NewRouteTable(reg, RoutingConfig{RoutePrefix: "/api/", RefreshInterval: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	select {
+	case <-rt.Ready():
+		t.Fatal("route table should not be ready before the first refresh")
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rt.Run(ctx)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	if err := rt.WaitReady(waitCtx); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+
+	if b := rt.Backend("orders", "orders-1"); b == nil || b.Address != "http://10.0.0.1:8080" {
+		t.Fatalf("expected orders backend, got %+v", b)
+	}
+	if b := rt.Lookup("billing"); b != nil {
+		t.Fatalf("expected no route for unhealthy billing, got %+v", b)
+	}
+}
+
+func TestRouteTable_SkipsInstancesHeldByHealthMonitor(t *testing.T) {
+	reg := newFakeConsul(t, map[string][]fakeConsulInstance{
+		"orders": {
+			{ID: "orders-1", Address: "10.0.0.1", Port: 8080, Status: "passing"},
+			{ID: "orders-2", Address: "10.0.0.2", Port: 8080, Status: "passing", Meta: map[string]string{types.MetadataHealthHold: "flapping"}},
+		},
+	})
+	rt := NewRouteTable(reg, RoutingConfig{RoutePrefix: "/api/", RefreshInterval: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := rt.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if n := rt.BackendCount("orders"); n != 1 {
+		t.Fatalf("expected 1 routable backend, got %d", n)
+	}
+	if b := rt.Backend("orders", "orders-2"); b != nil {
+		t.Fatalf("expected held instance not routed, got %+v", b)
+	}
+}
+
+func TestRouteTable_PrefetchWarmsCriticalServices(t *testing.T) {
+	reg := newFakeConsul(t, map[string][]fakeConsulInstance{
+		"payments": {{ID: "pay-1", Address: "10.0.0.3", Port: 443, Status: "passing", Meta: map[string]string{"scheme": "https"}}},
+	})
+	rt := NewRouteTable(reg, RoutingConfig{RoutePrefix: "/api/"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rt.prefetch([]string{"payments", "missing"})
+
+	if b := rt.Lookup("payments"); b == nil || b.Address != "https://10.0.0.3:443" {
+		t.Fatalf("expected prefetched payments backend, got %+v", b)
+	}
+	select {
+	case <-rt.Ready():
+		t.Fatal("prefetch alone should not mark the route table ready")
+	default:
+	}
+}
+
+func TestRouteTable_WatchTreatsIndexResetAsChange(t *testing.T) {
+	// Consul reports index 10, then 3 after a snapshot restore, then blocks.
+	indexes := make(chan string, 2)
+	indexes <- "10"
+	indexes <- "3"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case idx := <-indexes:
+			w.Header().Set("X-Consul-Index", idx)
+			w.Write([]byte("[]"))
+		case <-r.Context().Done():
 		}
+	}))
+	defer srv.Close()
+
+	reg, err := consul.NewRegistry(srv.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	rt := NewRouteTable(reg, RoutingConfig{RoutePrefix: "/api/", WatchWaitTime: time.Second}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 1)
+	go rt.watch(ctx, changes)
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an index reset to signal a change")
+	}
+}
+
+func TestRouteTable_ApplyServiceUpdatesOneRoute(t *testing.T) {
+	services := map[string][]fakeConsulInstance{
+		"orders": {{ID: "orders-1", Address: "10.0.0.1", Port: 8080, Status: "passing"}},
+		"users":  {{ID: "users-1", Address: "10.0.0.2", Port: 8080, Status: "passing"}},
+	}
+	rt := NewRouteTable(newFakeConsul(t, services), RoutingConfig{RoutePrefix: "/api/"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := rt.Refresh(); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	rt.applyService("orders", []types.Instance{
+		{ServiceID: "orders-1", Address: "10.0.0.1", Port: 8080, Status: types.HealthHealthy},
+		{ServiceID: "orders-2", Address: "10.0.0.3", Port: 8080, Status: types.HealthHealthy},
+	})
+	if n := rt.BackendCount("orders"); n != 2 {
+		t.Fatalf("expected orders to have 2 backends, got %d", n)
+	}
+	if diff := rt.LastDiff(); len(diff.ChangedServices) != 1 || diff.ChangedServices[0].ServiceName != "orders" {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	rt.applyService("users", nil)
+	if rt.Lookup("users") != nil {
+		t.Fatal("expected users route to be removed")
+	}
+	if diff := rt.LastDiff(); len(diff.RemovedServices) != 1 || diff.RemovedServices[0] != "users" {
+		t.Fatalf("unexpected diff %+v", diff)
 	}
 }
 
-func TestBuildBackendURL(t *testing.T) {
-	tests := []struct {
-		addr      string
-		remainder string
-		query     string
-		want      string
-	}{
-		{"http://10.0.0.1:8080", "/hello", "", "http://10.0.0.1:8080/hello"},
-		{"http://10.0.0.1:8080", "/hello", "q=1", "http://10.0.0.1:8080/hello?q=1"},
-		{"https://svc.local:443", "/api/v1/data", "page=2&limit=10", "https://svc.local:443/api/v1/data?page=2&limit=10"},
-	}
-
-	for _, tt := range tests {
-		got := BuildBackendURL(tt.addr, tt.remainder, tt.query)
-		if got != tt.want {
-			t.Errorf("BuildBackendURL(%q, %q, %q) = %q, want %q",
-				tt.addr, tt.remainder, tt.query, got, tt.want)
+func TestRouteTable_QualifiesBackendIDsAcrossDatacenters(t *testing.T) {
+	instances := []types.Instance{
+		{ServiceID: "api-1", Address: "10.0.0.1", Port: 8080, Status: types.HealthHealthy, Metadata: map[string]string{types.MetadataDatacenter: "eu-west"}},
+		{ServiceID: "api-1", Address: "10.1.0.1", Port: 8080, Status: types.HealthHealthy, Metadata: map[string]string{types.MetadataDatacenter: "us-east"}},
+	}
+
+	single := &RouteTable{}
+	if route := single.routeFromInstances("api", instances[:1]); route.Backends[0].ServiceID != "api-1" {
+		t.Fatalf("expected a bare ID within one datacenter, got %q", route.Backends[0].ServiceID)
+	}
+
+	rt := &RouteTable{qualifyIDs: true, routes: map[string]*ServiceRoute{}}
+	rt.routes["api"] = rt.routeFromInstances("api", instances)
+	for _, tt := range []struct{ id, address string }{
+		{"eu-west/api-1", "http://10.0.0.1:8080"},
+		{"us-east/api-1", "http://10.1.0.1:8080"},
+	} {
+		b := rt.Backend("api", tt.id)
+		if b == nil || b.Address != tt.address || b.RegistryID != "api-1" {
+			t.Fatalf("Backend(%q) = %+v, want address %s", tt.id, b, tt.address)
 		}
 	}
 }
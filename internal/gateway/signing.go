@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/pkg/requestsign"
+)
+
+// SignatureVerifier authenticates the calling service of service-to-service
+// requests signed with requestsign. Each calling service has its own HMAC
+// key, stored in Consul KV at {KVPrefix}{service} and watched for changes so
+// keys can be rotated without restarting the gateway.
+type SignatureVerifier struct {
+	registry registry.Registry
+	config   SigningConfig
+	logger   *slog.Logger
+	now      func() time.Time
+	replays  *requestsign.ReplayCache
+
+	mu   sync.RWMutex
+	keys map[string][]byte // keyed by lowercase service name
+
+	withoutJWT map[string]bool // services accepting a signature in place of a JWT
+}
+
+// NewSignatureVerifier creates a verifier backed by Consul KV.
+func NewSignatureVerifier(registry registry.Registry, config SigningConfig, logger *slog.Logger) *SignatureVerifier {
+	withoutJWT := make(map[string]bool, len(config.AcceptWithoutJWT))
+	for _, name := range config.AcceptWithoutJWT {
+		withoutJWT[strings.ToLower(name)] = true
+	}
+	return &SignatureVerifier{
+		registry:   registry,
+		config:     config,
+		logger:     logger,
+		now:        time.Now,
+		replays:    requestsign.NewReplayCache(config.MaxClockSkew),
+		keys:       make(map[string][]byte),
+		withoutJWT: withoutJWT,
+	}
+}
+
+// Run watches the KV prefix until ctx is cancelled, reloading keys whenever
+// they change.
+func (v *SignatureVerifier) Run(ctx context.Context) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	var index uint64
+	for {
+		values, newIndex, err := v.registry.ListKV(ctx, v.config.KVPrefix, index, v.config.WatchWaitTime)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			v.logger.Warn("signing key watch failed", "error", err, "retry_in", backoff)
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		v.load(values)
+		if newIndex < index {
+			newIndex = 0
+		}
+		index = newIndex
+	}
+}
+
+// load replaces the key set from raw KV values. Empty values are ignored so a
+// half-written key never accepts an empty secret.
+func (v *SignatureVerifier) load(values map[string][]byte) {
+	keys := make(map[string][]byte, len(values))
+	for key, value := range values {
+		name := strings.ToLower(strings.TrimPrefix(key, v.config.KVPrefix))
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+		secret := []byte(strings.TrimSpace(string(value)))
+		if len(secret) == 0 {
+			v.logger.Warn("ignoring empty signing key", "key", key)
+			continue
+		}
+		keys[name] = secret
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(keys) != len(v.keys) {
+		v.logger.Info("signing keys loaded", "services", len(keys))
+	}
+	v.keys = keys
+}
+
+func (v *SignatureVerifier) key(service string) ([]byte, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok := v.keys[strings.ToLower(service)]
+	return key, ok
+}
+
+type signedServiceKey struct{}
+
+// signedCall is a request whose signature was verified.
+type signedCall struct {
+	service    string          // the calling service
+	withoutJWT map[string]bool // the verifier's SigningConfig.AcceptWithoutJWT
+}
+
+// SignedServiceFromContext returns the calling service authenticated by a
+// request signature, or "" if the request was not signed.
+func SignedServiceFromContext(ctx context.Context) string {
+	call, _ := ctx.Value(signedServiceKey{}).(signedCall)
+	return call.service
+}
+
+// signatureReplacesJWT reports whether the request was signed by a verified
+// calling service and is routed to a service that accepts signatures in
+// place of JWTs.
+func signatureReplacesJWT(ctx context.Context, serviceName string) bool {
+	call, ok := ctx.Value(signedServiceKey{}).(signedCall)
+	return ok && (call.withoutJWT["*"] || serviceName != "" && call.withoutJWT[strings.ToLower(serviceName)])
+}
+
+// Middleware verifies requests carrying the requestsign.ServiceHeader and
+// rejects bad or replayed signatures with 401. The signature identifies the
+// calling service, and for services in SigningConfig.AcceptWithoutJWT it
+// authenticates the request in place of a JWT; unsigned requests pass
+// through to next unchanged.
+func (v *SignatureVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		service := r.Header.Get(requestsign.ServiceHeader)
+		if service == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := v.key(service)
+		if !ok {
+			http.Error(w, "unknown calling service", http.StatusUnauthorized)
+			return
+		}
+		if err := requestsign.Verify(r, key, v.now(), v.config.MaxClockSkew, v.config.MaxBodyBytes); err != nil {
+			if errors.Is(err, requestsign.ErrBodyTooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			v.logger.Warn("rejected signed request",
+				"service", service,
+				"path", r.URL.Path,
+				"error", err,
+				"correlation_id", CorrelationIDFromContext(r.Context()),
+			)
+			http.Error(w, "invalid request signature: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err := v.replays.Check(r, v.now()); err != nil {
+			v.logger.Warn("rejected replayed signed request",
+				"service", service,
+				"path", r.URL.Path,
+				"correlation_id", CorrelationIDFromContext(r.Context()),
+			)
+			http.Error(w, "invalid request signature: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		call := signedCall{service: service, withoutJWT: v.withoutJWT}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), signedServiceKey{}, call)))
+	})
+}
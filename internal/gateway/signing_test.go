@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/pkg/requestsign"
+)
+
+func TestSignatureVerifier_Middleware(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := NewSignatureVerifier(nil, SigningConfig{
+		KVPrefix:     "toska-mesh/signing-keys/",
+		MaxClockSkew: time.Minute,
+		MaxBodyBytes: 1 << 10,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	v.now = func() time.Time { return now }
+	v.load(map[string][]byte{
+		"toska-mesh/signing-keys/Billing": []byte("billing-secret\n"),
+		"toska-mesh/signing-keys/empty":   nil,
+	})
+
+	// The signature identifies the caller on top of JWT auth.
+	jwtCfg := JWTConfig{SecretKey: "jwt-secret"}
+	token, _ := SignJWT(jwtCfg, "billing", time.Hour, nil)
+	handler := v.Middleware(JWTAuth(jwtCfg, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(SignedServiceFromContext(r.Context()) + ":" + string(body)))
+		}),
+	))
+
+	signed := func(service, key string, at time.Time, withToken bool) *http.Request {
+		r := httptest.NewRequest("POST", "/api/orders/items", strings.NewReader("payload"))
+		if withToken {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if err := requestsign.Sign(r, service, []byte(key), at); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	replayed := signed("billing", "billing-secret", now, true)
+	handler.ServeHTTP(httptest.NewRecorder(), replayed.Clone(replayed.Context()))
+	replayed.Body = io.NopCloser(strings.NewReader("payload"))
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantCode int
+		wantBody string
+	}{
+		{"valid signature and token", signed("billing", "billing-secret", now, true), http.StatusOK, "billing:payload"},
+		{"valid signature without token", signed("billing", "billing-secret", now, false), http.StatusUnauthorized, "authorization header"},
+		{"replayed", replayed, http.StatusUnauthorized, "nonce already used"},
+		{"wrong key", signed("billing", "guess", now, true), http.StatusUnauthorized, "signature mismatch"},
+		{"stale", signed("billing", "billing-secret", now.Add(-time.Hour), true), http.StatusUnauthorized, "clock skew"},
+		{"unknown service", signed("empty", "", now, true), http.StatusUnauthorized, "unknown calling service"},
+		{"unsigned falls through to JWT", httptest.NewRequest("GET", "/api/orders/items", nil), http.StatusUnauthorized, "authorization header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body to contain %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSignatureVerifier_AcceptWithoutJWT(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := NewSignatureVerifier(nil, SigningConfig{
+		KVPrefix:         "toska-mesh/signing-keys/",
+		MaxClockSkew:     time.Minute,
+		MaxBodyBytes:     1 << 10,
+		AcceptWithoutJWT: []string{"Orders"},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	v.now = func() time.Time { return now }
+	v.load(map[string][]byte{"toska-mesh/signing-keys/billing": []byte("billing-secret")})
+
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	handler := v.Middleware(JWTAuthWithRoutes(JWTConfig{SecretKey: "jwt-secret"}, rt, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("reached " + SignedServiceFromContext(r.Context())))
+		}),
+	))
+
+	tests := []struct {
+		name     string
+		path     string
+		sign     bool
+		wantCode int
+	}{
+		{"signed to accepting service", "/api/orders/items", true, http.StatusOK},
+		{"signed to other service", "/api/payments/items", true, http.StatusUnauthorized},
+		{"unsigned to accepting service", "/api/orders/items", false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.sign {
+				if err := requestsign.Sign(r, "billing", []byte("billing-secret"), now); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && w.Body.String() != "reached billing" {
+				t.Fatalf("expected the backend reached as billing, got %q", w.Body.String())
+			}
+		})
+	}
+}
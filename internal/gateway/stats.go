@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/router"
+)
+
+// StatsStore persists the proxy's load balancer statistics to disk so that
+// dashboards keep their history across gateway restarts.
+type StatsStore struct {
+	proxy  *Proxy
+	config StatsConfig
+	logger *slog.Logger
+}
+
+// NewStatsStore creates a store for the proxy's statistics.
+func NewStatsStore(proxy *Proxy, config StatsConfig, logger *slog.Logger) *StatsStore {
+	return &StatsStore{proxy: proxy, config: config, logger: logger}
+}
+
+// Load restores statistics from PersistPath. A missing file is not an error.
+func (s *StatsStore) Load() error {
+	data, err := os.ReadFile(s.config.PersistPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read stats: %w", err)
+	}
+	var stats []router.InstanceStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return fmt.Errorf("decode stats %s: %w", s.config.PersistPath, err)
+	}
+	s.proxy.ImportStats(stats)
+	s.logger.Info("restored load balancer stats", "path", s.config.PersistPath, "instances", len(stats))
+	return nil
+}
+
+// Save writes the current statistics to PersistPath, replacing the file
+// atomically so a crash mid-write never leaves a truncated snapshot.
+func (s *StatsStore) Save() error {
+	data, err := json.Marshal(sortedStats(s.proxy.ExportStats()))
+	if err != nil {
+		return fmt.Errorf("encode stats: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.PersistPath), ".stats-*")
+	if err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write stats: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.config.PersistPath); err != nil {
+		return fmt.Errorf("write stats: %w", err)
+	}
+	return nil
+}
+
+// Run saves statistics every PersistInterval. Blocks until ctx is cancelled;
+// callers should Save once more after the server has drained.
+func (s *StatsStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PersistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Save(); err != nil {
+				s.logger.Warn("failed to persist load balancer stats", "error", err)
+			}
+		}
+	}
+}
+
+// StatsExportHandler serves GET /admin/stats: per-instance load balancer
+// statistics as JSON, or as CSV with ?format=csv.
+func StatsExportHandler(proxy *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := sortedStats(proxy.ExportStats())
+
+		switch r.URL.Query().Get("format") {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(stats)
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="gateway-stats.csv"`)
+			cw := csv.NewWriter(w)
+			cw.Write([]string{"service_name", "service_id", "total_requests", "successful_requests", "failed_requests", "avg_response_ms", "p50_response_ms", "p95_response_ms", "p99_response_ms"})
+			for _, st := range stats {
+				var avg float64
+				if st.TotalRequests > 0 {
+					avg = float64(st.TotalResponseNanos) / float64(st.TotalRequests) / float64(time.Millisecond)
+				}
+				cw.Write([]string{
+					st.ServiceName,
+					st.ServiceID,
+					strconv.FormatInt(st.TotalRequests, 10),
+					strconv.FormatInt(st.SuccessfulRequests, 10),
+					strconv.FormatInt(st.FailedRequests, 10),
+					strconv.FormatFloat(avg, 'f', 3, 64),
+					formatMillis(st.P50ResponseNanos),
+					formatMillis(st.P95ResponseNanos),
+					formatMillis(st.P99ResponseNanos),
+				})
+			}
+			cw.Flush()
+		default:
+			http.Error(w, "unsupported format: use json or csv", http.StatusBadRequest)
+		}
+	})
+}
+
+// errorRateView is the JSON view of an instance's recent error rate.
+type errorRateView struct {
+	Requests int64   `json:"requests"`
+	Failures int64   `json:"failures"`
+	Rate     float64 `json:"rate"`
+}
+
+// ErrorRatesHandler serves GET /admin/stats/{service}/errors: the recent error
+// rate of each instance of the service, keyed by service ID.
+func ErrorRatesHandler(proxy *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := make(map[string]errorRateView)
+		for id, rate := range proxy.ErrorRates(r.PathValue("service")) {
+			out[id] = errorRateView{Requests: rate.Requests, Failures: rate.Failures, Rate: rate.Rate}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+}
+
+// StatsResetHandler serves DELETE /admin/stats/{service} and DELETE
+// /admin/stats: discards the load balancer's request history of one service,
+// or of every service.
+func StatsResetHandler(proxy *Proxy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxy.ResetStats(r.PathValue("service"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// sortedStats orders stats by service name, then service ID, for stable output.
+func sortedStats(stats []router.InstanceStats) []router.InstanceStats {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ServiceName != stats[j].ServiceName {
+			return stats[i].ServiceName < stats[j].ServiceName
+		}
+		return stats[i].ServiceID < stats[j].ServiceID
+	})
+	return stats
+}
+
+// formatMillis formats nanoseconds as milliseconds for CSV export.
+func formatMillis(nanos int64) string {
+	return strconv.FormatFloat(float64(nanos)/float64(time.Millisecond), 'f', 3, 64)
+}
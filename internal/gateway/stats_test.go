@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/router"
+)
+
+func newStatsTestProxy() *Proxy {
+	rt := &RouteTable{config: RoutingConfig{RoutePrefix: "/api/"}}
+	return NewProxy(rt, ResilienceConfig{BreakerFailureThreshold: 5, BreakerBreakDuration: time.Minute}, ResponseConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestStatsStore_SaveAndLoad(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := StatsConfig{PersistPath: filepath.Join(t.TempDir(), "stats.json"), PersistInterval: time.Minute}
+
+	fresh := newStatsTestProxy()
+	if err := NewStatsStore(fresh, cfg, logger).Load(); err != nil {
+		t.Fatalf("expected missing file to be ignored, got %v", err)
+	}
+
+	before := newStatsTestProxy()
+	before.ImportStats([]router.InstanceStats{{ServiceName: "orders", ServiceID: "orders-1", TotalRequests: 5, SuccessfulRequests: 4, FailedRequests: 1}})
+	if err := NewStatsStore(before, cfg, logger).Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	after := newStatsTestProxy()
+	if err := NewStatsStore(after, cfg, logger).Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := after.balancer.Stats("orders"); got.TotalRequests != 5 || got.FailedRequests != 1 {
+		t.Fatalf("expected restored stats, got %+v", got)
+	}
+}
+
+func TestStatsExportHandler_Formats(t *testing.T) {
+	proxy := newStatsTestProxy()
+	proxy.ImportStats([]router.InstanceStats{
+		{ServiceName: "users", ServiceID: "users-1", TotalRequests: 2, SuccessfulRequests: 2, TotalResponseNanos: int64(30 * time.Millisecond)},
+		{ServiceName: "orders", ServiceID: "orders-1", TotalRequests: 1, FailedRequests: 1},
+	})
+	handler := StatsExportHandler(proxy)
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{"", http.StatusOK, `"serviceId":"orders-1"`},
+		{"?format=csv", http.StatusOK, "service_name,service_id,total_requests,successful_requests,failed_requests,avg_response_ms,p50_response_ms,p95_response_ms,p99_response_ms\norders,orders-1,1,0,1,0.000,0.000,0.000,0.000\nusers,users-1,2,2,0,15.000,0.000,0.000,0.000\n"},
+		{"?format=xml", http.StatusBadRequest, "unsupported format"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/admin/stats"+tt.query, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("%q: expected %d, got %d", tt.query, tt.wantCode, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%q: expected body to contain %q, got %q", tt.query, tt.wantBody, w.Body.String())
+		}
+	}
+}
+
+func TestErrorRatesAndResetHandlers(t *testing.T) {
+	proxy := newStatsTestProxy()
+	proxy.ImportStats([]router.InstanceStats{
+		{ServiceName: "orders", ServiceID: "orders-1"},
+		{ServiceName: "users", ServiceID: "users-1"},
+	})
+	proxy.balancer.ReportResult("orders-1", router.RequestResult{ServiceID: "orders-1", Success: false})
+	proxy.balancer.ReportResult("orders-1", router.RequestResult{ServiceID: "orders-1", Success: true})
+	proxy.balancer.ReportResult("users-1", router.RequestResult{ServiceID: "users-1", Success: true})
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /admin/stats/{service}/errors", ErrorRatesHandler(proxy))
+	mux.Handle("DELETE /admin/stats/{service}", StatsResetHandler(proxy))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/stats/orders/errors", nil))
+	if want := `{"orders-1":{"requests":2,"failures":1,"rate":0.5}}`; strings.TrimSpace(w.Body.String()) != want {
+		t.Fatalf("expected %s, got %s", want, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/stats/orders", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if rates := proxy.ErrorRates("orders"); len(rates) != 0 {
+		t.Fatalf("expected orders history to be reset, got %+v", rates)
+	}
+	if rates := proxy.ErrorRates("users"); len(rates) != 1 {
+		t.Fatalf("expected users history to be kept, got %+v", rates)
+	}
+}
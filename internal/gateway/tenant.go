@@ -0,0 +1,120 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/toska-mesh/toska-mesh/pkg/routing"
+)
+
+type tenantKey struct{}
+
+// TenantFromContext returns the tenant resolved by TenantRouter, or "".
+func TenantFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+type metadataFilterKey struct{}
+
+// withMetadataFilter adds a key/value pair that selected backends must carry
+// in their metadata. Filters added by outer handlers are preserved.
+func withMetadataFilter(ctx context.Context, key, value string) context.Context {
+	prev := metadataFilterFromContext(ctx)
+	filter := make(map[string]string, len(prev)+1)
+	for k, v := range prev {
+		filter[k] = v
+	}
+	filter[key] = value
+	return context.WithValue(ctx, metadataFilterKey{}, filter)
+}
+
+func metadataFilterFromContext(ctx context.Context) map[string]string {
+	f, _ := ctx.Value(metadataFilterKey{}).(map[string]string)
+	return f
+}
+
+type excludedMetadataKey struct{}
+
+// withExcludedMetadataKey adds a metadata key that selected backends must not
+// carry.
+func withExcludedMetadataKey(ctx context.Context, key string) context.Context {
+	prev := excludedMetadataKeysFromContext(ctx)
+	return context.WithValue(ctx, excludedMetadataKey{}, append(prev[:len(prev):len(prev)], key))
+}
+
+func excludedMetadataKeysFromContext(ctx context.Context) []string {
+	k, _ := ctx.Value(excludedMetadataKey{}).([]string)
+	return k
+}
+
+type metadataPreferenceKey struct{}
+
+// withMetadataPreference adds a key/value pair that selected backends should
+// carry when any available backend does.
+func withMetadataPreference(ctx context.Context, key, value string) context.Context {
+	prev := metadataPreferenceFromContext(ctx)
+	pref := make(map[string]string, len(prev)+1)
+	for k, v := range prev {
+		pref[k] = v
+	}
+	pref[key] = value
+	return context.WithValue(ctx, metadataPreferenceKey{}, pref)
+}
+
+func metadataPreferenceFromContext(ctx context.Context) map[string]string {
+	p, _ := ctx.Value(metadataPreferenceKey{}).(map[string]string)
+	return p
+}
+
+// TenantRouter serves tenant-scoped routes such as /t/{tenant}/api/{service}/...
+// The tenant segment must match the tenant claim of the validated JWT. The
+// tenant prefix is stripped and the request is handed to next (the proxy),
+// which restricts backend selection to instances tagged with that tenant.
+func TenantRouter(cfg TenancyConfig, next http.Handler) http.Handler {
+	prefix := routing.NormalizePrefix(cfg.PathPrefix)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, rest, ok := splitTenantPath(prefix, r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		claims := ClaimsFromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "tenant routes require authentication", http.StatusUnauthorized)
+			return
+		}
+		if !strings.EqualFold(claims.String(cfg.TenantClaim), tenant) {
+			http.Error(w, "token is not valid for tenant: "+tenant, http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), tenantKey{}, tenant)
+		ctx = withMetadataFilter(ctx, cfg.MetadataKey, tenant)
+		r2 := r.WithContext(ctx)
+		u := *r.URL
+		u.Path = rest
+		u.RawPath = ""
+		r2.URL = &u
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// splitTenantPath splits a tenant-scoped path such as /t/acme/api/orders
+// into the tenant and the path after it, /api/orders.
+func splitTenantPath(prefix, path string) (tenant, rest string, ok bool) {
+	tenant, rest, ok = routing.ParseServiceFromPath(prefix, path)
+	return tenant, rest, ok && tenant != ""
+}
+
+// SharedRoutes serves the routes that are not tenant-scoped while tenancy is
+// enabled. Instances tagged with a tenant serve only that tenant, so they are
+// kept out of backend selection for these requests.
+func SharedRoutes(cfg TenancyConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withExcludedMetadataKey(r.Context(), cfg.MetadataKey)))
+	})
+}
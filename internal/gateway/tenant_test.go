@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTenantRouter_RoutesToTenantBackends(t *testing.T) {
+	acme := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders" {
+			t.Errorf("expected backend path /orders, got %s", r.URL.Path)
+		}
+		fmt.Fprint(w, "acme")
+	}))
+	defer acme.Close()
+	globex := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "globex")
+	}))
+	defer globex.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"shop": {
+				ServiceName: "shop",
+				Backends: []Backend{
+					{ServiceID: "shop-acme", Address: acme.URL, Metadata: map[string]string{"tenant": "acme"}},
+					{ServiceID: "shop-globex", Address: globex.URL, Metadata: map[string]string{"tenant": "globex"}},
+				},
+			},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, ResponseConfig{}, logger)
+
+	jwtCfg := JWTConfig{SecretKey: "test-secret-key-at-least-32-characters"}
+	tenancy := TenancyConfig{Enabled: true, PathPrefix: "/t/", TenantClaim: "tenant", MetadataKey: "tenant"}
+	handler := JWTAuth(jwtCfg, nil)(TenantRouter(tenancy, proxy))
+
+	tests := []struct {
+		name        string
+		path        string
+		tokenTenant string
+		wantCode    int
+		wantBody    string
+	}{
+		{"matching tenant", "/t/acme/api/shop/orders", "acme", http.StatusOK, "acme"},
+		{"tenant mismatch", "/t/globex/api/shop/orders", "acme", http.StatusForbidden, ""},
+		{"no tenant claim", "/t/acme/api/shop/orders", "", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extra := map[string]any{}
+			if tt.tokenTenant != "" {
+				extra["tenant"] = tt.tokenTenant
+			}
+			token, _ := SignJWT(jwtCfg, "user", time.Hour, extra)
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTenantRouter_RequiresAuthentication(t *testing.T) {
+	tenancy := TenancyConfig{PathPrefix: "/t/", TenantClaim: "tenant", MetadataKey: "tenant"}
+	handler := TenantRouter(tenancy, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not be called")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/t/acme/api/shop/orders", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestSharedRoutes_ExcludeTenantBackends(t *testing.T) {
+	acme := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "acme")
+	}))
+	defer acme.Close()
+	shared := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "shared")
+	}))
+	defer shared.Close()
+
+	rt := &RouteTable{
+		config: RoutingConfig{RoutePrefix: "/api/"},
+		routes: map[string]*ServiceRoute{
+			"shop": {
+				ServiceName: "shop",
+				Backends: []Backend{
+					{ServiceID: "shop-acme", Address: acme.URL, Metadata: map[string]string{"tenant": "acme"}},
+					{ServiceID: "shop-shared", Address: shared.URL},
+				},
+			},
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	proxy := NewProxy(rt, ResilienceConfig{RetryCount: 0, BreakerFailureThreshold: 10, BreakerBreakDuration: time.Minute}, ResponseConfig{}, logger)
+	tenancy := TenancyConfig{Enabled: true, PathPrefix: "/t/", TenantClaim: "tenant", MetadataKey: "tenant"}
+	handler := SharedRoutes(tenancy, proxy)
+
+	for i := 0; i < 6; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/shop/orders", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "shared" {
+			t.Fatalf("request %d: expected the shared backend, got %d %q", i, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package geoip maps client IP addresses to countries and regions using a
+// MaxMind-style network database in CSV form.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Location is the geographic information known for a network.
+type Location struct {
+	Country string // ISO 3166-1 alpha-2, upper case
+	Region  string // operator-defined region, e.g. "eu-west"; may be empty
+}
+
+// Locator resolves an address to a location.
+type Locator interface {
+	Lookup(addr netip.Addr) (Location, bool)
+}
+
+// DB is an in-memory network table. The most specific network containing an
+// address wins.
+type DB struct {
+	networks map[netip.Prefix]Location
+	bits     []int // distinct prefix lengths, longest first
+}
+
+// Open loads a CSV database from path.
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database: %w", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads a CSV database. The header row must contain "network" (CIDR)
+// and "country_iso_code" columns, as in MaxMind GeoLite2 exports joined with
+// their locations file; an optional "region" column tags networks with a
+// region. Other columns are ignored.
+func Parse(r io.Reader) (*DB, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read geoip header: %w", err)
+	}
+	networkCol, countryCol, regionCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case "network":
+			networkCol = i
+		case "country_iso_code":
+			countryCol = i
+		case "region":
+			regionCol = i
+		}
+	}
+	if networkCol < 0 || countryCol < 0 {
+		return nil, errors.New("geoip header must contain network and country_iso_code columns")
+	}
+
+	db := &DB{networks: make(map[netip.Prefix]Location)}
+	seenBits := make(map[int]struct{})
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read geoip line %d: %w", line, err)
+		}
+		if networkCol >= len(rec) || countryCol >= len(rec) {
+			return nil, fmt.Errorf("geoip line %d: missing columns", line)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(rec[networkCol]))
+		if err != nil {
+			return nil, fmt.Errorf("geoip line %d: %w", line, err)
+		}
+		prefix = prefix.Masked()
+		loc := Location{Country: strings.ToUpper(strings.TrimSpace(rec[countryCol]))}
+		if regionCol >= 0 && regionCol < len(rec) {
+			loc.Region = strings.TrimSpace(rec[regionCol])
+		}
+		db.networks[prefix] = loc
+		seenBits[prefix.Bits()] = struct{}{}
+	}
+	for b := range seenBits {
+		db.bits = append(db.bits, b)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(db.bits)))
+	return db, nil
+}
+
+// Lookup returns the location of the most specific network containing addr.
+func (db *DB) Lookup(addr netip.Addr) (Location, bool) {
+	addr = addr.Unmap()
+	for _, bits := range db.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if loc, ok := db.networks[prefix]; ok {
+			return loc, true
+		}
+	}
+	return Location{}, false
+}
+
+// Len returns the number of networks in the database.
+func (db *DB) Len() int {
+	return len(db.networks)
+}
@@ -0,0 +1,59 @@
+package geoip
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+const testDB = `network,geoname_id,country_iso_code,region
+10.0.0.0/8,1,us,us-east
+10.1.0.0/16,2,DE,eu-central
+192.0.2.0/24,3,FR,
+2001:db8::/32,4,JP,ap-northeast
+`
+
+func TestDB_Lookup(t *testing.T) {
+	db, err := Parse(strings.NewReader(testDB))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("expected 4 networks, got %d", db.Len())
+	}
+
+	tests := []struct {
+		addr   string
+		want   Location
+		wantOK bool
+	}{
+		{"10.2.3.4", Location{"US", "us-east"}, true},
+		{"10.1.3.4", Location{"DE", "eu-central"}, true}, // most specific wins
+		{"::ffff:10.1.3.4", Location{"DE", "eu-central"}, true},
+		{"192.0.2.7", Location{"FR", ""}, true},
+		{"2001:db8::1", Location{"JP", "ap-northeast"}, true},
+		{"203.0.113.1", Location{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := db.Lookup(netip.MustParseAddr(tt.addr))
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("%s: expected %+v/%v, got %+v/%v", tt.addr, tt.want, tt.wantOK, got, ok)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing columns", "network,region\n10.0.0.0/8,x\n"},
+		{"bad network", "network,country_iso_code\nnot-a-cidr,US\n"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// AlertRules decide which probe results reach the alert webhooks. The zero
+// value alerts as soon as an instance turns Unhealthy and again when it
+// recovers.
+type AlertRules struct {
+	// MinUnhealthy is how long an instance must stay Unhealthy before it
+	// alerts, so transient blips don't page anyone.
+	MinUnhealthy time.Duration
+	// ServiceDownPercent holds alerts until at least this percentage of the
+	// service's instances are Unhealthy. Zero alerts on any instance.
+	ServiceDownPercent float64
+	// QuietHours suppresses notifications during a daily window. An
+	// instance still Unhealthy when the window ends alerts then, and one
+	// that recovered during it is resolved then.
+	QuietHours QuietHours
+	// RepeatInterval re-sends the alert while an instance stays Unhealthy.
+	// Zero sends it once.
+	RepeatInterval time.Duration
+}
+
+// QuietHours is a daily window of local time, from Start to End after
+// midnight. A window where End is before Start spans midnight. The zero
+// value is empty.
+type QuietHours struct {
+	Start, End time.Duration
+}
+
+// ParseQuietHours parses a window such as "22:00-07:00".
+func ParseQuietHours(s string) (QuietHours, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("invalid quiet hours %q: want HH:MM-HH:MM", s)
+	}
+	var q QuietHours
+	for _, p := range []struct {
+		s   string
+		dst *time.Duration
+	}{{from, &q.Start}, {to, &q.End}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(p.s))
+		if err != nil {
+			return QuietHours{}, fmt.Errorf("invalid quiet hours %q: %w", s, err)
+		}
+		*p.dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return q, nil
+}
+
+// Contains reports whether t falls inside the window.
+func (q QuietHours) Contains(t time.Time) bool {
+	if q.Start == q.End {
+		return false
+	}
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if q.Start < q.End {
+		return tod >= q.Start && tod < q.End
+	}
+	return tod >= q.Start || tod < q.End
+}
+
+// alertState tracks an instance from the probe it turned Unhealthy.
+type alertState struct {
+	inst       types.Instance
+	registered int
+	previous   HealthStatus // status before it turned Unhealthy
+	since      time.Time
+	firing     bool
+	lastSent   time.Time
+	// resolve is set when a firing alert has ended but its resolution is
+	// held back by quiet hours.
+	resolve *resolution
+}
+
+// resolution is the result that ended a firing alert.
+type resolution struct {
+	status  HealthStatus
+	message string
+	latency time.Duration
+}
+
+// evaluateAlert applies the alert rules to a probe result and notifies the
+// alert webhooks when an instance starts or stops firing. It runs on every
+// probe, not only on transitions, so duration and repeat rules fire while
+// the status holds.
+func (w *Worker) evaluateAlert(inst types.Instance, registered int, previous, status HealthStatus, message string, latency time.Duration, now time.Time) {
+	if w.alerts == nil {
+		return
+	}
+	rules := w.config.AlertRules
+
+	w.mu.Lock()
+	st := w.alerting[inst.ServiceID]
+	if status != StatusUnhealthy {
+		if st != nil && st.firing && rules.QuietHours.Contains(now) {
+			// Resolved once the window ends, by flushResolutions.
+			st.resolve = &resolution{status: status, message: message, latency: latency}
+			w.mu.Unlock()
+			return
+		}
+		delete(w.alerting, inst.ServiceID)
+		w.mu.Unlock()
+		if st != nil && st.firing {
+			w.sendAlert(inst, registered, StatusUnhealthy, status, message, latency, "resolved")
+		}
+		return
+	}
+	if st != nil {
+		// Down again before a held resolution went out: the alert never
+		// stopped firing downstream.
+		st.resolve = nil
+	}
+	if st == nil {
+		// Like health events, the first status seen after a restart is not
+		// an alertable transition, so restarting the monitor does not page
+		// again for instances that were already down.
+		if previous == StatusUnknown || previous == StatusUnhealthy {
+			w.mu.Unlock()
+			return
+		}
+		st = &alertState{inst: inst, registered: registered, previous: previous, since: now}
+		w.alerting[inst.ServiceID] = st
+	}
+	due := now.Sub(st.since) >= rules.MinUnhealthy &&
+		!rules.QuietHours.Contains(now) &&
+		(!st.firing || rules.RepeatInterval > 0 && now.Sub(st.lastSent) >= rules.RepeatInterval)
+	w.mu.Unlock()
+	if !due || !w.serviceDown(inst.ServiceName, rules.ServiceDownPercent) {
+		return
+	}
+
+	w.mu.Lock()
+	kind, from := "firing", st.previous
+	if st.firing {
+		kind, from = "repeat", StatusUnhealthy
+	}
+	st.firing, st.lastSent = true, now
+	w.mu.Unlock()
+	w.sendAlert(inst, registered, from, status, message, latency, kind)
+}
+
+// dropAlert ends alerting for an instance no longer monitored here, because
+// it was deregistered or its service moved to another replica. A firing
+// alert is resolved, subject to quiet hours like a recovery. w.mu must be
+// held.
+func (w *Worker) dropAlert(serviceID string) {
+	st := w.alerting[serviceID]
+	if st == nil || !st.firing {
+		delete(w.alerting, serviceID)
+		return
+	}
+	if st.resolve == nil {
+		st.resolve = &resolution{status: StatusUnknown, message: "No longer monitored"}
+	}
+}
+
+// flushResolutions sends the resolutions held back by quiet hours once the
+// window has ended.
+func (w *Worker) flushResolutions(now time.Time) {
+	if w.alerts == nil || w.config.AlertRules.QuietHours.Contains(now) {
+		return
+	}
+	var due []*alertState
+	w.mu.Lock()
+	for id, st := range w.alerting {
+		if st.resolve != nil {
+			due = append(due, st)
+			delete(w.alerting, id)
+		}
+	}
+	w.mu.Unlock()
+	for _, st := range due {
+		w.sendAlert(st.inst, st.registered, StatusUnhealthy, st.resolve.status, st.resolve.message, st.resolve.latency, "resolved")
+	}
+}
+
+// serviceDown reports whether at least percent of a service's probed
+// instances are Unhealthy.
+func (w *Worker) serviceDown(serviceName string, percent float64) bool {
+	if percent <= 0 {
+		return true
+	}
+	instances := w.cache.GetByService(serviceName)
+	if len(instances) == 0 {
+		return false
+	}
+	down := 0
+	for _, inst := range instances {
+		if inst.Status == StatusUnhealthy {
+			down++
+		}
+	}
+	return float64(down)*100 >= percent*float64(len(instances))
+}
+
+func (w *Worker) sendAlert(inst types.Instance, registered int, previous, current HealthStatus, message string, latency time.Duration, kind string) {
+	w.telemetry.Count("healthmonitor_alerts_total", 1, telemetry.Labels{
+		"service": inst.ServiceName,
+		"kind":    kind,
+	})
+	w.alerts.Notify(w.healthChangedEvent(inst, registered, previous, current, message, latency))
+}
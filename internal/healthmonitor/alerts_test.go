@@ -0,0 +1,271 @@
+package healthmonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// alertSink records the kind of each alert sent.
+type alertSink struct {
+	telemetry.Sink
+	kinds []string
+}
+
+func (s *alertSink) Count(name string, delta float64, labels telemetry.Labels) {
+	if name == "healthmonitor_alerts_total" {
+		s.kinds = append(s.kinds, labels["kind"])
+	}
+}
+
+func newAlertingWorker(t *testing.T, rules AlertRules) (*Worker, *alertSink) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	alerts, err := messaging.NewWebhookNotifier([]messaging.Webhook{{URL: "http://alerts.invalid"}}, messaging.WebhookOptions{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.AlertRules = rules
+	sink := &alertSink{}
+	w := NewWorkerWithOptions(nil, nil, NewCache(), cfg, WorkerOptions{Alerts: alerts}, logger)
+	w.telemetry = sink
+	return w, sink
+}
+
+func TestWorker_AlertRules(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		rules AlertRules
+		// statuses are probed a minute apart, after a Healthy baseline.
+		statuses []HealthStatus
+		want     []string
+	}{
+		{"immediate", AlertRules{}, []HealthStatus{StatusUnhealthy, StatusUnhealthy, StatusHealthy}, []string{"firing", "resolved"}},
+		{"blip under min duration", AlertRules{MinUnhealthy: 2 * time.Minute}, []HealthStatus{StatusUnhealthy, StatusUnhealthy, StatusHealthy}, nil},
+		{"held past min duration", AlertRules{MinUnhealthy: 2 * time.Minute}, []HealthStatus{StatusUnhealthy, StatusUnhealthy, StatusUnhealthy, StatusDegraded}, []string{"firing", "resolved"}},
+		{"repeat", AlertRules{RepeatInterval: 2 * time.Minute}, []HealthStatus{StatusUnhealthy, StatusUnhealthy, StatusUnhealthy, StatusUnhealthy, StatusUnhealthy}, []string{"firing", "repeat", "repeat"}},
+		{"quiet hours", AlertRules{QuietHours: QuietHours{Start: 11 * time.Hour, End: 12*time.Hour + 2*time.Minute}}, []HealthStatus{StatusUnhealthy, StatusUnhealthy, StatusUnhealthy}, []string{"firing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, sink := newAlertingWorker(t, tt.rules)
+			inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+			previous := StatusHealthy
+			for i, status := range tt.statuses {
+				w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, status, "http", "", nil)
+				w.evaluateAlert(inst, 1, previous, status, "", 0, start.Add(time.Duration(i)*time.Minute))
+				previous = status
+			}
+			if fmt.Sprint(sink.kinds) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected alerts %v, got %v", tt.want, sink.kinds)
+			}
+		})
+	}
+}
+
+func TestWorker_AlertResolutionHeldByQuietHours(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	w, sink := newAlertingWorker(t, AlertRules{QuietHours: QuietHours{Start: 12*time.Hour + time.Minute, End: 12*time.Hour + 3*time.Minute}})
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+
+	w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusUnhealthy, "http", "", nil)
+	w.evaluateAlert(inst, 1, StatusHealthy, StatusUnhealthy, "", 0, start)
+	w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusHealthy, "http", "", nil)
+	w.evaluateAlert(inst, 1, StatusUnhealthy, StatusHealthy, "", 0, start.Add(time.Minute))
+	w.flushResolutions(start.Add(2 * time.Minute))
+	if fmt.Sprint(sink.kinds) != "[firing]" {
+		t.Fatalf("expected the resolution held during quiet hours, got %v", sink.kinds)
+	}
+	w.flushResolutions(start.Add(3 * time.Minute))
+	if fmt.Sprint(sink.kinds) != "[firing resolved]" {
+		t.Fatalf("expected the resolution sent once quiet hours end, got %v", sink.kinds)
+	}
+	w.flushResolutions(start.Add(4 * time.Minute))
+	if len(sink.kinds) != 2 {
+		t.Fatalf("expected the resolution sent once, got %v", sink.kinds)
+	}
+}
+
+func TestWorker_ProbeLoopsFlushHeldResolutions(t *testing.T) {
+	for _, spread := range []bool{false, true} {
+		t.Run(fmt.Sprintf("spread=%v", spread), func(t *testing.T) {
+			// Quiet hours start an hour from now, so the loops run after them.
+			now := time.Now()
+			tod := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute
+			quiet := QuietHours{Start: (tod + time.Hour) % (24 * time.Hour), End: (tod + time.Hour + 2*time.Minute) % (24 * time.Hour)}
+			w, sink := newAlertingWorker(t, AlertRules{QuietHours: quiet})
+			w.registry = &flakyRegistry{}
+			w.config.SpreadProbes = spread
+			inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+
+			w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusUnhealthy, "http", "", nil)
+			w.evaluateAlert(inst, 1, StatusHealthy, StatusUnhealthy, "", 0, now)
+			w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusHealthy, "http", "", nil)
+			w.evaluateAlert(inst, 1, StatusUnhealthy, StatusHealthy, "", 0, now.Add(time.Hour+time.Minute))
+			if fmt.Sprint(sink.kinds) != "[firing]" {
+				t.Fatalf("expected the resolution held during quiet hours, got %v", sink.kinds)
+			}
+
+			// A cancelled context runs one listing cycle.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if spread {
+				w.runSpread(ctx)
+			} else {
+				w.probeAll(ctx)
+			}
+			if fmt.Sprint(sink.kinds) != "[firing resolved]" {
+				t.Fatalf("expected the held resolution sent, got %v", sink.kinds)
+			}
+			if len(w.alerting) != 0 {
+				t.Fatalf("expected no alert state left, got %v", w.alerting)
+			}
+		})
+	}
+}
+
+func TestWorker_AlertResolvedOnDeregistration(t *testing.T) {
+	w, sink := newAlertingWorker(t, AlertRules{})
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+	w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusUnhealthy, "http", "", nil)
+	w.evaluateAlert(inst, 1, StatusHealthy, StatusUnhealthy, "", 0, time.Now())
+
+	w.evict(map[string]struct{}{})
+	w.flushResolutions(time.Now())
+	if fmt.Sprint(sink.kinds) != "[firing resolved]" {
+		t.Fatalf("expected the alert resolved when the instance left, got %v", sink.kinds)
+	}
+	if len(w.alerting) != 0 {
+		t.Fatalf("expected no alert state left, got %v", w.alerting)
+	}
+}
+
+// flakyRegistry lists one service whose instance listing fails the next
+// failures times.
+type flakyRegistry struct {
+	registry.Registry
+	instances []types.Instance
+	failures  int
+}
+
+func (r *flakyRegistry) GetServices() ([]string, error) { return []string{"api"}, nil }
+
+func (r *flakyRegistry) GetInstances(serviceName string) ([]types.Instance, error) {
+	if r.failures > 0 {
+		r.failures--
+		return nil, errors.New("registry unavailable")
+	}
+	return r.instances, nil
+}
+
+func TestWorker_ListingFailureKeepsInstanceState(t *testing.T) {
+	w, sink := newAlertingWorker(t, AlertRules{})
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+	reg := &flakyRegistry{instances: []types.Instance{inst}}
+	w.registry = reg
+	list := func() {
+		t.Helper()
+		_, liveIDs, err := w.listTargets(make(chan struct{}, 1))
+		if err != nil {
+			t.Fatalf("listTargets: %v", err)
+		}
+		w.evict(liveIDs)
+		w.flushResolutions(time.Now())
+	}
+
+	list()
+	w.cache.Update(inst.ServiceID, inst.ServiceName, "", 0, StatusUnhealthy, "http", "", nil)
+	w.evaluateAlert(inst, 1, StatusHealthy, StatusUnhealthy, "", 0, time.Now())
+	w.flaps[inst.ServiceID] = &flapState{}
+
+	reg.failures = 1
+	list()
+	if fmt.Sprint(sink.kinds) != "[firing]" {
+		t.Fatalf("expected no resolution after a failed listing, got %v", sink.kinds)
+	}
+	if w.cache.Get(inst.ServiceID) == nil || w.flaps[inst.ServiceID] == nil {
+		t.Fatal("expected the instance's state kept after a failed listing")
+	}
+
+	reg.instances = nil
+	list()
+	if fmt.Sprint(sink.kinds) != "[firing resolved]" {
+		t.Fatalf("expected the alert resolved once a listing succeeds without it, got %v", sink.kinds)
+	}
+}
+
+func TestWorker_AlertServiceDownPercent(t *testing.T) {
+	w, sink := newAlertingWorker(t, AlertRules{ServiceDownPercent: 50})
+	now := time.Now()
+	for _, id := range []string{"api-1", "api-2", "api-3"} {
+		w.cache.Update(id, "api", "", 0, StatusHealthy, "http", "", nil)
+	}
+
+	down := func(id string) {
+		w.cache.Update(id, "api", "", 0, StatusUnhealthy, "http", "", nil)
+		w.evaluateAlert(consul.Instance{ServiceID: id, ServiceName: "api"}, 3, StatusHealthy, StatusUnhealthy, "", 0, now)
+	}
+	down("api-1")
+	if len(sink.kinds) != 0 {
+		t.Fatalf("expected no alert with 1 of 3 instances down, got %v", sink.kinds)
+	}
+	down("api-2")
+	if fmt.Sprint(sink.kinds) != "[firing]" {
+		t.Fatalf("expected an alert with 2 of 3 instances down, got %v", sink.kinds)
+	}
+}
+
+func TestWorker_AlertIgnoresFirstStatus(t *testing.T) {
+	w, sink := newAlertingWorker(t, AlertRules{})
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+	w.evaluateAlert(inst, 1, StatusUnknown, StatusUnhealthy, "", 0, time.Now())
+	w.evaluateAlert(inst, 1, StatusUnhealthy, StatusUnhealthy, "", 0, time.Now())
+	if len(sink.kinds) != 0 {
+		t.Fatalf("expected no alert for an instance Unhealthy since startup, got %v", sink.kinds)
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 1, 1, h, m, 0, 0, time.Local) }
+	tests := []struct {
+		in      string
+		inside  []time.Time
+		outside []time.Time
+		wantErr bool
+	}{
+		{in: "22:00-07:00", inside: []time.Time{at(23, 0), at(3, 0), at(22, 0)}, outside: []time.Time{at(7, 0), at(12, 0)}},
+		{in: "09:30-17:00", inside: []time.Time{at(9, 30), at(16, 59)}, outside: []time.Time{at(9, 29), at(17, 0)}},
+		{in: "22:00", wantErr: true},
+		{in: "25:00-07:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			q, err := ParseQuietHours(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuietHours(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			for _, ts := range tt.inside {
+				if !q.Contains(ts) {
+					t.Errorf("expected %s inside %q", ts.Format("15:04"), tt.in)
+				}
+			}
+			for _, ts := range tt.outside {
+				if q.Contains(ts) {
+					t.Errorf("expected %s outside %q", ts.Format("15:04"), tt.in)
+				}
+			}
+		})
+	}
+}
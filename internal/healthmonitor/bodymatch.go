@@ -0,0 +1,127 @@
+package healthmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxProbeBody bounds the response body read for content matching.
+const maxProbeBody = 64 << 10
+
+// jsonAssertion checks one value in a JSON response body, written as
+// `$.path.to[0].field == "value"`, with != also accepted. The right-hand side
+// is a JSON literal. A bare path only asserts that the value exists.
+type jsonAssertion struct {
+	expr  string
+	path  []any // string keys and int indexes
+	op    string
+	value any
+}
+
+// parseJSONAssertion parses expr.
+func parseJSONAssertion(expr string) (*jsonAssertion, error) {
+	a := &jsonAssertion{expr: expr}
+	pathExpr := expr
+	for _, op := range []string{"==", "!="} {
+		if lhs, rhs, ok := strings.Cut(expr, op); ok {
+			if err := json.Unmarshal([]byte(strings.TrimSpace(rhs)), &a.value); err != nil {
+				return nil, fmt.Errorf("invalid value %q: %w", strings.TrimSpace(rhs), err)
+			}
+			a.op, pathExpr = op, lhs
+			break
+		}
+	}
+
+	path, err := parseJSONPath(strings.TrimSpace(pathExpr))
+	if err != nil {
+		return nil, err
+	}
+	a.path = path
+	return a, nil
+}
+
+// parseJSONPath parses the $.key[0].key subset of JSONPath.
+func parseJSONPath(s string) ([]any, error) {
+	rest, ok := strings.CutPrefix(s, "$")
+	if !ok {
+		return nil, fmt.Errorf("path %q must start with $", s)
+	}
+	var path []any
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q has an empty key", s)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q has an unclosed [", s)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("path %q has an invalid index %q", s, rest[1:end])
+			}
+			path = append(path, i)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", s, rest[0])
+		}
+	}
+	return path, nil
+}
+
+// check evaluates the assertion against body, returning why it failed.
+func (a *jsonAssertion) check(body []byte) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+
+	v := doc
+	for _, step := range a.path {
+		switch key := step.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%s: not found", a.expr)
+			}
+			if v, ok = obj[key]; !ok {
+				return fmt.Errorf("%s: not found", a.expr)
+			}
+		case int:
+			arr, ok := v.([]any)
+			if !ok || key >= len(arr) {
+				return fmt.Errorf("%s: not found", a.expr)
+			}
+			v = arr[key]
+		}
+	}
+
+	switch a.op {
+	case "==":
+		if !reflect.DeepEqual(v, a.value) {
+			return fmt.Errorf("%s: got %s", a.expr, compactJSON(v))
+		}
+	case "!=":
+		if reflect.DeepEqual(v, a.value) {
+			return fmt.Errorf("%s: got %s", a.expr, compactJSON(v))
+		}
+	}
+	return nil
+}
+
+func compactJSON(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,136 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestJSONAssertion(t *testing.T) {
+	body := []byte(`{"status":"Healthy","checks":[{"name":"db","ok":true}],"uptime":42}`)
+	tests := []struct {
+		expr     string
+		wantPass bool
+	}{
+		{`$.status == "Healthy"`, true},
+		{`$.status == "Degraded"`, false},
+		{`$.status != "Degraded"`, true},
+		{`$.checks[0].ok == true`, true},
+		{`$.checks[0].name=="cache"`, false},
+		{`$.uptime == 42`, true},
+		{`$.checks[1]`, false},
+		{`$.checks`, true},
+		{`$.missing == null`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			a, err := parseJSONAssertion(tt.expr)
+			if err != nil {
+				t.Fatalf("parseJSONAssertion: %v", err)
+			}
+			if err := a.check(body); (err == nil) != tt.wantPass {
+				t.Fatalf("check = %v, want pass %v", err, tt.wantPass)
+			}
+		})
+	}
+
+	for _, bad := range []string{`status == "Healthy"`, `$.status == Healthy`, `$..status`, `$.checks[x]`, `$.checks[0`} {
+		if _, err := parseJSONAssertion(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestWorker_HTTPProbe_MatchesBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"Degraded","version":"1.4.2"}`)
+	}))
+	defer ts.Close()
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	tests := []struct {
+		name string
+		meta map[string]string
+		want HealthStatus
+	}{
+		{"no checks", nil, StatusHealthy},
+		{"json assertion fails", map[string]string{metaHealthJSONAssert: `$.status == "Healthy"`}, StatusUnhealthy},
+		{"json assertion passes", map[string]string{metaHealthJSONAssert: `$.version == "1.4.2"`}, StatusHealthy},
+		{"regex fails", map[string]string{metaHealthExpectedBody: `"status":"Healthy"`}, StatusUnhealthy},
+		{"regex passes", map[string]string{metaHealthExpectedBody: `"version":"1\.\d+`}, StatusHealthy},
+		{"invalid regex", map[string]string{metaHealthExpectedBody: `(`}, StatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Worker{config: DefaultConfig(), client: ts.Client()}
+			inst := consul.Instance{ServiceID: "svc-1", Address: parts[0], Port: mustPort(parts[1]), Metadata: tt.meta}
+			if status, msg := w.httpProbe(context.Background(), inst, "/health"); status != tt.want {
+				t.Fatalf("expected %v, got %v (%s)", tt.want, status, msg)
+			}
+		})
+	}
+}
+
+func TestWorker_HTTPProbe_ReusesCompiledMatchers(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"Healthy"}`)
+	}))
+	defer ts.Close()
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	w := NewWorker(nil, nil, NewCache(), DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.client = ts.Client()
+	inst := consul.Instance{ServiceID: "svc-1", Address: parts[0], Port: mustPort(parts[1]),
+		Metadata: map[string]string{metaHealthJSONAssert: `$.status == "Healthy"`}}
+
+	w.httpProbe(context.Background(), inst, "/health")
+	first := w.matchers["svc-1"]
+	w.httpProbe(context.Background(), inst, "/health")
+	if first == nil || w.matchers["svc-1"] != first {
+		t.Fatal("expected the compiled assertion reused between probes")
+	}
+
+	inst.Metadata = map[string]string{metaHealthJSONAssert: `$.status != "Down"`}
+	if status, msg := w.httpProbe(context.Background(), inst, "/health"); status != StatusHealthy {
+		t.Fatalf("expected the changed assertion to pass, got %v (%s)", status, msg)
+	}
+	if w.matchers["svc-1"] == first {
+		t.Fatal("expected the assertion recompiled after the metadata changed")
+	}
+}
+
+func TestWorker_HTTPProbe_ReportsTruncatedBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status":"Healthy","padding":"%s"}`, strings.Repeat("x", maxProbeBody))
+	}))
+	defer ts.Close()
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	tests := []struct {
+		name    string
+		meta    map[string]string
+		want    HealthStatus
+		message string
+	}{
+		{"json assertion", map[string]string{metaHealthJSONAssert: `$.status == "Healthy"`}, StatusUnhealthy, "too large"},
+		{"regex in the prefix", map[string]string{metaHealthExpectedBody: `"status":"Healthy"`}, StatusHealthy, ""},
+		{"regex past the prefix", map[string]string{metaHealthExpectedBody: `"\}$`}, StatusUnhealthy, "first 64 KiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Worker{config: DefaultConfig(), client: ts.Client()}
+			inst := consul.Instance{ServiceID: "svc-1", Address: parts[0], Port: mustPort(parts[1]), Metadata: tt.meta}
+			status, msg := w.httpProbe(context.Background(), inst, "/health")
+			if status != tt.want || !strings.Contains(msg, tt.message) {
+				t.Fatalf("expected %v mentioning %q, got %v (%s)", tt.want, tt.message, status, msg)
+			}
+		})
+	}
+}
@@ -33,16 +33,17 @@ func (s BreakerState) String() string {
 // The breaker requires recoveryThreshold consecutive successes in half-open
 // before fully closing.
 type CircuitBreaker struct {
-	mu                 sync.Mutex
-	state              BreakerState
-	failureCount       int
-	failureThreshold   int
-	recoveryThreshold  int
-	recoveryCount      int  // consecutive successes in half-open
-	breakDuration      time.Duration
-	openedAt           time.Time
-	halfOpenUsed       bool           // true once a request has been admitted in half-open
-	now                func() time.Time // for testing
+	mu                sync.Mutex
+	state             BreakerState
+	failureCount      int
+	failureThreshold  int
+	recoveryThreshold int
+	recoveryCount     int // consecutive successes in half-open
+	breakDuration     time.Duration
+	openedAt          time.Time
+	transitionedAt    time.Time        // zero until the first state change
+	halfOpenUsed      bool             // true once a request has been admitted in half-open
+	now               func() time.Time // for testing
 }
 
 // NewCircuitBreaker creates a breaker that opens after failureThreshold consecutive
@@ -86,7 +87,7 @@ func (cb *CircuitBreaker) Allow() bool {
 		return true
 	case BreakerOpen:
 		if cb.now().Sub(cb.openedAt) >= cb.breakDuration {
-			cb.state = BreakerHalfOpen
+			cb.setState(BreakerHalfOpen)
 			cb.halfOpenUsed = false
 			// Allow the first probe request through.
 			cb.halfOpenUsed = true
@@ -115,7 +116,7 @@ func (cb *CircuitBreaker) RecordSuccess() {
 	if cb.state == BreakerHalfOpen {
 		cb.recoveryCount++
 		if cb.recoveryCount >= cb.recoveryThreshold {
-			cb.state = BreakerClosed
+			cb.setState(BreakerClosed)
 			cb.recoveryCount = 0
 		}
 		// Allow the next probe request through.
@@ -123,7 +124,7 @@ func (cb *CircuitBreaker) RecordSuccess() {
 		return
 	}
 
-	cb.state = BreakerClosed
+	cb.setState(BreakerClosed)
 	cb.halfOpenUsed = false
 }
 
@@ -136,7 +137,7 @@ func (cb *CircuitBreaker) RecordFailure() {
 	cb.recoveryCount = 0
 
 	if cb.state == BreakerHalfOpen || cb.failureCount >= cb.failureThreshold {
-		cb.state = BreakerOpen
+		cb.setState(BreakerOpen)
 		cb.openedAt = cb.now()
 		cb.halfOpenUsed = false
 	}
@@ -149,7 +150,48 @@ func (cb *CircuitBreaker) State() BreakerState {
 
 	// Check for time-based transition.
 	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.breakDuration {
-		cb.state = BreakerHalfOpen
+		cb.setState(BreakerHalfOpen)
 	}
 	return cb.state
 }
+
+// BreakerSnapshot is a point-in-time view of a circuit breaker for
+// introspection.
+type BreakerSnapshot struct {
+	State            BreakerState
+	FailureCount     int
+	FailureThreshold int
+	LastTransition   time.Time     // zero if the breaker has never changed state
+	HalfOpenIn       time.Duration // time until an open breaker admits a probe
+}
+
+// Snapshot returns the breaker's current state and counters.
+func (cb *CircuitBreaker) Snapshot() BreakerSnapshot {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.now()
+	if cb.state == BreakerOpen && now.Sub(cb.openedAt) >= cb.breakDuration {
+		cb.setState(BreakerHalfOpen)
+	}
+
+	snap := BreakerSnapshot{
+		State:            cb.state,
+		FailureCount:     cb.failureCount,
+		FailureThreshold: cb.failureThreshold,
+		LastTransition:   cb.transitionedAt,
+	}
+	if cb.state == BreakerOpen {
+		snap.HalfOpenIn = cb.openedAt.Add(cb.breakDuration).Sub(now)
+	}
+	return snap
+}
+
+// setState changes state, recording the transition time. Callers hold cb.mu.
+func (cb *CircuitBreaker) setState(s BreakerState) {
+	if cb.state == s {
+		return
+	}
+	cb.state = s
+	cb.transitionedAt = cb.now()
+}
@@ -136,3 +136,33 @@ func TestBreaker_SuccessResetsFailureCount(t *testing.T) {
 		t.Fatalf("expected closed, got %v", cb.State())
 	}
 }
+
+func TestBreaker_Snapshot(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Minute)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb.now = func() time.Time { return now }
+
+	if snap := cb.Snapshot(); snap.State != BreakerClosed || !snap.LastTransition.IsZero() {
+		t.Fatalf("expected fresh closed breaker, got %+v", snap)
+	}
+
+	cb.RecordFailure()
+	cb.RecordFailure()
+	now = now.Add(20 * time.Second)
+
+	snap := cb.Snapshot()
+	if snap.State != BreakerOpen || snap.FailureCount != 2 || snap.FailureThreshold != 2 {
+		t.Fatalf("expected open breaker with 2/2 failures, got %+v", snap)
+	}
+	if want := now.Add(-20 * time.Second); !snap.LastTransition.Equal(want) {
+		t.Errorf("expected last transition %v, got %v", want, snap.LastTransition)
+	}
+	if snap.HalfOpenIn != 40*time.Second {
+		t.Errorf("expected half-open in 40s, got %v", snap.HalfOpenIn)
+	}
+
+	now = now.Add(time.Minute)
+	if snap := cb.Snapshot(); snap.State != BreakerHalfOpen || snap.HalfOpenIn != 0 || !snap.LastTransition.Equal(now) {
+		t.Fatalf("expected half-open breaker transitioned now, got %+v", snap)
+	}
+}
@@ -29,29 +29,68 @@ type MonitoredInstance struct {
 	ProbeType   string            `json:"probeType"`
 	Message     string            `json:"message,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	// LatencyMs is the round-trip time of the last probe.
+	LatencyMs float64 `json:"latencyMs"`
+
+	// Set when the probe scheme and endpoint were found by detection rather
+	// than declared in metadata.
+	DetectedScheme   string `json:"detectedScheme,omitempty"`
+	DetectedEndpoint string `json:"detectedEndpoint,omitempty"`
+
+	// Flapping is set while the instance oscillates between Healthy and
+	// Unhealthy; its health events are suppressed meanwhile.
+	Flapping bool `json:"flapping"`
 }
 
-// Cache is a thread-safe store of the latest health probe results.
+// Cache is a thread-safe store of the latest health probe results, and of a
+// short history of results per instance.
 type Cache struct {
-	mu        sync.RWMutex
-	instances map[string]*MonitoredInstance
+	mu          sync.RWMutex
+	instances   map[string]*MonitoredInstance
+	history     map[string]*probeRing
+	historySize int
+	watchers    map[*watcher]struct{}
+}
+
+// StatusChange is a change of a cached instance's status, or its first
+// status, delivered to watchers.
+type StatusChange struct {
+	Instance MonitoredInstance
+	Previous HealthStatus
+}
+
+// watcher is a Watch subscription.
+type watcher struct {
+	serviceName string
+	ch          chan StatusChange
 }
 
-// NewCache creates an empty health report cache.
+// NewCache creates an empty health report cache keeping DefaultHistorySize
+// results per instance.
 func NewCache() *Cache {
+	return NewCacheWithHistory(DefaultHistorySize)
+}
+
+// NewCacheWithHistory creates an empty cache keeping historySize results per
+// instance. Zero disables the history.
+func NewCacheWithHistory(historySize int) *Cache {
 	return &Cache{
-		instances: make(map[string]*MonitoredInstance),
+		instances:   make(map[string]*MonitoredInstance),
+		history:     make(map[string]*probeRing),
+		historySize: historySize,
+		watchers:    make(map[*watcher]struct{}),
 	}
 }
 
-// Update records a probe result for an instance.
+// Update records a probe result for an instance. The instance keeps its
+// detected probe target and flapping state.
 func (c *Cache) Update(serviceID, serviceName, address string, port int,
 	status HealthStatus, probeType, message string, metadata map[string]string) {
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.instances[serviceID] = &MonitoredInstance{
+	inst := MonitoredInstance{
 		ServiceID:   serviceID,
 		ServiceName: serviceName,
 		Address:     address,
@@ -62,6 +101,80 @@ func (c *Cache) Update(serviceID, serviceName, address string, port int,
 		Message:     message,
 		Metadata:    metadata,
 	}
+	if prev := c.instances[serviceID]; prev != nil {
+		inst.Flapping = prev.Flapping
+	}
+	c.put(inst)
+}
+
+// UpdateProbe records a probe result together with its latency and the
+// instance's flapping state, so watchers see them in a single change. An
+// empty detected scheme keeps the one already cached, and a zero LastProbe
+// is set to now.
+func (c *Cache) UpdateProbe(inst MonitoredInstance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if inst.LastProbe.IsZero() {
+		inst.LastProbe = time.Now().UTC()
+	}
+	c.put(inst)
+}
+
+// put stores an instance and notifies watchers if its status changed. The
+// caller holds c.mu.
+func (c *Cache) put(inst MonitoredInstance) {
+	prev := c.instances[inst.ServiceID]
+	if prev != nil && inst.DetectedScheme == "" {
+		inst.DetectedScheme = prev.DetectedScheme
+		inst.DetectedEndpoint = prev.DetectedEndpoint
+	}
+	c.instances[inst.ServiceID] = &inst
+	if prev == nil || prev.Status != inst.Status {
+		previous := StatusUnknown
+		if prev != nil {
+			previous = prev.Status
+		}
+		c.notify(StatusChange{Instance: inst, Previous: previous})
+	}
+}
+
+// Watch subscribes to status changes of a service's instances, or of every
+// instance if serviceName is empty. It returns a channel of up to buffer
+// pending changes and a function that ends the subscription. A watcher that
+// falls further behind has its channel closed, and must watch again; changes
+// to other services do not count against it.
+func (c *Cache) Watch(serviceName string, buffer int) (<-chan StatusChange, func()) {
+	w := &watcher{serviceName: serviceName, ch: make(chan StatusChange, buffer)}
+
+	c.mu.Lock()
+	c.watchers[w] = struct{}{}
+	c.mu.Unlock()
+
+	return w.ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.watchers[w]; ok {
+			delete(c.watchers, w)
+			close(w.ch)
+		}
+	}
+}
+
+// notify delivers a change to every watcher of its service, dropping those
+// that are full. The caller holds c.mu.
+func (c *Cache) notify(change StatusChange) {
+	for w := range c.watchers {
+		if w.serviceName != "" && w.serviceName != change.Instance.ServiceName {
+			continue
+		}
+		select {
+		case w.ch <- change:
+		default:
+			delete(c.watchers, w)
+			close(w.ch)
+		}
+	}
 }
 
 // GetAll returns a snapshot of all monitored instances.
@@ -90,6 +203,23 @@ func (c *Cache) GetByService(serviceName string) []MonitoredInstance {
 	return out
 }
 
+// ServiceCounts returns the number of healthy and cached instances of a service.
+func (c *Cache) ServiceCounts(serviceName string) (healthy, total int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, inst := range c.instances {
+		if inst.ServiceName != serviceName {
+			continue
+		}
+		total++
+		if inst.Status == StatusHealthy {
+			healthy++
+		}
+	}
+	return healthy, total
+}
+
 // Get returns the monitored instance for a specific service ID, or nil.
 func (c *Cache) Get(serviceID string) *MonitoredInstance {
 	c.mu.RLock()
@@ -109,6 +239,7 @@ func (c *Cache) Remove(serviceID string) {
 	defer c.mu.Unlock()
 
 	delete(c.instances, serviceID)
+	delete(c.history, serviceID)
 }
 
 // RemoveByService deletes all instances matching the given service name.
@@ -119,6 +250,7 @@ func (c *Cache) RemoveByService(serviceName string) {
 	for id, inst := range c.instances {
 		if inst.ServiceName == serviceName {
 			delete(c.instances, id)
+			delete(c.history, id)
 		}
 	}
 }
@@ -131,6 +263,7 @@ func (c *Cache) EvictOlderThan(cutoff time.Time) {
 	for id, inst := range c.instances {
 		if inst.LastProbe.Before(cutoff) {
 			delete(c.instances, id)
+			delete(c.history, id)
 		}
 	}
 }
@@ -88,3 +88,15 @@ func TestCache_GetReturnsNilForUnknown(t *testing.T) {
 		t.Fatalf("expected nil, got %+v", inst)
 	}
 }
+
+func TestCache_ServiceCounts(t *testing.T) {
+	c := NewCache()
+	c.Update("a-1", "a", "10.0.0.1", 80, StatusHealthy, "http", "", nil)
+	c.Update("a-2", "a", "10.0.0.2", 80, StatusDegraded, "http", "", nil)
+	c.Update("b-1", "b", "10.0.0.3", 80, StatusHealthy, "http", "", nil)
+
+	healthy, total := c.ServiceCounts("a")
+	if healthy != 1 || total != 2 {
+		t.Fatalf("expected 1/2 for service a, got %d/%d", healthy, total)
+	}
+}
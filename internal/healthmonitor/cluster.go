@@ -0,0 +1,155 @@
+package healthmonitor
+
+import (
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// ClusterConfig lets several HealthMonitor replicas share the probing. Each
+// replica registers itself under Service and probes only the services that
+// rendezvous hashing on service name assigns to it among the replicas whose
+// registrations are passing, so every instance is probed once and a
+// replica's share moves to the others when it stops renewing. Sharding whole
+// services keeps each one's instances together, so alerts and composite
+// health on a replica see every instance of the services it owns.
+type ClusterConfig struct {
+	Enabled bool
+	Service string
+	// ReplicaID identifies this replica, e.g. its hostname. Address and
+	// Port are where its HTTP API listens. Replicas do not probe each
+	// other; their membership lapses with their TTL instead.
+	ReplicaID string
+	Address   string
+	Port      int
+}
+
+// DefaultClusterService is the service replicas register under by default.
+const DefaultClusterService = "toska-mesh-healthmonitor"
+
+// cluster tracks this replica's membership and the live replicas. It is
+// only used from the probe loop.
+type cluster struct {
+	registry   registry.Registry
+	config     ClusterConfig
+	interval   time.Duration
+	logger     *slog.Logger
+	registered bool
+	replicas   []string // sorted, always including this replica
+}
+
+func newCluster(reg registry.Registry, config ClusterConfig, interval time.Duration, logger *slog.Logger) *cluster {
+	if config.Service == "" {
+		config.Service = DefaultClusterService
+	}
+	return &cluster{
+		registry: reg,
+		config:   config,
+		interval: interval,
+		logger:   logger,
+		replicas: []string{config.ReplicaID},
+	}
+}
+
+// refresh renews this replica's registration and lists the live replicas.
+// If the replicas cannot be listed, the previous list is kept.
+func (c *cluster) refresh() {
+	if c.registered {
+		err := c.registry.UpdateHealth(c.config.ReplicaID, StatusHealthy, "probing")
+		if errors.Is(err, types.ErrNotRegistered) {
+			c.registered = false
+		} else if err != nil {
+			c.logger.Warn("failed to renew cluster membership", "replica", c.config.ReplicaID, "error", err)
+		}
+	}
+	if !c.registered {
+		c.register()
+	}
+
+	instances, err := c.registry.GetInstances(c.config.Service)
+	if err != nil {
+		c.logger.Warn("failed to list healthmonitor replicas", "error", err)
+		return
+	}
+	replicas := []string{c.config.ReplicaID}
+	for _, inst := range instances {
+		if inst.Status == StatusHealthy && inst.ServiceID != c.config.ReplicaID {
+			replicas = append(replicas, inst.ServiceID)
+		}
+	}
+	slices.Sort(replicas)
+	if !slices.Equal(replicas, c.replicas) {
+		c.logger.Info("healthmonitor replicas changed", "replicas", replicas)
+	}
+	c.replicas = replicas
+}
+
+// register adds this replica to the cluster. Its TTL spans a few probe
+// intervals, since it is renewed once per listing.
+func (c *cluster) register() {
+	err := c.registry.Register(types.Registration{
+		ServiceName: c.config.Service,
+		ServiceID:   c.config.ReplicaID,
+		Address:     c.config.Address,
+		Port:        c.config.Port,
+		HealthCheck: &types.HealthCheckConfig{IntervalSeconds: int(3 * c.interval / time.Second)},
+	})
+	if err != nil {
+		c.logger.Warn("failed to join healthmonitor cluster", "replica", c.config.ReplicaID, "error", err)
+		return
+	}
+	c.registered = true
+}
+
+// leave deregisters this replica so the others take over its share without
+// waiting for its TTL to lapse.
+func (c *cluster) leave() {
+	if !c.registered {
+		return
+	}
+	if err := c.registry.Deregister(c.config.ReplicaID); err != nil {
+		c.logger.Warn("failed to leave healthmonitor cluster", "replica", c.config.ReplicaID, "error", err)
+	}
+	c.registered = false
+}
+
+// owns reports whether this replica probes the service.
+func (c *cluster) owns(serviceName string) bool {
+	return replicaFor(c.replicas, serviceName) == c.config.ReplicaID
+}
+
+// replicaFor picks the replica with the highest hash of replica and
+// service, so adding or removing a replica only moves the services it gains
+// or loses.
+func replicaFor(replicas []string, serviceName string) string {
+	var (
+		best  string
+		score uint64
+	)
+	for _, r := range replicas {
+		h := fnv.New64a()
+		h.Write([]byte(r))
+		h.Write([]byte{0})
+		h.Write([]byte(serviceName))
+		if s := mix64(h.Sum64()); best == "" || s > score {
+			best, score = r, s
+		}
+	}
+	return best
+}
+
+// mix64 spreads FNV hashes of similar strings across the whole range
+// (the splitmix64 finalizer).
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
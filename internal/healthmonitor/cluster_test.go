@@ -0,0 +1,155 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// clusterRegistry serves fixed instances plus registered replicas, whose
+// health tests set directly.
+type clusterRegistry struct {
+	registry.Registry
+	mu        sync.Mutex
+	instances map[string][]types.Instance
+}
+
+func (r *clusterRegistry) GetServices() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for name := range r.instances {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (r *clusterRegistry) GetInstances(serviceName string) ([]types.Instance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.Instance(nil), r.instances[serviceName]...), nil
+}
+
+func (r *clusterRegistry) Register(reg types.Registration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances[reg.ServiceName] = append(r.instances[reg.ServiceName], types.Instance{
+		ServiceName: reg.ServiceName, ServiceID: reg.ServiceID, Status: StatusHealthy,
+	})
+	return nil
+}
+
+func (r *clusterRegistry) UpdateHealth(serviceID string, status types.HealthStatus, output string) error {
+	return nil
+}
+
+func (r *clusterRegistry) Deregister(serviceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, insts := range r.instances {
+		for i, inst := range insts {
+			if inst.ServiceID == serviceID {
+				r.instances[name] = append(insts[:i], insts[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return types.ErrNotRegistered
+}
+
+func (r *clusterRegistry) setReplicaStatus(replicaID string, status types.HealthStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, inst := range r.instances[DefaultClusterService] {
+		if inst.ServiceID == replicaID {
+			r.instances[DefaultClusterService][i].Status = status
+		}
+	}
+}
+
+func TestCluster_PartitionsTargets(t *testing.T) {
+	reg := &clusterRegistry{instances: make(map[string][]types.Instance)}
+	for i := range 100 {
+		name := fmt.Sprintf("svc-%d", i)
+		for j := range 2 {
+			reg.instances[name] = append(reg.instances[name], types.Instance{ServiceName: name, ServiceID: fmt.Sprintf("%s-%d", name, j)})
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var workers []*Worker
+	for _, id := range []string{"hm-a", "hm-b", "hm-c"} {
+		cfg := DefaultConfig()
+		cfg.Cluster = ClusterConfig{Enabled: true, ReplicaID: id}
+		w := NewWorker(reg, nil, NewCache(), cfg, logger)
+		w.cluster.refresh() // join before anyone lists targets
+		workers = append(workers, w)
+	}
+
+	// owned lists each worker's services, failing if any instance is probed
+	// by more than one replica or a service is split between replicas.
+	owned := func() map[string]string {
+		t.Helper()
+		probed := make(map[string]bool)
+		owner := make(map[string]string)
+		for _, w := range workers {
+			targets, _, err := w.listTargets(make(chan struct{}, 4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, tgt := range targets {
+				if probed[tgt.inst.ServiceID] {
+					t.Fatalf("%s probed by more than one replica", tgt.inst.ServiceID)
+				}
+				probed[tgt.inst.ServiceID] = true
+				if prev, ok := owner[tgt.inst.ServiceName]; ok && prev != w.cluster.config.ReplicaID {
+					t.Fatalf("%s split between %s and %s", tgt.inst.ServiceName, prev, w.cluster.config.ReplicaID)
+				}
+				owner[tgt.inst.ServiceName] = w.cluster.config.ReplicaID
+			}
+		}
+		if len(probed) != 200 {
+			t.Fatalf("expected the 200 instances probed and not the replicas, got %d", len(probed))
+		}
+		return owner
+	}
+
+	before := owned()
+	counts := make(map[string]int)
+	for _, r := range before {
+		counts[r]++
+	}
+	for id, n := range counts {
+		if n < 20 {
+			t.Fatalf("replica %s only probes %d of 100 services: %v", id, n, counts)
+		}
+	}
+
+	// hm-c stops renewing; its share moves and nothing else does.
+	reg.setReplicaStatus("hm-c", StatusUnhealthy)
+	workers = workers[:2]
+	after := owned()
+	for name, r := range before {
+		if r != "hm-c" && after[name] != r {
+			t.Fatalf("%s moved from %s to %s", name, r, after[name])
+		}
+	}
+}
+
+func TestCluster_LeaveDeregisters(t *testing.T) {
+	reg := &clusterRegistry{instances: make(map[string][]types.Instance)}
+	c := newCluster(reg, ClusterConfig{Enabled: true, ReplicaID: "hm-a"}, DefaultConfig().ProbeInterval, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.refresh()
+	if n := len(reg.instances[DefaultClusterService]); n != 1 {
+		t.Fatalf("expected the replica to register, got %d registrations", n)
+	}
+	c.leave()
+	if n := len(reg.instances[DefaultClusterService]); n != 0 {
+		t.Fatalf("expected the replica to deregister, got %d registrations", n)
+	}
+}
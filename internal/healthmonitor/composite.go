@@ -0,0 +1,226 @@
+package healthmonitor
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+// DependenciesMetadataKey lists, comma-separated, the services an instance's
+// service depends on. A service is only Healthy in its composite health if
+// every dependency is.
+const DependenciesMetadataKey = "health_dependencies"
+
+// CompositeHealth is a service's health combined with that of the services
+// it depends on, directly or transitively.
+type CompositeHealth struct {
+	ServiceName string `json:"serviceName"`
+	// Status is the worst of OwnStatus and every dependency's Status.
+	Status HealthStatus `json:"status"`
+	// OwnStatus summarises the service's instances: Healthy if all are,
+	// Unhealthy if none are, Degraded in between, and Unknown if none
+	// have been probed.
+	OwnStatus    HealthStatus       `json:"ownStatus"`
+	Dependencies []DependencyHealth `json:"dependencies,omitempty"`
+	// Reason names the first service, along the dependency chain, that
+	// makes Status worse than Healthy.
+	Reason string `json:"reason,omitempty"`
+}
+
+// DependencyHealth is the composite status of one declared dependency.
+type DependencyHealth struct {
+	ServiceName string       `json:"serviceName"`
+	Status      HealthStatus `json:"status"`
+	// Cycle is set when the dependency leads back to a service already on
+	// the chain; it is then judged on its own instances only.
+	Cycle bool `json:"cycle,omitempty"`
+}
+
+// EvaluateComposite returns a service's composite health from the cache.
+func EvaluateComposite(cache *Cache, serviceName string) CompositeHealth {
+	return newCompositeEval(cache).evaluate(serviceName)
+}
+
+// EvaluateComposites returns the composite health of every monitored service
+// that declares dependencies, sorted by name.
+func EvaluateComposites(cache *Cache) []CompositeHealth {
+	e := newCompositeEval(cache)
+	var out []CompositeHealth
+	for name, instances := range e.byService {
+		if len(dependencies(instances)) > 0 {
+			out = append(out, e.evaluate(name))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ServiceName < out[j].ServiceName })
+	return out
+}
+
+// compositeEval is one evaluation pass over a snapshot of the cache. It
+// remembers each service's result, so a dependency shared along many paths
+// is walked once rather than once per path.
+type compositeEval struct {
+	byService map[string][]MonitoredInstance
+	memo      map[string]CompositeHealth
+	// onChain holds the services between the root and the one being
+	// walked, by depth, to stop at cycles.
+	onChain map[string]int
+}
+
+func newCompositeEval(cache *Cache) *compositeEval {
+	byService := make(map[string][]MonitoredInstance)
+	for _, inst := range cache.GetAll() {
+		byService[inst.ServiceName] = append(byService[inst.ServiceName], inst)
+	}
+	return &compositeEval{
+		byService: byService,
+		memo:      make(map[string]CompositeHealth),
+		onChain:   make(map[string]int),
+	}
+}
+
+func (e *compositeEval) evaluate(serviceName string) CompositeHealth {
+	c, _ := e.walk(serviceName)
+	return c
+}
+
+// walk evaluates a service depth first. It also returns the shallowest depth
+// on the chain that a cycle from the service led back to. A result whose
+// cycles lead back no further than the service itself does not depend on the
+// chain it was reached by, so it is memoized.
+func (e *compositeEval) walk(serviceName string) (CompositeHealth, int) {
+	if c, ok := e.memo[serviceName]; ok {
+		return c, math.MaxInt
+	}
+
+	instances := e.byService[serviceName]
+	c := CompositeHealth{
+		ServiceName: serviceName,
+		OwnStatus:   ownStatus(instances),
+	}
+	c.Status = c.OwnStatus
+	if c.Status != StatusHealthy {
+		c.Reason = serviceName
+	}
+
+	depth := len(e.onChain)
+	e.onChain[serviceName] = depth
+	defer delete(e.onChain, serviceName)
+	lowest := math.MaxInt
+	for _, dep := range dependencies(instances) {
+		d := DependencyHealth{ServiceName: dep}
+		reason := dep
+		if at, ok := e.onChain[dep]; ok {
+			d.Cycle = true
+			d.Status = ownStatus(e.byService[dep])
+			lowest = min(lowest, at)
+		} else {
+			sub, subLowest := e.walk(dep)
+			d.Status = sub.Status
+			if sub.Reason != "" {
+				reason = sub.Reason
+			}
+			lowest = min(lowest, subLowest)
+		}
+		c.Dependencies = append(c.Dependencies, d)
+		if severity(d.Status) > severity(c.Status) {
+			c.Status = d.Status
+			c.Reason = reason
+		}
+	}
+	if lowest >= depth {
+		e.memo[serviceName] = c
+	}
+	return c, lowest
+}
+
+// ownStatus summarises a service's probed instances.
+func ownStatus(instances []MonitoredInstance) HealthStatus {
+	healthy, known := 0, 0
+	for _, inst := range instances {
+		if inst.Status == StatusUnknown {
+			continue
+		}
+		known++
+		if inst.Status == StatusHealthy {
+			healthy++
+		}
+	}
+	switch {
+	case known == 0:
+		return StatusUnknown
+	case healthy == known:
+		return StatusHealthy
+	case healthy == 0:
+		return StatusUnhealthy
+	default:
+		return StatusDegraded
+	}
+}
+
+// dependencies collects the services declared by any instance.
+func dependencies(instances []MonitoredInstance) []string {
+	var deps []string
+	for _, inst := range instances {
+		for _, d := range strings.Split(inst.Metadata[DependenciesMetadataKey], ",") {
+			if d = strings.TrimSpace(d); d != "" && !slices.Contains(deps, d) {
+				deps = append(deps, d)
+			}
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// severity orders statuses from best to worst.
+func severity(s HealthStatus) int {
+	switch s {
+	case StatusHealthy:
+		return 0
+	case StatusDegraded:
+		return 1
+	case StatusUnknown:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// checkComposites re-evaluates composite health, logging services whose
+// composite status changed since the last evaluation.
+func (w *Worker) checkComposites() {
+	composites := EvaluateComposites(w.cache)
+
+	w.mu.Lock()
+	previous := w.composites
+	w.composites = make(map[string]HealthStatus, len(composites))
+	for _, c := range composites {
+		w.composites[c.ServiceName] = c.Status
+	}
+	w.mu.Unlock()
+
+	for _, c := range composites {
+		was, ok := previous[c.ServiceName]
+		if !ok || was == c.Status {
+			continue
+		}
+		w.telemetry.Count("healthmonitor_composite_changes_total", 1, telemetry.Labels{
+			"service": c.ServiceName,
+			"status":  c.Status.String(),
+		})
+		level := slog.LevelWarn
+		if c.Status == StatusHealthy {
+			level = slog.LevelInfo
+		}
+		w.logger.Log(context.Background(), level, "composite health changed",
+			"service", c.ServiceName,
+			"previous", was.String(),
+			"status", c.Status.String(),
+			"reason", c.Reason,
+		)
+	}
+}
@@ -0,0 +1,117 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateComposite(t *testing.T) {
+	deps := func(services string) map[string]string {
+		return map[string]string{DependenciesMetadataKey: services}
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(c *Cache)
+		service    string
+		wantStatus HealthStatus
+		wantReason string
+	}{
+		{
+			name: "all healthy",
+			setup: func(c *Cache) {
+				c.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", deps("db"))
+				c.Update("db-1", "db", "10.0.0.2", 5432, StatusHealthy, "tcp", "", nil)
+			},
+			service:    "api",
+			wantStatus: StatusHealthy,
+		},
+		{
+			name: "unhealthy dependency",
+			setup: func(c *Cache) {
+				c.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", deps("db"))
+				c.Update("db-1", "db", "10.0.0.2", 5432, StatusUnhealthy, "tcp", "", nil)
+			},
+			service:    "api",
+			wantStatus: StatusUnhealthy,
+			wantReason: "db",
+		},
+		{
+			name: "transitive dependency",
+			setup: func(c *Cache) {
+				c.Update("web-1", "web", "10.0.0.1", 80, StatusHealthy, "http", "", deps("api"))
+				c.Update("api-1", "api", "10.0.0.2", 80, StatusHealthy, "http", "", deps("db"))
+				c.Update("db-1", "db", "10.0.0.3", 5432, StatusHealthy, "tcp", "", nil)
+				c.Update("db-2", "db", "10.0.0.4", 5432, StatusUnhealthy, "tcp", "", nil)
+			},
+			service:    "web",
+			wantStatus: StatusDegraded,
+			wantReason: "db",
+		},
+		{
+			name: "unmonitored dependency",
+			setup: func(c *Cache) {
+				c.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", deps("cache"))
+			},
+			service:    "api",
+			wantStatus: StatusUnknown,
+			wantReason: "cache",
+		},
+		{
+			name: "cycle",
+			setup: func(c *Cache) {
+				c.Update("a-1", "a", "10.0.0.1", 80, StatusHealthy, "http", "", deps("b"))
+				c.Update("b-1", "b", "10.0.0.2", 80, StatusHealthy, "http", "", deps("a"))
+			},
+			service:    "a",
+			wantStatus: StatusHealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCache()
+			tt.setup(c)
+
+			got := EvaluateComposite(c, tt.service)
+			if got.Status != tt.wantStatus {
+				t.Fatalf("expected %v, got %v", tt.wantStatus, got.Status)
+			}
+			if got.Reason != tt.wantReason {
+				t.Fatalf("expected reason %q, got %q", tt.wantReason, got.Reason)
+			}
+		})
+	}
+}
+
+func TestEvaluateComposites_OnlyServicesWithDependencies(t *testing.T) {
+	c := NewCache()
+	c.Update("web-1", "web", "10.0.0.1", 80, StatusHealthy, "http", "", map[string]string{DependenciesMetadataKey: "api"})
+	c.Update("api-1", "api", "10.0.0.2", 80, StatusHealthy, "http", "", nil)
+
+	got := EvaluateComposites(c)
+	if len(got) != 1 || got[0].ServiceName != "web" {
+		t.Fatalf("expected only web, got %+v", got)
+	}
+	if len(got[0].Dependencies) != 1 || got[0].Dependencies[0].ServiceName != "api" {
+		t.Fatalf("expected dependency api, got %+v", got[0].Dependencies)
+	}
+}
+
+func TestEvaluateComposite_SharedDependenciesWalkedOnce(t *testing.T) {
+	// Each layer depends on both services of the next, so without
+	// memoization the walk doubles with every layer.
+	c := NewCache()
+	const layers = 40
+	for i := 0; i < layers; i++ {
+		next := fmt.Sprintf("l%d-a,l%d-b", i+1, i+1)
+		c.Update(fmt.Sprintf("l%d-a-1", i), fmt.Sprintf("l%d-a", i), "10.0.0.1", 80, StatusHealthy, "http", "", map[string]string{DependenciesMetadataKey: next})
+		c.Update(fmt.Sprintf("l%d-b-1", i), fmt.Sprintf("l%d-b", i), "10.0.0.2", 80, StatusHealthy, "http", "", map[string]string{DependenciesMetadataKey: next})
+	}
+	c.Update("last-1", fmt.Sprintf("l%d-a", layers), "10.0.0.3", 80, StatusUnhealthy, "http", "", nil)
+
+	got := EvaluateComposite(c, "l0-a")
+	if got.Status != StatusUnhealthy || got.Reason != fmt.Sprintf("l%d-a", layers) {
+		t.Fatalf("expected the unhealthy last layer to decide, got %v (%s)", got.Status, got.Reason)
+	}
+}
@@ -1,25 +1,120 @@
 package healthmonitor
 
-import "time"
+import (
+	"crypto/x509"
+	"time"
+)
 
 // Config holds HealthMonitor runtime configuration.
 type Config struct {
-	ProbeInterval    time.Duration
-	HTTPTimeout      time.Duration
-	TCPTimeout       time.Duration
-	FailureThreshold int
+	ProbeInterval     time.Duration
+	HTTPTimeout       time.Duration
+	TCPTimeout        time.Duration
+	FailureThreshold  int
 	RecoveryThreshold int
-	HTTPHeaders      map[string]string
+	HTTPHeaders       map[string]string
+
+	// MaxConcurrentProbes bounds the registry lookups and probes in flight
+	// at once. ProbeCycleTimeout bounds how long a cycle dispatches probes
+	// when probes are not spread; zero means ProbeInterval.
+	MaxConcurrentProbes int
+	ProbeCycleTimeout   time.Duration
+
+	// WatchEnabled keeps the probe targets current with registry blocking
+	// queries of up to WatchWaitTime, instead of listing every service each
+	// interval. Registries that cannot stream changes are still listed.
+	WatchEnabled  bool
+	WatchWaitTime time.Duration
+
+	// SpreadProbes gives each instance its own schedule across the probe
+	// interval instead of probing every instance at once. A new instance's
+	// first probe then waits for its offset, up to one interval. ProbeJitter
+	// is the fraction of the interval each instance's schedule varies by.
+	SpreadProbes bool
+	ProbeJitter  float64
+
+	// ReportHealth renews each probed instance's registry TTL check with
+	// the probe result. Probes must then run more often than the TTL, so
+	// healthy instances do not back off beyond ProbeInterval.
+	ReportHealth bool
+
+	// MinProbeInterval and MaxProbeInterval adapt spread schedules to
+	// health: Unhealthy, Degraded and flapping instances are probed every
+	// MinProbeInterval, and instances that stay Healthy back off towards
+	// MaxProbeInterval. Zero keeps ProbeInterval.
+	MinProbeInterval time.Duration
+	MaxProbeInterval time.Duration
+
+	// TLS probes (metadata tls_port) verify certificates against
+	// TLSRootCAs, or the system roots when nil, and report Degraded once a
+	// certificate expires within TLSExpiryThreshold.
+	TLSTimeout         time.Duration
+	TLSExpiryThreshold time.Duration
+	TLSRootCAs         *x509.CertPool
+
+	// ExecEnabled runs the local command instances name in metadata
+	// exec_probe. ExecCommands maps each name to a fixed argv, in which
+	// {id}, {address} and {port} are replaced by the instance's.
+	ExecEnabled  bool
+	ExecCommands map[string][]string
+	ExecTimeout  time.Duration
+
+	// UDPTimeout bounds how long UDP probes (metadata udp_port) wait for a
+	// response.
+	UDPTimeout time.Duration
+
+	// LatencyThreshold marks healthy instances Degraded once
+	// LatencyConsecutive probes in a row exceed it. Zero disables.
+	LatencyThreshold   time.Duration
+	LatencyConsecutive int
+
+	// FlapThreshold marks an instance flapping once it changes between
+	// Healthy and Unhealthy this many times within FlapWindow, suppressing
+	// its health events until it is stable for a whole window. FlapHoldOut
+	// also holds it out of routing meanwhile. Zero disables.
+	FlapThreshold int
+	FlapWindow    time.Duration
+	FlapHoldOut   bool
+
+	// AlertRules filter the notifications sent to alert webhooks.
+	AlertRules AlertRules
+
+	// Cluster partitions probe targets between HealthMonitor replicas.
+	Cluster ClusterConfig
+
+	// DetectEnabled probes instances that register without health metadata,
+	// trying https then http on the registered port with each of
+	// DetectHealthPaths. Failed detections are retried after
+	// DetectRetryInterval. DetectWriteBack stores the result in the
+	// instance's Consul metadata (scheme, health_check_endpoint).
+	DetectEnabled       bool
+	DetectHealthPaths   []string
+	DetectRetryInterval time.Duration
+	DetectWriteBack     bool
 }
 
 // DefaultConfig returns sensible defaults matching the C# HealthMonitorOptions.
 func DefaultConfig() Config {
 	return Config{
-		ProbeInterval:    30 * time.Second,
-		HTTPTimeout:      5 * time.Second,
-		TCPTimeout:       3 * time.Second,
-		FailureThreshold: 3,
-		RecoveryThreshold: 2,
-		HTTPHeaders:      nil,
+		ProbeInterval:       30 * time.Second,
+		HTTPTimeout:         5 * time.Second,
+		TCPTimeout:          3 * time.Second,
+		FailureThreshold:    3,
+		RecoveryThreshold:   2,
+		HTTPHeaders:         nil,
+		MaxConcurrentProbes: 64,
+		WatchEnabled:        true,
+		WatchWaitTime:       5 * time.Minute,
+		ProbeJitter:         0.1,
+		TLSTimeout:          5 * time.Second,
+		TLSExpiryThreshold:  14 * 24 * time.Hour,
+		ExecTimeout:         10 * time.Second,
+		UDPTimeout:          3 * time.Second,
+		LatencyConsecutive:  3,
+		FlapWindow:          10 * time.Minute,
+		DetectEnabled:       false,
+		DetectHealthPaths:   []string{"/health", "/healthz", "/ready", "/actuator/health"},
+		DetectRetryInterval: 10 * time.Minute,
+		DetectWriteBack:     false,
 	}
 }
@@ -0,0 +1,100 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// detection records how an instance without health metadata can be probed.
+type detection struct {
+	scheme   string
+	endpoint string
+}
+
+// detectedProbe returns the probe settings previously detected for an
+// instance, running detection if it is enabled and not recently attempted.
+func (w *Worker) detectedProbe(ctx context.Context, inst types.Instance) (detection, bool) {
+	w.mu.Lock()
+	d, ok := w.detected[inst.ServiceID]
+	last, attempted := w.detectAttempts[inst.ServiceID]
+	w.mu.Unlock()
+
+	if ok {
+		return d, true
+	}
+	if !w.config.DetectEnabled || (attempted && time.Since(last) < w.config.DetectRetryInterval) {
+		return detection{}, false
+	}
+
+	d, ok = w.detect(ctx, inst)
+
+	w.mu.Lock()
+	w.detectAttempts[inst.ServiceID] = time.Now()
+	if ok {
+		w.detected[inst.ServiceID] = d
+	}
+	w.mu.Unlock()
+
+	if !ok {
+		w.logger.Debug("no health endpoint detected", "service_id", inst.ServiceID)
+		return detection{}, false
+	}
+
+	w.logger.Info("detected health endpoint",
+		"service_id", inst.ServiceID,
+		"scheme", d.scheme,
+		"endpoint", d.endpoint,
+	)
+	if w.config.DetectWriteBack && w.registry != nil {
+		meta := map[string]string{"scheme": d.scheme, "health_check_endpoint": d.endpoint}
+		if err := w.registry.UpdateMetadata(inst.ServiceID, meta); err != nil {
+			w.logger.Warn("failed to write detected health metadata to consul", "service_id", inst.ServiceID, "error", err)
+		}
+	}
+	return d, true
+}
+
+// detect tries https then http on the registered port with each configured
+// health path and returns the first combination that answers 2xx. A transport
+// error means the scheme is not spoken on the port, so its other paths are
+// skipped.
+func (w *Worker) detect(ctx context.Context, inst types.Instance) (detection, bool) {
+	for _, scheme := range []string{"https", "http"} {
+		for _, path := range w.config.DetectHealthPaths {
+			url := fmt.Sprintf("%s://%s:%d%s", scheme, inst.Address, inst.Port, path)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return detection{}, false
+			}
+			for k, v := range w.config.HTTPHeaders {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := w.client.Do(req)
+			if err != nil {
+				break
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return detection{scheme: scheme, endpoint: path}, true
+			}
+		}
+	}
+	return detection{}, false
+}
+
+// withScheme returns a copy of inst whose metadata declares scheme.
+func withScheme(inst types.Instance, scheme string) types.Instance {
+	meta := make(map[string]string, len(inst.Metadata)+1)
+	for k, v := range inst.Metadata {
+		meta[k] = v
+	}
+	meta["scheme"] = scheme
+	inst.Metadata = meta
+	return inst
+}
@@ -0,0 +1,90 @@
+package healthmonitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// maxExecOutput bounds the command output kept in the probe message.
+const maxExecOutput = 256
+
+// execWaitDelay bounds the wait for output after a timed-out command is
+// killed.
+const execWaitDelay = 100 * time.Millisecond
+
+// execProbe runs the command named in metadata exec_probe on the monitor's
+// host and maps its exit code the way Consul script checks do: 0 is Healthy,
+// 1 is Degraded, anything else, a timeout, or a failure to start is
+// Unhealthy.
+//
+// Metadata comes from whoever registers the instance, so it only selects
+// one of the operator's ExecCommands by name; the argv is fixed by the
+// operator. In it, {id}, {address} and {port} are replaced by the
+// instance's, which are also passed as TOSKA_SERVICE_ID,
+// TOSKA_SERVICE_ADDRESS and TOSKA_SERVICE_PORT. Commands run without a
+// shell.
+func (w *Worker) execProbe(ctx context.Context, inst types.Instance, name string) (HealthStatus, string) {
+	if !w.config.ExecEnabled {
+		return StatusUnknown, "exec probes are disabled"
+	}
+	argv, ok := w.config.ExecCommands[strings.TrimSpace(name)]
+	if !ok || len(argv) == 0 {
+		return StatusUnknown, fmt.Sprintf("command %q is not configured", name)
+	}
+	replacer := strings.NewReplacer("{id}", inst.ServiceID, "{address}", inst.Address, "{port}", strconv.Itoa(inst.Port))
+	args := make([]string, len(argv))
+	for i, a := range argv {
+		args[i] = replacer.Replace(a)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.ExecTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"TOSKA_SERVICE_ID="+inst.ServiceID,
+		"TOSKA_SERVICE_ADDRESS="+inst.Address,
+		"TOSKA_SERVICE_PORT="+strconv.Itoa(inst.Port),
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Children the command started can hold the output open past the kill.
+	cmd.WaitDelay = execWaitDelay
+
+	err := cmd.Run()
+	output := execOutput(out.Bytes())
+	if ctx.Err() == context.DeadlineExceeded {
+		return StatusUnhealthy, fmt.Sprintf("command timed out after %s", w.config.ExecTimeout)
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return StatusHealthy, fmt.Sprintf("exit 0: %s", output)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return StatusDegraded, fmt.Sprintf("exit 1: %s", output)
+	case errors.As(err, &exitErr):
+		return StatusUnhealthy, fmt.Sprintf("exit %d: %s", exitErr.ExitCode(), output)
+	default:
+		return StatusUnhealthy, fmt.Sprintf("command failed: %v", err)
+	}
+}
+
+// execOutput trims command output for a probe message.
+func execOutput(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > maxExecOutput {
+		s = s[:maxExecOutput] + "..."
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package healthmonitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestWorker_ExecProbe(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "check.sh")
+	body := "#!/bin/sh\necho \"$TOSKA_SERVICE_ID at $TOSKA_SERVICE_ADDRESS:$TOSKA_SERVICE_PORT via $2\"\n[ \"$1\" = sleep ] && sleep 5\nexit $1\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := map[string][]string{
+		"ok":       {script, "0", "{address}:{port}"},
+		"degraded": {script, "1"},
+		"failing":  {script, "2"},
+		"slow":     {script, "sleep"},
+		"missing":  {"/nonexistent/check"},
+	}
+	tests := []struct {
+		name     string
+		command  string
+		enabled  bool
+		want     HealthStatus
+		wantMsgs string
+	}{
+		{"exit 0", "ok", true, StatusHealthy, "exit 0: svc-1 at 10.0.0.1:8080 via 10.0.0.1:8080"},
+		{"exit 1", "degraded", true, StatusDegraded, "exit 1"},
+		{"exit 2", "failing", true, StatusUnhealthy, "exit 2"},
+		{"timeout", "slow", true, StatusUnhealthy, "timed out"},
+		{"missing program", "missing", true, StatusUnhealthy, "command failed"},
+		{"argv from metadata", script + " 0", true, StatusUnknown, "not configured"},
+		{"shell from metadata", "sh -c 'id'", true, StatusUnknown, "not configured"},
+		{"disabled", "ok", false, StatusUnknown, "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.ExecEnabled = tt.enabled
+			cfg.ExecCommands = commands
+			cfg.ExecTimeout = 500 * time.Millisecond
+			w := &Worker{config: cfg}
+
+			inst := consul.Instance{
+				ServiceID:   "svc-1",
+				ServiceName: "api",
+				Address:     "10.0.0.1",
+				Port:        8080,
+				Metadata:    map[string]string{"exec_probe": tt.command},
+			}
+			status, probeType, msg := w.runProbes(context.Background(), inst)
+			if probeType != "exec" {
+				t.Fatalf("expected probe type exec, got %q", probeType)
+			}
+			if status != tt.want {
+				t.Fatalf("expected %v, got %v (%s)", tt.want, status, msg)
+			}
+			if !strings.Contains(msg, tt.wantMsgs) {
+				t.Fatalf("expected message to contain %q, got %q", tt.wantMsgs, msg)
+			}
+		})
+	}
+}
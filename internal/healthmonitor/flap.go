@@ -0,0 +1,143 @@
+package healthmonitor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// flapState tracks an instance's recent changes between up (Healthy or
+// Degraded) and down (Unhealthy).
+type flapState struct {
+	changes  []time.Time
+	flapping bool
+	reported HealthStatus // last status published in an event
+}
+
+// flapEvent decides whether a probe result publishes a health changed event,
+// and the previous status to report in it. Without flap detection that is
+// every transition out of a known status. An instance that changes between
+// up and down FlapThreshold times within FlapWindow is flapping: its events
+// are suppressed until it has not changed for a whole window, when one event
+// reports its settled status.
+func (w *Worker) flapEvent(inst types.Instance, previous, status HealthStatus, now time.Time) (HealthStatus, bool) {
+	transition := previous != status && previous != StatusUnknown
+	if w.config.FlapThreshold <= 0 {
+		return previous, transition
+	}
+
+	w.mu.Lock()
+	st := w.flaps[inst.ServiceID]
+	if st == nil {
+		st = &flapState{}
+		w.flaps[inst.ServiceID] = st
+		if inst.Metadata[types.MetadataHealthHold] == holdFlapping {
+			// Held by a previous run of the monitor; start afresh.
+			defer func() {
+				if !w.isFlapping(inst.ServiceID) {
+					w.releaseHold(inst)
+				}
+			}()
+		}
+	}
+	if transition && isDown(previous) != isDown(status) {
+		st.changes = append(st.changes, now)
+	}
+	for len(st.changes) > 0 && now.Sub(st.changes[0]) >= w.config.FlapWindow {
+		st.changes = st.changes[1:]
+	}
+
+	switch {
+	case !st.flapping && len(st.changes) >= w.config.FlapThreshold:
+		st.flapping = true
+		// The transition that tipped it over is still published, so
+		// consumers see its latest status.
+		if transition {
+			st.reported = status
+		}
+		w.mu.Unlock()
+		w.setFlapping(inst, true)
+		return previous, transition
+	case st.flapping && len(st.changes) == 0:
+		st.flapping = false
+		reported := st.reported
+		st.reported = status
+		w.mu.Unlock()
+		w.setFlapping(inst, false)
+		return reported, reported != status && reported != StatusUnknown
+	case st.flapping:
+		w.mu.Unlock()
+		if transition {
+			w.telemetry.Count("healthmonitor_flap_suppressed_total", 1, telemetry.Labels{"service": inst.ServiceName})
+		}
+		return previous, false
+	}
+	if transition || st.reported == StatusUnknown {
+		st.reported = status
+	}
+	w.mu.Unlock()
+	return previous, transition
+}
+
+// holdFlapping is the MetadataHealthHold value set on flapping instances.
+const holdFlapping = "flapping"
+
+// setFlapping records an instance entering or leaving the flapping state,
+// and holds it out of routing meanwhile if FlapHoldOut is set.
+func (w *Worker) setFlapping(inst types.Instance, flapping bool) {
+	if flapping {
+		w.logger.Warn("instance is flapping, suppressing health events",
+			"service_id", inst.ServiceID,
+			"window", w.config.FlapWindow,
+		)
+		w.telemetry.Count("healthmonitor_flap_detected_total", 1, telemetry.Labels{"service": inst.ServiceName})
+	} else {
+		w.logger.Info("instance stopped flapping", "service_id", inst.ServiceID)
+	}
+
+	if !w.config.FlapHoldOut {
+		return
+	}
+	if !flapping {
+		w.releaseHold(inst)
+		return
+	}
+	w.applyHold(inst, types.MetadataUpdate{Set: map[string]string{types.MetadataHealthHold: holdFlapping}})
+}
+
+// releaseHold lets a held instance back into routing.
+func (w *Worker) releaseHold(inst types.Instance) {
+	w.applyHold(inst, types.MetadataUpdate{Remove: []string{types.MetadataHealthHold}})
+}
+
+// applyHold publishes a routing hold change in the instance's registry
+// metadata. The registry finds the instance through its catalog and updates
+// it with the agent that owns it, keeping its health checks, so holds work
+// for instances on any node. An instance deregistered meanwhile needs no
+// hold.
+func (w *Worker) applyHold(inst types.Instance, update types.MetadataUpdate) {
+	if w.registry == nil {
+		return
+	}
+	_, err := w.registry.ApplyMetadata(inst.ServiceID, update)
+	switch {
+	case errors.Is(err, types.ErrNotRegistered):
+		w.logger.Debug("instance gone before routing hold update", "service_id", inst.ServiceID)
+	case err != nil:
+		w.logger.Warn("failed to update routing hold", "service_id", inst.ServiceID, "error", err)
+	}
+}
+
+// isFlapping reports whether an instance is currently flapping.
+func (w *Worker) isFlapping(serviceID string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := w.flaps[serviceID]
+	return st != nil && st.flapping
+}
+
+func isDown(status HealthStatus) bool {
+	return status == StatusUnhealthy
+}
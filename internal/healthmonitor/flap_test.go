@@ -0,0 +1,89 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// holdRegistry records metadata updates.
+type holdRegistry struct {
+	registry.Registry
+	updates []types.MetadataUpdate
+}
+
+func (r *holdRegistry) ApplyMetadata(serviceID string, update types.MetadataUpdate) (types.MetadataChange, error) {
+	r.updates = append(r.updates, update)
+	return types.MetadataChange{}, nil
+}
+
+func TestWorker_FlapDetection(t *testing.T) {
+	reg := &holdRegistry{}
+	cfg := DefaultConfig()
+	cfg.FlapThreshold = 3
+	cfg.FlapWindow = 10 * time.Minute
+	cfg.FlapHoldOut = true
+	w := NewWorkerWithOptions(reg, nil, NewCache(), cfg, WorkerOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+
+	start := time.Now()
+	previous := StatusUnknown
+	var events []string
+	probe := func(minute int, status HealthStatus) {
+		if from, ok := w.flapEvent(inst, previous, status, start.Add(time.Duration(minute)*time.Minute)); ok {
+			events = append(events, fmt.Sprintf("%d:%s->%s", minute, from, status))
+		}
+		previous = status
+	}
+
+	for i, status := range []HealthStatus{StatusHealthy, StatusUnhealthy, StatusHealthy, StatusUnhealthy, StatusHealthy, StatusUnhealthy, StatusDegraded} {
+		probe(i, status)
+	}
+	if !w.isFlapping("api-1") {
+		t.Fatal("expected the instance to be marked flapping")
+	}
+	if len(reg.updates) != 1 || reg.updates[0].Set[types.MetadataHealthHold] != "flapping" {
+		t.Fatalf("expected the instance held out of routing, got %+v", reg.updates)
+	}
+
+	// Degraded counts as up, so the last change was at minute 6.
+	for minute := 7; minute <= 16; minute++ {
+		probe(minute, StatusDegraded)
+	}
+	want := "[1:Healthy->Unhealthy 2:Unhealthy->Healthy 3:Healthy->Unhealthy 16:Unhealthy->Degraded]"
+	if fmt.Sprint(events) != want {
+		t.Fatalf("expected events %s, got %v", want, events)
+	}
+	if w.isFlapping("api-1") {
+		t.Fatal("expected the instance to be stable again")
+	}
+	if len(reg.updates) != 2 || len(reg.updates[1].Remove) != 1 {
+		t.Fatalf("expected the routing hold released, got %+v", reg.updates)
+	}
+}
+
+func TestWorker_FlapDetectionDisabled(t *testing.T) {
+	w := NewWorkerWithOptions(nil, nil, NewCache(), DefaultConfig(), WorkerOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+	tests := []struct {
+		previous, status HealthStatus
+		want             bool
+	}{
+		{StatusUnknown, StatusUnhealthy, false},
+		{StatusHealthy, StatusHealthy, false},
+		{StatusHealthy, StatusUnhealthy, true},
+		{StatusUnhealthy, StatusHealthy, true},
+	}
+	for _, tt := range tests {
+		from, ok := w.flapEvent(inst, tt.previous, tt.status, time.Now())
+		if ok != tt.want || from != tt.previous {
+			t.Errorf("flapEvent(%s, %s) = %s, %v; want %s, %v", tt.previous, tt.status, from, ok, tt.previous, tt.want)
+		}
+	}
+}
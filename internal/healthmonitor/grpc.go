@@ -0,0 +1,136 @@
+package healthmonitor
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// watchBuffer is how many status changes a WatchStatus stream may fall
+// behind before it is ended.
+const watchBuffer = 256
+
+// GRPCServer serves the health cache over the HealthMonitor gRPC service, so
+// other mesh components can consume health data without scraping JSON.
+type GRPCServer struct {
+	pb.UnimplementedHealthMonitorServer
+	cache  *Cache
+	logger *slog.Logger
+}
+
+// NewGRPCServer creates a HealthMonitor gRPC service backed by cache.
+func NewGRPCServer(cache *Cache, logger *slog.Logger) *GRPCServer {
+	return &GRPCServer{cache: cache, logger: logger}
+}
+
+// GetStatus returns the latest probe result of each instance of a service,
+// or of every instance when no service is named, ordered by service ID.
+func (s *GRPCServer) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.GetStatusResponse, error) {
+	var instances []MonitoredInstance
+	if req.ServiceName != "" {
+		instances = s.cache.GetByService(req.ServiceName)
+	} else {
+		instances = s.cache.GetAll()
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ServiceID < instances[j].ServiceID })
+
+	resp := &pb.GetStatusResponse{Instances: make([]*pb.InstanceHealth, 0, len(instances))}
+	for _, inst := range instances {
+		resp.Instances = append(resp.Instances, toProtoInstance(inst))
+	}
+	return resp, nil
+}
+
+// GetHistory returns an instance's recent probe results, oldest first.
+func (s *GRPCServer) GetHistory(ctx context.Context, req *pb.GetHistoryRequest) (*pb.GetHistoryResponse, error) {
+	if req.ServiceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "serviceId is required")
+	}
+	history := s.cache.History(req.ServiceId)
+	if history == nil {
+		return nil, status.Errorf(codes.NotFound, "no probe history for %s", req.ServiceId)
+	}
+
+	resp := &pb.GetHistoryResponse{Results: make([]*pb.ProbeRecord, 0, len(history))}
+	for _, r := range history {
+		resp.Results = append(resp.Results, &pb.ProbeRecord{
+			Time:      timestamppb.New(r.Time),
+			Status:    toProtoHealth(r.Status),
+			ProbeType: r.ProbeType,
+			LatencyMs: r.LatencyMs,
+			Message:   r.Message,
+		})
+	}
+	return resp, nil
+}
+
+// WatchStatus streams the current state of the matching instances, then
+// their status changes until the client goes away.
+func (s *GRPCServer) WatchStatus(req *pb.WatchStatusRequest, stream pb.HealthMonitor_WatchStatusServer) error {
+	// Subscribe before taking the snapshot so no change falls between them.
+	changes, stop := s.cache.Watch(req.ServiceName, watchBuffer)
+	defer stop()
+
+	initial, err := s.GetStatus(stream.Context(), &pb.GetStatusRequest{ServiceName: req.ServiceName})
+	if err != nil {
+		return err
+	}
+	for _, inst := range initial.Instances {
+		if err := stream.Send(&pb.StatusChange{Instance: inst, Initial: true}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case c, ok := <-changes:
+			if !ok {
+				s.logger.Warn("status watcher fell behind", "service", req.ServiceName)
+				return status.Error(codes.ResourceExhausted, "watcher fell behind; watch again")
+			}
+			if err := stream.Send(&pb.StatusChange{
+				Instance: toProtoInstance(c.Instance),
+				Previous: toProtoHealth(c.Previous),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProtoInstance(inst MonitoredInstance) *pb.InstanceHealth {
+	return &pb.InstanceHealth{
+		ServiceId:   inst.ServiceID,
+		ServiceName: inst.ServiceName,
+		Address:     inst.Address,
+		Port:        int32(inst.Port),
+		Status:      toProtoHealth(inst.Status),
+		LastProbe:   timestamppb.New(inst.LastProbe),
+		ProbeType:   inst.ProbeType,
+		Message:     inst.Message,
+		LatencyMs:   inst.LatencyMs,
+		Flapping:    inst.Flapping,
+		Metadata:    inst.Metadata,
+	}
+}
+
+func toProtoHealth(s HealthStatus) pb.HealthStatus {
+	switch s {
+	case StatusHealthy:
+		return pb.HealthStatus_HEALTH_STATUS_HEALTHY
+	case StatusUnhealthy:
+		return pb.HealthStatus_HEALTH_STATUS_UNHEALTHY
+	case StatusDegraded:
+		return pb.HealthStatus_HEALTH_STATUS_DEGRADED
+	default:
+		return pb.HealthStatus_HEALTH_STATUS_UNKNOWN
+	}
+}
@@ -0,0 +1,143 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// newGRPCClient serves cache over an in-memory connection.
+func newGRPCClient(t *testing.T, cache *Cache) pb.HealthMonitorClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterHealthMonitorServer(srv, NewGRPCServer(cache, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewHealthMonitorClient(conn)
+}
+
+func TestGRPCServer_GetStatusAndHistory(t *testing.T) {
+	cache := NewCache()
+	cache.Update("web-1", "web", "10.0.0.3", 80, StatusHealthy, "http", "", nil)
+	cache.Update("api-2", "api", "10.0.0.2", 80, StatusDegraded, "http", "slow", nil)
+	cache.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", nil)
+	cache.RecordProbe("api-1", ProbeResult{Status: StatusUnhealthy, ProbeType: "http"})
+	cache.RecordProbe("api-1", ProbeResult{Status: StatusHealthy, ProbeType: "http"})
+	client := newGRPCClient(t, cache)
+	ctx := context.Background()
+
+	all, err := client.GetStatus(ctx, &pb.GetStatusRequest{})
+	if err != nil || len(all.Instances) != 3 {
+		t.Fatalf("GetStatus = %v, %v; want 3 instances", all, err)
+	}
+	api, err := client.GetStatus(ctx, &pb.GetStatusRequest{ServiceName: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(api.Instances) != 2 || api.Instances[0].ServiceId != "api-1" || api.Instances[1].Status != pb.HealthStatus_HEALTH_STATUS_DEGRADED {
+		t.Fatalf("unexpected api instances %v", api.Instances)
+	}
+
+	history, err := client.GetHistory(ctx, &pb.GetHistoryRequest{ServiceId: "api-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Results) != 2 || history.Results[0].Status != pb.HealthStatus_HEALTH_STATUS_UNHEALTHY {
+		t.Fatalf("unexpected history %v", history.Results)
+	}
+	if _, err := client.GetHistory(ctx, &pb.GetHistoryRequest{ServiceId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestGRPCServer_WatchStatus(t *testing.T) {
+	cache := NewCache()
+	cache.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", nil)
+	client := newGRPCClient(t, cache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchStatus(ctx, &pb.WatchStatusRequest{ServiceName: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Initial || first.Instance.ServiceId != "api-1" {
+		t.Fatalf("expected initial state of api-1, got %v", first)
+	}
+
+	// Unchanged statuses and other services are not streamed.
+	cache.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", nil)
+	cache.Update("web-1", "web", "10.0.0.2", 80, StatusUnhealthy, "http", "", nil)
+	cache.Update("api-1", "api", "10.0.0.1", 80, StatusUnhealthy, "http", "HTTP 503", nil)
+
+	change, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Initial || change.Instance.ServiceId != "api-1" ||
+		change.Previous != pb.HealthStatus_HEALTH_STATUS_HEALTHY || change.Instance.Status != pb.HealthStatus_HEALTH_STATUS_UNHEALTHY {
+		t.Fatalf("expected api-1 Healthy -> Unhealthy, got %v", change)
+	}
+}
+
+func TestCache_WatchDropsSlowWatchers(t *testing.T) {
+	cache := NewCache()
+	changes, stop := cache.Watch("", 1)
+	defer stop()
+
+	cache.Update("api-1", "api", "", 0, StatusHealthy, "http", "", nil)
+	cache.Update("api-1", "api", "", 0, StatusUnhealthy, "http", "", nil)
+
+	if c := <-changes; c.Instance.Status != StatusHealthy || c.Previous != StatusUnknown {
+		t.Fatalf("expected first status Healthy, got %+v", c)
+	}
+	if _, ok := <-changes; ok {
+		t.Fatal("expected the full watcher to be closed")
+	}
+}
+
+func TestCache_WatchFiltersByService(t *testing.T) {
+	cache := NewCache()
+	changes, stop := cache.Watch("api", 1)
+	defer stop()
+
+	// Churn in other services must not fill, and so drop, the watcher.
+	for i := range 5 {
+		cache.Update(fmt.Sprintf("web-%d", i), "web", "", 0, StatusHealthy, "http", "", nil)
+	}
+	cache.UpdateProbe(MonitoredInstance{ServiceID: "api-1", ServiceName: "api", Status: StatusHealthy, LatencyMs: 12, Flapping: true})
+
+	c, ok := <-changes
+	if !ok {
+		t.Fatal("expected the watcher to survive changes to other services")
+	}
+	if c.Instance.ServiceID != "api-1" || c.Instance.LatencyMs != 12 || !c.Instance.Flapping {
+		t.Fatalf("expected api-1 with its latency and flapping state, got %+v", c.Instance)
+	}
+}
@@ -0,0 +1,73 @@
+package healthmonitor
+
+import "time"
+
+// DefaultHistorySize is how many probe results NewCache keeps per instance.
+const DefaultHistorySize = 50
+
+// ProbeResult is one entry in an instance's probe history.
+type ProbeResult struct {
+	Time      time.Time    `json:"time"`
+	Status    HealthStatus `json:"status"`
+	ProbeType string       `json:"probeType"`
+	LatencyMs float64      `json:"latencyMs"`
+	Message   string       `json:"message,omitempty"`
+}
+
+// probeRing holds the most recent probe results in a fixed-size buffer.
+type probeRing struct {
+	entries []ProbeResult
+	next    int
+	full    bool
+}
+
+func newProbeRing(size int) *probeRing {
+	return &probeRing{entries: make([]ProbeResult, size)}
+}
+
+func (r *probeRing) add(p ProbeResult) {
+	r.entries[r.next] = p
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the results oldest first.
+func (r *probeRing) snapshot() []ProbeResult {
+	if !r.full {
+		return append([]ProbeResult(nil), r.entries[:r.next]...)
+	}
+	out := make([]ProbeResult, 0, len(r.entries))
+	out = append(out, r.entries[r.next:]...)
+	return append(out, r.entries[:r.next]...)
+}
+
+// RecordProbe appends a probe result to an instance's history, dropping the
+// oldest once the history is full. It has no effect when history is disabled.
+func (c *Cache) RecordProbe(serviceID string, result ProbeResult) {
+	if c.historySize <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.history[serviceID]
+	if !ok {
+		r = newProbeRing(c.historySize)
+		c.history[serviceID] = r
+	}
+	r.add(result)
+}
+
+// History returns an instance's recent probe results, oldest first, or nil if
+// it has none.
+func (c *Cache) History(serviceID string) []ProbeResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if r, ok := c.history[serviceID]; ok {
+		return r.snapshot()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCache_HistoryKeepsNewestResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded int
+		want     []string
+	}{
+		{"empty", 0, nil},
+		{"partial", 2, []string{"probe 0", "probe 1"}},
+		{"exactly full", 3, []string{"probe 0", "probe 1", "probe 2"}},
+		{"wrapped", 7, []string{"probe 4", "probe 5", "probe 6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCacheWithHistory(3)
+			for i := range tt.recorded {
+				c.RecordProbe("svc-1", ProbeResult{Status: StatusHealthy, Message: fmt.Sprintf("probe %d", i)})
+			}
+			var got []string
+			for _, p := range c.History("svc-1") {
+				got = append(got, p.Message)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCache_HistoryRemovedWithInstance(t *testing.T) {
+	c := NewCache()
+	c.Update("svc-1", "api", "10.0.0.1", 8080, StatusHealthy, "http", "", nil)
+	c.RecordProbe("svc-1", ProbeResult{Status: StatusHealthy})
+	c.Remove("svc-1")
+	if h := c.History("svc-1"); h != nil {
+		t.Fatalf("expected history removed, got %v", h)
+	}
+
+	disabled := NewCacheWithHistory(0)
+	disabled.RecordProbe("svc-1", ProbeResult{Status: StatusHealthy})
+	if h := disabled.History("svc-1"); h != nil {
+		t.Fatalf("expected no history when disabled, got %v", h)
+	}
+}
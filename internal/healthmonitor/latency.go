@@ -0,0 +1,35 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// gradeLatency marks a healthy instance Degraded once LatencyConsecutive
+// probes in a row took longer than LatencyThreshold, so a slow but
+// answering backend is flagged without being taken out of rotation. Any
+// fast or failed probe resets the run.
+func (w *Worker) gradeLatency(serviceID string, status HealthStatus, message string, latency time.Duration) (HealthStatus, string) {
+	if w.config.LatencyThreshold <= 0 {
+		return status, message
+	}
+
+	w.mu.Lock()
+	if w.slowProbes == nil {
+		w.slowProbes = make(map[string]int)
+	}
+	if status != StatusHealthy || latency <= w.config.LatencyThreshold {
+		delete(w.slowProbes, serviceID)
+		w.mu.Unlock()
+		return status, message
+	}
+	w.slowProbes[serviceID]++
+	slow := w.slowProbes[serviceID]
+	w.mu.Unlock()
+
+	if slow < max(w.config.LatencyConsecutive, 1) {
+		return status, message
+	}
+	return StatusDegraded, fmt.Sprintf("%s; latency %s above %s for %d probes",
+		message, latency.Round(time.Millisecond), w.config.LatencyThreshold, slow)
+}
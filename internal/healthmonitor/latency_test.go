@@ -0,0 +1,81 @@
+package healthmonitor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestWorker_GradeLatency(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LatencyThreshold = 100 * time.Millisecond
+	cfg.LatencyConsecutive = 2
+	w := &Worker{config: cfg}
+
+	steps := []struct {
+		status  HealthStatus
+		latency time.Duration
+		want    HealthStatus
+	}{
+		{StatusHealthy, 150 * time.Millisecond, StatusHealthy},
+		{StatusHealthy, 150 * time.Millisecond, StatusDegraded},
+		{StatusHealthy, 300 * time.Millisecond, StatusDegraded},
+		{StatusHealthy, 50 * time.Millisecond, StatusHealthy}, // fast probe resets
+		{StatusHealthy, 150 * time.Millisecond, StatusHealthy},
+		{StatusUnhealthy, 150 * time.Millisecond, StatusUnhealthy}, // failures are not downgraded and reset
+		{StatusHealthy, 150 * time.Millisecond, StatusHealthy},
+	}
+	for i, s := range steps {
+		got, msg := w.gradeLatency("svc-1", s.status, "HTTP 200", s.latency)
+		if got != s.want {
+			t.Fatalf("step %d: expected %v, got %v (%s)", i, s.want, got, msg)
+		}
+		if got == StatusDegraded && !strings.Contains(msg, "above 100ms") {
+			t.Fatalf("step %d: expected latency in message, got %q", i, msg)
+		}
+	}
+
+	w.config.LatencyThreshold = 0
+	if got, _ := w.gradeLatency("svc-2", StatusHealthy, "", time.Hour); got != StatusHealthy {
+		t.Fatalf("expected latency grading disabled, got %v", got)
+	}
+}
+
+func TestWorker_ProbeInstance_RecordsLatency(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer ts.Close()
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	cfg := DefaultConfig()
+	cfg.LatencyThreshold = 10 * time.Millisecond
+	cfg.LatencyConsecutive = 1
+	cache := NewCache()
+	w := NewWorker(nil, nil, cache, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.client = ts.Client()
+
+	inst := consul.Instance{
+		ServiceID:   "svc-1",
+		ServiceName: "api",
+		Address:     parts[0],
+		Port:        mustPort(parts[1]),
+		Metadata:    map[string]string{"health_check_endpoint": "/health"},
+	}
+	w.probeInstance(context.Background(), inst, 1)
+
+	cached := cache.Get("svc-1")
+	if cached.Status != StatusDegraded {
+		t.Fatalf("expected Degraded for a slow probe, got %v (%s)", cached.Status, cached.Message)
+	}
+	if cached.LatencyMs < 20 {
+		t.Fatalf("expected probe latency recorded, got %vms", cached.LatencyMs)
+	}
+}
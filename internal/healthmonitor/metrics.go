@@ -0,0 +1,60 @@
+package healthmonitor
+
+import (
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+// Metrics reported to the worker's telemetry sink:
+//
+//	healthmonitor_probes_total{service,status}                    probe results
+//	healthmonitor_probe_errors_total{service,probe_type}          probes reporting Unhealthy
+//	healthmonitor_probe_duration_seconds{service,probe_type}      probe round-trip time
+//	healthmonitor_status_changes_total{service,status}            status transitions
+//	healthmonitor_flap_detected_total{service}                    instances that started flapping
+//	healthmonitor_flap_suppressed_total{service}                  transitions not published while flapping
+//	healthmonitor_alerts_total{service,kind}                      alerts sent: firing, repeat, resolved
+//	healthmonitor_probes_deferred_total{service}                  probes left for later at the cycle deadline or with the pool full
+//	healthmonitor_registry_reports_total{service,result}          probe results written to registry TTL checks
+//	healthmonitor_skipped_cycles_total                            cycles, or scheduler ticks when spread, skipped by the watchdog
+//	healthmonitor_instance_status{service,service_id,status}      1 for the instance's current status, 0 for the others
+//	healthmonitor_circuit_breaker_state{service,service_id}       0 closed, 1 open, 2 half-open
+//
+// The per-instance gauges are dropped when an instance is deregistered.
+
+// instanceStatuses are the values of the status label on
+// healthmonitor_instance_status.
+var instanceStatuses = []HealthStatus{StatusHealthy, StatusDegraded, StatusUnhealthy, StatusUnknown}
+
+// reportInstance sets the per-instance gauges after a probe.
+func (w *Worker) reportInstance(serviceID, serviceName string, status HealthStatus, breaker BreakerState) {
+	for _, s := range instanceStatuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		w.telemetry.Gauge("healthmonitor_instance_status", value, telemetry.Labels{
+			"service":    serviceName,
+			"service_id": serviceID,
+			"status":     s.String(),
+		})
+	}
+	w.telemetry.Gauge("healthmonitor_circuit_breaker_state", float64(breaker), telemetry.Labels{
+		"service":    serviceName,
+		"service_id": serviceID,
+	})
+}
+
+// forgetInstance drops the per-instance gauges of a deregistered instance.
+func (w *Worker) forgetInstance(serviceID, serviceName string) {
+	for _, s := range instanceStatuses {
+		telemetry.Delete(w.telemetry, "healthmonitor_instance_status", telemetry.Labels{
+			"service":    serviceName,
+			"service_id": serviceID,
+			"status":     s.String(),
+		})
+	}
+	telemetry.Delete(w.telemetry, "healthmonitor_circuit_breaker_state", telemetry.Labels{
+		"service":    serviceName,
+		"service_id": serviceID,
+	})
+}
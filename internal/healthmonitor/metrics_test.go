@@ -0,0 +1,47 @@
+package healthmonitor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+func TestWorker_ExportsInstanceMetrics(t *testing.T) {
+	prom := telemetry.NewPrometheus()
+	w := NewWorkerWithOptions(nil, nil, NewCache(), DefaultConfig(), WorkerOptions{Telemetry: prom}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inst := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+
+	w.updateStatus(context.Background(), inst, 1, StatusUnhealthy, "tcp", "TCP connection failed", 0)
+	for range DefaultConfig().FailureThreshold {
+		w.getBreaker("api-1").RecordFailure()
+	}
+	w.updateStatus(context.Background(), inst, 1, StatusUnhealthy, "circuit-breaker", "Circuit open", 0)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		prom.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+	body := scrape()
+	for _, want := range []string{
+		`healthmonitor_instance_status{service="api",service_id="api-1",status="Unhealthy"} 1`,
+		`healthmonitor_instance_status{service="api",service_id="api-1",status="Healthy"} 0`,
+		`healthmonitor_circuit_breaker_state{service="api",service_id="api-1"} 1`,
+		`healthmonitor_probe_errors_total{probe_type="tcp",service="api"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+
+	w.forgetInstance("api-1", "api")
+	if body := scrape(); strings.Contains(body, `service_id="api-1"`) {
+		t.Errorf("expected per-instance gauges dropped, got:\n%s", body)
+	}
+}
@@ -0,0 +1,120 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// staticRegistry serves a fixed set of instances.
+type staticRegistry struct {
+	registry.Registry
+	instances map[string][]types.Instance
+}
+
+func (r *staticRegistry) GetServices() ([]string, error) {
+	var names []string
+	for name := range r.instances {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (r *staticRegistry) GetInstances(serviceName string) ([]types.Instance, error) {
+	return r.instances[serviceName], nil
+}
+
+// slowBackend answers health checks after delay, tracking the most requests
+// in flight at once.
+type slowBackend struct {
+	delay    time.Duration
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (b *slowBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	b.inFlight++
+	b.peak = max(b.peak, b.inFlight)
+	b.mu.Unlock()
+	time.Sleep(b.delay)
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+}
+
+// newPoolWorker returns a worker probing n instances of each of services
+// against backend.
+func newPoolWorker(t *testing.T, backend http.Handler, services, n int, cfg Config) *Worker {
+	t.Helper()
+	ts := httptest.NewServer(backend)
+	t.Cleanup(ts.Close)
+	host, port, _ := strings.Cut(ts.Listener.Addr().String(), ":")
+
+	reg := &staticRegistry{instances: make(map[string][]types.Instance)}
+	for s := range services {
+		name := fmt.Sprintf("svc-%d", s)
+		for i := range n {
+			reg.instances[name] = append(reg.instances[name], types.Instance{
+				ServiceID:   fmt.Sprintf("%s-%d", name, i),
+				ServiceName: name,
+				Address:     host,
+				Port:        mustPort(port),
+				Metadata:    map[string]string{"health_check_endpoint": "/health"},
+			})
+		}
+	}
+	return NewWorkerWithOptions(reg, nil, NewCache(), cfg, WorkerOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestWorker_ProbeAllBoundsConcurrency(t *testing.T) {
+	backend := &slowBackend{delay: 20 * time.Millisecond}
+	cfg := DefaultConfig()
+	cfg.MaxConcurrentProbes = 3
+	w := newPoolWorker(t, backend, 4, 5, cfg)
+
+	w.probeAll(context.Background())
+
+	if n := len(w.cache.GetAll()); n != 20 {
+		t.Fatalf("expected all 20 instances probed, got %d", n)
+	}
+	if backend.peak > 3 {
+		t.Fatalf("expected at most 3 probes in flight, got %d", backend.peak)
+	}
+}
+
+func TestWorker_ProbeAllDefersPastDeadline(t *testing.T) {
+	backend := &slowBackend{delay: 200 * time.Millisecond}
+	cfg := DefaultConfig()
+	cfg.MaxConcurrentProbes = 1
+	cfg.ProbeCycleTimeout = 50 * time.Millisecond
+	w := newPoolWorker(t, backend, 1, 5, cfg)
+
+	w.probeAll(context.Background())
+
+	probed := w.cache.GetAll()
+	if len(probed) != 1 {
+		t.Fatalf("expected 1 instance probed before the deadline, got %d", len(probed))
+	}
+	if probed[0].Status != StatusHealthy {
+		t.Fatalf("expected the in-flight probe to finish Healthy, got %v (%s)", probed[0].Status, probed[0].Message)
+	}
+
+	// The deferred instances go first next cycle, rather than the same
+	// instance being probed again while the rest never are.
+	w.probeAll(context.Background())
+	if n := len(w.cache.GetAll()); n != 2 {
+		t.Fatalf("expected a deferred instance probed in the next cycle, got %d probed", n)
+	}
+}
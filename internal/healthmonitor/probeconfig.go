@@ -0,0 +1,173 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instance metadata that tunes the HTTP probe. Unset keys keep the defaults:
+// GET, no body, any 2xx is healthy, and the configured HTTPTimeout.
+//
+//	health_check_method             HTTP method, e.g. HEAD or POST
+//	health_check_expected_status    healthy codes and ranges, e.g. "200,204" or "200-399"
+//	health_check_body               request body
+//	health_check_header_<Name>      request header, overriding HTTPHeaders
+//	health_check_timeout_seconds    per-instance timeout, at most the probe interval
+//	health_check_expected_body      regular expression the body must match
+//	health_check_json_assert        JSON body assertion, e.g. `$.status == "Healthy"`
+//
+// Body checks apply only to an expected status; a body that fails them is
+// Unhealthy, so a backend answering 200 {"status":"Degraded"} is not Healthy.
+const (
+	metaHealthMethod         = "health_check_method"
+	metaHealthExpectedStatus = "health_check_expected_status"
+	metaHealthBody           = "health_check_body"
+	metaHealthHeaderPrefix   = "health_check_header_"
+	metaHealthTimeout        = "health_check_timeout_seconds"
+	metaHealthExpectedBody   = "health_check_expected_body"
+	metaHealthJSONAssert     = "health_check_json_assert"
+)
+
+// httpProbeConfig is the HTTP probe as an instance's metadata describes it.
+type httpProbeConfig struct {
+	method   string
+	body     string
+	headers  map[string]string
+	expected []statusRange
+	timeout  time.Duration // zero keeps the client's timeout
+	matchers *bodyMatchers
+}
+
+// bodyMatchers are an instance's compiled body checks, kept with the
+// metadata they were compiled from so that probes reuse them until it
+// changes.
+type bodyMatchers struct {
+	expectedBody string
+	jsonAssert   string
+	pattern      *regexp.Regexp
+	assertion    *jsonAssertion
+}
+
+// compileBodyMatchers compiles the body checks in meta, returning cached if
+// it was compiled from the same metadata.
+func compileBodyMatchers(meta map[string]string, cached *bodyMatchers) (*bodyMatchers, error) {
+	m := &bodyMatchers{expectedBody: meta[metaHealthExpectedBody], jsonAssert: meta[metaHealthJSONAssert]}
+	if cached != nil && cached.expectedBody == m.expectedBody && cached.jsonAssert == m.jsonAssert {
+		return cached, nil
+	}
+	if m.expectedBody != "" {
+		re, err := regexp.Compile(m.expectedBody)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", metaHealthExpectedBody, err)
+		}
+		m.pattern = re
+	}
+	if m.jsonAssert != "" {
+		a, err := parseJSONAssertion(m.jsonAssert)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", metaHealthJSONAssert, err)
+		}
+		m.assertion = a
+	}
+	return m, nil
+}
+
+// checksBody reports whether the response body must be read.
+func (c httpProbeConfig) checksBody() bool {
+	return c.matchers != nil && (c.matchers.pattern != nil || c.matchers.assertion != nil)
+}
+
+// checkBody applies the body checks, returning why body fails them.
+// truncated reports that body is only the first maxProbeBody bytes, which
+// the pattern may still match but a JSON assertion cannot parse.
+func (c httpProbeConfig) checkBody(body []byte, truncated bool) error {
+	if p := c.matchers.pattern; p != nil && !p.Match(body) {
+		if truncated {
+			return fmt.Errorf("body does not match %s in its first %d KiB", p, maxProbeBody>>10)
+		}
+		return fmt.Errorf("body does not match %s", p)
+	}
+	if a := c.matchers.assertion; a != nil {
+		if truncated {
+			return fmt.Errorf("body exceeds %d KiB, too large for %s", maxProbeBody>>10, metaHealthJSONAssert)
+		}
+		return a.check(body)
+	}
+	return nil
+}
+
+type statusRange struct{ lo, hi int }
+
+// parseHTTPProbeConfig reads the probe settings in meta, reusing cached body
+// matchers if they are still current.
+func parseHTTPProbeConfig(meta map[string]string, cached *bodyMatchers) (httpProbeConfig, error) {
+	cfg := httpProbeConfig{
+		method:   http.MethodGet,
+		body:     meta[metaHealthBody],
+		expected: []statusRange{{200, 299}},
+	}
+	if m := meta[metaHealthMethod]; m != "" {
+		cfg.method = strings.ToUpper(m)
+	}
+	if v := meta[metaHealthExpectedStatus]; v != "" {
+		expected, err := parseStatusRanges(v)
+		if err != nil {
+			return httpProbeConfig{}, fmt.Errorf("%s: %w", metaHealthExpectedStatus, err)
+		}
+		cfg.expected = expected
+	}
+	if v := meta[metaHealthTimeout]; v != "" {
+		secs, err := strconv.ParseFloat(v, 64)
+		if err != nil || secs <= 0 {
+			return httpProbeConfig{}, fmt.Errorf("%s: invalid timeout %q", metaHealthTimeout, v)
+		}
+		cfg.timeout = time.Duration(secs * float64(time.Second))
+	}
+	matchers, err := compileBodyMatchers(meta, cached)
+	if err != nil {
+		return httpProbeConfig{}, err
+	}
+	cfg.matchers = matchers
+	for k, v := range meta {
+		if name, ok := strings.CutPrefix(k, metaHealthHeaderPrefix); ok && name != "" {
+			if cfg.headers == nil {
+				cfg.headers = make(map[string]string)
+			}
+			cfg.headers[name] = v
+		}
+	}
+	return cfg, nil
+}
+
+// parseStatusRanges parses comma-separated status codes and lo-hi ranges.
+func parseStatusRanges(s string) ([]statusRange, error) {
+	var out []statusRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hi = lo
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || from < 100 || to > 599 || from > to {
+			return nil, fmt.Errorf("invalid status %q", part)
+		}
+		out = append(out, statusRange{from, to})
+	}
+	return out, nil
+}
+
+// healthy reports whether code is an expected status.
+func (c httpProbeConfig) healthy(code int) bool {
+	for _, r := range c.expected {
+		if code >= r.lo && code <= r.hi {
+			return true
+		}
+	}
+	return false
+}
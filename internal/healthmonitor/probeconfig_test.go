@@ -0,0 +1,113 @@
+package healthmonitor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestParseHTTPProbeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    map[string]string
+		healthy []int
+		sick    []int
+		wantErr bool
+	}{
+		{"defaults", nil, []int{200, 204, 299}, []int{301, 404, 503}, false},
+		{"codes", map[string]string{metaHealthExpectedStatus: "200, 401"}, []int{200, 401}, []int{204, 500}, false},
+		{"ranges", map[string]string{metaHealthExpectedStatus: "200-399,418"}, []int{200, 302, 418}, []int{404, 500}, false},
+		{"invalid code", map[string]string{metaHealthExpectedStatus: "2xx"}, nil, nil, true},
+		{"reversed range", map[string]string{metaHealthExpectedStatus: "399-200"}, nil, nil, true},
+		{"invalid timeout", map[string]string{metaHealthTimeout: "soon"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseHTTPProbeConfig(tt.meta, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHTTPProbeConfig error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, code := range tt.healthy {
+				if !cfg.healthy(code) {
+					t.Errorf("expected %d healthy", code)
+				}
+			}
+			for _, code := range tt.sick {
+				if cfg.healthy(code) {
+					t.Errorf("expected %d unhealthy", code)
+				}
+			}
+		})
+	}
+}
+
+func TestWorker_HTTPProbe_HonorsMetadata(t *testing.T) {
+	// Timed-out /slow requests are still running when later probes arrive,
+	// so only other paths are captured, under mu.
+	var (
+		mu                          sync.Mutex
+		method, body, token, global string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		method, body, token, global = r.Method, string(b), r.Header.Get("X-Probe-Token"), r.Header.Get("X-Global")
+		mu.Unlock()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	cfg := DefaultConfig()
+	cfg.HTTPHeaders = map[string]string{"X-Global": "monitor", "X-Probe-Token": "global"}
+	w := &Worker{config: cfg, client: ts.Client()}
+	inst := consul.Instance{
+		ServiceID: "svc-1",
+		Address:   parts[0],
+		Port:      mustPort(parts[1]),
+		Metadata: map[string]string{
+			metaHealthMethod:                         "post",
+			metaHealthBody:                           `{"deep":true}`,
+			metaHealthExpectedStatus:                 "200,401",
+			metaHealthHeaderPrefix + "X-Probe-Token": "s3cret",
+		},
+	}
+
+	status, msg := w.httpProbe(context.Background(), inst, "/health")
+	if status != StatusHealthy {
+		t.Fatalf("expected 401 to be healthy when expected, got %v (%s)", status, msg)
+	}
+	mu.Lock()
+	gotMethod, gotBody, gotToken, gotGlobal := method, body, token, global
+	mu.Unlock()
+	if gotMethod != http.MethodPost || gotBody != `{"deep":true}` || gotToken != "s3cret" || gotGlobal != "monitor" {
+		t.Fatalf("unexpected request: method=%s body=%q token=%q global=%q", gotMethod, gotBody, gotToken, gotGlobal)
+	}
+
+	inst.Metadata[metaHealthTimeout] = "0.05"
+	if status, msg := w.httpProbe(context.Background(), inst, "/slow"); status != StatusUnhealthy {
+		t.Fatalf("expected the per-instance timeout to fail the probe, got %v (%s)", status, msg)
+	}
+	inst.Metadata[metaHealthTimeout] = "3600"
+	w.config.ProbeInterval = 50 * time.Millisecond
+	if status, msg := w.httpProbe(context.Background(), inst, "/slow"); status != StatusUnhealthy {
+		t.Fatalf("expected the timeout clamped to the probe interval, got %v (%s)", status, msg)
+	}
+
+	inst.Metadata[metaHealthExpectedStatus] = "bogus"
+	if status, msg := w.httpProbe(context.Background(), inst, "/health"); status != StatusUnknown || !strings.Contains(msg, "invalid probe configuration") {
+		t.Fatalf("expected Unknown for invalid metadata, got %v (%s)", status, msg)
+	}
+}
@@ -0,0 +1,95 @@
+package healthmonitor
+
+import (
+	"context"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	"github.com/toska-mesh/toska-mesh/pkg/healthprobe"
+)
+
+// prober checks the health of an instance. Built-in probes implement it
+// directly; custom ones are healthprobe.Probers adapted by customProber.
+type prober interface {
+	// Name is reported as the probe type of the results.
+	Name() string
+	// Probe checks the instance. ok is false when the instance is not
+	// configured for this probe, so the next prober is tried.
+	Probe(ctx context.Context, inst types.Instance) (status HealthStatus, message string, ok bool)
+}
+
+type funcProber struct {
+	name  string
+	probe func(ctx context.Context, inst types.Instance) (HealthStatus, string, bool)
+}
+
+func newProber(name string, probe func(ctx context.Context, inst types.Instance) (HealthStatus, string, bool)) prober {
+	return funcProber{name: name, probe: probe}
+}
+
+func (p funcProber) Name() string { return p.name }
+
+func (p funcProber) Probe(ctx context.Context, inst types.Instance) (HealthStatus, string, bool) {
+	return p.probe(ctx, inst)
+}
+
+// customProber adapts a prober registered through pkg/healthprobe.
+func customProber(p healthprobe.Prober) prober {
+	return newProber(p.Name(), func(ctx context.Context, inst types.Instance) (HealthStatus, string, bool) {
+		status, msg, ok := p.Probe(ctx, healthprobe.Instance{
+			ServiceName: inst.ServiceName,
+			ServiceID:   inst.ServiceID,
+			Address:     inst.Address,
+			Port:        inst.Port,
+			Metadata:    inst.Metadata,
+		})
+		return probeStatus(status), msg, ok
+	})
+}
+
+func probeStatus(s healthprobe.Status) HealthStatus {
+	switch s {
+	case healthprobe.Healthy:
+		return StatusHealthy
+	case healthprobe.Unhealthy:
+		return StatusUnhealthy
+	case healthprobe.Degraded:
+		return StatusDegraded
+	default:
+		return StatusUnknown
+	}
+}
+
+// metadataProber returns a prober that runs probe with the value of a
+// metadata key, applying only to instances that set it.
+func metadataProber(name, key string, probe func(ctx context.Context, inst types.Instance, value string) (HealthStatus, string)) prober {
+	return newProber(name, func(ctx context.Context, inst types.Instance) (HealthStatus, string, bool) {
+		value := inst.Metadata[key]
+		if value == "" {
+			return StatusUnknown, "", false
+		}
+		status, msg := probe(ctx, inst, value)
+		return status, msg, true
+	})
+}
+
+// builtinProbers returns the worker's own probes in the order they are
+// tried: HTTP, a TLS handshake with certificate checks, a local command, a
+// UDP exchange, a TCP connection, and finally a detected scheme and health
+// path.
+func (w *Worker) builtinProbers() []prober {
+	return []prober{
+		metadataProber("http", "health_check_endpoint", w.httpProbe),
+		metadataProber("tls", "tls_port", w.tlsProbe),
+		metadataProber("exec", "exec_probe", w.execProbe),
+		metadataProber("udp", "udp_port", w.udpProbe),
+		metadataProber("tcp", "tcp_port", w.tcpProbe),
+		newProber("http-detected", func(ctx context.Context, inst types.Instance) (HealthStatus, string, bool) {
+			d, ok := w.detectedProbe(ctx, inst)
+			if !ok {
+				return StatusUnknown, "", false
+			}
+			status, msg := w.httpProbe(ctx, withScheme(inst, d.scheme), d.endpoint)
+			return status, msg, true
+		}),
+	}
+}
@@ -0,0 +1,43 @@
+package healthmonitor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/pkg/healthprobe"
+)
+
+func TestWorker_CustomProber(t *testing.T) {
+	lag := healthprobe.New("kafka-lag", func(ctx context.Context, inst healthprobe.Instance) (healthprobe.Status, string, bool) {
+		group := inst.Metadata["kafka_group"]
+		if group == "" {
+			return healthprobe.Unknown, "", false
+		}
+		return healthprobe.Degraded, "lag 1200 on " + group, true
+	})
+	w := NewWorkerWithOptions(nil, nil, NewCache(), DefaultConfig(), WorkerOptions{Probers: []healthprobe.Prober{lag}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name      string
+		meta      map[string]string
+		wantType  string
+		wantState HealthStatus
+	}{
+		// The custom prober is tried before the built-in ones.
+		{"custom", map[string]string{"kafka_group": "orders", "tcp_port": "1"}, "kafka-lag", StatusDegraded},
+		{"falls through to built-in", map[string]string{"exec_probe": "true"}, "exec", StatusUnknown},
+		{"no prober applies", nil, "none", StatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := consul.Instance{ServiceID: "svc-1", ServiceName: "orders", Address: "127.0.0.1", Metadata: tt.meta}
+			status, probeType, msg := w.runProbes(context.Background(), inst)
+			if probeType != tt.wantType || status != tt.wantState {
+				t.Fatalf("expected %v via %s, got %v via %s (%s)", tt.wantState, tt.wantType, status, probeType, msg)
+			}
+		})
+	}
+}
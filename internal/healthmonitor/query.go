@@ -0,0 +1,136 @@
+package healthmonitor
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatusQuery selects, orders and pages cached instances.
+type StatusQuery struct {
+	// Statuses keeps instances with any of these statuses; empty keeps all.
+	Statuses []HealthStatus
+	// ServicePrefix keeps instances whose service name starts with it.
+	ServicePrefix string
+	// SortBy is serviceId (the default), serviceName, status (best first),
+	// lastProbe or latency. Descending reverses the order.
+	SortBy     string
+	Descending bool
+	// Offset skips matching instances; Limit caps how many are returned,
+	// with zero returning all.
+	Offset int
+	Limit  int
+}
+
+// statusSorts compares instances for each StatusQuery.SortBy value. Ties
+// are broken by service ID so pages are stable.
+var statusSorts = map[string]func(a, b *MonitoredInstance) int{
+	"serviceId":   func(a, b *MonitoredInstance) int { return 0 },
+	"serviceName": func(a, b *MonitoredInstance) int { return strings.Compare(a.ServiceName, b.ServiceName) },
+	"status":      func(a, b *MonitoredInstance) int { return severity(a.Status) - severity(b.Status) },
+	"lastProbe":   func(a, b *MonitoredInstance) int { return a.LastProbe.Compare(b.LastProbe) },
+	"latency": func(a, b *MonitoredInstance) int {
+		switch {
+		case a.LatencyMs < b.LatencyMs:
+			return -1
+		case a.LatencyMs > b.LatencyMs:
+			return 1
+		}
+		return 0
+	},
+}
+
+// ParseStatusQuery reads a query from URL parameters: status (repeatable or
+// comma-separated, e.g. status=Unhealthy,Degraded), service (a service name
+// prefix), sort (a SortBy value, prefixed with "-" for descending), limit
+// and offset.
+func ParseStatusQuery(v url.Values) (StatusQuery, error) {
+	q := StatusQuery{ServicePrefix: v.Get("service"), SortBy: "serviceId"}
+	for _, param := range v["status"] {
+		for _, name := range strings.Split(param, ",") {
+			s, ok := parseHealthStatus(strings.TrimSpace(name))
+			if !ok {
+				return q, fmt.Errorf("unknown status %q", name)
+			}
+			q.Statuses = append(q.Statuses, s)
+		}
+	}
+	if sortBy := v.Get("sort"); sortBy != "" {
+		sortBy, q.Descending = strings.CutPrefix(sortBy, "-")
+		if _, ok := statusSorts[sortBy]; !ok {
+			return q, fmt.Errorf("unknown sort %q", sortBy)
+		}
+		q.SortBy = sortBy
+	}
+	for name, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if s := v.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return q, fmt.Errorf("invalid %s %q", name, s)
+			}
+			*dst = n
+		}
+	}
+	return q, nil
+}
+
+// parseHealthStatus parses a status name, ignoring case.
+func parseHealthStatus(name string) (HealthStatus, bool) {
+	for _, s := range []HealthStatus{StatusUnknown, StatusHealthy, StatusUnhealthy, StatusDegraded} {
+		if strings.EqualFold(name, s.String()) {
+			return s, true
+		}
+	}
+	return StatusUnknown, false
+}
+
+// Query returns one page of the instances matching q, and how many match in
+// total.
+func (c *Cache) Query(q StatusQuery) (page []MonitoredInstance, total int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	matched := make([]*MonitoredInstance, 0, len(c.instances))
+	for _, inst := range c.instances {
+		if q.matches(inst) {
+			matched = append(matched, inst)
+		}
+	}
+
+	compare := statusSorts[q.SortBy]
+	if compare == nil {
+		compare = statusSorts["serviceId"]
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if q.Descending {
+			a, b = b, a
+		}
+		if n := compare(a, b); n != 0 {
+			return n < 0
+		}
+		return a.ServiceID < b.ServiceID
+	})
+
+	total = len(matched)
+	start := min(q.Offset, total)
+	end := total
+	if q.Limit > 0 {
+		end = min(start+q.Limit, total)
+	}
+	page = make([]MonitoredInstance, 0, end-start)
+	for _, inst := range matched[start:end] {
+		page = append(page, *inst)
+	}
+	return page, total
+}
+
+func (q StatusQuery) matches(inst *MonitoredInstance) bool {
+	if !strings.HasPrefix(inst.ServiceName, q.ServicePrefix) {
+		return false
+	}
+	return len(q.Statuses) == 0 || slices.Contains(q.Statuses, inst.Status)
+}
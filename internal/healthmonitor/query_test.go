@@ -0,0 +1,65 @@
+package healthmonitor
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCache_Query(t *testing.T) {
+	c := NewCache()
+	c.UpdateProbe(MonitoredInstance{ServiceID: "orders-2", ServiceName: "orders", Status: StatusUnhealthy, ProbeType: "http", LatencyMs: 10})
+	c.UpdateProbe(MonitoredInstance{ServiceID: "orders-1", ServiceName: "orders", Status: StatusHealthy, ProbeType: "http", LatencyMs: 30})
+	c.UpdateProbe(MonitoredInstance{ServiceID: "payments-1", ServiceName: "payments", Status: StatusUnhealthy, ProbeType: "http", LatencyMs: 20})
+	c.Update("search-1", "search", "", 0, StatusDegraded, "http", "", nil)
+
+	ids := func(page []MonitoredInstance) []string {
+		var out []string
+		for _, inst := range page {
+			out = append(out, inst.ServiceID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		want      []string
+		wantTotal int
+	}{
+		{"all by service ID", "", []string{"orders-1", "orders-2", "payments-1", "search-1"}, 4},
+		{"status filter", "status=unhealthy", []string{"orders-2", "payments-1"}, 2},
+		{"several statuses", "status=Unhealthy,Degraded", []string{"orders-2", "payments-1", "search-1"}, 3},
+		{"service prefix", "service=ord", []string{"orders-1", "orders-2"}, 2},
+		{"latency descending", "sort=-latency&service=o", []string{"orders-1", "orders-2"}, 2},
+		{"page", "sort=latency&limit=2&offset=1", []string{"orders-2", "payments-1"}, 4},
+		{"offset past the end", "offset=10", []string{}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := url.ParseQuery(tt.query)
+			q, err := ParseStatusQuery(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, total := c.Query(q)
+			got := ids(page)
+			if total != tt.wantTotal || len(got) != len(tt.want) {
+				t.Fatalf("expected %v of %d, got %v of %d", tt.want, tt.wantTotal, got, total)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStatusQuery_Invalid(t *testing.T) {
+	for _, query := range []string{"status=sick", "sort=age", "limit=-1", "offset=x"} {
+		v, _ := url.ParseQuery(query)
+		if _, err := ParseStatusQuery(v); err == nil {
+			t.Errorf("expected %q to be rejected", query)
+		}
+	}
+}
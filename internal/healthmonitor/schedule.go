@@ -0,0 +1,201 @@
+package healthmonitor
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+// probeSchedule is when an instance is next due for a probe.
+type probeSchedule struct {
+	next    time.Time
+	running bool
+	started time.Time // when the running probe was dispatched
+	// healthyStreak counts consecutive Healthy results, for adaptive
+	// intervals.
+	healthyStreak int
+}
+
+// adaptiveStableProbes is how many consecutive Healthy results double a
+// stable instance's interval, up to MaxProbeInterval.
+const adaptiveStableProbes = 5
+
+// runSpread probes each instance on its own schedule instead of all at once,
+// so backends and the registry see a steady load rather than a burst every
+// interval. Instances start at a fixed offset within the interval derived
+// from their ID, and are then probed every adaptiveInterval plus or minus
+// ProbeJitter. The registry is listed once per interval.
+func (w *Worker) runSpread(ctx context.Context) {
+	sem := make(chan struct{}, max(w.config.MaxConcurrentProbes, 1))
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(schedulerTick(w.config.ProbeInterval))
+	defer ticker.Stop()
+
+	var (
+		targets  []probeTarget
+		listedAt time.Time
+	)
+	for {
+		now := time.Now()
+		if now.Sub(listedAt) >= w.config.ProbeInterval {
+			listedAt = now
+			listed, liveIDs, err := w.listTargets(sem)
+			if err != nil {
+				w.logger.Error("failed to list services", "error", err)
+			} else {
+				targets = listed
+				w.evict(liveIDs)
+			}
+			w.flushResolutions(now)
+			w.checkComposites()
+		}
+
+		if w.watchdog.Overloaded() {
+			w.logger.Debug("skipping probe dispatch, process overloaded")
+			w.telemetry.Count("healthmonitor_skipped_cycles_total", 1, nil)
+		} else {
+			w.dispatchDue(ctx, targets, sem, &wg, now)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("health probe worker stopping")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue starts a probe for each target whose schedule is due, while
+// the pool has room. Due instances that find the pool full stay due and are
+// dispatched on a later tick.
+func (w *Worker) dispatchDue(ctx context.Context, targets []probeTarget, sem chan struct{}, wg *sync.WaitGroup, now time.Time) {
+	for i, t := range targets {
+		if !w.claimProbe(t.inst.ServiceID, now) {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			w.unclaimProbe(t.inst.ServiceID)
+			for _, d := range targets[i:] {
+				w.telemetry.Count("healthmonitor_probes_deferred_total", 1, telemetry.Labels{"service": d.inst.ServiceName})
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			w.probeInstance(ctx, t.inst, t.registered)
+			w.finishProbe(t.inst.ServiceID)
+		}()
+	}
+}
+
+// claimProbe reports whether an instance is due at now and not already being
+// probed, and if so marks it running. An instance seen for the first time is
+// scheduled at its offset within the interval.
+func (w *Worker) claimProbe(serviceID string, now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	s := w.schedule[serviceID]
+	if s == nil {
+		s = &probeSchedule{next: now.Add(probeOffset(serviceID, w.config.ProbeInterval))}
+		w.schedule[serviceID] = s
+	}
+	if s.running || now.Before(s.next) {
+		return false
+	}
+	s.running = true
+	s.started = now
+	return true
+}
+
+// unclaimProbe returns a claimed instance to the due state.
+func (w *Worker) unclaimProbe(serviceID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s := w.schedule[serviceID]; s != nil {
+		s.running = false
+		s.next = time.Time{}
+	}
+}
+
+// finishProbe schedules an instance's next probe from the result of the one
+// just finished.
+func (w *Worker) finishProbe(serviceID string) {
+	status := StatusUnknown
+	if inst := w.cache.Get(serviceID); inst != nil {
+		status = inst.Status
+	}
+	flapping := w.isFlapping(serviceID)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.schedule[serviceID]
+	if s == nil {
+		return
+	}
+	s.running = false
+	if status == StatusHealthy && !flapping {
+		s.healthyStreak++
+	} else {
+		s.healthyStreak = 0
+	}
+	s.next = s.started.Add(jittered(w.adaptiveInterval(status, flapping, s.healthyStreak), w.config.ProbeJitter))
+}
+
+// adaptiveInterval is the interval until an instance's next probe. Without
+// MinProbeInterval or MaxProbeInterval it is always ProbeInterval. Otherwise
+// Unhealthy, Degraded and flapping instances are probed every
+// MinProbeInterval to catch recovery sooner, and instances that stay Healthy
+// back off towards MaxProbeInterval unless their TTL checks depend on the
+// probes (ReportHealth).
+func (w *Worker) adaptiveInterval(status HealthStatus, flapping bool, healthyStreak int) time.Duration {
+	interval := w.config.ProbeInterval
+	if flapping || status == StatusUnhealthy || status == StatusDegraded {
+		if w.config.MinProbeInterval > 0 {
+			return min(w.config.MinProbeInterval, interval)
+		}
+		return interval
+	}
+	if status != StatusHealthy || w.config.MaxProbeInterval <= interval || w.config.ReportHealth {
+		return interval
+	}
+	for range healthyStreak / adaptiveStableProbes {
+		interval *= 2
+		if interval >= w.config.MaxProbeInterval {
+			return w.config.MaxProbeInterval
+		}
+	}
+	return interval
+}
+
+// jittered varies interval by up to plus or minus fraction of it.
+func jittered(interval time.Duration, fraction float64) time.Duration {
+	return interval + time.Duration(float64(interval)*fraction*(2*rand.Float64()-1))
+}
+
+// probeOffset spreads instances evenly across the interval. It depends only
+// on the instance ID, so the spread survives restarts.
+func probeOffset(serviceID string, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(serviceID))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// schedulerTick is how often runSpread looks for due instances: a thirtieth
+// of the interval, between 100ms and 1s.
+func schedulerTick(interval time.Duration) time.Duration {
+	return min(max(interval/30, 100*time.Millisecond), time.Second)
+}
@@ -0,0 +1,127 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestProbeOffset_SpreadsAcrossInterval(t *testing.T) {
+	interval := 30 * time.Second
+	buckets := make([]int, 3)
+	for i := range 300 {
+		off := probeOffset(fmt.Sprintf("svc-%d", i), interval)
+		if off < 0 || off >= interval {
+			t.Fatalf("offset %v outside [0, %v)", off, interval)
+		}
+		buckets[off*3/interval]++
+	}
+	for i, n := range buckets {
+		if n < 60 {
+			t.Errorf("expected offsets spread evenly, third %d holds only %d of 300", i, n)
+		}
+	}
+	if probeOffset("svc-1", interval) != probeOffset("svc-1", interval) {
+		t.Fatal("expected a stable offset per instance")
+	}
+}
+
+func TestWorker_DispatchDueFollowsSchedule(t *testing.T) {
+	backend := &slowBackend{}
+	cfg := DefaultConfig()
+	cfg.ProbeInterval = time.Minute
+	cfg.ProbeJitter = 0.1
+	w := newPoolWorker(t, backend, 2, 5, cfg)
+	sem := make(chan struct{}, 64)
+	targets, _, err := w.listTargets(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	w.dispatchDue(context.Background(), targets, sem, &wg, start)
+	wg.Wait()
+	first := len(w.cache.GetAll())
+	if first == len(targets) {
+		t.Fatalf("expected instances spread over the interval, all %d probed at once", first)
+	}
+
+	w.dispatchDue(context.Background(), targets, sem, &wg, start.Add(time.Minute))
+	wg.Wait()
+	if n := len(w.cache.GetAll()); n != len(targets) {
+		t.Fatalf("expected every instance probed within one interval, got %d of %d", n, len(targets))
+	}
+
+	for id, s := range w.schedule {
+		due := s.next.Sub(start)
+		if due < 54*time.Second || due > 2*time.Minute+6*time.Second {
+			t.Errorf("%s: next probe %v after start, outside the jittered interval", id, due)
+		}
+	}
+}
+
+func TestWorker_DispatchDueSkipsRunningAndFullPool(t *testing.T) {
+	cfg := DefaultConfig()
+	w := NewWorkerWithOptions(nil, nil, NewCache(), cfg, WorkerOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now()
+
+	w.schedule["a"] = &probeSchedule{next: now, running: true}
+	if w.claimProbe("a", now) {
+		t.Fatal("expected an instance already being probed not to be claimed")
+	}
+
+	sem := make(chan struct{}) // no room
+	var wg sync.WaitGroup
+	w.schedule["b"] = &probeSchedule{next: now.Add(-time.Second)}
+	w.dispatchDue(context.Background(), []probeTarget{{inst: consul.Instance{ServiceID: "b", ServiceName: "api"}}}, sem, &wg, now)
+	if s := w.schedule["b"]; s.running || s.next.After(now) {
+		t.Fatalf("expected b to stay due with the pool full, got %+v", s)
+	}
+}
+
+func TestWorker_AdaptiveInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ProbeInterval = 30 * time.Second
+	cfg.MinProbeInterval = 5 * time.Second
+	cfg.MaxProbeInterval = 2 * time.Minute
+	w := &Worker{config: cfg}
+
+	tests := []struct {
+		name     string
+		status   HealthStatus
+		flapping bool
+		streak   int
+		want     time.Duration
+	}{
+		{"unhealthy", StatusUnhealthy, false, 0, 5 * time.Second},
+		{"degraded", StatusDegraded, false, 0, 5 * time.Second},
+		{"flapping while healthy", StatusHealthy, true, 0, 5 * time.Second},
+		{"unknown", StatusUnknown, false, 0, 30 * time.Second},
+		{"newly healthy", StatusHealthy, false, 4, 30 * time.Second},
+		{"stable", StatusHealthy, false, 5, time.Minute},
+		{"stable longer", StatusHealthy, false, 12, 2 * time.Minute},
+		{"capped", StatusHealthy, false, 100, 2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.adaptiveInterval(tt.status, tt.flapping, tt.streak); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	w.config.MinProbeInterval, w.config.MaxProbeInterval = 0, 0
+	if got := w.adaptiveInterval(StatusUnhealthy, false, 0); got != 30*time.Second {
+		t.Fatalf("expected ProbeInterval without adaptive bounds, got %v", got)
+	}
+	if got := w.adaptiveInterval(StatusHealthy, false, 50); got != 30*time.Second {
+		t.Fatalf("expected ProbeInterval without adaptive bounds, got %v", got)
+	}
+}
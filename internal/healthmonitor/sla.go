@@ -0,0 +1,137 @@
+package healthmonitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SLA reports a service's availability and incidents over a window, from
+// the probe results and transitions of all its instances.
+type SLA struct {
+	ServiceName string    `json:"serviceName"`
+	Since       time.Time `json:"since"`
+	Probes      int       `json:"probes"`
+	// AvailabilityPercent is the share of probes that were Healthy or
+	// Degraded, from 0 to 100.
+	AvailabilityPercent float64 `json:"availabilityPercent"`
+	// Incidents counts instances becoming Unhealthy; OpenIncidents those
+	// still Unhealthy at the end of the window.
+	Incidents     int `json:"incidents"`
+	OpenIncidents int `json:"openIncidents"`
+	// MTTRSeconds is the mean time from an instance becoming Unhealthy to
+	// it recovering, over the incidents that recovered.
+	MTTRSeconds float64 `json:"mttrSeconds"`
+}
+
+// incidentTransition is the part of a stored transition incidents are
+// computed from.
+type incidentTransition struct {
+	ServiceID string
+	Time      time.Time
+	Current   string
+}
+
+// SLA summarises a service's probe results and incidents since a time.
+func (s *SQLStore) SLA(ctx context.Context, serviceName string, since time.Time) (SLA, error) {
+	sla := SLA{ServiceName: serviceName, Since: since.UTC()}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(
+		`SELECT status, COUNT(*) FROM probe_results WHERE service_name = ? AND probed_at >= ? GROUP BY status`),
+		serviceName, since.UTC())
+	if err != nil {
+		return sla, fmt.Errorf("query sla probes: %w", err)
+	}
+	available := 0
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			rows.Close()
+			return sla, fmt.Errorf("scan sla probes: %w", err)
+		}
+		sla.Probes += n
+		if status == StatusHealthy.String() || status == StatusDegraded.String() {
+			available += n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return sla, fmt.Errorf("query sla probes: %w", err)
+	}
+	if sla.Probes > 0 {
+		sla.AvailabilityPercent = 100 * float64(available) / float64(sla.Probes)
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.rebind(
+		`SELECT service_id, changed_at, current_status FROM status_transitions WHERE service_name = ? AND changed_at >= ? ORDER BY changed_at`),
+		serviceName, since.UTC())
+	if err != nil {
+		return sla, fmt.Errorf("query sla transitions: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions []incidentTransition
+	for rows.Next() {
+		var t incidentTransition
+		if err := rows.Scan(&t.ServiceID, &t.Time, &t.Current); err != nil {
+			return sla, fmt.Errorf("scan sla transitions: %w", err)
+		}
+		transitions = append(transitions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return sla, fmt.Errorf("query sla transitions: %w", err)
+	}
+	sla.Incidents, sla.OpenIncidents, sla.MTTRSeconds = summariseIncidents(transitions)
+	return sla, nil
+}
+
+// summariseIncidents counts incidents in time-ordered transitions. An
+// incident starts when an instance becomes Unhealthy and ends when it turns
+// Healthy or Degraded; recoveries of instances already Unhealthy before the
+// first transition are not incidents.
+func summariseIncidents(transitions []incidentTransition) (incidents, open int, mttrSeconds float64) {
+	started := make(map[string]time.Time)
+	var repaired int
+	var total time.Duration
+	for _, t := range transitions {
+		start, down := started[t.ServiceID]
+		switch t.Current {
+		case StatusUnhealthy.String():
+			if !down {
+				started[t.ServiceID] = t.Time
+				incidents++
+			}
+		case StatusHealthy.String(), StatusDegraded.String():
+			if down {
+				delete(started, t.ServiceID)
+				repaired++
+				total += t.Time.Sub(start)
+			}
+		}
+	}
+	if repaired > 0 {
+		mttrSeconds = math.Round(total.Seconds()/float64(repaired)*1000) / 1000
+	}
+	return incidents, len(started), mttrSeconds
+}
+
+// ParseWindow parses a reporting window such as "30d", "12h" or "90m". Days
+// are accepted in addition to time.ParseDuration units.
+func ParseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	return d, nil
+}
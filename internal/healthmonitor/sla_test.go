@@ -0,0 +1,50 @@
+package healthmonitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummariseIncidents(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	transitions := []incidentTransition{
+		// Already Unhealthy before the window: its recovery is not counted.
+		{ServiceID: "api-2", Time: at, Current: "Healthy"},
+		{ServiceID: "api-1", Time: at.Add(time.Minute), Current: "Unhealthy"},
+		{ServiceID: "api-1", Time: at.Add(2 * time.Minute), Current: "Unknown"},
+		{ServiceID: "api-1", Time: at.Add(3 * time.Minute), Current: "Degraded"},
+		{ServiceID: "api-2", Time: at.Add(4 * time.Minute), Current: "Unhealthy"},
+		{ServiceID: "api-2", Time: at.Add(5 * time.Minute), Current: "Healthy"},
+		{ServiceID: "api-1", Time: at.Add(6 * time.Minute), Current: "Unhealthy"},
+	}
+
+	incidents, open, mttr := summariseIncidents(transitions)
+	if incidents != 3 || open != 1 {
+		t.Fatalf("expected 3 incidents with 1 open, got %d and %d", incidents, open)
+	}
+	if mttr != 90 {
+		t.Fatalf("expected MTTR of 90s, got %v", mttr)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"xd", 0, true},
+		{"-1h", 0, true},
+		{"month", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWindow(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseWindow(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
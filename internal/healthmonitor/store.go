@@ -0,0 +1,215 @@
+package healthmonitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store persists probe results and status transitions beyond the in-memory
+// cache, so history survives restarts and availability can be reported over
+// long ranges.
+type Store interface {
+	SaveProbe(ctx context.Context, serviceID, serviceName string, result ProbeResult) error
+	SaveTransition(ctx context.Context, t Transition) error
+	// Purge deletes records older than before, returning how many.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// Availability summarises an instance's probe results since a time.
+	Availability(ctx context.Context, serviceID string, since time.Time) (Availability, error)
+	// SLA summarises a service's availability and incidents since a time.
+	SLA(ctx context.Context, serviceName string, since time.Time) (SLA, error)
+}
+
+// Transition is a change of an instance's health status.
+type Transition struct {
+	Time        time.Time    `json:"time"`
+	ServiceID   string       `json:"serviceId"`
+	ServiceName string       `json:"serviceName"`
+	Previous    HealthStatus `json:"previous"`
+	Current     HealthStatus `json:"current"`
+	Message     string       `json:"message,omitempty"`
+}
+
+// Availability counts an instance's probe results by status. Degraded
+// results count as available.
+type Availability struct {
+	ServiceID    string    `json:"serviceId"`
+	Since        time.Time `json:"since"`
+	Probes       int       `json:"probes"`
+	Healthy      int       `json:"healthy"`
+	Degraded     int       `json:"degraded"`
+	Unhealthy    int       `json:"unhealthy"`
+	Unknown      int       `json:"unknown"`
+	Availability float64   `json:"availability"`
+}
+
+// SQL dialects supported by SQLStore.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// SQLStore is a Store in a SQLite or PostgreSQL database. The caller opens
+// the database with a driver for its dialect linked into the binary.
+type SQLStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// NewSQLStore returns a store using db, creating its tables if needed.
+func NewSQLStore(ctx context.Context, db *sql.DB, dialect string) (*SQLStore, error) {
+	if dialect != DialectSQLite && dialect != DialectPostgres {
+		return nil, fmt.Errorf("unsupported store dialect %q", dialect)
+	}
+	s := &SQLStore{db: db, dialect: dialect}
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS probe_results (
+			service_id TEXT NOT NULL,
+			service_name TEXT NOT NULL,
+			probed_at TIMESTAMP NOT NULL,
+			status TEXT NOT NULL,
+			probe_type TEXT NOT NULL,
+			latency_ms DOUBLE PRECISION NOT NULL,
+			message TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS probe_results_service ON probe_results (service_id, probed_at)`,
+		`CREATE TABLE IF NOT EXISTS status_transitions (
+			service_id TEXT NOT NULL,
+			service_name TEXT NOT NULL,
+			changed_at TIMESTAMP NOT NULL,
+			previous_status TEXT NOT NULL,
+			current_status TEXT NOT NULL,
+			message TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS status_transitions_service ON status_transitions (service_id, changed_at)`,
+		// SLA reports select by service name across instances.
+		`CREATE INDEX IF NOT EXISTS probe_results_service_name ON probe_results (service_name, probed_at)`,
+		`CREATE INDEX IF NOT EXISTS status_transitions_service_name ON status_transitions (service_name, changed_at)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("create schema: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// SaveProbe records one probe result.
+func (s *SQLStore) SaveProbe(ctx context.Context, serviceID, serviceName string, r ProbeResult) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(
+		`INSERT INTO probe_results (service_id, service_name, probed_at, status, probe_type, latency_ms, message) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		serviceID, serviceName, r.Time.UTC(), r.Status.String(), r.ProbeType, r.LatencyMs, r.Message)
+	if err != nil {
+		return fmt.Errorf("save probe result: %w", err)
+	}
+	return nil
+}
+
+// SaveTransition records one status transition.
+func (s *SQLStore) SaveTransition(ctx context.Context, t Transition) error {
+	_, err := s.db.ExecContext(ctx, s.rebind(
+		`INSERT INTO status_transitions (service_id, service_name, changed_at, previous_status, current_status, message) VALUES (?, ?, ?, ?, ?, ?)`),
+		t.ServiceID, t.ServiceName, t.Time.UTC(), t.Previous.String(), t.Current.String(), t.Message)
+	if err != nil {
+		return fmt.Errorf("save transition: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes probe results and transitions older than before.
+func (s *SQLStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, stmt := range []string{
+		`DELETE FROM probe_results WHERE probed_at < ?`,
+		`DELETE FROM status_transitions WHERE changed_at < ?`,
+	} {
+		res, err := s.db.ExecContext(ctx, s.rebind(stmt), before.UTC())
+		if err != nil {
+			return total, fmt.Errorf("purge: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// Availability counts an instance's probe results since a time.
+func (s *SQLStore) Availability(ctx context.Context, serviceID string, since time.Time) (Availability, error) {
+	a := Availability{ServiceID: serviceID, Since: since.UTC()}
+	rows, err := s.db.QueryContext(ctx, s.rebind(
+		`SELECT status, COUNT(*) FROM probe_results WHERE service_id = ? AND probed_at >= ? GROUP BY status`),
+		serviceID, since.UTC())
+	if err != nil {
+		return a, fmt.Errorf("query availability: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return a, fmt.Errorf("scan availability: %w", err)
+		}
+		a.Probes += n
+		switch status {
+		case StatusHealthy.String():
+			a.Healthy += n
+		case StatusDegraded.String():
+			a.Degraded += n
+		case StatusUnhealthy.String():
+			a.Unhealthy += n
+		default:
+			a.Unknown += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return a, fmt.Errorf("query availability: %w", err)
+	}
+	if a.Probes > 0 {
+		a.Availability = float64(a.Healthy+a.Degraded) / float64(a.Probes)
+	}
+	return a, nil
+}
+
+// rebind rewrites ? placeholders as $1, $2, ... for PostgreSQL.
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// RunStorePurge deletes records older than retention every interval until
+// ctx is cancelled.
+func RunStorePurge(ctx context.Context, store Store, retention, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := store.Purge(ctx, time.Now().Add(-retention))
+		if err != nil {
+			logger.Warn("health history purge failed", "error", err)
+			continue
+		}
+		if n > 0 {
+			logger.Info("purged health history", "records", n, "retention", retention)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package healthmonitor
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// These tests run SQLStore against the drivers cmd/healthmonitor links in.
+// PostgreSQL runs only when HEALTHMONITOR_TEST_POSTGRES_DSN is set.
+
+func TestSQLStore_SQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	testSQLStore(t, db, DialectSQLite)
+
+	// SLA queries filter on service_name, so both tables index it.
+	for _, index := range []string{"probe_results_service_name", "status_transitions_service_name"} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, index).Scan(&n); err != nil || n != 1 {
+			t.Errorf("expected index %s, got %d (%v)", index, n, err)
+		}
+	}
+}
+
+func TestSQLStore_Postgres(t *testing.T) {
+	dsn := os.Getenv("HEALTHMONITOR_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("HEALTHMONITOR_TEST_POSTGRES_DSN not set")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	for _, table := range []string{"probe_results", "status_transitions"} {
+		if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+			t.Fatalf("drop %s: %v", table, err)
+		}
+	}
+	testSQLStore(t, db, DialectPostgres)
+}
+
+func testSQLStore(t *testing.T, db *sql.DB, dialect string) {
+	ctx := context.Background()
+	store, err := NewSQLStore(ctx, db, dialect)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+
+	probes := []struct {
+		id     string
+		at     time.Time
+		status HealthStatus
+	}{
+		{"api-1", now.Add(-48 * time.Hour), StatusUnhealthy}, // purged
+		{"api-1", now.Add(-30 * time.Minute), StatusHealthy},
+		{"api-1", now.Add(-20 * time.Minute), StatusUnhealthy},
+		{"api-1", now.Add(-10 * time.Minute), StatusDegraded},
+		{"api-2", now.Add(-10 * time.Minute), StatusHealthy},
+	}
+	for _, p := range probes {
+		if err := store.SaveProbe(ctx, p.id, "api", ProbeResult{Time: p.at, Status: p.status, ProbeType: "http"}); err != nil {
+			t.Fatalf("save probe: %v", err)
+		}
+	}
+	for _, tr := range []Transition{
+		{Time: now.Add(-20 * time.Minute), ServiceID: "api-1", ServiceName: "api", Previous: StatusHealthy, Current: StatusUnhealthy},
+		{Time: now.Add(-10 * time.Minute), ServiceID: "api-1", ServiceName: "api", Previous: StatusUnhealthy, Current: StatusDegraded},
+	} {
+		if err := store.SaveTransition(ctx, tr); err != nil {
+			t.Fatalf("save transition: %v", err)
+		}
+	}
+
+	n, err := store.Purge(ctx, now.Add(-24*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 purged record, got %d (%v)", n, err)
+	}
+
+	a, err := store.Availability(ctx, "api-1", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("availability: %v", err)
+	}
+	if a.Probes != 3 || a.Healthy != 1 || a.Degraded != 1 || a.Unhealthy != 1 {
+		t.Fatalf("unexpected availability %+v", a)
+	}
+
+	sla, err := store.SLA(ctx, "api", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("sla: %v", err)
+	}
+	if sla.Probes != 4 || sla.AvailabilityPercent != 75 || sla.Incidents != 1 || sla.OpenIncidents != 0 || sla.MTTRSeconds != 600 {
+		t.Fatalf("unexpected sla %+v", sla)
+	}
+}
@@ -0,0 +1,197 @@
+package healthmonitor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+// recordingDriver is a database/sql driver that records statements and
+// answers queries with canned rows.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs []string
+	args  [][]driver.Value
+	rows  [][]driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.d, query}, nil
+}
+func (c recordingConn) Close() error              { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.execs = append(s.d.execs, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(2), nil
+}
+
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	return &cannedRows{rows: s.d.rows}, nil
+}
+
+type cannedRows struct {
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *cannedRows) Columns() []string { return []string{"status", "count"} }
+func (r *cannedRows) Close() error      { return nil }
+func (r *cannedRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+// newRecordingStore opens a SQLStore on a fresh recording driver.
+func newRecordingStore(t *testing.T, dialect string) (*SQLStore, *recordingDriver) {
+	t.Helper()
+	d := &recordingDriver{}
+	db := sql.OpenDB(connector{d})
+	t.Cleanup(func() { db.Close() })
+	store, err := NewSQLStore(context.Background(), db, dialect)
+	if err != nil {
+		t.Fatalf("NewSQLStore: %v", err)
+	}
+	d.execs, d.args = nil, nil
+	return store, d
+}
+
+type connector struct{ d *recordingDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return recordingConn{c.d}, nil }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+func TestSQLStore_Placeholders(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{DialectSQLite, "VALUES (?, ?, ?, ?, ?, ?, ?)"},
+		{DialectPostgres, "VALUES ($1, $2, $3, $4, $5, $6, $7)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			store, d := newRecordingStore(t, tt.dialect)
+			at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			err := store.SaveProbe(context.Background(), "svc-1", "api", ProbeResult{Time: at, Status: StatusDegraded, ProbeType: "http", LatencyMs: 12.5, Message: "slow"})
+			if err != nil {
+				t.Fatalf("SaveProbe: %v", err)
+			}
+			if len(d.execs) != 1 || !strings.Contains(d.execs[0], tt.want) {
+				t.Fatalf("expected insert with %q, got %v", tt.want, d.execs)
+			}
+			if d.args[0][3] != "Degraded" || d.args[0][5] != 12.5 {
+				t.Fatalf("unexpected arguments %v", d.args[0])
+			}
+		})
+	}
+
+	if _, err := NewSQLStore(context.Background(), nil, "mysql"); err == nil {
+		t.Fatal("expected an unsupported dialect to be rejected")
+	}
+}
+
+func TestSQLStore_PurgeAndAvailability(t *testing.T) {
+	store, d := newRecordingStore(t, DialectSQLite)
+
+	n, err := store.Purge(context.Background(), time.Now())
+	if err != nil || n != 4 {
+		t.Fatalf("Purge = %d, %v; want 4 rows from both tables", n, err)
+	}
+	if len(d.execs) != 2 || !strings.Contains(d.execs[0], "probe_results") || !strings.Contains(d.execs[1], "status_transitions") {
+		t.Fatalf("unexpected purge statements %v", d.execs)
+	}
+
+	d.rows = [][]driver.Value{{"Healthy", int64(7)}, {"Degraded", int64(1)}, {"Unhealthy", int64(2)}}
+	a, err := store.Availability(context.Background(), "svc-1", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Availability: %v", err)
+	}
+	if a.Probes != 10 || a.Healthy != 7 || a.Degraded != 1 || a.Unhealthy != 2 || a.Availability != 0.8 {
+		t.Fatalf("unexpected availability %+v", a)
+	}
+}
+
+// memStore is a Store that keeps records in memory.
+type memStore struct {
+	Store
+	probes      []ProbeResult
+	transitions []Transition
+}
+
+func (m *memStore) SaveProbe(ctx context.Context, serviceID, serviceName string, r ProbeResult) error {
+	m.probes = append(m.probes, r)
+	return nil
+}
+
+func (m *memStore) SaveTransition(ctx context.Context, t Transition) error {
+	m.transitions = append(m.transitions, t)
+	return nil
+}
+
+func TestWorker_PersistsProbesAndTransitions(t *testing.T) {
+	store := &memStore{}
+	w := NewWorkerWithOptions(nil, nil, NewCache(), DefaultConfig(), WorkerOptions{Store: store}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inst := consul.Instance{ServiceID: "svc-1", ServiceName: "api"}
+
+	w.cache.Update("svc-1", "api", "", 0, StatusHealthy, "http", "", nil)
+	for _, status := range []HealthStatus{StatusHealthy, StatusHealthy, StatusDegraded} {
+		w.persist(inst, w.cache.PreviousStatus("svc-1"), ProbeResult{Status: status})
+		w.cache.Update("svc-1", "api", "", 0, status, "http", "", nil)
+	}
+	if len(store.probes) != 0 {
+		t.Fatal("expected writes to wait for the store writer")
+	}
+	for len(w.storeQueue) > 0 {
+		w.save(context.Background(), <-w.storeQueue)
+	}
+
+	if len(store.probes) != 3 {
+		t.Fatalf("expected 3 stored probes, got %d", len(store.probes))
+	}
+	if len(store.transitions) != 1 || store.transitions[0].Previous != StatusHealthy || store.transitions[0].Current != StatusDegraded {
+		t.Fatalf("expected one Healthy -> Degraded transition, got %+v", store.transitions)
+	}
+}
+
+func TestWorker_PersistDropsWhenQueueFull(t *testing.T) {
+	w := NewWorkerWithOptions(nil, nil, NewCache(), DefaultConfig(), WorkerOptions{Store: &memStore{}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	inst := consul.Instance{ServiceID: "svc-1", ServiceName: "api"}
+
+	// Nothing drains the queue, so the probe past its size must not block.
+	for range storeQueueSize + 1 {
+		w.persist(inst, StatusHealthy, ProbeResult{Status: StatusHealthy})
+	}
+	if len(w.storeQueue) != storeQueueSize {
+		t.Fatalf("expected a full queue of %d, got %d", storeQueueSize, len(w.storeQueue))
+	}
+}
@@ -0,0 +1,61 @@
+package healthmonitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// tlsProbe completes a TLS handshake with the instance, verifying its
+// certificate chain against the configured roots and server name. It reports
+// Degraded when any certificate in the chain expires within
+// TLSExpiryThreshold, so an expiring certificate is flagged while the
+// instance still serves traffic.
+func (w *Worker) tlsProbe(ctx context.Context, inst types.Instance, portStr string) (HealthStatus, string) {
+	serverName := inst.Metadata["tls_server_name"]
+	if serverName == "" {
+		serverName = inst.Address
+	}
+
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: w.config.TLSTimeout},
+		Config: &tls.Config{
+			ServerName: serverName,
+			RootCAs:    w.config.TLSRootCAs,
+		},
+	}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(inst.Address, portStr))
+	if err != nil {
+		return StatusUnhealthy, fmt.Sprintf("TLS handshake failed: %v", err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	return certExpiryStatus(certs, time.Now(), w.config.TLSExpiryThreshold)
+}
+
+// certExpiryStatus grades a verified chain by its earliest expiry.
+func certExpiryStatus(certs []*x509.Certificate, now time.Time, threshold time.Duration) (HealthStatus, string) {
+	if len(certs) == 0 {
+		return StatusUnhealthy, "TLS handshake returned no certificates"
+	}
+	earliest := certs[0]
+	for _, c := range certs[1:] {
+		if c.NotAfter.Before(earliest.NotAfter) {
+			earliest = c
+		}
+	}
+
+	remaining := earliest.NotAfter.Sub(now)
+	msg := fmt.Sprintf("certificate %q expires %s (in %s)",
+		earliest.Subject.CommonName, earliest.NotAfter.UTC().Format(time.RFC3339), remaining.Truncate(time.Hour))
+	if remaining < threshold {
+		return StatusDegraded, msg
+	}
+	return StatusHealthy, msg
+}
@@ -0,0 +1,83 @@
+package healthmonitor
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+func TestWorker_TLSProbe(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(ts.Certificate())
+	parts := strings.SplitN(ts.Listener.Addr().String(), ":", 2)
+
+	tests := []struct {
+		name       string
+		roots      *x509.CertPool
+		threshold  time.Duration
+		serverName string
+		want       HealthStatus
+		wantMsg    string
+	}{
+		{"valid", trusted, 14 * 24 * time.Hour, "", StatusHealthy, "expires"},
+		{"expiring within threshold", trusted, 200 * 365 * 24 * time.Hour, "", StatusDegraded, "expires"},
+		{"untrusted", nil, 14 * 24 * time.Hour, "", StatusUnhealthy, "TLS handshake failed"},
+		{"name mismatch", trusted, 14 * 24 * time.Hour, "orders.internal", StatusUnhealthy, "TLS handshake failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TLSRootCAs = tt.roots
+			cfg.TLSExpiryThreshold = tt.threshold
+			w := &Worker{config: cfg}
+
+			inst := consul.Instance{
+				ServiceID:   "svc-1",
+				ServiceName: "api",
+				Address:     parts[0],
+				Metadata:    map[string]string{"tls_port": parts[1]},
+			}
+			if tt.serverName != "" {
+				inst.Metadata["tls_server_name"] = tt.serverName
+			}
+
+			status, probeType, msg := w.runProbes(context.Background(), inst)
+			if probeType != "tls" {
+				t.Fatalf("expected probe type tls, got %q", probeType)
+			}
+			if status != tt.want {
+				t.Fatalf("expected %v, got %v (%s)", tt.want, status, msg)
+			}
+			if !strings.Contains(msg, tt.wantMsg) {
+				t.Fatalf("expected message to contain %q, got %q", tt.wantMsg, msg)
+			}
+		})
+	}
+}
+
+func TestCertExpiryStatus_UsesEarliestExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "orders"}, NotAfter: now.Add(90 * 24 * time.Hour)}
+	intermediate := &x509.Certificate{Subject: pkix.Name{CommonName: "issuing-ca"}, NotAfter: now.Add(10 * 24 * time.Hour)}
+
+	status, msg := certExpiryStatus([]*x509.Certificate{leaf, intermediate}, now, 14*24*time.Hour)
+	if status != StatusDegraded || !strings.Contains(msg, `"issuing-ca"`) {
+		t.Fatalf("expected Degraded on the intermediate, got %v (%s)", status, msg)
+	}
+	if status, _ := certExpiryStatus([]*x509.Certificate{leaf}, now, 14*24*time.Hour); status != StatusHealthy {
+		t.Fatalf("expected Healthy, got %v", status)
+	}
+	if status, _ := certExpiryStatus(nil, now, 0); status != StatusUnhealthy {
+		t.Fatalf("expected Unhealthy without certificates, got %v", status)
+	}
+}
@@ -0,0 +1,84 @@
+package healthmonitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// maxUDPResponse bounds the datagram read from a UDP probe.
+const maxUDPResponse = 64 * 1024
+
+// udpProbe sends the datagram in metadata udp_payload to udp_port and waits
+// up to UDPTimeout for a reply. With udp_expect set, the reply must contain
+// it; otherwise any reply, or none, is Healthy, and only an ICMP port
+// unreachable (reported as a refused connection) is Unhealthy. Payload and
+// expectation are literal strings, or hex-encoded bytes when prefixed with
+// "hex:", so binary protocols such as DNS can be probed.
+func (w *Worker) udpProbe(ctx context.Context, inst types.Instance, portStr string) (HealthStatus, string) {
+	payload, err := decodeUDPBytes(inst.Metadata["udp_payload"])
+	if err != nil {
+		return StatusUnknown, fmt.Sprintf("invalid udp_payload: %v", err)
+	}
+	expect, err := decodeUDPBytes(inst.Metadata["udp_expect"])
+	if err != nil {
+		return StatusUnknown, fmt.Sprintf("invalid udp_expect: %v", err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", net.JoinHostPort(inst.Address, portStr))
+	if err != nil {
+		return StatusUnhealthy, fmt.Sprintf("UDP dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(w.config.UDPTimeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(payload); err != nil {
+		return StatusUnhealthy, fmt.Sprintf("UDP send failed: %v", err)
+	}
+
+	buf := make([]byte, maxUDPResponse)
+	n, err := conn.Read(buf)
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StatusUnhealthy, "UDP port unreachable"
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		if expect != nil {
+			return StatusUnhealthy, "no UDP response"
+		}
+		return StatusHealthy, "no UDP response, port not reported unreachable"
+	case err != nil:
+		return StatusUnhealthy, fmt.Sprintf("UDP receive failed: %v", err)
+	}
+
+	if expect != nil && !bytes.Contains(buf[:n], expect) {
+		return StatusUnhealthy, fmt.Sprintf("UDP response of %d bytes does not match udp_expect", n)
+	}
+	return StatusHealthy, fmt.Sprintf("UDP response of %d bytes", n)
+}
+
+// decodeUDPBytes decodes a udp_payload or udp_expect value. Empty values
+// decode to nil.
+func decodeUDPBytes(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if h, ok := strings.CutPrefix(s, "hex:"); ok {
+		return hex.DecodeString(h)
+	}
+	return []byte(s), nil
+}
@@ -0,0 +1,90 @@
+package healthmonitor
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+)
+
+// udpEcho answers every datagram with "pong:" and the datagram.
+func udpEcho(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(append([]byte("pong:"), buf[:n]...), addr)
+		}
+	}()
+	return strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+// udpSilent reads datagrams without answering.
+func udpSilent(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func TestWorker_UDPProbe(t *testing.T) {
+	echo, silent := udpEcho(t), udpSilent(t)
+
+	// A port nothing listens on any more.
+	closedConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := strconv.Itoa(closedConn.LocalAddr().(*net.UDPAddr).Port)
+	closedConn.Close()
+
+	tests := []struct {
+		name    string
+		meta    map[string]string
+		want    HealthStatus
+		wantMsg string
+	}{
+		{"response matches", map[string]string{"udp_port": echo, "udp_payload": "ping", "udp_expect": "pong:ping"}, StatusHealthy, "response of 9 bytes"},
+		{"hex payload", map[string]string{"udp_port": echo, "udp_payload": "hex:0001", "udp_expect": "hex:706f6e673a0001"}, StatusHealthy, "response"},
+		{"response mismatch", map[string]string{"udp_port": echo, "udp_payload": "ping", "udp_expect": "ok"}, StatusUnhealthy, "does not match"},
+		{"no response expected", map[string]string{"udp_port": silent, "udp_payload": "ping"}, StatusHealthy, "no UDP response"},
+		{"no response with expectation", map[string]string{"udp_port": silent, "udp_payload": "ping", "udp_expect": "pong"}, StatusUnhealthy, "no UDP response"},
+		{"port unreachable", map[string]string{"udp_port": closed, "udp_payload": "ping"}, StatusUnhealthy, "unreachable"},
+		{"invalid hex", map[string]string{"udp_port": echo, "udp_payload": "hex:zz"}, StatusUnknown, "invalid udp_payload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.UDPTimeout = 200 * time.Millisecond
+			w := &Worker{config: cfg}
+
+			inst := consul.Instance{ServiceID: "dns-1", ServiceName: "dns", Address: "127.0.0.1", Metadata: tt.meta}
+			status, probeType, msg := w.runProbes(context.Background(), inst)
+			if probeType != "udp" {
+				t.Fatalf("expected probe type udp, got %q", probeType)
+			}
+			if status != tt.want {
+				t.Fatalf("expected %v, got %v (%s)", tt.want, status, msg)
+			}
+			if !strings.Contains(msg, tt.wantMsg) {
+				t.Fatalf("expected message to contain %q, got %q", tt.wantMsg, msg)
+			}
+		})
+	}
+}
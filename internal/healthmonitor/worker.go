@@ -3,44 +3,120 @@ package healthmonitor
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/toska-mesh/toska-mesh/internal/consul"
 	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+	"github.com/toska-mesh/toska-mesh/internal/watchdog"
+	"github.com/toska-mesh/toska-mesh/pkg/healthprobe"
 )
 
-// Worker is the background health probe service. It periodically queries
-// Consul for registered services, probes each instance via HTTP or TCP,
-// and caches the results.
+// Worker is the background health probe service. It tracks registered
+// services by watching or periodically listing the registry, probes each
+// instance via HTTP, TLS, UDP, TCP, a command, or a custom
+// healthprobe.Prober, and caches the results.
 type Worker struct {
-	registry  *consul.Registry
+	registry  registry.Registry
 	publisher *messaging.Publisher
 	cache     *Cache
+	watchdog  *watchdog.Watchdog
+	telemetry telemetry.Sink
 	config    Config
 	logger    *slog.Logger
 	client    *http.Client
-
-	mu       sync.Mutex
-	breakers map[string]*CircuitBreaker
+	store     Store
+	alerts    *messaging.WebhookNotifier
+	probers   []prober
+	cluster   *cluster          // nil unless Config.Cluster is enabled
+	catalog   *registry.Catalog // nil unless watching; set by Run
+
+	storeQueue chan storeRecord // nil without a store; drained by writeStore
+
+	mu             sync.Mutex
+	breakers       map[string]*CircuitBreaker
+	detected       map[string]detection // keyed by service ID
+	detectAttempts map[string]time.Time
+	slowProbes     map[string]int // consecutive slow probes, keyed by service ID
+	alerting       map[string]*alertState
+	flaps          map[string]*flapState
+	schedule       map[string]*probeSchedule
+	deferred       map[string]bool          // instances the last cycle left unprobed
+	matchers       map[string]*bodyMatchers // compiled body checks, keyed by service ID
+	composites     map[string]HealthStatus  // last composite status, keyed by service name
+
+	// listed holds the instance IDs of each service's last successful
+	// listing; only the probe loop touches it.
+	listed map[string][]string
 }
 
 // NewWorker creates a HealthMonitor probe worker.
-func NewWorker(registry *consul.Registry, publisher *messaging.Publisher, cache *Cache, config Config, logger *slog.Logger) *Worker {
-	return &Worker{
+func NewWorker(registry registry.Registry, publisher *messaging.Publisher, cache *Cache, config Config, logger *slog.Logger) *Worker {
+	return NewWorkerWithOptions(registry, publisher, cache, config, WorkerOptions{}, logger)
+}
+
+// WorkerOptions holds optional worker dependencies. Zero values disable the
+// corresponding feature.
+type WorkerOptions struct {
+	// Watchdog, if set, makes the worker skip probe cycles while the process
+	// is overloaded.
+	Watchdog *watchdog.Watchdog
+	// Telemetry receives probe metrics.
+	Telemetry telemetry.Sink
+	// Store persists probe results and status transitions. Writes are
+	// queued and made off the probe path once Run starts.
+	Store Store
+	// Alerts, if set, is notified when an instance becomes Unhealthy and
+	// when it recovers, subject to Config.AlertRules.
+	Alerts *messaging.WebhookNotifier
+	// Probers are custom probes tried, in order, before the built-in ones.
+	Probers []healthprobe.Prober
+}
+
+// NewWorkerWithOptions creates a probe worker with optional dependencies.
+func NewWorkerWithOptions(registry registry.Registry, publisher *messaging.Publisher, cache *Cache, config Config, opts WorkerOptions, logger *slog.Logger) *Worker {
+	w := &Worker{
 		registry:  registry,
 		publisher: publisher,
 		cache:     cache,
+		watchdog:  opts.Watchdog,
+		telemetry: telemetry.OrNop(opts.Telemetry),
+		store:     opts.Store,
+		alerts:    opts.Alerts,
 		config:    config,
 		logger:    logger,
 		client: &http.Client{
 			Timeout: config.HTTPTimeout,
 		},
-		breakers: make(map[string]*CircuitBreaker),
+		breakers:       make(map[string]*CircuitBreaker),
+		detected:       make(map[string]detection),
+		detectAttempts: make(map[string]time.Time),
+		slowProbes:     make(map[string]int),
+		alerting:       make(map[string]*alertState),
+		flaps:          make(map[string]*flapState),
+		schedule:       make(map[string]*probeSchedule),
+		deferred:       make(map[string]bool),
+		matchers:       make(map[string]*bodyMatchers),
+		listed:         make(map[string][]string),
 	}
+	for _, p := range opts.Probers {
+		w.probers = append(w.probers, customProber(p))
+	}
+	w.probers = append(w.probers, w.builtinProbers()...)
+	if opts.Store != nil {
+		w.storeQueue = make(chan storeRecord, storeQueueSize)
+	}
+	if config.Cluster.Enabled {
+		w.cluster = newCluster(registry, config.Cluster, config.ProbeInterval, logger)
+	}
+	return w
 }
 
 // Run starts the probe loop. It blocks until ctx is cancelled.
@@ -48,7 +124,22 @@ func (w *Worker) Run(ctx context.Context) {
 	w.logger.Info("health probe worker starting",
 		"probe_interval", w.config.ProbeInterval,
 		"failure_threshold", w.config.FailureThreshold,
+		"spread_probes", w.config.SpreadProbes,
+		"clustered", w.cluster != nil,
 	)
+	if w.cluster != nil {
+		defer w.cluster.leave()
+	}
+	if w.storeQueue != nil {
+		go w.writeStore(ctx)
+	}
+	if watcher, ok := w.registry.(registry.Watcher); ok && w.config.WatchEnabled {
+		w.catalog = registry.WatchCatalog(ctx, watcher, w.config.WatchWaitTime, nil)
+	}
+	if w.config.SpreadProbes {
+		w.runSpread(ctx)
+		return
+	}
 
 	ticker := time.NewTicker(w.config.ProbeInterval)
 	defer ticker.Stop()
@@ -62,99 +153,292 @@ func (w *Worker) Run(ctx context.Context) {
 			w.logger.Info("health probe worker stopping")
 			return
 		case <-ticker.C:
+			if w.watchdog.Overloaded() {
+				w.logger.Warn("skipping probe cycle, process overloaded")
+				w.telemetry.Count("healthmonitor_skipped_cycles_total", 1, nil)
+				continue
+			}
 			w.probeAll(ctx)
 		}
 	}
 }
 
+// probeAll probes every registered instance at once.
 func (w *Worker) probeAll(ctx context.Context) {
-	services, err := w.registry.GetServices()
+	// At most MaxConcurrentProbes registry lookups, then probes, run at
+	// once, however large the mesh.
+	sem := make(chan struct{}, max(w.config.MaxConcurrentProbes, 1))
+	targets, liveIDs, err := w.listTargets(sem)
 	if err != nil {
 		w.logger.Error("failed to list services", "error", err)
 		return
 	}
+	w.runProbeTargets(ctx, targets, sem)
+	w.evict(liveIDs)
+	w.flushResolutions(time.Now())
+	w.checkComposites()
+}
 
-	// Collect all live service IDs so we can evict stale cache entries.
-	var liveIDsMu sync.Mutex
-	liveIDs := make(map[string]struct{})
+// listTargets lists the instances of every registered service, or when
+// clustered of the services assigned to this replica. They are taken from
+// the watched catalog once it has synced, and otherwise listed from the
+// registry using sem to bound concurrent lookups. liveIDs holds the ID of
+// every instance listed, and of the instances a service had at its last
+// successful listing when listing it fails, so a transient registry error
+// does not evict them.
+func (w *Worker) listTargets(sem chan struct{}) (targets []probeTarget, liveIDs map[string]struct{}, err error) {
+	if w.catalog != nil {
+		if snapshot, ok := w.catalog.Snapshot(); ok {
+			if w.cluster != nil {
+				w.cluster.refresh()
+			}
+			liveIDs = make(map[string]struct{})
+			for serviceName, instances := range snapshot {
+				if !w.probes(serviceName) {
+					continue
+				}
+				for _, inst := range instances {
+					liveIDs[inst.ServiceID] = struct{}{}
+					targets = append(targets, probeTarget{inst: inst, registered: len(instances)})
+				}
+			}
+			return targets, liveIDs, nil
+		}
+	}
+
+	services, err := w.registry.GetServices()
+	if err != nil {
+		return nil, nil, err
+	}
+	if w.cluster != nil {
+		w.cluster.refresh()
+	}
 
-	// Fan out at the service level so slow services don't block others.
-	var svcWg sync.WaitGroup
+	var (
+		mu     sync.Mutex
+		listed = make(map[string][]string, len(services))
+		failed []string
+	)
+	liveIDs = make(map[string]struct{})
+
+	var wg sync.WaitGroup
 	for _, serviceName := range services {
-		svcWg.Add(1)
-		go func(serviceName string) {
-			defer svcWg.Done()
+		if !w.probes(serviceName) {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
 
 			instances, err := w.registry.GetInstances(serviceName)
 			if err != nil {
 				w.logger.Error("failed to list instances", "service", serviceName, "error", err)
+				mu.Lock()
+				failed = append(failed, serviceName)
+				mu.Unlock()
 				return
 			}
 
-			liveIDsMu.Lock()
-			for _, inst := range instances {
+			mu.Lock()
+			defer mu.Unlock()
+			ids := make([]string, len(instances))
+			for i, inst := range instances {
+				ids[i] = inst.ServiceID
 				liveIDs[inst.ServiceID] = struct{}{}
+				targets = append(targets, probeTarget{inst: inst, registered: len(instances)})
 			}
-			liveIDsMu.Unlock()
-
-			// Fan out: probe all instances concurrently.
-			var instWg sync.WaitGroup
-			for _, inst := range instances {
-				instWg.Add(1)
-				go func(inst consul.Instance) {
-					defer instWg.Done()
-					w.probeInstance(ctx, inst)
-				}(inst)
+			listed[serviceName] = ids
+		}()
+	}
+	wg.Wait()
+
+	// Keep the last listing of services that failed to list until one
+	// succeeds.
+	for _, serviceName := range failed {
+		if ids, ok := w.listed[serviceName]; ok {
+			listed[serviceName] = ids
+			for _, id := range ids {
+				liveIDs[id] = struct{}{}
 			}
-			instWg.Wait()
-		}(serviceName)
+		}
 	}
-	svcWg.Wait()
+	w.listed = listed
+	return targets, liveIDs, nil
+}
+
+// probes reports whether this replica probes a service. Services other
+// replicas probe, and the replicas themselves, are left out, and so evicted
+// from this replica's cache.
+func (w *Worker) probes(serviceName string) bool {
+	return w.cluster == nil || (serviceName != w.cluster.config.Service && w.cluster.owns(serviceName))
+}
 
-	// Evict cache entries for services no longer registered in Consul.
+// evict drops cached results and per-instance state for instances no longer
+// registered.
+func (w *Worker) evict(liveIDs map[string]struct{}) {
 	for _, cached := range w.cache.GetAll() {
 		if _, ok := liveIDs[cached.ServiceID]; !ok {
 			w.cache.Remove(cached.ServiceID)
+			w.forgetInstance(cached.ServiceID, cached.ServiceName)
 		}
 	}
+
+	w.mu.Lock()
+	for id := range w.detectAttempts {
+		if _, ok := liveIDs[id]; !ok {
+			delete(w.detectAttempts, id)
+			delete(w.detected, id)
+		}
+	}
+	for id := range w.slowProbes {
+		if _, ok := liveIDs[id]; !ok {
+			delete(w.slowProbes, id)
+		}
+	}
+	for id := range w.matchers {
+		if _, ok := liveIDs[id]; !ok {
+			delete(w.matchers, id)
+		}
+	}
+	for id := range w.alerting {
+		if _, ok := liveIDs[id]; !ok {
+			w.dropAlert(id)
+		}
+	}
+	for id := range w.flaps {
+		if _, ok := liveIDs[id]; !ok {
+			delete(w.flaps, id)
+		}
+	}
+	for id := range w.schedule {
+		if _, ok := liveIDs[id]; !ok {
+			delete(w.schedule, id)
+		}
+	}
+	w.mu.Unlock()
+}
+
+// probeTarget is an instance due for a probe, with the number of instances
+// of its service currently registered.
+type probeTarget struct {
+	inst       types.Instance
+	registered int
+}
+
+// runProbeTargets probes targets using sem to bound concurrency. Probes are
+// dispatched until the cycle deadline, ProbeCycleTimeout or else the probe
+// interval; instances not reached by then go first in the next cycle, so a
+// cycle that always overruns still probes every instance in turn. Probes
+// already running are not cut short, so a slow cycle never reports healthy
+// instances as Unhealthy.
+func (w *Worker) runProbeTargets(ctx context.Context, targets []probeTarget, sem chan struct{}) {
+	w.mu.Lock()
+	if len(w.deferred) > 0 {
+		ordered := make([]probeTarget, 0, len(targets))
+		for _, first := range []bool{true, false} {
+			for _, t := range targets {
+				if w.deferred[t.inst.ServiceID] == first {
+					ordered = append(ordered, t)
+				}
+			}
+		}
+		targets = ordered
+		clear(w.deferred)
+	}
+	w.mu.Unlock()
+
+	timeout := w.config.ProbeCycleTimeout
+	if timeout <= 0 {
+		timeout = w.config.ProbeInterval
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-deadline.C:
+			w.deferProbes(targets[i:], timeout)
+			wg.Wait()
+			return
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			w.probeInstance(ctx, t.inst, t.registered)
+		}()
+	}
+	wg.Wait()
+}
+
+// deferProbes records instances left unprobed when a cycle hits its
+// deadline, so the next cycle probes them first.
+func (w *Worker) deferProbes(targets []probeTarget, timeout time.Duration) {
+	w.mu.Lock()
+	for _, t := range targets {
+		w.deferred[t.inst.ServiceID] = true
+	}
+	w.mu.Unlock()
+
+	w.logger.Warn("probe cycle deadline reached, deferring remaining probes",
+		"deferred", len(targets),
+		"timeout", timeout,
+		"max_concurrent_probes", w.config.MaxConcurrentProbes,
+	)
+	for _, t := range targets {
+		w.telemetry.Count("healthmonitor_probes_deferred_total", 1, telemetry.Labels{"service": t.inst.ServiceName})
+	}
 }
 
-func (w *Worker) probeInstance(ctx context.Context, inst consul.Instance) {
+// probeInstance probes one instance. registered is the number of instances of
+// the service currently registered in Consul.
+func (w *Worker) probeInstance(ctx context.Context, inst types.Instance, registered int) {
 	breaker := w.getBreaker(inst.ServiceID)
 
 	if !breaker.Allow() {
-		w.updateStatus(ctx, inst, StatusUnhealthy, "circuit-breaker", "Circuit open due to repeated failures")
+		w.updateStatus(ctx, inst, registered, StatusUnhealthy, "circuit-breaker", "Circuit open due to repeated failures", 0)
 		return
 	}
 
+	start := time.Now()
 	status, probeType, message := w.runProbes(ctx, inst)
+	latency := time.Since(start)
+	w.telemetry.Observe("healthmonitor_probe_duration_seconds", latency.Seconds(), telemetry.Labels{"service": inst.ServiceName, "probe_type": probeType})
+	status, message = w.gradeLatency(inst.ServiceID, status, message, latency)
 
-	if status == StatusHealthy {
+	// Degraded instances still answer, e.g. with an expiring certificate,
+	// so they do not count towards opening the circuit.
+	if status == StatusHealthy || status == StatusDegraded {
 		breaker.RecordSuccess()
 	} else {
 		breaker.RecordFailure()
 	}
 
-	w.updateStatus(ctx, inst, status, probeType, message)
+	w.updateStatus(ctx, inst, registered, status, probeType, message, latency)
 }
 
-func (w *Worker) runProbes(ctx context.Context, inst consul.Instance) (HealthStatus, string, string) {
-	// Try HTTP probe first.
-	if endpoint, ok := inst.Metadata["health_check_endpoint"]; ok && endpoint != "" {
-		status, msg := w.httpProbe(ctx, inst, endpoint)
-		return status, "http", msg
+// runProbes runs the first prober that applies to the instance: custom
+// probers in the order given, then the built-in ones. Workers not built by
+// NewWorkerWithOptions only have the built-in probers.
+func (w *Worker) runProbes(ctx context.Context, inst types.Instance) (HealthStatus, string, string) {
+	probers := w.probers
+	if probers == nil {
+		probers = w.builtinProbers()
 	}
-
-	// Fall back to TCP probe.
-	if portStr, ok := inst.Metadata["tcp_port"]; ok && portStr != "" {
-		status, msg := w.tcpProbe(ctx, inst, portStr)
-		return status, "tcp", msg
+	for _, p := range probers {
+		if status, msg, ok := p.Probe(ctx, inst); ok {
+			return status, p.Name(), msg
+		}
 	}
-
 	return StatusUnknown, "none", "No probe configuration available"
 }
 
-func (w *Worker) httpProbe(ctx context.Context, inst consul.Instance, endpoint string) (HealthStatus, string) {
+func (w *Worker) httpProbe(ctx context.Context, inst types.Instance, endpoint string) (HealthStatus, string) {
 	scheme := "http"
 	if s, ok := inst.Metadata["scheme"]; ok && s != "" {
 		scheme = s
@@ -162,7 +446,27 @@ func (w *Worker) httpProbe(ctx context.Context, inst consul.Instance, endpoint s
 
 	url := fmt.Sprintf("%s://%s:%d%s", scheme, inst.Address, inst.Port, endpoint)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	w.mu.Lock()
+	cached := w.matchers[inst.ServiceID]
+	w.mu.Unlock()
+	probe, err := parseHTTPProbeConfig(inst.Metadata, cached)
+	if err != nil {
+		return StatusUnknown, fmt.Sprintf("invalid probe configuration: %v", err)
+	}
+	if probe.matchers != cached {
+		w.mu.Lock()
+		if w.matchers == nil {
+			w.matchers = make(map[string]*bodyMatchers)
+		}
+		w.matchers[inst.ServiceID] = probe.matchers
+		w.mu.Unlock()
+	}
+
+	var body io.Reader
+	if probe.body != "" {
+		body = strings.NewReader(probe.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, probe.method, url, body)
 	if err != nil {
 		return StatusUnhealthy, fmt.Sprintf("request error: %v", err)
 	}
@@ -170,20 +474,48 @@ func (w *Worker) httpProbe(ctx context.Context, inst consul.Instance, endpoint s
 	for k, v := range w.config.HTTPHeaders {
 		req.Header.Set(k, v)
 	}
+	for k, v := range probe.headers {
+		req.Header.Set(k, v)
+	}
 
-	resp, err := w.client.Do(req)
+	// A registrant's timeout may not outlast the interval, or its probes
+	// would hold workers other instances are waiting for.
+	client := w.client
+	if probe.timeout > 0 {
+		c := *w.client
+		c.Timeout = probe.timeout
+		if interval := w.config.ProbeInterval; interval > 0 {
+			c.Timeout = min(c.Timeout, interval)
+		}
+		client = &c
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return StatusUnhealthy, fmt.Sprintf("probe failed: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return StatusHealthy, fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if !probe.healthy(resp.StatusCode) {
+		return StatusUnhealthy, fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	if probe.checksBody() {
+		b, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody+1))
+		if err != nil {
+			return StatusUnhealthy, fmt.Sprintf("HTTP %d, reading body: %v", resp.StatusCode, err)
+		}
+		truncated := len(b) > maxProbeBody
+		if truncated {
+			b = b[:maxProbeBody]
+		}
+		if err := probe.checkBody(b, truncated); err != nil {
+			return StatusUnhealthy, fmt.Sprintf("HTTP %d, %v", resp.StatusCode, err)
+		}
 	}
-	return StatusUnhealthy, fmt.Sprintf("HTTP %d", resp.StatusCode)
+	return StatusHealthy, fmt.Sprintf("HTTP %d", resp.StatusCode)
 }
 
-func (w *Worker) tcpProbe(ctx context.Context, inst consul.Instance, portStr string) (HealthStatus, string) {
+func (w *Worker) tcpProbe(ctx context.Context, inst types.Instance, portStr string) (HealthStatus, string) {
 	addr := net.JoinHostPort(inst.Address, portStr)
 
 	var d net.Dialer
@@ -198,27 +530,179 @@ func (w *Worker) tcpProbe(ctx context.Context, inst consul.Instance, portStr str
 	return StatusHealthy, "TCP connection successful"
 }
 
-func (w *Worker) updateStatus(ctx context.Context, inst consul.Instance, status HealthStatus, probeType, message string) {
+func (w *Worker) updateStatus(ctx context.Context, inst types.Instance, registered int, status HealthStatus, probeType, message string, latency time.Duration) {
 	previousStatus := w.cache.PreviousStatus(inst.ServiceID)
+	w.telemetry.Count("healthmonitor_probes_total", 1, telemetry.Labels{
+		"service": inst.ServiceName,
+		"status":  status.String(),
+	})
+	if status == StatusUnhealthy {
+		w.telemetry.Count("healthmonitor_probe_errors_total", 1, telemetry.Labels{
+			"service":    inst.ServiceName,
+			"probe_type": probeType,
+		})
+	}
+	w.reportInstance(inst.ServiceID, inst.ServiceName, status, w.getBreaker(inst.ServiceID).State())
+
+	result := ProbeResult{
+		Time:      time.Now().UTC(),
+		Status:    status,
+		ProbeType: probeType,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		Message:   message,
+	}
+	// Settle the flapping state first, so the cache records it with the
+	// result in one change.
+	from, changed := w.flapEvent(inst, previousStatus, status, result.Time)
 
-	w.cache.Update(
-		inst.ServiceID, inst.ServiceName,
-		inst.Address, inst.Port,
-		status, probeType, message,
-		inst.Metadata,
-	)
-
-	// Publish health change event if status transitioned.
-	if previousStatus != status && previousStatus != StatusUnknown {
-		_ = w.publisher.Publish(ctx, messaging.ServiceHealthChangedEvent{
-			EventID:           fmt.Sprintf("%d", time.Now().UnixNano()),
-			Timestamp:         time.Now().UTC(),
-			ServiceID:         inst.ServiceID,
-			ServiceName:       inst.ServiceName,
-			PreviousStatus:    previousStatus.String(),
-			CurrentStatus:     status.String(),
-			HealthCheckOutput: message,
+	w.mu.Lock()
+	d := w.detected[inst.ServiceID]
+	w.mu.Unlock()
+	w.cache.UpdateProbe(MonitoredInstance{
+		ServiceID:        inst.ServiceID,
+		ServiceName:      inst.ServiceName,
+		Address:          inst.Address,
+		Port:             inst.Port,
+		Status:           status,
+		LastProbe:        result.Time,
+		ProbeType:        probeType,
+		Message:          message,
+		Metadata:         inst.Metadata,
+		LatencyMs:        result.LatencyMs,
+		DetectedScheme:   d.scheme,
+		DetectedEndpoint: d.endpoint,
+		Flapping:         w.isFlapping(inst.ServiceID),
+	})
+	w.cache.RecordProbe(inst.ServiceID, result)
+	w.persist(inst, previousStatus, result)
+	w.reportHealth(inst, result)
+
+	// Publish health change event if status transitioned, unless the
+	// instance is flapping.
+	if changed {
+		w.telemetry.Count("healthmonitor_status_changes_total", 1, telemetry.Labels{
+			"service": inst.ServiceName,
+			"status":  status.String(),
 		})
+		_ = w.publisher.Publish(ctx, w.healthChangedEvent(inst, registered, from, status, message, latency))
+	}
+	if !w.isFlapping(inst.ServiceID) {
+		w.evaluateAlert(inst, registered, previousStatus, status, message, latency, result.Time)
+	}
+}
+
+// storeQueueSize bounds the records waiting for the store.
+const storeQueueSize = 1024
+
+// storeRecord is a probe result, and the transition it makes if any, waiting
+// to be written to the store.
+type storeRecord struct {
+	serviceID   string
+	serviceName string
+	result      ProbeResult
+	transition  *Transition
+}
+
+// persist queues a probe result, and the transition it makes, for the store.
+// Unlike events, the first status after a restart is saved as a transition
+// from Unknown, so availability reports show when monitoring resumed. It
+// never blocks the probe; a full queue drops the record.
+func (w *Worker) persist(inst types.Instance, previous HealthStatus, result ProbeResult) {
+	if w.storeQueue == nil {
+		return
+	}
+	rec := storeRecord{serviceID: inst.ServiceID, serviceName: inst.ServiceName, result: result}
+	if previous != result.Status {
+		rec.transition = &Transition{
+			Time:        result.Time,
+			ServiceID:   inst.ServiceID,
+			ServiceName: inst.ServiceName,
+			Previous:    previous,
+			Current:     result.Status,
+			Message:     result.Message,
+		}
+	}
+	select {
+	case w.storeQueue <- rec:
+	default:
+		w.telemetry.Count("healthmonitor_store_dropped_total", 1, nil)
+		w.logger.Warn("store queue full, dropping probe result", "service_id", inst.ServiceID)
+	}
+}
+
+// writeStore writes queued records to the store until ctx is cancelled.
+func (w *Worker) writeStore(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rec := <-w.storeQueue:
+			w.save(ctx, rec)
+		}
+	}
+}
+
+func (w *Worker) save(ctx context.Context, rec storeRecord) {
+	if err := w.store.SaveProbe(ctx, rec.serviceID, rec.serviceName, rec.result); err != nil {
+		w.logger.Warn("failed to store probe result", "service_id", rec.serviceID, "error", err)
+	}
+	if rec.transition == nil {
+		return
+	}
+	if err := w.store.SaveTransition(ctx, *rec.transition); err != nil {
+		w.logger.Warn("failed to store status transition", "service_id", rec.serviceID, "error", err)
+	}
+}
+
+// autoRenewMetadataKey marks instances whose TTL checks discovery renews
+// from this monitor's results (discovery.AutoRenewMetadataKey).
+const autoRenewMetadataKey = "ttl_auto_renew"
+
+// reportHealth feeds a probe result back into the registry's TTL check when
+// ReportHealth is set, so instances that never report their own health are
+// still routed by it. The registry reports through the agent that owns the
+// instance, whichever node it is on. Unknown results are not reported, and
+// instances renewed by discovery are left to it.
+func (w *Worker) reportHealth(inst types.Instance, result ProbeResult) {
+	if !w.config.ReportHealth || w.registry == nil {
+		return
+	}
+	if result.Status == StatusUnknown || inst.Metadata[autoRenewMetadataKey] == "true" {
+		return
+	}
+	output := fmt.Sprintf("Reported by healthmonitor: %s probe", result.ProbeType)
+	if result.Message != "" {
+		output += ", " + result.Message
+	}
+	outcome := "ok"
+	if err := w.registry.UpdateHealth(inst.ServiceID, result.Status, output); err != nil {
+		outcome = "error"
+		w.logger.Warn("failed to report health to registry", "service_id", inst.ServiceID, "error", err)
+	}
+	w.telemetry.Count("healthmonitor_registry_reports_total", 1, telemetry.Labels{
+		"service": inst.ServiceName,
+		"result":  outcome,
+	})
+}
+
+// healthChangedEvent builds the transition event, including the service's
+// healthy/total instance counts as seen by the cache after this probe.
+func (w *Worker) healthChangedEvent(inst types.Instance, registered int, previous, current HealthStatus, message string, latency time.Duration) messaging.ServiceHealthChangedEvent {
+	healthy, total := w.cache.ServiceCounts(inst.ServiceName)
+	// Instances not probed yet in this cycle are missing from the cache.
+	total = max(total, registered)
+
+	return messaging.ServiceHealthChangedEvent{
+		EventID:           fmt.Sprintf("%d", time.Now().UnixNano()),
+		Timestamp:         time.Now().UTC(),
+		ServiceID:         inst.ServiceID,
+		ServiceName:       inst.ServiceName,
+		PreviousStatus:    previous.String(),
+		CurrentStatus:     current.String(),
+		HealthCheckOutput: message,
+		HealthyInstances:  healthy,
+		TotalInstances:    total,
+		ProbeLatencyMs:    float64(latency.Microseconds()) / 1000,
 	}
 }
 
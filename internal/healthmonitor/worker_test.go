@@ -3,6 +3,8 @@ package healthmonitor
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,6 +12,9 @@ import (
 	"time"
 
 	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/messaging"
+	"github.com/toska-mesh/toska-mesh/internal/registry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
 )
 
 func TestWorker_HTTPProbe_Healthy(t *testing.T) {
@@ -130,3 +135,234 @@ func mustPort(s string) int {
 	fmt.Sscanf(s, "%d", &port)
 	return port
 }
+
+func TestWorker_HealthChangedEvent_IncludesInstanceCounts(t *testing.T) {
+	w := &Worker{config: DefaultConfig(), cache: NewCache()}
+	w.cache.Update("api-1", "api", "10.0.0.1", 80, StatusHealthy, "http", "", nil)
+	w.cache.Update("api-2", "api", "10.0.0.2", 80, StatusUnhealthy, "http", "", nil)
+	w.cache.Update("other-1", "other", "10.0.0.3", 80, StatusHealthy, "http", "", nil)
+
+	inst := consul.Instance{ServiceID: "api-2", ServiceName: "api"}
+	ev := w.healthChangedEvent(inst, 3, StatusHealthy, StatusUnhealthy, "HTTP 503", 1500*time.Microsecond)
+
+	if ev.HealthyInstances != 1 {
+		t.Fatalf("expected 1 healthy instance, got %d", ev.HealthyInstances)
+	}
+	if ev.TotalInstances != 3 {
+		t.Fatalf("expected total to include unprobed registered instances (3), got %d", ev.TotalInstances)
+	}
+	if ev.ProbeLatencyMs != 1.5 {
+		t.Fatalf("expected probe latency 1.5ms, got %v", ev.ProbeLatencyMs)
+	}
+	if ev.PreviousStatus != "Healthy" || ev.CurrentStatus != "Unhealthy" {
+		t.Fatalf("unexpected transition %s -> %s", ev.PreviousStatus, ev.CurrentStatus)
+	}
+}
+
+func TestWorker_AlertsOnUnhealthyAndRecovery(t *testing.T) {
+	received := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer ts.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	alerts, err := messaging.NewWebhookNotifier([]messaging.Webhook{{
+		URL:      ts.URL,
+		Services: []string{"api"},
+		Template: `{{.Message.ServiceID}} {{.Message.PreviousStatus}} -> {{.Message.CurrentStatus}}`,
+	}}, messaging.WebhookOptions{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go alerts.Run(ctx)
+
+	publisher, _ := messaging.NewPublisher("", logger)
+	w := NewWorkerWithOptions(nil, publisher, NewCache(), DefaultConfig(), WorkerOptions{Alerts: alerts}, logger)
+	api := consul.Instance{ServiceID: "api-1", ServiceName: "api"}
+	for _, status := range []HealthStatus{StatusHealthy, StatusDegraded, StatusUnhealthy, StatusHealthy} {
+		w.updateStatus(ctx, api, 1, status, "http", "", 0)
+	}
+	other := consul.Instance{ServiceID: "other-1", ServiceName: "other"}
+	for _, status := range []HealthStatus{StatusHealthy, StatusUnhealthy} {
+		w.updateStatus(ctx, other, 1, status, "http", "", 0)
+	}
+
+	for _, want := range []string{"api-1 Degraded -> Unhealthy", "api-1 Unhealthy -> Healthy"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("expected alert %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for alert %q", want)
+		}
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("unexpected alert %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWorker_DetectsSchemeAndHealthPath(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	addr := ts.Listener.Addr().String()
+	parts := strings.SplitN(addr, ":", 2)
+
+	cfg := DefaultConfig()
+	cfg.DetectEnabled = true
+	cache := NewCache()
+	w := NewWorker(nil, nil, cache, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.client = ts.Client()
+
+	inst := consul.Instance{
+		ServiceID:   "svc-1",
+		ServiceName: "api",
+		Address:     parts[0],
+		Port:        mustPort(parts[1]),
+		Metadata:    map[string]string{},
+	}
+
+	status, probeType, msg := w.runProbes(context.Background(), inst)
+	if status != StatusHealthy || probeType != "http-detected" {
+		t.Fatalf("expected Healthy via http-detected, got %v/%s (%s)", status, probeType, msg)
+	}
+
+	// The detection is reused on the next cycle without re-scanning paths.
+	requests = nil
+	w.runProbes(context.Background(), inst)
+	if len(requests) != 1 || requests[0] != "/healthz" {
+		t.Fatalf("expected a single probe of the detected path, got %v", requests)
+	}
+
+	w.updateStatus(context.Background(), inst, 1, status, probeType, msg, 0)
+	cached := cache.Get("svc-1")
+	if cached.DetectedScheme != "http" || cached.DetectedEndpoint != "/healthz" {
+		t.Fatalf("expected detection recorded in cache, got %+v", cached)
+	}
+}
+
+func TestWorker_DetectionFailureIsNotRetriedImmediately(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DetectEnabled = true
+	w := NewWorker(nil, nil, NewCache(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.client = &http.Client{Timeout: time.Second}
+
+	inst := consul.Instance{ServiceID: "svc-1", ServiceName: "api", Address: "127.0.0.1", Port: 19999}
+
+	if status, _, _ := w.runProbes(context.Background(), inst); status != StatusUnknown {
+		t.Fatalf("expected Unknown when nothing answers, got %v", status)
+	}
+	if _, ok := w.detectAttempts["svc-1"]; !ok {
+		t.Fatal("expected failed detection attempt to be recorded")
+	}
+}
+
+// healthRegistry records health reports.
+type healthRegistry struct {
+	registry.Registry
+	reports []string
+}
+
+func (r *healthRegistry) UpdateHealth(serviceID string, status types.HealthStatus, output string) error {
+	r.reports = append(r.reports, serviceID+"="+status.String())
+	return nil
+}
+
+func TestWorker_ReportsHealthToRegistry(t *testing.T) {
+	tests := []struct {
+		name   string
+		enable bool
+		meta   map[string]string
+		status HealthStatus
+		want   []string
+	}{
+		{"reported", true, nil, StatusUnhealthy, []string{"api-1=Unhealthy"}},
+		{"disabled", false, nil, StatusUnhealthy, nil},
+		{"unknown not reported", true, nil, StatusUnknown, nil},
+		{"renewed by discovery", true, map[string]string{"ttl_auto_renew": "true"}, StatusHealthy, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &healthRegistry{}
+			cfg := DefaultConfig()
+			cfg.ReportHealth = tt.enable
+			w := NewWorkerWithOptions(reg, nil, NewCache(), cfg, WorkerOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			inst := consul.Instance{ServiceID: "api-1", ServiceName: "api", Metadata: tt.meta}
+
+			w.updateStatus(context.Background(), inst, 1, tt.status, "http", "HTTP 503", 0)
+			if fmt.Sprint(reg.reports) != fmt.Sprint(tt.want) {
+				t.Fatalf("expected reports %v, got %v", tt.want, reg.reports)
+			}
+		})
+	}
+}
+
+// watchedRegistry streams a fixed catalog. Its other methods are unset, so
+// listing the registry directly panics.
+type watchedRegistry struct {
+	registry.Registry
+	catalog map[string][]types.Instance
+}
+
+func (r *watchedRegistry) WatchServices(ctx context.Context, wait time.Duration) <-chan []string {
+	ch := make(chan []string, 1)
+	var names []string
+	for name := range r.catalog {
+		names = append(names, name)
+	}
+	ch <- names
+	return ch
+}
+
+func (r *watchedRegistry) WatchInstances(ctx context.Context, serviceName string, wait time.Duration) <-chan []types.Instance {
+	ch := make(chan []types.Instance, 1)
+	ch <- r.catalog[serviceName]
+	return ch
+}
+
+func TestWorker_ListsTargetsFromWatchedCatalog(t *testing.T) {
+	reg := &watchedRegistry{catalog: map[string][]types.Instance{
+		"api": {{ServiceID: "api-1", ServiceName: "api"}, {ServiceID: "api-2", ServiceName: "api"}},
+		"web": {{ServiceID: "web-1", ServiceName: "web"}},
+	}}
+	w := NewWorker(reg, nil, NewCache(), DefaultConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.catalog = registry.WatchCatalog(ctx, reg, time.Minute, nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, ok := w.catalog.Snapshot(); !ok; _, ok = w.catalog.Snapshot() {
+		if time.Now().After(deadline) {
+			t.Fatal("catalog did not sync")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	targets, liveIDs, err := w.listTargets(make(chan struct{}, 1))
+	if err != nil {
+		t.Fatalf("listTargets: %v", err)
+	}
+	if len(targets) != 3 || len(liveIDs) != 3 {
+		t.Fatalf("expected 3 targets, got %d (%v)", len(targets), liveIDs)
+	}
+	for _, target := range targets {
+		if target.inst.ServiceName == "api" && target.registered != 2 {
+			t.Fatalf("expected api targets to count 2 registered instances, got %d", target.registered)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// client is a minimal Kubernetes API client: authenticated GETs for lists
+// and watch streams, which is all the registry needs.
+type client struct {
+	base      string
+	http      *http.Client
+	token     string
+	tokenFile string
+}
+
+func newClient(cfg Config) (*client, error) {
+	if cfg.APIServer == "" {
+		return nil, errors.New("kubernetes: no API server configured (not running in a cluster?)")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("kubernetes: no certificates in %s", cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &client{
+		base:      strings.TrimRight(cfg.APIServer, "/"),
+		http:      &http.Client{Transport: transport},
+		token:     cfg.Token,
+		tokenFile: cfg.TokenFile,
+	}, nil
+}
+
+// bearer returns the service account token. The file is re-read on every
+// request because projected tokens are rotated by the kubelet.
+func (c *client) bearer() (string, error) {
+	if c.token != "" || c.tokenFile == "" {
+		return c.token, nil
+	}
+	data, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("kubernetes: read token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (c *client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.bearer()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// errGone means a watch's resource version has been compacted away and the
+// caller must list again.
+var errGone = errors.New("resource version too old")
+
+// apiStatus is the Status object the API server returns on errors, both as a
+// response body and as the object of a watch ERROR event.
+type apiStatus struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+func (s apiStatus) err() error {
+	if s.Code == http.StatusGone {
+		return errGone
+	}
+	return fmt.Errorf("kubernetes API returned %d: %s", s.Code, s.Message)
+}
+
+func statusError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	status := apiStatus{Code: resp.StatusCode}
+	json.Unmarshal(data, &status)
+	if status.Message == "" {
+		status.Message = strings.TrimSpace(string(data))
+	}
+	status.Code = resp.StatusCode
+	return status.err()
+}
+
+// list returns the items of a collection and its resource version.
+func (c *client) list(ctx context.Context, path string) ([]json.RawMessage, string, error) {
+	resp, err := c.get(ctx, path, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("list %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", path, err)
+	}
+	return body.Items, body.Metadata.ResourceVersion, nil
+}
+
+// watchEvent is one line of a watch stream.
+type watchEvent struct {
+	Type   string          `json:"type"` // ADDED, MODIFIED, DELETED, BOOKMARK or ERROR
+	Object json.RawMessage `json:"object"`
+}
+
+// watch streams changes to a collection from resourceVersion, calling fn for
+// each ADDED, MODIFIED or DELETED event. It returns the last resource version
+// seen when the server ends the stream, or errGone if it must relist.
+func (c *client) watch(ctx context.Context, path, resourceVersion string, timeoutSeconds int, fn func(typ string, obj json.RawMessage) error) (string, error) {
+	query := url.Values{
+		"watch":               {"1"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {fmt.Sprint(timeoutSeconds)},
+	}
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return resourceVersion, fmt.Errorf("watch %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var ev watchEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return resourceVersion, fmt.Errorf("decode %s event: %w", path, err)
+		}
+		if ev.Type == "ERROR" {
+			var status apiStatus
+			json.Unmarshal(ev.Object, &status)
+			return resourceVersion, status.err()
+		}
+		var meta struct {
+			Metadata struct {
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		}
+		json.Unmarshal(ev.Object, &meta)
+		if v := meta.Metadata.ResourceVersion; v != "" {
+			resourceVersion = v
+		}
+		if ev.Type == "BOOKMARK" {
+			continue
+		}
+		if err := fn(ev.Type, ev.Object); err != nil {
+			return resourceVersion, err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return resourceVersion, fmt.Errorf("watch %s: %w", path, err)
+	}
+	return resourceVersion, nil
+}
@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// watchTimeoutSeconds asks the API server to end each watch after five
+// minutes; the informer resumes from the last resource version.
+const watchTimeoutSeconds = 300
+
+// informer keeps a local copy of one collection in sync: it lists, then
+// watches from the list's resource version, and lists again whenever the
+// watch falls too far behind or fails.
+type informer struct {
+	client *client
+	path   string
+	logger *slog.Logger
+
+	// replace swaps in the full contents of the collection after a list;
+	// apply handles a single watch event.
+	replace func(items []json.RawMessage) error
+	apply   func(typ string, obj json.RawMessage) error
+}
+
+// run syncs the collection until ctx is cancelled, calling synced after each
+// successful list.
+func (inf *informer) run(ctx context.Context, synced func()) {
+	const maxBackoff = 30 * time.Second
+	backoff := time.Second
+
+	for ctx.Err() == nil {
+		items, rv, err := inf.client.list(ctx, inf.path)
+		if err == nil {
+			err = inf.replace(items)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			inf.logger.Warn("kubernetes list failed", "path", inf.path, "error", err, "retry_in", backoff)
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		synced()
+		backoff = time.Second
+
+		for ctx.Err() == nil {
+			rv, err = inf.client.watch(ctx, inf.path, rv, watchTimeoutSeconds, inf.apply)
+			if errors.Is(err, errGone) {
+				inf.logger.Debug("kubernetes watch expired, relisting", "path", inf.path)
+				break
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				inf.logger.Warn("kubernetes watch failed", "path", inf.path, "error", err, "retry_in", backoff)
+				if !sleep(ctx, backoff) {
+					return
+				}
+				break
+			}
+		}
+	}
+}
+
+// sleep waits for d or until ctx is cancelled, reporting whether it slept.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,463 @@
+// Package kubernetes implements a read-only service registry backed by the
+// Kubernetes API. Services and their EndpointSlices are mirrored by informers
+// (list then watch), so the gateway and healthmonitor can route to pods
+// directly without Consul in the cluster.
+//
+// Each endpoint address becomes an instance named <pod>.<service>, healthy
+// while the endpoint is ready. Kubernetes readiness is authoritative, so
+// writes return types.ErrReadOnly.
+// Service annotations under AnnotationPrefix become instance metadata, e.g.
+// toska-mesh.io/scheme: https.
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+const (
+	// serviceAccountDir holds the credentials mounted into every pod.
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+	// serviceNameLabel links an EndpointSlice to its Service.
+	serviceNameLabel = "kubernetes.io/service-name"
+)
+
+// Config configures the Kubernetes registry.
+type Config struct {
+	// APIServer is the API server URL. DefaultConfig derives it from
+	// KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT.
+	APIServer string
+	// Token is a bearer token; if empty, TokenFile is read per request.
+	Token     string
+	TokenFile string
+	// CAFile verifies the API server certificate.
+	CAFile string
+	// Namespace to watch. If empty, the pod's own namespace is used.
+	Namespace string
+	// PortName selects the endpoint port to route to; empty picks the first.
+	PortName string
+	// AnnotationPrefix marks Service annotations copied into metadata.
+	AnnotationPrefix string
+	// RequestTimeout bounds how long reads wait for the initial sync.
+	RequestTimeout time.Duration
+}
+
+// DefaultConfig returns in-cluster settings for the pod's service account.
+func DefaultConfig() Config {
+	cfg := Config{
+		TokenFile:        serviceAccountDir + "token",
+		CAFile:           serviceAccountDir + "ca.crt",
+		AnnotationPrefix: "toska-mesh.io/",
+		RequestTimeout:   10 * time.Second,
+	}
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" && port != "" {
+		cfg.APIServer = "https://" + joinHostPort(host, port)
+	}
+	return cfg
+}
+
+func joinHostPort(host, port string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]:" + port
+	}
+	return host + ":" + port
+}
+
+// Kubernetes object fields the registry reads.
+
+type objectMeta struct {
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels"`
+	Annotations       map[string]string `json:"annotations"`
+	CreationTimestamp time.Time         `json:"creationTimestamp"`
+}
+
+type service struct {
+	Metadata objectMeta `json:"metadata"`
+}
+
+type endpointSlice struct {
+	Metadata  objectMeta     `json:"metadata"`
+	Endpoints []endpoint     `json:"endpoints"`
+	Ports     []endpointPort `json:"ports"`
+}
+
+type endpoint struct {
+	Addresses  []string `json:"addresses"`
+	Conditions struct {
+		Ready       *bool `json:"ready"`
+		Serving     *bool `json:"serving"`
+		Terminating *bool `json:"terminating"`
+	} `json:"conditions"`
+	TargetRef *struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"targetRef"`
+	NodeName string `json:"nodeName"`
+	Zone     string `json:"zone"`
+}
+
+type endpointPort struct {
+	Name string `json:"name"`
+	Port *int   `json:"port"`
+}
+
+// status maps endpoint conditions to a health status. A nil ready condition
+// means unknown, which Kubernetes treats as ready. Terminating endpoints that
+// still serve are degraded so the gateway drains them.
+func (ep endpoint) status() types.HealthStatus {
+	c := ep.Conditions
+	switch {
+	case c.Ready == nil || *c.Ready:
+		return types.HealthHealthy
+	case c.Serving != nil && *c.Serving:
+		return types.HealthDegraded
+	default:
+		return types.HealthUnhealthy
+	}
+}
+
+// Registry is a read-only registry over Kubernetes EndpointSlices.
+type Registry struct {
+	config Config
+	logger *slog.Logger
+	cancel context.CancelFunc
+	synced chan struct{}
+
+	mu       sync.RWMutex
+	services map[string]service       // by Service name
+	slices   map[string]endpointSlice // by EndpointSlice name
+	index    uint64
+	changed  chan struct{} // closed and replaced on every change
+}
+
+// NewRegistry creates a Registry and starts its informers. Call Close to stop
+// them.
+func NewRegistry(config Config, logger *slog.Logger) (*Registry, error) {
+	if config.Namespace == "" {
+		if data, err := os.ReadFile(serviceAccountDir + "namespace"); err == nil {
+			config.Namespace = strings.TrimSpace(string(data))
+		} else {
+			config.Namespace = "default"
+		}
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 10 * time.Second
+	}
+	c, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Registry{
+		config:   config,
+		logger:   logger,
+		cancel:   cancel,
+		synced:   make(chan struct{}),
+		services: make(map[string]service),
+		slices:   make(map[string]endpointSlice),
+		index:    1,
+		changed:  make(chan struct{}),
+	}
+
+	ns := "/namespaces/" + config.Namespace
+	informers := []*informer{
+		{
+			client:  c,
+			path:    "/api/v1" + ns + "/services",
+			logger:  logger,
+			replace: func(items []json.RawMessage) error { return replaceAll(r, r.services, items) },
+			apply:   func(typ string, obj json.RawMessage) error { return applyEvent(r, r.services, typ, obj) },
+		},
+		{
+			client:  c,
+			path:    "/apis/discovery.k8s.io/v1" + ns + "/endpointslices",
+			logger:  logger,
+			replace: func(items []json.RawMessage) error { return replaceAll(r, r.slices, items) },
+			apply:   func(typ string, obj json.RawMessage) error { return applyEvent(r, r.slices, typ, obj) },
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, inf := range informers {
+		wg.Add(1)
+		var once sync.Once
+		go inf.run(ctx, func() { once.Do(wg.Done) })
+	}
+	go func() {
+		wg.Wait()
+		close(r.synced)
+	}()
+	return r, nil
+}
+
+// Close stops the informers.
+func (r *Registry) Close() { r.cancel() }
+
+// named is satisfied by the mirrored object types.
+type named interface{ name() string }
+
+func (s service) name() string       { return s.Metadata.Name }
+func (s endpointSlice) name() string { return s.Metadata.Name }
+
+func replaceAll[T named](r *Registry, cache map[string]T, items []json.RawMessage) error {
+	fresh := make(map[string]T, len(items))
+	for _, raw := range items {
+		var obj T
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return fmt.Errorf("decode object: %w", err)
+		}
+		fresh[obj.name()] = obj
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(cache)
+	maps.Copy(cache, fresh)
+	r.bumpLocked()
+	return nil
+}
+
+func applyEvent[T named](r *Registry, cache map[string]T, typ string, raw json.RawMessage) error {
+	var obj T
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return fmt.Errorf("decode %s event: %w", strings.ToLower(typ), err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if typ == "DELETED" {
+		delete(cache, obj.name())
+	} else {
+		cache[obj.name()] = obj
+	}
+	r.bumpLocked()
+	return nil
+}
+
+// bumpLocked advances the change index and wakes WaitForChange callers.
+func (r *Registry) bumpLocked() {
+	r.index++
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// waitSynced blocks until both informers have listed once.
+func (r *Registry) waitSynced(ctx context.Context) error {
+	timer := time.NewTimer(r.config.RequestTimeout)
+	defer timer.Stop()
+	select {
+	case <-r.synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("kubernetes: caches not synced after %s", r.config.RequestTimeout)
+	}
+}
+
+// instancesLocked returns the instances of one EndpointSlice.
+func (r *Registry) instancesLocked(slice endpointSlice) []types.Instance {
+	serviceName := slice.Metadata.Labels[serviceNameLabel]
+	if serviceName == "" {
+		return nil
+	}
+	port := 0
+	for _, p := range slice.Ports {
+		if p.Port != nil && (r.config.PortName == "" || p.Name == r.config.PortName) {
+			port = *p.Port
+			break
+		}
+	}
+	if port == 0 {
+		return nil
+	}
+
+	svcMeta := make(map[string]string)
+	if prefix := r.config.AnnotationPrefix; prefix != "" {
+		for k, v := range r.services[serviceName].Metadata.Annotations {
+			if key, ok := strings.CutPrefix(k, prefix); ok {
+				svcMeta[key] = v
+			}
+		}
+	}
+
+	var instances []types.Instance
+	for _, ep := range slice.Endpoints {
+		for _, addr := range ep.Addresses {
+			id := addr
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				id = ep.TargetRef.Name
+			}
+			meta := maps.Clone(svcMeta)
+			meta["k8s_namespace"] = r.config.Namespace
+			if ep.TargetRef != nil {
+				meta["k8s_pod"] = ep.TargetRef.Name
+			}
+			if ep.NodeName != "" {
+				meta["k8s_node"] = ep.NodeName
+			}
+			if ep.Zone != "" {
+				meta["zone"] = ep.Zone
+			}
+			instances = append(instances, types.Instance{
+				ServiceName:  serviceName,
+				ServiceID:    id + "." + serviceName,
+				Address:      addr,
+				Port:         port,
+				Status:       ep.status(),
+				Metadata:     meta,
+				RegisteredAt: slice.Metadata.CreationTimestamp,
+			})
+		}
+	}
+	return instances
+}
+
+func (r *Registry) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), r.config.RequestTimeout)
+}
+
+// GetServices returns the names of services that have endpoint slices.
+func (r *Registry) GetServices() ([]string, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	if err := r.waitSynced(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool)
+	for _, slice := range r.slices {
+		if name := slice.Metadata.Labels[serviceNameLabel]; name != "" {
+			seen[name] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(seen)), nil
+}
+
+// GetInstances returns the endpoints of a service, ordered by ID.
+func (r *Registry) GetInstances(serviceName string) ([]types.Instance, error) {
+	ctx, cancel := r.ctx()
+	defer cancel()
+	if err := r.waitSynced(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var instances []types.Instance
+	for _, slice := range r.slices {
+		if slice.Metadata.Labels[serviceNameLabel] == serviceName {
+			instances = append(instances, r.instancesLocked(slice)...)
+		}
+	}
+	slices.SortFunc(instances, func(a, b types.Instance) int { return strings.Compare(a.ServiceID, b.ServiceID) })
+	return instances, nil
+}
+
+// GetInstance returns one endpoint by ID, or nil if not found. Service names
+// are DNS labels, so the service is whatever follows the last dot.
+func (r *Registry) GetInstance(serviceID string) (*types.Instance, error) {
+	i := strings.LastIndex(serviceID, ".")
+	if i < 0 {
+		return nil, nil
+	}
+	instances, err := r.GetInstances(serviceID[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	for _, inst := range instances {
+		if inst.ServiceID == serviceID {
+			return &inst, nil
+		}
+	}
+	return nil, nil
+}
+
+// WaitForChange blocks until a Service or EndpointSlice changes after index
+// or wait elapses.
+func (r *Registry) WaitForChange(ctx context.Context, index uint64, wait time.Duration) (uint64, error) {
+	if err := r.waitSynced(ctx); err != nil {
+		return 0, err
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		r.mu.RLock()
+		current, changed := r.index, r.changed
+		r.mu.RUnlock()
+		if index == 0 || current != index {
+			return current, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return current, nil
+		case <-ctx.Done():
+			return current, ctx.Err()
+		}
+	}
+}
+
+// ListKV returns no keys: the Kubernetes backend has no key/value store, so
+// features built on it (maintenance mode, signing keys) stay off. It blocks
+// like a watch that never fires so callers do not spin.
+func (r *Registry) ListKV(ctx context.Context, prefix string, index uint64, wait time.Duration) (map[string][]byte, uint64, error) {
+	if index == 0 {
+		return map[string][]byte{}, 1, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return map[string][]byte{}, index, nil
+	case <-ctx.Done():
+		return nil, index, ctx.Err()
+	}
+}
+
+// Register is not supported; pods are registered by Kubernetes.
+func (r *Registry) Register(reg types.Registration) error {
+	return fmt.Errorf("kubernetes register %s: %w", reg.ServiceID, types.ErrReadOnly)
+}
+
+// Deregister is not supported.
+func (r *Registry) Deregister(serviceID string) error {
+	return fmt.Errorf("kubernetes deregister %s: %w", serviceID, types.ErrReadOnly)
+}
+
+// UpdateHealth is not supported; readiness probes decide endpoint health.
+func (r *Registry) UpdateHealth(serviceID string, status types.HealthStatus, output string) error {
+	return fmt.Errorf("kubernetes update health %s: %w", serviceID, types.ErrReadOnly)
+}
+
+// UpdateMetadata is not supported; annotate the Service instead.
+func (r *Registry) UpdateMetadata(serviceID string, meta map[string]string) error {
+	return fmt.Errorf("kubernetes update metadata %s: %w", serviceID, types.ErrReadOnly)
+}
+
+// ApplyMetadata is not supported; annotate the Service instead.
+func (r *Registry) ApplyMetadata(serviceID string, update types.MetadataUpdate) (types.MetadataChange, error) {
+	return types.MetadataChange{}, fmt.Errorf("kubernetes update metadata %s: %w", serviceID, types.ErrReadOnly)
+}
+
+// PutKV is not supported.
+func (r *Registry) PutKV(key string, value []byte) error {
+	return fmt.Errorf("kubernetes put %s: %w", key, types.ErrReadOnly)
+}
+
+// DeleteKV is not supported.
+func (r *Registry) DeleteKV(key string) error {
+	return fmt.Errorf("kubernetes delete %s: %w", key, types.ErrReadOnly)
+}
@@ -0,0 +1,237 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+const (
+	servicesPath = "/api/v1/namespaces/shop/services"
+	slicesPath   = "/apis/discovery.k8s.io/v1/namespaces/shop/endpointslices"
+)
+
+// fakeAPIServer serves fixed lists and streams watch events pushed by tests.
+type fakeAPIServer struct {
+	lists  map[string][]any
+	events map[string]chan watchEvent
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		http.Error(w, `{"message":"Unauthorized","code":401}`, http.StatusUnauthorized)
+		return
+	}
+	items, ok := f.lists[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Get("watch") != "1" {
+		json.NewEncoder(w).Encode(map[string]any{
+			"metadata": map[string]string{"resourceVersion": "10"},
+			"items":    items,
+		})
+		return
+	}
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case ev := <-f.events[r.URL.Path]:
+			json.NewEncoder(w).Encode(ev)
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func slice(name, service string, endpoints ...map[string]any) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{
+			"name":              name,
+			"resourceVersion":   "10",
+			"labels":            map[string]string{serviceNameLabel: service},
+			"creationTimestamp": "2026-01-02T03:04:05Z",
+		},
+		"endpoints": endpoints,
+		"ports":     []map[string]any{{"name": "metrics", "port": 9100}, {"name": "http", "port": 8080}},
+	}
+}
+
+func podEndpoint(pod, addr string, ready bool) map[string]any {
+	return map[string]any{
+		"addresses":  []string{addr},
+		"conditions": map[string]bool{"ready": ready, "serving": ready},
+		"targetRef":  map[string]string{"kind": "Pod", "name": pod},
+		"nodeName":   "node-a",
+		"zone":       "eu-west-1a",
+	}
+}
+
+func newTestRegistry(t *testing.T) (*fakeAPIServer, *Registry) {
+	t.Helper()
+	f := &fakeAPIServer{
+		lists: map[string][]any{
+			servicesPath: {map[string]any{"metadata": map[string]any{
+				"name":        "orders",
+				"annotations": map[string]string{"toska-mesh.io/scheme": "https", "unrelated": "x"},
+			}}},
+			slicesPath: {
+				slice("orders-abc", "orders",
+					podEndpoint("orders-7d9f-x1", "10.1.0.5", true),
+					podEndpoint("orders-7d9f-x2", "10.1.0.6", false),
+				),
+				slice("payments-def", "payments", podEndpoint("payments-55-y1", "10.1.0.9", true)),
+			},
+		},
+		events: map[string]chan watchEvent{
+			servicesPath: make(chan watchEvent, 1),
+			slicesPath:   make(chan watchEvent, 1),
+		},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig()
+	cfg.APIServer = srv.URL
+	cfg.Token = "test-token"
+	cfg.CAFile = ""
+	cfg.Namespace = "shop"
+	cfg.PortName = "http"
+	reg, err := NewRegistry(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(reg.Close)
+	return f, reg
+}
+
+func TestRegistry_ListsEndpointSlices(t *testing.T) {
+	_, reg := newTestRegistry(t)
+
+	names, err := reg.GetServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"orders", "payments"}) {
+		t.Fatalf("expected orders and payments, got %v", names)
+	}
+
+	instances, err := reg.GetInstances("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %+v", instances)
+	}
+	ready, notReady := instances[0], instances[1]
+	if ready.ServiceID != "orders-7d9f-x1.orders" || ready.Address != "10.1.0.5" || ready.Port != 8080 {
+		t.Fatalf("unexpected instance %+v", ready)
+	}
+	if ready.Status != types.HealthHealthy || notReady.Status != types.HealthUnhealthy {
+		t.Fatalf("expected ready/not ready to map to healthy/unhealthy, got %v/%v", ready.Status, notReady.Status)
+	}
+	if ready.Metadata["scheme"] != "https" || ready.Metadata["zone"] != "eu-west-1a" || ready.Metadata["k8s_pod"] != "orders-7d9f-x1" {
+		t.Fatalf("unexpected metadata %v", ready.Metadata)
+	}
+	if _, ok := ready.Metadata["unrelated"]; ok {
+		t.Fatal("expected annotations outside the prefix to be ignored")
+	}
+
+	inst, err := reg.GetInstance("payments-55-y1.payments")
+	if err != nil || inst == nil || inst.Address != "10.1.0.9" {
+		t.Fatalf("GetInstance: %+v %v", inst, err)
+	}
+}
+
+func TestRegistry_WatchUpdatesCache(t *testing.T) {
+	f, reg := newTestRegistry(t)
+	ctx := context.Background()
+
+	index, err := reg.WaitForChange(ctx, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, _ := json.Marshal(slice("orders-abc", "orders",
+		podEndpoint("orders-7d9f-x1", "10.1.0.5", true),
+		podEndpoint("orders-7d9f-x2", "10.1.0.6", true),
+	))
+	f.events[slicesPath] <- watchEvent{Type: "MODIFIED", Object: updated}
+
+	newIndex, err := reg.WaitForChange(ctx, index, 5*time.Second)
+	if err != nil || newIndex == index {
+		t.Fatalf("expected the watch event to advance the index, got %d %v", newIndex, err)
+	}
+	instances, _ := reg.GetInstances("orders")
+	for _, inst := range instances {
+		if inst.Status != types.HealthHealthy {
+			t.Fatalf("expected %s healthy after update, got %v", inst.ServiceID, inst.Status)
+		}
+	}
+
+	deleted, _ := json.Marshal(slice("payments-def", "payments"))
+	f.events[slicesPath] <- watchEvent{Type: "DELETED", Object: deleted}
+	if _, err := reg.WaitForChange(ctx, newIndex, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	names, _ := reg.GetServices()
+	if !slices.Equal(names, []string{"orders"}) {
+		t.Fatalf("expected payments removed, got %v", names)
+	}
+}
+
+func TestRegistry_WritesAreReadOnly(t *testing.T) {
+	_, reg := newTestRegistry(t)
+
+	for name, err := range map[string]error{
+		"Register":       reg.Register(types.Registration{ServiceID: "x"}),
+		"Deregister":     reg.Deregister("x"),
+		"UpdateHealth":   reg.UpdateHealth("x", types.HealthHealthy, ""),
+		"UpdateMetadata": reg.UpdateMetadata("x", nil),
+		"PutKV":          reg.PutKV("k", nil),
+		"DeleteKV":       reg.DeleteKV("k"),
+	} {
+		if !errors.Is(err, types.ErrReadOnly) {
+			t.Errorf("%s: expected ErrReadOnly, got %v", name, err)
+		}
+	}
+
+	values, index, err := reg.ListKV(context.Background(), "toska-mesh/", 0, time.Second)
+	if err != nil || len(values) != 0 || index == 0 {
+		t.Fatalf("expected an empty KV listing, got %v %d %v", values, index, err)
+	}
+}
+
+func TestEndpointStatus(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name           string
+		ready, serving *bool
+		want           types.HealthStatus
+	}{
+		{"unknown readiness", nil, nil, types.HealthHealthy},
+		{"ready", &yes, &yes, types.HealthHealthy},
+		{"terminating but serving", &no, &yes, types.HealthDegraded},
+		{"not ready", &no, &no, types.HealthUnhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ep endpoint
+			ep.Conditions.Ready, ep.Conditions.Serving = tt.ready, tt.serving
+			if got := ep.status(); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
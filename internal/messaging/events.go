@@ -27,6 +27,9 @@ type ServiceDeregisteredEvent struct {
 }
 
 // ServiceHealthChangedEvent is published when a service's health status changes.
+// HealthyInstances and TotalInstances describe the whole service at the time of
+// the transition so consumers can judge severity (last instance down vs one of
+// many) without querying the monitor.
 type ServiceHealthChangedEvent struct {
 	EventID           string    `json:"eventId"`
 	Timestamp         time.Time `json:"timestamp"`
@@ -36,4 +39,83 @@ type ServiceHealthChangedEvent struct {
 	PreviousStatus    string    `json:"previousStatus"`
 	CurrentStatus     string    `json:"currentStatus"`
 	HealthCheckOutput string    `json:"healthCheckOutput,omitempty"`
+	HealthyInstances  int       `json:"healthyInstances"`
+	TotalInstances    int       `json:"totalInstances"`
+	ProbeLatencyMs    float64   `json:"probeLatencyMs"`
+}
+
+// ServiceMetadataChangedEvent is published when an instance's metadata is
+// updated in place (weight, lb_strategy, zone, ...).
+type ServiceMetadataChangedEvent struct {
+	EventID          string            `json:"eventId"`
+	Timestamp        time.Time         `json:"timestamp"`
+	CorrelationID    string            `json:"correlationId,omitempty"`
+	ServiceID        string            `json:"serviceId"`
+	ServiceName      string            `json:"serviceName"`
+	PreviousMetadata map[string]string `json:"previousMetadata,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// RegisteredInstance describes one instance in a ServiceBatchRegisteredEvent.
+type RegisteredInstance struct {
+	ServiceID   string            `json:"serviceId"`
+	ServiceName string            `json:"serviceName"`
+	Address     string            `json:"address"`
+	Port        int               `json:"port"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// ServiceBatchRegisteredEvent is published once for a batch registration, in
+// place of one ServiceRegisteredEvent per instance.
+type ServiceBatchRegisteredEvent struct {
+	EventID       string               `json:"eventId"`
+	Timestamp     time.Time            `json:"timestamp"`
+	CorrelationID string               `json:"correlationId,omitempty"`
+	Instances     []RegisteredInstance `json:"instances"`
+}
+
+// DeregisteredInstance describes one instance in a ServiceBatchDeregisteredEvent.
+type DeregisteredInstance struct {
+	ServiceID   string `json:"serviceId"`
+	ServiceName string `json:"serviceName"`
+}
+
+// ServiceBatchDeregisteredEvent is published once for a batch deregistration,
+// listing the instances that were removed.
+type ServiceBatchDeregisteredEvent struct {
+	EventID       string                 `json:"eventId"`
+	Timestamp     time.Time              `json:"timestamp"`
+	CorrelationID string                 `json:"correlationId,omitempty"`
+	Instances     []DeregisteredInstance `json:"instances"`
+	Reason        string                 `json:"reason,omitempty"`
+}
+
+// ServiceDecommissionedEvent is published once when every instance of a
+// service is deregistered by name, listing the instances that were removed.
+type ServiceDecommissionedEvent struct {
+	EventID       string    `json:"eventId"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	ServiceName   string    `json:"serviceName"`
+	ServiceIDs    []string  `json:"serviceIds"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// ServiceRegistrationConflictEvent is published when a registration clashes
+// with an existing instance: another ID at the same address:port (Kind
+// "address"). Resolution is "allowed", "rejected", or "superseded" per the
+// discovery conflict policy.
+type ServiceRegistrationConflictEvent struct {
+	EventID           string    `json:"eventId"`
+	Timestamp         time.Time `json:"timestamp"`
+	CorrelationID     string    `json:"correlationId,omitempty"`
+	ServiceID         string    `json:"serviceId"`
+	ServiceName       string    `json:"serviceName"`
+	Address           string    `json:"address"`
+	Port              int       `json:"port"`
+	Kind              string    `json:"kind"`
+	ExistingServiceID string    `json:"existingServiceId"`
+	ExistingAddress   string    `json:"existingAddress"`
+	ExistingPort      int       `json:"existingPort"`
+	Resolution        string    `json:"resolution"`
 }
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
 )
 
 // idCounter provides a monotonically increasing suffix to ensure unique event IDs
@@ -37,19 +40,71 @@ type massTransitHost struct {
 	OperatingSystemVersion string `json:"operatingSystemVersion"`
 }
 
+// ExchangeMode selects the RabbitMQ exchange topology used for publishing.
+type ExchangeMode string
+
+const (
+	// ExchangeFanout publishes to one fanout exchange per event type, matching
+	// the MassTransit convention. Every consumer receives every event.
+	ExchangeFanout ExchangeMode = "fanout"
+	// ExchangeTopic publishes to a single topic exchange with per-service
+	// routing keys such as service.<name>.health.changed.
+	ExchangeTopic ExchangeMode = "topic"
+	// ExchangeBoth publishes to both topologies, so topic consumers can be
+	// introduced alongside existing MassTransit consumers.
+	ExchangeBoth ExchangeMode = "both"
+)
+
+// DefaultTopicExchange is the topic exchange name used when none is configured.
+const DefaultTopicExchange = "toska-mesh.events"
+
+// PublisherOptions controls the exchange topology of a Publisher.
+type PublisherOptions struct {
+	Mode          ExchangeMode
+	TopicExchange string
+	// Telemetry receives publish metrics. Nil disables metrics.
+	Telemetry telemetry.Sink
+}
+
+// DefaultPublisherOptions returns MassTransit-compatible fanout publishing.
+func DefaultPublisherOptions() PublisherOptions {
+	return PublisherOptions{
+		Mode:          ExchangeFanout,
+		TopicExchange: DefaultTopicExchange,
+	}
+}
+
 // Publisher sends events to RabbitMQ in MassTransit-compatible envelope format.
 type Publisher struct {
 	conn    *amqp.Connection
 	ch      *amqp.Channel
+	opts    PublisherOptions
 	logger  *slog.Logger
 }
 
 // NewPublisher creates a Publisher connected to the given AMQP URL.
 // If url is empty, returns a no-op publisher that logs events instead of sending them.
 func NewPublisher(url string, logger *slog.Logger) (*Publisher, error) {
+	return NewPublisherWithOptions(url, DefaultPublisherOptions(), logger)
+}
+
+// NewPublisherWithOptions creates a Publisher with an explicit exchange topology.
+func NewPublisherWithOptions(url string, opts PublisherOptions, logger *slog.Logger) (*Publisher, error) {
+	switch opts.Mode {
+	case "":
+		opts.Mode = ExchangeFanout
+	case ExchangeFanout, ExchangeTopic, ExchangeBoth:
+	default:
+		return nil, fmt.Errorf("unknown exchange mode %q", opts.Mode)
+	}
+	if opts.TopicExchange == "" {
+		opts.TopicExchange = DefaultTopicExchange
+	}
+	opts.Telemetry = telemetry.OrNop(opts.Telemetry)
+
 	if url == "" {
 		logger.Info("RabbitMQ URL not configured, using no-op publisher")
-		return &Publisher{logger: logger}, nil
+		return &Publisher{opts: opts, logger: logger}, nil
 	}
 
 	conn, err := amqp.Dial(url)
@@ -66,6 +121,7 @@ func NewPublisher(url string, logger *slog.Logger) (*Publisher, error) {
 	return &Publisher{
 		conn:   conn,
 		ch:     ch,
+		opts:   opts,
 		logger: logger,
 	}, nil
 }
@@ -73,6 +129,24 @@ func NewPublisher(url string, logger *slog.Logger) (*Publisher, error) {
 // Publish sends an event message to the appropriate RabbitMQ exchange.
 // The exchange name and message type URN are derived from the event type.
 func (p *Publisher) Publish(ctx context.Context, event any) error {
+	_, exchangeName := eventMeta(event)
+	err := p.publish(ctx, event)
+
+	result := "ok"
+	switch {
+	case err != nil:
+		result = "error"
+	case p.ch == nil:
+		result = "noop"
+	}
+	p.opts.Telemetry.Count("messaging_published_total", 1, telemetry.Labels{
+		"event":  exchangeName[strings.LastIndex(exchangeName, ":")+1:],
+		"result": result,
+	})
+	return err
+}
+
+func (p *Publisher) publish(ctx context.Context, event any) error {
 	typeName, exchangeName := eventMeta(event)
 
 	envelope := massTransitEnvelope{
@@ -94,21 +168,38 @@ func (p *Publisher) Publish(ctx context.Context, event any) error {
 		return fmt.Errorf("marshal event: %w", err)
 	}
 
+	key := routingKey(event)
+
 	// No-op mode: just log.
 	if p.ch == nil {
-		p.logger.Info("event published (no-op)", "type", typeName, "exchange", exchangeName)
+		p.logger.Info("event published (no-op)", "type", typeName, "exchange", exchangeName, "routing_key", key)
 		return nil
 	}
 
-	// Declare a fanout exchange matching MassTransit convention.
-	if err := p.ch.ExchangeDeclare(exchangeName, "fanout", true, false, false, false, nil); err != nil {
-		return fmt.Errorf("declare exchange %s: %w", exchangeName, err)
-	}
-
-	return p.ch.PublishWithContext(ctx, exchangeName, "", false, false, amqp.Publishing{
+	msg := amqp.Publishing{
 		ContentType: "application/vnd.masstransit+json",
 		Body:        body,
-	})
+	}
+
+	if p.opts.Mode != ExchangeTopic {
+		// Declare a fanout exchange matching MassTransit convention.
+		if err := p.ch.ExchangeDeclare(exchangeName, "fanout", true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", exchangeName, err)
+		}
+		if err := p.ch.PublishWithContext(ctx, exchangeName, "", false, false, msg); err != nil {
+			return fmt.Errorf("publish to %s: %w", exchangeName, err)
+		}
+	}
+
+	if p.opts.Mode != ExchangeFanout {
+		if err := p.ch.ExchangeDeclare(p.opts.TopicExchange, "topic", true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", p.opts.TopicExchange, err)
+		}
+		if err := p.ch.PublishWithContext(ctx, p.opts.TopicExchange, key, false, false, msg); err != nil {
+			return fmt.Errorf("publish to %s: %w", p.opts.TopicExchange, err)
+		}
+	}
+	return nil
 }
 
 // Close cleanly shuts down the AMQP connection.
@@ -122,6 +213,20 @@ func (p *Publisher) Close() error {
 	return nil
 }
 
+// Ping opens and closes an AMQP connection to url, reporting whether the
+// broker is reachable. ctx's deadline, if any, bounds the connection attempt.
+func Ping(ctx context.Context, url string) error {
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	conn, err := amqp.DialConfig(url, amqp.Config{Dial: amqp.DefaultDial(timeout)})
+	if err != nil {
+		return fmt.Errorf("amqp dial: %w", err)
+	}
+	return conn.Close()
+}
+
 func eventMeta(event any) (typeName, exchangeName string) {
 	switch event.(type) {
 	case ServiceRegisteredEvent:
@@ -133,11 +238,58 @@ func eventMeta(event any) (typeName, exchangeName string) {
 	case ServiceHealthChangedEvent:
 		return "urn:message:ToskaMesh.Common.Messaging:ServiceHealthChangedEvent",
 			"ToskaMesh.Common.Messaging:ServiceHealthChangedEvent"
+	case ServiceMetadataChangedEvent:
+		return "urn:message:ToskaMesh.Common.Messaging:ServiceMetadataChangedEvent",
+			"ToskaMesh.Common.Messaging:ServiceMetadataChangedEvent"
+	case ServiceBatchRegisteredEvent:
+		return "urn:message:ToskaMesh.Common.Messaging:ServiceBatchRegisteredEvent",
+			"ToskaMesh.Common.Messaging:ServiceBatchRegisteredEvent"
+	case ServiceBatchDeregisteredEvent:
+		return "urn:message:ToskaMesh.Common.Messaging:ServiceBatchDeregisteredEvent",
+			"ToskaMesh.Common.Messaging:ServiceBatchDeregisteredEvent"
+	case ServiceDecommissionedEvent:
+		return "urn:message:ToskaMesh.Common.Messaging:ServiceDecommissionedEvent",
+			"ToskaMesh.Common.Messaging:ServiceDecommissionedEvent"
+	case ServiceRegistrationConflictEvent:
+		return "urn:message:ToskaMesh.Common.Messaging:ServiceRegistrationConflictEvent",
+			"ToskaMesh.Common.Messaging:ServiceRegistrationConflictEvent"
 	default:
 		return "urn:message:Unknown", "Unknown"
 	}
 }
 
+// routingKey returns the topic routing key for an event, of the form
+// service.<name>.<event>. Dots in service names are replaced so that
+// wildcard bindings like service.orders.# match exactly one service. Batch
+// events may span services and use batch.<event> instead.
+func routingKey(event any) string {
+	var name, suffix string
+	switch e := event.(type) {
+	case ServiceBatchRegisteredEvent:
+		return "batch.registered"
+	case ServiceBatchDeregisteredEvent:
+		return "batch.deregistered"
+	case ServiceRegisteredEvent:
+		name, suffix = e.ServiceName, "registered"
+	case ServiceDeregisteredEvent:
+		name, suffix = e.ServiceName, "deregistered"
+	case ServiceHealthChangedEvent:
+		name, suffix = e.ServiceName, "health.changed"
+	case ServiceMetadataChangedEvent:
+		name, suffix = e.ServiceName, "metadata.changed"
+	case ServiceDecommissionedEvent:
+		name, suffix = e.ServiceName, "decommissioned"
+	case ServiceRegistrationConflictEvent:
+		name, suffix = e.ServiceName, "registration.conflict"
+	default:
+		return "unknown"
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return "service." + strings.ReplaceAll(name, ".", "_") + "." + suffix
+}
+
 func generateID() string {
 	seq := idCounter.Add(1)
 	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), seq)
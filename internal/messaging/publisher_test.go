@@ -1,6 +1,8 @@
 package messaging
 
 import (
+	"io"
+	"log/slog"
 	"strings"
 	"testing"
 	"time"
@@ -31,6 +33,36 @@ func TestEventMeta(t *testing.T) {
 			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceHealthChangedEvent",
 			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceHealthChangedEvent",
 		},
+		{
+			name:             "ServiceMetadataChangedEvent",
+			event:            ServiceMetadataChangedEvent{},
+			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceMetadataChangedEvent",
+			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceMetadataChangedEvent",
+		},
+		{
+			name:             "ServiceBatchRegisteredEvent",
+			event:            ServiceBatchRegisteredEvent{},
+			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceBatchRegisteredEvent",
+			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceBatchRegisteredEvent",
+		},
+		{
+			name:             "ServiceBatchDeregisteredEvent",
+			event:            ServiceBatchDeregisteredEvent{},
+			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceBatchDeregisteredEvent",
+			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceBatchDeregisteredEvent",
+		},
+		{
+			name:             "ServiceDecommissionedEvent",
+			event:            ServiceDecommissionedEvent{},
+			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceDecommissionedEvent",
+			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceDecommissionedEvent",
+		},
+		{
+			name:             "ServiceRegistrationConflictEvent",
+			event:            ServiceRegistrationConflictEvent{},
+			wantTypeName:     "urn:message:ToskaMesh.Common.Messaging:ServiceRegistrationConflictEvent",
+			wantExchangeName: "ToskaMesh.Common.Messaging:ServiceRegistrationConflictEvent",
+		},
 		{
 			name:             "unknown event type",
 			event:            "not an event",
@@ -86,3 +118,46 @@ func TestMassTransitEnvelope_Fields(t *testing.T) {
 		t.Errorf("expected URN prefix, got %q", typeName)
 	}
 }
+
+func TestRoutingKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		event any
+		want  string
+	}{
+		{"registered", ServiceRegisteredEvent{ServiceName: "orders"}, "service.orders.registered"},
+		{"deregistered", ServiceDeregisteredEvent{ServiceName: "orders"}, "service.orders.deregistered"},
+		{"health changed", ServiceHealthChangedEvent{ServiceName: "orders"}, "service.orders.health.changed"},
+		{"dotted service name", ServiceHealthChangedEvent{ServiceName: "billing.v2"}, "service.billing_v2.health.changed"},
+		{"metadata changed", ServiceMetadataChangedEvent{ServiceName: "orders"}, "service.orders.metadata.changed"},
+		{"batch registered", ServiceBatchRegisteredEvent{}, "batch.registered"},
+		{"batch deregistered", ServiceBatchDeregisteredEvent{}, "batch.deregistered"},
+		{"decommissioned", ServiceDecommissionedEvent{ServiceName: "orders"}, "service.orders.decommissioned"},
+		{"registration conflict", ServiceRegistrationConflictEvent{ServiceName: "orders"}, "service.orders.registration.conflict"},
+		{"unknown event type", "not an event", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routingKey(tt.event); got != tt.want {
+				t.Errorf("routingKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPublisherWithOptions_ValidatesMode(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	p, err := NewPublisherWithOptions("", PublisherOptions{Mode: ExchangeTopic}, logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.opts.TopicExchange != DefaultTopicExchange {
+		t.Fatalf("expected default topic exchange, got %q", p.opts.TopicExchange)
+	}
+
+	if _, err := NewPublisherWithOptions("", PublisherOptions{Mode: "direct"}, logger); err == nil {
+		t.Fatal("expected error for unknown exchange mode")
+	}
+}
@@ -0,0 +1,332 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/pkg/requestsign"
+)
+
+// Webhook is an HTTP endpoint notified of events, for systems that do not
+// consume RabbitMQ (a CMDB, a chat integration).
+type Webhook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Secret, if set, signs each delivery with requestsign as service
+	// WebhookService, so receivers can verify it with requestsign.Verify.
+	Secret string `json:"secret,omitempty"`
+	// Events limits deliveries to these event types, e.g.
+	// "ServiceRegisteredEvent". Empty means every event.
+	Events []string `json:"events,omitempty"`
+	// Services limits deliveries to events about these service names.
+	// Empty means every service.
+	Services []string `json:"services,omitempty"`
+	// Template, if set, is a text/template rendering the request body from
+	// the payload (.EventType, .RoutingKey, .SentTime, .DeliveryID and
+	// .Message), e.g. {"text": {{json .Message.ServiceName}}} for a chat
+	// integration. The json function encodes a value as JSON. Empty means
+	// the payload itself, encoded as JSON.
+	Template string `json:"template,omitempty"`
+	// ContentType is sent with templated bodies. Defaults to
+	// application/json.
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// WebhookService is the service name deliveries are signed as by default.
+const WebhookService = "toska-mesh-discovery"
+
+// Delivery headers, in addition to the requestsign headers.
+const (
+	WebhookEventHeader    = "X-Toska-Event"
+	WebhookDeliveryHeader = "X-Toska-Delivery"
+)
+
+// webhookPayload is the body POSTed to a webhook.
+type webhookPayload struct {
+	DeliveryID string    `json:"deliveryId"`
+	EventType  string    `json:"eventType"`
+	RoutingKey string    `json:"routingKey"`
+	SentTime   time.Time `json:"sentTime"`
+	Message    any       `json:"message"`
+}
+
+// WebhookOptions controls delivery.
+type WebhookOptions struct {
+	// MaxAttempts bounds deliveries per event, including the first.
+	MaxAttempts int
+	// InitialBackoff is the pause before the first retry; it doubles up to
+	// MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds each HTTP request.
+	Timeout time.Duration
+	// QueueSize bounds events waiting per webhook; newer events are dropped
+	// beyond it so a dead endpoint cannot grow memory.
+	QueueSize int
+	// Telemetry receives delivery metrics. Nil disables metrics.
+	Telemetry telemetry.Sink
+	// Service is the service name deliveries are signed as. Defaults to
+	// WebhookService.
+	Service string
+}
+
+// DefaultWebhookOptions returns the delivery defaults.
+func DefaultWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Timeout:        5 * time.Second,
+		QueueSize:      1000,
+	}
+}
+
+// WebhookNotifier delivers events to webhooks in the background. Each webhook
+// has its own queue and worker, so events reach it in order and a slow
+// endpoint does not delay the others.
+type WebhookNotifier struct {
+	hooks     []Webhook
+	templates []*template.Template
+	queues    []chan webhookPayload
+	opts      WebhookOptions
+	client    *http.Client
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// NewWebhookNotifier creates a notifier for hooks. Call Run to deliver.
+func NewWebhookNotifier(hooks []Webhook, opts WebhookOptions, logger *slog.Logger) (*WebhookNotifier, error) {
+	defaults := DefaultWebhookOptions()
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaults.MaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaults.InitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaults.QueueSize
+	}
+	if opts.Service == "" {
+		opts.Service = WebhookService
+	}
+	opts.Telemetry = telemetry.OrNop(opts.Telemetry)
+
+	n := &WebhookNotifier{
+		hooks:  hooks,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		logger: logger,
+		now:    time.Now,
+	}
+	for i, h := range hooks {
+		if h.URL == "" {
+			return nil, fmt.Errorf("webhook %d (%s): url is required", i, h.Name)
+		}
+		if n.hooks[i].Name == "" {
+			n.hooks[i].Name = h.URL
+		}
+		var tmpl *template.Template
+		if h.Template != "" {
+			var err error
+			tmpl, err = template.New(n.hooks[i].Name).Funcs(templateFuncs).Option("missingkey=error").Parse(h.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %d (%s): invalid template: %w", i, h.Name, err)
+			}
+		}
+		n.templates = append(n.templates, tmpl)
+		n.queues = append(n.queues, make(chan webhookPayload, opts.QueueSize))
+	}
+	return n, nil
+}
+
+// Notify queues event for every webhook subscribed to its type. It never
+// blocks; a full queue drops the event for that webhook.
+func (n *WebhookNotifier) Notify(event any) {
+	_, exchangeName := eventMeta(event)
+	eventType := exchangeName[strings.LastIndex(exchangeName, ":")+1:]
+	payload := webhookPayload{
+		DeliveryID: generateID(),
+		EventType:  eventType,
+		RoutingKey: routingKey(event),
+		SentTime:   n.now().UTC(),
+		Message:    event,
+	}
+	services := eventServices(event)
+	for i, h := range n.hooks {
+		if len(h.Events) > 0 && !slices.Contains(h.Events, eventType) {
+			continue
+		}
+		if len(h.Services) > 0 && !slices.ContainsFunc(services, func(s string) bool { return slices.Contains(h.Services, s) }) {
+			continue
+		}
+		select {
+		case n.queues[i] <- payload:
+		default:
+			n.count(h, "dropped")
+			n.logger.Warn("webhook queue full, dropping event", "webhook", h.Name, "event", eventType)
+		}
+	}
+}
+
+// Run delivers queued events until ctx is cancelled.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	done := make(chan struct{})
+	for i := range n.hooks {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case payload := <-n.queues[i]:
+					n.deliver(ctx, n.hooks[i], n.templates[i], payload)
+				}
+			}
+		}()
+	}
+	for range n.hooks {
+		<-done
+	}
+}
+
+// deliver POSTs payload to h, retrying network errors, 429s, and 5xx
+// responses with exponential backoff.
+func (n *WebhookNotifier) deliver(ctx context.Context, h Webhook, tmpl *template.Template, payload webhookPayload) {
+	body, err := renderPayload(tmpl, payload)
+	if err != nil {
+		n.count(h, "failed")
+		n.logger.Error("failed to encode webhook payload", "webhook", h.Name, "error", err)
+		return
+	}
+
+	backoff := n.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, h, payload, body)
+		if err == nil {
+			n.count(h, "ok")
+			return
+		}
+		if !retry || attempt >= n.opts.MaxAttempts {
+			n.count(h, "failed")
+			n.logger.Warn("webhook delivery failed", "webhook", h.Name, "event", payload.EventType, "attempts", attempt, "error", err)
+			return
+		}
+		n.count(h, "retry")
+		n.logger.Debug("retrying webhook delivery", "webhook", h.Name, "event", payload.EventType, "error", err, "retry_in", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, n.opts.MaxBackoff)
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying.
+func (n *WebhookNotifier) post(ctx context.Context, h Webhook, payload webhookPayload, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("build request: %w", err)
+	}
+	contentType := "application/json"
+	if h.Template != "" && h.ContentType != "" {
+		contentType = h.ContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(WebhookEventHeader, payload.EventType)
+	req.Header.Set(WebhookDeliveryHeader, payload.DeliveryID)
+	if h.Secret != "" {
+		if err := requestsign.Sign(req, n.opts.Service, []byte(h.Secret), n.now()); err != nil {
+			return false, fmt.Errorf("sign request: %w", err)
+		}
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+}
+
+// templateFuncs are the functions available to webhook templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// renderPayload encodes payload as JSON, or with tmpl if the webhook has a
+// template.
+func renderPayload(tmpl *template.Template, payload webhookPayload) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(payload)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, payload); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// eventServices returns the service names an event is about.
+func eventServices(event any) []string {
+	switch e := event.(type) {
+	case ServiceRegisteredEvent:
+		return []string{e.ServiceName}
+	case ServiceDeregisteredEvent:
+		return []string{e.ServiceName}
+	case ServiceHealthChangedEvent:
+		return []string{e.ServiceName}
+	case ServiceMetadataChangedEvent:
+		return []string{e.ServiceName}
+	case ServiceDecommissionedEvent:
+		return []string{e.ServiceName}
+	case ServiceRegistrationConflictEvent:
+		return []string{e.ServiceName}
+	case ServiceBatchRegisteredEvent:
+		names := make([]string, 0, len(e.Instances))
+		for _, inst := range e.Instances {
+			names = append(names, inst.ServiceName)
+		}
+		return names
+	case ServiceBatchDeregisteredEvent:
+		names := make([]string, 0, len(e.Instances))
+		for _, inst := range e.Instances {
+			names = append(names, inst.ServiceName)
+		}
+		return names
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) count(h Webhook, result string) {
+	n.opts.Telemetry.Count("messaging_webhook_deliveries_total", 1, telemetry.Labels{"webhook": h.Name, "result": result})
+}
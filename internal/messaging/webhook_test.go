@@ -0,0 +1,199 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/pkg/requestsign"
+)
+
+// webhookReceiver fails the first failures requests with status, then
+// accepts, recording each verified delivery.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	status   int
+	attempts int
+	received []webhookPayload
+	errs     []error
+	done     chan struct{}
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.attempts++
+	if rcv.attempts <= rcv.failures {
+		w.WriteHeader(rcv.status)
+		return
+	}
+	if rcv.secret != "" {
+		if err := requestsign.Verify(r, []byte(rcv.secret), time.Now(), time.Minute, 0); err != nil {
+			rcv.errs = append(rcv.errs, err)
+		}
+	}
+	body, _ := io.ReadAll(r.Body)
+	var p webhookPayload
+	json.Unmarshal(body, &p)
+	if r.Header.Get(WebhookEventHeader) != p.EventType {
+		rcv.errs = append(rcv.errs, io.ErrUnexpectedEOF)
+	}
+	rcv.received = append(rcv.received, p)
+	close(rcv.done)
+}
+
+func newTestNotifier(t *testing.T, hooks []Webhook, sink telemetry.Sink) *WebhookNotifier {
+	t.Helper()
+	opts := DefaultWebhookOptions()
+	opts.InitialBackoff = time.Millisecond
+	opts.MaxAttempts = 3
+	opts.Telemetry = sink
+	n, err := NewWebhookNotifier(hooks, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestWebhookNotifier_RetriesAndSigns(t *testing.T) {
+	rcv := &webhookReceiver{secret: "s3cret", failures: 2, status: http.StatusServiceUnavailable, done: make(chan struct{})}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	sink := telemetry.NewPrometheus()
+	n := newTestNotifier(t, []Webhook{{Name: "cmdb", URL: srv.URL, Secret: "s3cret"}}, sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go n.Run(ctx)
+
+	n.Notify(ServiceRegisteredEvent{ServiceID: "orders-1", ServiceName: "orders"})
+	select {
+	case <-rcv.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+
+	rcv.mu.Lock()
+	errs, attempts, p := rcv.errs, rcv.attempts, rcv.received[0]
+	rcv.mu.Unlock()
+	if len(errs) > 0 {
+		t.Fatalf("delivery did not verify: %v", errs)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if p.EventType != "ServiceRegisteredEvent" || p.RoutingKey != "service.orders.registered" {
+		t.Fatalf("unexpected payload %+v", p)
+	}
+
+	// The success is counted once the response is read, after the handler.
+	want := `messaging_webhook_deliveries_total{result="ok",webhook="cmdb"} 1`
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w := httptest.NewRecorder()
+		sink.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if strings.Contains(w.Body.String(), want) {
+			if retries := `messaging_webhook_deliveries_total{result="retry",webhook="cmdb"} 2`; !strings.Contains(w.Body.String(), retries) {
+				t.Errorf("expected %q in:\n%s", retries, w.Body.String())
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q in:\n%s", want, w.Body.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookNotifier_FiltersAndGivesUp(t *testing.T) {
+	rcv := &webhookReceiver{failures: 100, status: http.StatusBadRequest, done: make(chan struct{})}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	n := newTestNotifier(t, []Webhook{{URL: srv.URL, Events: []string{"ServiceDeregisteredEvent"}}}, nil)
+
+	n.Notify(ServiceRegisteredEvent{ServiceName: "orders"})
+	if len(n.queues[0]) != 0 {
+		t.Fatal("expected an unsubscribed event not to be queued")
+	}
+
+	n.Notify(ServiceDeregisteredEvent{ServiceName: "orders"})
+	n.deliver(context.Background(), n.hooks[0], nil, <-n.queues[0])
+	if rcv.attempts != 1 {
+		t.Fatalf("expected a 4xx not to be retried, got %d attempts", rcv.attempts)
+	}
+}
+
+func TestWebhookNotifier_RoutesByService(t *testing.T) {
+	n := newTestNotifier(t, []Webhook{
+		{URL: "http://orders.invalid", Services: []string{"orders"}},
+		{URL: "http://all.invalid"},
+	}, nil)
+
+	tests := []struct {
+		name  string
+		event any
+		want  []int
+	}{
+		{"matching service", ServiceHealthChangedEvent{ServiceName: "orders"}, []int{1, 1}},
+		{"other service", ServiceHealthChangedEvent{ServiceName: "billing"}, []int{0, 1}},
+		{"batch containing service", ServiceBatchDeregisteredEvent{Instances: []DeregisteredInstance{{ServiceName: "billing"}, {ServiceName: "orders"}}}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n.Notify(tt.event)
+			for i, q := range n.queues {
+				if len(q) != tt.want[i] {
+					t.Errorf("webhook %d: expected %d queued, got %d", i, tt.want[i], len(q))
+				}
+				for len(q) > 0 {
+					<-q
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_RendersTemplate(t *testing.T) {
+	var (
+		body        string
+		contentType string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body, contentType = string(b), r.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+
+	tmpl := `{"text": {{json (printf "%s is %s" .Message.ServiceName .Message.CurrentStatus)}}}`
+	n := newTestNotifier(t, []Webhook{{URL: srv.URL, Template: tmpl, ContentType: "application/vnd.chat+json"}}, nil)
+	n.Notify(ServiceHealthChangedEvent{ServiceName: "orders", CurrentStatus: "Unhealthy"})
+	n.deliver(context.Background(), n.hooks[0], n.templates[0], <-n.queues[0])
+
+	if want := `{"text": "orders is Unhealthy"}`; body != want {
+		t.Fatalf("expected body %s, got %s", want, body)
+	}
+	if contentType != "application/vnd.chat+json" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+
+	if _, err := NewWebhookNotifier([]Webhook{{URL: srv.URL, Template: "{{.Message"}}, WebhookOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("expected an invalid template to be rejected")
+	}
+}
+
+func TestNewWebhookNotifier_RequiresURL(t *testing.T) {
+	if _, err := NewWebhookNotifier([]Webhook{{Name: "slack"}}, WebhookOptions{}, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("expected an error for a webhook without a URL")
+	}
+}
@@ -0,0 +1,233 @@
+// Package proxyproto implements a net.Listener that understands the HAProxy
+// PROXY protocol (v1 text and v2 binary headers), so servers behind an L4
+// load balancer see the original client address as the connection's remote
+// address.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config controls header parsing.
+type Config struct {
+	// TrustedProxies limits header parsing to connections from these
+	// networks. Headers from anyone else are not interpreted, so clients
+	// cannot spoof their address. Empty trusts every peer.
+	TrustedProxies []netip.Prefix
+	// Required rejects trusted connections that do not start with a header.
+	Required bool
+	// HeaderTimeout bounds how long to wait for the header.
+	HeaderTimeout time.Duration
+}
+
+// Listener wraps a net.Listener, parsing PROXY headers on accepted
+// connections. Parsing happens lazily in the connection's own goroutine (on
+// first Read or RemoteAddr), so a slow peer never blocks Accept.
+type Listener struct {
+	net.Listener
+	config Config
+}
+
+// NewListener wraps inner.
+func NewListener(inner net.Listener, config Config) *Listener {
+	return &Listener{Listener: inner, config: config}
+}
+
+// Accept returns the next connection, wrapped for header parsing.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: c, config: l.config, reader: bufio.NewReader(c)}, nil
+}
+
+// Conn is a connection whose remote address comes from its PROXY header.
+type Conn struct {
+	net.Conn
+	config Config
+	reader *bufio.Reader
+
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+// Read reads connection data following the header. If the header is
+// malformed (or missing when required) Read returns the parse error.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.parse)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY header, or the peer
+// address if there was none.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.parse)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) parse() {
+	if !c.trusted() {
+		return
+	}
+	if c.config.HeaderTimeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.config.HeaderTimeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+	addr, err := readHeader(c.reader, c.config.Required)
+	if err != nil {
+		c.err = fmt.Errorf("proxy protocol: %w", err)
+		c.Conn.Close()
+		return
+	}
+	c.remoteAddr = addr
+}
+
+func (c *Conn) trusted() bool {
+	if len(c.config.TrustedProxies) == 0 {
+		return true
+	}
+	tcp, ok := c.Conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcp.IP)
+	if !ok {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range c.config.TrustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	errNoHeader = errors.New("missing header")
+)
+
+// readHeader consumes a v1 or v2 header from r and returns the source
+// address it carries. It returns a nil address for headers that carry none
+// (v1 UNKNOWN, v2 LOCAL) and, unless required, when no header is present.
+func readHeader(r *bufio.Reader, required bool) (net.Addr, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		if required {
+			return nil, err
+		}
+		return nil, nil
+	}
+	switch first[0] {
+	case v1Prefix[0]:
+		if b, err := r.Peek(len(v1Prefix)); err == nil && bytes.Equal(b, v1Prefix) {
+			return readV1(r)
+		}
+	case v2Signature[0]:
+		if b, err := r.Peek(len(v2Signature)); err == nil && bytes.Equal(b, v2Signature) {
+			return readV2(r)
+		}
+	}
+	if required {
+		return nil, errNoHeader
+	}
+	return nil, nil
+}
+
+// maxV1Length is the longest valid v1 header, including CRLF.
+const maxV1Length = 107
+
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1Length {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header too long or not CRLF terminated")
+	}
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", line)
+	}
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("v1 source address: %w", err)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("v1 source port: %w", err)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	verCmd, family := hdr[12], hdr[13]
+	length := int(binary.BigEndian.Uint16(hdr[14:16]))
+	if verCmd>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 version %d", verCmd>>4)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	switch verCmd & 0x0f {
+	case 0x0: // LOCAL: health checks from the proxy itself
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported v2 command %d", verCmd&0x0f)
+	}
+
+	var ipLen int
+	switch family >> 4 {
+	case 0x1:
+		ipLen = 4
+	case 0x2:
+		ipLen = 16
+	default: // AF_UNSPEC or AF_UNIX: no usable address
+		return nil, nil
+	}
+	if len(payload) < 2*ipLen+4 {
+		return nil, errors.New("v2 address block too short")
+	}
+	ip, _ := netip.AddrFromSlice(payload[:ipLen])
+	port := binary.BigEndian.Uint16(payload[2*ipLen : 2*ipLen+2])
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip.Unmap(), port)), nil
+}
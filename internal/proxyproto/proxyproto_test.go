@@ -0,0 +1,115 @@
+package proxyproto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func v2Header(cmd byte, src netip.AddrPort) []byte {
+	var payload []byte
+	family := byte(0x11)
+	if src.Addr().Is6() {
+		family = 0x21
+	}
+	payload = append(payload, src.Addr().AsSlice()...)
+	payload = append(payload, make([]byte, len(src.Addr().AsSlice()))...) // dst
+	payload = binary.BigEndian.AppendUint16(payload, src.Port())
+	payload = binary.BigEndian.AppendUint16(payload, 443)
+	payload = append(payload, 0x04, 0x00, 0x01, 'x') // a TLV to skip
+
+	hdr := append([]byte(nil), v2Signature...)
+	hdr = append(hdr, 0x20|cmd, family)
+	hdr = binary.BigEndian.AppendUint16(hdr, uint16(len(payload)))
+	return append(hdr, payload...)
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		required bool
+		want     string // expected address, "" for none
+		wantErr  bool
+	}{
+		{"v1 tcp4", "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET /", false, "203.0.113.7:51234", false},
+		{"v1 tcp6", "PROXY TCP6 2001:db8::7 2001:db8::1 51234 443\r\nGET /", false, "[2001:db8::7]:51234", false},
+		{"v1 unknown", "PROXY UNKNOWN\r\nGET /", false, "", false},
+		{"v1 malformed", "PROXY TCP4 nope\r\nGET /", false, "", true},
+		{"v2 tcp4", string(v2Header(0x1, netip.MustParseAddrPort("198.51.100.9:4000"))) + "GET /", false, "198.51.100.9:4000", false},
+		{"v2 tcp6", string(v2Header(0x1, netip.MustParseAddrPort("[2001:db8::9]:4000"))) + "GET /", false, "[2001:db8::9]:4000", false},
+		{"v2 local", string(v2Header(0x0, netip.MustParseAddrPort("198.51.100.9:4000"))) + "GET /", false, "", false},
+		{"no header optional", "GET / HTTP/1.1\r\n", false, "", false},
+		{"no header required", "GET / HTTP/1.1\r\n", true, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			addr, err := readHeader(r, tt.required)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Fatalf("expected address %q, got %q", tt.want, got)
+			}
+			// The application data must follow untouched.
+			rest, _ := io.ReadAll(r)
+			if !strings.HasPrefix(string(rest), "GET /") {
+				t.Fatalf("expected remaining data to start with the request, got %q", rest)
+			}
+		})
+	}
+}
+
+func TestListener_RewritesRemoteAddrForTrustedPeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []netip.Prefix
+		want    string
+	}{
+		{"trusted", []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, "203.0.113.7"},
+		{"untrusted", []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ln := NewListener(inner, Config{TrustedProxies: tt.trusted, HeaderTimeout: time.Second})
+			defer ln.Close()
+
+			go func() {
+				c, err := net.Dial("tcp", inner.Addr().String())
+				if err != nil {
+					return
+				}
+				defer c.Close()
+				io.WriteString(c, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nhello")
+			}()
+
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			if host != tt.want {
+				t.Fatalf("expected remote host %s, got %s", tt.want, host)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// Watcher is implemented by registries that stream catalog changes, such as
+// Consul's. Both channels send the full current result first, then again on
+// every change, and are closed when ctx is cancelled.
+type Watcher interface {
+	WatchServices(ctx context.Context, wait time.Duration) <-chan []string
+	WatchInstances(ctx context.Context, serviceName string, wait time.Duration) <-chan []types.Instance
+}
+
+var _ Watcher = (*consul.Registry)(nil)
+
+// Catalog keeps the instances of every service current from a Watcher: one
+// watch lists the services, and each listed service has its own instance
+// watch, started and stopped as services come and go.
+type Catalog struct {
+	watcher  Watcher
+	wait     time.Duration
+	onChange func(serviceName string, instances []types.Instance)
+
+	mu        sync.Mutex
+	listed    bool                          // a service list has arrived
+	instances map[string][]types.Instance   // services whose instances have arrived
+	watches   map[string]context.CancelFunc // instance watch of each listed service
+}
+
+// WatchCatalog starts watching the catalog until ctx is cancelled. onChange,
+// if set, is called with a service's instances each time they change, and
+// with nil once the service is gone. Calls are serialized, in the order the
+// changes were seen, and must not call back into the Catalog. Each query
+// blocks for up to wait.
+func WatchCatalog(ctx context.Context, watcher Watcher, wait time.Duration, onChange func(serviceName string, instances []types.Instance)) *Catalog {
+	c := &Catalog{
+		watcher:   watcher,
+		wait:      wait,
+		onChange:  onChange,
+		instances: make(map[string][]types.Instance),
+		watches:   make(map[string]context.CancelFunc),
+	}
+	go c.run(ctx)
+	return c
+}
+
+// Snapshot returns the instances of every service, or false until the
+// service list and the instances of every listed service have arrived.
+func (c *Catalog) Snapshot() (map[string][]types.Instance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.listed || len(c.instances) != len(c.watches) {
+		return nil, false
+	}
+	return maps.Clone(c.instances), true
+}
+
+func (c *Catalog) run(ctx context.Context) {
+	for services := range c.watcher.WatchServices(ctx, c.wait) {
+		c.setServices(ctx, services)
+	}
+}
+
+// setServices starts instance watches for new services and stops those of
+// services no longer listed.
+func (c *Catalog) setServices(ctx context.Context, services []string) {
+	listed := make(map[string]struct{}, len(services))
+	for _, name := range services {
+		listed[name] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listed = true
+	for name, cancel := range c.watches {
+		if _, ok := listed[name]; !ok {
+			cancel()
+			delete(c.watches, name)
+			if _, seen := c.instances[name]; seen {
+				delete(c.instances, name)
+				if c.onChange != nil {
+					c.onChange(name, nil)
+				}
+			}
+		}
+	}
+	for name := range listed {
+		if _, ok := c.watches[name]; ok {
+			continue
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		c.watches[name] = cancel
+		go c.watchInstances(watchCtx, name)
+	}
+}
+
+func (c *Catalog) watchInstances(ctx context.Context, serviceName string) {
+	for instances := range c.watcher.WatchInstances(ctx, serviceName, c.wait) {
+		if !c.setInstances(ctx, serviceName, instances) {
+			return
+		}
+	}
+}
+
+// setInstances records a service's instances, unless the service was removed
+// while the update was in flight.
+func (c *Catalog) setInstances(ctx context.Context, serviceName string, instances []types.Instance) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ctx.Err() != nil {
+		return false
+	}
+	if instances == nil {
+		instances = []types.Instance{}
+	}
+	c.instances[serviceName] = instances
+	if c.onChange != nil {
+		c.onChange(serviceName, instances)
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// fakeWatcher streams whatever the test sends on its channels.
+type fakeWatcher struct {
+	services  chan []string
+	mu        sync.Mutex
+	instances map[string]chan []types.Instance
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{services: make(chan []string), instances: make(map[string]chan []types.Instance)}
+}
+
+func (f *fakeWatcher) WatchServices(ctx context.Context, wait time.Duration) <-chan []string {
+	return f.services
+}
+
+func (f *fakeWatcher) WatchInstances(ctx context.Context, serviceName string, wait time.Duration) <-chan []types.Instance {
+	return f.instanceChan(serviceName)
+}
+
+func (f *fakeWatcher) instanceChan(serviceName string) chan []types.Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch, ok := f.instances[serviceName]
+	if !ok {
+		ch = make(chan []types.Instance)
+		f.instances[serviceName] = ch
+	}
+	return ch
+}
+
+type catalogChange struct {
+	service   string
+	instances []types.Instance
+}
+
+func TestCatalog_FollowsServicesAndInstances(t *testing.T) {
+	w := newFakeWatcher()
+	changes := make(chan catalogChange, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := WatchCatalog(ctx, w, time.Minute, func(service string, instances []types.Instance) {
+		changes <- catalogChange{service, instances}
+	})
+
+	next := func() catalogChange {
+		t.Helper()
+		select {
+		case ch := <-changes:
+			return ch
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a catalog change")
+			return catalogChange{}
+		}
+	}
+
+	w.services <- []string{"api", "web"}
+	if _, ok := c.Snapshot(); ok {
+		t.Fatal("snapshot should not be ready before every service's instances arrive")
+	}
+
+	w.instanceChan("api") <- []types.Instance{{ServiceID: "api-1"}}
+	if got := next(); got.service != "api" || len(got.instances) != 1 {
+		t.Fatalf("unexpected change %+v", got)
+	}
+	w.instanceChan("web") <- nil
+	if got := next(); got.service != "web" || got.instances == nil || len(got.instances) != 0 {
+		t.Fatalf("expected web with no instances, got %+v", got)
+	}
+	snap, ok := c.Snapshot()
+	if !ok || len(snap) != 2 || snap["api"][0].ServiceID != "api-1" {
+		t.Fatalf("unexpected snapshot %+v (ready %v)", snap, ok)
+	}
+
+	// web is deregistered: its removal is reported and it leaves the snapshot.
+	w.services <- []string{"api"}
+	if got := next(); got.service != "web" || got.instances != nil {
+		t.Fatalf("expected web to be removed, got %+v", got)
+	}
+	if snap, ok := c.Snapshot(); !ok || len(snap) != 1 {
+		t.Fatalf("unexpected snapshot after removal %+v (ready %v)", snap, ok)
+	}
+}
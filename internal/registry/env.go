@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/etcd"
+	"github.com/toska-mesh/toska-mesh/internal/kubernetes"
+)
+
+// ConfigFromEnv reads REGISTRY_BACKEND, the CONSUL_* read settings and the
+// ETCD_* and KUBERNETES_* backend settings, so every binary selects its
+// registry the same way.
+func ConfigFromEnv(consulAddr string) Config {
+	cfg := Config{
+		Backend:       BackendConsul,
+		ConsulAddress: consulAddr,
+		Etcd:          etcd.DefaultConfig(),
+		Kubernetes:    kubernetes.DefaultConfig(),
+	}
+	if v := os.Getenv("REGISTRY_BACKEND"); v != "" {
+		cfg.Backend = v
+	}
+	cfg.ConsulReads.AllowStale = os.Getenv("CONSUL_ALLOW_STALE") == "true"
+	cfg.ConsulReads.UseCache = os.Getenv("CONSUL_USE_CACHE") == "true"
+	if v, err := strconv.Atoi(os.Getenv("CONSUL_MAX_STALE_SECONDS")); err == nil && v > 0 {
+		cfg.ConsulReads.MaxStale = time.Duration(v) * time.Second
+	}
+	if v := os.Getenv("ETCD_ENDPOINTS"); v != "" {
+		cfg.Etcd.Endpoints = splitComma(v)
+	}
+	if v := os.Getenv("ETCD_PREFIX"); v != "" {
+		cfg.Etcd.Prefix = v
+	}
+	if v := os.Getenv("KUBERNETES_NAMESPACE"); v != "" {
+		cfg.Kubernetes.Namespace = v
+	}
+	if v := os.Getenv("KUBERNETES_ENDPOINT_PORT_NAME"); v != "" {
+		cfg.Kubernetes.PortName = v
+	}
+	return cfg
+}
+
+// splitComma splits a comma-separated list, dropping empty entries.
+func splitComma(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+// Package registry defines the service registry abstraction the control
+// plane is written against, and selects a backend at start-up. Consul is the
+// default; etcd is available for environments that standardize on it, and
+// the read-only Kubernetes backend routes to pods from EndpointSlices.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/consul"
+	"github.com/toska-mesh/toska-mesh/internal/etcd"
+	"github.com/toska-mesh/toska-mesh/internal/kubernetes"
+	"github.com/toska-mesh/toska-mesh/internal/types"
+)
+
+// Registry stores service instances with TTL health checks, plus a small
+// key/value store for gateway configuration.
+type Registry interface {
+	// Register adds or replaces an instance. Its health check starts
+	// passing and must be renewed with UpdateHealth within the TTL.
+	Register(reg types.Registration) error
+	// Deregister removes an instance.
+	Deregister(serviceID string) error
+	// GetInstances returns all instances of a service, including health.
+	GetInstances(serviceName string) ([]types.Instance, error)
+	// GetServices returns the names of all registered services.
+	GetServices() ([]string, error)
+	// GetInstance returns one instance by ID, or nil if not found.
+	GetInstance(serviceID string) (*types.Instance, error)
+	// UpdateHealth renews an instance's TTL check with the given status.
+	UpdateHealth(serviceID string, status types.HealthStatus, output string) error
+	// UpdateMetadata merges meta into an instance's metadata.
+	UpdateMetadata(serviceID string, meta map[string]string) error
+	// ApplyMetadata applies update to an instance's metadata, returning
+	// types.ErrNotRegistered if the instance is unknown.
+	ApplyMetadata(serviceID string, update types.MetadataUpdate) (types.MetadataChange, error)
+	// WaitForChange blocks until the service catalog changes after index or
+	// wait elapses, returning the new index. Index 0 returns immediately.
+	WaitForChange(ctx context.Context, index uint64, wait time.Duration) (uint64, error)
+
+	// ListKV blocks like WaitForChange for keys under prefix, returning
+	// their values keyed by full key.
+	ListKV(ctx context.Context, prefix string, index uint64, wait time.Duration) (map[string][]byte, uint64, error)
+	// PutKV writes value to key.
+	PutKV(key string, value []byte) error
+	// DeleteKV removes key.
+	DeleteKV(key string) error
+}
+
+var (
+	_ Registry = (*consul.Registry)(nil)
+	_ Registry = (*etcd.Registry)(nil)
+	_ Registry = (*kubernetes.Registry)(nil)
+)
+
+// Backend names accepted by Config.Backend.
+const (
+	BackendConsul     = "consul"
+	BackendEtcd       = "etcd"
+	BackendKubernetes = "kubernetes"
+)
+
+// Config selects and configures a backend.
+type Config struct {
+	Backend       string // consul (default), etcd or kubernetes
+	ConsulAddress string
+	Etcd          etcd.Config
+	Kubernetes    kubernetes.Config
+
+	// ConsulDatacenters are the datacenters Consul queries instances
+	// across, for registries that route between datacenters.
+	ConsulDatacenters []string
+	// ConsulReads allows stale or agent-cached Consul lookups.
+	ConsulReads consul.ReadConfig
+}
+
+// New creates the configured registry.
+func New(cfg Config, logger *slog.Logger) (Registry, error) {
+	switch cfg.Backend {
+	case "", BackendConsul:
+		return consul.NewRegistryWithConfig(consul.Config{
+			Address:     cfg.ConsulAddress,
+			Datacenters: cfg.ConsulDatacenters,
+			Reads:       cfg.ConsulReads,
+		}, logger)
+	case BackendEtcd:
+		return etcd.NewRegistry(cfg.Etcd, logger)
+	case BackendKubernetes:
+		return kubernetes.NewRegistry(cfg.Kubernetes, logger)
+	default:
+		return nil, fmt.Errorf("unknown registry backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,55 @@
+package router
+
+import "time"
+
+type affinityKey struct {
+	serviceName string
+	sessionID   string
+}
+
+type affinityEntry struct {
+	serviceID string
+	expires   time.Time
+}
+
+// pinned returns the candidate the session is pinned to, extending the pin,
+// or nil if the session is not pinned or its instance is not a candidate.
+func (lb *LoadBalancer) pinned(serviceName, sessionID string, candidates []Instance) *Instance {
+	now := lb.now()
+	key := affinityKey{serviceName, sessionID}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	entry, ok := lb.affinity[key]
+	if !ok || now.After(entry.expires) {
+		return nil
+	}
+	for i := range candidates {
+		if candidates[i].ServiceID == entry.serviceID {
+			entry.expires = now.Add(lb.config.AffinityTTL)
+			lb.affinity[key] = entry
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+// pin routes the session to serviceID until it is idle for AffinityTTL.
+// Expired pins are swept at most once per TTL.
+func (lb *LoadBalancer) pin(serviceName, sessionID, serviceID string) {
+	now := lb.now()
+	ttl := lb.config.AffinityTTL
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.affinity[affinityKey{serviceName, sessionID}] = affinityEntry{serviceID: serviceID, expires: now.Add(ttl)}
+	if now.Sub(lb.affinitySwept) < ttl {
+		return
+	}
+	lb.affinitySwept = now
+	for key, entry := range lb.affinity {
+		if now.After(entry.expires) {
+			delete(lb.affinity, key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSelect_AffinityKeepsSessionsWhenInstancesChange(t *testing.T) {
+	iphash := map[string]string{"lb_strategy": "IPHash"}
+	provider := newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, iphash),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, iphash),
+	)
+	lb := NewLoadBalancerWithConfig(provider, Config{AffinityTTL: time.Minute}, nil)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	pinnedTo := map[string]string{}
+	for i := range 20 {
+		session := fmt.Sprintf("client-%d", i)
+		inst, _ := lb.Select("api", Context{SessionID: session})
+		pinnedTo[session] = inst.ServiceID
+	}
+
+	// A new instance changes every IPHash modulo, but pinned sessions stay.
+	provider.instances["api"] = append(provider.instances["api"], makeInstanceWithMeta("svc-3", "api", HealthHealthy, iphash))
+	for session, id := range pinnedTo {
+		if inst, _ := lb.Select("api", Context{SessionID: session}); inst.ServiceID != id {
+			t.Fatalf("%s moved from %s to %s", session, id, inst.ServiceID)
+		}
+	}
+
+	// Sessions pinned to an instance that goes away are re-routed and
+	// pinned to the new instance.
+	provider.instances["api"] = provider.instances["api"][1:] // drop svc-1
+	for session, id := range pinnedTo {
+		inst, _ := lb.Select("api", Context{SessionID: session})
+		if id == "svc-1" {
+			if inst.ServiceID == "svc-1" {
+				t.Fatalf("%s still routed to removed svc-1", session)
+			}
+			pinnedTo[session] = inst.ServiceID
+		} else if inst.ServiceID != id {
+			t.Fatalf("%s moved from %s to %s", session, id, inst.ServiceID)
+		}
+	}
+
+	// Idle pins expire and are swept.
+	now = now.Add(2 * time.Minute)
+	lb.Select("api", Context{SessionID: "newcomer"})
+	if n := len(lb.affinity); n != 1 {
+		t.Fatalf("expected expired pins to be swept, %d remain", n)
+	}
+}
@@ -1,86 +1,224 @@
 package router
 
 import (
+	"container/list"
 	"math"
 	"math/rand/v2"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+	"github.com/toska-mesh/toska-mesh/internal/types"
 )
 
 // LoadBalancer implements the Balancer interface with support for multiple strategies.
 type LoadBalancer struct {
-	provider InstanceProvider
-
-	mu              sync.Mutex
-	roundRobinIdx   map[string]*atomic.Int64
-	connectionCount map[string]map[string]*atomic.Int64
-	stats           map[string]*serviceStats
+	provider  InstanceProvider
+	telemetry telemetry.Sink
+	config    Config
+
+	mu            sync.Mutex
+	roundRobinIdx map[string]*atomic.Int64
+	connections   map[string]*connection // keyed by service ID
+	stats         map[string]*serviceStats
+	rings         map[ringKey]*list.Element // values are *hashRing
+	ringLRU       *list.List                // front is most recently used
+	subsets       map[string]*cachedSubset
+	healthySince  map[string]map[string]time.Time
+	draining      map[string]struct{} // keyed by service ID
+	affinity      map[affinityKey]affinityEntry
+	affinitySwept time.Time
+	strategies    map[string]Strategy // last strategy used, keyed by service name
+	conflicts     map[string]bool     // services whose instances disagree on lb_strategy
+	instanceCache map[string]*cachedInstances
+	collected     map[string]time.Time // last connection counter GC, keyed by service name
+	now           func() time.Time     // for testing
 }
 
 // NewLoadBalancer creates a LoadBalancer that fetches instances from provider.
 func NewLoadBalancer(provider InstanceProvider) *LoadBalancer {
+	return NewLoadBalancerWithTelemetry(provider, nil)
+}
+
+// NewLoadBalancerWithTelemetry is like NewLoadBalancer but records selection
+// metrics to sink. A nil sink disables metrics.
+func NewLoadBalancerWithTelemetry(provider InstanceProvider, sink telemetry.Sink) *LoadBalancer {
+	return NewLoadBalancerWithConfig(provider, Config{}, sink)
+}
+
+// NewLoadBalancerWithConfig is like NewLoadBalancerWithTelemetry but tuned
+// by config.
+func NewLoadBalancerWithConfig(provider InstanceProvider, config Config, sink telemetry.Sink) *LoadBalancer {
 	return &LoadBalancer{
-		provider:        provider,
-		roundRobinIdx:   make(map[string]*atomic.Int64),
-		connectionCount: make(map[string]map[string]*atomic.Int64),
-		stats:           make(map[string]*serviceStats),
+		provider:      provider,
+		telemetry:     telemetry.OrNop(sink),
+		config:        config,
+		roundRobinIdx: make(map[string]*atomic.Int64),
+		connections:   make(map[string]*connection),
+		stats:         make(map[string]*serviceStats),
+		rings:         make(map[ringKey]*list.Element),
+		ringLRU:       list.New(),
+		subsets:       make(map[string]*cachedSubset),
+		healthySince:  make(map[string]map[string]time.Time),
+		draining:      make(map[string]struct{}),
+		affinity:      make(map[affinityKey]affinityEntry),
+		strategies:    make(map[string]Strategy),
+		conflicts:     make(map[string]bool),
+		instanceCache: make(map[string]*cachedInstances),
+		collected:     make(map[string]time.Time),
+		now:           time.Now,
 	}
 }
 
+// Select picks an instance and immediately commits the selection. It is
+// equivalent to Reserve followed by Commit.
 func (lb *LoadBalancer) Select(serviceName string, ctx Context) (*Instance, error) {
-	instances, err := lb.provider.GetInstances(serviceName)
+	res, err := lb.Reserve(serviceName, ctx)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	res.Commit()
+	return res.Instance, nil
+}
+
+// Reserve picks an instance and counts it as in-flight so that concurrent
+// selections see the pending work. The caller must either Commit the
+// reservation once the request is actually sent, or Abort it to release the
+// slot. Returns nil if no instance is available.
+func (lb *LoadBalancer) Reserve(serviceName string, ctx Context) (*Reservation, error) {
+	instances, err := lb.getInstances(serviceName)
 	if err != nil {
 		return nil, err
 	}
 
-	candidates := filterHealthy(instances)
+	lb.collectConnections(serviceName, instances)
+	instances = lb.withoutDraining(instances)
+	shares := lb.warmupShares(serviceName, instances)
+
+	if len(ctx.MetadataFilter) > 0 {
+		instances = filterMetadata(instances, ctx.MetadataFilter)
+	}
+	if len(ctx.ExcludeMetadataKeys) > 0 {
+		instances = withoutMetadataKeys(instances, ctx.ExcludeMetadataKeys)
+	}
+	instances = lb.preferLocalDatacenter(instances)
+	instances = priorityGroup(instances)
+
+	// Route within this balancer's subset while it has healthy instances.
+	candidates := filterHealthy(lb.subset(serviceName, instances))
+	if len(candidates) == 0 {
+		candidates = filterHealthy(instances)
+	}
 	if len(candidates) == 0 {
 		candidates = filterNonUnknown(instances)
 	}
 	if len(candidates) == 0 {
 		return nil, nil
 	}
+	if len(ctx.Exclude) > 0 {
+		if untried := excludeIDs(candidates, ctx.Exclude); len(untried) > 0 {
+			candidates = untried
+		}
+	}
+	if len(ctx.PreferMetadata) > 0 {
+		if preferred := filterMetadata(candidates, ctx.PreferMetadata); len(preferred) > 0 {
+			candidates = preferred
+		}
+	}
 
-	strategy := resolveStrategy(candidates)
+	strategy := lb.resolveStrategy(serviceName, candidates)
+	// Hash strategies keep every instance so keys stay where they map.
+	if !strategy.hashed() {
+		candidates = applySlowStart(candidates, shares)
+	}
+
+	// Sessions of hash strategies stick to their instance while it lasts.
+	session := ctx.SessionID
+	if strategy == HashByHeader {
+		session = lb.shardKey(ctx)
+	}
+	affinity := lb.config.AffinityTTL > 0 && session != "" && strategy.hashed()
 	var selected *Instance
+	if affinity {
+		selected = lb.pinned(serviceName, session, candidates)
+	}
+	if selected == nil {
+		selected = lb.selectWith(strategy, serviceName, candidates, ctx)
+		if affinity && selected != nil {
+			lb.pin(serviceName, session, selected.ServiceID)
+		}
+	}
 
-	switch strategy {
-	case LeastConnections:
-		selected = lb.selectLeastConnections(serviceName, candidates)
-	case WeightedRoundRobin:
-		selected = lb.selectWeightedRoundRobin(serviceName, candidates)
-	case IPHash:
-		selected = selectIPHash(candidates, ctx)
-	case Random:
-		selected = selectRandom(candidates)
-	default:
-		selected = lb.selectRoundRobin(serviceName, candidates)
+	if selected == nil {
+		return nil, nil
 	}
+	lb.recordSelection(serviceName, strategy, selected.ServiceID)
+
+	conn := lb.connectionFor(serviceName, selected.ServiceID)
+	lb.recordActive(serviceName, selected.ServiceID, conn.active.Add(1))
+
+	return &Reservation{
+		Instance:    selected,
+		lb:          lb,
+		serviceName: serviceName,
+		conn:        conn,
+	}, nil
+}
+
+// Reservation is a selected instance whose in-flight slot is held until the
+// caller either commits or aborts it.
+type Reservation struct {
+	Instance *Instance
 
-	if selected != nil {
-		lb.recordRequest(serviceName, selected)
+	lb          *LoadBalancer
+	serviceName string
+	conn        *connection
+	state       atomic.Int32 // reservationPending, reservationCommitted, reservationAborted
+}
+
+const (
+	reservationPending int32 = iota
+	reservationCommitted
+	reservationAborted
+)
+
+// Commit confirms that the request is being sent to the reserved instance.
+// The in-flight slot stays held until ReportResult is called for the instance.
+// Calling Commit after Commit or Abort is a no-op.
+func (r *Reservation) Commit() {
+	if r.state.CompareAndSwap(reservationPending, reservationCommitted) {
+		r.lb.recordRequest(r.serviceName, r.Instance)
 	}
+}
 
-	return selected, nil
+// Abort releases the in-flight slot without recording a request, e.g. when
+// the circuit breaker rejects the instance. Calling Abort after Commit or
+// Abort is a no-op.
+func (r *Reservation) Abort() {
+	if r.state.CompareAndSwap(reservationPending, reservationAborted) {
+		decrementFloor(&r.conn.active)
+		r.lb.recordActive(r.serviceName, r.Instance.ServiceID, r.conn.active.Load())
+	}
 }
 
 func (lb *LoadBalancer) ReportResult(serviceID string, result RequestResult) {
+	slice := lb.errorRateSlice()
+
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 
-	// Decrement connection count across all services.
-	for _, counts := range lb.connectionCount {
-		if c, ok := counts[serviceID]; ok {
-			if v := c.Load(); v > 0 {
-				c.Add(-1)
-			}
-		}
+	if c, ok := lb.connections[serviceID]; ok {
+		decrementFloor(&c.active)
+		lb.recordActive(c.serviceName, serviceID, c.active.Load())
 	}
 
 	if s, ok := lb.stats[serviceID]; ok {
-		s.report(result)
+		s.report(result, slice)
+		lb.recordResult(s.serviceName, serviceID, result.Success)
 	}
 }
 
@@ -91,11 +229,15 @@ func (lb *LoadBalancer) Stats(serviceName string) Stats {
 	var totalReq, successReq, failedReq int64
 	var totalTicks int64
 	instanceCounts := make(map[string]int)
+	instanceLatency := make(map[string]Percentiles)
+	var latency latencyHistogram
 
-	for _, s := range lb.stats {
+	for id, s := range lb.stats {
 		if s.serviceName != serviceName {
 			continue
 		}
+		s.latency.mergeInto(&latency)
+		instanceLatency[id] = s.latency.percentiles()
 		totalReq += s.totalRequests.Load()
 		successReq += s.successfulRequests.Load()
 		failedReq += s.failedRequests.Load()
@@ -118,12 +260,38 @@ func (lb *LoadBalancer) Stats(serviceName string) Stats {
 		SuccessfulRequests:    int(successReq),
 		FailedRequests:        int(failedReq),
 		AverageResponseTime:   avg,
+		ResponseTimes:         latency.percentiles(),
 		InstanceRequestCounts: instanceCounts,
+		InstanceResponseTimes: instanceLatency,
+		DrainingInstances:     lb.drainingInFlight(serviceName),
 	}
 }
 
 // --- Strategy implementations ---
 
+func (lb *LoadBalancer) selectWith(strategy Strategy, serviceName string, candidates []Instance, ctx Context) *Instance {
+	switch strategy {
+	case LeastConnections:
+		return lb.selectLeastConnections(candidates)
+	case WeightedRoundRobin:
+		return lb.selectWeightedRoundRobin(serviceName, candidates)
+	case IPHash:
+		return selectIPHash(candidates, ctx)
+	case ConsistentHash:
+		return lb.selectConsistentHash(serviceName, candidates, ctx)
+	case HashByHeader:
+		return lb.selectHashByHeader(serviceName, candidates, ctx)
+	case PowerOfTwoChoices:
+		return lb.selectPowerOfTwoChoices(candidates)
+	case WeightedLeastRequest:
+		return lb.selectWeightedLeastRequest(candidates)
+	case Random:
+		return selectRandom(candidates)
+	default:
+		return lb.selectRoundRobin(serviceName, candidates)
+	}
+}
+
 func (lb *LoadBalancer) selectRoundRobin(serviceName string, instances []Instance) *Instance {
 	idx := lb.getRoundRobinIdx(serviceName)
 	n := idx.Add(1)
@@ -131,46 +299,84 @@ func (lb *LoadBalancer) selectRoundRobin(serviceName string, instances []Instanc
 	return &instances[i]
 }
 
-func (lb *LoadBalancer) selectLeastConnections(serviceName string, instances []Instance) *Instance {
-	counts := lb.getConnectionCounts(serviceName)
-
+func (lb *LoadBalancer) selectLeastConnections(instances []Instance) *Instance {
 	var best *Instance
 	var bestCount int64 = -1
 
 	for i := range instances {
-		c := lb.getOrCreateCounter(counts, instances[i].ServiceID)
-		v := c.Load()
+		v := lb.activeRequests(instances[i].ServiceID)
 		if bestCount < 0 || v < bestCount {
 			bestCount = v
 			best = &instances[i]
 		}
 	}
 
-	if best != nil {
-		c := lb.getOrCreateCounter(counts, best.ServiceID)
-		c.Add(1)
-	}
-
 	return best
 }
 
+// selectPowerOfTwoChoices compares the in-flight requests of two random
+// instances and picks the less loaded one, which tracks LeastConnections
+// closely without scanning every instance.
+func (lb *LoadBalancer) selectPowerOfTwoChoices(instances []Instance) *Instance {
+	if len(instances) == 1 {
+		return &instances[0]
+	}
+	i := rand.IntN(len(instances))
+	j := rand.IntN(len(instances) - 1)
+	if j >= i {
+		j++
+	}
+	if lb.activeRequests(instances[j].ServiceID) < lb.activeRequests(instances[i].ServiceID) {
+		return &instances[j]
+	}
+	return &instances[i]
+}
+
 func (lb *LoadBalancer) selectWeightedRoundRobin(serviceName string, instances []Instance) *Instance {
 	var weighted []Instance
 	for _, inst := range instances {
-		weight := 1
-		if w, ok := inst.Metadata["weight"]; ok {
-			if parsed, err := strconv.Atoi(w); err == nil && parsed > 0 {
-				weight = parsed
-			}
-		}
-		for range weight {
+		for range instanceWeight(inst) {
 			weighted = append(weighted, inst)
 		}
 	}
 	return lb.selectRoundRobin(serviceName+"-weighted", weighted)
 }
 
+// selectWeightedLeastRequest picks the instance with the fewest in-flight
+// requests per unit of weight, counting the request being placed, so an
+// instance of weight 2 carries twice the outstanding requests of one of
+// weight 1 under load.
+func (lb *LoadBalancer) selectWeightedLeastRequest(instances []Instance) *Instance {
+	var best *Instance
+	bestScore := math.Inf(1)
+	for i := range instances {
+		active := lb.activeRequests(instances[i].ServiceID)
+		if score := float64(active+1) / float64(instanceWeight(instances[i])); score < bestScore {
+			bestScore = score
+			best = &instances[i]
+		}
+	}
+	return best
+}
+
+// instanceWeight returns the instance's weight metadata, or 1 if it is
+// missing or not a positive integer.
+func instanceWeight(inst Instance) int {
+	if w, err := strconv.Atoi(inst.Metadata["weight"]); err == nil && w > 0 {
+		return w
+	}
+	return 1
+}
+
 func selectIPHash(instances []Instance, ctx Context) *Instance {
+	h := fnv1a(sessionKey(ctx))
+	i := h % uint32(len(instances))
+	return &instances[i]
+}
+
+// sessionKey returns the key hashing strategies route by: the session ID,
+// else the correlation ID header, else a random key.
+func sessionKey(ctx Context) string {
 	key := ctx.SessionID
 	if key == "" {
 		if ctx.Headers != nil {
@@ -180,9 +386,7 @@ func selectIPHash(instances []Instance, ctx Context) *Instance {
 	if key == "" {
 		key = strconv.FormatInt(rand.Int64(), 16)
 	}
-	h := fnv1a(key)
-	i := h % uint32(len(instances))
-	return &instances[i]
+	return key
 }
 
 func selectRandom(instances []Instance) *Instance {
@@ -192,10 +396,56 @@ func selectRandom(instances []Instance) *Instance {
 
 // --- Helpers ---
 
+// filterHealthy keeps healthy instances the health monitor is not holding
+// out of routing.
 func filterHealthy(instances []Instance) []Instance {
 	var out []Instance
 	for _, inst := range instances {
-		if inst.Status == HealthHealthy {
+		if inst.Status == HealthHealthy && inst.Metadata[types.MetadataHealthHold] == "" {
+			out = append(out, inst)
+		}
+	}
+	return out
+}
+
+func filterMetadata(instances []Instance, filter map[string]string) []Instance {
+	var out []Instance
+	for _, inst := range instances {
+		if matchesMetadata(inst.Metadata, filter) {
+			out = append(out, inst)
+		}
+	}
+	return out
+}
+
+// withoutMetadataKeys drops instances whose metadata has any of keys.
+func withoutMetadataKeys(instances []Instance, keys []string) []Instance {
+	var out []Instance
+next:
+	for _, inst := range instances {
+		for _, k := range keys {
+			if _, ok := inst.Metadata[k]; ok {
+				continue next
+			}
+		}
+		out = append(out, inst)
+	}
+	return out
+}
+
+func matchesMetadata(meta, filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := meta[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func excludeIDs(instances []Instance, ids []string) []Instance {
+	var out []Instance
+	for _, inst := range instances {
+		if !slices.Contains(ids, inst.ServiceID) {
 			out = append(out, inst)
 		}
 	}
@@ -212,13 +462,35 @@ func filterNonUnknown(instances []Instance) []Instance {
 	return out
 }
 
-func resolveStrategy(candidates []Instance) Strategy {
+// resolveStrategy picks the service's strategy: its entry in
+// Config.Strategies, else the lb_strategy declared by most candidates, else
+// Config.DefaultStrategy. Ties go to the strategy declared earliest in the
+// Strategy constants, so the choice never depends on instance order.
+// Candidates that disagree are flagged by the router_strategy_conflict
+// gauge.
+func (lb *LoadBalancer) resolveStrategy(serviceName string, candidates []Instance) Strategy {
+	if s, ok := lb.config.Strategies[strings.ToLower(serviceName)]; ok {
+		return s
+	}
+
+	votes := make(map[Strategy]int)
 	for _, inst := range candidates {
-		if s, ok := inst.Metadata["lb_strategy"]; ok && s != "" {
-			return ParseStrategy(s)
+		if name := inst.Metadata["lb_strategy"]; name != "" {
+			votes[ParseStrategy(name)]++
+		}
+	}
+	lb.recordStrategyConflict(serviceName, len(votes) > 1)
+	if len(votes) == 0 {
+		return lb.config.DefaultStrategy
+	}
+
+	best, bestVotes := RoundRobin, 0
+	for s, n := range votes {
+		if n > bestVotes || (n == bestVotes && s < best) {
+			best, bestVotes = s, n
 		}
 	}
-	return RoundRobin
+	return best
 }
 
 func (lb *LoadBalancer) getRoundRobinIdx(name string) *atomic.Int64 {
@@ -232,28 +504,6 @@ func (lb *LoadBalancer) getRoundRobinIdx(name string) *atomic.Int64 {
 	return idx
 }
 
-func (lb *LoadBalancer) getConnectionCounts(serviceName string) map[string]*atomic.Int64 {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-	counts, ok := lb.connectionCount[serviceName]
-	if !ok {
-		counts = make(map[string]*atomic.Int64)
-		lb.connectionCount[serviceName] = counts
-	}
-	return counts
-}
-
-func (lb *LoadBalancer) getOrCreateCounter(counts map[string]*atomic.Int64, serviceID string) *atomic.Int64 {
-	lb.mu.Lock()
-	defer lb.mu.Unlock()
-	c, ok := counts[serviceID]
-	if !ok {
-		c = &atomic.Int64{}
-		counts[serviceID] = c
-	}
-	return c
-}
-
 func (lb *LoadBalancer) recordRequest(serviceName string, inst *Instance) {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
@@ -279,6 +529,16 @@ func fnv1a(s string) uint32 {
 	return h
 }
 
+// decrementFloor decrements c without letting it go below zero.
+func decrementFloor(c *atomic.Int64) {
+	for {
+		v := c.Load()
+		if v <= 0 || c.CompareAndSwap(v, v-1) {
+			return
+		}
+	}
+}
+
 func abs64(n int64) int64 {
 	if n == math.MinInt64 {
 		return math.MaxInt64
@@ -297,9 +557,11 @@ type serviceStats struct {
 	successfulRequests atomic.Int64
 	failedRequests     atomic.Int64
 	totalResponseNanos atomic.Int64
+	latency            latencyHistogram
 
 	mu             sync.Mutex
 	instanceCounts map[string]int
+	outcomes       outcomeWindow
 }
 
 func newServiceStats(serviceName string) *serviceStats {
@@ -316,11 +578,15 @@ func (s *serviceStats) recordRequest(instanceID string) {
 	s.mu.Unlock()
 }
 
-func (s *serviceStats) report(result RequestResult) {
+func (s *serviceStats) report(result RequestResult, slice int64) {
 	if result.Success {
 		s.successfulRequests.Add(1)
 	} else {
 		s.failedRequests.Add(1)
 	}
 	s.totalResponseNanos.Add(int64(result.ResponseTime))
+	s.latency.record(result.ResponseTime)
+	s.mu.Lock()
+	s.outcomes.record(slice, !result.Success)
+	s.mu.Unlock()
 }
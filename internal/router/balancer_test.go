@@ -140,6 +140,25 @@ func TestSelect_LeastConnections_AlternatesInstances(t *testing.T) {
 	}
 }
 
+func TestSelect_PowerOfTwoChoices_AvoidsLoadedInstances(t *testing.T) {
+	p2c := map[string]string{"lb_strategy": "PowerOfTwoChoices"}
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, p2c),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, p2c),
+	))
+
+	// With two instances both are always compared, so requests held open
+	// alternate between them.
+	counts := map[string]int{}
+	for range 10 {
+		inst, _ := lb.Select("api", Context{})
+		counts[inst.ServiceID]++
+	}
+	if counts["svc-1"] != 5 || counts["svc-2"] != 5 {
+		t.Fatalf("expected 5/5 split, got %v", counts)
+	}
+}
+
 func TestSelect_WeightedRoundRobin_RespectsWeights(t *testing.T) {
 	lb := NewLoadBalancer(newProvider(
 		makeInstanceWithMeta("svc-heavy", "api", HealthHealthy, map[string]string{"lb_strategy": "WeightedRoundRobin", "weight": "3"}),
@@ -157,6 +176,31 @@ func TestSelect_WeightedRoundRobin_RespectsWeights(t *testing.T) {
 	}
 }
 
+func TestSelect_WeightedLeastRequest_BalancesOutstandingByWeight(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("svc-big", "api", HealthHealthy, map[string]string{"lb_strategy": "WeightedLeastRequest", "weight": "3"}),
+		makeInstanceWithMeta("svc-small", "api", HealthHealthy, map[string]string{"lb_strategy": "WeightedLeastRequest"}),
+	))
+
+	// With every request held open, outstanding requests split 3:1.
+	counts := map[string]int{}
+	for range 40 {
+		inst, _ := lb.Select("api", Context{})
+		counts[inst.ServiceID]++
+	}
+	if counts["svc-big"] != 30 || counts["svc-small"] != 10 {
+		t.Fatalf("expected 30/10 split, got %v", counts)
+	}
+
+	// Completing the big instance's requests sends new ones back to it.
+	for range 30 {
+		lb.ReportResult("svc-big", RequestResult{ServiceID: "svc-big", Success: true})
+	}
+	if inst, _ := lb.Select("api", Context{}); inst.ServiceID != "svc-big" {
+		t.Fatalf("expected svc-big once idle, got %s", inst.ServiceID)
+	}
+}
+
 func TestSelect_IPHash_SameSessionSameInstance(t *testing.T) {
 	lb := NewLoadBalancer(newProvider(
 		makeInstanceWithMeta("svc-1", "api", HealthHealthy, map[string]string{"lb_strategy": "IPHash"}),
@@ -291,6 +335,10 @@ func TestParseStrategy(t *testing.T) {
 		{"Random", Random},
 		{"WeightedRoundRobin", WeightedRoundRobin},
 		{"IPHash", IPHash},
+		{"consistent_hash", ConsistentHash},
+		{"p2c", PowerOfTwoChoices},
+		{"WeightedLeastRequest", WeightedLeastRequest},
+		{"hash_by_header", HashByHeader},
 		{"unknown", RoundRobin},
 		{"", RoundRobin},
 	}
@@ -302,3 +350,129 @@ func TestParseStrategy(t *testing.T) {
 		}
 	}
 }
+
+func TestReserve_AbortReleasesInFlightSlot(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, map[string]string{"lb_strategy": "LeastConnections"}),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, map[string]string{"lb_strategy": "LeastConnections"}),
+	))
+
+	first, err := lb.Reserve("api", Context{})
+	if err != nil || first == nil {
+		t.Fatalf("Reserve: %v, %v", first, err)
+	}
+	first.Abort()
+
+	second, _ := lb.Reserve("api", Context{})
+	if second.Instance.ServiceID != first.Instance.ServiceID {
+		t.Fatalf("expected aborted slot to be reusable: first %s, second %s",
+			first.Instance.ServiceID, second.Instance.ServiceID)
+	}
+	second.Abort()
+
+	if stats := lb.Stats("api"); stats.TotalRequests != 0 {
+		t.Fatalf("expected aborted reservations not to count as requests, got %d", stats.TotalRequests)
+	}
+}
+
+func TestReserve_PendingReservationCountsAsInFlight(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, map[string]string{"lb_strategy": "LeastConnections"}),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, map[string]string{"lb_strategy": "LeastConnections"}),
+	))
+
+	first, _ := lb.Reserve("api", Context{})
+	second, _ := lb.Reserve("api", Context{})
+	if first.Instance.ServiceID == second.Instance.ServiceID {
+		t.Fatalf("expected concurrent reservations on different instances, both got %s", first.Instance.ServiceID)
+	}
+}
+
+func TestReserve_CommitRecordsRequestOnce(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+	))
+
+	res, _ := lb.Reserve("api", Context{})
+	res.Commit()
+	res.Commit()
+	res.Abort() // no-op after commit
+
+	if stats := lb.Stats("api"); stats.TotalRequests != 1 {
+		t.Fatalf("expected 1 request, got %d", stats.TotalRequests)
+	}
+	if v := lb.activeRequests("svc-1"); v != 1 {
+		t.Fatalf("expected committed request to stay in flight, got %d", v)
+	}
+
+	lb.ReportResult("svc-1", RequestResult{ServiceID: "svc-1", Success: true})
+	if v := lb.activeRequests("svc-1"); v != 0 {
+		t.Fatalf("expected in-flight count 0 after ReportResult, got %d", v)
+	}
+}
+
+func TestSelect_MetadataFilter(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("acme-1", "api", HealthHealthy, map[string]string{"tenant": "acme"}),
+		makeInstanceWithMeta("globex-1", "api", HealthHealthy, map[string]string{"tenant": "globex"}),
+		makeInstance("shared-1", "api", HealthHealthy),
+	))
+
+	for range 5 {
+		result, err := lb.Select("api", Context{MetadataFilter: map[string]string{"tenant": "acme"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result == nil || result.ServiceID != "acme-1" {
+			t.Fatalf("expected acme-1, got %+v", result)
+		}
+	}
+
+	result, _ := lb.Select("api", Context{MetadataFilter: map[string]string{"tenant": "initech"}})
+	if result != nil {
+		t.Fatalf("expected no instance for unknown tenant, got %s", result.ServiceID)
+	}
+}
+
+func TestSelect_ExcludeSkipsTriedInstances(t *testing.T) {
+	iphash := map[string]string{"lb_strategy": "IPHash"}
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, iphash),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, iphash),
+	))
+
+	ctx := Context{SessionID: "client"}
+	first, _ := lb.Select("api", ctx)
+	ctx.Exclude = []string{first.ServiceID}
+	second, _ := lb.Select("api", ctx)
+	if second.ServiceID == first.ServiceID {
+		t.Fatalf("expected the retry to avoid %s", first.ServiceID)
+	}
+
+	// Once every instance has been tried, they are all eligible again.
+	ctx.Exclude = append(ctx.Exclude, second.ServiceID)
+	if third, _ := lb.Select("api", ctx); third == nil {
+		t.Fatal("expected an instance when every candidate is excluded")
+	}
+}
+
+func TestSelect_PreferMetadata(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstanceWithMeta("eu-1", "api", HealthHealthy, map[string]string{"region": "eu-west"}),
+		makeInstanceWithMeta("us-1", "api", HealthHealthy, map[string]string{"region": "us-east"}),
+		makeInstanceWithMeta("ap-1", "api", HealthUnhealthy, map[string]string{"region": "ap-south"}),
+	))
+
+	for range 5 {
+		result, _ := lb.Select("api", Context{PreferMetadata: map[string]string{"region": "eu-west"}})
+		if result == nil || result.ServiceID != "eu-1" {
+			t.Fatalf("expected eu-1, got %+v", result)
+		}
+	}
+
+	// No healthy instance in the preferred region: fall back to any.
+	result, _ := lb.Select("api", Context{PreferMetadata: map[string]string{"region": "ap-south"}})
+	if result == nil || result.ServiceID == "ap-1" {
+		t.Fatalf("expected a healthy instance outside ap-south, got %+v", result)
+	}
+}
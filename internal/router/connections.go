@@ -0,0 +1,80 @@
+package router
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// connectionGCInterval is how often a service's connection counters are
+// checked for instances the provider no longer returns.
+const connectionGCInterval = time.Minute
+
+// connection counts an instance's in-flight requests.
+type connection struct {
+	serviceName string
+	active      atomic.Int64
+}
+
+// connectionFor returns the instance's counter, creating it on first use.
+func (lb *LoadBalancer) connectionFor(serviceName, serviceID string) *connection {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	c, ok := lb.connections[serviceID]
+	if !ok {
+		c = &connection{serviceName: serviceName}
+		lb.connections[serviceID] = c
+	}
+	return c
+}
+
+// activeRequests returns the instance's in-flight requests.
+func (lb *LoadBalancer) activeRequests(serviceID string) int64 {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if c, ok := lb.connections[serviceID]; ok {
+		return c.active.Load()
+	}
+	return 0
+}
+
+// collectConnections drops the connection counters and stats of the
+// service's instances that the provider no longer returns, once their
+// in-flight requests finish. It runs at most once per connectionGCInterval
+// for each service.
+func (lb *LoadBalancer) collectConnections(serviceName string, instances []Instance) {
+	now := lb.now()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if now.Sub(lb.collected[serviceName]) < connectionGCInterval {
+		return
+	}
+	lb.collected[serviceName] = now
+
+	live := make(map[string]struct{}, len(instances))
+	for _, inst := range instances {
+		live[inst.ServiceID] = struct{}{}
+	}
+	for id, c := range lb.connections {
+		if c.serviceName != serviceName || c.active.Load() > 0 {
+			continue
+		}
+		if _, ok := live[id]; !ok {
+			delete(lb.connections, id)
+			lb.deleteInstanceSeries(serviceName, id)
+		}
+	}
+	// Stats imported by ImportStats may have no connection counter.
+	for id, st := range lb.stats {
+		if st.serviceName != serviceName {
+			continue
+		}
+		if _, ok := live[id]; ok {
+			continue
+		}
+		if c, ok := lb.connections[id]; ok && c.active.Load() > 0 {
+			continue
+		}
+		delete(lb.stats, id)
+	}
+}
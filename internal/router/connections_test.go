@@ -0,0 +1,69 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadBalancer_CollectsDepartedConnections(t *testing.T) {
+	provider := newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	)
+	lb := NewLoadBalancer(provider)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	busy, _ := lb.Reserve("api", Context{})
+	idle, _ := lb.Reserve("api", Context{})
+	idle.Abort()
+
+	// Both instances leave; after the GC interval only the one with a
+	// request still in flight keeps its counter.
+	provider.instances["api"] = []Instance{makeInstance("svc-3", "api", HealthHealthy)}
+	now = now.Add(connectionGCInterval)
+	lb.Select("api", Context{})
+
+	if _, ok := lb.connections[idle.Instance.ServiceID]; ok {
+		t.Fatalf("expected the idle departed instance's counter to be collected")
+	}
+	if _, ok := lb.connections[busy.Instance.ServiceID]; !ok {
+		t.Fatalf("expected the busy departed instance's counter to be kept")
+	}
+
+	lb.ReportResult(busy.Instance.ServiceID, RequestResult{ServiceID: busy.Instance.ServiceID, Success: true})
+	now = now.Add(connectionGCInterval)
+	lb.Select("api", Context{})
+	if _, ok := lb.connections[busy.Instance.ServiceID]; ok || len(lb.connections) != 1 {
+		t.Fatalf("expected only svc-3's counter to remain, got %v", lb.connections)
+	}
+}
+
+func TestLoadBalancer_CollectsDepartedStats(t *testing.T) {
+	provider := newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	)
+	lb := NewLoadBalancer(provider)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+	lb.ImportStats([]InstanceStats{{ServiceID: "svc-gone", ServiceName: "api", TotalRequests: 5}})
+
+	busy, _ := lb.Reserve("api", Context{})
+	busy.Commit()
+	idle, _ := lb.Select("api", Context{})
+	lb.ReportResult(idle.ServiceID, RequestResult{ServiceID: idle.ServiceID, Success: true})
+
+	provider.instances["api"] = []Instance{makeInstance("svc-3", "api", HealthHealthy)}
+	now = now.Add(connectionGCInterval)
+	lb.Select("api", Context{})
+
+	for _, id := range []string{"svc-gone", idle.ServiceID} {
+		if _, ok := lb.stats[id]; ok {
+			t.Errorf("expected the stats of departed %s to be collected", id)
+		}
+	}
+	if _, ok := lb.stats[busy.Instance.ServiceID]; !ok {
+		t.Fatal("expected the busy departed instance's stats to be kept")
+	}
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"hash/fnv"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+const (
+	// ringVirtualNodes is how many points each instance gets on the hash
+	// ring, evening out the share of keys each instance owns.
+	ringVirtualNodes = 160
+
+	// consistentHashLoadFactor caps each instance's in-flight requests at
+	// this multiple of the average. Keys whose instance is at the cap
+	// overflow to the next instance along the ring.
+	consistentHashLoadFactor = 1.25
+
+	// ringCacheSize is how many rings a balancer keeps. Candidate sets vary
+	// between requests (retries exclude instances, routes filter on
+	// metadata), so a service can need several rings at once.
+	ringCacheSize = 32
+)
+
+// hashRing places instances at virtual node points on a 64-bit ring. A ring
+// is immutable once built, and is built for each set of instances.
+type hashRing struct {
+	key    ringKey
+	points []ringPoint
+}
+
+// ringKey identifies a service's ring for one set of instances.
+type ringKey struct {
+	serviceName string
+	members     uint64 // memberHash of the instances
+}
+
+type ringPoint struct {
+	hash      uint64
+	serviceID string
+}
+
+func newHashRing(key ringKey, ids []string) *hashRing {
+	r := &hashRing{key: key, points: make([]ringPoint, 0, len(ids)*ringVirtualNodes)}
+	for _, id := range ids {
+		for v := range ringVirtualNodes {
+			r.points = append(r.points, ringPoint{hash: ringHash(id + "#" + strconv.Itoa(v)), serviceID: id})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i].hash < r.points[j].hash })
+	return r
+}
+
+// selectConsistentHash maps the request's session key onto the ring, so
+// adding or removing an instance only moves the keys that instance gains or
+// loses. An instance already carrying more than its bounded share of
+// in-flight requests passes the key on to the next instance clockwise.
+func (lb *LoadBalancer) selectConsistentHash(serviceName string, instances []Instance, ctx Context) *Instance {
+	ring := lb.getRing(serviceName, instances)
+
+	byID := make(map[string]*Instance, len(instances))
+	loads := make(map[string]int64, len(instances))
+	var total int64
+	for i := range instances {
+		id := instances[i].ServiceID
+		byID[id] = &instances[i]
+		loads[id] = lb.activeRequests(id)
+		total += loads[id]
+	}
+	// Counting this request, some instance is always under capacity.
+	capacity := int64(math.Ceil(float64(total+1) / float64(len(instances)) * consistentHashLoadFactor))
+
+	h := ringHash(sessionKey(ctx))
+	start := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= h })
+	for i := range ring.points {
+		id := ring.points[(start+i)%len(ring.points)].serviceID
+		if loads[id] < capacity {
+			return byID[id]
+		}
+	}
+	return byID[ring.points[start%len(ring.points)].serviceID]
+}
+
+// getRing returns the ring of the service's instances, building it if it is
+// not among the most recently used rings.
+func (lb *LoadBalancer) getRing(serviceName string, instances []Instance) *hashRing {
+	key := ringKey{serviceName: serviceName, members: memberHash(instances)}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if el, ok := lb.rings[key]; ok {
+		lb.ringLRU.MoveToFront(el)
+		return el.Value.(*hashRing)
+	}
+
+	ids := make([]string, len(instances))
+	for i, inst := range instances {
+		ids[i] = inst.ServiceID
+	}
+	slices.Sort(ids)
+	ring := newHashRing(key, ids)
+	if lb.ringLRU.Len() >= ringCacheSize {
+		oldest := lb.ringLRU.Back()
+		lb.ringLRU.Remove(oldest)
+		delete(lb.rings, oldest.Value.(*hashRing).key)
+	}
+	lb.rings[key] = lb.ringLRU.PushFront(ring)
+	return ring
+}
+
+// ringHash is 64-bit FNV-1a followed by the splitmix64 finalizer, which
+// spreads the hashes of similar strings such as "svc-1#0" and "svc-1#1"
+// evenly around the ring.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,105 @@
+package router
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func consistentHashInstances(n int) []Instance {
+	var instances []Instance
+	for i := range n {
+		instances = append(instances, makeInstanceWithMeta(fmt.Sprintf("svc-%d", i), "api", HealthHealthy, map[string]string{"lb_strategy": "ConsistentHash"}))
+	}
+	return instances
+}
+
+// assignments routes 1000 sessions, releasing each request before the next
+// so load never overflows.
+func assignments(t *testing.T, lb *LoadBalancer) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	for i := range 1000 {
+		session := fmt.Sprintf("session-%d", i)
+		inst, err := lb.Select("api", Context{SessionID: session})
+		if err != nil || inst == nil {
+			t.Fatalf("Select: %v, %v", inst, err)
+		}
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: true})
+		out[session] = inst.ServiceID
+	}
+	return out
+}
+
+func TestSelect_ConsistentHash_RemovingInstanceRemapsOnlyItsKeys(t *testing.T) {
+	provider := newProvider(consistentHashInstances(10)...)
+	lb := NewLoadBalancer(provider)
+	before := assignments(t, lb)
+
+	perInstance := map[string]int{}
+	for _, id := range before {
+		perInstance[id]++
+	}
+	for id, n := range perInstance {
+		if n < 40 || n > 200 {
+			t.Fatalf("uneven ring: %s owns %d of 1000 sessions", id, n)
+		}
+	}
+
+	provider.instances["api"] = provider.instances["api"][:9] // drop svc-9
+	after := assignments(t, lb)
+	for session, id := range before {
+		if id != "svc-9" && after[session] != id {
+			t.Fatalf("%s moved from %s to %s though its instance stayed", session, id, after[session])
+		}
+	}
+}
+
+func TestSelect_ConsistentHash_BoundedLoadOverflows(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(consistentHashInstances(4)...))
+	ctx := Context{SessionID: "hot-session"}
+
+	// Hold every request open: the hot key's instance fills up to its
+	// bounded share, then the key spills over to other instances.
+	seen := map[string]int{}
+	for range 40 {
+		res, err := lb.Reserve("api", ctx)
+		if err != nil || res == nil {
+			t.Fatalf("Reserve: %v, %v", res, err)
+		}
+		res.Commit()
+		seen[res.Instance.ServiceID]++
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected the hot key to overflow, got %v", seen)
+	}
+	for id, n := range seen {
+		if n > 13 { // ceil(40/4 * 1.25)
+			t.Fatalf("%s took %d of 40 in-flight requests, above its bound", id, n)
+		}
+	}
+}
+
+func TestGetRing_KeepsRingsOfAlternatingCandidateSets(t *testing.T) {
+	lb := NewLoadBalancer(newProvider())
+	all := consistentHashInstances(5)
+	retry := all[1:]
+
+	ring := lb.getRing("api", all)
+	retryRing := lb.getRing("api", retry)
+	if ring == retryRing {
+		t.Fatal("expected a separate ring for a different candidate set")
+	}
+	reordered := slices.Clone(all)
+	slices.Reverse(reordered)
+	if lb.getRing("api", reordered) != ring || lb.getRing("api", retry) != retryRing {
+		t.Fatal("expected cached rings to be reused")
+	}
+
+	for i := range ringCacheSize {
+		lb.getRing(fmt.Sprintf("svc-%d", i), all)
+	}
+	if len(lb.rings) != ringCacheSize || lb.getRing("api", all) == ring {
+		t.Fatalf("expected the oldest rings to be evicted, have %d", len(lb.rings))
+	}
+}
@@ -0,0 +1,27 @@
+package router
+
+import "github.com/toska-mesh/toska-mesh/internal/types"
+
+// MetadataDatacenter is the instance metadata key holding its datacenter,
+// as labelled by multi-datacenter registry queries.
+const MetadataDatacenter = types.MetadataDatacenter
+
+// preferLocalDatacenter keeps the instances in the local datacenter while
+// any of them is healthy, failing over to every datacenter otherwise.
+// Instances without a datacenter label count as local.
+func (lb *LoadBalancer) preferLocalDatacenter(instances []Instance) []Instance {
+	local := lb.config.LocalDatacenter
+	if local == "" {
+		return instances
+	}
+	var out []Instance
+	for _, inst := range instances {
+		if dc := inst.Metadata[MetadataDatacenter]; dc == "" || dc == local {
+			out = append(out, inst)
+		}
+	}
+	if len(filterHealthy(out)) == 0 {
+		return instances
+	}
+	return out
+}
@@ -0,0 +1,24 @@
+package router
+
+import "testing"
+
+func TestSelect_PrefersLocalDatacenter(t *testing.T) {
+	provider := newProvider(
+		makeInstanceWithMeta("eu-1", "api", HealthHealthy, map[string]string{MetadataDatacenter: "eu-west"}),
+		makeInstanceWithMeta("us-1", "api", HealthHealthy, map[string]string{MetadataDatacenter: "us-east"}),
+		makeInstance("unlabelled-1", "api", HealthHealthy),
+	)
+	lb := NewLoadBalancerWithConfig(provider, Config{LocalDatacenter: "eu-west"}, nil)
+
+	for range 10 {
+		if inst, _ := lb.Select("api", Context{}); inst.ServiceID == "us-1" {
+			t.Fatal("expected local instances while they are healthy")
+		}
+	}
+
+	provider.instances["api"][0].Status = HealthUnhealthy
+	provider.instances["api"][2].Status = HealthUnhealthy
+	if inst, _ := lb.Select("api", Context{}); inst == nil || inst.ServiceID != "us-1" {
+		t.Fatalf("expected failover to us-east, got %v", inst)
+	}
+}
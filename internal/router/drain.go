@@ -0,0 +1,46 @@
+package router
+
+// Drain stops new selections of an instance while requests already sent to
+// it finish. Its in-flight count is reported in Stats.DrainingInstances, so
+// a caller can deregister it once the count reaches zero or a grace period
+// ends. Draining an instance that is not registered yet applies once it is.
+func (lb *LoadBalancer) Drain(serviceID string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.draining[serviceID] = struct{}{}
+}
+
+// Undrain returns a drained instance to selection.
+func (lb *LoadBalancer) Undrain(serviceID string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	delete(lb.draining, serviceID)
+}
+
+// withoutDraining drops draining instances.
+func (lb *LoadBalancer) withoutDraining(instances []Instance) []Instance {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if len(lb.draining) == 0 {
+		return instances
+	}
+	out := make([]Instance, 0, len(instances))
+	for _, inst := range instances {
+		if _, ok := lb.draining[inst.ServiceID]; !ok {
+			out = append(out, inst)
+		}
+	}
+	return out
+}
+
+// drainingInFlight returns the in-flight requests of each draining instance
+// the balancer has sent the service's traffic to. The caller holds lb.mu.
+func (lb *LoadBalancer) drainingInFlight(serviceName string) map[string]int64 {
+	out := make(map[string]int64)
+	for id := range lb.draining {
+		if c, ok := lb.connections[id]; ok && c.serviceName == serviceName {
+			out[id] = c.active.Load()
+		}
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package router
+
+import "testing"
+
+func TestDrain_StopsSelectionAndReportsInFlight(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	))
+
+	// Leave one request in flight on each instance, then drain svc-1.
+	lb.Select("api", Context{})
+	lb.Select("api", Context{})
+	lb.Drain("svc-1")
+
+	for range 5 {
+		inst, _ := lb.Select("api", Context{})
+		if inst.ServiceID == "svc-1" {
+			t.Fatal("selected draining instance svc-1")
+		}
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: true})
+	}
+
+	stats := lb.Stats("api")
+	if n, ok := stats.DrainingInstances["svc-1"]; !ok || n != 1 {
+		t.Fatalf("expected svc-1 draining with 1 in flight, got %v", stats.DrainingInstances)
+	}
+	lb.ReportResult("svc-1", RequestResult{ServiceID: "svc-1", Success: true})
+	if n := lb.Stats("api").DrainingInstances["svc-1"]; n != 0 {
+		t.Fatalf("expected svc-1 drained, %d still in flight", n)
+	}
+
+	lb.Undrain("svc-1")
+	if len(lb.Stats("api").DrainingInstances) != 0 {
+		t.Fatal("expected no draining instances after Undrain")
+	}
+	seen := map[string]bool{}
+	for range 4 {
+		inst, _ := lb.Select("api", Context{})
+		seen[inst.ServiceID] = true
+	}
+	if !seen["svc-1"] {
+		t.Fatal("expected svc-1 to be selected again after Undrain")
+	}
+}
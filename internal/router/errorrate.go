@@ -0,0 +1,104 @@
+package router
+
+import "time"
+
+const (
+	// DefaultErrorRateWindow is the window ErrorRates covers when
+	// Config.ErrorRateWindow is zero.
+	DefaultErrorRateWindow = time.Minute
+
+	// errorRateBuckets is how many slices the error rate window is split
+	// into. The window slides one slice at a time.
+	errorRateBuckets = 10
+)
+
+// ErrorRate is an instance's request outcomes over the error rate window.
+type ErrorRate struct {
+	Requests int64
+	Failures int64
+	// Rate is Failures / Requests.
+	Rate float64
+}
+
+// outcomeWindow counts request outcomes in a ring of time slices. Slices
+// are reused once they fall out of the window.
+type outcomeWindow struct {
+	buckets [errorRateBuckets]outcomeBucket
+}
+
+type outcomeBucket struct {
+	slice    int64 // time slice the counts belong to
+	requests int64
+	failures int64
+}
+
+func (w *outcomeWindow) record(slice int64, failed bool) {
+	b := &w.buckets[slice%errorRateBuckets]
+	if b.slice != slice {
+		*b = outcomeBucket{slice: slice}
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+}
+
+// sum totals the slices within the window ending at slice.
+func (w *outcomeWindow) sum(slice int64) (requests, failures int64) {
+	for _, b := range w.buckets {
+		if age := slice - b.slice; age >= 0 && age < errorRateBuckets {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+// errorRateSlice returns the time slice now falls in.
+func (lb *LoadBalancer) errorRateSlice() int64 {
+	window := lb.config.ErrorRateWindow
+	if window <= 0 {
+		window = DefaultErrorRateWindow
+	}
+	width := max(int64(window/errorRateBuckets), 1)
+	return lb.now().UnixNano() / width
+}
+
+// ErrorRates returns the error rate of each instance of a service over the
+// error rate window, keyed by service ID. Instances without reported
+// results in the window are omitted.
+func (lb *LoadBalancer) ErrorRates(serviceName string) map[string]ErrorRate {
+	slice := lb.errorRateSlice()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	out := make(map[string]ErrorRate)
+	for id, s := range lb.stats {
+		if s.serviceName != serviceName {
+			continue
+		}
+		s.mu.Lock()
+		requests, failures := s.outcomes.sum(slice)
+		s.mu.Unlock()
+		if requests > 0 {
+			out[id] = ErrorRate{Requests: requests, Failures: failures, Rate: float64(failures) / float64(requests)}
+		}
+	}
+	return out
+}
+
+// ResetStats discards the request history of a service's instances, or of
+// every instance when serviceName is empty, including history restored by
+// ImportStats. Results of requests in flight during the reset are not
+// counted.
+func (lb *LoadBalancer) ResetStats(serviceName string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	for id, s := range lb.stats {
+		if serviceName == "" || s.serviceName == serviceName {
+			delete(lb.stats, id)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestErrorRates_SlideWithWindow(t *testing.T) {
+	lb := NewLoadBalancerWithConfig(newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	), Config{ErrorRateWindow: 10 * time.Second}, nil)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	// svc-1 fails every request until it recovers.
+	recovered := false
+	send := func() {
+		inst, _ := lb.Select("api", Context{})
+		success := recovered || inst.ServiceID != "svc-1"
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: success})
+	}
+	for range 8 {
+		send()
+	}
+	rates := lb.ErrorRates("api")
+	if got := rates["svc-1"]; got != (ErrorRate{Requests: 4, Failures: 4, Rate: 1}) {
+		t.Fatalf("svc-1 error rate = %+v", got)
+	}
+	if got := rates["svc-2"]; got != (ErrorRate{Requests: 4}) {
+		t.Fatalf("svc-2 error rate = %+v", got)
+	}
+
+	// Later results count while the earlier ones slide out of the window.
+	now = now.Add(6 * time.Second)
+	recovered = true
+	send()
+	send()
+	if got := lb.ErrorRates("api")["svc-1"]; got != (ErrorRate{Requests: 5, Failures: 4, Rate: 0.8}) {
+		t.Fatalf("svc-1 error rate = %+v", got)
+	}
+	now = now.Add(5 * time.Second)
+	if got := lb.ErrorRates("api"); got["svc-1"] != (ErrorRate{Requests: 1}) || got["svc-2"] != (ErrorRate{Requests: 1}) {
+		t.Fatalf("expected only the latest results in the window, got %+v", got)
+	}
+	if lb.Stats("api").FailedRequests != 4 {
+		t.Fatal("expected the window to leave lifetime counters alone")
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstance("api-1", "api", HealthHealthy),
+		makeInstance("web-1", "web", HealthHealthy),
+	))
+	lb.ImportStats([]InstanceStats{{ServiceName: "api", ServiceID: "api-old", TotalRequests: 7}})
+	for _, service := range []string{"api", "web"} {
+		inst, _ := lb.Select(service, Context{})
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: false})
+	}
+
+	lb.ResetStats("api")
+	if s := lb.Stats("api"); s.TotalRequests != 0 || len(s.InstanceRequestCounts) != 0 || len(lb.ErrorRates("api")) != 0 {
+		t.Fatalf("expected api history to be reset, got %+v", s)
+	}
+	if s := lb.Stats("web"); s.TotalRequests != 1 || s.FailedRequests != 1 {
+		t.Fatalf("expected web history to be kept, got %+v", s)
+	}
+
+	lb.ResetStats("")
+	if n := len(lb.ExportStats()); n != 0 {
+		t.Fatalf("expected no history after a global reset, got %d instances", n)
+	}
+}
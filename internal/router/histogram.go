@@ -0,0 +1,90 @@
+package router
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Latency histogram buckets are log-linear, like an HDR histogram with 16
+// sub-buckets per power of two: values below 16µs are exact and larger ones
+// are within about 6%, from 1µs to beyond any real response time.
+const (
+	histogramSubBuckets = 16
+	histogramBuckets    = (64-4)*histogramSubBuckets + histogramSubBuckets
+)
+
+// latencyHistogram records response times in microseconds.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	counts [histogramBuckets]uint64
+	total  uint64
+}
+
+// Percentiles summarises a response time distribution.
+type Percentiles struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	us := uint64(max(d.Microseconds(), 0))
+	h.mu.Lock()
+	h.counts[histogramBucket(us)]++
+	h.total++
+	h.mu.Unlock()
+}
+
+// mergeInto adds h's counts to dst, which the caller owns.
+func (h *latencyHistogram) mergeInto(dst *latencyHistogram) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range h.counts {
+		dst.counts[i] += c
+	}
+	dst.total += h.total
+}
+
+func (h *latencyHistogram) percentiles() Percentiles {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return Percentiles{P50: h.quantile(0.50), P95: h.quantile(0.95), P99: h.quantile(0.99)}
+}
+
+// quantile returns the value at quantile q, or zero with no samples. The
+// caller holds h.mu.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	var seen uint64
+	for i, c := range h.counts {
+		if seen += c; seen >= max(rank, 1) {
+			return time.Duration(histogramValue(i)) * time.Microsecond
+		}
+	}
+	return 0
+}
+
+func histogramBucket(us uint64) int {
+	if us < histogramSubBuckets {
+		return int(us)
+	}
+	exp := bits.Len64(us) - 1 // at least 4
+	sub := (us >> (exp - 4)) & (histogramSubBuckets - 1)
+	return (exp-3)*histogramSubBuckets + int(sub)
+}
+
+// histogramValue returns the midpoint of a bucket.
+func histogramValue(bucket int) uint64 {
+	if bucket < histogramSubBuckets {
+		return uint64(bucket)
+	}
+	exp := bucket/histogramSubBuckets + 3
+	sub := uint64(bucket % histogramSubBuckets)
+	width := uint64(1) << (exp - 4)
+	return (histogramSubBuckets+sub)*width + width/2
+}
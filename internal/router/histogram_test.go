@@ -0,0 +1,60 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Percentiles(t *testing.T) {
+	var h latencyHistogram
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	p := h.percentiles()
+	for _, tt := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"p50", p.P50, 500 * time.Millisecond},
+		{"p95", p.P95, 950 * time.Millisecond},
+		{"p99", p.P99, 990 * time.Millisecond},
+	} {
+		if diff := tt.got - tt.want; diff < -tt.want/16 || diff > tt.want/16 {
+			t.Errorf("%s = %v, want %v within 6%%", tt.name, tt.got, tt.want)
+		}
+	}
+
+	var empty latencyHistogram
+	if p := empty.percentiles(); p != (Percentiles{}) {
+		t.Errorf("expected zero percentiles without samples, got %+v", p)
+	}
+}
+
+func TestStats_ResponseTimePercentiles(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	))
+	var svc2 int
+	for range 100 {
+		inst, _ := lb.Select("api", Context{})
+		rt := 10 * time.Millisecond
+		if inst.ServiceID == "svc-2" {
+			if svc2++; svc2%5 == 0 {
+				rt = time.Second // svc-2's slow tail
+			}
+		}
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: true, ResponseTime: rt})
+	}
+
+	stats := lb.Stats("api")
+	if p := stats.ResponseTimes; p.P50 > 11*time.Millisecond || p.P99 < 900*time.Millisecond {
+		t.Fatalf("unexpected service percentiles %+v", p)
+	}
+	if p := stats.InstanceResponseTimes["svc-1"]; p.P99 > 11*time.Millisecond {
+		t.Fatalf("svc-1 has no slow tail, got %+v", p)
+	}
+	if p := stats.InstanceResponseTimes["svc-2"]; p.P95 < 900*time.Millisecond {
+		t.Fatalf("expected svc-2's slow tail in p95, got %+v", p)
+	}
+}
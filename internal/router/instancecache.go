@@ -0,0 +1,66 @@
+package router
+
+import (
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+type cachedInstances struct {
+	instances  []Instance
+	refreshAt  time.Time
+	refreshing bool
+}
+
+// getInstances returns the service's instances from the provider, through
+// the instance cache when Config.InstanceCacheTTL is set. Once an entry is
+// older than the TTL it is still served while one background fetch
+// refreshes it, and it keeps being served if that fetch fails, until a
+// retry a TTL later. Only a service's first lookup waits on the provider.
+func (lb *LoadBalancer) getInstances(serviceName string) ([]Instance, error) {
+	ttl := lb.config.InstanceCacheTTL
+	if ttl <= 0 {
+		return lb.provider.GetInstances(serviceName)
+	}
+
+	now := lb.now()
+	lb.mu.Lock()
+	entry, ok := lb.instanceCache[serviceName]
+	if ok {
+		if !now.Before(entry.refreshAt) && !entry.refreshing {
+			entry.refreshing = true
+			go lb.refreshInstances(serviceName)
+		}
+		instances := entry.instances
+		lb.mu.Unlock()
+		return instances, nil
+	}
+	lb.mu.Unlock()
+
+	instances, err := lb.provider.GetInstances(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	lb.mu.Lock()
+	lb.instanceCache[serviceName] = &cachedInstances{instances: instances, refreshAt: now.Add(ttl)}
+	lb.mu.Unlock()
+	return instances, nil
+}
+
+// refreshInstances refetches a cached service, keeping the stale entry if
+// the provider fails.
+func (lb *LoadBalancer) refreshInstances(serviceName string) {
+	instances, err := lb.provider.GetInstances(serviceName)
+	now := lb.now()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	entry := lb.instanceCache[serviceName]
+	entry.refreshing = false
+	entry.refreshAt = now.Add(lb.config.InstanceCacheTTL)
+	if err != nil {
+		lb.telemetry.Count("router_instance_refresh_errors_total", 1, telemetry.Labels{"service": serviceName})
+		return
+	}
+	entry.instances = instances
+}
@@ -0,0 +1,97 @@
+package router
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingProvider counts lookups and can be made to fail.
+type countingProvider struct {
+	mu        sync.Mutex
+	instances []Instance
+	calls     int
+	err       error
+	fetched   chan struct{}
+}
+
+func (p *countingProvider) GetInstances(serviceName string) ([]Instance, error) {
+	p.mu.Lock()
+	defer func() {
+		p.mu.Unlock()
+		p.fetched <- struct{}{}
+	}()
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.instances, nil
+}
+
+func TestLoadBalancer_InstanceCache(t *testing.T) {
+	provider := &countingProvider{
+		instances: []Instance{makeInstance("svc-1", "api", HealthHealthy)},
+		fetched:   make(chan struct{}, 10),
+	}
+	lb := NewLoadBalancerWithConfig(provider, Config{InstanceCacheTTL: 5 * time.Second}, nil)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	selectID := func() string {
+		t.Helper()
+		inst, err := lb.Select("api", Context{})
+		if err != nil || inst == nil {
+			t.Fatalf("Select: %v, %v", inst, err)
+		}
+		return inst.ServiceID
+	}
+
+	for range 10 {
+		selectID()
+	}
+	<-provider.fetched
+	if provider.calls != 1 {
+		t.Fatalf("expected one provider lookup within the TTL, got %d", provider.calls)
+	}
+
+	// Expired: the stale list is served while it refreshes in the background.
+	provider.mu.Lock()
+	provider.instances = []Instance{makeInstance("svc-2", "api", HealthHealthy)}
+	provider.mu.Unlock()
+	now = now.Add(5 * time.Second)
+	if id := selectID(); id != "svc-1" {
+		t.Fatalf("expected stale svc-1 during refresh, got %s", id)
+	}
+	<-provider.fetched
+	waitFor(t, func() bool { return selectID() == "svc-2" })
+
+	// A failed refresh keeps serving the last good list.
+	provider.mu.Lock()
+	provider.err = errors.New("consul unavailable")
+	provider.mu.Unlock()
+	now = now.Add(5 * time.Second)
+	selectID()
+	<-provider.fetched
+	for range 5 {
+		if id := selectID(); id != "svc-2" {
+			t.Fatalf("expected stale svc-2 after a failed refresh, got %s", id)
+		}
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if provider.calls != 3 {
+		t.Fatalf("expected the failed refresh to wait a TTL before retrying, got %d lookups", provider.calls)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package router
+
+import "github.com/toska-mesh/toska-mesh/internal/telemetry"
+
+// The balancer exports, per service:
+//
+//	router_selections_total{service,strategy}               selections by strategy
+//	router_strategy{service,strategy}                        1 for the strategy in use
+//	router_strategy_conflict{service}                        1 while instances declare different lb_strategy
+//	router_instance_selections_total{service,service_id}     selections per instance
+//	router_active_requests{service,service_id}               in-flight requests per instance
+//	router_requests_total{service,service_id,outcome}        reported results, success or failure
+//
+// so uneven selection or load across instances shows up on dashboards.
+
+// recordSelection counts a selection and tracks the service's strategy,
+// dropping the previous strategy's series when it changes.
+func (lb *LoadBalancer) recordSelection(serviceName string, strategy Strategy, serviceID string) {
+	lb.telemetry.Count("router_selections_total", 1, telemetry.Labels{
+		"service":  serviceName,
+		"strategy": strategy.String(),
+	})
+	lb.telemetry.Count("router_instance_selections_total", 1, telemetry.Labels{
+		"service":    serviceName,
+		"service_id": serviceID,
+	})
+
+	lb.mu.Lock()
+	prev, seen := lb.strategies[serviceName]
+	lb.strategies[serviceName] = strategy
+	lb.mu.Unlock()
+	if seen && prev == strategy {
+		return
+	}
+	if seen {
+		telemetry.Delete(lb.telemetry, "router_strategy", telemetry.Labels{"service": serviceName, "strategy": prev.String()})
+	}
+	lb.telemetry.Gauge("router_strategy", 1, telemetry.Labels{"service": serviceName, "strategy": strategy.String()})
+}
+
+// recordStrategyConflict exports whether the service's instances disagree
+// on their strategy, when that changes.
+func (lb *LoadBalancer) recordStrategyConflict(serviceName string, conflict bool) {
+	lb.mu.Lock()
+	prev, seen := lb.conflicts[serviceName]
+	lb.conflicts[serviceName] = conflict
+	lb.mu.Unlock()
+	if seen && prev == conflict {
+		return
+	}
+	value := 0.0
+	if conflict {
+		value = 1
+	}
+	lb.telemetry.Gauge("router_strategy_conflict", value, telemetry.Labels{"service": serviceName})
+}
+
+// recordActive exports an instance's in-flight request count.
+func (lb *LoadBalancer) recordActive(serviceName, serviceID string, active int64) {
+	lb.telemetry.Gauge("router_active_requests", float64(active), telemetry.Labels{
+		"service":    serviceName,
+		"service_id": serviceID,
+	})
+}
+
+// recordResult counts a reported request outcome.
+func (lb *LoadBalancer) recordResult(serviceName, serviceID string, success bool) {
+	outcome := "failure"
+	if success {
+		outcome = "success"
+	}
+	lb.telemetry.Count("router_requests_total", 1, telemetry.Labels{
+		"service":    serviceName,
+		"service_id": serviceID,
+		"outcome":    outcome,
+	})
+}
+
+// deleteInstanceSeries drops the per-instance series of a departed instance.
+func (lb *LoadBalancer) deleteInstanceSeries(serviceName, serviceID string) {
+	labels := telemetry.Labels{"service": serviceName, "service_id": serviceID}
+	telemetry.Delete(lb.telemetry, "router_active_requests", labels)
+	telemetry.Delete(lb.telemetry, "router_instance_selections_total", labels)
+	for _, outcome := range []string{"success", "failure"} {
+		telemetry.Delete(lb.telemetry, "router_requests_total", telemetry.Labels{
+			"service":    serviceName,
+			"service_id": serviceID,
+			"outcome":    outcome,
+		})
+	}
+}
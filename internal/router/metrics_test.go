@@ -0,0 +1,72 @@
+package router
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+func TestLoadBalancer_ExportsMetrics(t *testing.T) {
+	prom := telemetry.NewPrometheus()
+	provider := newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	)
+	lb := NewLoadBalancerWithTelemetry(provider, prom)
+
+	for range 4 {
+		inst, _ := lb.Select("api", Context{})
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: inst.ServiceID == "svc-1"})
+	}
+	lb.Select("api", Context{}) // left in flight
+
+	for i := range provider.instances["api"] {
+		provider.instances["api"][i].Metadata = map[string]string{"lb_strategy": "LeastConnections"}
+	}
+	lb.Select("api", Context{})
+
+	rec := httptest.NewRecorder()
+	prom.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`router_selections_total{service="api",strategy="RoundRobin"} 5`,
+		`router_strategy{service="api",strategy="LeastConnections"} 1`,
+		`router_instance_selections_total{service="api",service_id="svc-1"} 3`,
+		`router_instance_selections_total{service="api",service_id="svc-2"} 3`,
+		`router_active_requests{service="api",service_id="svc-1"} 1`,
+		`router_active_requests{service="api",service_id="svc-2"} 1`,
+		`router_requests_total{outcome="success",service="api",service_id="svc-1"} 2`,
+		`router_requests_total{outcome="failure",service="api",service_id="svc-2"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `router_strategy{service="api",strategy="RoundRobin"}`) {
+		t.Errorf("expected the previous strategy's series to be dropped:\n%s", body)
+	}
+}
+
+func TestLoadBalancer_DropsDepartedInstanceSeries(t *testing.T) {
+	prom := telemetry.NewPrometheus()
+	provider := newProvider(makeInstance("svc-1", "api", HealthHealthy))
+	lb := NewLoadBalancerWithTelemetry(provider, prom)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	inst, _ := lb.Select("api", Context{})
+	lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: true})
+
+	provider.instances["api"] = []Instance{makeInstance("svc-2", "api", HealthHealthy)}
+	now = now.Add(connectionGCInterval)
+	lb.Select("api", Context{})
+
+	rec := httptest.NewRecorder()
+	prom.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); strings.Contains(body, `service_id="svc-1"`) {
+		t.Errorf("expected svc-1's series to be dropped:\n%s", body)
+	}
+}
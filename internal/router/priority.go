@@ -0,0 +1,42 @@
+package router
+
+import "strconv"
+
+// MetadataPriority is the instance metadata key holding its failover
+// priority. Lower values are preferred; instances without it have priority 0.
+const MetadataPriority = "priority"
+
+// priorityGroup keeps the instances of the most preferred priority that has
+// a healthy instance, so backups only receive traffic once every instance
+// of each higher priority is unhealthy. Without healthy instances, the most
+// preferred priority with an instance of known health is used instead.
+func priorityGroup(instances []Instance) []Instance {
+	group, ok := bestPriority(filterHealthy(instances))
+	if !ok {
+		if group, ok = bestPriority(filterNonUnknown(instances)); !ok {
+			return instances
+		}
+	}
+	var out []Instance
+	for _, inst := range instances {
+		if instancePriority(inst) == group {
+			out = append(out, inst)
+		}
+	}
+	return out
+}
+
+func bestPriority(instances []Instance) (int, bool) {
+	best, ok := 0, false
+	for _, inst := range instances {
+		if p := instancePriority(inst); !ok || p < best {
+			best, ok = p, true
+		}
+	}
+	return best, ok
+}
+
+func instancePriority(inst Instance) int {
+	p, _ := strconv.Atoi(inst.Metadata[MetadataPriority])
+	return p
+}
@@ -0,0 +1,47 @@
+package router
+
+import "testing"
+
+func TestSelect_PriorityFailover(t *testing.T) {
+	provider := newProvider(
+		makeInstanceWithMeta("primary-1", "db", HealthHealthy, map[string]string{"priority": "0"}),
+		makeInstanceWithMeta("primary-2", "db", HealthHealthy, map[string]string{}),
+		makeInstanceWithMeta("backup-1", "db", HealthHealthy, map[string]string{"priority": "1"}),
+		makeInstanceWithMeta("standby-1", "db", HealthHealthy, map[string]string{"priority": "2"}),
+	)
+	lb := NewLoadBalancer(provider)
+	setStatus := func(id string, status HealthStatus) {
+		for i := range provider.instances["db"] {
+			if provider.instances["db"][i].ServiceID == id {
+				provider.instances["db"][i].Status = status
+			}
+		}
+	}
+	selectsOnly := func(want ...string) {
+		t.Helper()
+		allowed := map[string]bool{}
+		for _, id := range want {
+			allowed[id] = true
+		}
+		for range 10 {
+			inst, _ := lb.Select("db", Context{})
+			if inst == nil || !allowed[inst.ServiceID] {
+				t.Fatalf("expected one of %v, got %v", want, inst)
+			}
+		}
+	}
+
+	selectsOnly("primary-1", "primary-2")
+
+	setStatus("primary-1", HealthUnhealthy)
+	selectsOnly("primary-2")
+
+	setStatus("primary-2", HealthUnhealthy)
+	selectsOnly("backup-1")
+
+	setStatus("backup-1", HealthUnhealthy)
+	selectsOnly("standby-1")
+
+	setStatus("primary-1", HealthHealthy)
+	selectsOnly("primary-1")
+}
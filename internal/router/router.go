@@ -18,6 +18,10 @@ const (
 	Random
 	WeightedRoundRobin
 	IPHash
+	ConsistentHash
+	PowerOfTwoChoices
+	WeightedLeastRequest
+	HashByHeader
 )
 
 // ParseStrategy parses a strategy name (case-insensitive) into a Strategy.
@@ -34,11 +38,24 @@ func ParseStrategy(name string) Strategy {
 		return WeightedRoundRobin
 	case "iphash", "ip_hash":
 		return IPHash
+	case "consistenthash", "consistent_hash":
+		return ConsistentHash
+	case "poweroftwochoices", "power_of_two_choices", "p2c":
+		return PowerOfTwoChoices
+	case "weightedleastrequest", "weighted_least_request":
+		return WeightedLeastRequest
+	case "hashbyheader", "hash_by_header":
+		return HashByHeader
 	default:
 		return RoundRobin
 	}
 }
 
+// hashed reports whether the strategy maps sessions to instances by hash.
+func (s Strategy) hashed() bool {
+	return s == IPHash || s == ConsistentHash || s == HashByHeader
+}
+
 func (s Strategy) String() string {
 	switch s {
 	case RoundRobin:
@@ -51,6 +68,14 @@ func (s Strategy) String() string {
 		return "WeightedRoundRobin"
 	case IPHash:
 		return "IPHash"
+	case ConsistentHash:
+		return "ConsistentHash"
+	case PowerOfTwoChoices:
+		return "PowerOfTwoChoices"
+	case WeightedLeastRequest:
+		return "WeightedLeastRequest"
+	case HashByHeader:
+		return "HashByHeader"
 	default:
 		return "RoundRobin"
 	}
@@ -69,13 +94,13 @@ const (
 
 // Instance represents a registered service instance available for routing.
 type Instance struct {
-	ServiceName    string
-	ServiceID      string
-	Address        string
-	Port           int
-	Status         HealthStatus
-	Metadata       map[string]string
-	RegisteredAt   time.Time
+	ServiceName     string
+	ServiceID       string
+	Address         string
+	Port            int
+	Status          HealthStatus
+	Metadata        map[string]string
+	RegisteredAt    time.Time
 	LastHealthCheck time.Time
 }
 
@@ -84,6 +109,24 @@ type Context struct {
 	PreferredZone string
 	Headers       map[string]string
 	SessionID     string
+
+	// MetadataFilter restricts selection to instances whose metadata
+	// contains every key/value pair (e.g. {"tenant": "acme"}).
+	MetadataFilter map[string]string
+
+	// ExcludeMetadataKeys drops instances whose metadata has any of these
+	// keys, such as tenant-scoped instances on shared routes.
+	ExcludeMetadataKeys []string
+
+	// PreferMetadata narrows selection to instances whose metadata contains
+	// every key/value pair, but only when at least one such instance is
+	// available (e.g. {"region": "eu-west"}).
+	PreferMetadata map[string]string
+
+	// Exclude lists service IDs to avoid, such as the instances a request
+	// has already been tried on. They are only selected when no other
+	// candidate remains.
+	Exclude []string
 }
 
 // RequestResult reports the outcome of a proxied request for tracking.
@@ -97,12 +140,71 @@ type RequestResult struct {
 
 // Stats provides aggregate load balancing statistics for a service.
 type Stats struct {
-	ServiceName           string
-	TotalRequests         int
-	SuccessfulRequests    int
-	FailedRequests        int
-	AverageResponseTime   time.Duration
+	ServiceName         string
+	TotalRequests       int
+	SuccessfulRequests  int
+	FailedRequests      int
+	AverageResponseTime time.Duration
+	// ResponseTimes are the p50, p95 and p99 response times across the
+	// service; InstanceResponseTimes break them down by instance.
+	ResponseTimes         Percentiles
 	InstanceRequestCounts map[string]int
+	InstanceResponseTimes map[string]Percentiles
+	// DrainingInstances holds the in-flight requests of each draining
+	// instance of the service.
+	DrainingInstances map[string]int64
+}
+
+// Config tunes a LoadBalancer. The zero value routes across all instances.
+type Config struct {
+	// Strategies fixes the strategy of services, keyed by lowercase
+	// service name, over their instances' lb_strategy metadata.
+	// DefaultStrategy applies to services with neither.
+	Strategies      map[string]Strategy
+	DefaultStrategy Strategy
+
+	// SubsetSize, if positive, limits services with more instances than
+	// this to a deterministic subset of that many, chosen by ClientIndex
+	// out of the ClientCount balancers in the fleet (e.g. a StatefulSet
+	// ordinal and replica count). Across the fleet every instance receives
+	// an even share, while each balancer connects to only a few. Subsetting
+	// is off unless ClientCount is set. Balancers fall back to all instances
+	// while their subset has no healthy ones.
+	SubsetSize  int
+	ClientIndex int
+	ClientCount int
+
+	// SlowStartWindow, if positive, ramps traffic to an instance up
+	// linearly over this long after it appears or recovers, starting from
+	// a tenth of its share, so cold instances are not flooded. Hash
+	// strategies are not ramped, to keep sessions in place.
+	SlowStartWindow time.Duration
+
+	// AffinityTTL, if positive, pins each session of a hash strategy (or
+	// shard key, for HashByHeader) to the instance it was first routed to,
+	// until the session is idle this long.
+	// Sessions then keep their instance when others come and go, and are
+	// re-routed only when it stops being a candidate.
+	AffinityTTL time.Duration
+
+	// InstanceCacheTTL, if positive, caches each service's instances for
+	// this long instead of asking the provider on every selection. Expired
+	// entries are refreshed in the background and served stale meanwhile,
+	// or for as long as the provider keeps failing.
+	InstanceCacheTTL time.Duration
+
+	// ShardHeader names the request header HashByHeader shards on, such as
+	// a tenant ID. Defaults to DefaultShardHeader.
+	ShardHeader string
+
+	// ErrorRateWindow is how far back ErrorRates looks. Defaults to
+	// DefaultErrorRateWindow.
+	ErrorRateWindow time.Duration
+
+	// LocalDatacenter, if set, routes to instances labelled with this
+	// datacenter (or unlabelled) while any of them is healthy, and fails
+	// over to other datacenters otherwise.
+	LocalDatacenter string
 }
 
 // InstanceProvider fetches instances for a given service name.
@@ -116,9 +218,32 @@ type Balancer interface {
 	// Select picks the next instance for the given service and request context.
 	Select(serviceName string, ctx Context) (*Instance, error)
 
+	// Reserve picks the next instance and holds an in-flight slot on it until
+	// the returned reservation is committed or aborted.
+	Reserve(serviceName string, ctx Context) (*Reservation, error)
+
 	// ReportResult feeds back request outcomes for connection tracking.
 	ReportResult(serviceID string, result RequestResult)
 
+	// Drain stops new selections of an instance while in-flight requests
+	// finish; Undrain reverses it.
+	Drain(serviceID string)
+	Undrain(serviceID string)
+
 	// Stats returns aggregate statistics for a service.
 	Stats(serviceName string) Stats
+
+	// ExportStats returns per-instance request history for persistence.
+	ExportStats() []InstanceStats
+
+	// ImportStats restores request history returned by ExportStats.
+	ImportStats(stats []InstanceStats)
+
+	// ErrorRates returns each instance's recent error rate, keyed by
+	// service ID.
+	ErrorRates(serviceName string) map[string]ErrorRate
+
+	// ResetStats discards the request history of a service, or of every
+	// service when serviceName is empty.
+	ResetStats(serviceName string)
 }
@@ -0,0 +1,44 @@
+package router
+
+import "sort"
+
+// DefaultShardHeader is the header HashByHeader shards on when
+// Config.ShardHeader is empty.
+const DefaultShardHeader = "X-Tenant-ID"
+
+// shardHeader returns the header HashByHeader shards on.
+func (lb *LoadBalancer) shardHeader() string {
+	if lb.config.ShardHeader != "" {
+		return lb.config.ShardHeader
+	}
+	return DefaultShardHeader
+}
+
+// shardKey returns the request's shard header value, or "" if it has none.
+func (lb *LoadBalancer) shardKey(ctx Context) string {
+	return ctx.Headers[lb.shardHeader()]
+}
+
+// selectHashByHeader maps the request's shard key onto the service's hash
+// ring, so every request for a key reaches the same instance and only the
+// keys of an added or removed instance move. Unlike ConsistentHash, keys
+// are not spilled to other instances under load, keeping per-key state such
+// as a tenant's cache in one place. Requests without the header are
+// balanced round-robin.
+func (lb *LoadBalancer) selectHashByHeader(serviceName string, instances []Instance, ctx Context) *Instance {
+	key := lb.shardKey(ctx)
+	if key == "" {
+		return lb.selectRoundRobin(serviceName, instances)
+	}
+
+	ring := lb.getRing(serviceName, instances)
+	h := ringHash(key)
+	i := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= h })
+	id := ring.points[i%len(ring.points)].serviceID
+	for j := range instances {
+		if instances[j].ServiceID == id {
+			return &instances[j]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package router
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSelect_HashByHeader_PinsTenantsUnderLoad(t *testing.T) {
+	var instances []Instance
+	for i := range 4 {
+		instances = append(instances, makeInstanceWithMeta(fmt.Sprintf("svc-%d", i), "api", HealthHealthy, map[string]string{"lb_strategy": "HashByHeader"}))
+	}
+	lb := NewLoadBalancerWithConfig(newProvider(instances...), Config{ShardHeader: "X-Org"}, nil)
+
+	// Requests are held open, so ConsistentHash would spill them elsewhere.
+	owners := map[string]string{}
+	for i := range 40 {
+		tenant := fmt.Sprintf("tenant-%d", i%8)
+		res, err := lb.Reserve("api", Context{SessionID: fmt.Sprintf("client-%d", i), Headers: map[string]string{"X-Org": tenant}})
+		if err != nil || res == nil {
+			t.Fatalf("Reserve: %v, %v", res, err)
+		}
+		if owner, ok := owners[tenant]; ok && owner != res.Instance.ServiceID {
+			t.Fatalf("%s moved from %s to %s", tenant, owner, res.Instance.ServiceID)
+		}
+		owners[tenant] = res.Instance.ServiceID
+	}
+
+	// Requests without the header are spread round-robin.
+	seen := map[string]bool{}
+	for range 4 {
+		inst, _ := lb.Select("api", Context{Headers: map[string]string{"X-Tenant-ID": "ignored"}})
+		seen[inst.ServiceID] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("expected requests without the header on every instance, got %v", seen)
+	}
+}
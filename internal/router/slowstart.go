@@ -0,0 +1,75 @@
+package router
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// slowStartMinShare is the share of its normal traffic an instance gets at
+// the start of its slow-start window.
+const slowStartMinShare = 0.1
+
+// warmupShares records when each healthy instance of a service became
+// healthy, and returns the traffic share of those still inside the
+// slow-start window. Instances already healthy when the balancer first sees
+// a service are treated as warm, since they were serving before it started.
+func (lb *LoadBalancer) warmupShares(serviceName string, instances []Instance) map[string]float64 {
+	window := lb.config.SlowStartWindow
+	if window <= 0 {
+		return nil
+	}
+	now := lb.now()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	since, seen := lb.healthySince[serviceName]
+	if !seen {
+		since = make(map[string]time.Time)
+		lb.healthySince[serviceName] = since
+	}
+
+	healthy := make(map[string]struct{}, len(instances))
+	var shares map[string]float64
+	for _, inst := range filterHealthy(instances) {
+		healthy[inst.ServiceID] = struct{}{}
+		t, ok := since[inst.ServiceID]
+		if !ok {
+			if seen {
+				t = now
+			}
+			since[inst.ServiceID] = t
+		}
+		if elapsed := now.Sub(t); elapsed < window {
+			if shares == nil {
+				shares = make(map[string]float64)
+			}
+			shares[inst.ServiceID] = max(float64(elapsed)/float64(window), slowStartMinShare)
+		}
+	}
+	// Instances that left or became unhealthy warm up again on return.
+	for id := range since {
+		if _, ok := healthy[id]; !ok {
+			delete(since, id)
+		}
+	}
+	return shares
+}
+
+// applySlowStart drops each warming instance from the candidates with
+// probability one minus its share, so its traffic ramps up linearly over
+// the window. If every candidate is dropped, all are kept.
+func applySlowStart(candidates []Instance, shares map[string]float64) []Instance {
+	if len(shares) == 0 {
+		return candidates
+	}
+	kept := make([]Instance, 0, len(candidates))
+	for _, inst := range candidates {
+		if share, ok := shares[inst.ServiceID]; !ok || rand.Float64() < share {
+			kept = append(kept, inst)
+		}
+	}
+	if len(kept) == 0 {
+		return candidates
+	}
+	return kept
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelect_SlowStartRampsNewInstances(t *testing.T) {
+	provider := newProvider(
+		makeInstance("svc-1", "api", HealthHealthy),
+		makeInstance("svc-2", "api", HealthHealthy),
+	)
+	lb := NewLoadBalancerWithConfig(provider, Config{SlowStartWindow: time.Minute}, nil)
+	now := time.Unix(1000, 0)
+	lb.now = func() time.Time { return now }
+
+	// share counts how many of n selections go to svc-3.
+	share := func(n int) int {
+		got := 0
+		for range n {
+			inst, _ := lb.Select("api", Context{})
+			if inst.ServiceID == "svc-3" {
+				got++
+			}
+		}
+		return got
+	}
+
+	lb.Select("api", Context{}) // svc-1 and svc-2 were serving before
+	provider.instances["api"] = append(provider.instances["api"], makeInstance("svc-3", "api", HealthHealthy))
+
+	// At the start of the window svc-3 gets about a tenth of its third.
+	if got := share(3000); got > 200 {
+		t.Fatalf("new instance got %d of 3000 requests at the start of slow start", got)
+	}
+	// Once warm it gets a full third.
+	now = now.Add(time.Minute)
+	if got := share(3000); got < 900 {
+		t.Fatalf("warm instance got only %d of 3000 requests", got)
+	}
+
+	// Recovering restarts the window.
+	provider.instances["api"][2].Status = HealthUnhealthy
+	lb.Select("api", Context{})
+	provider.instances["api"][2].Status = HealthHealthy
+	if got := share(3000); got > 200 {
+		t.Fatalf("recovered instance got %d of 3000 requests at the start of slow start", got)
+	}
+}
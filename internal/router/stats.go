@@ -0,0 +1,69 @@
+package router
+
+// InstanceStats is the serializable request history of one service instance,
+// used to persist load balancer statistics across restarts and to export them
+// for offline analysis.
+type InstanceStats struct {
+	ServiceName        string `json:"serviceName"`
+	ServiceID          string `json:"serviceId"`
+	TotalRequests      int64  `json:"totalRequests"`
+	SuccessfulRequests int64  `json:"successfulRequests"`
+	FailedRequests     int64  `json:"failedRequests"`
+	TotalResponseNanos int64  `json:"totalResponseNanos"`
+
+	// Response time percentiles since the balancer started. They are not
+	// restored by ImportStats.
+	P50ResponseNanos int64 `json:"p50ResponseNanos,omitempty"`
+	P95ResponseNanos int64 `json:"p95ResponseNanos,omitempty"`
+	P99ResponseNanos int64 `json:"p99ResponseNanos,omitempty"`
+}
+
+// ExportStats returns the request history of every instance the balancer has
+// sent traffic to.
+func (lb *LoadBalancer) ExportStats() []InstanceStats {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	out := make([]InstanceStats, 0, len(lb.stats))
+	for id, s := range lb.stats {
+		p := s.latency.percentiles()
+		out = append(out, InstanceStats{
+			ServiceName:        s.serviceName,
+			ServiceID:          id,
+			TotalRequests:      s.totalRequests.Load(),
+			SuccessfulRequests: s.successfulRequests.Load(),
+			FailedRequests:     s.failedRequests.Load(),
+			TotalResponseNanos: s.totalResponseNanos.Load(),
+			P50ResponseNanos:   int64(p.P50),
+			P95ResponseNanos:   int64(p.P95),
+			P99ResponseNanos:   int64(p.P99),
+		})
+	}
+	return out
+}
+
+// ImportStats adds previously exported history to the balancer's counters.
+// Instances that already have traffic keep it; imported counts are added on
+// top, so importing right after start-up restores the pre-restart view.
+func (lb *LoadBalancer) ImportStats(stats []InstanceStats) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	for _, in := range stats {
+		if in.ServiceID == "" {
+			continue
+		}
+		s, ok := lb.stats[in.ServiceID]
+		if !ok {
+			s = newServiceStats(in.ServiceName)
+			lb.stats[in.ServiceID] = s
+		}
+		s.totalRequests.Add(in.TotalRequests)
+		s.successfulRequests.Add(in.SuccessfulRequests)
+		s.failedRequests.Add(in.FailedRequests)
+		s.totalResponseNanos.Add(in.TotalResponseNanos)
+		s.mu.Lock()
+		s.instanceCounts[in.ServiceID] += int(in.TotalRequests)
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats_ExportImportRoundTrip(t *testing.T) {
+	lb := NewLoadBalancer(newProvider(makeInstance("a-1", "api", HealthHealthy)))
+	for _, ok := range []bool{true, true, false} {
+		inst, err := lb.Select("api", Context{})
+		if err != nil || inst == nil {
+			t.Fatalf("Select: %v", err)
+		}
+		lb.ReportResult(inst.ServiceID, RequestResult{ServiceID: inst.ServiceID, Success: ok, ResponseTime: 10 * time.Millisecond})
+	}
+
+	exported := lb.ExportStats()
+	if len(exported) != 1 || exported[0].TotalRequests != 3 || exported[0].FailedRequests != 1 {
+		t.Fatalf("unexpected export %+v", exported)
+	}
+
+	restored := NewLoadBalancer(newProvider())
+	restored.ImportStats(exported)
+	restored.ImportStats([]InstanceStats{{ServiceName: "api"}}) // no ID, ignored
+
+	got := restored.Stats("api")
+	if got.TotalRequests != 3 || got.SuccessfulRequests != 2 || got.FailedRequests != 1 {
+		t.Fatalf("unexpected restored stats %+v", got)
+	}
+	if got.AverageResponseTime != 10*time.Millisecond {
+		t.Errorf("expected 10ms average, got %v", got.AverageResponseTime)
+	}
+	if got.InstanceRequestCounts["a-1"] != 3 {
+		t.Errorf("expected 3 requests for a-1, got %v", got.InstanceRequestCounts)
+	}
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toska-mesh/toska-mesh/internal/telemetry"
+)
+
+func TestResolveStrategy(t *testing.T) {
+	declaring := func(strategies ...string) []Instance {
+		var out []Instance
+		for i, s := range strategies {
+			meta := map[string]string{}
+			if s != "" {
+				meta["lb_strategy"] = s
+			}
+			out = append(out, makeInstanceWithMeta(string(rune('a'+i)), "api", HealthHealthy, meta))
+		}
+		return out
+	}
+	tests := []struct {
+		name       string
+		config     Config
+		candidates []Instance
+		want       Strategy
+	}{
+		{"none declared", Config{}, declaring("", ""), RoundRobin},
+		{"configured default", Config{DefaultStrategy: Random}, declaring("", ""), Random},
+		{"majority", Config{}, declaring("Random", "LeastConnections", "LeastConnections"), LeastConnections},
+		{"majority regardless of order", Config{}, declaring("LeastConnections", "Random", "LeastConnections", "Random", "Random"), Random},
+		{"tie goes to earliest constant", Config{}, declaring("IPHash", "LeastConnections"), LeastConnections},
+		{"per-service config wins", Config{Strategies: map[string]Strategy{"api": ConsistentHash}}, declaring("Random", "Random"), ConsistentHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLoadBalancerWithConfig(newProvider(), tt.config, nil)
+			if got := lb.resolveStrategy("API", tt.candidates); got != tt.want {
+				t.Fatalf("resolveStrategy = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveStrategy_ExportsConflicts(t *testing.T) {
+	prom := telemetry.NewPrometheus()
+	provider := newProvider(
+		makeInstanceWithMeta("svc-1", "api", HealthHealthy, map[string]string{"lb_strategy": "Random"}),
+		makeInstanceWithMeta("svc-2", "api", HealthHealthy, map[string]string{"lb_strategy": "LeastConnections"}),
+	)
+	lb := NewLoadBalancerWithTelemetry(provider, prom)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		prom.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	lb.Select("api", Context{})
+	if body := scrape(); !strings.Contains(body, `router_strategy_conflict{service="api"} 1`) {
+		t.Fatalf("expected a conflict in:\n%s", body)
+	}
+
+	provider.instances["api"][0].Metadata = map[string]string{"lb_strategy": "LeastConnections"}
+	lb.Select("api", Context{})
+	if body := scrape(); !strings.Contains(body, `router_strategy_conflict{service="api"} 0`) {
+		t.Fatalf("expected the conflict to clear in:\n%s", body)
+	}
+}
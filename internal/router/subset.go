@@ -0,0 +1,84 @@
+package router
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// cachedSubset is a balancer's subset of one set of a service's instances.
+type cachedSubset struct {
+	members uint64              // memberHash of the instances it was chosen from
+	ids     map[string]struct{} // service IDs in the subset
+}
+
+// subset returns the instances in this balancer's subset of instances. The
+// subset is chosen once per instance set and cached for the service, so
+// requests only filter by it.
+func (lb *LoadBalancer) subset(serviceName string, instances []Instance) []Instance {
+	size, index, count := lb.config.SubsetSize, lb.config.ClientIndex, lb.config.ClientCount
+	if size <= 0 || count <= 0 || len(instances) <= size {
+		return instances
+	}
+	members := memberHash(instances)
+
+	lb.mu.Lock()
+	cached, ok := lb.subsets[serviceName]
+	if !ok || cached.members != members {
+		chosen := subset(instances, size, index, count)
+		cached = &cachedSubset{members: members, ids: make(map[string]struct{}, len(chosen))}
+		for _, inst := range chosen {
+			cached.ids[inst.ServiceID] = struct{}{}
+		}
+		lb.subsets[serviceName] = cached
+	}
+	lb.mu.Unlock()
+
+	var result []Instance
+	for _, inst := range instances {
+		if _, ok := cached.ids[inst.ServiceID]; ok {
+			result = append(result, inst)
+		}
+	}
+	return result
+}
+
+// subset deterministically picks the instances client clientIndex of
+// clientCount routes to, using the deterministic subsetting scheme from
+// Google's SRE book: clients are grouped into rounds of one client per
+// subset, each round shuffles the instances with a seed shared by every
+// client in it and deals them out into disjoint subsets of size, one per
+// client. Every instance therefore gets an even share of clients, and a
+// client's subset only changes when the instance set does. With fewer
+// clients than subsets the subsets are widened so every instance is still
+// in one.
+func subset(instances []Instance, size, clientIndex, clientCount int) []Instance {
+	if size <= 0 || clientCount <= 0 || len(instances) <= size {
+		return instances
+	}
+	shuffled := slices.Clone(instances)
+	slices.SortFunc(shuffled, func(a, b Instance) int { return strings.Compare(a.ServiceID, b.ServiceID) })
+
+	count := len(shuffled) / size
+	if clientCount < count {
+		count = clientCount
+		size = (len(shuffled) + count - 1) / count
+	}
+	round := uint64(clientIndex / count)
+	rand.New(rand.NewPCG(round, 0)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	start := clientIndex % count * size
+	return shuffled[start:min(start+size, len(shuffled))]
+}
+
+// memberHash identifies a set of instances by their service IDs,
+// independent of order.
+func memberHash(instances []Instance) uint64 {
+	h := uint64(len(instances))
+	for _, inst := range instances {
+		h += ringHash(inst.ServiceID)
+	}
+	return h
+}
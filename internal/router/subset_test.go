@@ -0,0 +1,90 @@
+package router
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestSubset_EvenAndDeterministic(t *testing.T) {
+	var instances []Instance
+	for i := range 100 {
+		instances = append(instances, makeInstance(fmt.Sprintf("svc-%03d", i), "api", HealthHealthy))
+	}
+
+	reversed := slices.Clone(instances)
+	slices.Reverse(reversed)
+
+	// 100 clients with subsets of 10: every instance gets exactly 10.
+	clients := map[string]int{}
+	for c := range 100 {
+		sub := subset(instances, 10, c, 100)
+		if len(sub) != 10 {
+			t.Fatalf("expected subsets of 10, got %d", len(sub))
+		}
+		again := subset(reversed, 10, c, 100)
+		for i := range sub {
+			if sub[i].ServiceID != again[i].ServiceID {
+				t.Fatalf("subset of client %d is not deterministic", c)
+			}
+			clients[sub[i].ServiceID]++
+		}
+	}
+	if len(clients) != 100 {
+		t.Fatalf("only %d of 100 instances are in any subset", len(clients))
+	}
+	for id, n := range clients {
+		if n != 10 {
+			t.Fatalf("%s is in %d of 100 subsets", id, n)
+		}
+	}
+}
+
+func TestSubset_FewClientsCoverEveryInstance(t *testing.T) {
+	var instances []Instance
+	for i := range 100 {
+		instances = append(instances, makeInstance(fmt.Sprintf("svc-%03d", i), "api", HealthHealthy))
+	}
+
+	covered := map[string]bool{}
+	for c := range 3 {
+		for _, inst := range subset(instances, 10, c, 3) {
+			if covered[inst.ServiceID] {
+				t.Fatalf("%s is in more than one subset", inst.ServiceID)
+			}
+			covered[inst.ServiceID] = true
+		}
+	}
+	if len(covered) != 100 {
+		t.Fatalf("only %d of 100 instances are in any subset", len(covered))
+	}
+}
+
+func TestSelect_SubsetFallsBackWhenSubsetUnhealthy(t *testing.T) {
+	var instances []Instance
+	for i := range 6 {
+		instances = append(instances, makeInstance(fmt.Sprintf("svc-%d", i), "api", HealthHealthy))
+	}
+	provider := newProvider(instances...)
+	lb := NewLoadBalancerWithConfig(provider, Config{SubsetSize: 2, ClientIndex: 1, ClientCount: 3}, nil)
+
+	inSubset := map[string]bool{}
+	for _, inst := range subset(instances, 2, 1, 3) {
+		inSubset[inst.ServiceID] = true
+	}
+	for range 10 {
+		inst, _ := lb.Select("api", Context{})
+		if !inSubset[inst.ServiceID] {
+			t.Fatalf("%s is outside the subset %v", inst.ServiceID, inSubset)
+		}
+	}
+
+	for i := range provider.instances["api"] {
+		if inSubset[provider.instances["api"][i].ServiceID] {
+			provider.instances["api"][i].Status = HealthUnhealthy
+		}
+	}
+	if inst, _ := lb.Select("api", Context{}); inst == nil || inSubset[inst.ServiceID] {
+		t.Fatalf("expected a healthy instance outside the subset, got %v", inst)
+	}
+}
@@ -0,0 +1,29 @@
+package telemetry
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// ConfigFromEnv reads the TELEMETRY_* settings shared by all control plane
+// processes over DefaultConfig(serviceName).
+func ConfigFromEnv(serviceName string) Config {
+	cfg := DefaultConfig(serviceName)
+	if v := os.Getenv("TELEMETRY_SINK"); v != "" {
+		cfg.Sink = v
+	}
+	if v := os.Getenv("TELEMETRY_OTLP_ENDPOINT"); v != "" {
+		cfg.OTLPEndpoint = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("TELEMETRY_OTLP_INTERVAL_SECONDS")); err == nil && v > 0 {
+		cfg.OTLPInterval = time.Duration(v) * time.Second
+	}
+	if os.Getenv("TELEMETRY_TRACING_ENABLED") == "true" {
+		cfg.Tracing = true
+	}
+	if v := os.Getenv("TELEMETRY_OTLP_TRACES_ENDPOINT"); v != "" {
+		cfg.OTLPTracesEndpoint = v
+	}
+	return cfg
+}
@@ -0,0 +1,206 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OTLP aggregates metrics in memory and periodically pushes them to an
+// OpenTelemetry collector using OTLP/HTTP with JSON encoding. Values are
+// reported with cumulative temporality.
+type OTLP struct {
+	*store
+	config Config
+	client *http.Client
+	logger *slog.Logger
+}
+
+// NewOTLP creates an OTLP push sink. Call Run to start exporting.
+func NewOTLP(cfg Config, logger *slog.Logger) *OTLP {
+	return &OTLP{
+		store:  newStore(),
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+// Run exports every OTLPInterval until ctx is cancelled, then exports once
+// more so the final values are not lost.
+func (o *OTLP) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.config.OTLPInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := o.Export(shutdownCtx); err != nil {
+				o.logger.Warn("final OTLP metrics export failed", "error", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := o.Export(ctx); err != nil {
+				o.logger.Warn("OTLP metrics export failed", "error", err)
+			}
+		}
+	}
+}
+
+// Export pushes the current metric values to the collector.
+func (o *OTLP) Export(ctx context.Context) error {
+	body, err := json.Marshal(o.payload(time.Now()))
+	if err != nil {
+		return fmt.Errorf("encode otlp metrics: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.OTLPEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp export: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp export: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON payload types (opentelemetry-proto metrics/v1). 64-bit integers
+// are encoded as strings, per the OTLP JSON mapping.
+
+type otlpPayload struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Sum       *otlpSum       `json:"sum,omitempty"`
+	Gauge     *otlpGauge     `json:"gauge,omitempty"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+}
+
+const otlpCumulative = 2 // AGGREGATION_TEMPORALITY_CUMULATIVE
+
+type otlpSum struct {
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberPoint `json:"dataPoints"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpHistogram struct {
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+	DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+}
+
+type otlpHistogramPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+func (o *OTLP) payload(now time.Time) otlpPayload {
+	start := strconv.FormatInt(o.start.UnixNano(), 10)
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+
+	var metrics []otlpMetric
+	for _, f := range o.snapshot() {
+		m := otlpMetric{Name: f.name}
+		switch f.kind {
+		case kindCounter:
+			m.Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+			for _, s := range f.series {
+				m.Sum.DataPoints = append(m.Sum.DataPoints, otlpNumberPoint{otlpAttributes(s.labels), start, ts, s.value})
+			}
+		case kindGauge:
+			m.Gauge = &otlpGauge{}
+			for _, s := range f.series {
+				m.Gauge.DataPoints = append(m.Gauge.DataPoints, otlpNumberPoint{otlpAttributes(s.labels), start, ts, s.value})
+			}
+		case kindHistogram:
+			m.Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+			for _, s := range f.series {
+				counts := make([]string, len(s.counts))
+				for i, c := range s.counts {
+					counts[i] = strconv.FormatUint(c, 10)
+				}
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, otlpHistogramPoint{
+					Attributes:        otlpAttributes(s.labels),
+					StartTimeUnixNano: start,
+					TimeUnixNano:      ts,
+					Count:             strconv.FormatUint(s.count, 10),
+					Sum:               s.sum,
+					BucketCounts:      counts,
+					ExplicitBounds:    DefaultBuckets,
+				})
+			}
+		}
+		metrics = append(metrics, m)
+	}
+
+	return otlpPayload{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": o.config.ServiceName}},
+		}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "github.com/toska-mesh/toska-mesh"},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+func otlpAttributes(labels Labels) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		attrs = append(attrs, otlpAttribute{Key: k, Value: map[string]string{"stringValue": labels[k]}})
+	}
+	return attrs
+}
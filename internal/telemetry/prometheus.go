@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Prometheus aggregates metrics in memory and serves them in the Prometheus
+// text exposition format for scraping.
+type Prometheus struct {
+	*store
+}
+
+// NewPrometheus creates an empty Prometheus sink.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{store: newStore()}
+}
+
+// ServeHTTP writes all metrics in text exposition format 0.0.4.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for _, f := range p.snapshot() {
+		switch f.kind {
+		case kindCounter:
+			fmt.Fprintf(bw, "# TYPE %s counter\n", f.name)
+		case kindGauge:
+			fmt.Fprintf(bw, "# TYPE %s gauge\n", f.name)
+		case kindHistogram:
+			fmt.Fprintf(bw, "# TYPE %s histogram\n", f.name)
+		}
+		for _, s := range f.series {
+			if f.kind != kindHistogram {
+				fmt.Fprintf(bw, "%s%s %s\n", f.name, promLabels(s.labels, "", ""), formatFloat(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, bound := range DefaultBuckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, promLabels(s.labels, "le", formatFloat(bound)), cumulative)
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", f.name, promLabels(s.labels, "le", "+Inf"), s.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", f.name, promLabels(s.labels, "", ""), formatFloat(s.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", f.name, promLabels(s.labels, "", ""), s.count)
+		}
+	}
+}
+
+// promLabels formats labels as {k="v",...}, optionally appending one extra
+// label (used for histogram "le").
+func promLabels(labels Labels, extraKey, extraValue string) string {
+	if len(labels) == 0 && extraKey == "" {
+		return ""
+	}
+	var parts []string
+	for _, k := range sortedKeys(labels) {
+		parts = append(parts, k+`="`+escapeLabel(labels[k])+`"`)
+	}
+	if extraKey != "" {
+		parts = append(parts, extraKey+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,169 @@
+package telemetry
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, matching the
+// Prometheus client defaults.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type kind int
+
+const (
+	kindCounter kind = iota
+	kindGauge
+	kindHistogram
+)
+
+// series is one labelled time series.
+type series struct {
+	labels Labels
+	value  float64  // counter or gauge value
+	counts []uint64 // histogram: per-bucket (non-cumulative) counts, len(buckets)+1
+	sum    float64
+	count  uint64
+}
+
+// family groups the series of one metric name.
+type family struct {
+	name   string
+	kind   kind
+	series map[string]*series // keyed by encoded labels
+}
+
+// store aggregates metric updates in memory for exporters to read.
+type store struct {
+	start time.Time
+
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func newStore() *store {
+	return &store{start: time.Now(), families: make(map[string]*family)}
+}
+
+// series returns the series for name and labels, creating it if needed. It
+// returns nil if name was first used with a different kind. Callers hold s.mu.
+func (s *store) series(name string, k kind, labels Labels) *series {
+	f, ok := s.families[name]
+	if !ok {
+		f = &family{name: name, kind: k, series: make(map[string]*series)}
+		s.families[name] = f
+	}
+	if f.kind != k {
+		return nil
+	}
+	key := labelKey(labels)
+	ser, ok := f.series[key]
+	if !ok {
+		copied := make(Labels, len(labels))
+		for k, v := range labels {
+			copied[k] = v
+		}
+		ser = &series{labels: copied}
+		if k == kindHistogram {
+			ser.counts = make([]uint64, len(DefaultBuckets)+1)
+		}
+		f.series[key] = ser
+	}
+	return ser
+}
+
+func (s *store) Count(name string, delta float64, labels Labels) {
+	if delta < 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ser := s.series(name, kindCounter, labels); ser != nil {
+		ser.value += delta
+	}
+}
+
+func (s *store) Gauge(name string, value float64, labels Labels) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ser := s.series(name, kindGauge, labels); ser != nil {
+		ser.value = value
+	}
+}
+
+func (s *store) Observe(name string, value float64, labels Labels) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ser := s.series(name, kindHistogram, labels)
+	if ser == nil {
+		return
+	}
+	i := sort.SearchFloat64s(DefaultBuckets, value)
+	ser.counts[i]++
+	ser.sum += value
+	ser.count++
+}
+
+// Delete drops the series of name with exactly labels.
+func (s *store) Delete(name string, labels Labels) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.families[name]; ok {
+		delete(f.series, labelKey(labels))
+	}
+}
+
+// snapshot returns a deep copy of all families, sorted by name, with series
+// sorted by labels.
+func (s *store) snapshot() []familySnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]familySnapshot, 0, len(s.families))
+	for _, f := range s.families {
+		fs := familySnapshot{name: f.name, kind: f.kind}
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ser := *f.series[k]
+			ser.counts = append([]uint64(nil), ser.counts...)
+			fs.series = append(fs.series, ser)
+		}
+		out = append(out, fs)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+type familySnapshot struct {
+	name   string
+	kind   kind
+	series []series
+}
+
+// labelKey encodes labels in sorted order for use as a map key.
+func labelKey(labels Labels) string {
+	keys := sortedKeys(labels)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte(0)
+		sb.WriteString(labels[k])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+func sortedKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+// Package telemetry defines the metrics sink shared by every control plane
+// component. Components record counters, gauges, and histograms against a
+// Sink; operators choose one backend (Prometheus scrape, OTLP push, or none)
+// at start-up instead of each feature wiring its own metrics plumbing. A
+// Tracer records spans and pushes them to the same OTLP collector.
+package telemetry
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Labels are metric dimensions. Keep label values low-cardinality (service
+// names, status codes), never request IDs or paths.
+type Labels map[string]string
+
+// Sink receives metric updates. Implementations must be safe for concurrent
+// use and must not block the caller.
+type Sink interface {
+	// Count adds delta to a monotonically increasing counter.
+	Count(name string, delta float64, labels Labels)
+	// Gauge sets a value that can go up and down.
+	Gauge(name string, value float64, labels Labels)
+	// Observe records a sample in a histogram. Durations are recorded in
+	// seconds.
+	Observe(name string, value float64, labels Labels)
+}
+
+// Deleter is implemented by sinks that can drop a series, so gauges for
+// things that no longer exist (a deregistered instance) stop being exported.
+type Deleter interface {
+	Delete(name string, labels Labels)
+}
+
+// Delete drops a series from sink if the sink supports it.
+func Delete(sink Sink, name string, labels Labels) {
+	if d, ok := sink.(Deleter); ok {
+		d.Delete(name, labels)
+	}
+}
+
+// Nop discards all metrics.
+type Nop struct{}
+
+func (Nop) Count(string, float64, Labels)   {}
+func (Nop) Gauge(string, float64, Labels)   {}
+func (Nop) Observe(string, float64, Labels) {}
+
+// Sink kinds accepted by Config.Sink.
+const (
+	SinkNone       = "none"
+	SinkPrometheus = "prometheus"
+	SinkOTLP       = "otlp"
+)
+
+// Config selects and configures a sink.
+type Config struct {
+	Sink         string // none (default), prometheus, or otlp
+	ServiceName  string // reported as the OTLP service.name resource attribute
+	OTLPEndpoint string // OTLP/HTTP metrics endpoint
+	OTLPInterval time.Duration
+
+	// Tracing enables span export to OTLPTracesEndpoint, independently of
+	// the metrics sink.
+	Tracing            bool
+	OTLPTracesEndpoint string
+	TraceInterval      time.Duration
+}
+
+// DefaultConfig returns a disabled sink and tracer with the standard OTLP
+// collector endpoints.
+func DefaultConfig(serviceName string) Config {
+	return Config{
+		Sink:         SinkNone,
+		ServiceName:  serviceName,
+		OTLPEndpoint: "http://localhost:4318/v1/metrics",
+		OTLPInterval: 15 * time.Second,
+
+		OTLPTracesEndpoint: "http://localhost:4318/v1/traces",
+		TraceInterval:      5 * time.Second,
+	}
+}
+
+// New creates the configured sink. A *Prometheus sink is also an
+// http.Handler to mount at /metrics; an *OTLP sink must be started with Run.
+func New(cfg Config, logger *slog.Logger) (Sink, error) {
+	switch cfg.Sink {
+	case "", SinkNone:
+		return Nop{}, nil
+	case SinkPrometheus:
+		return NewPrometheus(), nil
+	case SinkOTLP:
+		return NewOTLP(cfg, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown telemetry sink %q", cfg.Sink)
+	}
+}
+
+// OrNop returns s, or Nop if s is nil.
+func OrNop(s Sink) Sink {
+	if s == nil {
+		return Nop{}
+	}
+	return s
+}
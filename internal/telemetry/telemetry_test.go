@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheus_Exposition(t *testing.T) {
+	p := NewPrometheus()
+	p.Count("gateway_requests_total", 1, Labels{"service": "orders", "code": "200"})
+	p.Count("gateway_requests_total", 2, Labels{"code": "200", "service": "orders"})
+	p.Count("gateway_requests_total", -5, Labels{"service": "orders", "code": "200"}) // ignored
+	p.Gauge("queue_depth", 3, Labels{"service": `we"ird`})
+	p.Observe("latency_seconds", 0.02, nil)
+	p.Observe("latency_seconds", 20, nil)
+	p.Gauge("gateway_requests_total", 9, nil) // kind mismatch, ignored
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		"# TYPE gateway_requests_total counter\n",
+		`gateway_requests_total{code="200",service="orders"} 3` + "\n",
+		`queue_depth{service="we\"ird"} 3` + "\n",
+		"# TYPE latency_seconds histogram\n",
+		`latency_seconds_bucket{le="0.01"} 0` + "\n",
+		`latency_seconds_bucket{le="0.025"} 1` + "\n",
+		`latency_seconds_bucket{le="+Inf"} 2` + "\n",
+		"latency_seconds_sum 20.02\n",
+		"latency_seconds_count 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected exposition to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "gateway_requests_total 9") {
+		t.Error("expected gauge update of a counter name to be ignored")
+	}
+}
+
+func TestDelete_DropsSeries(t *testing.T) {
+	p := NewPrometheus()
+	p.Gauge("instance_up", 1, Labels{"service_id": "orders-1"})
+	p.Gauge("instance_up", 1, Labels{"service_id": "orders-2"})
+	Delete(p, "instance_up", Labels{"service_id": "orders-1"})
+	Delete(Nop{}, "instance_up", nil) // unsupported sinks are ignored
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	if strings.Contains(body, "orders-1") || !strings.Contains(body, `instance_up{service_id="orders-2"} 1`) {
+		t.Fatalf("expected only orders-2 exported, got:\n%s", body)
+	}
+}
+
+func TestOTLP_Export(t *testing.T) {
+	var got otlpPayload
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer collector.Close()
+
+	cfg := DefaultConfig("gateway")
+	cfg.OTLPEndpoint = collector.URL
+	o := NewOTLP(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	o.Count("requests_total", 4, Labels{"service": "orders"})
+	o.Observe("latency_seconds", 0.2, nil)
+
+	if err := o.Export(context.Background()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	rm := got.ResourceMetrics[0]
+	if rm.Resource.Attributes[0].Value["stringValue"] != "gateway" {
+		t.Fatalf("expected service.name gateway, got %+v", rm.Resource.Attributes)
+	}
+	metrics := rm.ScopeMetrics[0].Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(metrics))
+	}
+	hist, sum := metrics[0], metrics[1]
+	if hist.Histogram == nil || hist.Histogram.DataPoints[0].Count != "1" || len(hist.Histogram.DataPoints[0].BucketCounts) != len(DefaultBuckets)+1 {
+		t.Fatalf("unexpected histogram %+v", hist)
+	}
+	if sum.Sum == nil || !sum.Sum.IsMonotonic || sum.Sum.DataPoints[0].AsDouble != 4 {
+		t.Fatalf("unexpected sum %+v", sum)
+	}
+}
+
+func TestNew_SelectsSink(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tests := []struct {
+		sink    string
+		wantErr bool
+		check   func(Sink) bool
+	}{
+		{"", false, func(s Sink) bool { _, ok := s.(Nop); return ok }},
+		{"prometheus", false, func(s Sink) bool { _, ok := s.(http.Handler); return ok }},
+		{"otlp", false, func(s Sink) bool { _, ok := s.(*OTLP); return ok }},
+		{"statsd", true, nil},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig("test")
+		cfg.Sink = tt.sink
+		cfg.OTLPInterval = time.Second
+		s, err := New(cfg, logger)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: unexpected error %v", tt.sink, err)
+		}
+		if err == nil && !tt.check(s) {
+			t.Fatalf("%q: unexpected sink type %T", tt.sink, s)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid reports whether the trace ID is set.
+func (sc SpanContext) IsValid() bool { return sc.TraceID != [16]byte{} }
+
+// TraceIDString returns the trace ID as 32 lowercase hex digits.
+func (sc SpanContext) TraceIDString() string { return hex.EncodeToString(sc.TraceID[:]) }
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceIDString() + "-" + hex.EncodeToString(sc.SpanID[:]) + "-01"
+}
+
+// Header names read from requests, lowercased for gRPC metadata. The
+// gateway's correlation ID may come from the client, so it is recorded on
+// spans as CorrelationIDAttribute but never used as a trace ID.
+const (
+	TraceparentHeader   = "traceparent"
+	CorrelationIDHeader = "x-correlation-id"
+)
+
+// CorrelationIDAttribute is the span attribute holding a request's
+// correlation ID.
+const CorrelationIDAttribute = "correlation_id"
+
+// Extract returns the remote parent carried by a request's W3C traceparent.
+// get looks up a header or metadata value by lowercase name.
+func Extract(get func(key string) string) (SpanContext, bool) {
+	return parseTraceparent(get(TraceparentHeader))
+}
+
+// SetCorrelationID records the request's correlation ID, if any, on span.
+func SetCorrelationID(span *Span, get func(key string) string) {
+	if id := get(CorrelationIDHeader); id != "" {
+		span.SetAttribute(CorrelationIDAttribute, id)
+	}
+}
+
+func parseTraceparent(v string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	if !sc.IsValid() || sc.SpanID == [8]byte{} {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+type spanContextKey struct{}
+
+// ContextWithRemoteParent returns ctx carrying sc as the parent for spans
+// started from it.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the current span's context, if any.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok
+}
+
+// SpanKind values follow the OTLP enumeration.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// Span is an in-progress operation. All methods are safe on a nil Span, which
+// is what a nil Tracer starts.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent [8]byte
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu    sync.Mutex
+	attrs Labels
+}
+
+// Context returns the span's identity, for logging or propagation.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute records a key/value on the span. Unlike metric labels, span
+// attributes may be high-cardinality (service IDs, peers).
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// End finishes the span, marking it failed if err is non-nil, and queues it
+// for export.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+	s.mu.Lock()
+	attrs := s.attrs
+	s.mu.Unlock()
+
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.sc.TraceID[:]),
+		SpanID:            hex.EncodeToString(s.sc.SpanID[:]),
+		Name:              s.name,
+		Kind:              int(s.kind),
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+		Attributes:        otlpAttributes(attrs),
+		Status:            otlpStatus{Code: otlpStatusOK},
+	}
+	if s.parent != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parent[:])
+	}
+	if err != nil {
+		span.Status = otlpStatus{Code: otlpStatusError, Message: err.Error()}
+	}
+	s.tracer.enqueue(span)
+}
+
+// DefaultMaxQueuedSpans bounds the spans buffered between exports; spans
+// beyond it are dropped rather than blocking callers.
+const DefaultMaxQueuedSpans = 4096
+
+// Tracer records spans and periodically pushes them to an OpenTelemetry
+// collector using OTLP/HTTP with JSON encoding. A nil Tracer records nothing.
+type Tracer struct {
+	config Config
+	client *http.Client
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	queue   []otlpSpan
+	dropped int
+}
+
+// NewTracer creates a tracer, or returns nil when cfg.Tracing is off. Call
+// Run to start exporting.
+func NewTracer(cfg Config, logger *slog.Logger) *Tracer {
+	if !cfg.Tracing {
+		return nil
+	}
+	return &Tracer{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+// Start begins a span that is a child of the span (or remote parent) in ctx,
+// or the root of a new trace. The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now(), attrs: Labels{}}
+	if parent, ok := SpanContextFromContext(ctx); ok && parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.sc.TraceID[:])
+	}
+	rand.Read(span.sc.SpanID[:])
+	return context.WithValue(ctx, spanContextKey{}, span.sc), span
+}
+
+func (t *Tracer) enqueue(span otlpSpan) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= DefaultMaxQueuedSpans {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, span)
+}
+
+// Run exports every TraceInterval until ctx is cancelled, then exports once
+// more so queued spans are not lost.
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.config.TraceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := t.Export(shutdownCtx); err != nil {
+				t.logger.Warn("final OTLP trace export failed", "error", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := t.Export(ctx); err != nil {
+				t.logger.Warn("OTLP trace export failed", "error", err)
+			}
+		}
+	}
+}
+
+// Export pushes the queued spans to the collector. Spans are dropped if the
+// push fails; tracing is best-effort.
+func (t *Tracer) Export(ctx context.Context) error {
+	t.mu.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+
+	if dropped > 0 {
+		t.logger.Warn("dropped spans: export queue full", "count", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(otlpTracePayload{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": t.config.ServiceName}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/toska-mesh/toska-mesh"},
+			Spans: spans,
+		}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("encode otlp spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.OTLPTracesEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp trace export: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp trace export: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON trace payload types (opentelemetry-proto trace/v1). Trace and
+// span IDs are hex strings, per the OTLP JSON mapping.
+
+type otlpTracePayload struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
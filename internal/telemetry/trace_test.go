@@ -0,0 +1,121 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantOK      bool
+		wantTraceID string
+		wantSpanID  string
+	}{
+		{"traceparent", map[string]string{TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, true, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"correlation id is not a trace", map[string]string{CorrelationIDHeader: "4bf92f3577b34da6a3ce929d0e0e4736"}, false, "", ""},
+		{"traceparent beside correlation id", map[string]string{
+			TraceparentHeader:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			CorrelationIDHeader: "11111111111111111111111111111111",
+		}, true, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"invalid traceparent", map[string]string{
+			TraceparentHeader:   "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			CorrelationIDHeader: "11111111111111111111111111111111",
+		}, false, "", ""},
+		{"none", nil, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := Extract(func(key string) string { return tt.headers[key] })
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceIDString() != tt.wantTraceID {
+				t.Fatalf("expected trace ID %s, got %s", tt.wantTraceID, sc.TraceIDString())
+			}
+			if got := sc.Traceparent()[36:52]; got != tt.wantSpanID {
+				t.Fatalf("expected span ID %s, got %s", tt.wantSpanID, got)
+			}
+		})
+	}
+}
+
+func TestTracer_ExportsSpans(t *testing.T) {
+	var got otlpTracePayload
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer collector.Close()
+
+	cfg := DefaultConfig("discovery")
+	cfg.Tracing = true
+	cfg.OTLPTracesEndpoint = collector.URL
+	tracer := NewTracer(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	parent, _ := Extract(func(key string) string {
+		if key == TraceparentHeader {
+			return "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		}
+		return ""
+	})
+	ctx, server := tracer.Start(ContextWithRemoteParent(context.Background(), parent), "Register", SpanKindServer)
+	_, client := tracer.Start(ctx, "consul register", SpanKindClient)
+	client.SetAttribute("peer.service", "consul")
+	client.End(errors.New("connection refused"))
+	server.End(nil)
+
+	if err := tracer.Export(context.Background()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, s := spans[0], spans[1]
+	if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || c.TraceID != s.TraceID {
+		t.Fatalf("expected spans to join the remote trace, got %s and %s", s.TraceID, c.TraceID)
+	}
+	if s.ParentSpanID != "00f067aa0ba902b7" {
+		t.Fatalf("expected the remote span as parent, got %s", s.ParentSpanID)
+	}
+	if c.ParentSpanID != s.SpanID {
+		t.Fatalf("expected client span parent %s, got %s", s.SpanID, c.ParentSpanID)
+	}
+	if c.Status.Code != otlpStatusError || c.Status.Message != "connection refused" || s.Status.Code != otlpStatusOK {
+		t.Fatalf("unexpected statuses %+v / %+v", c.Status, s.Status)
+	}
+	if c.Kind != int(SpanKindClient) || c.Attributes[0].Key != "peer.service" {
+		t.Fatalf("unexpected client span %+v", c)
+	}
+
+	// The queue is drained after a successful export.
+	got = otlpTracePayload{}
+	tracer.Export(context.Background())
+	if len(got.ResourceSpans) != 0 {
+		t.Fatalf("expected no second export, got %+v", got)
+	}
+}
+
+func TestNewTracer_Disabled(t *testing.T) {
+	tracer := NewTracer(DefaultConfig("discovery"), nil)
+	if tracer != nil {
+		t.Fatal("expected nil tracer when tracing is off")
+	}
+	ctx, span := tracer.Start(context.Background(), "Register", SpanKindServer)
+	span.SetAttribute("k", "v")
+	span.End(nil)
+	if _, ok := SpanContextFromContext(ctx); ok {
+		t.Fatal("expected nil tracer to leave the context unchanged")
+	}
+}
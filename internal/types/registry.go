@@ -0,0 +1,81 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+// Instance represents a registered service instance.
+type Instance struct {
+	ServiceName     string
+	ServiceID       string
+	Address         string
+	Port            int
+	Status          HealthStatus
+	Metadata        map[string]string
+	RegisteredAt    time.Time
+	LastHealthCheck time.Time
+}
+
+// MetadataHealthHold is set on an instance the health monitor is holding
+// out of routing, e.g. while it flaps between Healthy and Unhealthy. Routers
+// skip instances where it is non-empty.
+const MetadataHealthHold = "health_hold"
+
+// MetadataDatacenter labels instances returned by multi-datacenter queries
+// with the datacenter they were found in.
+const MetadataDatacenter = "dc"
+
+// Registration contains the information needed to register a service.
+type Registration struct {
+	ServiceName string
+	ServiceID   string
+	Address     string
+	Port        int
+	Metadata    map[string]string
+	HealthCheck *HealthCheckConfig
+}
+
+// HealthCheckConfig defines health check parameters for registration.
+type HealthCheckConfig struct {
+	Endpoint           string
+	IntervalSeconds    int
+	TimeoutSeconds     int
+	UnhealthyThreshold int
+}
+
+// ErrNotRegistered is returned when a service is not known to the registry
+// (for Consul, not in the catalog).
+var ErrNotRegistered = errors.New("not registered")
+
+// ErrReadOnly is returned by writes to a registry whose contents are owned by
+// another system, such as Kubernetes endpoints.
+var ErrReadOnly = errors.New("registry is read-only")
+
+// MetadataUpdate describes a change to a service's metadata.
+type MetadataUpdate struct {
+	Set     map[string]string // keys to add or overwrite
+	Remove  []string          // keys to delete, applied after Set
+	Replace bool              // discard existing keys before applying Set
+}
+
+// MetadataChange is the outcome of applying a MetadataUpdate.
+type MetadataChange struct {
+	ServiceName string
+	Previous    map[string]string
+	Current     map[string]string
+}
+
+// TTLWithBuffer returns the TTL a registry should enforce for a registration:
+// the health check interval (30s by default) plus 5s of slack, at least 10s.
+func (r Registration) TTLWithBuffer() time.Duration {
+	ttl := 30 * time.Second
+	if r.HealthCheck != nil && r.HealthCheck.IntervalSeconds > 0 {
+		ttl = time.Duration(r.HealthCheck.IntervalSeconds) * time.Second
+	}
+	ttl += 5 * time.Second
+	if ttl < 10*time.Second {
+		ttl = 10 * time.Second
+	}
+	return ttl
+}
@@ -0,0 +1,253 @@
+// Package watchdog guards control plane processes against self-inflicted
+// outages. It samples goroutine count, heap usage, and ticker lag; when a
+// threshold is exceeded it marks the process overloaded, writes diagnostics
+// dumps, and raises an alert so callers can shed load or skip work.
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds watchdog thresholds. A zero threshold disables that check.
+type Config struct {
+	Enabled       bool
+	Interval      time.Duration
+	MaxGoroutines int
+	MaxHeapBytes  uint64
+	MaxLag        time.Duration
+
+	// DumpDir receives goroutine and heap profiles when the process becomes
+	// overloaded, at most once per DumpCooldown. Empty disables dumps.
+	DumpDir      string
+	DumpCooldown time.Duration
+
+	// AlertWebhookURL receives a JSON Report on each transition into or out
+	// of the overloaded state. Empty only logs alerts.
+	AlertWebhookURL string
+}
+
+// DefaultConfig returns conservative thresholds.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:       false,
+		Interval:      5 * time.Second,
+		MaxGoroutines: 20000,
+		MaxHeapBytes:  1 << 30,
+		MaxLag:        2 * time.Second,
+		DumpCooldown:  5 * time.Minute,
+	}
+}
+
+// Report is a single watchdog sample.
+type Report struct {
+	Process    string    `json:"process"`
+	Time       time.Time `json:"time"`
+	Overloaded bool      `json:"overloaded"`
+	Goroutines int       `json:"goroutines"`
+	HeapBytes  uint64    `json:"heapBytes"`
+	LagMs      int64     `json:"lagMs"`
+	Reasons    []string  `json:"reasons,omitempty"`
+}
+
+// Watchdog samples runtime health on a ticker.
+type Watchdog struct {
+	process string
+	config  Config
+	logger  *slog.Logger
+	client  *http.Client
+
+	overloaded atomic.Bool
+
+	mu       sync.Mutex
+	last     Report
+	lastDump time.Time
+}
+
+// New creates a watchdog for the named process (e.g. "gateway").
+func New(process string, config Config, logger *slog.Logger) *Watchdog {
+	return &Watchdog{
+		process: process,
+		config:  config,
+		logger:  logger,
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Overloaded reports whether the last sample exceeded a threshold. A nil
+// watchdog is never overloaded.
+func (w *Watchdog) Overloaded() bool {
+	return w != nil && w.overloaded.Load()
+}
+
+// Last returns the most recent sample.
+func (w *Watchdog) Last() Report {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// Run samples every Interval until ctx is cancelled. It returns immediately
+// if the watchdog is disabled.
+func (w *Watchdog) Run(ctx context.Context) {
+	if !w.config.Enabled || w.config.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	expected := time.Now().Add(w.config.Interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			// Ticks delivered late mean the scheduler or GC is starving us.
+			lag := max(now.Sub(expected), time.Since(expected))
+			expected = now.Add(w.config.Interval)
+			w.check(ctx, max(lag, 0))
+		}
+	}
+}
+
+// check takes one sample and reacts to state transitions.
+func (w *Watchdog) check(ctx context.Context, lag time.Duration) Report {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	r := Report{
+		Process:    w.process,
+		Time:       time.Now().UTC(),
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  mem.HeapAlloc,
+		LagMs:      lag.Milliseconds(),
+	}
+	if w.config.MaxGoroutines > 0 && r.Goroutines > w.config.MaxGoroutines {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("goroutines %d > %d", r.Goroutines, w.config.MaxGoroutines))
+	}
+	if w.config.MaxHeapBytes > 0 && r.HeapBytes > w.config.MaxHeapBytes {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("heap %d bytes > %d", r.HeapBytes, w.config.MaxHeapBytes))
+	}
+	if w.config.MaxLag > 0 && lag > w.config.MaxLag {
+		r.Reasons = append(r.Reasons, fmt.Sprintf("ticker lag %s > %s", lag, w.config.MaxLag))
+	}
+	r.Overloaded = len(r.Reasons) > 0
+
+	w.mu.Lock()
+	w.last = r
+	w.mu.Unlock()
+
+	if was := w.overloaded.Swap(r.Overloaded); was == r.Overloaded {
+		return r
+	}
+
+	if r.Overloaded {
+		w.logger.Error("watchdog: process overloaded",
+			"process", w.process,
+			"reasons", strings.Join(r.Reasons, "; "),
+			"goroutines", r.Goroutines,
+			"heap_bytes", r.HeapBytes,
+			"lag_ms", r.LagMs,
+		)
+		w.dump(r.Time)
+	} else {
+		w.logger.Info("watchdog: process recovered", "process", w.process)
+	}
+	w.alert(ctx, r)
+	return r
+}
+
+// dump writes goroutine and heap profiles to DumpDir, rate limited by
+// DumpCooldown.
+func (w *Watchdog) dump(now time.Time) {
+	if w.config.DumpDir == "" {
+		return
+	}
+	w.mu.Lock()
+	if !w.lastDump.IsZero() && now.Sub(w.lastDump) < w.config.DumpCooldown {
+		w.mu.Unlock()
+		return
+	}
+	w.lastDump = now
+	w.mu.Unlock()
+
+	stamp := now.Format("20060102T150405Z")
+	for _, profile := range []struct {
+		name  string
+		debug int
+	}{{"goroutine", 2}, {"heap", 0}} {
+		path := filepath.Join(w.config.DumpDir, fmt.Sprintf("%s-%s-%s.pprof", w.process, profile.name, stamp))
+		if err := writeProfile(path, profile.name, profile.debug); err != nil {
+			w.logger.Warn("watchdog: diagnostics dump failed", "profile", profile.name, "error", err)
+			continue
+		}
+		w.logger.Info("watchdog: wrote diagnostics dump", "path", path)
+	}
+}
+
+func writeProfile(path, name string, debug int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create dump: %w", err)
+	}
+	defer f.Close()
+	if err := pprof.Lookup(name).WriteTo(f, debug); err != nil {
+		return fmt.Errorf("write %s profile: %w", name, err)
+	}
+	return nil
+}
+
+// alert posts the report to the configured webhook.
+func (w *Watchdog) alert(ctx context.Context, r Report) {
+	if w.config.AlertWebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		w.logger.Warn("watchdog: alert failed", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		w.logger.Warn("watchdog: alert failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// Middleware rejects requests with 503 while the process is overloaded.
+// Paths with one of the exempt prefixes (e.g. /health) are always served.
+func (w *Watchdog) Middleware(exempt []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if w.Overloaded() {
+			for _, p := range exempt {
+				if strings.HasPrefix(r.URL.Path, p) {
+					next.ServeHTTP(rw, r)
+					return
+				}
+			}
+			rw.Header().Set("Retry-After", "5")
+			http.Error(rw, w.process+" overloaded, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,93 @@
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchdog_CheckTransitionsAndAlerts(t *testing.T) {
+	alerts := make(chan Report, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rep Report
+		json.NewDecoder(r.Body).Decode(&rep)
+		alerts <- rep
+	}))
+	defer hook.Close()
+
+	dir := t.TempDir()
+	cfg := Config{
+		Enabled:         true,
+		MaxGoroutines:   1, // always exceeded
+		MaxLag:          time.Second,
+		DumpDir:         dir,
+		DumpCooldown:    time.Minute,
+		AlertWebhookURL: hook.URL,
+	}
+	w := New("test", cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r := w.check(context.Background(), 0)
+	if !r.Overloaded || !w.Overloaded() {
+		t.Fatalf("expected overloaded report, got %+v", r)
+	}
+	if rep := <-alerts; !rep.Overloaded || rep.Process != "test" {
+		t.Fatalf("unexpected alert %+v", rep)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("expected goroutine and heap dumps, got %d files", len(entries))
+	}
+
+	// Staying overloaded does not re-alert or re-dump.
+	w.check(context.Background(), 0)
+	if len(alerts) != 0 {
+		t.Fatal("expected no alert without a state change")
+	}
+
+	w.config.MaxGoroutines = 0
+	if r := w.check(context.Background(), 0); r.Overloaded || w.Overloaded() {
+		t.Fatalf("expected recovery, got %+v", r)
+	}
+	if rep := <-alerts; rep.Overloaded {
+		t.Fatalf("expected recovery alert, got %+v", rep)
+	}
+
+	if r := w.check(context.Background(), 2*time.Second); !r.Overloaded {
+		t.Fatalf("expected ticker lag to overload, got %+v", r)
+	}
+}
+
+func TestWatchdog_MiddlewareShedsExceptExempt(t *testing.T) {
+	w := New("gateway", Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.overloaded.Store(true)
+
+	handler := w.Middleware([]string{"/health"}, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/orders/1", http.StatusServiceUnavailable},
+		{"/health", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.path, tt.want, rec.Code)
+		}
+	}
+
+	var nilWatchdog *Watchdog
+	if nilWatchdog.Overloaded() {
+		t.Fatal("expected nil watchdog to never be overloaded")
+	}
+}
@@ -0,0 +1,43 @@
+package gatewayplugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"plugin"
+	"sort"
+)
+
+// RegisterSymbol is the function a Go plugin (.so) must export:
+//
+//	func Register(r *gatewayplugin.Registry) error
+const RegisterSymbol = "Register"
+
+// LoadDir opens every *.so file in dir and calls its Register function. Go
+// plugins need a cgo-enabled gateway and must be built with the same
+// toolchain and module versions as the gateway binary.
+func (r *Registry) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return fmt.Errorf("list plugins in %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return fmt.Errorf("open plugin %s: %w", path, err)
+		}
+		sym, err := p.Lookup(RegisterSymbol)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+		register, ok := sym.(func(*Registry) error)
+		if !ok {
+			return fmt.Errorf("plugin %s: %s has type %T, want func(*gatewayplugin.Registry) error", path, RegisterSymbol, sym)
+		}
+		if err := register(r); err != nil {
+			return fmt.Errorf("plugin %s: %w", path, err)
+		}
+	}
+	return nil
+}
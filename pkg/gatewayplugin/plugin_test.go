@@ -0,0 +1,80 @@
+package gatewayplugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func headerPlugin(name string, stage Stage) Plugin {
+	return Plugin{
+		Name:  name,
+		Stage: stage,
+		New: func(config map[string]string) (Middleware, error) {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("X-Trace", name+config["suffix"])
+					next.ServeHTTP(w, r)
+				})
+			}, nil
+		},
+	}
+}
+
+func TestRegistry_BuildAndWrapInOrder(t *testing.T) {
+	reg := NewRegistry()
+	for _, p := range []Plugin{
+		headerPlugin("a", StagePreAuth),
+		headerPlugin("b", StagePreAuth),
+		headerPlugin("c", StagePostAuth),
+	} {
+		if err := reg.Register(p); err != nil {
+			t.Fatalf("register %s: %v", p.Name, err)
+		}
+	}
+
+	chain, err := reg.Build([]string{"b", "c", "a"}, map[string]map[string]string{"b": {"suffix": "!"}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := chain.Wrap(StagePreAuth, chain.Wrap(StagePostAuth, final))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if got := strings.Join(w.Header().Values("X-Trace"), ","); got != "b!,a,c" {
+		t.Fatalf("expected pre-auth plugins in enabled order then post-auth, got %q", got)
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(headerPlugin("a", StagePreAuth)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"duplicate name", func() error { return reg.Register(headerPlugin("a", StagePostAuth)) }},
+		{"missing factory", func() error { return reg.Register(Plugin{Name: "x"}) }},
+		{"unknown plugin", func() error { _, err := reg.Build([]string{"missing"}, nil); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestRegistry_LoadDirWithoutPlugins(t *testing.T) {
+	if err := NewRegistry().LoadDir(t.TempDir()); err != nil {
+		t.Fatalf("expected empty dir to load cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package healthprobe is the extension point for custom HealthMonitor probes.
+// Deployments register probers — such as a Kafka consumer-lag check — from a
+// package blank-imported into their HealthMonitor build, and the worker tries
+// them before its built-in HTTP, TLS, exec, UDP and TCP probes, so new checks
+// can be added without forking the monitor.
+package healthprobe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Status is the health reported by a probe.
+type Status int
+
+const (
+	Unknown Status = iota
+	Healthy
+	Unhealthy
+	Degraded
+)
+
+func (s Status) String() string {
+	switch s {
+	case Healthy:
+		return "Healthy"
+	case Unhealthy:
+		return "Unhealthy"
+	case Degraded:
+		return "Degraded"
+	default:
+		return "Unknown"
+	}
+}
+
+// Instance is the service instance being probed.
+type Instance struct {
+	ServiceName string
+	ServiceID   string
+	Address     string
+	Port        int
+	Metadata    map[string]string
+}
+
+// Prober checks the health of an instance.
+type Prober interface {
+	// Name is reported as the probe type of the results.
+	Name() string
+	// Probe checks the instance. ok is false when the instance is not
+	// configured for this probe, so the next prober is tried.
+	Probe(ctx context.Context, inst Instance) (status Status, message string, ok bool)
+}
+
+// Func probes an instance as Prober.Probe does.
+type Func func(ctx context.Context, inst Instance) (status Status, message string, ok bool)
+
+// New returns a Prober named name that runs probe.
+func New(name string, probe Func) Prober {
+	return funcProber{name: name, probe: probe}
+}
+
+type funcProber struct {
+	name  string
+	probe Func
+}
+
+func (p funcProber) Name() string { return p.name }
+
+func (p funcProber) Probe(ctx context.Context, inst Instance) (Status, string, bool) {
+	return p.probe(ctx, inst)
+}
+
+// Registry holds probers in the order they are tried.
+type Registry struct {
+	mu      sync.Mutex
+	probers []Prober
+}
+
+// NewRegistry creates an empty prober registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a prober after those already registered. Names must be
+// unique.
+func (r *Registry) Register(p Prober) error {
+	if p == nil || p.Name() == "" {
+		return fmt.Errorf("prober must have a name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.probers {
+		if existing.Name() == p.Name() {
+			return fmt.Errorf("prober %q already registered", p.Name())
+		}
+	}
+	r.probers = append(r.probers, p)
+	return nil
+}
+
+// Probers returns the registered probers in registration order.
+func (r *Registry) Probers() []Prober {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Prober(nil), r.probers...)
+}
+
+// Default is the registry the HealthMonitor binary reads its custom probers
+// from.
+var Default = NewRegistry()
+
+// Register adds a prober to Default. It is meant to be called from an init
+// function and panics if the prober is unnamed or its name is taken, like
+// database/sql.Register.
+func Register(p Prober) {
+	if err := Default.Register(p); err != nil {
+		panic("healthprobe: " + err.Error())
+	}
+}
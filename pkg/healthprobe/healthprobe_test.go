@@ -0,0 +1,43 @@
+package healthprobe
+
+import (
+	"context"
+	"testing"
+)
+
+func named(name string) Prober {
+	return New(name, func(ctx context.Context, inst Instance) (Status, string, bool) {
+		return Healthy, "", true
+	})
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(named("kafka-lag")); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := r.Register(named("redis-ping")); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		p    Prober
+	}{
+		{"duplicate name", named("kafka-lag")},
+		{"empty name", named("")},
+		{"nil prober", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.p); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	probers := r.Probers()
+	if len(probers) != 2 || probers[0].Name() != "kafka-lag" || probers[1].Name() != "redis-ping" {
+		t.Fatalf("expected probers in registration order, got %v", probers)
+	}
+}
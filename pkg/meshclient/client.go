@@ -0,0 +1,91 @@
+// Package meshclient provides a Go client for the ToskaMesh DiscoveryRegistry
+// gRPC service with transport defaults suited to large meshes.
+package meshclient
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// DefaultMaxMsgSize matches the discovery server default (16MB).
+const DefaultMaxMsgSize = 16 << 20
+
+// Options controls how the client connects to the discovery server.
+type Options struct {
+	// Compression enables gzip compression for every RPC.
+	Compression bool
+
+	// MaxRecvMsgSize and MaxSendMsgSize bound per-message sizes in bytes.
+	// Non-positive values leave the gRPC defaults (4MB receive) in place.
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+
+	// Credentials secures the connection. Nil means plaintext.
+	Credentials credentials.TransportCredentials
+
+	// DialOptions are appended after the options derived from the fields above.
+	DialOptions []grpc.DialOption
+}
+
+// DefaultOptions returns options with gzip enabled and 16MB message limits.
+func DefaultOptions() Options {
+	return Options{
+		Compression:    true,
+		MaxRecvMsgSize: DefaultMaxMsgSize,
+		MaxSendMsgSize: DefaultMaxMsgSize,
+	}
+}
+
+// Client is a DiscoveryRegistry client bound to a single connection.
+type Client struct {
+	pb.DiscoveryRegistryClient
+	conn *grpc.ClientConn
+}
+
+// New creates a client for the discovery server at target (e.g. "localhost:8080").
+// The connection is established lazily on the first RPC.
+func New(target string, opts Options) (*Client, error) {
+	conn, err := grpc.NewClient(target, opts.dialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("discovery client: %w", err)
+	}
+	return &Client{
+		DiscoveryRegistryClient: pb.NewDiscoveryRegistryClient(conn),
+		conn:                    conn,
+	}, nil
+}
+
+// Close tears down the underlying connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (o Options) dialOptions() []grpc.DialOption {
+	creds := o.Credentials
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+
+	var callOpts []grpc.CallOption
+	if o.Compression {
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	}
+	if o.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	if o.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(o.MaxSendMsgSize))
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if len(callOpts) > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	return append(dialOpts, o.DialOptions...)
+}
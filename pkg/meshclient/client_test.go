@@ -0,0 +1,72 @@
+package meshclient
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/toska-mesh/toska-mesh/pkg/meshpb"
+)
+
+// largeCatalogServer returns a GetServices response larger than the 4MB gRPC default.
+type largeCatalogServer struct {
+	pb.UnimplementedDiscoveryRegistryServer
+}
+
+func (largeCatalogServer) GetServices(context.Context, *pb.GetServicesRequest) (*pb.GetServicesResponse, error) {
+	names := make([]string, 6000)
+	for i := range names {
+		names[i] = strings.Repeat("x", 1000)
+	}
+	return &pb.GetServicesResponse{ServiceNames: names}, nil
+}
+
+func startServer(t *testing.T) *bufconn.Listener {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterDiscoveryRegistryServer(srv, largeCatalogServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+func dial(t *testing.T, lis *bufconn.Listener, opts Options) *Client {
+	t.Helper()
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	c, err := New("passthrough:///bufnet", opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClient_DefaultOptionsReceiveLargeCatalog(t *testing.T) {
+	c := dial(t, startServer(t), DefaultOptions())
+
+	resp, err := c.GetServices(context.Background(), &pb.GetServicesRequest{})
+	if err != nil {
+		t.Fatalf("GetServices: %v", err)
+	}
+	if len(resp.ServiceNames) != 6000 {
+		t.Fatalf("expected 6000 services, got %d", len(resp.ServiceNames))
+	}
+}
+
+func TestClient_SmallLimitRejectsLargeCatalog(t *testing.T) {
+	c := dial(t, startServer(t), Options{MaxRecvMsgSize: 1 << 20})
+
+	_, err := c.GetServices(context.Background(), &pb.GetServicesRequest{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ package meshpb
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -143,13 +144,20 @@ func (x *HealthCheckConfig) GetUnhealthyThreshold() int32 {
 }
 
 type RegisterServiceRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServiceName   string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
-	ServiceId     string                 `protobuf:"bytes,2,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
-	Address       string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
-	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
-	Metadata      map[string]string      `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	HealthCheck   *HealthCheckConfig     `protobuf:"bytes,6,opt,name=healthCheck,proto3" json:"healthCheck,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	ServiceId   string                 `protobuf:"bytes,2,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	Address     string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Port        int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Metadata    map[string]string      `protobuf:"bytes,5,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	HealthCheck *HealthCheckConfig     `protobuf:"bytes,6,opt,name=healthCheck,proto3" json:"healthCheck,omitempty"`
+	// autoRenew asks discovery to keep the TTL check current from the
+	// HealthMonitor's probe results, for services that cannot run a heartbeat
+	// loop. The HealthMonitor must be able to probe the instance: set
+	// healthCheck.endpoint or the health_check_endpoint/tcp_port metadata.
+	// Honoured only for services the operator lists in
+	// DISCOVERY_AUTO_RENEW_SERVICES.
+	AutoRenew     bool `protobuf:"varint,7,opt,name=autoRenew,proto3" json:"autoRenew,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -226,6 +234,13 @@ func (x *RegisterServiceRequest) GetHealthCheck() *HealthCheckConfig {
 	return nil
 }
 
+func (x *RegisterServiceRequest) GetAutoRenew() bool {
+	if x != nil {
+		return x.AutoRenew
+	}
+	return false
+}
+
 type RegisterServiceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -375,8 +390,22 @@ func (x *DeregisterServiceResponse) GetRemoved() bool {
 }
 
 type GetInstancesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServiceName   string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	// Metadata selectors, all of which must match: "key=value", "key!=value",
+	// "key" (present) or "!key" (absent). A value may end in a wildcard
+	// segment, "*" or an "x" after a version prefix, so "version=2.x" matches
+	// 2, 2.1 and 2.1.3. A plain "x" matches literally.
+	Selectors []string `protobuf:"bytes,2,rep,name=selectors,proto3" json:"selectors,omitempty"`
+	// healthyOnly omits instances whose health check is not passing.
+	HealthyOnly bool `protobuf:"varint,3,opt,name=healthyOnly,proto3" json:"healthyOnly,omitempty"`
+	// pageSize limits instances per response; 0 or more than 1000 means 1000.
+	PageSize int32 `protobuf:"varint,4,opt,name=pageSize,proto3" json:"pageSize,omitempty"`
+	// pageToken is the nextPageToken of the previous response.
+	PageToken string `protobuf:"bytes,5,opt,name=pageToken,proto3" json:"pageToken,omitempty"`
+	// fieldMask selects the ServiceInstance fields to return, e.g.
+	// "serviceId,address,port"; empty returns every field.
+	FieldMask     *fieldmaskpb.FieldMask `protobuf:"bytes,6,opt,name=fieldMask,proto3" json:"fieldMask,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -418,9 +447,46 @@ func (x *GetInstancesRequest) GetServiceName() string {
 	return ""
 }
 
+func (x *GetInstancesRequest) GetSelectors() []string {
+	if x != nil {
+		return x.Selectors
+	}
+	return nil
+}
+
+func (x *GetInstancesRequest) GetHealthyOnly() bool {
+	if x != nil {
+		return x.HealthyOnly
+	}
+	return false
+}
+
+func (x *GetInstancesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetInstancesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *GetInstancesRequest) GetFieldMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.FieldMask
+	}
+	return nil
+}
+
 type GetInstancesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Instances     []*ServiceInstance     `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Instances []*ServiceInstance     `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	// nextPageToken fetches the next page; empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=nextPageToken,proto3" json:"nextPageToken,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -462,6 +528,13 @@ func (x *GetInstancesResponse) GetInstances() []*ServiceInstance {
 	return nil
 }
 
+func (x *GetInstancesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 type ServiceInstance struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	ServiceName     string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
@@ -562,15 +635,110 @@ func (x *ServiceInstance) GetLastHealthCheck() *timestamppb.Timestamp {
 	return nil
 }
 
-type GetServicesRequest struct {
+// GetInstanceRequest looks up one instance by ID, for instance detail views.
+type GetInstanceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInstanceRequest) Reset() {
+	*x = GetInstanceRequest{}
+	mi := &file_discovery_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInstanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInstanceRequest) ProtoMessage() {}
+
+func (x *GetInstanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInstanceRequest.ProtoReflect.Descriptor instead.
+func (*GetInstanceRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetInstanceRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+type GetInstanceResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The catalog entry with its current health-check status, tracked metadata
+	// merged in, and tracked registration and health-check times.
+	Instance      *ServiceInstance `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInstanceResponse) Reset() {
+	*x = GetInstanceResponse{}
+	mi := &file_discovery_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInstanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInstanceResponse) ProtoMessage() {}
+
+func (x *GetInstanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInstanceResponse.ProtoReflect.Descriptor instead.
+func (*GetInstanceResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetInstanceResponse) GetInstance() *ServiceInstance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+type GetServicesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// pageSize limits names per response; 0 or more than 1000 means 1000.
+	PageSize int32 `protobuf:"varint,1,opt,name=pageSize,proto3" json:"pageSize,omitempty"`
+	// pageToken is the nextPageToken of the previous response.
+	PageToken     string `protobuf:"bytes,2,opt,name=pageToken,proto3" json:"pageToken,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetServicesRequest) Reset() {
 	*x = GetServicesRequest{}
-	mi := &file_discovery_proto_msgTypes[8]
+	mi := &file_discovery_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -582,7 +750,7 @@ func (x *GetServicesRequest) String() string {
 func (*GetServicesRequest) ProtoMessage() {}
 
 func (x *GetServicesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_discovery_proto_msgTypes[8]
+	mi := &file_discovery_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -595,19 +763,35 @@ func (x *GetServicesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetServicesRequest.ProtoReflect.Descriptor instead.
 func (*GetServicesRequest) Descriptor() ([]byte, []int) {
-	return file_discovery_proto_rawDescGZIP(), []int{8}
+	return file_discovery_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetServicesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *GetServicesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
 }
 
 type GetServicesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ServiceNames  []string               `protobuf:"bytes,1,rep,name=serviceNames,proto3" json:"serviceNames,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	ServiceNames []string               `protobuf:"bytes,1,rep,name=serviceNames,proto3" json:"serviceNames,omitempty"`
+	// nextPageToken fetches the next page; empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=nextPageToken,proto3" json:"nextPageToken,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetServicesResponse) Reset() {
 	*x = GetServicesResponse{}
-	mi := &file_discovery_proto_msgTypes[9]
+	mi := &file_discovery_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -619,7 +803,7 @@ func (x *GetServicesResponse) String() string {
 func (*GetServicesResponse) ProtoMessage() {}
 
 func (x *GetServicesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_discovery_proto_msgTypes[9]
+	mi := &file_discovery_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -632,7 +816,7 @@ func (x *GetServicesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetServicesResponse.ProtoReflect.Descriptor instead.
 func (*GetServicesResponse) Descriptor() ([]byte, []int) {
-	return file_discovery_proto_rawDescGZIP(), []int{9}
+	return file_discovery_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *GetServicesResponse) GetServiceNames() []string {
@@ -642,6 +826,13 @@ func (x *GetServicesResponse) GetServiceNames() []string {
 	return nil
 }
 
+func (x *GetServicesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
 type ReportHealthRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
@@ -653,7 +844,7 @@ type ReportHealthRequest struct {
 
 func (x *ReportHealthRequest) Reset() {
 	*x = ReportHealthRequest{}
-	mi := &file_discovery_proto_msgTypes[10]
+	mi := &file_discovery_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -665,7 +856,7 @@ func (x *ReportHealthRequest) String() string {
 func (*ReportHealthRequest) ProtoMessage() {}
 
 func (x *ReportHealthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_discovery_proto_msgTypes[10]
+	mi := &file_discovery_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -678,7 +869,7 @@ func (x *ReportHealthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportHealthRequest.ProtoReflect.Descriptor instead.
 func (*ReportHealthRequest) Descriptor() ([]byte, []int) {
-	return file_discovery_proto_rawDescGZIP(), []int{10}
+	return file_discovery_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ReportHealthRequest) GetServiceId() string {
@@ -711,7 +902,7 @@ type ReportHealthResponse struct {
 
 func (x *ReportHealthResponse) Reset() {
 	*x = ReportHealthResponse{}
-	mi := &file_discovery_proto_msgTypes[11]
+	mi := &file_discovery_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -723,7 +914,7 @@ func (x *ReportHealthResponse) String() string {
 func (*ReportHealthResponse) ProtoMessage() {}
 
 func (x *ReportHealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_discovery_proto_msgTypes[11]
+	mi := &file_discovery_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -736,7 +927,7 @@ func (x *ReportHealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportHealthResponse.ProtoReflect.Descriptor instead.
 func (*ReportHealthResponse) Descriptor() ([]byte, []int) {
-	return file_discovery_proto_rawDescGZIP(), []int{11}
+	return file_discovery_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ReportHealthResponse) GetSuccess() bool {
@@ -746,71 +937,1426 @@ func (x *ReportHealthResponse) GetSuccess() bool {
 	return false
 }
 
-var File_discovery_proto protoreflect.FileDescriptor
+// UpdateMetadataRequest changes an instance's metadata without
+// re-registering it. By default metadata is merged into the existing keys.
+type UpdateMetadataRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	Metadata  map[string]string      `protobuf:"bytes,2,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// replace discards existing keys instead of merging.
+	Replace bool `protobuf:"varint,3,opt,name=replace,proto3" json:"replace,omitempty"`
+	// removeKeys are deleted after metadata is applied.
+	RemoveKeys    []string `protobuf:"bytes,4,rep,name=removeKeys,proto3" json:"removeKeys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_discovery_proto_rawDesc = "" +
-	"\n" +
-	"\x0fdiscovery.proto\x12\x13toskamesh.discovery\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb1\x01\n" +
-	"\x11HealthCheckConfig\x12\x1a\n" +
-	"\bendpoint\x18\x01 \x01(\tR\bendpoint\x12(\n" +
-	"\x0fintervalSeconds\x18\x02 \x01(\x05R\x0fintervalSeconds\x12&\n" +
-	"\x0etimeoutSeconds\x18\x03 \x01(\x05R\x0etimeoutSeconds\x12.\n" +
-	"\x12unhealthyThreshold\x18\x04 \x01(\x05R\x12unhealthyThreshold\"\xe4\x02\n" +
-	"\x16RegisterServiceRequest\x12 \n" +
-	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x1c\n" +
-	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\x18\n" +
-	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x12\n" +
-	"\x04port\x18\x04 \x01(\x05R\x04port\x12U\n" +
-	"\bmetadata\x18\x05 \x03(\v29.toskamesh.discovery.RegisterServiceRequest.MetadataEntryR\bmetadata\x12H\n" +
-	"\vhealthCheck\x18\x06 \x01(\v2&.toskamesh.discovery.HealthCheckConfigR\vhealthCheck\x1a;\n" +
-	"\rMetadataEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"u\n" +
-	"\x17RegisterServiceResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1c\n" +
-	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\"\n" +
-	"\ferrorMessage\x18\x03 \x01(\tR\ferrorMessage\"8\n" +
-	"\x18DeregisterServiceRequest\x12\x1c\n" +
-	"\tserviceId\x18\x01 \x01(\tR\tserviceId\"5\n" +
-	"\x19DeregisterServiceResponse\x12\x18\n" +
-	"\aremoved\x18\x01 \x01(\bR\aremoved\"7\n" +
-	"\x13GetInstancesRequest\x12 \n" +
-	"\vserviceName\x18\x01 \x01(\tR\vserviceName\"Z\n" +
-	"\x14GetInstancesResponse\x12B\n" +
-	"\tinstances\x18\x01 \x03(\v2$.toskamesh.discovery.ServiceInstanceR\tinstances\"\xcd\x03\n" +
-	"\x0fServiceInstance\x12 \n" +
-	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x1c\n" +
-	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\x18\n" +
-	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x12\n" +
-	"\x04port\x18\x04 \x01(\x05R\x04port\x129\n" +
-	"\x06status\x18\x05 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12N\n" +
-	"\bmetadata\x18\x06 \x03(\v22.toskamesh.discovery.ServiceInstance.MetadataEntryR\bmetadata\x12>\n" +
-	"\fregisteredAt\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\fregisteredAt\x12D\n" +
-	"\x0flastHealthCheck\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x0flastHealthCheck\x1a;\n" +
-	"\rMetadataEntry\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x14\n" +
-	"\x12GetServicesRequest\"9\n" +
-	"\x13GetServicesResponse\x12\"\n" +
-	"\fserviceNames\x18\x01 \x03(\tR\fserviceNames\"\x86\x01\n" +
-	"\x13ReportHealthRequest\x12\x1c\n" +
-	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x129\n" +
-	"\x06status\x18\x02 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12\x16\n" +
-	"\x06output\x18\x03 \x01(\tR\x06output\"0\n" +
-	"\x14ReportHealthResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess*}\n" +
+func (x *UpdateMetadataRequest) Reset() {
+	*x = UpdateMetadataRequest{}
+	mi := &file_discovery_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetadataRequest) ProtoMessage() {}
+
+func (x *UpdateMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetadataRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdateMetadataRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *UpdateMetadataRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *UpdateMetadataRequest) GetReplace() bool {
+	if x != nil {
+		return x.Replace
+	}
+	return false
+}
+
+func (x *UpdateMetadataRequest) GetRemoveKeys() []string {
+	if x != nil {
+		return x.RemoveKeys
+	}
+	return nil
+}
+
+type UpdateMetadataResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// The instance's metadata after the update.
+	Metadata      map[string]string `protobuf:"bytes,2,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ErrorMessage  string            `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetadataResponse) Reset() {
+	*x = UpdateMetadataResponse{}
+	mi := &file_discovery_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetadataResponse) ProtoMessage() {}
+
+func (x *UpdateMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetadataResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UpdateMetadataResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UpdateMetadataResponse) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *UpdateMetadataResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+// RegisterBatchRequest registers several instances all-or-nothing: if any
+// registration fails, the ones that succeeded are rolled back.
+type RegisterBatchRequest struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Services      []*RegisterServiceRequest `protobuf:"bytes,1,rep,name=services,proto3" json:"services,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterBatchRequest) Reset() {
+	*x = RegisterBatchRequest{}
+	mi := &file_discovery_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBatchRequest) ProtoMessage() {}
+
+func (x *RegisterBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBatchRequest.ProtoReflect.Descriptor instead.
+func (*RegisterBatchRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RegisterBatchRequest) GetServices() []*RegisterServiceRequest {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+type RegisterBatchResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// One result per request, in request order.
+	Results       []*RegisterServiceResponse `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterBatchResponse) Reset() {
+	*x = RegisterBatchResponse{}
+	mi := &file_discovery_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterBatchResponse) ProtoMessage() {}
+
+func (x *RegisterBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterBatchResponse.ProtoReflect.Descriptor instead.
+func (*RegisterBatchResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RegisterBatchResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterBatchResponse) GetResults() []*RegisterServiceResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// DeregisterBatchRequest deregisters several instances. Each is attempted
+// independently; failures do not stop the rest.
+type DeregisterBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceIds    []string               `protobuf:"bytes,1,rep,name=serviceIds,proto3" json:"serviceIds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterBatchRequest) Reset() {
+	*x = DeregisterBatchRequest{}
+	mi := &file_discovery_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterBatchRequest) ProtoMessage() {}
+
+func (x *DeregisterBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterBatchRequest.ProtoReflect.Descriptor instead.
+func (*DeregisterBatchRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeregisterBatchRequest) GetServiceIds() []string {
+	if x != nil {
+		return x.ServiceIds
+	}
+	return nil
+}
+
+type DeregisterResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	Removed       bool                   `protobuf:"varint,2,opt,name=removed,proto3" json:"removed,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterResult) Reset() {
+	*x = DeregisterResult{}
+	mi := &file_discovery_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterResult) ProtoMessage() {}
+
+func (x *DeregisterResult) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterResult.ProtoReflect.Descriptor instead.
+func (*DeregisterResult) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DeregisterResult) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *DeregisterResult) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *DeregisterResult) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+type DeregisterBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*DeregisterResult    `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterBatchResponse) Reset() {
+	*x = DeregisterBatchResponse{}
+	mi := &file_discovery_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterBatchResponse) ProtoMessage() {}
+
+func (x *DeregisterBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterBatchResponse.ProtoReflect.Descriptor instead.
+func (*DeregisterBatchResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *DeregisterBatchResponse) GetResults() []*DeregisterResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// DeregisterByServiceNameRequest removes every registered instance of a
+// service, for decommissioning one whose instance IDs are unknown. Each
+// instance is attempted independently; failures do not stop the rest.
+type DeregisterByServiceNameRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	// Recorded on the published event; defaults to "Service decommissioned".
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterByServiceNameRequest) Reset() {
+	*x = DeregisterByServiceNameRequest{}
+	mi := &file_discovery_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterByServiceNameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterByServiceNameRequest) ProtoMessage() {}
+
+func (x *DeregisterByServiceNameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterByServiceNameRequest.ProtoReflect.Descriptor instead.
+func (*DeregisterByServiceNameRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeregisterByServiceNameRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *DeregisterByServiceNameRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type DeregisterByServiceNameResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One result per instance found, in registry order.
+	Results       []*DeregisterResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeregisterByServiceNameResponse) Reset() {
+	*x = DeregisterByServiceNameResponse{}
+	mi := &file_discovery_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeregisterByServiceNameResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeregisterByServiceNameResponse) ProtoMessage() {}
+
+func (x *DeregisterByServiceNameResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeregisterByServiceNameResponse.ProtoReflect.Descriptor instead.
+func (*DeregisterByServiceNameResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DeregisterByServiceNameResponse) GetResults() []*DeregisterResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// RestoreServiceRequest re-registers an instance deregistered within the
+// tombstone grace period.
+type RestoreServiceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreServiceRequest) Reset() {
+	*x = RestoreServiceRequest{}
+	mi := &file_discovery_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreServiceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreServiceRequest) ProtoMessage() {}
+
+func (x *RestoreServiceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreServiceRequest.ProtoReflect.Descriptor instead.
+func (*RestoreServiceRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RestoreServiceRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+type RestoreServiceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *ServiceInstance       `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreServiceResponse) Reset() {
+	*x = RestoreServiceResponse{}
+	mi := &file_discovery_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreServiceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreServiceResponse) ProtoMessage() {}
+
+func (x *RestoreServiceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreServiceResponse.ProtoReflect.Descriptor instead.
+func (*RestoreServiceResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RestoreServiceResponse) GetInstance() *ServiceInstance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+// ListTrackingRequest lists the server's in-memory tracking table, for
+// debugging. Tracking is per replica: each one only knows the calls it served.
+type ListTrackingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Limits the listing to one service; empty lists every entry.
+	ServiceName   string `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrackingRequest) Reset() {
+	*x = ListTrackingRequest{}
+	mi := &file_discovery_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrackingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrackingRequest) ProtoMessage() {}
+
+func (x *ListTrackingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrackingRequest.ProtoReflect.Descriptor instead.
+func (*ListTrackingRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListTrackingRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+type TrackedInstance struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId    string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	ServiceName  string                 `protobuf:"bytes,2,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	RegisteredAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=registeredAt,proto3" json:"registeredAt,omitempty"`
+	// Unset while the instance is registered.
+	DeregisteredAt  *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=deregisteredAt,proto3" json:"deregisteredAt,omitempty"`
+	LastUpdated     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=lastUpdated,proto3" json:"lastUpdated,omitempty"`
+	Status          HealthStatus           `protobuf:"varint,6,opt,name=status,proto3,enum=toskamesh.discovery.HealthStatus" json:"status,omitempty"`
+	LastHealthCheck *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=lastHealthCheck,proto3" json:"lastHealthCheck,omitempty"`
+	Metadata        map[string]string      `protobuf:"bytes,8,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TrackedInstance) Reset() {
+	*x = TrackedInstance{}
+	mi := &file_discovery_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrackedInstance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrackedInstance) ProtoMessage() {}
+
+func (x *TrackedInstance) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrackedInstance.ProtoReflect.Descriptor instead.
+func (*TrackedInstance) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TrackedInstance) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *TrackedInstance) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *TrackedInstance) GetRegisteredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RegisteredAt
+	}
+	return nil
+}
+
+func (x *TrackedInstance) GetDeregisteredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeregisteredAt
+	}
+	return nil
+}
+
+func (x *TrackedInstance) GetLastUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return nil
+}
+
+func (x *TrackedInstance) GetStatus() HealthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return HealthStatus_HEALTH_STATUS_UNKNOWN
+}
+
+func (x *TrackedInstance) GetLastHealthCheck() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastHealthCheck
+	}
+	return nil
+}
+
+func (x *TrackedInstance) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type ListTrackingResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sorted by service name, then service ID.
+	Instances     []*TrackedInstance `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrackingResponse) Reset() {
+	*x = ListTrackingResponse{}
+	mi := &file_discovery_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrackingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrackingResponse) ProtoMessage() {}
+
+func (x *ListTrackingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrackingResponse.ProtoReflect.Descriptor instead.
+func (*ListTrackingResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListTrackingResponse) GetInstances() []*TrackedInstance {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
+}
+
+// ExpireInstanceRequest deregisters an instance as if its health check had
+// expired, publishing a ServiceDeregisteredEvent.
+type ExpireInstanceRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	// Recorded on the published event; defaults to "Expired by operator".
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpireInstanceRequest) Reset() {
+	*x = ExpireInstanceRequest{}
+	mi := &file_discovery_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpireInstanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpireInstanceRequest) ProtoMessage() {}
+
+func (x *ExpireInstanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpireInstanceRequest.ProtoReflect.Descriptor instead.
+func (*ExpireInstanceRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ExpireInstanceRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *ExpireInstanceRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ExpireInstanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       bool                   `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpireInstanceResponse) Reset() {
+	*x = ExpireInstanceResponse{}
+	mi := &file_discovery_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpireInstanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpireInstanceResponse) ProtoMessage() {}
+
+func (x *ExpireInstanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpireInstanceResponse.ProtoReflect.Descriptor instead.
+func (*ExpireInstanceResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ExpireInstanceResponse) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+// RebuildTrackingRequest resynchronises the tracking table with the registry:
+// live instances missing from it are added and entries for instances no
+// longer registered are dropped. Existing entries keep their timestamps.
+type RebuildTrackingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebuildTrackingRequest) Reset() {
+	*x = RebuildTrackingRequest{}
+	mi := &file_discovery_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebuildTrackingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebuildTrackingRequest) ProtoMessage() {}
+
+func (x *RebuildTrackingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebuildTrackingRequest.ProtoReflect.Descriptor instead.
+func (*RebuildTrackingRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{30}
+}
+
+type RebuildTrackingResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Added   int32                  `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	Removed int32                  `protobuf:"varint,2,opt,name=removed,proto3" json:"removed,omitempty"`
+	// Entries in the table after the rebuild.
+	Total         int32 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebuildTrackingResponse) Reset() {
+	*x = RebuildTrackingResponse{}
+	mi := &file_discovery_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebuildTrackingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebuildTrackingResponse) ProtoMessage() {}
+
+func (x *RebuildTrackingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebuildTrackingResponse.ProtoReflect.Descriptor instead.
+func (*RebuildTrackingResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RebuildTrackingResponse) GetAdded() int32 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *RebuildTrackingResponse) GetRemoved() int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+func (x *RebuildTrackingResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// GetServiceHistoryRequest returns the recent registration history of a
+// service as seen by this replica, newest last.
+type GetServiceHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName   string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServiceHistoryRequest) Reset() {
+	*x = GetServiceHistoryRequest{}
+	mi := &file_discovery_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServiceHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServiceHistoryRequest) ProtoMessage() {}
+
+func (x *GetServiceHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServiceHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetServiceHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *GetServiceHistoryRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+type HistoryEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ServiceId string                 `protobuf:"bytes,2,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	// registered, deregistered, health_changed, metadata_changed, or conflict.
+	Action        string `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	Detail        string `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_discovery_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *HistoryEntry) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type GetServiceHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetServiceHistoryResponse) Reset() {
+	*x = GetServiceHistoryResponse{}
+	mi := &file_discovery_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetServiceHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetServiceHistoryResponse) ProtoMessage() {}
+
+func (x *GetServiceHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetServiceHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetServiceHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *GetServiceHistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// HeartbeatRequest is one ping on a Heartbeat stream. Each ping renews the
+// instance's Consul TTL check; status defaults to healthy when unset.
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	Status        HealthStatus           `protobuf:"varint,2,opt,name=status,proto3,enum=toskamesh.discovery.HealthStatus" json:"status,omitempty"`
+	Output        string                 `protobuf:"bytes,3,opt,name=output,proto3" json:"output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_discovery_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *HeartbeatRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetStatus() HealthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return HealthStatus_HEALTH_STATUS_UNKNOWN
+}
+
+func (x *HeartbeatRequest) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	Acknowledged  bool                   `protobuf:"varint,2,opt,name=acknowledged,proto3" json:"acknowledged,omitempty"`
+	ErrorMessage  string                 `protobuf:"bytes,3,opt,name=errorMessage,proto3" json:"errorMessage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_discovery_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_discovery_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_discovery_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *HeartbeatResponse) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *HeartbeatResponse) GetAcknowledged() bool {
+	if x != nil {
+		return x.Acknowledged
+	}
+	return false
+}
+
+func (x *HeartbeatResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+var File_discovery_proto protoreflect.FileDescriptor
+
+const file_discovery_proto_rawDesc = "" +
+	"\n" +
+	"\x0fdiscovery.proto\x12\x13toskamesh.discovery\x1a google/protobuf/field_mask.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb1\x01\n" +
+	"\x11HealthCheckConfig\x12\x1a\n" +
+	"\bendpoint\x18\x01 \x01(\tR\bendpoint\x12(\n" +
+	"\x0fintervalSeconds\x18\x02 \x01(\x05R\x0fintervalSeconds\x12&\n" +
+	"\x0etimeoutSeconds\x18\x03 \x01(\x05R\x0etimeoutSeconds\x12.\n" +
+	"\x12unhealthyThreshold\x18\x04 \x01(\x05R\x12unhealthyThreshold\"\x82\x03\n" +
+	"\x16RegisterServiceRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x1c\n" +
+	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12U\n" +
+	"\bmetadata\x18\x05 \x03(\v29.toskamesh.discovery.RegisterServiceRequest.MetadataEntryR\bmetadata\x12H\n" +
+	"\vhealthCheck\x18\x06 \x01(\v2&.toskamesh.discovery.HealthCheckConfigR\vhealthCheck\x12\x1c\n" +
+	"\tautoRenew\x18\a \x01(\bR\tautoRenew\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"u\n" +
+	"\x17RegisterServiceResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1c\n" +
+	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\"\n" +
+	"\ferrorMessage\x18\x03 \x01(\tR\ferrorMessage\"8\n" +
+	"\x18DeregisterServiceRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\"5\n" +
+	"\x19DeregisterServiceResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\bR\aremoved\"\xeb\x01\n" +
+	"\x13GetInstancesRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x1c\n" +
+	"\tselectors\x18\x02 \x03(\tR\tselectors\x12 \n" +
+	"\vhealthyOnly\x18\x03 \x01(\bR\vhealthyOnly\x12\x1a\n" +
+	"\bpageSize\x18\x04 \x01(\x05R\bpageSize\x12\x1c\n" +
+	"\tpageToken\x18\x05 \x01(\tR\tpageToken\x128\n" +
+	"\tfieldMask\x18\x06 \x01(\v2\x1a.google.protobuf.FieldMaskR\tfieldMask\"\x80\x01\n" +
+	"\x14GetInstancesResponse\x12B\n" +
+	"\tinstances\x18\x01 \x03(\v2$.toskamesh.discovery.ServiceInstanceR\tinstances\x12$\n" +
+	"\rnextPageToken\x18\x02 \x01(\tR\rnextPageToken\"\xcd\x03\n" +
+	"\x0fServiceInstance\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x1c\n" +
+	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x129\n" +
+	"\x06status\x18\x05 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12N\n" +
+	"\bmetadata\x18\x06 \x03(\v22.toskamesh.discovery.ServiceInstance.MetadataEntryR\bmetadata\x12>\n" +
+	"\fregisteredAt\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\fregisteredAt\x12D\n" +
+	"\x0flastHealthCheck\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x0flastHealthCheck\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"2\n" +
+	"\x12GetInstanceRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\"W\n" +
+	"\x13GetInstanceResponse\x12@\n" +
+	"\binstance\x18\x01 \x01(\v2$.toskamesh.discovery.ServiceInstanceR\binstance\"N\n" +
+	"\x12GetServicesRequest\x12\x1a\n" +
+	"\bpageSize\x18\x01 \x01(\x05R\bpageSize\x12\x1c\n" +
+	"\tpageToken\x18\x02 \x01(\tR\tpageToken\"_\n" +
+	"\x13GetServicesResponse\x12\"\n" +
+	"\fserviceNames\x18\x01 \x03(\tR\fserviceNames\x12$\n" +
+	"\rnextPageToken\x18\x02 \x01(\tR\rnextPageToken\"\x86\x01\n" +
+	"\x13ReportHealthRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x129\n" +
+	"\x06status\x18\x02 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12\x16\n" +
+	"\x06output\x18\x03 \x01(\tR\x06output\"0\n" +
+	"\x14ReportHealthResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x82\x02\n" +
+	"\x15UpdateMetadataRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12T\n" +
+	"\bmetadata\x18\x02 \x03(\v28.toskamesh.discovery.UpdateMetadataRequest.MetadataEntryR\bmetadata\x12\x18\n" +
+	"\areplace\x18\x03 \x01(\bR\areplace\x12\x1e\n" +
+	"\n" +
+	"removeKeys\x18\x04 \x03(\tR\n" +
+	"removeKeys\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xea\x01\n" +
+	"\x16UpdateMetadataResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12U\n" +
+	"\bmetadata\x18\x02 \x03(\v29.toskamesh.discovery.UpdateMetadataResponse.MetadataEntryR\bmetadata\x12\"\n" +
+	"\ferrorMessage\x18\x03 \x01(\tR\ferrorMessage\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"_\n" +
+	"\x14RegisterBatchRequest\x12G\n" +
+	"\bservices\x18\x01 \x03(\v2+.toskamesh.discovery.RegisterServiceRequestR\bservices\"y\n" +
+	"\x15RegisterBatchResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12F\n" +
+	"\aresults\x18\x02 \x03(\v2,.toskamesh.discovery.RegisterServiceResponseR\aresults\"8\n" +
+	"\x16DeregisterBatchRequest\x12\x1e\n" +
+	"\n" +
+	"serviceIds\x18\x01 \x03(\tR\n" +
+	"serviceIds\"n\n" +
+	"\x10DeregisterResult\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12\x18\n" +
+	"\aremoved\x18\x02 \x01(\bR\aremoved\x12\"\n" +
+	"\ferrorMessage\x18\x03 \x01(\tR\ferrorMessage\"Z\n" +
+	"\x17DeregisterBatchResponse\x12?\n" +
+	"\aresults\x18\x01 \x03(\v2%.toskamesh.discovery.DeregisterResultR\aresults\"Z\n" +
+	"\x1eDeregisterByServiceNameRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"b\n" +
+	"\x1fDeregisterByServiceNameResponse\x12?\n" +
+	"\aresults\x18\x01 \x03(\v2%.toskamesh.discovery.DeregisterResultR\aresults\"5\n" +
+	"\x15RestoreServiceRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\"Z\n" +
+	"\x16RestoreServiceResponse\x12@\n" +
+	"\binstance\x18\x01 \x01(\v2$.toskamesh.discovery.ServiceInstanceR\binstance\"7\n" +
+	"\x13ListTrackingRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\"\xa1\x04\n" +
+	"\x0fTrackedInstance\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12 \n" +
+	"\vserviceName\x18\x02 \x01(\tR\vserviceName\x12>\n" +
+	"\fregisteredAt\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\fregisteredAt\x12B\n" +
+	"\x0ederegisteredAt\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0ederegisteredAt\x12<\n" +
+	"\vlastUpdated\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated\x129\n" +
+	"\x06status\x18\x06 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12D\n" +
+	"\x0flastHealthCheck\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x0flastHealthCheck\x12N\n" +
+	"\bmetadata\x18\b \x03(\v22.toskamesh.discovery.TrackedInstance.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"Z\n" +
+	"\x14ListTrackingResponse\x12B\n" +
+	"\tinstances\x18\x01 \x03(\v2$.toskamesh.discovery.TrackedInstanceR\tinstances\"M\n" +
+	"\x15ExpireInstanceRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"2\n" +
+	"\x16ExpireInstanceResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\bR\aremoved\"\x18\n" +
+	"\x16RebuildTrackingRequest\"_\n" +
+	"\x17RebuildTrackingResponse\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\x05R\x05added\x12\x18\n" +
+	"\aremoved\x18\x02 \x01(\x05R\aremoved\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x05R\x05total\"<\n" +
+	"\x18GetServiceHistoryRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\"\x96\x01\n" +
+	"\fHistoryEntry\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1c\n" +
+	"\tserviceId\x18\x02 \x01(\tR\tserviceId\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x16\n" +
+	"\x06detail\x18\x04 \x01(\tR\x06detail\"X\n" +
+	"\x19GetServiceHistoryResponse\x12;\n" +
+	"\aentries\x18\x01 \x03(\v2!.toskamesh.discovery.HistoryEntryR\aentries\"\x83\x01\n" +
+	"\x10HeartbeatRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x129\n" +
+	"\x06status\x18\x02 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12\x16\n" +
+	"\x06output\x18\x03 \x01(\tR\x06output\"y\n" +
+	"\x11HeartbeatResponse\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12\"\n" +
+	"\facknowledged\x18\x02 \x01(\bR\facknowledged\x12\"\n" +
+	"\ferrorMessage\x18\x03 \x01(\tR\ferrorMessage*}\n" +
 	"\fHealthStatus\x12\x19\n" +
 	"\x15HEALTH_STATUS_UNKNOWN\x10\x00\x12\x19\n" +
 	"\x15HEALTH_STATUS_HEALTHY\x10\x01\x12\x1b\n" +
 	"\x17HEALTH_STATUS_UNHEALTHY\x10\x02\x12\x1a\n" +
-	"\x16HEALTH_STATUS_DEGRADED\x10\x032\x93\x04\n" +
+	"\x16HEALTH_STATUS_DEGRADED\x10\x032\xb3\r\n" +
 	"\x11DiscoveryRegistry\x12e\n" +
 	"\bRegister\x12+.toskamesh.discovery.RegisterServiceRequest\x1a,.toskamesh.discovery.RegisterServiceResponse\x12k\n" +
 	"\n" +
 	"Deregister\x12-.toskamesh.discovery.DeregisterServiceRequest\x1a..toskamesh.discovery.DeregisterServiceResponse\x12c\n" +
 	"\fGetInstances\x12(.toskamesh.discovery.GetInstancesRequest\x1a).toskamesh.discovery.GetInstancesResponse\x12`\n" +
+	"\vGetInstance\x12'.toskamesh.discovery.GetInstanceRequest\x1a(.toskamesh.discovery.GetInstanceResponse\x12`\n" +
 	"\vGetServices\x12'.toskamesh.discovery.GetServicesRequest\x1a(.toskamesh.discovery.GetServicesResponse\x12c\n" +
-	"\fReportHealth\x12(.toskamesh.discovery.ReportHealthRequest\x1a).toskamesh.discovery.ReportHealthResponseBHZ+github.com/toska-mesh/toska-mesh/pkg/meshpb\xaa\x02\x18ToskaMesh.Grpc.Discoveryb\x06proto3"
+	"\fReportHealth\x12(.toskamesh.discovery.ReportHealthRequest\x1a).toskamesh.discovery.ReportHealthResponse\x12^\n" +
+	"\tHeartbeat\x12%.toskamesh.discovery.HeartbeatRequest\x1a&.toskamesh.discovery.HeartbeatResponse(\x010\x01\x12f\n" +
+	"\rRegisterBatch\x12).toskamesh.discovery.RegisterBatchRequest\x1a*.toskamesh.discovery.RegisterBatchResponse\x12l\n" +
+	"\x0fDeregisterBatch\x12+.toskamesh.discovery.DeregisterBatchRequest\x1a,.toskamesh.discovery.DeregisterBatchResponse\x12\x84\x01\n" +
+	"\x17DeregisterByServiceName\x123.toskamesh.discovery.DeregisterByServiceNameRequest\x1a4.toskamesh.discovery.DeregisterByServiceNameResponse\x12b\n" +
+	"\aRestore\x12*.toskamesh.discovery.RestoreServiceRequest\x1a+.toskamesh.discovery.RestoreServiceResponse\x12i\n" +
+	"\x0eUpdateMetadata\x12*.toskamesh.discovery.UpdateMetadataRequest\x1a+.toskamesh.discovery.UpdateMetadataResponse\x12c\n" +
+	"\fListTracking\x12(.toskamesh.discovery.ListTrackingRequest\x1a).toskamesh.discovery.ListTrackingResponse\x12i\n" +
+	"\x0eExpireInstance\x12*.toskamesh.discovery.ExpireInstanceRequest\x1a+.toskamesh.discovery.ExpireInstanceResponse\x12l\n" +
+	"\x0fRebuildTracking\x12+.toskamesh.discovery.RebuildTrackingRequest\x1a,.toskamesh.discovery.RebuildTrackingResponse\x12r\n" +
+	"\x11GetServiceHistory\x12-.toskamesh.discovery.GetServiceHistoryRequest\x1a..toskamesh.discovery.GetServiceHistoryResponseBHZ+github.com/toska-mesh/toska-mesh/pkg/meshpb\xaa\x02\x18ToskaMesh.Grpc.Discoveryb\x06proto3"
 
 var (
 	file_discovery_proto_rawDescOnce sync.Once
@@ -825,49 +2371,119 @@ func file_discovery_proto_rawDescGZIP() []byte {
 }
 
 var file_discovery_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_discovery_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_discovery_proto_msgTypes = make([]protoimpl.MessageInfo, 42)
 var file_discovery_proto_goTypes = []any{
-	(HealthStatus)(0),                 // 0: toskamesh.discovery.HealthStatus
-	(*HealthCheckConfig)(nil),         // 1: toskamesh.discovery.HealthCheckConfig
-	(*RegisterServiceRequest)(nil),    // 2: toskamesh.discovery.RegisterServiceRequest
-	(*RegisterServiceResponse)(nil),   // 3: toskamesh.discovery.RegisterServiceResponse
-	(*DeregisterServiceRequest)(nil),  // 4: toskamesh.discovery.DeregisterServiceRequest
-	(*DeregisterServiceResponse)(nil), // 5: toskamesh.discovery.DeregisterServiceResponse
-	(*GetInstancesRequest)(nil),       // 6: toskamesh.discovery.GetInstancesRequest
-	(*GetInstancesResponse)(nil),      // 7: toskamesh.discovery.GetInstancesResponse
-	(*ServiceInstance)(nil),           // 8: toskamesh.discovery.ServiceInstance
-	(*GetServicesRequest)(nil),        // 9: toskamesh.discovery.GetServicesRequest
-	(*GetServicesResponse)(nil),       // 10: toskamesh.discovery.GetServicesResponse
-	(*ReportHealthRequest)(nil),       // 11: toskamesh.discovery.ReportHealthRequest
-	(*ReportHealthResponse)(nil),      // 12: toskamesh.discovery.ReportHealthResponse
-	nil,                               // 13: toskamesh.discovery.RegisterServiceRequest.MetadataEntry
-	nil,                               // 14: toskamesh.discovery.ServiceInstance.MetadataEntry
-	(*timestamppb.Timestamp)(nil),     // 15: google.protobuf.Timestamp
+	(HealthStatus)(0),                       // 0: toskamesh.discovery.HealthStatus
+	(*HealthCheckConfig)(nil),               // 1: toskamesh.discovery.HealthCheckConfig
+	(*RegisterServiceRequest)(nil),          // 2: toskamesh.discovery.RegisterServiceRequest
+	(*RegisterServiceResponse)(nil),         // 3: toskamesh.discovery.RegisterServiceResponse
+	(*DeregisterServiceRequest)(nil),        // 4: toskamesh.discovery.DeregisterServiceRequest
+	(*DeregisterServiceResponse)(nil),       // 5: toskamesh.discovery.DeregisterServiceResponse
+	(*GetInstancesRequest)(nil),             // 6: toskamesh.discovery.GetInstancesRequest
+	(*GetInstancesResponse)(nil),            // 7: toskamesh.discovery.GetInstancesResponse
+	(*ServiceInstance)(nil),                 // 8: toskamesh.discovery.ServiceInstance
+	(*GetInstanceRequest)(nil),              // 9: toskamesh.discovery.GetInstanceRequest
+	(*GetInstanceResponse)(nil),             // 10: toskamesh.discovery.GetInstanceResponse
+	(*GetServicesRequest)(nil),              // 11: toskamesh.discovery.GetServicesRequest
+	(*GetServicesResponse)(nil),             // 12: toskamesh.discovery.GetServicesResponse
+	(*ReportHealthRequest)(nil),             // 13: toskamesh.discovery.ReportHealthRequest
+	(*ReportHealthResponse)(nil),            // 14: toskamesh.discovery.ReportHealthResponse
+	(*UpdateMetadataRequest)(nil),           // 15: toskamesh.discovery.UpdateMetadataRequest
+	(*UpdateMetadataResponse)(nil),          // 16: toskamesh.discovery.UpdateMetadataResponse
+	(*RegisterBatchRequest)(nil),            // 17: toskamesh.discovery.RegisterBatchRequest
+	(*RegisterBatchResponse)(nil),           // 18: toskamesh.discovery.RegisterBatchResponse
+	(*DeregisterBatchRequest)(nil),          // 19: toskamesh.discovery.DeregisterBatchRequest
+	(*DeregisterResult)(nil),                // 20: toskamesh.discovery.DeregisterResult
+	(*DeregisterBatchResponse)(nil),         // 21: toskamesh.discovery.DeregisterBatchResponse
+	(*DeregisterByServiceNameRequest)(nil),  // 22: toskamesh.discovery.DeregisterByServiceNameRequest
+	(*DeregisterByServiceNameResponse)(nil), // 23: toskamesh.discovery.DeregisterByServiceNameResponse
+	(*RestoreServiceRequest)(nil),           // 24: toskamesh.discovery.RestoreServiceRequest
+	(*RestoreServiceResponse)(nil),          // 25: toskamesh.discovery.RestoreServiceResponse
+	(*ListTrackingRequest)(nil),             // 26: toskamesh.discovery.ListTrackingRequest
+	(*TrackedInstance)(nil),                 // 27: toskamesh.discovery.TrackedInstance
+	(*ListTrackingResponse)(nil),            // 28: toskamesh.discovery.ListTrackingResponse
+	(*ExpireInstanceRequest)(nil),           // 29: toskamesh.discovery.ExpireInstanceRequest
+	(*ExpireInstanceResponse)(nil),          // 30: toskamesh.discovery.ExpireInstanceResponse
+	(*RebuildTrackingRequest)(nil),          // 31: toskamesh.discovery.RebuildTrackingRequest
+	(*RebuildTrackingResponse)(nil),         // 32: toskamesh.discovery.RebuildTrackingResponse
+	(*GetServiceHistoryRequest)(nil),        // 33: toskamesh.discovery.GetServiceHistoryRequest
+	(*HistoryEntry)(nil),                    // 34: toskamesh.discovery.HistoryEntry
+	(*GetServiceHistoryResponse)(nil),       // 35: toskamesh.discovery.GetServiceHistoryResponse
+	(*HeartbeatRequest)(nil),                // 36: toskamesh.discovery.HeartbeatRequest
+	(*HeartbeatResponse)(nil),               // 37: toskamesh.discovery.HeartbeatResponse
+	nil,                                     // 38: toskamesh.discovery.RegisterServiceRequest.MetadataEntry
+	nil,                                     // 39: toskamesh.discovery.ServiceInstance.MetadataEntry
+	nil,                                     // 40: toskamesh.discovery.UpdateMetadataRequest.MetadataEntry
+	nil,                                     // 41: toskamesh.discovery.UpdateMetadataResponse.MetadataEntry
+	nil,                                     // 42: toskamesh.discovery.TrackedInstance.MetadataEntry
+	(*fieldmaskpb.FieldMask)(nil),           // 43: google.protobuf.FieldMask
+	(*timestamppb.Timestamp)(nil),           // 44: google.protobuf.Timestamp
 }
 var file_discovery_proto_depIdxs = []int32{
-	13, // 0: toskamesh.discovery.RegisterServiceRequest.metadata:type_name -> toskamesh.discovery.RegisterServiceRequest.MetadataEntry
+	38, // 0: toskamesh.discovery.RegisterServiceRequest.metadata:type_name -> toskamesh.discovery.RegisterServiceRequest.MetadataEntry
 	1,  // 1: toskamesh.discovery.RegisterServiceRequest.healthCheck:type_name -> toskamesh.discovery.HealthCheckConfig
-	8,  // 2: toskamesh.discovery.GetInstancesResponse.instances:type_name -> toskamesh.discovery.ServiceInstance
-	0,  // 3: toskamesh.discovery.ServiceInstance.status:type_name -> toskamesh.discovery.HealthStatus
-	14, // 4: toskamesh.discovery.ServiceInstance.metadata:type_name -> toskamesh.discovery.ServiceInstance.MetadataEntry
-	15, // 5: toskamesh.discovery.ServiceInstance.registeredAt:type_name -> google.protobuf.Timestamp
-	15, // 6: toskamesh.discovery.ServiceInstance.lastHealthCheck:type_name -> google.protobuf.Timestamp
-	0,  // 7: toskamesh.discovery.ReportHealthRequest.status:type_name -> toskamesh.discovery.HealthStatus
-	2,  // 8: toskamesh.discovery.DiscoveryRegistry.Register:input_type -> toskamesh.discovery.RegisterServiceRequest
-	4,  // 9: toskamesh.discovery.DiscoveryRegistry.Deregister:input_type -> toskamesh.discovery.DeregisterServiceRequest
-	6,  // 10: toskamesh.discovery.DiscoveryRegistry.GetInstances:input_type -> toskamesh.discovery.GetInstancesRequest
-	9,  // 11: toskamesh.discovery.DiscoveryRegistry.GetServices:input_type -> toskamesh.discovery.GetServicesRequest
-	11, // 12: toskamesh.discovery.DiscoveryRegistry.ReportHealth:input_type -> toskamesh.discovery.ReportHealthRequest
-	3,  // 13: toskamesh.discovery.DiscoveryRegistry.Register:output_type -> toskamesh.discovery.RegisterServiceResponse
-	5,  // 14: toskamesh.discovery.DiscoveryRegistry.Deregister:output_type -> toskamesh.discovery.DeregisterServiceResponse
-	7,  // 15: toskamesh.discovery.DiscoveryRegistry.GetInstances:output_type -> toskamesh.discovery.GetInstancesResponse
-	10, // 16: toskamesh.discovery.DiscoveryRegistry.GetServices:output_type -> toskamesh.discovery.GetServicesResponse
-	12, // 17: toskamesh.discovery.DiscoveryRegistry.ReportHealth:output_type -> toskamesh.discovery.ReportHealthResponse
-	13, // [13:18] is the sub-list for method output_type
-	8,  // [8:13] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	43, // 2: toskamesh.discovery.GetInstancesRequest.fieldMask:type_name -> google.protobuf.FieldMask
+	8,  // 3: toskamesh.discovery.GetInstancesResponse.instances:type_name -> toskamesh.discovery.ServiceInstance
+	0,  // 4: toskamesh.discovery.ServiceInstance.status:type_name -> toskamesh.discovery.HealthStatus
+	39, // 5: toskamesh.discovery.ServiceInstance.metadata:type_name -> toskamesh.discovery.ServiceInstance.MetadataEntry
+	44, // 6: toskamesh.discovery.ServiceInstance.registeredAt:type_name -> google.protobuf.Timestamp
+	44, // 7: toskamesh.discovery.ServiceInstance.lastHealthCheck:type_name -> google.protobuf.Timestamp
+	8,  // 8: toskamesh.discovery.GetInstanceResponse.instance:type_name -> toskamesh.discovery.ServiceInstance
+	0,  // 9: toskamesh.discovery.ReportHealthRequest.status:type_name -> toskamesh.discovery.HealthStatus
+	40, // 10: toskamesh.discovery.UpdateMetadataRequest.metadata:type_name -> toskamesh.discovery.UpdateMetadataRequest.MetadataEntry
+	41, // 11: toskamesh.discovery.UpdateMetadataResponse.metadata:type_name -> toskamesh.discovery.UpdateMetadataResponse.MetadataEntry
+	2,  // 12: toskamesh.discovery.RegisterBatchRequest.services:type_name -> toskamesh.discovery.RegisterServiceRequest
+	3,  // 13: toskamesh.discovery.RegisterBatchResponse.results:type_name -> toskamesh.discovery.RegisterServiceResponse
+	20, // 14: toskamesh.discovery.DeregisterBatchResponse.results:type_name -> toskamesh.discovery.DeregisterResult
+	20, // 15: toskamesh.discovery.DeregisterByServiceNameResponse.results:type_name -> toskamesh.discovery.DeregisterResult
+	8,  // 16: toskamesh.discovery.RestoreServiceResponse.instance:type_name -> toskamesh.discovery.ServiceInstance
+	44, // 17: toskamesh.discovery.TrackedInstance.registeredAt:type_name -> google.protobuf.Timestamp
+	44, // 18: toskamesh.discovery.TrackedInstance.deregisteredAt:type_name -> google.protobuf.Timestamp
+	44, // 19: toskamesh.discovery.TrackedInstance.lastUpdated:type_name -> google.protobuf.Timestamp
+	0,  // 20: toskamesh.discovery.TrackedInstance.status:type_name -> toskamesh.discovery.HealthStatus
+	44, // 21: toskamesh.discovery.TrackedInstance.lastHealthCheck:type_name -> google.protobuf.Timestamp
+	42, // 22: toskamesh.discovery.TrackedInstance.metadata:type_name -> toskamesh.discovery.TrackedInstance.MetadataEntry
+	27, // 23: toskamesh.discovery.ListTrackingResponse.instances:type_name -> toskamesh.discovery.TrackedInstance
+	44, // 24: toskamesh.discovery.HistoryEntry.timestamp:type_name -> google.protobuf.Timestamp
+	34, // 25: toskamesh.discovery.GetServiceHistoryResponse.entries:type_name -> toskamesh.discovery.HistoryEntry
+	0,  // 26: toskamesh.discovery.HeartbeatRequest.status:type_name -> toskamesh.discovery.HealthStatus
+	2,  // 27: toskamesh.discovery.DiscoveryRegistry.Register:input_type -> toskamesh.discovery.RegisterServiceRequest
+	4,  // 28: toskamesh.discovery.DiscoveryRegistry.Deregister:input_type -> toskamesh.discovery.DeregisterServiceRequest
+	6,  // 29: toskamesh.discovery.DiscoveryRegistry.GetInstances:input_type -> toskamesh.discovery.GetInstancesRequest
+	9,  // 30: toskamesh.discovery.DiscoveryRegistry.GetInstance:input_type -> toskamesh.discovery.GetInstanceRequest
+	11, // 31: toskamesh.discovery.DiscoveryRegistry.GetServices:input_type -> toskamesh.discovery.GetServicesRequest
+	13, // 32: toskamesh.discovery.DiscoveryRegistry.ReportHealth:input_type -> toskamesh.discovery.ReportHealthRequest
+	36, // 33: toskamesh.discovery.DiscoveryRegistry.Heartbeat:input_type -> toskamesh.discovery.HeartbeatRequest
+	17, // 34: toskamesh.discovery.DiscoveryRegistry.RegisterBatch:input_type -> toskamesh.discovery.RegisterBatchRequest
+	19, // 35: toskamesh.discovery.DiscoveryRegistry.DeregisterBatch:input_type -> toskamesh.discovery.DeregisterBatchRequest
+	22, // 36: toskamesh.discovery.DiscoveryRegistry.DeregisterByServiceName:input_type -> toskamesh.discovery.DeregisterByServiceNameRequest
+	24, // 37: toskamesh.discovery.DiscoveryRegistry.Restore:input_type -> toskamesh.discovery.RestoreServiceRequest
+	15, // 38: toskamesh.discovery.DiscoveryRegistry.UpdateMetadata:input_type -> toskamesh.discovery.UpdateMetadataRequest
+	26, // 39: toskamesh.discovery.DiscoveryRegistry.ListTracking:input_type -> toskamesh.discovery.ListTrackingRequest
+	29, // 40: toskamesh.discovery.DiscoveryRegistry.ExpireInstance:input_type -> toskamesh.discovery.ExpireInstanceRequest
+	31, // 41: toskamesh.discovery.DiscoveryRegistry.RebuildTracking:input_type -> toskamesh.discovery.RebuildTrackingRequest
+	33, // 42: toskamesh.discovery.DiscoveryRegistry.GetServiceHistory:input_type -> toskamesh.discovery.GetServiceHistoryRequest
+	3,  // 43: toskamesh.discovery.DiscoveryRegistry.Register:output_type -> toskamesh.discovery.RegisterServiceResponse
+	5,  // 44: toskamesh.discovery.DiscoveryRegistry.Deregister:output_type -> toskamesh.discovery.DeregisterServiceResponse
+	7,  // 45: toskamesh.discovery.DiscoveryRegistry.GetInstances:output_type -> toskamesh.discovery.GetInstancesResponse
+	10, // 46: toskamesh.discovery.DiscoveryRegistry.GetInstance:output_type -> toskamesh.discovery.GetInstanceResponse
+	12, // 47: toskamesh.discovery.DiscoveryRegistry.GetServices:output_type -> toskamesh.discovery.GetServicesResponse
+	14, // 48: toskamesh.discovery.DiscoveryRegistry.ReportHealth:output_type -> toskamesh.discovery.ReportHealthResponse
+	37, // 49: toskamesh.discovery.DiscoveryRegistry.Heartbeat:output_type -> toskamesh.discovery.HeartbeatResponse
+	18, // 50: toskamesh.discovery.DiscoveryRegistry.RegisterBatch:output_type -> toskamesh.discovery.RegisterBatchResponse
+	21, // 51: toskamesh.discovery.DiscoveryRegistry.DeregisterBatch:output_type -> toskamesh.discovery.DeregisterBatchResponse
+	23, // 52: toskamesh.discovery.DiscoveryRegistry.DeregisterByServiceName:output_type -> toskamesh.discovery.DeregisterByServiceNameResponse
+	25, // 53: toskamesh.discovery.DiscoveryRegistry.Restore:output_type -> toskamesh.discovery.RestoreServiceResponse
+	16, // 54: toskamesh.discovery.DiscoveryRegistry.UpdateMetadata:output_type -> toskamesh.discovery.UpdateMetadataResponse
+	28, // 55: toskamesh.discovery.DiscoveryRegistry.ListTracking:output_type -> toskamesh.discovery.ListTrackingResponse
+	30, // 56: toskamesh.discovery.DiscoveryRegistry.ExpireInstance:output_type -> toskamesh.discovery.ExpireInstanceResponse
+	32, // 57: toskamesh.discovery.DiscoveryRegistry.RebuildTracking:output_type -> toskamesh.discovery.RebuildTrackingResponse
+	35, // 58: toskamesh.discovery.DiscoveryRegistry.GetServiceHistory:output_type -> toskamesh.discovery.GetServiceHistoryResponse
+	43, // [43:59] is the sub-list for method output_type
+	27, // [27:43] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_discovery_proto_init() }
@@ -881,7 +2497,7 @@ func file_discovery_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_discovery_proto_rawDesc), len(file_discovery_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   14,
+			NumMessages:   42,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
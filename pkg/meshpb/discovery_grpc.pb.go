@@ -19,11 +19,22 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DiscoveryRegistry_Register_FullMethodName     = "/toskamesh.discovery.DiscoveryRegistry/Register"
-	DiscoveryRegistry_Deregister_FullMethodName   = "/toskamesh.discovery.DiscoveryRegistry/Deregister"
-	DiscoveryRegistry_GetInstances_FullMethodName = "/toskamesh.discovery.DiscoveryRegistry/GetInstances"
-	DiscoveryRegistry_GetServices_FullMethodName  = "/toskamesh.discovery.DiscoveryRegistry/GetServices"
-	DiscoveryRegistry_ReportHealth_FullMethodName = "/toskamesh.discovery.DiscoveryRegistry/ReportHealth"
+	DiscoveryRegistry_Register_FullMethodName                = "/toskamesh.discovery.DiscoveryRegistry/Register"
+	DiscoveryRegistry_Deregister_FullMethodName              = "/toskamesh.discovery.DiscoveryRegistry/Deregister"
+	DiscoveryRegistry_GetInstances_FullMethodName            = "/toskamesh.discovery.DiscoveryRegistry/GetInstances"
+	DiscoveryRegistry_GetInstance_FullMethodName             = "/toskamesh.discovery.DiscoveryRegistry/GetInstance"
+	DiscoveryRegistry_GetServices_FullMethodName             = "/toskamesh.discovery.DiscoveryRegistry/GetServices"
+	DiscoveryRegistry_ReportHealth_FullMethodName            = "/toskamesh.discovery.DiscoveryRegistry/ReportHealth"
+	DiscoveryRegistry_Heartbeat_FullMethodName               = "/toskamesh.discovery.DiscoveryRegistry/Heartbeat"
+	DiscoveryRegistry_RegisterBatch_FullMethodName           = "/toskamesh.discovery.DiscoveryRegistry/RegisterBatch"
+	DiscoveryRegistry_DeregisterBatch_FullMethodName         = "/toskamesh.discovery.DiscoveryRegistry/DeregisterBatch"
+	DiscoveryRegistry_DeregisterByServiceName_FullMethodName = "/toskamesh.discovery.DiscoveryRegistry/DeregisterByServiceName"
+	DiscoveryRegistry_Restore_FullMethodName                 = "/toskamesh.discovery.DiscoveryRegistry/Restore"
+	DiscoveryRegistry_UpdateMetadata_FullMethodName          = "/toskamesh.discovery.DiscoveryRegistry/UpdateMetadata"
+	DiscoveryRegistry_ListTracking_FullMethodName            = "/toskamesh.discovery.DiscoveryRegistry/ListTracking"
+	DiscoveryRegistry_ExpireInstance_FullMethodName          = "/toskamesh.discovery.DiscoveryRegistry/ExpireInstance"
+	DiscoveryRegistry_RebuildTracking_FullMethodName         = "/toskamesh.discovery.DiscoveryRegistry/RebuildTracking"
+	DiscoveryRegistry_GetServiceHistory_FullMethodName       = "/toskamesh.discovery.DiscoveryRegistry/GetServiceHistory"
 )
 
 // DiscoveryRegistryClient is the client API for DiscoveryRegistry service.
@@ -33,8 +44,19 @@ type DiscoveryRegistryClient interface {
 	Register(ctx context.Context, in *RegisterServiceRequest, opts ...grpc.CallOption) (*RegisterServiceResponse, error)
 	Deregister(ctx context.Context, in *DeregisterServiceRequest, opts ...grpc.CallOption) (*DeregisterServiceResponse, error)
 	GetInstances(ctx context.Context, in *GetInstancesRequest, opts ...grpc.CallOption) (*GetInstancesResponse, error)
+	GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GetInstanceResponse, error)
 	GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error)
 	ReportHealth(ctx context.Context, in *ReportHealthRequest, opts ...grpc.CallOption) (*ReportHealthResponse, error)
+	Heartbeat(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse], error)
+	RegisterBatch(ctx context.Context, in *RegisterBatchRequest, opts ...grpc.CallOption) (*RegisterBatchResponse, error)
+	DeregisterBatch(ctx context.Context, in *DeregisterBatchRequest, opts ...grpc.CallOption) (*DeregisterBatchResponse, error)
+	DeregisterByServiceName(ctx context.Context, in *DeregisterByServiceNameRequest, opts ...grpc.CallOption) (*DeregisterByServiceNameResponse, error)
+	Restore(ctx context.Context, in *RestoreServiceRequest, opts ...grpc.CallOption) (*RestoreServiceResponse, error)
+	UpdateMetadata(ctx context.Context, in *UpdateMetadataRequest, opts ...grpc.CallOption) (*UpdateMetadataResponse, error)
+	ListTracking(ctx context.Context, in *ListTrackingRequest, opts ...grpc.CallOption) (*ListTrackingResponse, error)
+	ExpireInstance(ctx context.Context, in *ExpireInstanceRequest, opts ...grpc.CallOption) (*ExpireInstanceResponse, error)
+	RebuildTracking(ctx context.Context, in *RebuildTrackingRequest, opts ...grpc.CallOption) (*RebuildTrackingResponse, error)
+	GetServiceHistory(ctx context.Context, in *GetServiceHistoryRequest, opts ...grpc.CallOption) (*GetServiceHistoryResponse, error)
 }
 
 type discoveryRegistryClient struct {
@@ -75,6 +97,16 @@ func (c *discoveryRegistryClient) GetInstances(ctx context.Context, in *GetInsta
 	return out, nil
 }
 
+func (c *discoveryRegistryClient) GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GetInstanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInstanceResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_GetInstance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *discoveryRegistryClient) GetServices(ctx context.Context, in *GetServicesRequest, opts ...grpc.CallOption) (*GetServicesResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetServicesResponse)
@@ -95,6 +127,109 @@ func (c *discoveryRegistryClient) ReportHealth(ctx context.Context, in *ReportHe
 	return out, nil
 }
 
+func (c *discoveryRegistryClient) Heartbeat(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DiscoveryRegistry_ServiceDesc.Streams[0], DiscoveryRegistry_Heartbeat_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[HeartbeatRequest, HeartbeatResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DiscoveryRegistry_HeartbeatClient = grpc.BidiStreamingClient[HeartbeatRequest, HeartbeatResponse]
+
+func (c *discoveryRegistryClient) RegisterBatch(ctx context.Context, in *RegisterBatchRequest, opts ...grpc.CallOption) (*RegisterBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterBatchResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_RegisterBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) DeregisterBatch(ctx context.Context, in *DeregisterBatchRequest, opts ...grpc.CallOption) (*DeregisterBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeregisterBatchResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_DeregisterBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) DeregisterByServiceName(ctx context.Context, in *DeregisterByServiceNameRequest, opts ...grpc.CallOption) (*DeregisterByServiceNameResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeregisterByServiceNameResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_DeregisterByServiceName_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) Restore(ctx context.Context, in *RestoreServiceRequest, opts ...grpc.CallOption) (*RestoreServiceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreServiceResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_Restore_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) UpdateMetadata(ctx context.Context, in *UpdateMetadataRequest, opts ...grpc.CallOption) (*UpdateMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateMetadataResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_UpdateMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) ListTracking(ctx context.Context, in *ListTrackingRequest, opts ...grpc.CallOption) (*ListTrackingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTrackingResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_ListTracking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) ExpireInstance(ctx context.Context, in *ExpireInstanceRequest, opts ...grpc.CallOption) (*ExpireInstanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpireInstanceResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_ExpireInstance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) RebuildTracking(ctx context.Context, in *RebuildTrackingRequest, opts ...grpc.CallOption) (*RebuildTrackingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RebuildTrackingResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_RebuildTracking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *discoveryRegistryClient) GetServiceHistory(ctx context.Context, in *GetServiceHistoryRequest, opts ...grpc.CallOption) (*GetServiceHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetServiceHistoryResponse)
+	err := c.cc.Invoke(ctx, DiscoveryRegistry_GetServiceHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DiscoveryRegistryServer is the server API for DiscoveryRegistry service.
 // All implementations must embed UnimplementedDiscoveryRegistryServer
 // for forward compatibility.
@@ -102,8 +237,19 @@ type DiscoveryRegistryServer interface {
 	Register(context.Context, *RegisterServiceRequest) (*RegisterServiceResponse, error)
 	Deregister(context.Context, *DeregisterServiceRequest) (*DeregisterServiceResponse, error)
 	GetInstances(context.Context, *GetInstancesRequest) (*GetInstancesResponse, error)
+	GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error)
 	GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error)
 	ReportHealth(context.Context, *ReportHealthRequest) (*ReportHealthResponse, error)
+	Heartbeat(grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]) error
+	RegisterBatch(context.Context, *RegisterBatchRequest) (*RegisterBatchResponse, error)
+	DeregisterBatch(context.Context, *DeregisterBatchRequest) (*DeregisterBatchResponse, error)
+	DeregisterByServiceName(context.Context, *DeregisterByServiceNameRequest) (*DeregisterByServiceNameResponse, error)
+	Restore(context.Context, *RestoreServiceRequest) (*RestoreServiceResponse, error)
+	UpdateMetadata(context.Context, *UpdateMetadataRequest) (*UpdateMetadataResponse, error)
+	ListTracking(context.Context, *ListTrackingRequest) (*ListTrackingResponse, error)
+	ExpireInstance(context.Context, *ExpireInstanceRequest) (*ExpireInstanceResponse, error)
+	RebuildTracking(context.Context, *RebuildTrackingRequest) (*RebuildTrackingResponse, error)
+	GetServiceHistory(context.Context, *GetServiceHistoryRequest) (*GetServiceHistoryResponse, error)
 	mustEmbedUnimplementedDiscoveryRegistryServer()
 }
 
@@ -123,12 +269,45 @@ func (UnimplementedDiscoveryRegistryServer) Deregister(context.Context, *Deregis
 func (UnimplementedDiscoveryRegistryServer) GetInstances(context.Context, *GetInstancesRequest) (*GetInstancesResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetInstances not implemented")
 }
+func (UnimplementedDiscoveryRegistryServer) GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetInstance not implemented")
+}
 func (UnimplementedDiscoveryRegistryServer) GetServices(context.Context, *GetServicesRequest) (*GetServicesResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetServices not implemented")
 }
 func (UnimplementedDiscoveryRegistryServer) ReportHealth(context.Context, *ReportHealthRequest) (*ReportHealthResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ReportHealth not implemented")
 }
+func (UnimplementedDiscoveryRegistryServer) Heartbeat(grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]) error {
+	return status.Error(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) RegisterBatch(context.Context, *RegisterBatchRequest) (*RegisterBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RegisterBatch not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) DeregisterBatch(context.Context, *DeregisterBatchRequest) (*DeregisterBatchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeregisterBatch not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) DeregisterByServiceName(context.Context, *DeregisterByServiceNameRequest) (*DeregisterByServiceNameResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeregisterByServiceName not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) Restore(context.Context, *RestoreServiceRequest) (*RestoreServiceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) UpdateMetadata(context.Context, *UpdateMetadataRequest) (*UpdateMetadataResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateMetadata not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) ListTracking(context.Context, *ListTrackingRequest) (*ListTrackingResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTracking not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) ExpireInstance(context.Context, *ExpireInstanceRequest) (*ExpireInstanceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExpireInstance not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) RebuildTracking(context.Context, *RebuildTrackingRequest) (*RebuildTrackingResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RebuildTracking not implemented")
+}
+func (UnimplementedDiscoveryRegistryServer) GetServiceHistory(context.Context, *GetServiceHistoryRequest) (*GetServiceHistoryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetServiceHistory not implemented")
+}
 func (UnimplementedDiscoveryRegistryServer) mustEmbedUnimplementedDiscoveryRegistryServer() {}
 func (UnimplementedDiscoveryRegistryServer) testEmbeddedByValue()                           {}
 
@@ -204,6 +383,24 @@ func _DiscoveryRegistry_GetInstances_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DiscoveryRegistry_GetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).GetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_GetInstance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).GetInstance(ctx, req.(*GetInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DiscoveryRegistry_GetServices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetServicesRequest)
 	if err := dec(in); err != nil {
@@ -240,6 +437,175 @@ func _DiscoveryRegistry_ReportHealth_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DiscoveryRegistry_Heartbeat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DiscoveryRegistryServer).Heartbeat(&grpc.GenericServerStream[HeartbeatRequest, HeartbeatResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DiscoveryRegistry_HeartbeatServer = grpc.BidiStreamingServer[HeartbeatRequest, HeartbeatResponse]
+
+func _DiscoveryRegistry_RegisterBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).RegisterBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_RegisterBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).RegisterBatch(ctx, req.(*RegisterBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_DeregisterBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).DeregisterBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_DeregisterBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).DeregisterBatch(ctx, req.(*DeregisterBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_DeregisterByServiceName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterByServiceNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).DeregisterByServiceName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_DeregisterByServiceName_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).DeregisterByServiceName(ctx, req.(*DeregisterByServiceNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_Restore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).Restore(ctx, req.(*RestoreServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_UpdateMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).UpdateMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_UpdateMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).UpdateMetadata(ctx, req.(*UpdateMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_ListTracking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrackingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).ListTracking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_ListTracking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).ListTracking(ctx, req.(*ListTrackingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_ExpireInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).ExpireInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_ExpireInstance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).ExpireInstance(ctx, req.(*ExpireInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_RebuildTracking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebuildTrackingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).RebuildTracking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_RebuildTracking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).RebuildTracking(ctx, req.(*RebuildTrackingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DiscoveryRegistry_GetServiceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DiscoveryRegistryServer).GetServiceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DiscoveryRegistry_GetServiceHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DiscoveryRegistryServer).GetServiceHistory(ctx, req.(*GetServiceHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DiscoveryRegistry_ServiceDesc is the grpc.ServiceDesc for DiscoveryRegistry service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -259,6 +625,10 @@ var DiscoveryRegistry_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetInstances",
 			Handler:    _DiscoveryRegistry_GetInstances_Handler,
 		},
+		{
+			MethodName: "GetInstance",
+			Handler:    _DiscoveryRegistry_GetInstance_Handler,
+		},
 		{
 			MethodName: "GetServices",
 			Handler:    _DiscoveryRegistry_GetServices_Handler,
@@ -267,7 +637,50 @@ var DiscoveryRegistry_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportHealth",
 			Handler:    _DiscoveryRegistry_ReportHealth_Handler,
 		},
+		{
+			MethodName: "RegisterBatch",
+			Handler:    _DiscoveryRegistry_RegisterBatch_Handler,
+		},
+		{
+			MethodName: "DeregisterBatch",
+			Handler:    _DiscoveryRegistry_DeregisterBatch_Handler,
+		},
+		{
+			MethodName: "DeregisterByServiceName",
+			Handler:    _DiscoveryRegistry_DeregisterByServiceName_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _DiscoveryRegistry_Restore_Handler,
+		},
+		{
+			MethodName: "UpdateMetadata",
+			Handler:    _DiscoveryRegistry_UpdateMetadata_Handler,
+		},
+		{
+			MethodName: "ListTracking",
+			Handler:    _DiscoveryRegistry_ListTracking_Handler,
+		},
+		{
+			MethodName: "ExpireInstance",
+			Handler:    _DiscoveryRegistry_ExpireInstance_Handler,
+		},
+		{
+			MethodName: "RebuildTracking",
+			Handler:    _DiscoveryRegistry_RebuildTracking_Handler,
+		},
+		{
+			MethodName: "GetServiceHistory",
+			Handler:    _DiscoveryRegistry_GetServiceHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Heartbeat",
+			Handler:       _DiscoveryRegistry_Heartbeat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "discovery.proto",
 }
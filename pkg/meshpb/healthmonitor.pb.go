@@ -0,0 +1,634 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.1
+// source: healthmonitor.proto
+
+package meshpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// InstanceHealth is the latest probe result for a service instance.
+type InstanceHealth struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId   string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	ServiceName string                 `protobuf:"bytes,2,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	Address     string                 `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Port        int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Status      HealthStatus           `protobuf:"varint,5,opt,name=status,proto3,enum=toskamesh.discovery.HealthStatus" json:"status,omitempty"`
+	LastProbe   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=lastProbe,proto3" json:"lastProbe,omitempty"`
+	ProbeType   string                 `protobuf:"bytes,7,opt,name=probeType,proto3" json:"probeType,omitempty"`
+	Message     string                 `protobuf:"bytes,8,opt,name=message,proto3" json:"message,omitempty"`
+	LatencyMs   float64                `protobuf:"fixed64,9,opt,name=latencyMs,proto3" json:"latencyMs,omitempty"`
+	// flapping is set while the instance oscillates between healthy and
+	// unhealthy; its health events are suppressed meanwhile.
+	Flapping      bool              `protobuf:"varint,10,opt,name=flapping,proto3" json:"flapping,omitempty"`
+	Metadata      map[string]string `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstanceHealth) Reset() {
+	*x = InstanceHealth{}
+	mi := &file_healthmonitor_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstanceHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstanceHealth) ProtoMessage() {}
+
+func (x *InstanceHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstanceHealth.ProtoReflect.Descriptor instead.
+func (*InstanceHealth) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *InstanceHealth) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *InstanceHealth) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *InstanceHealth) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *InstanceHealth) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *InstanceHealth) GetStatus() HealthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return HealthStatus_HEALTH_STATUS_UNKNOWN
+}
+
+func (x *InstanceHealth) GetLastProbe() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastProbe
+	}
+	return nil
+}
+
+func (x *InstanceHealth) GetProbeType() string {
+	if x != nil {
+		return x.ProbeType
+	}
+	return ""
+}
+
+func (x *InstanceHealth) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *InstanceHealth) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *InstanceHealth) GetFlapping() bool {
+	if x != nil {
+		return x.Flapping
+	}
+	return false
+}
+
+func (x *InstanceHealth) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// GetStatusRequest lists the monitored instances of a service, or of every
+// service when serviceName is empty.
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName   string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_healthmonitor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetStatusRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instances     []*InstanceHealth      `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_healthmonitor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetStatusResponse) GetInstances() []*InstanceHealth {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
+}
+
+type GetHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     string                 `protobuf:"bytes,1,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryRequest) Reset() {
+	*x = GetHistoryRequest{}
+	mi := &file_healthmonitor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryRequest) ProtoMessage() {}
+
+func (x *GetHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryRequest.ProtoReflect.Descriptor instead.
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetHistoryRequest) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+type ProbeRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Status        HealthStatus           `protobuf:"varint,2,opt,name=status,proto3,enum=toskamesh.discovery.HealthStatus" json:"status,omitempty"`
+	ProbeType     string                 `protobuf:"bytes,3,opt,name=probeType,proto3" json:"probeType,omitempty"`
+	LatencyMs     float64                `protobuf:"fixed64,4,opt,name=latencyMs,proto3" json:"latencyMs,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProbeRecord) Reset() {
+	*x = ProbeRecord{}
+	mi := &file_healthmonitor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProbeRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProbeRecord) ProtoMessage() {}
+
+func (x *ProbeRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProbeRecord.ProtoReflect.Descriptor instead.
+func (*ProbeRecord) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ProbeRecord) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *ProbeRecord) GetStatus() HealthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return HealthStatus_HEALTH_STATUS_UNKNOWN
+}
+
+func (x *ProbeRecord) GetProbeType() string {
+	if x != nil {
+		return x.ProbeType
+	}
+	return ""
+}
+
+func (x *ProbeRecord) GetLatencyMs() float64 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *ProbeRecord) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// GetHistoryResponse holds an instance's recent probe results, oldest first.
+type GetHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*ProbeRecord         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHistoryResponse) Reset() {
+	*x = GetHistoryResponse{}
+	mi := &file_healthmonitor_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHistoryResponse) ProtoMessage() {}
+
+func (x *GetHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHistoryResponse.ProtoReflect.Descriptor instead.
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetHistoryResponse) GetResults() []*ProbeRecord {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// WatchStatusRequest streams the status changes of a service's instances,
+// or of every instance when serviceName is empty.
+type WatchStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceName   string                 `protobuf:"bytes,1,opt,name=serviceName,proto3" json:"serviceName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatusRequest) Reset() {
+	*x = WatchStatusRequest{}
+	mi := &file_healthmonitor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusRequest) ProtoMessage() {}
+
+func (x *WatchStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusRequest.ProtoReflect.Descriptor instead.
+func (*WatchStatusRequest) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchStatusRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+// StatusChange is one message on a WatchStatus stream. The stream opens
+// with the current state of every matching instance, marked initial.
+type StatusChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *InstanceHealth        `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	Previous      HealthStatus           `protobuf:"varint,2,opt,name=previous,proto3,enum=toskamesh.discovery.HealthStatus" json:"previous,omitempty"`
+	Initial       bool                   `protobuf:"varint,3,opt,name=initial,proto3" json:"initial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusChange) Reset() {
+	*x = StatusChange{}
+	mi := &file_healthmonitor_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusChange) ProtoMessage() {}
+
+func (x *StatusChange) ProtoReflect() protoreflect.Message {
+	mi := &file_healthmonitor_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusChange.ProtoReflect.Descriptor instead.
+func (*StatusChange) Descriptor() ([]byte, []int) {
+	return file_healthmonitor_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StatusChange) GetInstance() *InstanceHealth {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+func (x *StatusChange) GetPrevious() HealthStatus {
+	if x != nil {
+		return x.Previous
+	}
+	return HealthStatus_HEALTH_STATUS_UNKNOWN
+}
+
+func (x *StatusChange) GetInitial() bool {
+	if x != nil {
+		return x.Initial
+	}
+	return false
+}
+
+var File_healthmonitor_proto protoreflect.FileDescriptor
+
+const file_healthmonitor_proto_rawDesc = "" +
+	"\n" +
+	"\x13healthmonitor.proto\x12\x17toskamesh.healthmonitor\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x0fdiscovery.proto\"\xf5\x03\n" +
+	"\x0eInstanceHealth\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\x12 \n" +
+	"\vserviceName\x18\x02 \x01(\tR\vserviceName\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x129\n" +
+	"\x06status\x18\x05 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x128\n" +
+	"\tlastProbe\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tlastProbe\x12\x1c\n" +
+	"\tprobeType\x18\a \x01(\tR\tprobeType\x12\x18\n" +
+	"\amessage\x18\b \x01(\tR\amessage\x12\x1c\n" +
+	"\tlatencyMs\x18\t \x01(\x01R\tlatencyMs\x12\x1a\n" +
+	"\bflapping\x18\n" +
+	" \x01(\bR\bflapping\x12Q\n" +
+	"\bmetadata\x18\v \x03(\v25.toskamesh.healthmonitor.InstanceHealth.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"4\n" +
+	"\x10GetStatusRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\"Z\n" +
+	"\x11GetStatusResponse\x12E\n" +
+	"\tinstances\x18\x01 \x03(\v2'.toskamesh.healthmonitor.InstanceHealthR\tinstances\"1\n" +
+	"\x11GetHistoryRequest\x12\x1c\n" +
+	"\tserviceId\x18\x01 \x01(\tR\tserviceId\"\xce\x01\n" +
+	"\vProbeRecord\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x129\n" +
+	"\x06status\x18\x02 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\x06status\x12\x1c\n" +
+	"\tprobeType\x18\x03 \x01(\tR\tprobeType\x12\x1c\n" +
+	"\tlatencyMs\x18\x04 \x01(\x01R\tlatencyMs\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\"T\n" +
+	"\x12GetHistoryResponse\x12>\n" +
+	"\aresults\x18\x01 \x03(\v2$.toskamesh.healthmonitor.ProbeRecordR\aresults\"6\n" +
+	"\x12WatchStatusRequest\x12 \n" +
+	"\vserviceName\x18\x01 \x01(\tR\vserviceName\"\xac\x01\n" +
+	"\fStatusChange\x12C\n" +
+	"\binstance\x18\x01 \x01(\v2'.toskamesh.healthmonitor.InstanceHealthR\binstance\x12=\n" +
+	"\bprevious\x18\x02 \x01(\x0e2!.toskamesh.discovery.HealthStatusR\bprevious\x12\x18\n" +
+	"\ainitial\x18\x03 \x01(\bR\ainitial2\xbf\x02\n" +
+	"\rHealthMonitor\x12b\n" +
+	"\tGetStatus\x12).toskamesh.healthmonitor.GetStatusRequest\x1a*.toskamesh.healthmonitor.GetStatusResponse\x12e\n" +
+	"\n" +
+	"GetHistory\x12*.toskamesh.healthmonitor.GetHistoryRequest\x1a+.toskamesh.healthmonitor.GetHistoryResponse\x12c\n" +
+	"\vWatchStatus\x12+.toskamesh.healthmonitor.WatchStatusRequest\x1a%.toskamesh.healthmonitor.StatusChange0\x01BLZ+github.com/toska-mesh/toska-mesh/pkg/meshpb\xaa\x02\x1cToskaMesh.Grpc.HealthMonitorb\x06proto3"
+
+var (
+	file_healthmonitor_proto_rawDescOnce sync.Once
+	file_healthmonitor_proto_rawDescData []byte
+)
+
+func file_healthmonitor_proto_rawDescGZIP() []byte {
+	file_healthmonitor_proto_rawDescOnce.Do(func() {
+		file_healthmonitor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_healthmonitor_proto_rawDesc), len(file_healthmonitor_proto_rawDesc)))
+	})
+	return file_healthmonitor_proto_rawDescData
+}
+
+var file_healthmonitor_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_healthmonitor_proto_goTypes = []any{
+	(*InstanceHealth)(nil),        // 0: toskamesh.healthmonitor.InstanceHealth
+	(*GetStatusRequest)(nil),      // 1: toskamesh.healthmonitor.GetStatusRequest
+	(*GetStatusResponse)(nil),     // 2: toskamesh.healthmonitor.GetStatusResponse
+	(*GetHistoryRequest)(nil),     // 3: toskamesh.healthmonitor.GetHistoryRequest
+	(*ProbeRecord)(nil),           // 4: toskamesh.healthmonitor.ProbeRecord
+	(*GetHistoryResponse)(nil),    // 5: toskamesh.healthmonitor.GetHistoryResponse
+	(*WatchStatusRequest)(nil),    // 6: toskamesh.healthmonitor.WatchStatusRequest
+	(*StatusChange)(nil),          // 7: toskamesh.healthmonitor.StatusChange
+	nil,                           // 8: toskamesh.healthmonitor.InstanceHealth.MetadataEntry
+	(HealthStatus)(0),             // 9: toskamesh.discovery.HealthStatus
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_healthmonitor_proto_depIdxs = []int32{
+	9,  // 0: toskamesh.healthmonitor.InstanceHealth.status:type_name -> toskamesh.discovery.HealthStatus
+	10, // 1: toskamesh.healthmonitor.InstanceHealth.lastProbe:type_name -> google.protobuf.Timestamp
+	8,  // 2: toskamesh.healthmonitor.InstanceHealth.metadata:type_name -> toskamesh.healthmonitor.InstanceHealth.MetadataEntry
+	0,  // 3: toskamesh.healthmonitor.GetStatusResponse.instances:type_name -> toskamesh.healthmonitor.InstanceHealth
+	10, // 4: toskamesh.healthmonitor.ProbeRecord.time:type_name -> google.protobuf.Timestamp
+	9,  // 5: toskamesh.healthmonitor.ProbeRecord.status:type_name -> toskamesh.discovery.HealthStatus
+	4,  // 6: toskamesh.healthmonitor.GetHistoryResponse.results:type_name -> toskamesh.healthmonitor.ProbeRecord
+	0,  // 7: toskamesh.healthmonitor.StatusChange.instance:type_name -> toskamesh.healthmonitor.InstanceHealth
+	9,  // 8: toskamesh.healthmonitor.StatusChange.previous:type_name -> toskamesh.discovery.HealthStatus
+	1,  // 9: toskamesh.healthmonitor.HealthMonitor.GetStatus:input_type -> toskamesh.healthmonitor.GetStatusRequest
+	3,  // 10: toskamesh.healthmonitor.HealthMonitor.GetHistory:input_type -> toskamesh.healthmonitor.GetHistoryRequest
+	6,  // 11: toskamesh.healthmonitor.HealthMonitor.WatchStatus:input_type -> toskamesh.healthmonitor.WatchStatusRequest
+	2,  // 12: toskamesh.healthmonitor.HealthMonitor.GetStatus:output_type -> toskamesh.healthmonitor.GetStatusResponse
+	5,  // 13: toskamesh.healthmonitor.HealthMonitor.GetHistory:output_type -> toskamesh.healthmonitor.GetHistoryResponse
+	7,  // 14: toskamesh.healthmonitor.HealthMonitor.WatchStatus:output_type -> toskamesh.healthmonitor.StatusChange
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_healthmonitor_proto_init() }
+func file_healthmonitor_proto_init() {
+	if File_healthmonitor_proto != nil {
+		return
+	}
+	file_discovery_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_healthmonitor_proto_rawDesc), len(file_healthmonitor_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_healthmonitor_proto_goTypes,
+		DependencyIndexes: file_healthmonitor_proto_depIdxs,
+		MessageInfos:      file_healthmonitor_proto_msgTypes,
+	}.Build()
+	File_healthmonitor_proto = out.File
+	file_healthmonitor_proto_goTypes = nil
+	file_healthmonitor_proto_depIdxs = nil
+}
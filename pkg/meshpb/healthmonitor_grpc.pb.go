@@ -0,0 +1,205 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             v6.33.1
+// source: healthmonitor.proto
+
+package meshpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	HealthMonitor_GetStatus_FullMethodName   = "/toskamesh.healthmonitor.HealthMonitor/GetStatus"
+	HealthMonitor_GetHistory_FullMethodName  = "/toskamesh.healthmonitor.HealthMonitor/GetHistory"
+	HealthMonitor_WatchStatus_FullMethodName = "/toskamesh.healthmonitor.HealthMonitor/WatchStatus"
+)
+
+// HealthMonitorClient is the client API for HealthMonitor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HealthMonitorClient interface {
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// WatchStatus ends with RESOURCE_EXHAUSTED when the watcher falls too far
+	// behind; watch again to resynchronise.
+	WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusChange], error)
+}
+
+type healthMonitorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHealthMonitorClient(cc grpc.ClientConnInterface) HealthMonitorClient {
+	return &healthMonitorClient{cc}
+}
+
+func (c *healthMonitorClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, HealthMonitor_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *healthMonitorClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, HealthMonitor_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *healthMonitorClient) WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &HealthMonitor_ServiceDesc.Streams[0], HealthMonitor_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatusRequest, StatusChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HealthMonitor_WatchStatusClient = grpc.ServerStreamingClient[StatusChange]
+
+// HealthMonitorServer is the server API for HealthMonitor service.
+// All implementations must embed UnimplementedHealthMonitorServer
+// for forward compatibility.
+type HealthMonitorServer interface {
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// WatchStatus ends with RESOURCE_EXHAUSTED when the watcher falls too far
+	// behind; watch again to resynchronise.
+	WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[StatusChange]) error
+	mustEmbedUnimplementedHealthMonitorServer()
+}
+
+// UnimplementedHealthMonitorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHealthMonitorServer struct{}
+
+func (UnimplementedHealthMonitorServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedHealthMonitorServer) GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedHealthMonitorServer) WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[StatusChange]) error {
+	return status.Error(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedHealthMonitorServer) mustEmbedUnimplementedHealthMonitorServer() {}
+func (UnimplementedHealthMonitorServer) testEmbeddedByValue()                       {}
+
+// UnsafeHealthMonitorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HealthMonitorServer will
+// result in compilation errors.
+type UnsafeHealthMonitorServer interface {
+	mustEmbedUnimplementedHealthMonitorServer()
+}
+
+func RegisterHealthMonitorServer(s grpc.ServiceRegistrar, srv HealthMonitorServer) {
+	// If the following call panics, it indicates UnimplementedHealthMonitorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HealthMonitor_ServiceDesc, srv)
+}
+
+func _HealthMonitor_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthMonitorServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HealthMonitor_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthMonitorServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HealthMonitor_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthMonitorServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HealthMonitor_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthMonitorServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HealthMonitor_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HealthMonitorServer).WatchStatus(m, &grpc.GenericServerStream[WatchStatusRequest, StatusChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HealthMonitor_WatchStatusServer = grpc.ServerStreamingServer[StatusChange]
+
+// HealthMonitor_ServiceDesc is the grpc.ServiceDesc for HealthMonitor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HealthMonitor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "toskamesh.healthmonitor.HealthMonitor",
+	HandlerType: (*HealthMonitorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _HealthMonitor_GetStatus_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _HealthMonitor_GetHistory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _HealthMonitor_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "healthmonitor.proto",
+}
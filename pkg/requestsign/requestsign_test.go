@@ -0,0 +1,112 @@
+package requestsign
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	key := []byte("orders-secret")
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name    string
+		mutate  func(*http.Request)
+		verify  time.Time
+		key     []byte
+		maxBody int64
+		wantErr error
+	}{
+		{name: "valid", verify: now, key: key},
+		{name: "wrong key", verify: now, key: []byte("other"), wantErr: ErrBadSignature},
+		{name: "clock skew", verify: now.Add(10 * time.Minute), key: key, wantErr: ErrExpired},
+		{name: "body too large", verify: now, key: key, maxBody: 4, wantErr: ErrBodyTooLarge},
+		{
+			name: "tampered path", verify: now, key: key, wantErr: ErrBadSignature,
+			mutate: func(r *http.Request) { r.URL.Path = "/api/orders/other" },
+		},
+		{
+			name: "tampered body", verify: now, key: key, wantErr: ErrBadSignature,
+			mutate: func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"id":2}`)) },
+		},
+		{
+			name: "tampered host", verify: now, key: key, wantErr: ErrBadSignature,
+			mutate: func(r *http.Request) { r.Host = "evil.example" },
+		},
+		{
+			name: "tampered content type", verify: now, key: key, wantErr: ErrBadSignature,
+			mutate: func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		},
+		{
+			name: "tampered nonce", verify: now, key: key, wantErr: ErrBadSignature,
+			mutate: func(r *http.Request) { r.Header.Set(NonceHeader, "0123") },
+		},
+		{
+			name: "missing headers", verify: now, key: key, wantErr: ErrMissingHeaders,
+			mutate: func(r *http.Request) { r.Header.Del(SignatureHeader) },
+		},
+		{
+			name: "missing nonce", verify: now, key: key, wantErr: ErrMissingHeaders,
+			mutate: func(r *http.Request) { r.Header.Del(NonceHeader) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/orders/items?x=1", strings.NewReader(`{"id":1}`))
+			r.Header.Set("Content-Type", "application/json")
+			if err := Sign(r, "billing", key, now); err != nil {
+				t.Fatal(err)
+			}
+			if tt.mutate != nil {
+				tt.mutate(r)
+			}
+			err := Verify(r, tt.key, tt.verify, 5*time.Minute, tt.maxBody)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != `{"id":1}` {
+					t.Fatalf("expected body to remain readable, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := NewReplayCache(time.Minute)
+	sign := func(service string) *http.Request {
+		r := httptest.NewRequest("GET", "/api/orders", nil)
+		if err := Sign(r, service, []byte("k"), now); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := sign("billing")
+	if err := c.Check(r, now); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := c.Check(r, now.Add(time.Minute)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected replay within the skew window, got %v", err)
+	}
+	if err := c.Check(sign("billing"), now); err != nil {
+		t.Fatalf("fresh nonce: %v", err)
+	}
+
+	// Once the timestamp is outside the skew window Verify rejects the
+	// request, so the nonce may be forgotten.
+	if err := c.Check(r, now.Add(3*time.Minute)); err != nil {
+		t.Fatalf("expected the nonce to be forgotten, got %v", err)
+	}
+	if len(c.seen) != 1 {
+		t.Fatalf("expected expired nonces swept, have %d", len(c.seen))
+	}
+}
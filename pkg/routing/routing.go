@@ -0,0 +1,68 @@
+// Package routing exposes the gateway's request routing semantics — route
+// prefix normalization, service name extraction, and backend URL construction —
+// so sidecars, tests, and external tools can reuse them exactly.
+package routing
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultScheme is used for backends that do not declare a scheme.
+const DefaultScheme = "http"
+
+// NormalizePrefix ensures the prefix starts and ends with "/".
+func NormalizePrefix(prefix string) string {
+	if prefix == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// ParseServiceFromPath extracts the service name from a request path given a
+// normalized prefix. For example, with prefix "/api/" and path
+// "/api/my-service/foo/bar", returns ("my-service", "/foo/bar", true).
+func ParseServiceFromPath(prefix, path string) (serviceName, remainder string, ok bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", "", false
+	}
+
+	rest := path[len(prefix):]
+	if rest == "" {
+		return "", "", false
+	}
+
+	// Split on first "/".
+	idx := strings.IndexByte(rest, '/')
+	if idx < 0 {
+		return rest, "/", true
+	}
+	return rest[:idx], rest[idx:], true
+}
+
+// BackendAddress builds the base URL of a backend instance (scheme://host:port).
+// An empty scheme defaults to DefaultScheme.
+func BackendAddress(scheme, host string, port int) string {
+	if scheme == "" {
+		scheme = DefaultScheme
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, host, port)
+}
+
+// BuildBackendURL constructs the full backend URL for a request.
+func BuildBackendURL(backendAddr, remainder, rawQuery string) string {
+	u, err := url.Parse(backendAddr)
+	if err != nil {
+		return backendAddr + remainder
+	}
+	u.Path = remainder
+	u.RawQuery = rawQuery
+	return u.String()
+}
@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"testing"
+)
+
+func TestNormalizePrefix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/api", "/api/"},
+		{"/api/", "/api/"},
+		{"api", "/api/"},
+		{"api/", "/api/"},
+	}
+
+	for _, tt := range tests {
+		got := NormalizePrefix(tt.input)
+		if got != tt.expected {
+			t.Errorf("NormalizePrefix(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseServiceFromPath(t *testing.T) {
+	tests := []struct {
+		prefix      string
+		path        string
+		wantService string
+		wantRest    string
+		wantOK      bool
+	}{
+		{"/api/", "/api/my-service/foo/bar", "my-service", "/foo/bar", true},
+		{"/api/", "/api/my-service", "my-service", "/", true},
+		{"/api/", "/api/my-service/", "my-service", "/", true},
+		{"/api/", "/api/", "", "", false},
+		{"/api/", "/other/path", "", "", false},
+		{"/", "/my-service/hello", "my-service", "/hello", true},
+		{"/", "/my-service", "my-service", "/", true},
+	}
+
+	for _, tt := range tests {
+		svc, rest, ok := ParseServiceFromPath(tt.prefix, tt.path)
+		if ok != tt.wantOK || svc != tt.wantService || rest != tt.wantRest {
+			t.Errorf("ParseServiceFromPath(%q, %q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.prefix, tt.path, svc, rest, ok, tt.wantService, tt.wantRest, tt.wantOK)
+		}
+	}
+}
+
+func TestBackendAddress(t *testing.T) {
+	tests := []struct {
+		scheme string
+		host   string
+		port   int
+		want   string
+	}{
+		{"", "10.0.0.1", 8080, "http://10.0.0.1:8080"},
+		{"http", "10.0.0.1", 8080, "http://10.0.0.1:8080"},
+		{"https", "svc.local", 443, "https://svc.local:443"},
+	}
+
+	for _, tt := range tests {
+		got := BackendAddress(tt.scheme, tt.host, tt.port)
+		if got != tt.want {
+			t.Errorf("BackendAddress(%q, %q, %d) = %q, want %q", tt.scheme, tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestBuildBackendURL(t *testing.T) {
+	tests := []struct {
+		addr      string
+		remainder string
+		query     string
+		want      string
+	}{
+		{"http://10.0.0.1:8080", "/hello", "", "http://10.0.0.1:8080/hello"},
+		{"http://10.0.0.1:8080", "/hello", "q=1", "http://10.0.0.1:8080/hello?q=1"},
+		{"https://svc.local:443", "/api/v1/data", "page=2&limit=10", "https://svc.local:443/api/v1/data?page=2&limit=10"},
+	}
+
+	for _, tt := range tests {
+		got := BuildBackendURL(tt.addr, tt.remainder, tt.query)
+		if got != tt.want {
+			t.Errorf("BuildBackendURL(%q, %q, %q) = %q, want %q",
+				tt.addr, tt.remainder, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRuleSet_Match(t *testing.T) {
+	rules, err := CompileRules([]Rule{
+		{Name: "catalog-read", Methods: []string{"get"}, Path: "/api/catalog/{rest...}", Service: "catalog-read", StripPrefix: "/api/catalog"},
+		{Name: "catalog-write", Methods: []string{"POST", "PUT"}, Path: "/api/catalog/{rest...}", Service: "catalog-write", StripPrefix: "/api/catalog"},
+		{Name: "catalog-item", Path: "/api/catalog/items/{id}", Service: "catalog-items"},
+		{Name: "legacy", Path: "/api/catalog/{rest...}", Service: "catalog-legacy", Priority: -1},
+	})
+	if err != nil {
+		t.Fatalf("CompileRules: %v", err)
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		wantService string
+		wantRest    string
+		wantOK      bool
+	}{
+		{"GET", "/api/catalog", "catalog-read", "/", true},
+		{"GET", "/api/catalog/search", "catalog-read", "/search", true},
+		{"POST", "/api/catalog", "catalog-write", "/", true},
+		{"GET", "/api/catalog/items/42", "catalog-items", "/api/catalog/items/42", true},
+		{"GET", "/api/catalog/items/42/reviews", "catalog-read", "/items/42/reviews", true},
+		{"DELETE", "/api/catalog/1", "catalog-legacy", "/api/catalog/1", true},
+		{"GET", "/api/orders/1", "", "", false},
+	}
+	for _, tt := range tests {
+		rule, rest, ok := rules.Match(tt.method, tt.path)
+		if ok != tt.wantOK || rule.Service != tt.wantService || rest != tt.wantRest {
+			t.Errorf("Match(%s %s) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.method, tt.path, rule.Service, rest, ok, tt.wantService, tt.wantRest, tt.wantOK)
+		}
+	}
+
+	var none *RuleSet
+	if _, _, ok := none.Match("GET", "/api/catalog"); ok {
+		t.Error("expected nil rule set to match nothing")
+	}
+}
+
+func TestCompileRules_Invalid(t *testing.T) {
+	tests := []Rule{
+		{Path: "/api/x"},
+		{Path: "api/x", Service: "x"},
+		{Path: "/api/{rest...}/x", Service: "x"},
+		{Path: "/api/{id", Service: "x"},
+	}
+	for _, r := range tests {
+		if _, err := CompileRules([]Rule{r}); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rule routes requests by HTTP method and path pattern to a service, ahead of
+// the conventional {prefix}{service}/... mapping. This lets several services
+// share a public path space, e.g. GET /api/catalog to a read service and
+// POST /api/catalog to a write service.
+//
+// Path patterns are slash-separated segments. A segment {name} matches any
+// single segment, and a final segment {name...} matches the rest of the path,
+// including nothing. Other segments match literally.
+type Rule struct {
+	Name     string   `json:"name,omitempty"`
+	Methods  []string `json:"methods,omitempty"` // empty matches any method
+	Path     string   `json:"path"`
+	Service  string   `json:"service"`
+	Priority int      `json:"priority,omitempty"` // higher is tried first
+
+	// StripPrefix is removed from the request path before it is forwarded.
+	// Empty forwards the path unchanged.
+	StripPrefix string `json:"stripPrefix,omitempty"`
+}
+
+// RuleSet is a compiled, ordered set of rules. A nil RuleSet matches nothing.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	rule     Rule
+	methods  map[string]struct{}
+	segments []string
+	rest     bool // last pattern segment is {name...}
+	literals int
+}
+
+// CompileRules validates and orders rules: by descending Priority, then by
+// specificity (more literal segments first, catch-all patterns last), then
+// in the given order.
+func CompileRules(rules []Rule) (*RuleSet, error) {
+	var errs []error
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			name := r.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			errs = append(errs, fmt.Errorf("route rule %s: %w", name, err))
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	sort.SliceStable(compiled, func(i, j int) bool {
+		a, b := compiled[i], compiled[j]
+		if a.rule.Priority != b.rule.Priority {
+			return a.rule.Priority > b.rule.Priority
+		}
+		if a.literals != b.literals {
+			return a.literals > b.literals
+		}
+		return !a.rest && b.rest
+	})
+	return &RuleSet{rules: compiled}, nil
+}
+
+func compileRule(r Rule) (compiledRule, error) {
+	if r.Service == "" {
+		return compiledRule{}, errors.New("service is required")
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return compiledRule{}, fmt.Errorf("path %q must start with /", r.Path)
+	}
+
+	c := compiledRule{rule: r, segments: strings.Split(strings.TrimPrefix(r.Path, "/"), "/")}
+	for i, seg := range c.segments {
+		if !strings.HasPrefix(seg, "{") {
+			c.literals++
+			continue
+		}
+		if !strings.HasSuffix(seg, "}") || len(seg) < 3 {
+			return compiledRule{}, fmt.Errorf("invalid wildcard %q in path %q", seg, r.Path)
+		}
+		if strings.HasSuffix(seg, "...}") {
+			if i != len(c.segments)-1 {
+				return compiledRule{}, fmt.Errorf("%s must be the last segment of %q", seg, r.Path)
+			}
+			c.rest = true
+		}
+	}
+
+	if len(r.Methods) > 0 {
+		c.methods = make(map[string]struct{}, len(r.Methods))
+		for _, m := range r.Methods {
+			c.methods[strings.ToUpper(m)] = struct{}{}
+		}
+	}
+	return c, nil
+}
+
+// Match returns the first rule matching method and path, together with the
+// path to forward to the backend.
+func (rs *RuleSet) Match(method, path string) (rule Rule, remainder string, ok bool) {
+	if rs == nil {
+		return Rule{}, "", false
+	}
+	for _, c := range rs.rules {
+		if c.match(method, path) {
+			return c.rule, c.remainder(path), true
+		}
+	}
+	return Rule{}, "", false
+}
+
+func (c compiledRule) match(method, path string) bool {
+	if c.methods != nil {
+		if _, ok := c.methods[strings.ToUpper(method)]; !ok {
+			return false
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, seg := range c.segments {
+		if c.rest && i == len(c.segments)-1 {
+			return true
+		}
+		if i >= len(parts) {
+			return false
+		}
+		if strings.HasPrefix(seg, "{") {
+			if parts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if seg != parts[i] {
+			return false
+		}
+	}
+	return len(parts) == len(c.segments)
+}
+
+func (c compiledRule) remainder(path string) string {
+	if c.rule.StripPrefix == "" || !strings.HasPrefix(path, c.rule.StripPrefix) {
+		return path
+	}
+	rest := path[len(c.rule.StripPrefix):]
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return rest
+}